@@ -0,0 +1,298 @@
+// Package agents splits kasa into specialized agents, a deployer, a
+// debugger and a security reviewer, each with its own toolset and
+// instructions. A coordinator hands each user message to the one that
+// suits it, unless the user selected one with /mode.
+package agents
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strings"
+	"sync"
+
+	"github.com/perbu/kasa/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+)
+
+// Mode selects the agent that handles the user's messages.
+type Mode string
+
+const (
+	// ModeAuto lets the coordinator pick an agent for every message.
+	ModeAuto Mode = "auto"
+	// ModeDeploy sends every message to the deployer.
+	ModeDeploy Mode = "deploy"
+	// ModeDebug sends every message to the debugger.
+	ModeDebug Mode = "debug"
+	// ModeReview sends every message to the security reviewer.
+	ModeReview Mode = "review"
+)
+
+// Modes lists the modes in the order they are shown to the user.
+var Modes = []Mode{ModeAuto, ModeDeploy, ModeDebug, ModeReview}
+
+// ParseMode returns the mode named s.
+func ParseMode(s string) (Mode, error) {
+	for _, m := range Modes {
+		if strings.EqualFold(s, string(m)) {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown mode %q (available: %s)", s, modeList())
+}
+
+func modeList() string {
+	names := make([]string, len(Modes))
+	for i, m := range Modes {
+		names[i] = string(m)
+	}
+	return strings.Join(names, ", ")
+}
+
+// specialist describes one of the agents the coordinator hands messages to.
+type specialist struct {
+	mode        Mode
+	name        string
+	description string // tells the coordinator when to pick it
+	mutating    bool   // gets the mutating and planning tools
+}
+
+var specialists = []specialist{
+	{ModeDeploy, "deployer", "Makes changes to the cluster and the stored manifests: deploys, updates, scales, rolls back, deletes and fixes resources, through plans the user approves.", true},
+	{ModeDebug, "debugger", "Investigates problems without changing anything: crashing or pending pods, failed rollouts, unreachable services, errors in logs, events and metrics, drift.", false},
+	{ModeReview, "reviewer", "Reviews security without changing anything: RBAC, secrets, pod security settings, images, network exposure and policies.", false},
+}
+
+// AgentName returns the name of the agent that handles messages in mode.
+func AgentName(mode Mode) string {
+	for _, s := range specialists {
+		if s.mode == mode {
+			return s.name
+		}
+	}
+	return coordinatorName
+}
+
+// IsSpecialist reports whether name is one of the specialized agents.
+func IsSpecialist(name string) bool {
+	for _, s := range specialists {
+		if s.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Reserved reports whether name is taken by an agent of this package.
+func Reserved(name string) bool {
+	return name == coordinatorName || IsSpecialist(name)
+}
+
+const coordinatorName = "coordinator"
+
+// Router holds the mode the user selected.
+type Router struct {
+	mu   sync.Mutex
+	mode Mode
+}
+
+// NewRouter returns a router starting in mode.
+func NewRouter(mode Mode) *Router {
+	return &Router{mode: mode}
+}
+
+// Mode returns the selected mode.
+func (r *Router) Mode() Mode {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.mode
+}
+
+// SetMode selects mode for the following messages.
+func (r *Router) SetMode(mode Mode) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mode = mode
+}
+
+type modeKey struct{}
+
+// WithMode makes a run of the agent use mode, whatever the user selected.
+// Approved plans are executed this way by the deployer.
+func WithMode(ctx context.Context, mode Mode) context.Context {
+	return context.WithValue(ctx, modeKey{}, mode)
+}
+
+// Config configures the agents New builds.
+type Config struct {
+	// Name is the name of the root agent.
+	Name string
+	// Template is copied into every agent: the model and callbacks.
+	Template llmagent.Config
+	// Tools are all tools; only the deployer gets the mutating and
+	// planning ones.
+	Tools []tool.Tool
+	// Instruction returns the instruction shared by the specialists, given
+	// the tools one of them has. Its role follows it.
+	Instruction func(tools []tool.Tool) (string, error)
+	// Prompts replace the built-in role instructions, by mode; ModeAuto
+	// is the coordinator's.
+	Prompts map[Mode]string
+}
+
+// New builds the agent tree: a root that runs the coordinator or, if a
+// mode other than auto is selected, the specialist directly. A message
+// answering a function call, such as ask_clarification, goes to the agent
+// that made the call.
+func New(cfg Config, router *Router) (agent.Agent, error) {
+	byMode := make(map[Mode]agent.Agent)
+	byName := make(map[string]agent.Agent)
+	var subAgents []agent.Agent
+	for _, s := range specialists {
+		toolset := cfg.Tools
+		if !s.mutating {
+			toolset = readOnly(cfg.Tools)
+		}
+		instruction, err := cfg.Instruction(toolset)
+		if err != nil {
+			return nil, err
+		}
+		ac := cfg.Template
+		ac.Name = s.name
+		ac.Description = s.description
+		ac.Instruction = instruction + "\n\n" + rolePrompt(cfg.Prompts, s.mode)
+		ac.Tools = toolset
+		// The coordinator routes every message; specialists never hand over
+		ac.DisallowTransferToParent = true
+		ac.DisallowTransferToPeers = true
+		a, err := llmagent.New(ac)
+		if err != nil {
+			return nil, fmt.Errorf("creating %s agent: %w", s.name, err)
+		}
+		byMode[s.mode] = a
+		byName[s.name] = a
+		subAgents = append(subAgents, a)
+	}
+
+	cc := cfg.Template
+	cc.Name = coordinatorName
+	cc.Description = "Hands each message to the agent that suits it"
+	cc.Instruction = rolePrompt(cfg.Prompts, ModeAuto)
+	cc.SubAgents = subAgents
+	coordinator, err := llmagent.New(cc)
+	if err != nil {
+		return nil, fmt.Errorf("creating coordinator agent: %w", err)
+	}
+	byMode[ModeAuto] = coordinator
+
+	return agent.New(agent.Config{
+		Name:        cfg.Name,
+		Description: "Kubernetes deployment assistant",
+		SubAgents:   []agent.Agent{coordinator},
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			// Answers to a question go back to the agent that asked it
+			if a, ok := byName[caller(ctx)]; ok {
+				return a.Run(ctx)
+			}
+			mode := router.Mode()
+			if m, ok := ctx.Value(modeKey{}).(Mode); ok {
+				mode = m
+			}
+			a, ok := byMode[mode]
+			if !ok {
+				a = coordinator
+			}
+			return a.Run(ctx)
+		},
+	})
+}
+
+// caller returns the author of the function call the user's message
+// responds to, if it is a function response.
+func caller(ctx agent.InvocationContext) string {
+	content := ctx.UserContent()
+	if content == nil {
+		return ""
+	}
+	ids := make(map[string]bool)
+	for _, part := range content.Parts {
+		if part.FunctionResponse != nil && part.FunctionResponse.ID != "" {
+			ids[part.FunctionResponse.ID] = true
+		}
+	}
+	if len(ids) == 0 {
+		return ""
+	}
+	events := ctx.Session().Events()
+	for i := events.Len() - 1; i >= 0; i-- {
+		event := events.At(i)
+		if event.Content == nil {
+			continue
+		}
+		for _, part := range event.Content.Parts {
+			if part.FunctionCall != nil && ids[part.FunctionCall.ID] {
+				return event.Author
+			}
+		}
+	}
+	return ""
+}
+
+// readOnly returns the read-only tools of all.
+func readOnly(all []tool.Tool) []tool.Tool {
+	var result []tool.Tool
+	for _, t := range all {
+		if tools.IsReadOnly(t) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// rolePrompt returns the role instruction for mode.
+func rolePrompt(prompts map[Mode]string, mode Mode) string {
+	if p := strings.TrimSpace(prompts[mode]); p != "" {
+		return p
+	}
+	return defaultPrompts[mode]
+}
+
+var defaultPrompts = map[Mode]string{
+	ModeAuto: `You are the coordinator of Kasa, a Kubernetes deployment assistant. You never
+answer the user and never use tools yourself. For every user message, call
+transfer_to_agent with the agent that should handle it:
+- deployer: the user wants something created, changed, scaled, restarted, rolled
+  back or deleted, or is approving, rejecting or confirming a plan.
+- debugger: the user asks why something is broken or slow, or wants to inspect
+  pods, logs, events, metrics or drift.
+- reviewer: the user asks about security, permissions, secrets or compliance.
+Follow-up questions go to the agent that handled the previous message, unless
+the user clearly moves on to another kind of task. Read-only questions that fit
+no agent go to the debugger.`,
+
+	ModeDeploy: `## Your Role: Deployer
+You make changes carefully. Gather what you need with read-only tools, validate
+with dry runs where possible, propose a plan, and only change anything after the
+user approved it. Prefer small, reversible steps and verify each change took
+effect before moving on.`,
+
+	ModeDebug: `## Your Role: Debugger
+You investigate. You cannot change anything, so use your read-only tools freely
+and aggressively: check pods, events, logs, rollouts, services, endpoints,
+resource usage and drift until you find the root cause. Report what is wrong,
+the evidence, and the fix you recommend. If the user wants the fix applied, tell
+them to ask for it, so the deployer can propose a plan, or to switch with
+/mode deploy.`,
+
+	ModeReview: `## Your Role: Security Reviewer
+You review security. You cannot change anything. Look for overly broad RBAC,
+ServiceAccount tokens mounted where unneeded, secrets in plain environment
+variables or ConfigMaps, containers running as root or privileged, missing
+resource limits, mutable image tags, services and ingresses exposed without need,
+and namespaces without network policies. Rank findings by severity and say how to
+fix each one.`,
+}
@@ -0,0 +1,208 @@
+package agents
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/perbu/kasa/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// scriptedModel answers the coordinator with a transfer to the agent named
+// in the user's message, and everyone else with text.
+type scriptedModel struct {
+	mu       sync.Mutex
+	requests []seenRequest
+}
+
+type seenRequest struct {
+	agent    string // from the role in the instruction
+	tools    []string
+	response map[string]any // the function response the request ends with
+}
+
+func (m *scriptedModel) Name() string { return "scripted" }
+
+func (m *scriptedModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		instruction := ""
+		if req.Config != nil && req.Config.SystemInstruction != nil {
+			for _, p := range req.Config.SystemInstruction.Parts {
+				instruction += p.Text
+			}
+		}
+		var names []string
+		for name := range req.Tools {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		role := "unknown"
+		for _, r := range []string{"coordinator", "Deployer", "Debugger", "Security Reviewer"} {
+			if strings.Contains(instruction, r) {
+				role = r
+				break
+			}
+		}
+		last := req.Contents[len(req.Contents)-1].Parts[0]
+		seen := seenRequest{agent: role, tools: names}
+		if last.FunctionResponse != nil {
+			seen.response = last.FunctionResponse.Response
+		}
+		m.mu.Lock()
+		m.requests = append(m.requests, seen)
+		m.mu.Unlock()
+
+		if role == "coordinator" {
+			target := strings.TrimPrefix(last.Text, "route to ")
+			yield(&model.LLMResponse{Content: genai.NewContentFromFunctionCall("transfer_to_agent", map[string]any{"agent_name": target}, genai.RoleModel)}, nil)
+			return
+		}
+		if last.Text == "ask" {
+			yield(&model.LLMResponse{Content: genai.NewContentFromFunctionCall("ask_clarification", map[string]any{"context": "replicas?"}, genai.RoleModel)}, nil)
+			return
+		}
+		yield(&model.LLMResponse{Content: genai.NewContentFromText("done", genai.RoleModel)}, nil)
+	}
+}
+
+// staticTool is a tool with a fixed category.
+type staticTool struct {
+	name     string
+	category tools.ToolCategory
+}
+
+func (t staticTool) Name() string                 { return t.name }
+func (t staticTool) Description() string          { return t.name }
+func (t staticTool) IsLongRunning() bool          { return false }
+func (t staticTool) Category() tools.ToolCategory { return t.category }
+func (t staticTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{Name: t.name, Description: t.name}
+}
+func (t staticTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	return map[string]any{"status": "awaiting_answers"}, nil
+}
+func (t staticTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	if req.Tools == nil {
+		req.Tools = make(map[string]any)
+	}
+	req.Tools[t.name] = t
+	return nil
+}
+
+func TestAgents(t *testing.T) {
+	llm := &scriptedModel{}
+	router := NewRouter(ModeAuto)
+	root, err := New(Config{
+		Name:     "kasa",
+		Template: llmagent.Config{Model: llm},
+		Tools: []tool.Tool{
+			staticTool{"list_pods", tools.CategoryReadOnly},
+			staticTool{"delete_resource", tools.CategoryMutating},
+			staticTool{"propose_plan", tools.CategoryPlanning},
+			staticTool{"ask_clarification", tools.CategoryPlanning},
+		},
+		Instruction: func(ts []tool.Tool) (string, error) {
+			return "You are Kasa.\n" + tools.FormatToolDocs(ts), nil
+		},
+	}, router)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := session.InMemoryService()
+	r, err := runner.New(runner.Config{AppName: "kasa", Agent: root, SessionService: sessions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessions.Create(context.Background(), &session.CreateRequest{AppName: "kasa", UserID: "u", SessionID: "s"}); err != nil {
+		t.Fatal(err)
+	}
+	var callID string
+	sendContent := func(ctx context.Context, content *genai.Content) []seenRequest {
+		t.Helper()
+		llm.mu.Lock()
+		llm.requests = nil
+		llm.mu.Unlock()
+		for event, err := range r.Run(ctx, "u", "s", content, agent.RunConfig{}) {
+			if err != nil {
+				t.Fatalf("%+v: %v", content.Parts[0], err)
+			}
+			for _, part := range event.Content.Parts {
+				if part.FunctionCall != nil {
+					callID = part.FunctionCall.ID
+				}
+			}
+		}
+		llm.mu.Lock()
+		defer llm.mu.Unlock()
+		return llm.requests
+	}
+	send := func(ctx context.Context, text string) []seenRequest {
+		t.Helper()
+		return sendContent(ctx, genai.NewContentFromText(text, genai.RoleUser))
+	}
+
+	// Auto: the coordinator hands over, and the debugger only reads
+	seen := send(context.Background(), "route to debugger")
+	if len(seen) != 2 || seen[0].agent != "coordinator" || seen[1].agent != "Debugger" {
+		t.Fatalf("expected the coordinator to hand over to the debugger, got %+v", seen)
+	}
+	if !slices.Equal(seen[0].tools, []string{"transfer_to_agent"}) {
+		t.Errorf("the coordinator should only transfer, got tools %v", seen[0].tools)
+	}
+	if !slices.Equal(seen[1].tools, []string{"list_pods"}) {
+		t.Errorf("the debugger should only get read-only tools, got %v", seen[1].tools)
+	}
+
+	// The coordinator routes every message, not just the first
+	seen = send(context.Background(), "route to deployer")
+	if len(seen) != 2 || seen[1].agent != "Deployer" || !slices.Equal(seen[1].tools, []string{"ask_clarification", "delete_resource", "list_pods", "propose_plan"}) {
+		t.Errorf("expected the deployer with every tool, got %+v", seen)
+	}
+
+	// A selected mode skips the coordinator
+	router.SetMode(ModeReview)
+	seen = send(context.Background(), "anything")
+	if len(seen) != 1 || seen[0].agent != "Security Reviewer" {
+		t.Errorf("expected the reviewer to answer directly, got %+v", seen)
+	}
+
+	// Approved plans go to the deployer whatever is selected
+	seen = send(WithMode(context.Background(), ModeDeploy), "Plan approved")
+	if len(seen) != 1 || seen[0].agent != "Deployer" {
+		t.Errorf("expected the deployer to execute the plan, got %+v", seen)
+	}
+
+	// The answer to a question goes to the agent that asked, in any mode
+	router.SetMode(ModeDeploy)
+	seen = send(context.Background(), "ask")
+	if len(seen) != 2 || seen[0].agent != "Deployer" || callID == "" {
+		t.Fatalf("expected the deployer to ask a question, got %+v", seen)
+	}
+	router.SetMode(ModeDebug)
+	answer := map[string]any{"status": "answered", "answers": []any{"3"}}
+	seen = sendContent(context.Background(), &genai.Content{Role: genai.RoleUser, Parts: []*genai.Part{
+		{FunctionResponse: &genai.FunctionResponse{ID: callID, Name: "ask_clarification", Response: answer}},
+	}})
+	if len(seen) != 1 || seen[0].agent != "Deployer" || seen[0].response["status"] != "answered" {
+		t.Errorf("expected the deployer to get the answer as the response to its call, got %+v", seen)
+	}
+
+	if _, err := ParseMode("Debug"); err != nil {
+		t.Errorf("modes should parse case-insensitively: %v", err)
+	}
+	if _, err := ParseMode("ops"); err == nil || !strings.Contains(err.Error(), "auto, deploy, debug, review") {
+		t.Errorf("expected an error listing the modes, got %v", err)
+	}
+}
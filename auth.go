@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/perbu/kasa/keychain"
+	"golang.org/x/term"
+)
+
+// loadKeychainSecrets fills API keys missing from the environment and .env
+// with values stored in the OS keychain. Explicit environment variables always
+// take precedence so CI and one-off overrides keep working.
+func loadKeychainSecrets(debug bool) {
+	if !keychain.Available() {
+		return
+	}
+	for _, name := range keychain.KnownKeys {
+		if os.Getenv(name) != "" {
+			continue
+		}
+		value, err := keychain.Get(name)
+		if err != nil {
+			continue
+		}
+		os.Setenv(name, value)
+		if debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Loaded %s from keychain\n", name)
+		}
+	}
+}
+
+// runAuthCommand implements the "kasa auth <subcommand>" CLI.
+func runAuthCommand(args []string) int {
+	usage := func() int {
+		fmt.Fprintln(os.Stderr, "usage: kasa auth set <NAME> | kasa auth delete <NAME> | kasa auth status")
+		fmt.Fprintf(os.Stderr, "known names: %s\n", strings.Join(keychain.KnownKeys, ", "))
+		return 2
+	}
+	if len(args) == 0 {
+		return usage()
+	}
+
+	switch args[0] {
+	case "set":
+		if len(args) != 2 {
+			return usage()
+		}
+		value, err := readSecret(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := keychain.Set(args[1], value); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Stored %s in the keychain.\n", args[1])
+		if keychain.ArgumentExposed() {
+			fmt.Println("Note: macOS `security` only takes the value as a command-line argument, so other processes could read it (e.g. with ps) while it ran.")
+		}
+		return 0
+
+	case "delete":
+		if len(args) != 2 {
+			return usage()
+		}
+		if err := keychain.Delete(args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Removed %s from the keychain.\n", args[1])
+		return 0
+
+	case "status":
+		if !keychain.Available() {
+			fmt.Println(keychain.ErrUnsupported)
+		}
+		for _, name := range keychain.KnownKeys {
+			source := "not set"
+			if os.Getenv(name) != "" {
+				source = "environment/.env"
+			} else if _, err := keychain.Get(name); err == nil {
+				source = "keychain"
+			}
+			fmt.Printf("%-24s %s\n", name, source)
+		}
+		return 0
+
+	default:
+		return usage()
+	}
+}
+
+// readSecret reads a secret value without echoing it when stdin is a
+// terminal, or the first line of stdin otherwise (for scripted use).
+func readSecret(name string) (string, error) {
+	var value string
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprintf(os.Stderr, "Enter value for %s: ", name)
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("reading value: %w", err)
+		}
+		value = string(b)
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("reading value from stdin: %w", err)
+		}
+		value = line
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errors.New("empty value")
+	}
+	return value, nil
+}
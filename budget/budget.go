@@ -0,0 +1,433 @@
+// Package budget keeps long agent sessions inside the model's context window
+// and the user's spending limit. A Tracker adds up the token usage reported
+// by the model and estimates its cost; a Compactor rewrites each model
+// request so that older tool results are truncated and, once the
+// conversation grows past a threshold, older turns are replaced by a
+// model-written summary. The session history itself is never modified.
+package budget
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// Defaults for unset Config fields.
+const (
+	DefaultCompactAt       = 200_000
+	DefaultKeepTurns       = 4
+	DefaultToolResultChars = 2_000
+)
+
+// warnFraction is the share of the budget at which the user is warned.
+const warnFraction = 0.8
+
+// Price is the cost of a model in USD per million tokens.
+type Price struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// prices are published list prices for prompts up to 200k tokens, matched
+// by model name prefix (longest first). Set budget.prices for other models
+// or negotiated rates.
+var prices = []struct {
+	prefix string
+	price  Price
+}{
+	{"gemini-3-pro", Price{Input: 2.00, Output: 12.00}},
+	{"gemini-3-flash", Price{Input: 0.50, Output: 3.00}},
+	{"gemini-2.5-flash-lite", Price{Input: 0.10, Output: 0.40}},
+	{"gemini-2.5-flash", Price{Input: 0.30, Output: 2.50}},
+	{"gemini-2.5-pro", Price{Input: 1.25, Output: 10.00}},
+	{"gemini-2.0-flash-lite", Price{Input: 0.075, Output: 0.30}},
+	{"gemini-2.0-flash", Price{Input: 0.10, Output: 0.40}},
+}
+
+// Config configures the token budget and context compaction (budget in
+// config.yaml). The zero value compacts with the defaults and sets no limit.
+type Config struct {
+	// MaxTokens stops model calls once this many tokens (input plus output)
+	// have been used since kasa started. 0 means no limit.
+	MaxTokens int64 `yaml:"max_tokens"`
+	// DailyLimit stops model calls once the estimated cost of the day, in
+	// USD, reaches it. Usage is kept per day in ~/.kasa/usage.json, so it
+	// counts every kasa run on the machine that day. 0 means no limit.
+	DailyLimit float64 `yaml:"daily_limit"`
+	// CompactAt is the estimated request size in tokens above which older
+	// turns are summarized (default 200000). -1 disables summarization.
+	CompactAt int `yaml:"compact_at"`
+	// KeepTurns is the number of recent user turns always sent verbatim (default 4).
+	KeepTurns int `yaml:"keep_turns"`
+	// ToolResultChars truncates tool results older than the kept turns to
+	// this many characters (default 2000). -1 disables truncation.
+	ToolResultChars int `yaml:"tool_result_chars"`
+	// Price overrides the price of the configured model, agent.model; the
+	// fallback models are priced by Prices or the built-in list.
+	Price *Price `yaml:"price"`
+	// Prices sets the price of models by model name prefix, overriding the
+	// built-in list prices; the longest matching prefix wins.
+	Prices map[string]Price `yaml:"prices"`
+}
+
+// Validate checks for negative or inconsistent settings.
+func (c Config) Validate() error {
+	switch {
+	case c.MaxTokens < 0:
+		return fmt.Errorf("max_tokens must not be negative")
+	case c.DailyLimit < 0:
+		return fmt.Errorf("daily_limit must not be negative")
+	case c.CompactAt < -1:
+		return fmt.Errorf("compact_at must be positive, or -1 to disable summarization")
+	case c.KeepTurns < 0:
+		return fmt.Errorf("keep_turns must not be negative")
+	case c.ToolResultChars < -1:
+		return fmt.Errorf("tool_result_chars must be positive, or -1 to disable truncation")
+	case c.Price != nil && (c.Price.Input < 0 || c.Price.Output < 0):
+		return fmt.Errorf("price must not be negative")
+	}
+	for prefix, p := range c.Prices {
+		if p.Input < 0 || p.Output < 0 {
+			return fmt.Errorf("prices.%s must not be negative", prefix)
+		}
+	}
+	return nil
+}
+
+func (c Config) compactAt() int {
+	if c.CompactAt == 0 {
+		return DefaultCompactAt
+	}
+	return c.CompactAt
+}
+
+func (c Config) keepTurns() int {
+	if c.KeepTurns == 0 {
+		return DefaultKeepTurns
+	}
+	return c.KeepTurns
+}
+
+func (c Config) toolResultChars() int {
+	if c.ToolResultChars == 0 {
+		return DefaultToolResultChars
+	}
+	return c.ToolResultChars
+}
+
+// PriceFor returns the price of the configured model: budget.price, else
+// ListedPriceFor. It returns false if the price is unknown.
+func (c Config) PriceFor(modelName string) (Price, bool) {
+	if c.Price != nil {
+		return *c.Price, true
+	}
+	return c.ListedPriceFor(modelName)
+}
+
+// ListedPriceFor returns the price of any model, such as a fallback: the
+// longest matching prefix in budget.prices, else the built-in list price.
+// It returns false if the price is unknown.
+func (c Config) ListedPriceFor(modelName string) (Price, bool) {
+	best := ""
+	for prefix := range c.Prices {
+		if strings.HasPrefix(modelName, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best != "" {
+		return c.Prices[best], true
+	}
+	for _, p := range prices {
+		if strings.HasPrefix(modelName, p.prefix) {
+			return p.price, true
+		}
+	}
+	return Price{}, false
+}
+
+// Usage is a snapshot of the tokens used since kasa started, or in one
+// session.
+type Usage struct {
+	ModelCalls   int
+	InputTokens  int64 // prompt tokens, including cached ones
+	CachedTokens int64
+	OutputTokens int64 // response and thinking tokens
+	// Cost is estimated in USD at the price of the model that answered
+	// each call, and 0 for models without a known price.
+	Cost        float64
+	Compactions int // requests sent with older turns summarized
+	Summaries   int // summaries written by the model
+}
+
+// Total returns input plus output tokens.
+func (u Usage) Total() int64 {
+	return u.InputTokens + u.OutputTokens
+}
+
+func (u *Usage) add(call Usage) {
+	u.ModelCalls += call.ModelCalls
+	u.InputTokens += call.InputTokens
+	u.CachedTokens += call.CachedTokens
+	u.OutputTokens += call.OutputTokens
+	u.Cost += call.Cost
+}
+
+// Tracker accumulates token usage since kasa started, per session and per
+// day. It is safe for concurrent use, so one Tracker can be shared by all
+// sessions of 'kasa serve'.
+type Tracker struct {
+	cfg   Config
+	model string
+	price *Price
+	now   func() time.Time
+
+	mu        sync.Mutex
+	usage     Usage
+	sessions  map[string]Usage
+	days      map[string]DayUsage
+	path      string // daily usage file, "" to count days in memory only
+	warned    bool
+	warnedDay string // day the daily limit warning was given
+}
+
+// NewTracker returns a Tracker for the given model, the first of the
+// configured models.
+func NewTracker(cfg Config, modelName string) *Tracker {
+	t := &Tracker{
+		cfg:      cfg,
+		model:    modelName,
+		now:      time.Now,
+		sessions: make(map[string]Usage),
+		days:     make(map[string]DayUsage),
+	}
+	if price, ok := cfg.PriceFor(modelName); ok {
+		t.price = &price
+	}
+	return t
+}
+
+// LoadDaily reads the daily usage kept at path (see UsagePath), and adds to
+// it from then on. Without it, days are only counted in memory.
+func (t *Tracker) LoadDaily(path string) error {
+	days, err := loadDays(path)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.days = days
+	t.path = path
+	return nil
+}
+
+// Record adds the usage reported with a model response of a session,
+// priced by modelName, the model that answered ("" for the tracker's). Nil
+// metadata (e.g. partial streaming responses) is ignored.
+func (t *Tracker) Record(sessionID, modelName string, md *genai.GenerateContentResponseUsageMetadata) {
+	if md == nil {
+		return
+	}
+	call := Usage{
+		ModelCalls:   1,
+		InputTokens:  int64(md.PromptTokenCount),
+		CachedTokens: int64(md.CachedContentTokenCount),
+		OutputTokens: int64(md.CandidatesTokenCount) + int64(md.ThoughtsTokenCount),
+	}
+	if price := t.priceOf(modelName); price != nil {
+		call.Cost = (float64(call.InputTokens)*price.Input + float64(call.OutputTokens)*price.Output) / 1e6
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage.add(call)
+	session := t.sessions[sessionID]
+	session.add(call)
+	t.sessions[sessionID] = session
+
+	// Other kasa processes add to the same file
+	if t.path != "" {
+		if days, err := loadDays(t.path); err == nil {
+			t.days = days
+		}
+	}
+	today := t.today()
+	t.days[today] = t.days[today].add(call)
+	if t.path != "" {
+		// Usage is best-effort bookkeeping; a failed write must not fail the call
+		_ = saveDays(t.path, t.days, t.now())
+	}
+}
+
+func (t *Tracker) compacted(summarized bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage.Compactions++
+	if summarized {
+		t.usage.Summaries++
+	}
+}
+
+// Usage returns the usage since kasa started.
+func (t *Tracker) Usage() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usage
+}
+
+// Session returns the usage of one session since kasa started.
+func (t *Tracker) Session(sessionID string) Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessions[sessionID]
+}
+
+// Today returns the usage of the current day.
+func (t *Tracker) Today() DayUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.days[t.today()]
+}
+
+func (t *Tracker) today() string {
+	return t.now().Format(dayFormat)
+}
+
+// Reset clears the usage since kasa started and of the sessions, which also
+// lifts an exhausted token budget. The daily usage stays, so the daily limit
+// can't be reset.
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage = Usage{}
+	t.sessions = make(map[string]Usage)
+	t.warned = false
+}
+
+// Exceeded reports whether the token budget or the daily limit is used up.
+func (t *Tracker) Exceeded() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tokensExceeded() || t.dailyExceeded()
+}
+
+func (t *Tracker) tokensExceeded() bool {
+	return t.cfg.MaxTokens > 0 && t.usage.Total() >= t.cfg.MaxTokens
+}
+
+func (t *Tracker) dailyExceeded() bool {
+	return t.cfg.DailyLimit > 0 && t.days[t.today()].Cost >= t.cfg.DailyLimit
+}
+
+// BudgetWarning returns a warning the first time usage passes 80% of the
+// token budget, or of the daily limit that day, and "" otherwise.
+func (t *Tracker) BudgetWarning() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cfg.MaxTokens > 0 && !t.warned && float64(t.usage.Total()) >= warnFraction*float64(t.cfg.MaxTokens) {
+		t.warned = true
+		return fmt.Sprintf("Warning: %s of the %s token budget used. Type /usage for details.",
+			formatCount(t.usage.Total()), formatCount(t.cfg.MaxTokens))
+	}
+	today := t.today()
+	if cost := t.days[today].Cost; t.cfg.DailyLimit > 0 && t.warnedDay != today && cost >= warnFraction*t.cfg.DailyLimit {
+		t.warnedDay = today
+		return fmt.Sprintf("Warning: $%.2f of the $%.2f daily limit used today. Type /usage for details.", cost, t.cfg.DailyLimit)
+	}
+	return ""
+}
+
+// ExceededMessage tells the user why model calls stopped.
+func (t *Tracker) ExceededMessage() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.dailyExceeded() {
+		return fmt.Sprintf("The daily limit of $%.2f is used up ($%.2f estimated today), so no further model calls are made until tomorrow. "+
+			"Type /usage for details, or raise budget.daily_limit in config.yaml.",
+			t.cfg.DailyLimit, t.days[t.today()].Cost)
+	}
+	return fmt.Sprintf("The token budget of %s tokens is used up, so no further model calls are made. "+
+		"Type /usage for details and '/usage reset' to start counting again, or raise budget.max_tokens in config.yaml.",
+		formatCount(t.cfg.MaxTokens))
+}
+
+// Cost returns the estimated cost of the usage in USD, and false if the
+// model's price is unknown.
+func (t *Tracker) Cost(u Usage) (float64, bool) {
+	if t.price == nil {
+		return 0, false
+	}
+	return u.Cost, true
+}
+
+// priceOf returns the price of the model that answered a call, or nil if
+// it is unknown.
+func (t *Tracker) priceOf(modelName string) *Price {
+	if modelName == "" || modelName == t.model {
+		return t.price
+	}
+	if price, ok := t.cfg.ListedPriceFor(modelName); ok {
+		return &price
+	}
+	return nil
+}
+
+// FormatUsage renders the usage for the /usage command.
+func (t *Tracker) FormatUsage() string {
+	u := t.Usage()
+	var b strings.Builder
+	b.WriteString("Token usage since kasa started:\n")
+	fmt.Fprintf(&b, "  Model calls:    %d\n", u.ModelCalls)
+	fmt.Fprintf(&b, "  Input tokens:   %s", formatCount(u.InputTokens))
+	if u.CachedTokens > 0 {
+		fmt.Fprintf(&b, " (%s cached)", formatCount(u.CachedTokens))
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "  Output tokens:  %s\n", formatCount(u.OutputTokens))
+	fmt.Fprintf(&b, "  Total:          %s", formatCount(u.Total()))
+	if t.cfg.MaxTokens > 0 {
+		fmt.Fprintf(&b, " of %s budget (%.0f%%)", formatCount(t.cfg.MaxTokens), 100*float64(u.Total())/float64(t.cfg.MaxTokens))
+	}
+	b.WriteString("\n")
+	if cost, ok := t.Cost(u); ok {
+		fmt.Fprintf(&b, "  Estimated cost: $%.4f (%s at $%.3g input / $%.3g output per 1M tokens)\n", cost, t.model, t.price.Input, t.price.Output)
+	} else {
+		fmt.Fprintf(&b, "  Estimated cost: unknown (no price for %s; set budget.prices)\n", t.model)
+	}
+	if u.Compactions > 0 {
+		fmt.Fprintf(&b, "  Compacted:      %d requests, %d summaries of older turns\n", u.Compactions, u.Summaries)
+	}
+
+	t.mu.Lock()
+	today, month := t.days[t.today()], t.since(t.now().AddDate(0, 0, -29))
+	t.mu.Unlock()
+	b.WriteString("\nAll kasa runs on this machine:\n")
+	fmt.Fprintf(&b, "  Today:          %s tokens in %d model calls", formatCount(today.Total()), today.ModelCalls)
+	if t.price != nil {
+		fmt.Fprintf(&b, ", $%.4f", today.Cost)
+	}
+	if t.cfg.DailyLimit > 0 {
+		fmt.Fprintf(&b, " of $%.2f daily limit (%.0f%%)", t.cfg.DailyLimit, 100*today.Cost/t.cfg.DailyLimit)
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "  Last 30 days:   %s tokens in %d model calls", formatCount(month.Total()), month.ModelCalls)
+	if t.price != nil {
+		fmt.Fprintf(&b, ", $%.2f", month.Cost)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// formatCount formats n with thousands separators.
+func formatCount(n int64) string {
+	s := fmt.Sprint(n)
+	if n < 0 {
+		return s
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
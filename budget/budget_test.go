@@ -0,0 +1,234 @@
+package budget
+
+import (
+	"context"
+	"iter"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker(Config{MaxTokens: 10_000}, "gemini-2.5-flash")
+	tr.Record("s1", "", &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 6_000, CandidatesTokenCount: 1_000, ThoughtsTokenCount: 500})
+	tr.Record("s1", "", nil)
+
+	u := tr.Usage()
+	if u.ModelCalls != 1 || u.InputTokens != 6_000 || u.OutputTokens != 1_500 {
+		t.Fatalf("usage = %+v", u)
+	}
+	if cost, ok := tr.Cost(u); !ok || cost < 0.00554 || cost > 0.00556 {
+		t.Errorf("cost = %v, %v; want $0.00555", cost, ok)
+	}
+	if tr.Exceeded() || tr.BudgetWarning() != "" {
+		t.Error("budget reported at 75% usage")
+	}
+
+	tr.Record("s1", "", &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 1_000})
+	if w := tr.BudgetWarning(); !strings.Contains(w, "8,500 of the 10,000") {
+		t.Errorf("warning = %q", w)
+	}
+	if tr.BudgetWarning() != "" {
+		t.Error("warning repeated")
+	}
+	tr.Record("s1", "", &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 2_000})
+	if !tr.Exceeded() {
+		t.Error("budget not exceeded at 10,500 tokens")
+	}
+	if out := tr.FormatUsage(); !strings.Contains(out, "10,500 of 10,000 budget (105%)") || !strings.Contains(out, "Estimated cost: $0.") {
+		t.Errorf("FormatUsage:\n%s", out)
+	}
+
+	tr.Reset()
+	if tr.Exceeded() || tr.Usage().ModelCalls != 0 {
+		t.Error("Reset didn't clear usage")
+	}
+
+	if out := NewTracker(Config{}, "some-local-model").FormatUsage(); !strings.Contains(out, "unknown (no price for some-local-model; set budget.prices") {
+		t.Errorf("unknown model:\n%s", out)
+	}
+}
+
+func TestTracker_FallbackPrice(t *testing.T) {
+	cfg := Config{
+		DailyLimit: 10,
+		Price:      &Price{Input: 1, Output: 1},
+		Prices:     map[string]Price{"big-model": {Input: 10, Output: 100}},
+	}
+	tr := NewTracker(cfg, "small-model")
+	tr.now = func() time.Time { return time.Date(2026, 3, 2, 10, 0, 0, 0, time.Local) }
+	md := &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 100_000, CandidatesTokenCount: 10_000}
+	tr.Record("s1", "small-model", md)
+	tr.Record("s1", "big-model", md)
+	tr.Record("s1", "unpriced-model", md)
+
+	// $0.11 for the first model, $2.00 for big-model, and nothing for the
+	// model without a price
+	if cost, ok := tr.Cost(tr.Session("s1")); !ok || cost < 2.109 || cost > 2.111 {
+		t.Errorf("session cost = %v, %v; want $2.11", cost, ok)
+	}
+	if cost := tr.Today().Cost; cost < 2.109 || cost > 2.111 {
+		t.Errorf("today's cost = %v; want $2.11", cost)
+	}
+}
+
+func TestDailyLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	day := time.Date(2026, 3, 2, 10, 0, 0, 0, time.Local)
+	newTracker := func() *Tracker {
+		tr := NewTracker(Config{DailyLimit: 1, Price: &Price{Input: 1, Output: 10}}, "gemini-2.5-flash")
+		tr.now = func() time.Time { return day }
+		if err := tr.LoadDaily(path); err != nil {
+			t.Fatal(err)
+		}
+		return tr
+	}
+
+	tr := newTracker()
+	tr.Record("a", "", &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 500_000})
+	tr.Record("b", "", &genai.GenerateContentResponseUsageMetadata{CandidatesTokenCount: 40_000})
+	if u := tr.Session("a"); u.ModelCalls != 1 || u.InputTokens != 500_000 {
+		t.Errorf("session a = %+v", u)
+	}
+	if w := tr.BudgetWarning(); !strings.Contains(w, "$0.90 of the $1.00 daily limit") {
+		t.Errorf("warning = %q", w)
+	}
+
+	// A second run the same day continues from the file, and /usage reset
+	// doesn't lift the daily limit
+	tr = newTracker()
+	if tr.Exceeded() {
+		t.Fatal("daily limit exceeded at $0.90")
+	}
+	tr.Record("c", "", &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 100_000})
+	tr.Reset()
+	if !tr.Exceeded() || !strings.Contains(tr.ExceededMessage(), "until tomorrow") {
+		t.Errorf("daily limit not exceeded at $%.2f", tr.Today().Cost)
+	}
+	if out := tr.FormatUsage(); !strings.Contains(out, "Today:          640,000 tokens in 3 model calls, $1.0000 of $1.00 daily limit (100%)") {
+		t.Errorf("FormatUsage:\n%s", out)
+	}
+
+	day = day.AddDate(0, 0, 1)
+	if tr.Exceeded() {
+		t.Error("daily limit still exceeded the next day")
+	}
+	if out := tr.FormatUsage(); !strings.Contains(out, "Last 30 days:   640,000 tokens in 3 model calls, $1.00") {
+		t.Errorf("FormatUsage:\n%s", out)
+	}
+}
+
+func TestPriceFor(t *testing.T) {
+	cfg := Config{Prices: map[string]Price{"gemini-2.5": {Input: 1}, "gemini-2.5-flash": {Input: 2}, "llama": {Input: 3}}}
+	for model, want := range map[string]float64{"gemini-2.5-flash-lite": 2, "gemini-2.5-pro": 1, "llama-3": 3, "gemini-2.0-flash": 0.10} {
+		if p, ok := cfg.PriceFor(model); !ok || p.Input != want {
+			t.Errorf("%s: price %v, %v; want input %v", model, p, ok, want)
+		}
+	}
+	if _, ok := cfg.PriceFor("mistral"); ok {
+		t.Error("expected no price for mistral")
+	}
+}
+
+// fakeLLM answers every request with a fixed summary.
+type fakeLLM struct {
+	calls   int
+	prompts []string
+}
+
+func (f *fakeLLM) Name() string { return "fake" }
+
+func (f *fakeLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	f.calls++
+	f.prompts = append(f.prompts, req.Contents[0].Parts[0].Text)
+	return func(yield func(*model.LLMResponse, error) bool) {
+		yield(&model.LLMResponse{
+			Content:       genai.NewContentFromText("- user deployed web to shop", genai.RoleModel),
+			UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 100, CandidatesTokenCount: 10},
+		}, nil)
+	}
+}
+
+// conversation returns n turns, each a user message, a tool call, a large
+// tool result and a model answer.
+func conversation(n int) []*genai.Content {
+	var contents []*genai.Content
+	for i := range n {
+		contents = append(contents,
+			genai.NewContentFromText("question "+string(rune('a'+i)), genai.RoleUser),
+			genai.NewContentFromFunctionCall("list_pods", map[string]any{"namespace": "shop"}, genai.RoleModel),
+			genai.NewContentFromFunctionResponse("list_pods", map[string]any{"output": strings.Repeat("x", 5_000)}, genai.RoleUser),
+			genai.NewContentFromText("answer", genai.RoleModel),
+		)
+	}
+	return contents
+}
+
+func TestCompactTruncatesOldToolResults(t *testing.T) {
+	contents := conversation(3)
+	c := NewCompactor(Config{KeepTurns: 1, CompactAt: -1}, &fakeLLM{}, nil)
+	req := &model.LLMRequest{Contents: contents}
+	c.Compact(context.Background(), "s", req)
+
+	if len(req.Contents) != 12 {
+		t.Fatalf("got %d contents, want 12", len(req.Contents))
+	}
+	for i, want := range []bool{true, true, false} {
+		resp := req.Contents[4*i+2].Parts[0].FunctionResponse.Response
+		if _, truncated := resp["truncated_result"]; truncated != want {
+			t.Errorf("turn %d: truncated = %v, want %v", i, truncated, want)
+		}
+	}
+	if _, ok := contents[2].Parts[0].FunctionResponse.Response["output"]; !ok {
+		t.Error("session contents were modified")
+	}
+}
+
+func TestCompactSummarizesOlderTurns(t *testing.T) {
+	llm := &fakeLLM{}
+	tr := NewTracker(Config{}, "gemini-2.5-flash")
+	c := NewCompactor(Config{KeepTurns: 2, CompactAt: 1_000, ToolResultChars: -1}, llm, tr)
+
+	req := &model.LLMRequest{Contents: conversation(4)}
+	c.Compact(context.Background(), "s", req)
+	if llm.calls != 1 {
+		t.Fatalf("summary calls = %d, want 1", llm.calls)
+	}
+	if len(req.Contents) != 8 {
+		t.Fatalf("got %d contents, want the last 2 turns", len(req.Contents))
+	}
+	first := req.Contents[0]
+	if first.Role != genai.RoleUser || !strings.Contains(first.Parts[0].Text, "- user deployed web to shop") || first.Parts[1].Text != "question c" {
+		t.Errorf("first content = %+v", first.Parts)
+	}
+	if !strings.Contains(llm.prompts[0], "user: question a") || strings.Contains(llm.prompts[0], "question c") {
+		t.Errorf("summary prompt covers the wrong turns:\n%s", llm.prompts[0])
+	}
+
+	// The next request reuses the summary until it's too large again
+	req = &model.LLMRequest{Contents: conversation(4)[:13]}
+	c.Compact(context.Background(), "s", req)
+	if llm.calls != 1 || len(req.Contents) != 5 {
+		t.Errorf("calls = %d, contents = %d; want cached summary and 5 contents", llm.calls, len(req.Contents))
+	}
+	req = &model.LLMRequest{Contents: conversation(6)}
+	c.Compact(context.Background(), "s", req)
+	if llm.calls != 2 || len(req.Contents) != 8 || !strings.Contains(llm.prompts[1], "- user deployed web to shop") {
+		t.Errorf("calls = %d, contents = %d; want the summary extended", llm.calls, len(req.Contents))
+	}
+
+	if u := tr.Usage(); u.Summaries != 2 || u.Compactions != 3 || u.InputTokens != 200 {
+		t.Errorf("usage = %+v", u)
+	}
+
+	// Other sessions have their own summary
+	req = &model.LLMRequest{Contents: conversation(2)}
+	c.Compact(context.Background(), "other", req)
+	if len(req.Contents) != 8 || strings.Contains(req.Contents[0].Parts[0].Text, "Summary") {
+		t.Errorf("other session was compacted: %+v", req.Contents[0].Parts)
+	}
+}
@@ -0,0 +1,252 @@
+package budget
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/perbu/kasa/fallback"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// charsPerToken is the rough ratio used to estimate request size without
+// calling the token counting API.
+const charsPerToken = 4
+
+// summaryPrompt asks the model to condense the older part of a conversation.
+const summaryPrompt = `Summarize the earlier part of a conversation between a user and Kasa, a Kubernetes deployment assistant, so that the assistant can continue without it.
+Keep: namespaces, resource and manifest names, what the user asked for, plans proposed and whether they were approved or rejected, changes that were applied or failed, problems found and still open, and any preferences the user stated.
+Drop: full tool output, YAML bodies and anything that can be looked up again with a tool.
+Write a concise bullet list, no preamble.`
+
+// summaryState is the summary in use for one session. covered is the number
+// of leading session contents the summary replaces.
+type summaryState struct {
+	covered int
+	summary string
+}
+
+// Compactor shrinks model requests. Summaries are cached per session and
+// extended as the conversation grows, so the model is only asked to
+// summarize when the request passes the threshold again.
+type Compactor struct {
+	cfg     Config
+	llm     model.LLM
+	tracker *Tracker
+
+	mu       sync.Mutex
+	sessions map[string]summaryState
+}
+
+// NewCompactor returns a Compactor that uses llm to write summaries and
+// records their token usage in tracker. tracker may be nil.
+func NewCompactor(cfg Config, llm model.LLM, tracker *Tracker) *Compactor {
+	return &Compactor{
+		cfg:      cfg,
+		llm:      llm,
+		tracker:  tracker,
+		sessions: make(map[string]summaryState),
+	}
+}
+
+// Compact rewrites req.Contents for the given session: tool results older
+// than the kept turns are truncated, and older turns are replaced by a
+// summary once the estimated size exceeds the threshold. The contents of
+// the session are not modified. A failed summary leaves the request
+// uncompacted apart from truncation.
+func (c *Compactor) Compact(ctx context.Context, sessionID string, req *model.LLMRequest) {
+	contents := req.Contents
+	starts := turnStarts(contents)
+	tail := 0
+	if keep := c.cfg.keepTurns(); len(starts) > keep {
+		tail = starts[len(starts)-keep]
+	}
+
+	c.mu.Lock()
+	state := c.sessions[sessionID]
+	c.mu.Unlock()
+	if state.covered > tail {
+		// The history is shorter than when the summary was written
+		state = summaryState{}
+	}
+
+	out := slices.Clone(contents[state.covered:])
+	if limit := c.cfg.toolResultChars(); limit > 0 {
+		truncateToolResults(out[:tail-state.covered], limit)
+	}
+
+	limit := c.cfg.compactAt()
+	if limit > 0 && tail > state.covered && c.llm != nil &&
+		estimateTokens(out)+len(state.summary)/charsPerToken > limit {
+		summary, err := c.summarize(ctx, sessionID, state.summary, out[:tail-state.covered])
+		if err == nil {
+			state = summaryState{covered: tail, summary: summary}
+			c.mu.Lock()
+			c.sessions[sessionID] = state
+			c.mu.Unlock()
+			out = slices.Clone(contents[tail:])
+			if c.tracker != nil {
+				c.tracker.compacted(true)
+			}
+		}
+	} else if state.summary != "" && c.tracker != nil {
+		c.tracker.compacted(false)
+	}
+
+	if state.summary != "" && len(out) > 0 {
+		out[0] = withSummary(out[0], state.summary)
+	}
+	req.Contents = out
+}
+
+// summarize asks the model to fold contents into the previous summary.
+func (c *Compactor) summarize(ctx context.Context, sessionID, previous string, contents []*genai.Content) (string, error) {
+	var b strings.Builder
+	b.WriteString(summaryPrompt)
+	if previous != "" {
+		b.WriteString("\n\nSummary of the conversation before that:\n")
+		b.WriteString(previous)
+	}
+	b.WriteString("\n\nConversation:\n")
+	b.WriteString(transcript(contents))
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText(b.String(), genai.RoleUser)},
+		Config:   &genai.GenerateContentConfig{},
+	}
+	var text strings.Builder
+	for resp, err := range c.llm.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return "", fmt.Errorf("summarizing conversation: %w", err)
+		}
+		if c.tracker != nil {
+			c.tracker.Record(sessionID, fallback.Model(resp.CustomMetadata), resp.UsageMetadata)
+		}
+		if resp.Content != nil {
+			for _, p := range resp.Content.Parts {
+				text.WriteString(p.Text)
+			}
+		}
+	}
+	summary := strings.TrimSpace(text.String())
+	if summary == "" {
+		return "", fmt.Errorf("summarizing conversation: empty response")
+	}
+	return summary, nil
+}
+
+// turnStarts returns the indexes of contents where the user typed a message,
+// as opposed to user-role contents carrying tool results. Cutting the
+// history there never separates a function call from its response.
+func turnStarts(contents []*genai.Content) []int {
+	var starts []int
+	for i, c := range contents {
+		if c == nil || c.Role != genai.RoleUser {
+			continue
+		}
+		hasText, hasResponse := false, false
+		for _, p := range c.Parts {
+			hasText = hasText || p.Text != ""
+			hasResponse = hasResponse || p.FunctionResponse != nil
+		}
+		if hasText && !hasResponse {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+// truncateToolResults replaces function responses longer than limit
+// characters of JSON with a truncated copy. Contents and parts are copied
+// before they are changed since they belong to the session history.
+func truncateToolResults(contents []*genai.Content, limit int) {
+	for i, c := range contents {
+		if c == nil {
+			continue
+		}
+		var parts []*genai.Part
+		for j, p := range c.Parts {
+			if p.FunctionResponse == nil {
+				continue
+			}
+			data, _ := json.Marshal(p.FunctionResponse.Response)
+			if len(data) <= limit {
+				continue
+			}
+			if parts == nil {
+				parts = slices.Clone(c.Parts)
+			}
+			parts[j] = &genai.Part{FunctionResponse: &genai.FunctionResponse{
+				ID:   p.FunctionResponse.ID,
+				Name: p.FunctionResponse.Name,
+				Response: map[string]any{
+					"truncated_result": string(data[:limit]),
+					"note":             fmt.Sprintf("Older result truncated from %d characters to save context; call the tool again if you need it.", len(data)),
+				},
+			}}
+		}
+		if parts != nil {
+			contents[i] = &genai.Content{Role: c.Role, Parts: parts}
+		}
+	}
+}
+
+// withSummary prepends the summary to the first kept content.
+func withSummary(c *genai.Content, summary string) *genai.Content {
+	text := "[Summary of the earlier conversation, which is no longer shown]\n" + summary + "\n[End of summary]"
+	parts := append([]*genai.Part{genai.NewPartFromText(text)}, c.Parts...)
+	return &genai.Content{Role: c.Role, Parts: parts}
+}
+
+// estimateTokens estimates the size of contents from their character count.
+func estimateTokens(contents []*genai.Content) int {
+	chars := 0
+	for _, c := range contents {
+		if c == nil {
+			continue
+		}
+		for _, p := range c.Parts {
+			chars += len(p.Text)
+			if p.FunctionCall != nil {
+				data, _ := json.Marshal(p.FunctionCall.Args)
+				chars += len(p.FunctionCall.Name) + len(data)
+			}
+			if p.FunctionResponse != nil {
+				data, _ := json.Marshal(p.FunctionResponse.Response)
+				chars += len(p.FunctionResponse.Name) + len(data)
+			}
+		}
+	}
+	return chars / charsPerToken
+}
+
+// transcript renders contents as plain text for the summary prompt.
+func transcript(contents []*genai.Content) string {
+	var b strings.Builder
+	for _, c := range contents {
+		if c == nil {
+			continue
+		}
+		for _, p := range c.Parts {
+			switch {
+			case p.Thought:
+			case p.Text != "":
+				fmt.Fprintf(&b, "%s: %s\n", c.Role, p.Text)
+			case p.FunctionCall != nil:
+				args, _ := json.Marshal(p.FunctionCall.Args)
+				fmt.Fprintf(&b, "%s called %s(%s)\n", c.Role, p.FunctionCall.Name, args)
+			case p.FunctionResponse != nil:
+				data, _ := json.Marshal(p.FunctionResponse.Response)
+				if len(data) > DefaultToolResultChars {
+					data = append(data[:DefaultToolResultChars], "..."...)
+				}
+				fmt.Fprintf(&b, "%s returned: %s\n", p.FunctionResponse.Name, data)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,120 @@
+package budget
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// dayFormat is the key of a day in the usage file, in local time.
+const dayFormat = "2006-01-02"
+
+// keepDays is how many days of usage the usage file keeps.
+const keepDays = 90
+
+// DayUsage is the usage of one day, summed over every kasa run on the
+// machine.
+type DayUsage struct {
+	ModelCalls   int   `json:"model_calls"`
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	// Cost is estimated at the price when the tokens were used, and 0 for
+	// models without a known price.
+	Cost float64 `json:"cost_usd"`
+}
+
+// Total returns input plus output tokens.
+func (d DayUsage) Total() int64 {
+	return d.InputTokens + d.OutputTokens
+}
+
+func (d DayUsage) add(u Usage) DayUsage {
+	d.ModelCalls += u.ModelCalls
+	d.InputTokens += u.InputTokens
+	d.OutputTokens += u.OutputTokens
+	d.Cost += u.Cost
+	return d
+}
+
+// since sums the days from the day of from until today. Callers hold t.mu.
+func (t *Tracker) since(from time.Time) DayUsage {
+	first := from.Format(dayFormat)
+	var sum DayUsage
+	for day, u := range t.days {
+		// Keys sort like the dates they hold
+		if day >= first {
+			sum = sum.add(Usage{ModelCalls: u.ModelCalls, InputTokens: u.InputTokens, OutputTokens: u.OutputTokens, Cost: u.Cost})
+		}
+	}
+	return sum
+}
+
+// UsagePath returns the location of the daily usage file
+// (~/.kasa/usage.json), or "" if there is no home directory.
+func UsagePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kasa", "usage.json")
+}
+
+// usageFile is the content of the daily usage file.
+type usageFile struct {
+	Days map[string]DayUsage `json:"days"`
+}
+
+// loadDays reads the daily usage file. A missing file yields no days.
+func loadDays(path string) (map[string]DayUsage, error) {
+	days := make(map[string]DayUsage)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return days, nil
+		}
+		return nil, err
+	}
+	var f usageFile
+	if err := json.Unmarshal(content, &f); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for day, u := range f.Days {
+		days[day] = u
+	}
+	return days, nil
+}
+
+// saveDays writes the daily usage file, dropping days older than keepDays.
+// It writes a temporary file and renames it, so concurrent kasa processes
+// never read a partial file.
+func saveDays(path string, days map[string]DayUsage, now time.Time) error {
+	oldest := now.AddDate(0, 0, -keepDays).Format(dayFormat)
+	for day := range days {
+		if day < oldest {
+			delete(days, day)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(usageFile{Days: days}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".usage-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
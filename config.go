@@ -1,10 +1,31 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/perbu/kasa/budget"
+	"github.com/perbu/kasa/fallback"
+	"github.com/perbu/kasa/integrations/slack"
+	"github.com/perbu/kasa/metrics"
+	"github.com/perbu/kasa/openai"
+	"github.com/perbu/kasa/repl"
+	"github.com/perbu/kasa/telemetry"
+	"github.com/perbu/kasa/tools"
+	"github.com/perbu/kasa/tracing"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/model/gemini"
+	"google.golang.org/genai"
 	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/util/homedir"
 )
 
 // Config represents the application configuration.
@@ -12,31 +33,371 @@ type Config struct {
 	Kubernetes struct {
 		Kubeconfig string `yaml:"kubeconfig"`
 		Context    string `yaml:"context"`
+		// As and AsGroups impersonate another user or ServiceAccount
+		// (system:serviceaccount:<namespace>:<name>) and groups, like
+		// kubectl --as and --as-group, so RBAC limits what kasa can do.
+		As       string   `yaml:"as"`
+		AsGroups []string `yaml:"as_groups"`
+		// API sets the client-side rate limit and retries (qps, burst,
+		// max_retries).
+		API tools.APIOptions `yaml:",inline"`
+		// Namespaces confines every tool to the allowed_namespaces and
+		// away from the denied_namespaces (glob patterns).
+		Namespaces tools.NamespacePolicy `yaml:",inline"`
 	} `yaml:"kubernetes"`
 	Agent struct {
-		Model string `yaml:"model"`
-		Name  string `yaml:"name"`
+		// ModelConfig is the model and where it runs.
+		ModelConfig `yaml:",inline"`
+		// Fallbacks are models tried in order when a model call keeps
+		// failing with rate limits (429), server errors (5xx), timeouts or
+		// connection failures, e.g. gemini-2.5-pro after gemini-2.5-flash.
+		// Backend settings a fallback leaves unset are the model's.
+		Fallbacks []ModelConfig `yaml:"fallbacks"`
+		// MaxRetries is how often a failing model call is retried with
+		// exponential backoff before the next fallback is tried (default
+		// 2). -1 disables retries.
+		MaxRetries int    `yaml:"max_retries"`
+		Name       string `yaml:"name"`
+		// Mode is the agent that handles messages at startup: auto (the
+		// coordinator picks one per message, the default), deploy, debug or
+		// review; /mode switches. single runs one agent with every tool.
+		Mode string `yaml:"mode"`
+		// MaxToolResultBytes caps the JSON size of a tool result (default
+		// 32768); larger results are pruned and truncated. -1 disables it.
+		MaxToolResultBytes int `yaml:"max_tool_result_bytes"`
+		// ParallelTools is how many read-only tool calls from one model
+		// response run concurrently (default 4); 1 runs them one by one.
+		ParallelTools int `yaml:"parallel_tools"`
 	} `yaml:"agent"`
 	Deployments struct {
 		Directory string `yaml:"directory"`
 		Remote    string `yaml:"remote"`
+		// PinDigests makes create_deployment and set_image deploy images
+		// by digest, recording the tag in the kasa.io/image-digests
+		// annotation.
+		PinDigests bool `yaml:"pin_digests"`
 	} `yaml:"deployments"`
 	Prompts struct {
+		// System is the instruction; see promptPlaceholders for the
+		// variables it may contain.
 		System string `yaml:"system"`
+		// Conventions are the organization's naming and labeling standards,
+		// for {{CONVENTIONS}}.
+		Conventions string `yaml:"conventions"`
+		// Rules are files of house rules for {{HOUSE_RULES}} (default
+		// ~/.kasa/rules.md, if it exists).
+		Rules []string `yaml:"rules"`
+		// Agents replace the role instructions of the specialized agents,
+		// by mode (auto is the coordinator's).
+		Agents map[string]string `yaml:"agents"`
 	} `yaml:"prompts"`
+	Safety struct {
+		// EnforceDryRun forces mutating tools into server-side dry-run for the
+		// listed namespaces/tools until the user confirms a second time.
+		EnforceDryRun tools.DryRunPolicy `yaml:"enforce_dry_run"`
+		// MaintenanceWindows restricts mutating tools to the given weekly
+		// windows in the namespaces they cover.
+		MaintenanceWindows tools.MaintenancePolicy `yaml:"maintenance_windows"`
+		// ProtectedResources lists resources no mutating tool may change,
+		// in addition to those annotated kasa.io/protected=true.
+		ProtectedResources tools.ProtectionPolicy `yaml:"protected_resources"`
+	} `yaml:"safety"`
+	Watch struct {
+		// Interval between drift scans in 'kasa watch' (default 5m).
+		Interval time.Duration `yaml:"interval"`
+		// AutoRemediate re-applies stored manifests when drift is detected.
+		AutoRemediate tools.RemediationPolicy `yaml:"auto_remediate"`
+	} `yaml:"watch"`
+	Sync struct {
+		// Interval between reconcile passes in 'kasa sync' (default 5m).
+		Interval time.Duration `yaml:"interval"`
+		// Namespaces limits reconciliation to these manifest namespaces
+		// (default: all, including _cluster).
+		Namespaces []string `yaml:"namespaces"`
+		// DryRun validates changes with server-side dry-run instead of applying them.
+		DryRun bool `yaml:"dry_run"`
+		// Webhook receives each pass's report as JSON when something changed.
+		Webhook string `yaml:"webhook"`
+		// Hooks are shell commands run with the report as JSON on stdin.
+		Hooks []string `yaml:"hooks"`
+	} `yaml:"sync"`
+	Cache struct {
+		// Disabled sends every read to the API server, like -no-cache.
+		Disabled bool `yaml:"disabled"`
+		// TTL is how long cached pods, deployments, services and events are
+		// trusted before they are listed again (default 5m).
+		TTL time.Duration `yaml:"ttl"`
+		// ResultTTL is how long the result of a read-only tool call is
+		// reused for an identical call in the same session (default 30s).
+		// A negative value turns this off.
+		ResultTTL time.Duration `yaml:"result_ttl"`
+	} `yaml:"cache"`
+	// Policies are the admission policies (Kyverno or rego) manifests are
+	// checked against before they are applied.
+	Policies tools.PolicyConfig `yaml:"policies"`
+	// Alerts ring the bell, and optionally notify the desktop, when a long
+	// tool call finishes while the terminal is in the background.
+	Alerts repl.AlertConfig `yaml:"alerts"`
+	// Budget limits token usage and compacts long conversations.
+	Budget budget.Config `yaml:"budget"`
+	// Telemetry is opt-in anonymous usage statistics (off by default).
+	Telemetry telemetry.Config `yaml:"telemetry"`
+	// Tracing exports OpenTelemetry spans of agent runs over OTLP (off by
+	// default).
+	Tracing tracing.Config `yaml:"tracing"`
+	// Metrics exposes Prometheus metrics on /metrics in serve and sync
+	// modes (off by default).
+	Metrics      metrics.Config `yaml:"metrics"`
+	Integrations struct {
+		Slack      slack.Config `yaml:"slack"`
+		Prometheus struct {
+			URL         string `yaml:"url"`
+			BearerToken string `yaml:"bearer_token"`
+		} `yaml:"prometheus"`
+		// Trivy scans images for inspect_image. Server is a Trivy server
+		// the trivy binary (default "trivy" on PATH) runs as a client of;
+		// without one, trivy downloads its own vulnerability database.
+		Trivy struct {
+			Server string `yaml:"server"`
+			Binary string `yaml:"binary"`
+		} `yaml:"trivy"`
+	} `yaml:"integrations"`
+	// CustomTools are team-specific tools backed by a shell command or an
+	// HTTP endpoint.
+	CustomTools []tools.CustomToolConfig `yaml:"custom_tools"`
+	// Profiles are named partial configurations, selected with -profile,
+	// whose settings override the ones above.
+	Profiles map[string]yaml.Node `yaml:"profiles"`
 }
 
-// loadConfig loads the configuration from a YAML file.
-func loadConfig(path string) (*Config, error) {
+// ModelConfig selects a model and the backend serving it.
+type ModelConfig struct {
+	Model string `yaml:"model"`
+	// Backend is the API serving the model: gemini (the Gemini API,
+	// with GOOGLE_API_KEY; the default), vertex (Vertex AI, with
+	// Application Default Credentials) or openai (a server with an
+	// OpenAI-compatible API, such as a local llama.cpp or vLLM).
+	Backend string `yaml:"backend"`
+	Vertex  struct {
+		// Project and Location default to GOOGLE_CLOUD_PROJECT and
+		// GOOGLE_CLOUD_LOCATION. The location picks the regional
+		// endpoint, e.g. europe-west4; global uses the global one.
+		Project  string `yaml:"project"`
+		Location string `yaml:"location"`
+		// Endpoint replaces the endpoint the location picks, e.g. with
+		// a Private Service Connect address inside a VPC-SC perimeter.
+		Endpoint string `yaml:"endpoint"`
+	} `yaml:"vertex"`
+	OpenAI struct {
+		// BaseURL is the API root, e.g. http://localhost:8080/v1.
+		BaseURL string `yaml:"base_url"`
+		// APIKey is sent as a bearer token, for servers that need one.
+		APIKey string `yaml:"api_key"`
+		// Timeout bounds one model call (default 10m).
+		Timeout time.Duration `yaml:"timeout"`
+		// FullTools sends the full tool descriptions and documentation
+		// instead of the compact ones, for models with a large context.
+		FullTools bool `yaml:"full_tools"`
+	} `yaml:"openai"`
+}
+
+// findConfig returns the config file to load: path if set (from -config or
+// $KASA_CONFIG), else ./config.yaml, else the user's config file.
+func findConfig(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	candidates := []string{"config.yaml"}
+	if user := userConfigPath(); user != "" {
+		candidates = append(candidates, user)
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no config file found (looked for %s); run 'kasa init' to create one", strings.Join(candidates, ", "))
+}
+
+// userConfigPath returns $XDG_CONFIG_HOME/kasa/config.yaml, which defaults
+// to ~/.config/kasa/config.yaml, or "" without a home directory.
+func userConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home := homedir.HomeDir()
+		if home == "" {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "kasa", "config.yaml")
+}
+
+// envReference matches ${NAME} and ${NAME:-default} in the config file.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// expandEnv replaces ${NAME} with the value of the environment variable
+// NAME, and ${NAME:-default} with default if NAME is unset or empty. Other
+// uses of $ are left alone, so prompts can contain them.
+func expandEnv(data []byte) []byte {
+	return envReference.ReplaceAllFunc(data, func(ref []byte) []byte {
+		m := envReference.FindSubmatch(ref)
+		if value := os.Getenv(string(m[1])); value != "" {
+			return []byte(value)
+		}
+		return m[2]
+	})
+}
+
+// loadConfig loads the configuration from a YAML file, with the settings of
+// the named profile, if any, applied on top. A relative deployments
+// directory is relative to the config file.
+func loadConfig(path, profile string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("reading config file: %w", err)
 	}
+	data = expandEnv(data)
 
 	var cfg Config
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("parsing config file: %w", err)
 	}
 
+	if profile != "" {
+		node, ok := cfg.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q (available: %s)", profile, profileNames(cfg.Profiles))
+		}
+		// Decoding into the loaded config only replaces the settings the
+		// profile sets; lists are replaced as a whole.
+		if err := node.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("parsing profile %q: %w", profile, err)
+		}
+	}
+
+	if dir := cfg.Deployments.Directory; dir != "" && !strings.HasPrefix(dir, "~") && !filepath.IsAbs(dir) {
+		cfg.Deployments.Directory = filepath.Join(filepath.Dir(path), dir)
+	}
+
 	return &cfg, nil
 }
+
+// profileNames lists the names of the profiles for error messages.
+func profileNames(profiles map[string]yaml.Node) string {
+	if len(profiles) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// agentModeSingle runs one agent with every tool instead of the specialized
+// agents.
+const agentModeSingle = "single"
+
+// Model backends for agent.backend.
+const (
+	backendGemini = "gemini"
+	backendVertex = "vertex"
+	backendOpenAI = "openai"
+)
+
+// compactTools reports whether the model gets the compact tool
+// documentation and schemas: local models have small context windows.
+func (m ModelConfig) compactTools() bool {
+	return m.Backend == backendOpenAI && !m.OpenAI.FullTools
+}
+
+// withDefaults returns the fallback m with the backend settings it leaves
+// unset taken from primary.
+func (m ModelConfig) withDefaults(primary ModelConfig) ModelConfig {
+	if m.Backend == "" {
+		m.Backend = primary.Backend
+	}
+	if m.Vertex == (ModelConfig{}).Vertex {
+		m.Vertex = primary.Vertex
+	}
+	if m.OpenAI == (ModelConfig{}).OpenAI {
+		m.OpenAI = primary.OpenAI
+	}
+	return m
+}
+
+// newModel creates the agent's model, chained with its fallbacks: each is
+// retried on transient provider errors before the next one takes over.
+func (c *Config) newModel(ctx context.Context) (model.LLM, error) {
+	primary, err := c.Agent.newModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	models := []model.LLM{primary}
+	for i, fb := range c.Agent.Fallbacks {
+		llm, err := fb.withDefaults(c.Agent.ModelConfig).newModel(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("agent.fallbacks[%d]: %w", i, err)
+		}
+		models = append(models, llm)
+	}
+	return fallback.New(models, c.Agent.MaxRetries), nil
+}
+
+// newModel creates the model on its backend.
+func (m ModelConfig) newModel(ctx context.Context) (model.LLM, error) {
+	if m.Backend == backendOpenAI {
+		if m.OpenAI.BaseURL == "" {
+			return nil, errors.New("the openai backend needs openai.base_url")
+		}
+		openaiConfig := openai.Config{
+			BaseURL: m.OpenAI.BaseURL,
+			APIKey:  m.OpenAI.APIKey,
+			Timeout: m.OpenAI.Timeout,
+		}
+		if m.compactTools() {
+			openaiConfig.Declaration = tools.CompactDeclaration
+		}
+		return openai.NewModel(m.Model, openaiConfig), nil
+	}
+
+	// The Gemini API takes an API key, Vertex AI a project and location
+	clientConfig, err := m.modelClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return gemini.NewModel(ctx, m.Model, clientConfig)
+}
+
+// modelClientConfig returns the genai client configuration for the model
+// backend. Vertex AI authenticates with Application Default Credentials,
+// which the client looks up itself.
+func (m ModelConfig) modelClientConfig() (*genai.ClientConfig, error) {
+	switch m.Backend {
+	case "", backendGemini:
+		apiKey := os.Getenv("GOOGLE_API_KEY")
+		if apiKey == "" {
+			return nil, errors.New("GOOGLE_API_KEY environment variable not set")
+		}
+		return &genai.ClientConfig{
+			APIKey:  apiKey,
+			Backend: genai.BackendGeminiAPI,
+		}, nil
+	case backendVertex:
+		vertex := m.Vertex
+		project := cmp.Or(vertex.Project, os.Getenv("GOOGLE_CLOUD_PROJECT"))
+		location := cmp.Or(vertex.Location, os.Getenv("GOOGLE_CLOUD_LOCATION"), os.Getenv("GOOGLE_CLOUD_REGION"))
+		if project == "" || location == "" {
+			return nil, errors.New("the vertex backend needs vertex.project and vertex.location (or GOOGLE_CLOUD_PROJECT and GOOGLE_CLOUD_LOCATION)")
+		}
+		return &genai.ClientConfig{
+			Backend:     genai.BackendVertexAI,
+			Project:     project,
+			Location:    location,
+			HTTPOptions: genai.HTTPOptions{BaseURL: vertex.Endpoint},
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown backend %q; use %s, %s or %s", m.Backend, backendGemini, backendVertex, backendOpenAI)
+}
@@ -0,0 +1,541 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/perbu/kasa/agents"
+	"github.com/perbu/kasa/tools"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+)
+
+// ValidationIssue describes a single configuration problem.
+type ValidationIssue struct {
+	Field   string
+	Message string
+	Fatal   bool // fatal issues prevent kasa from starting
+}
+
+func (v ValidationIssue) String() string {
+	level := "warning"
+	if v.Fatal {
+		level = "error"
+	}
+	return fmt.Sprintf("%s: %s: %s", level, v.Field, v.Message)
+}
+
+// providerAPIKeys maps model name prefixes to the environment variable
+// holding the API key for that provider.
+var providerAPIKeys = map[string]string{
+	"gemini-": "GOOGLE_API_KEY",
+}
+
+// validateConfig checks the configuration and environment for problems that
+// would otherwise surface as confusing runtime errors. Without agent, for the
+// subcommands that don't run it, the model and prompt settings aren't checked.
+func validateConfig(cfg *Config, agent bool) []ValidationIssue {
+	var issues []ValidationIssue
+	issues = append(issues, validateAgent(cfg, agent)...)
+	issues = append(issues, validateKubernetes(cfg)...)
+	issues = append(issues, validateDeployments(cfg)...)
+	if agent {
+		issues = append(issues, validatePrompts(cfg)...)
+	}
+	issues = append(issues, validateWatch(cfg)...)
+	issues = append(issues, validateSync(cfg)...)
+	if err := cfg.Safety.MaintenanceWindows.Validate(); err != nil {
+		issues = append(issues, ValidationIssue{Field: "safety.maintenance_windows", Message: err.Error(), Fatal: true})
+	}
+	if err := cfg.Safety.ProtectedResources.Validate(); err != nil {
+		issues = append(issues, ValidationIssue{Field: "safety.protected_resources", Message: err.Error(), Fatal: true})
+	}
+	if cfg.Policies.Path != "" {
+		if policies, err := tools.LoadPolicies(cfg.Policies); err != nil {
+			issues = append(issues, ValidationIssue{Field: "policies.path", Message: err.Error(), Fatal: true})
+		} else {
+			for _, skipped := range policies.Skipped() {
+				issues = append(issues, ValidationIssue{Field: "policies", Message: "not evaluated: " + skipped})
+			}
+		}
+	}
+	if trivy := cfg.Integrations.Trivy; trivy.Server != "" || trivy.Binary != "" {
+		binary := trivy.Binary
+		if binary == "" {
+			binary = "trivy"
+		}
+		if _, err := exec.LookPath(binary); err != nil {
+			issues = append(issues, ValidationIssue{Field: "integrations.trivy.binary", Message: fmt.Sprintf("%s not found; inspect_image can't scan for vulnerabilities", binary)})
+		}
+	}
+	if err := cfg.Kubernetes.API.Validate(); err != nil {
+		issues = append(issues, ValidationIssue{Field: "kubernetes", Message: err.Error(), Fatal: true})
+	}
+	if len(cfg.Kubernetes.AsGroups) > 0 && cfg.Kubernetes.As == "" {
+		issues = append(issues, ValidationIssue{Field: "kubernetes.as_groups", Message: "impersonating groups requires a user in kubernetes.as (or -as)", Fatal: true})
+	}
+	if err := cfg.Kubernetes.Namespaces.Validate(); err != nil {
+		issues = append(issues, ValidationIssue{Field: "kubernetes", Message: err.Error(), Fatal: true})
+	}
+	names := make(map[string]bool)
+	for _, custom := range cfg.CustomTools {
+		if err := custom.Validate(); err != nil {
+			issues = append(issues, ValidationIssue{Field: "custom_tools", Message: err.Error(), Fatal: true})
+		} else if names[custom.Name] {
+			issues = append(issues, ValidationIssue{Field: "custom_tools", Message: fmt.Sprintf("%s: declared more than once", custom.Name), Fatal: true})
+		}
+		names[custom.Name] = true
+	}
+	if err := cfg.Alerts.Validate(); err != nil {
+		issues = append(issues, ValidationIssue{Field: "alerts", Message: err.Error(), Fatal: true})
+	}
+	if err := cfg.Budget.Validate(); err != nil {
+		issues = append(issues, ValidationIssue{Field: "budget", Message: err.Error(), Fatal: true})
+	} else if cfg.Budget.DailyLimit > 0 {
+		// A fallback answering must count toward the limit at its own price
+		if _, ok := cfg.Budget.PriceFor(cfg.Agent.Model); !ok {
+			issues = append(issues, ValidationIssue{Field: "budget", Message: fmt.Sprintf("daily_limit needs a price for %s; set budget.prices", cfg.Agent.Model), Fatal: true})
+		}
+		for i, fb := range cfg.Agent.Fallbacks {
+			name := fb.withDefaults(cfg.Agent.ModelConfig).Model
+			if _, ok := cfg.Budget.ListedPriceFor(name); !ok {
+				issues = append(issues, ValidationIssue{Field: "budget", Message: fmt.Sprintf("daily_limit needs a price for %s (agent.fallbacks[%d]); set budget.prices", name, i), Fatal: true})
+			}
+		}
+	}
+	if err := cfg.Telemetry.Validate(); err != nil {
+		issues = append(issues, ValidationIssue{Field: "telemetry", Message: err.Error(), Fatal: true})
+	}
+	if err := cfg.Tracing.Validate(); err != nil {
+		issues = append(issues, ValidationIssue{Field: "tracing", Message: err.Error(), Fatal: true})
+	}
+	if err := cfg.Metrics.Validate(); err != nil {
+		issues = append(issues, ValidationIssue{Field: "metrics", Message: err.Error(), Fatal: true})
+	}
+	return issues
+}
+
+// hasFatal returns true if any issue prevents startup.
+func hasFatal(issues []ValidationIssue) bool {
+	for _, i := range issues {
+		if i.Fatal {
+			return true
+		}
+	}
+	return false
+}
+
+func validateAgent(cfg *Config, agent bool) []ValidationIssue {
+	var issues []ValidationIssue
+
+	if cfg.Agent.Name == "" {
+		issues = append(issues, ValidationIssue{
+			Field:   "agent.name",
+			Message: "must be set (e.g. \"kasa\")",
+			Fatal:   true,
+		})
+	}
+
+	if n := cfg.Agent.MaxToolResultBytes; n < -1 || (n > 0 && n < 1024) {
+		issues = append(issues, ValidationIssue{
+			Field:   "agent.max_tool_result_bytes",
+			Message: fmt.Sprintf("%d is too small; use at least 1024, 0 for the default or -1 for no limit", n),
+			Fatal:   true,
+		})
+	}
+
+	if cfg.Agent.Mode != "" && cfg.Agent.Mode != agentModeSingle {
+		if _, err := agents.ParseMode(cfg.Agent.Mode); err != nil {
+			issues = append(issues, ValidationIssue{
+				Field:   "agent.mode",
+				Message: fmt.Sprintf("%v, or %s", err, agentModeSingle),
+				Fatal:   true,
+			})
+		}
+	}
+	if agents.Reserved(cfg.Agent.Name) {
+		issues = append(issues, ValidationIssue{
+			Field:   "agent.name",
+			Message: fmt.Sprintf("%q is the name of one of the specialized agents", cfg.Agent.Name),
+			Fatal:   true,
+		})
+	}
+	for name := range cfg.Prompts.Agents {
+		if mode, err := agents.ParseMode(name); err != nil || string(mode) != name {
+			issues = append(issues, ValidationIssue{
+				Field:   "prompts.agents",
+				Message: fmt.Sprintf("unknown mode %q", name),
+				Fatal:   true,
+			})
+		}
+	}
+
+	if cfg.Agent.ParallelTools < 0 {
+		issues = append(issues, ValidationIssue{
+			Field:   "agent.parallel_tools",
+			Message: "must not be negative (0 for the default, 1 to run tools one by one)",
+			Fatal:   true,
+		})
+	}
+
+	if cfg.Cache.TTL < 0 {
+		issues = append(issues, ValidationIssue{
+			Field:   "cache.ttl",
+			Message: "must not be negative (0 for the default of 5m)",
+			Fatal:   true,
+		})
+	}
+
+	if cfg.Agent.MaxRetries < -1 {
+		issues = append(issues, ValidationIssue{
+			Field:   "agent.max_retries",
+			Message: "must be -1 (no retries) or more (0 for the default of 2)",
+			Fatal:   true,
+		})
+	}
+	if !agent {
+		return issues
+	}
+	issues = append(issues, validateModel("agent", cfg.Agent.ModelConfig)...)
+	for i, fb := range cfg.Agent.Fallbacks {
+		issues = append(issues, validateModel(fmt.Sprintf("agent.fallbacks[%d]", i), fb.withDefaults(cfg.Agent.ModelConfig))...)
+	}
+
+	// Offline, with a local model, there is no use for the web search keys
+	if cfg.Agent.Backend == backendOpenAI {
+		return issues
+	}
+
+	if os.Getenv("JINA_READER_API_KEY") == "" {
+		issues = append(issues, ValidationIssue{
+			Field:   "env",
+			Message: "JINA_READER_API_KEY is not set; fetch_url will be unavailable",
+		})
+	}
+	if os.Getenv("TAVILY_API_KEY") == "" {
+		issues = append(issues, ValidationIssue{
+			Field:   "env",
+			Message: "TAVILY_API_KEY is not set; search_web will be unavailable",
+		})
+	}
+
+	return issues
+}
+
+// validateModel checks a model and its backend settings; field is where
+// they are in the config.
+func validateModel(field string, m ModelConfig) []ValidationIssue {
+	var issues []ValidationIssue
+	model := m.Model
+	if model == "" {
+		issues = append(issues, ValidationIssue{
+			Field:   field + ".model",
+			Message: "must be set (e.g. \"gemini-2.5-flash\")",
+			Fatal:   true,
+		})
+		return issues
+	}
+
+	// Local servers serve models under any name and need no API key
+	if m.Backend == backendOpenAI {
+		baseURL := m.OpenAI.BaseURL
+		if u, err := url.Parse(baseURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			issues = append(issues, ValidationIssue{
+				Field:   field + ".openai.base_url",
+				Message: fmt.Sprintf("%q is not a URL (e.g. http://localhost:8080/v1)", baseURL),
+				Fatal:   true,
+			})
+		}
+		if m.OpenAI.Timeout < 0 {
+			issues = append(issues, ValidationIssue{
+				Field:   field + ".openai.timeout",
+				Message: "must not be negative (0 for the default of 10m)",
+				Fatal:   true,
+			})
+		}
+		return issues
+	}
+
+	envVar := ""
+	for prefix, key := range providerAPIKeys {
+		if strings.HasPrefix(model, prefix) {
+			envVar = key
+			break
+		}
+	}
+	if envVar == "" {
+		issues = append(issues, ValidationIssue{
+			Field:   field + ".model",
+			Message: fmt.Sprintf("%q is not a recognized model; only Gemini models (gemini-*) are supported, or any model with backend: openai", model),
+			Fatal:   true,
+		})
+		return issues
+	}
+
+	switch m.Backend {
+	case "", backendGemini:
+		if os.Getenv(envVar) == "" {
+			issues = append(issues, ValidationIssue{
+				Field:   field + ".model",
+				Message: fmt.Sprintf("model %q requires %s; set it in .env, the environment, or with 'kasa auth set %s'", model, envVar, envVar),
+				Fatal:   true,
+			})
+		}
+	case backendVertex:
+		// Vertex AI authenticates with Application Default Credentials,
+		// which are only looked up when the client is created
+		if _, err := m.modelClientConfig(); err != nil {
+			issues = append(issues, ValidationIssue{Field: field + ".vertex", Message: err.Error(), Fatal: true})
+		}
+		if endpoint := m.Vertex.Endpoint; endpoint != "" {
+			if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				issues = append(issues, ValidationIssue{
+					Field:   field + ".vertex.endpoint",
+					Message: fmt.Sprintf("%q is not a URL (e.g. https://europe-west4-aiplatform.googleapis.com/)", endpoint),
+					Fatal:   true,
+				})
+			}
+		}
+	default:
+		issues = append(issues, ValidationIssue{
+			Field:   field + ".backend",
+			Message: fmt.Sprintf("unknown backend %q; use %s, %s or %s", m.Backend, backendGemini, backendVertex, backendOpenAI),
+			Fatal:   true,
+		})
+	}
+	return issues
+}
+
+func validateKubernetes(cfg *Config) []ValidationIssue {
+	kubeconfig := cfg.Kubernetes.Kubeconfig
+	field := "kubernetes.kubeconfig"
+	if kubeconfig == "" {
+		if home := homedir.HomeDir(); home != "" {
+			kubeconfig = filepath.Join(home, ".kube", "config")
+		}
+	}
+	kubeconfig = expandHome(kubeconfig)
+
+	if inCluster(cfg.Kubernetes.Kubeconfig) {
+		if cfg.Kubernetes.Context != "" {
+			return []ValidationIssue{{
+				Field:   "kubernetes.context",
+				Message: "ignored: there is no kubeconfig, so kasa uses the pod's service account",
+			}}
+		}
+		return nil
+	}
+
+	if _, err := os.Stat(kubeconfig); err != nil {
+		return []ValidationIssue{{
+			Field:   field,
+			Message: fmt.Sprintf("cannot read %s: %v", kubeconfig, err),
+			Fatal:   true,
+		}}
+	}
+
+	rawConfig, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		return []ValidationIssue{{
+			Field:   field,
+			Message: fmt.Sprintf("cannot parse %s: %v", kubeconfig, err),
+			Fatal:   true,
+		}}
+	}
+
+	contextName := cfg.Kubernetes.Context
+	if contextName == "" {
+		if rawConfig.CurrentContext == "" {
+			return []ValidationIssue{{
+				Field:   "kubernetes.context",
+				Message: fmt.Sprintf("no context configured and %s has no current-context", kubeconfig),
+				Fatal:   true,
+			}}
+		}
+		contextName = rawConfig.CurrentContext
+	}
+
+	if _, ok := rawConfig.Contexts[contextName]; !ok {
+		available := make([]string, 0, len(rawConfig.Contexts))
+		for name := range rawConfig.Contexts {
+			available = append(available, name)
+		}
+		return []ValidationIssue{{
+			Field:   "kubernetes.context",
+			Message: fmt.Sprintf("context %q not found in %s (available: %s)", contextName, kubeconfig, strings.Join(available, ", ")),
+			Fatal:   true,
+		}}
+	}
+
+	return nil
+}
+
+func validateDeployments(cfg *Config) []ValidationIssue {
+	dir := cfg.Deployments.Directory
+	if dir == "" {
+		dir = "~/.kasa/deployments"
+	}
+	dir = expandHome(dir)
+
+	info, err := os.Stat(dir)
+	switch {
+	case err == nil && !info.IsDir():
+		return []ValidationIssue{{
+			Field:   "deployments.directory",
+			Message: fmt.Sprintf("%s exists but is not a directory", dir),
+			Fatal:   true,
+		}}
+	case err == nil:
+		if !isWritableDir(dir) {
+			return []ValidationIssue{{
+				Field:   "deployments.directory",
+				Message: fmt.Sprintf("%s is not writable", dir),
+				Fatal:   true,
+			}}
+		}
+	case os.IsNotExist(err):
+		// Will be created on startup; the nearest existing parent must be writable.
+		parent := filepath.Dir(dir)
+		for {
+			if _, err := os.Stat(parent); err == nil {
+				break
+			}
+			next := filepath.Dir(parent)
+			if next == parent {
+				break
+			}
+			parent = next
+		}
+		if !isWritableDir(parent) {
+			return []ValidationIssue{{
+				Field:   "deployments.directory",
+				Message: fmt.Sprintf("%s does not exist and cannot be created (%s is not writable)", dir, parent),
+				Fatal:   true,
+			}}
+		}
+	default:
+		return []ValidationIssue{{
+			Field:   "deployments.directory",
+			Message: fmt.Sprintf("cannot access %s: %v", dir, err),
+			Fatal:   true,
+		}}
+	}
+
+	return nil
+}
+
+func validatePrompts(cfg *Config) []ValidationIssue {
+	if strings.TrimSpace(cfg.Prompts.System) == "" {
+		return []ValidationIssue{{
+			Field:   "prompts.system",
+			Message: "system prompt is empty",
+			Fatal:   true,
+		}}
+	}
+	var issues []ValidationIssue
+	if !strings.Contains(cfg.Prompts.System, "{{TOOL_DOCS}}") {
+		issues = append(issues, ValidationIssue{
+			Field:   "prompts.system",
+			Message: "does not contain {{TOOL_DOCS}}; the agent will not be told which tools exist",
+		})
+	}
+	for _, placeholder := range promptPlaceholder.FindAllString(cfg.Prompts.System, -1) {
+		if !slices.Contains(promptPlaceholders, placeholder) {
+			issues = append(issues, ValidationIssue{
+				Field:   "prompts.system",
+				Message: fmt.Sprintf("unknown placeholder %s is passed to the agent as is (known: %s)", placeholder, strings.Join(promptPlaceholders, ", ")),
+			})
+		}
+	}
+	if _, err := loadHouseRules(cfg.Prompts.Rules); err != nil {
+		issues = append(issues, ValidationIssue{
+			Field:   "prompts.rules",
+			Message: err.Error(),
+			Fatal:   true,
+		})
+	}
+	return issues
+}
+
+// promptPlaceholder matches anything that looks like a prompt variable.
+var promptPlaceholder = regexp.MustCompile(`\{\{[A-Z_]+\}\}`)
+
+func validateWatch(cfg *Config) []ValidationIssue {
+	var issues []ValidationIssue
+	for _, pattern := range cfg.Watch.AutoRemediate.Apps {
+		if _, err := path.Match(pattern, ""); err != nil || strings.Count(pattern, "/") != 1 {
+			issues = append(issues, ValidationIssue{
+				Field:   "watch.auto_remediate.apps",
+				Message: fmt.Sprintf("%q must be a <namespace>/<app> glob", pattern),
+				Fatal:   true,
+			})
+		}
+	}
+
+	return issues
+}
+
+func validateSync(cfg *Config) []ValidationIssue {
+	var issues []ValidationIssue
+
+	if webhook := cfg.Sync.Webhook; webhook != "" {
+		if u, err := url.Parse(webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			issues = append(issues, ValidationIssue{
+				Field:   "sync.webhook",
+				Message: fmt.Sprintf("%q is not an http(s) URL", webhook),
+				Fatal:   true,
+			})
+		}
+	}
+
+	return issues
+}
+
+// isWritableDir reports whether a file can be created in dir.
+func isWritableDir(dir string) bool {
+	f, err := os.CreateTemp(dir, ".kasa-write-check-*")
+	if err != nil {
+		return false
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return true
+}
+
+// expandHome expands a leading ~ to the user's home directory.
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~") {
+		return path
+	}
+	home := homedir.HomeDir()
+	if home == "" {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// runConfigCommand implements the "kasa config <subcommand>" CLI.
+func runConfigCommand(cfg *Config, args []string) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "usage: kasa config validate")
+		return 2
+	}
+
+	issues := validateConfig(cfg, true)
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if hasFatal(issues) {
+		fmt.Println("Configuration is invalid.")
+		return 1
+	}
+	fmt.Println("Configuration OK.")
+	return 0
+}
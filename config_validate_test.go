@@ -0,0 +1,182 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://127.0.0.1:6443
+users:
+- name: dev
+  user:
+    token: t
+`
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		agent     bool
+		inCluster bool // no kubeconfig, in a pod
+		env       map[string]string
+		modify    func(cfg *Config, dir string)
+		fatal     string // field of the expected fatal issue, "" for none
+		warning   string // field of an expected warning
+	}{
+		{name: "valid", agent: true},
+		{
+			name:  "missing API key",
+			agent: true,
+			env:   map[string]string{"GOOGLE_API_KEY": ""},
+			fatal: "agent.model",
+		},
+		{
+			name: "missing API key without the agent",
+			env:  map[string]string{"GOOGLE_API_KEY": ""},
+		},
+		{
+			name:   "unknown model",
+			agent:  true,
+			modify: func(cfg *Config, dir string) { cfg.Agent.Model = "gpt-4o" },
+			fatal:  "agent.model",
+		},
+		{
+			name:  "local model without an API key",
+			agent: true,
+			env:   map[string]string{"GOOGLE_API_KEY": ""},
+			modify: func(cfg *Config, dir string) {
+				cfg.Agent.Backend = backendOpenAI
+				cfg.Agent.OpenAI.BaseURL = "http://localhost:8080/v1"
+			},
+		},
+		{
+			name:   "empty system prompt",
+			agent:  true,
+			modify: func(cfg *Config, dir string) { cfg.Prompts.System = "" },
+			fatal:  "prompts.system",
+		},
+		{
+			name:   "empty system prompt without the agent",
+			modify: func(cfg *Config, dir string) { cfg.Prompts.System = "" },
+		},
+		{
+			name:    "system prompt without tool docs",
+			agent:   true,
+			modify:  func(cfg *Config, dir string) { cfg.Prompts.System = "You are kasa." },
+			warning: "prompts.system",
+		},
+		{
+			name:    "no search key",
+			agent:   true,
+			env:     map[string]string{"TAVILY_API_KEY": ""},
+			warning: "env",
+		},
+		{
+			name:   "missing kubeconfig",
+			modify: func(cfg *Config, dir string) { cfg.Kubernetes.Kubeconfig = filepath.Join(dir, "missing") },
+			fatal:  "kubernetes.kubeconfig",
+		},
+		{
+			name:      "in-cluster",
+			inCluster: true,
+		},
+		{
+			name:      "in-cluster with a context",
+			inCluster: true,
+			modify:    func(cfg *Config, dir string) { cfg.Kubernetes.Context = "dev" },
+			warning:   "kubernetes.context",
+		},
+		{
+			name:   "unknown context",
+			modify: func(cfg *Config, dir string) { cfg.Kubernetes.Context = "prod" },
+			fatal:  "kubernetes.context",
+		},
+		{
+			name:   "impersonated groups without a user",
+			modify: func(cfg *Config, dir string) { cfg.Kubernetes.AsGroups = []string{"admins"} },
+			fatal:  "kubernetes.as_groups",
+		},
+		{
+			name: "deployments directory is a file",
+			modify: func(cfg *Config, dir string) {
+				cfg.Deployments.Directory = filepath.Join(dir, "file")
+				if err := os.WriteFile(cfg.Deployments.Directory, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			fatal: "deployments.directory",
+		},
+		{
+			name:   "watch app without a namespace",
+			modify: func(cfg *Config, dir string) { cfg.Watch.AutoRemediate.Apps = []string{"web"} },
+			fatal:  "watch.auto_remediate.apps",
+		},
+		{
+			name:   "sync webhook not a URL",
+			modify: func(cfg *Config, dir string) { cfg.Sync.Webhook = "hooks.example.com" },
+			fatal:  "sync.webhook",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("GOOGLE_API_KEY", "key")
+			t.Setenv("JINA_READER_API_KEY", "key")
+			t.Setenv("TAVILY_API_KEY", "key")
+			t.Setenv("KUBERNETES_SERVICE_HOST", "")
+			if tt.inCluster {
+				t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+			} else {
+				if err := os.Mkdir(filepath.Join(home, ".kube"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(home, ".kube", "config"), []byte(testKubeconfig), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			cfg := &Config{}
+			cfg.Agent.Name = "kasa"
+			cfg.Agent.Model = "gemini-2.5-flash"
+			cfg.Prompts.System = "You are kasa.\n{{TOOL_DOCS}}"
+			cfg.Deployments.Directory = filepath.Join(home, "deployments")
+			if tt.modify != nil {
+				tt.modify(cfg, home)
+			}
+
+			issues := validateConfig(cfg, tt.agent)
+			var fatal, warning bool
+			for _, issue := range issues {
+				switch {
+				case issue.Fatal && issue.Field == tt.fatal:
+					fatal = true
+				case issue.Fatal:
+					t.Errorf("unexpected %s", issue)
+				case issue.Field == tt.warning:
+					warning = true
+				}
+			}
+			if tt.fatal != "" && !fatal {
+				t.Errorf("no error on %s in %v", tt.fatal, issues)
+			}
+			if tt.warning != "" && !warning {
+				t.Errorf("no warning on %s in %v", tt.warning, issues)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/perbu/kasa/manifest"
+)
+
+// runExportCommand implements "kasa export", which writes the stored
+// manifests of an app or namespace as a bundle. It needs no cluster access.
+func runExportCommand(cfg *Config, args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", manifest.ExportYAML, "Bundle format: "+strings.Join(manifest.ExportFormats, ", "))
+	output := fs.String("o", "", "Write the bundle to this file instead of standard output")
+	includeSecrets := fs.Bool("include-secrets", false, "Also export Secrets, including their data")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: kasa export [-format yaml|tar|helm] [-o file] [-include-secrets] <namespace>[/<app>]")
+		return 2
+	}
+	namespace, app, _ := strings.Cut(fs.Arg(0), "/")
+
+	mgr, err := manifest.NewManager(manifestDirectory(cfg))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Nothing is written until the bundle is complete
+	var buf bytes.Buffer
+	result, err := mgr.Export(&buf, manifest.ExportOptions{
+		Namespace:      namespace,
+		App:            app,
+		Format:         *format,
+		IncludeSecrets: *includeSecrets,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = os.WriteFile(*output, buf.Bytes(), 0600)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	for _, skipped := range result.Skipped {
+		fmt.Fprintf(os.Stderr, "Skipped %s (use -include-secrets to export Secrets)\n", skipped.Path)
+	}
+	if *output != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d manifest(s) to %s\n", len(result.Manifests), *output)
+	}
+	return 0
+}
+
+// manifestDirectory returns the directory of the manifest store.
+func manifestDirectory(cfg *Config) string {
+	if cfg.Deployments.Directory == "" {
+		return "~/.kasa/deployments"
+	}
+	return cfg.Deployments.Directory
+}
@@ -0,0 +1,6 @@
+package main
+
+// Tool extensions register themselves with tools.Register from an init
+// function. Link them into kasa with a blank import here, e.g.
+//
+//	import _ "example.com/kasa-keda/tools"
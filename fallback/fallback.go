@@ -0,0 +1,179 @@
+// Package fallback chains models: a call that fails with a transient
+// provider error is retried with backoff, then handed to the next model, so
+// an API outage doesn't end a run halfway through a plan.
+package fallback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/perbu/kasa/openai"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// DefaultMaxRetries is how often a call to one model is retried before
+// the next model is tried.
+const DefaultMaxRetries = 2
+
+// Backoff between retries of one model: retryBaseDelay doubled on every
+// attempt, never more than retryMaxDelay.
+const (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// Keys of LLMResponse.CustomMetadata set on every response.
+const (
+	// ModelKey holds the name of the model that answered.
+	ModelKey = "kasa.model"
+	// FailuresKey holds the failed calls before it, if any.
+	FailuresKey = "kasa.model_failures"
+)
+
+type chain struct {
+	models     []model.LLM
+	maxRetries int
+	baseDelay  time.Duration
+}
+
+// New returns a model.LLM that calls models in order. Each is retried up to
+// maxRetries times (0 for DefaultMaxRetries, -1 for none) on rate limits,
+// server errors, timeouts and connection failures before the next one is
+// tried; other errors are returned as they are. The chain is named after
+// the first model.
+func New(models []model.LLM, maxRetries int) model.LLM {
+	switch {
+	case maxRetries == 0:
+		maxRetries = DefaultMaxRetries
+	case maxRetries < 0:
+		maxRetries = 0
+	}
+	return &chain{models: models, maxRetries: maxRetries, baseDelay: retryBaseDelay}
+}
+
+// Name returns the name of the first model.
+func (c *chain) Name() string {
+	return c.models[0].Name()
+}
+
+// GenerateContent calls the models until one answers. A call is only
+// retried if it failed before producing anything, so a stream is never
+// repeated.
+func (c *chain) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		var failures []string
+		var lastErr error
+		for _, m := range c.models {
+			for attempt := 0; attempt <= c.maxRetries; attempt++ {
+				if attempt > 0 && !c.wait(ctx, attempt) {
+					yield(nil, ctx.Err())
+					return
+				}
+
+				answered := false
+				var failed error
+				for resp, err := range m.GenerateContent(ctx, req, stream) {
+					if err != nil {
+						if !answered && Retryable(ctx, err) {
+							failed = err
+							break
+						}
+						yield(nil, err)
+						return
+					}
+					answered = true
+					if !yield(annotate(resp, m.Name(), failures), nil) {
+						return
+					}
+				}
+				if failed == nil {
+					return
+				}
+				lastErr = failed
+				failures = append(failures, fmt.Sprintf("%s: %v", m.Name(), failed))
+			}
+		}
+		yield(nil, fmt.Errorf("model call failed after %d attempts, last: %w", len(failures), lastErr))
+	}
+}
+
+// wait sleeps before the given retry, returning false if ctx ends first.
+func (c *chain) wait(ctx context.Context, attempt int) bool {
+	delay := min(c.baseDelay<<(attempt-1), retryMaxDelay)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// annotate records in resp which model answered, and after which failures.
+func annotate(resp *model.LLMResponse, name string, failures []string) *model.LLMResponse {
+	if resp == nil {
+		return nil
+	}
+	metadata := maps.Clone(resp.CustomMetadata)
+	if metadata == nil {
+		metadata = make(map[string]any)
+	}
+	metadata[ModelKey] = name
+	if len(failures) > 0 {
+		metadata[FailuresKey] = strings.Join(failures, "; ")
+	}
+	resp.CustomMetadata = metadata
+	return resp
+}
+
+// Retryable reports whether err is worth retrying or handing to another
+// model: a 429 or 5xx from the provider, a timeout or a failed connection.
+// Nothing is retried once ctx itself is done.
+func Retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var genaiErr genai.APIError
+	if errors.As(err, &genaiErr) {
+		return genaiErr.Code == 429 || genaiErr.Code >= 500
+	}
+	var openaiErr *openai.APIError
+	if errors.As(err, &openaiErr) {
+		return openaiErr.StatusCode == 429 || openaiErr.StatusCode >= 500
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// Model returns the name of the model that answered a response, or "" if
+// it didn't come from a Chain.
+func Model(metadata map[string]any) string {
+	name, _ := metadata[ModelKey].(string)
+	return name
+}
+
+// Note describes the model that answered a response when it wasn't a plain
+// answer from the first model, e.g. "gemini-2.5-pro answered after
+// gemini-2.5-flash: ...", or "" otherwise.
+func Note(metadata map[string]any) string {
+	failures, _ := metadata[FailuresKey].(string)
+	if failures == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s answered after %s", Model(metadata), failures)
+}
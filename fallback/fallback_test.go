@@ -0,0 +1,132 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"strings"
+	"testing"
+
+	"github.com/perbu/kasa/openai"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// fakeLLM fails with the given errors, one per call, then answers.
+type fakeLLM struct {
+	name   string
+	errs   []error
+	calls  int
+	stream []string // partial texts yielded before failing with the error
+}
+
+func (f *fakeLLM) Name() string { return f.name }
+
+func (f *fakeLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		f.calls++
+		for _, text := range f.stream {
+			if !yield(&model.LLMResponse{Content: genai.NewContentFromText(text, genai.RoleModel), Partial: true}, nil) {
+				return
+			}
+		}
+		if f.calls <= len(f.errs) {
+			yield(nil, f.errs[f.calls-1])
+			return
+		}
+		yield(&model.LLMResponse{Content: genai.NewContentFromText("answer from "+f.name, genai.RoleModel)}, nil)
+	}
+}
+
+// generate runs the chain and returns the responses and the last error.
+func generate(c *chain) ([]*model.LLMResponse, error) {
+	var responses []*model.LLMResponse
+	for resp, err := range c.GenerateContent(context.Background(), &model.LLMRequest{}, false) {
+		if err != nil {
+			return responses, err
+		}
+		responses = append(responses, resp)
+	}
+	return responses, nil
+}
+
+func TestGenerateContent(t *testing.T) {
+	unavailable := genai.APIError{Code: 503, Message: "overloaded"}
+	throttled := &openai.APIError{StatusCode: 429, Status: "429 Too Many Requests", Message: "slow down"}
+
+	t.Run("retries the first model", func(t *testing.T) {
+		flash := &fakeLLM{name: "flash", errs: []error{unavailable}}
+		responses, err := generate(&chain{models: []model.LLM{flash}, maxRetries: 2})
+		if err != nil || len(responses) != 1 || flash.calls != 2 {
+			t.Fatalf("expected an answer on the second call, got %v, %v after %d calls", responses, err, flash.calls)
+		}
+		if responses[0].CustomMetadata[ModelKey] != "flash" || !strings.Contains(Note(responses[0].CustomMetadata), "flash answered after flash: Error 503") {
+			t.Errorf("unexpected metadata %v", responses[0].CustomMetadata)
+		}
+	})
+
+	t.Run("falls back after the retries", func(t *testing.T) {
+		flash := &fakeLLM{name: "flash", errs: []error{unavailable, throttled}}
+		pro := &fakeLLM{name: "pro"}
+		responses, err := generate(&chain{models: []model.LLM{flash, pro}, maxRetries: 1})
+		if err != nil || len(responses) != 1 || flash.calls != 2 || pro.calls != 1 {
+			t.Fatalf("expected pro to answer, got %v, %v", responses, err)
+		}
+		if note := Note(responses[0].CustomMetadata); !strings.HasPrefix(note, "pro answered after flash") || !strings.Contains(note, "slow down") {
+			t.Errorf("unexpected note %q", note)
+		}
+	})
+
+	t.Run("returns other errors", func(t *testing.T) {
+		flash := &fakeLLM{name: "flash", errs: []error{genai.APIError{Code: 400, Message: "bad request"}}}
+		pro := &fakeLLM{name: "pro"}
+		if _, err := generate(&chain{models: []model.LLM{flash, pro}, maxRetries: 2}); err == nil || flash.calls != 1 || pro.calls != 0 {
+			t.Errorf("expected the 400 without retries, got %v after %d calls", err, flash.calls)
+		}
+	})
+
+	t.Run("doesn't repeat a stream", func(t *testing.T) {
+		flash := &fakeLLM{name: "flash", errs: []error{unavailable}, stream: []string{"Look"}}
+		pro := &fakeLLM{name: "pro"}
+		responses, err := generate(&chain{models: []model.LLM{flash, pro}, maxRetries: 2})
+		if !errors.As(err, new(genai.APIError)) || len(responses) != 1 || pro.calls != 0 {
+			t.Errorf("expected the error after the partial response, got %v, %v", responses, err)
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		flash := &fakeLLM{name: "flash", errs: []error{unavailable, unavailable}}
+		pro := &fakeLLM{name: "pro", errs: []error{throttled, throttled}}
+		_, err := generate(&chain{models: []model.LLM{flash, pro}, maxRetries: 1})
+		var last *openai.APIError
+		if !errors.As(err, &last) || !strings.Contains(err.Error(), "after 4 attempts") {
+			t.Errorf("expected the last error after 4 attempts, got %v", err)
+		}
+	})
+}
+
+func TestRetryable(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{genai.APIError{Code: 429}, true},
+		{genai.APIError{Code: 500}, true},
+		{genai.APIError{Code: 403}, false},
+		{&openai.APIError{StatusCode: 502}, true},
+		{context.DeadlineExceeded, true},
+		{errors.New("invalid argument"), false},
+	}
+	for _, tt := range tests {
+		if got := Retryable(ctx, tt.err); got != tt.want {
+			t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if Retryable(cancelled, genai.APIError{Code: 503}) {
+		t.Error("expected nothing to be retried once the context is done")
+	}
+}
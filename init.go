@@ -0,0 +1,56 @@
+package main
+
+import (
+	_ "embed"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// starterConfig is the config.yaml shipped with kasa, including the default
+// system prompt.
+//
+//go:embed config.yaml
+var starterConfig string
+
+// runInitCommand implements "kasa init", which writes a starter config to
+// path, or to the user's config file if path is empty.
+func runInitCommand(path string, args []string) int {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	force := fs.Bool("force", false, "Overwrite an existing config file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if path == "" {
+		path = userConfigPath()
+		if path == "" {
+			fmt.Fprintln(os.Stderr, "Error: no home directory; pass -config with the path to create")
+			return 1
+		}
+	}
+	if _, err := os.Stat(path); err == nil && !*force {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists (use 'kasa init -force' to overwrite it)\n", path)
+		return 1
+	}
+
+	// Manifests would otherwise end up next to the config file
+	content := strings.Replace(starterConfig, "directory: deployments\n", "directory: ~/.kasa/deployments\n", 1)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Wrote %s.\n", path)
+	fmt.Println("Next steps:")
+	fmt.Println("  1. Set kubernetes.context and agent.model in it, and adjust prompts.system to taste.")
+	fmt.Println("  2. Store your API keys with 'kasa auth set GOOGLE_API_KEY' (or in .env), or set agent.backend: vertex or openai.")
+	fmt.Println("  3. Run 'kasa config validate'.")
+	return 0
+}
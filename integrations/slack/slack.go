@@ -0,0 +1,365 @@
+// Package slack posts kasa plans and notifications to Slack and handles the
+// approve/reject buttons attached to proposed plans.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/perbu/kasa/repl"
+	"github.com/perbu/kasa/tools"
+)
+
+// Action IDs for the plan buttons.
+const (
+	ActionApprove = "kasa_approve"
+	ActionReject  = "kasa_reject"
+)
+
+const postMessageURL = "https://slack.com/api/chat.postMessage"
+
+// Config configures the Slack integration (integrations.slack in config.yaml).
+// Either WebhookURL or BotToken (with Channel) must be set. Secrets may be
+// left empty in the file and supplied via SLACK_WEBHOOK_URL, SLACK_BOT_TOKEN
+// and SLACK_SIGNING_SECRET instead.
+type Config struct {
+	WebhookURL      string `yaml:"webhook_url"`
+	BotToken        string `yaml:"bot_token"`
+	Channel         string `yaml:"channel"`
+	SigningSecret   string `yaml:"signing_secret"`
+	NotifyMutations bool   `yaml:"notify_mutations"`
+	NotifyDrift     bool   `yaml:"notify_drift"`
+}
+
+// withEnv fills empty secrets from the environment.
+func (c Config) withEnv() Config {
+	if c.WebhookURL == "" {
+		c.WebhookURL = os.Getenv("SLACK_WEBHOOK_URL")
+	}
+	if c.BotToken == "" {
+		c.BotToken = os.Getenv("SLACK_BOT_TOKEN")
+	}
+	if c.SigningSecret == "" {
+		c.SigningSecret = os.Getenv("SLACK_SIGNING_SECRET")
+	}
+	return c
+}
+
+// Enabled reports whether enough is configured to post messages.
+func (c Config) Enabled() bool {
+	c = c.withEnv()
+	return c.WebhookURL != "" || (c.BotToken != "" && c.Channel != "")
+}
+
+// Client posts messages to Slack. It implements repl.Notifier.
+type Client struct {
+	cfg        Config
+	isMutating func(toolName string) bool
+	httpClient *http.Client
+}
+
+// New creates a Client. isMutating decides which tool results are reported
+// when notify_mutations is enabled.
+func New(cfg Config, isMutating func(toolName string) bool) *Client {
+	return &Client{
+		cfg:        cfg.withEnv(),
+		isMutating: isMutating,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// PlanProposed posts a plan with approve/reject buttons. The session and plan
+// IDs are carried in the button values so the interaction handler can route
+// the decision back to the right session, and only to the plan it was made on.
+func (c *Client) PlanProposed(sessionID string, plan *repl.Plan) {
+	var sb strings.Builder
+	for i, a := range plan.Actions {
+		sb.WriteString(fmt.Sprintf("%d. `%s` — %s\n", i+1, a.Tool, a.Reason))
+	}
+
+	blocks := []map[string]any{
+		section(fmt.Sprintf("*Kasa proposed a plan*\n%s", plan.Description)),
+		section(sb.String()),
+	}
+
+	// Buttons are only actionable when the interaction endpoint can verify requests.
+	if sessionID != "" && plan.ID != "" && c.cfg.SigningSecret != "" {
+		value := sessionID + "/" + plan.ID
+		blocks = append(blocks, map[string]any{
+			"type": "actions",
+			"elements": []map[string]any{
+				button("Approve", ActionApprove, value, "primary"),
+				button("Reject", ActionReject, value, "danger"),
+			},
+		})
+	}
+
+	c.post(fmt.Sprintf("Kasa proposed a plan: %s", plan.Description), blocks)
+}
+
+// ToolExecuted reports a completed mutating tool call.
+func (c *Client) ToolExecuted(toolName string, result map[string]any) {
+	if !c.cfg.NotifyMutations || c.isMutating == nil || !c.isMutating(toolName) {
+		return
+	}
+
+	var text string
+	if dryRun, _ := result["dry_run"].(bool); dryRun {
+		text = fmt.Sprintf(":test_tube: `%s` ran as a dry-run only; awaiting confirmation", toolName)
+	} else if errMsg, ok := result["error"]; ok {
+		text = fmt.Sprintf(":x: `%s` failed: %v", toolName, errMsg)
+	} else if msg, ok := result["message"].(string); ok && msg != "" {
+		text = fmt.Sprintf(":white_check_mark: `%s`: %s", toolName, msg)
+	} else {
+		text = fmt.Sprintf(":white_check_mark: `%s` completed", toolName)
+	}
+
+	c.post(text, []map[string]any{section(text)})
+}
+
+// DriftDetected reports drift scan results if anything is out of sync.
+func (c *Client) DriftDetected(results *tools.DriftScanResults) {
+	if !c.cfg.NotifyDrift || results == nil || results.Drifted+results.Missing+results.Errors == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("*Drift detected:* %d drifted, %d not in cluster, %d errors (of %d manifests)\n",
+		results.Drifted, results.Missing, results.Errors, results.Total))
+	for _, r := range results.Results {
+		if r.Status == "in_sync" {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("• `%s/%s/%s`: %s\n", r.Namespace, r.Name, r.Kind, r.Status))
+	}
+
+	c.post("Kasa detected drift", []map[string]any{section(sb.String())})
+}
+
+// Remediated reports stored manifests that watch or sync mode re-applied to
+// revert drift.
+func (c *Client) Remediated(results []tools.RemediationResult) {
+	if len(results) == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	if results[0].DryRun {
+		sb.WriteString(fmt.Sprintf("*Dry run:* would re-apply %d stored manifests\n", len(results)))
+	} else {
+		sb.WriteString(fmt.Sprintf("*Auto-remediation:* re-applied %d stored manifests\n", len(results)))
+	}
+	for _, r := range results {
+		resource := fmt.Sprintf("%s/%s/%s", r.Namespace, r.Name, r.Kind)
+		if r.Error != "" {
+			sb.WriteString(fmt.Sprintf("• :x: `%s`: %s\n", resource, r.Error))
+		} else if r.DryRun {
+			sb.WriteString(fmt.Sprintf("• :grey_question: `%s` would be %s (%s)\n", resource, r.Action, r.Drift))
+		} else {
+			sb.WriteString(fmt.Sprintf("• :white_check_mark: `%s` %s (%s)\n", resource, r.Action, r.Drift))
+		}
+	}
+
+	c.post("Kasa remediated drift", []map[string]any{section(sb.String())})
+}
+
+// post sends a message via the webhook or chat.postMessage. Failures are
+// logged to stderr; notifications must never break the agent loop.
+func (c *Client) post(text string, blocks []map[string]any) {
+	payload := map[string]any{
+		"text":   text,
+		"blocks": blocks,
+	}
+
+	target := c.cfg.WebhookURL
+	if c.cfg.BotToken != "" && c.cfg.Channel != "" {
+		target = postMessageURL
+		payload["channel"] = c.cfg.Channel
+	}
+	if target == "" {
+		return
+	}
+
+	if err := c.postJSON(target, payload); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: slack notification failed: %v\n", err)
+	}
+}
+
+func (c *Client) postJSON(target string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if target == postMessageURL {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.BotToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	// chat.postMessage reports errors in the body with HTTP 200
+	if target == postMessageURL {
+		var apiResp struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(respBody, &apiResp); err == nil && !apiResp.OK {
+			return fmt.Errorf("slack API error: %s", apiResp.Error)
+		}
+	}
+	return nil
+}
+
+// Decider applies an approve/reject decision to the plan with planID, which
+// must still be the pending plan of the session.
+type Decider interface {
+	Approve(sessionID, planID string) error
+	Reject(sessionID, planID string) error
+}
+
+// InteractionHandler returns an HTTP handler for Slack's interactivity
+// request URL. It verifies the request signature, applies the button
+// decision, and updates the original message with the outcome.
+func (c *Client) InteractionHandler(d Decider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "reading body", http.StatusBadRequest)
+			return
+		}
+
+		if err := verifySignature(c.cfg.SigningSecret, r.Header, body, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			http.Error(w, "invalid form body", http.StatusBadRequest)
+			return
+		}
+
+		var payload struct {
+			User struct {
+				Username string `json:"username"`
+			} `json:"user"`
+			ResponseURL string `json:"response_url"`
+			Actions     []struct {
+				ActionID string `json:"action_id"`
+				Value    string `json:"value"`
+			} `json:"actions"`
+		}
+		if err := json.Unmarshal([]byte(form.Get("payload")), &payload); err != nil || len(payload.Actions) == 0 {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		action := payload.Actions[0]
+		sessionID, planID, _ := strings.Cut(action.Value, "/")
+		var outcome string
+		switch action.ActionID {
+		case ActionApprove:
+			err = d.Approve(sessionID, planID)
+			outcome = fmt.Sprintf(":white_check_mark: Plan approved by %s", payload.User.Username)
+		case ActionReject:
+			err = d.Reject(sessionID, planID)
+			outcome = fmt.Sprintf(":no_entry: Plan rejected by %s", payload.User.Username)
+		default:
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if err != nil {
+			outcome = fmt.Sprintf(":warning: Could not apply decision: %v", err)
+		}
+
+		// Acknowledge immediately; Slack expects a response within 3 seconds.
+		w.WriteHeader(http.StatusOK)
+
+		if payload.ResponseURL != "" {
+			go func() {
+				_ = c.postJSON(payload.ResponseURL, map[string]any{
+					"replace_original": false,
+					"text":             outcome,
+				})
+			}()
+		}
+	})
+}
+
+// verifySignature checks Slack's v0 request signature and rejects requests
+// older than five minutes to prevent replay.
+func verifySignature(secret string, header http.Header, body []byte, now time.Time) error {
+	if secret == "" {
+		return fmt.Errorf("signing secret not configured")
+	}
+
+	ts := header.Get("X-Slack-Request-Timestamp")
+	sig := header.Get("X-Slack-Signature")
+	if ts == "" || sig == "" {
+		return fmt.Errorf("missing signature headers")
+	}
+
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp")
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > 5*time.Minute || d < -5*time.Minute {
+		return fmt.Errorf("stale request")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", ts)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+func section(text string) map[string]any {
+	return map[string]any{
+		"type": "section",
+		"text": map[string]any{"type": "mrkdwn", "text": text},
+	}
+}
+
+func button(label, actionID, value, style string) map[string]any {
+	return map[string]any{
+		"type":      "button",
+		"text":      map[string]any{"type": "plain_text", "text": label},
+		"action_id": actionID,
+		"value":     value,
+		"style":     style,
+	}
+}
@@ -0,0 +1,148 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/perbu/kasa/repl"
+)
+
+func sign(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	secret := "8f742231b10e8888abcd99yyyzzz85a5"
+	body := []byte("payload=%7B%22type%22%3A%22block_actions%22%7D")
+	now := time.Unix(1700000000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	tests := []struct {
+		name    string
+		secret  string
+		ts      string
+		sig     string
+		wantErr bool
+	}{
+		{"valid", secret, ts, sign(secret, ts, body), false},
+		{"wrong secret", secret, ts, sign("other", ts, body), true},
+		{"stale", secret, strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10), sign(secret, strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10), body), true},
+		{"missing headers", secret, "", "", true},
+		{"no secret configured", "", ts, sign(secret, ts, body), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.ts != "" {
+				h.Set("X-Slack-Request-Timestamp", tt.ts)
+			}
+			if tt.sig != "" {
+				h.Set("X-Slack-Signature", tt.sig)
+			}
+			err := verifySignature(tt.secret, h, body, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifySignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// recordingDecider records decisions, refusing those not on plan-1.
+type recordingDecider struct {
+	decisions []string
+}
+
+func (d *recordingDecider) Approve(sessionID, planID string) error {
+	return d.decide("approve", sessionID, planID)
+}
+
+func (d *recordingDecider) Reject(sessionID, planID string) error {
+	return d.decide("reject", sessionID, planID)
+}
+
+func (d *recordingDecider) decide(decision, sessionID, planID string) error {
+	if planID != "plan-1" {
+		return fmt.Errorf("plan %s is no longer pending", planID)
+	}
+	d.decisions = append(d.decisions, decision+" "+sessionID+" "+planID)
+	return nil
+}
+
+func TestPlanProposed_Buttons(t *testing.T) {
+	var blocks []map[string]any
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Blocks []map[string]any `json:"blocks"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		blocks = payload.Blocks
+	}))
+	defer hook.Close()
+	c := New(Config{WebhookURL: hook.URL, SigningSecret: "secret"}, nil)
+
+	c.PlanProposed("sess-1", &repl.Plan{ID: "plan-1", Description: "Roll out web v2"})
+	if len(blocks) != 3 {
+		t.Fatalf("blocks = %v, want the plan with buttons", blocks)
+	}
+	elements, _ := blocks[2]["elements"].([]any)
+	for _, e := range elements {
+		if value := e.(map[string]any)["value"]; value != "sess-1/plan-1" {
+			t.Errorf("button value = %v, want the session and plan IDs", value)
+		}
+	}
+
+	// A plan without an ID can't be decided on from Slack
+	c.PlanProposed("sess-1", &repl.Plan{Description: "Roll out web v2"})
+	if len(blocks) != 2 {
+		t.Errorf("blocks = %v, want no buttons", blocks)
+	}
+}
+
+func TestInteractionHandler(t *testing.T) {
+	secret := "8f742231b10e8888abcd99yyyzzz85a5"
+	d := &recordingDecider{}
+	h := (&Client{cfg: Config{SigningSecret: secret}}).InteractionHandler(d)
+
+	click := func(actionID, value string) int {
+		payload, _ := json.Marshal(map[string]any{
+			"actions": []map[string]any{{"action_id": actionID, "value": value}},
+		})
+		body := "payload=" + url.QueryEscape(string(payload))
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req := httptest.NewRequest(http.MethodPost, "/integrations/slack/interactions", strings.NewReader(body))
+		req.Header.Set("X-Slack-Request-Timestamp", ts)
+		req.Header.Set("X-Slack-Signature", sign(secret, ts, []byte(body)))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := click(ActionApprove, "sess-1/plan-1"); code != http.StatusOK {
+		t.Errorf("approve = %d", code)
+	}
+	// Clicks on a plan that is no longer pending, or on a button that names
+	// no plan, decide nothing
+	click(ActionApprove, "sess-1/plan-0")
+	click(ActionReject, "sess-1")
+	if code := click(ActionReject, "sess-1/plan-1"); code != http.StatusOK {
+		t.Errorf("reject = %d", code)
+	}
+	want := []string{"approve sess-1 plan-1", "reject sess-1 plan-1"}
+	if fmt.Sprint(d.decisions) != fmt.Sprint(want) {
+		t.Errorf("decisions = %q, want %q", d.decisions, want)
+	}
+}
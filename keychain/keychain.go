@@ -0,0 +1,142 @@
+// Package keychain stores API keys in the operating system's secret store
+// instead of plaintext .env files. It shells out to the platform tools:
+// `security` (macOS Keychain) and `secret-tool` (Linux Secret Service / libsecret).
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// service is the service/label under which all kasa secrets are stored.
+const service = "kasa"
+
+// ErrNotFound is returned when no secret is stored under the given name.
+var ErrNotFound = errors.New("secret not found in keychain")
+
+// ErrUnsupported is returned when no supported secret store is available.
+var ErrUnsupported = errors.New("no supported keychain available (need macOS 'security' or Linux 'secret-tool')")
+
+// These run the secret store CLIs; tests replace them.
+var (
+	goos     = runtime.GOOS
+	lookPath = exec.LookPath
+	command  = exec.Command
+)
+
+// KnownKeys lists the environment variables kasa reads that may be kept in
+// the keychain.
+var KnownKeys = []string{
+	"GOOGLE_API_KEY",
+	"JINA_READER_API_KEY",
+	"TAVILY_API_KEY",
+	"SLACK_BOT_TOKEN",
+	"SLACK_WEBHOOK_URL",
+	"SLACK_SIGNING_SECRET",
+	"PROMETHEUS_BEARER_TOKEN",
+}
+
+// Available reports whether a supported secret store tool is installed.
+func Available() bool {
+	name := tool()
+	if name == "" {
+		return false
+	}
+	_, err := lookPath(name)
+	return err == nil
+}
+
+// tool returns the secret store CLI for this platform.
+func tool() string {
+	switch goos {
+	case "darwin":
+		return "security"
+	case "linux", "freebsd", "openbsd":
+		return "secret-tool"
+	default:
+		return ""
+	}
+}
+
+// ArgumentExposed reports whether Set passes the value on the command line of
+// the secret store CLI, where other processes can read it (e.g. with ps)
+// while it runs. That is the case with macOS `security`.
+func ArgumentExposed() bool {
+	return tool() == "security"
+}
+
+// Get returns the secret stored under name.
+func Get(name string) (string, error) {
+	if !Available() {
+		return "", ErrUnsupported
+	}
+
+	var cmd *exec.Cmd
+	switch tool() {
+	case "security":
+		cmd = command("security", "find-generic-password", "-s", service, "-a", name, "-w")
+	case "secret-tool":
+		cmd = command("secret-tool", "lookup", "service", service, "key", name)
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		// Both tools exit non-zero when the item does not exist.
+		return "", ErrNotFound
+	}
+
+	value := strings.TrimRight(string(output), "\r\n")
+	if value == "" {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Set stores value under name, replacing any existing secret.
+func Set(name, value string) error {
+	if !Available() {
+		return ErrUnsupported
+	}
+
+	var cmd *exec.Cmd
+	switch tool() {
+	case "security":
+		// -U updates the item if it already exists. The value is passed as an
+		// argument because `security` cannot read it from stdin
+		// non-interactively; see ArgumentExposed.
+		cmd = command("security", "add-generic-password", "-U", "-s", service, "-a", name, "-w", value)
+	case "secret-tool":
+		cmd = command("secret-tool", "store", "--label", fmt.Sprintf("%s %s", service, name), "service", service, "key", name)
+		cmd.Stdin = strings.NewReader(value)
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %w\nOutput: %s", tool(), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Delete removes the secret stored under name.
+func Delete(name string) error {
+	if !Available() {
+		return ErrUnsupported
+	}
+
+	var cmd *exec.Cmd
+	switch tool() {
+	case "security":
+		cmd = command("security", "delete-generic-password", "-s", service, "-a", name)
+	case "secret-tool":
+		cmd = command("secret-tool", "clear", "service", service, "key", name)
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %w\nOutput: %s", tool(), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeStore replaces the secret store CLI with this test binary, which
+// prints stdout, saves its stdin to the stdin file and exits with exit (see
+// TestHelperProcess), and records the commands run.
+type fakeStore struct {
+	stdout string
+	exit   int
+	stdin  string
+	cmds   []*exec.Cmd
+}
+
+func newFakeStore(t *testing.T, platform string) *fakeStore {
+	f := &fakeStore{stdin: filepath.Join(t.TempDir(), "stdin")}
+	oldGOOS, oldLookPath, oldCommand := goos, lookPath, command
+	t.Cleanup(func() { goos, lookPath, command = oldGOOS, oldLookPath, oldCommand })
+	goos = platform
+	lookPath = func(name string) (string, error) { return "/usr/bin/" + name, nil }
+	command = func(name string, args ...string) *exec.Cmd {
+		cmd := exec.Command(os.Args[0], append([]string{"-test.run=TestHelperProcess", "--", name}, args...)...)
+		cmd.Env = append(os.Environ(), "KEYCHAIN_HELPER=1", "KEYCHAIN_STDOUT="+f.stdout, "KEYCHAIN_EXIT="+strconv.Itoa(f.exit), "KEYCHAIN_STDIN="+f.stdin)
+		f.cmds = append(f.cmds, cmd)
+		return cmd
+	}
+	return f
+}
+
+// args returns the arguments of the last command, the CLI's name first.
+func (f *fakeStore) args(t *testing.T) []string {
+	t.Helper()
+	if len(f.cmds) == 0 {
+		t.Fatal("no command run")
+	}
+	args := f.cmds[len(f.cmds)-1].Args
+	return args[slices.Index(args, "--")+1:]
+}
+
+// TestHelperProcess is the fake secret store CLI.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("KEYCHAIN_HELPER") != "1" {
+		return
+	}
+	stdin, _ := io.ReadAll(os.Stdin)
+	_ = os.WriteFile(os.Getenv("KEYCHAIN_STDIN"), stdin, 0600)
+	fmt.Print(os.Getenv("KEYCHAIN_STDOUT"))
+	code, _ := strconv.Atoi(os.Getenv("KEYCHAIN_EXIT"))
+	os.Exit(code)
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		platform string
+		want     []string
+	}{
+		{"darwin", []string{"security", "find-generic-password", "-s", "kasa", "-a", "GOOGLE_API_KEY", "-w"}},
+		{"linux", []string{"secret-tool", "lookup", "service", "kasa", "key", "GOOGLE_API_KEY"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			f := newFakeStore(t, tt.platform)
+			f.stdout = "s3cret\n"
+			value, err := Get("GOOGLE_API_KEY")
+			if err != nil || value != "s3cret" {
+				t.Errorf("Get = %q, %v; want s3cret", value, err)
+			}
+			if args := f.args(t); !slices.Equal(args, tt.want) {
+				t.Errorf("ran %q, want %q", args, tt.want)
+			}
+
+			// Both tools exit non-zero for a missing item
+			f.stdout, f.exit = "", 44
+			if _, err := Get("GOOGLE_API_KEY"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get of a missing item = %v, want ErrNotFound", err)
+			}
+			f.stdout, f.exit = "\n", 0
+			if _, err := Get("GOOGLE_API_KEY"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get of an empty item = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	// security takes the value as an argument, secret-tool on stdin
+	f := newFakeStore(t, "darwin")
+	if err := Set("TAVILY_API_KEY", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"security", "add-generic-password", "-U", "-s", "kasa", "-a", "TAVILY_API_KEY", "-w", "s3cret"}
+	if args := f.args(t); !slices.Equal(args, want) {
+		t.Errorf("ran %q, want %q", args, want)
+	}
+	if !ArgumentExposed() {
+		t.Error("ArgumentExposed() = false with security")
+	}
+
+	f = newFakeStore(t, "linux")
+	if err := Set("TAVILY_API_KEY", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"secret-tool", "store", "--label", "kasa TAVILY_API_KEY", "service", "kasa", "key", "TAVILY_API_KEY"}
+	if args := f.args(t); !slices.Equal(args, want) {
+		t.Errorf("ran %q, want %q", args, want)
+	}
+	stdin, _ := os.ReadFile(f.stdin)
+	if string(stdin) != "s3cret" {
+		t.Errorf("stdin = %q, want the value", stdin)
+	}
+	if ArgumentExposed() {
+		t.Error("ArgumentExposed() = true with secret-tool")
+	}
+
+	// A failure reports the tool's output
+	f.stdout, f.exit = "no Secret Service running", 1
+	if err := Set("TAVILY_API_KEY", "s3cret"); err == nil || !strings.Contains(err.Error(), "no Secret Service running") {
+		t.Errorf("Set = %v, want the tool's output", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		platform string
+		want     []string
+	}{
+		{"darwin", []string{"security", "delete-generic-password", "-s", "kasa", "-a", "SLACK_BOT_TOKEN"}},
+		{"linux", []string{"secret-tool", "clear", "service", "kasa", "key", "SLACK_BOT_TOKEN"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			f := newFakeStore(t, tt.platform)
+			if err := Delete("SLACK_BOT_TOKEN"); err != nil {
+				t.Fatal(err)
+			}
+			if args := f.args(t); !slices.Equal(args, tt.want) {
+				t.Errorf("ran %q, want %q", args, tt.want)
+			}
+			f.exit = 1
+			if err := Delete("SLACK_BOT_TOKEN"); err == nil {
+				t.Error("Delete succeeded though the tool failed")
+			}
+		})
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	f := newFakeStore(t, "windows")
+	if _, err := Get("GOOGLE_API_KEY"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Get on windows = %v, want ErrUnsupported", err)
+	}
+
+	// The tool isn't installed
+	f = newFakeStore(t, "linux")
+	lookPath = func(name string) (string, error) { return "", exec.ErrNotFound }
+	if err := Set("GOOGLE_API_KEY", "s3cret"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Set without secret-tool = %v, want ErrUnsupported", err)
+	}
+	if err := Delete("GOOGLE_API_KEY"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Delete without secret-tool = %v, want ErrUnsupported", err)
+	}
+	if len(f.cmds) != 0 {
+		t.Errorf("ran %d commands", len(f.cmds))
+	}
+}
@@ -3,26 +3,39 @@ package main
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/charmbracelet/glamour"
 	"github.com/joho/godotenv"
+	"github.com/perbu/kasa/agents"
+	"github.com/perbu/kasa/budget"
+	"github.com/perbu/kasa/integrations/slack"
 	"github.com/perbu/kasa/manifest"
+	"github.com/perbu/kasa/metrics"
 	"github.com/perbu/kasa/repl"
+	"github.com/perbu/kasa/server"
+	"github.com/perbu/kasa/telemetry"
 	"github.com/perbu/kasa/tools"
+	"github.com/perbu/kasa/tracing"
+	"google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
-	"google.golang.org/adk/model/gemini"
 	"google.golang.org/adk/runner"
 	"google.golang.org/adk/session"
 	"google.golang.org/adk/tool"
-	"google.golang.org/genai"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 )
@@ -34,8 +47,23 @@ func main() {
 	prompt := flag.String("prompt", "", "Run a single prompt and exit (non-interactive mode)")
 	debug := flag.Bool("debug", false, "Enable debug output")
 	noTools := flag.Bool("no-tools", false, "Run without tools (for testing)")
+	noCache := flag.Bool("no-cache", false, "Read every resource from the API server instead of the informer cache, and never reuse tool results")
+	output := flag.String("output", "text", "Output format for non-interactive mode: text or json")
+	configPath := flag.String("config", os.Getenv("KASA_CONFIG"), "Config file (default $KASA_CONFIG, ./config.yaml or ~/.config/kasa/config.yaml)")
+	profile := flag.String("profile", os.Getenv("KASA_PROFILE"), "Named profile from config.yaml to apply (default $KASA_PROFILE)")
+	listen := flag.String("listen", "127.0.0.1:8080", "Listen address for 'kasa serve'; other than loopback requires $KASA_API_TOKEN")
+	as := flag.String("as", "", "User or ServiceAccount (system:serviceaccount:<namespace>:<name>) to impersonate for all API calls")
+	var asGroups []string
+	flag.Func("as-group", "Group to impersonate; repeat for more than one (requires -as)", func(group string) error {
+		asGroups = append(asGroups, group)
+		return nil
+	})
 	flag.Parse()
 
+	if *output != "text" && *output != "json" {
+		log.Fatalf("Invalid -output %q: must be text or json", *output)
+	}
+
 	// Load .env file (optional, won't error if missing)
 	if err := godotenv.Load(); err != nil {
 		if *debug {
@@ -43,23 +71,99 @@ func main() {
 		}
 	}
 
-	cfg, err := loadConfig("config.yaml")
+	if flag.Arg(0) == "auth" {
+		os.Exit(runAuthCommand(flag.Args()[1:]))
+	}
+	if flag.Arg(0) == "stats" {
+		os.Exit(runStatsCommand(flag.Args()[1:]))
+	}
+	if flag.Arg(0) == "init" {
+		os.Exit(runInitCommand(*configPath, flag.Args()[1:]))
+	}
+
+	// Fall back to the OS keychain for keys not in the environment or .env
+	loadKeychainSecrets(*debug)
+
+	path, err := findConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	cfg, err := loadConfig(path, *profile)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Impersonation flags override the config file
+	if *as != "" {
+		cfg.Kubernetes.As = *as
+	}
+	if len(asGroups) > 0 {
+		cfg.Kubernetes.AsGroups = asGroups
+	}
+
+	if flag.Arg(0) == "config" {
+		os.Exit(runConfigCommand(cfg, flag.Args()[1:]))
+	}
+	if flag.Arg(0) == "export" {
+		os.Exit(runExportCommand(cfg, flag.Args()[1:]))
+	}
+
+	// Fail fast on configuration problems instead of mysterious runtime errors
+	issues := validateConfig(cfg, runsAgent(flag.Arg(0)))
+	for _, issue := range issues {
+		if issue.Fatal || *debug {
+			fmt.Fprintln(os.Stderr, issue)
+		}
+	}
+	if hasFatal(issues) {
+		fmt.Fprintf(os.Stderr, "Run 'kasa config validate' after fixing %s or your environment.\n", path)
+		os.Exit(1)
+	}
+
+	// Opt-in OpenTelemetry tracing of agent runs, tool calls and API requests
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, version)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil && *debug {
+			log.Printf("Flushing traces: %v", err)
+		}
+	}()
+
 	// Initialize Kubernetes client
-	clientset, dynamicClient, err := initKubeClient(cfg.Kubernetes.Kubeconfig, cfg.Kubernetes.Context)
+	impersonate := rest.ImpersonationConfig{UserName: cfg.Kubernetes.As, Groups: cfg.Kubernetes.AsGroups}
+	loadRESTConfig := func() (*rest.Config, error) {
+		return loadKubeConfig(cfg.Kubernetes.Kubeconfig, cfg.Kubernetes.Context, impersonate)
+	}
+	restConfig, err := loadRESTConfig()
+	if err != nil {
+		log.Fatalf("Failed to initialize Kubernetes client: %v", err)
+	}
+	if cfg.Tracing.Enabled {
+		restConfig.Wrap(tracing.Transport)
+	}
+	// Prometheus metrics when running headless
+	var kasaMetrics *metrics.Metrics
+	if flag.Arg(0) == "serve" || flag.Arg(0) == "sync" {
+		kasaMetrics = metrics.New(cfg.Metrics)
+		restConfig.Wrap(kasaMetrics.Transport)
+	}
+	// Expired OIDC or exec plugin credentials are refreshed from the
+	// kubeconfig instead of failing every call for the rest of the session
+	credentials := tools.NewCredentialRefresher(loadRESTConfig)
+	clientset, dynamicClient, err := initKubeClient(restConfig, cfg.Kubernetes.API, credentials)
 	if err != nil {
 		log.Fatalf("Failed to initialize Kubernetes client: %v", err)
 	}
+	if *debug && impersonate.UserName != "" {
+		log.Printf("Impersonating %s (groups %v)", impersonate.UserName, impersonate.Groups)
+	}
 
 	// Initialize manifest manager
-	manifestDir := cfg.Deployments.Directory
-	if manifestDir == "" {
-		manifestDir = "~/.kasa/deployments"
-	}
-	manifestMgr, err := manifest.NewManager(manifestDir)
+	manifestMgr, err := manifest.NewManager(manifestDirectory(cfg))
 	if err != nil {
 		log.Fatalf("Failed to initialize manifest manager: %v", err)
 	}
@@ -85,22 +189,125 @@ func main() {
 
 	// Initialize tools
 	kubeTools := tools.NewKubeTools(clientset, dynamicClient, manifestMgr, jinaAPIKey, tavilyAPIKey)
+	kubeTools.SetRESTConfig(restConfig)
+	kubeTools.SetCredentialRefresher(credentials)
+	kubeTools.SetMaxResultBytes(cfg.Agent.MaxToolResultBytes)
+	kubeTools.SetParallelism(cfg.Agent.ParallelTools)
+	kubeTools.SetTimeouts(cfg.Kubernetes.API.Timeouts)
+	if cfg.Tracing.Enabled {
+		kubeTools.Use(tracing.Tools)
+	}
+	if kasaMetrics != nil {
+		kubeTools.Use(kasaMetrics.Tools)
+	}
+	if *debug {
+		kubeTools.SetToolTimer(func(t tools.ToolTiming) {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Tool %s\n", t)
+		})
+	}
 
-	// Get API key from environment
-	apiKey := os.Getenv("GOOGLE_API_KEY")
-	if apiKey == "" {
-		log.Fatalf("GOOGLE_API_KEY environment variable not set")
+	// Optional Prometheus for query_prometheus
+	if cfg.Integrations.Prometheus.URL != "" {
+		token := cfg.Integrations.Prometheus.BearerToken
+		if token == "" {
+			token = os.Getenv("PROMETHEUS_BEARER_TOKEN")
+		}
+		kubeTools.SetPrometheus(cfg.Integrations.Prometheus.URL, token)
 	}
 
-	ctx := context.Background()
+	// Optional Trivy for inspect_image vulnerability scans
+	if trivy := cfg.Integrations.Trivy; trivy.Server != "" || trivy.Binary != "" {
+		kubeTools.SetTrivy(trivy.Server, trivy.Binary)
+	}
 
-	// Create Gemini model for ADK
-	geminiModel, err := gemini.NewModel(ctx, cfg.Agent.Model, &genai.ClientConfig{
-		APIKey:  apiKey,
-		Backend: genai.BackendGeminiAPI,
-	})
+	// Deploy images by digest so re-pushed tags don't change what runs
+	kubeTools.SetPinDigests(cfg.Deployments.PinDigests)
+
+	// Team-specific tools declared in the config
+	if err := kubeTools.SetCustomTools(cfg.CustomTools); err != nil {
+		log.Fatalf("Invalid custom_tools: %v", err)
+	}
+
+	// Force mutating tools into server-side dry-run where configured
+	if policy := cfg.Safety.EnforceDryRun; !policy.IsEmpty() {
+		dryClientset, dryDynamic, err := tools.NewDryRunClients(restConfig, credentials)
+		if err != nil {
+			log.Fatalf("Failed to initialize dry-run clients: %v", err)
+		}
+		kubeTools.SetDryRunPolicy(policy, dryClientset, dryDynamic)
+	}
+
+	// Refuse mutations outside the configured maintenance windows
+	if err := kubeTools.SetMaintenanceWindows(cfg.Safety.MaintenanceWindows, tools.WindowOverrideLogPath()); err != nil {
+		log.Fatalf("Invalid maintenance windows: %v", err)
+	}
+
+	// Confine every tool to the allowed namespaces
+	if err := kubeTools.SetNamespacePolicy(cfg.Kubernetes.Namespaces); err != nil {
+		log.Fatalf("Invalid namespace policy: %v", err)
+	}
+
+	// Refuse changes to protected resources, even in approved plans
+	if err := kubeTools.SetProtectedResources(cfg.Safety.ProtectedResources); err != nil {
+		log.Fatalf("Invalid protected resources: %v", err)
+	}
+
+	// Refuse manifests that break the cluster's enforced admission policies
+	if err := kubeTools.SetPolicies(cfg.Policies); err != nil {
+		log.Fatalf("Invalid policies: %v", err)
+	}
+
+	// Record every mutating tool call, including refused and dry-run ones
+	kubeTools.SetAuditLog(tools.AuditLogPath())
+
+	// Optional Slack integration for plan approvals and notifications
+	var slackClient *slack.Client
+	if cfg.Integrations.Slack.Enabled() {
+		slackClient = slack.New(cfg.Integrations.Slack, kubeTools.IsMutatingName)
+	}
+
+	// Headless drift watcher; doesn't need the model
+	if flag.Arg(0) == "watch" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		code := runWatch(ctx, cfg, dynamicClient, kubeTools, manifestMgr, slackClient)
+		stop()
+		os.Exit(code)
+	}
+
+	// Headless reconciler; doesn't need the model either
+	if flag.Arg(0) == "sync" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		code := runSync(ctx, cfg, flag.Args()[1:], dynamicClient, kubeTools, manifestMgr, slackClient, kasaMetrics)
+		stop()
+		os.Exit(code)
+	}
+
+	// Re-run the changes of an exported transcript; doesn't need the model
+	if flag.Arg(0) == "replay" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		code := runReplay(ctx, flag.Args()[1:], kubeTools, currentCluster(cfg.Kubernetes.Kubeconfig, cfg.Kubernetes.Context))
+		stop()
+		os.Exit(code)
+	}
+
+	// Serve repeated reads of pods, deployments, services and events from
+	// informers instead of the API server
+	if !*noCache && !cfg.Cache.Disabled {
+		resourceCache := tools.NewResourceCache(clientset, cfg.Cache.TTL)
+		defer resourceCache.Stop()
+		kubeTools.SetResourceCache(resourceCache)
+		kubeTools.SetResultCacheTTL(cfg.Cache.ResultTTL)
+	}
+
+	// Cancel in-flight agent runs on SIGINT/SIGTERM so tools and session
+	// state get a chance to wind down instead of dying mid-write.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Create the model on the configured backend
+	llm, err := cfg.newModel(ctx)
 	if err != nil {
-		log.Fatalf("Failed to create Gemini model: %v", err)
+		log.Fatalf("Failed to create model: %v", err)
 	}
 
 	// Create agent
@@ -108,38 +315,100 @@ func main() {
 	if !*noTools {
 		agentTools = kubeTools.All()
 	} else if *debug {
-		fmt.Println("[DEBUG] Running without tools")
+		fmt.Fprintln(os.Stderr, "[DEBUG] Running without tools")
 	}
 
 	// Generate dynamic tool documentation and inject into system prompt
-	toolDocs := kubeTools.GenerateToolDocs()
-	systemPrompt := strings.Replace(cfg.Prompts.System, "{{TOOL_DOCS}}", toolDocs, 1)
+	// Local models get a compact list instead
+	formatToolDocs := tools.FormatToolDocs
+	if cfg.Agent.compactTools() {
+		formatToolDocs = tools.FormatCompactToolDocs
+	}
+	toolDocs := formatToolDocs(kubeTools.All())
+	cluster := currentCluster(cfg.Kubernetes.Kubeconfig, cfg.Kubernetes.Context)
+	systemPrompt, err := renderSystemPrompt(cfg, toolDocs, cluster)
+	if err != nil {
+		log.Fatalf("Failed to build system prompt: %v", err)
+	}
 
 	// In interactive mode, run drift scan and inject results into system prompt
-	isInteractive := *prompt == ""
+	serveMode := flag.Arg(0) == "serve"
+	isInteractive := *prompt == "" && !serveMode
 	var scanResults *tools.DriftScanResults
+	var driftContext string
 	if isInteractive {
-		progress := func(current, total int, namespace, name, kind string) {
-			fmt.Fprintf(os.Stderr, "\rDrift scan: checking %s/%s/%s (%d/%d)...", namespace, name, kind, current+1, total)
+		progress := func(done, total int, namespace, name, kind string) {
+			fmt.Fprintf(os.Stderr, "\r\033[KDrift scan: %d/%d checked (%s/%s/%s)", done, total, namespace, name, kind)
 		}
-		scanResults, err = tools.RunDriftScan(ctx, dynamicClient, manifestMgr, progress)
+		scanResults, err = tools.RunDriftScan(ctx, dynamicClient, kubeTools.Resolver(), manifestMgr, progress)
 		fmt.Fprintf(os.Stderr, "\r\033[K")
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: drift scan failed: %v\n", err)
 		} else if scanResults != nil {
-			systemPrompt += tools.FormatDriftContext(scanResults)
+			kubeTools.RecordDriftScan(scanResults)
+			driftContext = tools.FormatDriftContext(scanResults)
+			systemPrompt += driftContext
+			if slackClient != nil {
+				slackClient.DriftDetected(scanResults)
+			}
 		}
 	}
 
 	agentConfig := llmagent.Config{
 		Name:        cfg.Agent.Name,
 		Description: "Kubernetes deployment assistant",
-		Model:       geminiModel,
+		Model:       llm,
 		Instruction: systemPrompt,
 		Tools:       agentTools,
 	}
 
-	agt, err := llmagent.New(agentConfig)
+	// Run independent read-only tool calls concurrently
+	if !*noTools {
+		agentConfig.AfterModelCallbacks = append(agentConfig.AfterModelCallbacks, kubeTools.PrefetchReadOnlyCalls)
+	}
+
+	// Token budget and context compaction
+	usageTracker := budget.NewTracker(cfg.Budget, cfg.Agent.Model)
+	if path := budget.UsagePath(); path != "" {
+		if err := usageTracker.LoadDaily(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: daily usage not kept: %v\n", err)
+		}
+	}
+	addBudgetCallbacks(&agentConfig, usageTracker, budget.NewCompactor(cfg.Budget, llm, usageTracker))
+
+	// Opt-in anonymous usage statistics
+	recorder := telemetry.New(cfg.Telemetry, version)
+	defer recorder.Close()
+	go recorder.Run(ctx)
+	addTelemetryCallbacks(&agentConfig, recorder)
+	addMetricsCallbacks(&agentConfig, kasaMetrics)
+
+	// Specialized agents behind a coordinator, unless one agent does it all
+	var agt agent.Agent
+	var agentModes *agents.Router
+	if cfg.Agent.Mode == agentModeSingle || *noTools {
+		agt, err = llmagent.New(agentConfig)
+	} else {
+		mode := agents.ModeAuto
+		if cfg.Agent.Mode != "" {
+			mode, _ = agents.ParseMode(cfg.Agent.Mode) // checked by validateConfig
+		}
+		agentModes = agents.NewRouter(mode)
+		prompts := make(map[agents.Mode]string)
+		for name, p := range cfg.Prompts.Agents {
+			prompts[agents.Mode(name)] = p
+		}
+		agt, err = agents.New(agents.Config{
+			Name:     cfg.Agent.Name,
+			Template: agentConfig,
+			Tools:    agentTools,
+			Instruction: func(ts []tool.Tool) (string, error) {
+				p, err := renderSystemPrompt(cfg, formatToolDocs(ts), cluster)
+				return p + driftContext, err
+			},
+			Prompts: prompts,
+		}, agentModes)
+	}
 	if err != nil {
 		log.Fatalf("Failed to create agent: %v", err)
 	}
@@ -165,17 +434,63 @@ func main() {
 		log.Fatalf("Failed to create session: %v", err)
 	}
 
+	// Headless server mode: expose the same runner over HTTP
+	if serveMode {
+		// The API can approve plans, so it is only left open on loopback
+		token := os.Getenv("KASA_API_TOKEN")
+		if token == "" && !server.Loopback(*listen) {
+			log.Fatalf("Listening on %s requires KASA_API_TOKEN, the bearer token API clients must send", *listen)
+		}
+		srv := server.New(ctx, r, sessionService, "kasa")
+		srv.SetToken(token)
+		srv.SetDryRunConfirmer(kubeTools)
+		srv.SetMetrics(kasaMetrics)
+		srv.SetUsage(usageTracker)
+		if slackClient != nil {
+			srv.SetNotifier(slackClient)
+			srv.Handle("POST /integrations/slack/interactions", slackClient.InteractionHandler(srv))
+		}
+		fmt.Fprintf(os.Stderr, "Kasa %s serving on http://%s (model: %s)\n", strings.TrimSpace(version), *listen, cfg.Agent.Model)
+		if err := srv.ListenAndServe(ctx, *listen); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server error: %v", err)
+		}
+		return
+	}
+
 	// Create REPL instance
 	replInstance := repl.New(r, *debug)
+	replInstance.SetDryRunConfirmer(kubeTools)
+	replInstance.SetUsageReporter(usageTracker)
+	replInstance.SetAgentModes(agentModes)
+	replInstance.SetSessionService(sessionService)
+	replInstance.SetCompleter(kubeTools)
+	replInstance.SetManifestEditor(kubeTools)
+	replInstance.SetEventWatcher(kubeTools)
+	replInstance.SetAlerts(cfg.Alerts)
+	replInstance.SetKubeContext(cluster.Context)
+	credentials.SetPrompt(replInstance.PromptReauthentication)
+	if slackClient != nil {
+		replInstance.SetNotifier(slackClient)
+	}
 
 	// Non-interactive mode (no approval workflow - runs directly)
 	if !isInteractive {
+		if *output == "json" {
+			// Keep stdout a clean JSON stream; diagnostics go to stderr.
+			if *debug {
+				fmt.Fprintf(os.Stderr, "Model: %s | Tools: %d | Deployments folder: %s\n", cfg.Agent.Model, len(kubeTools.All()), manifestMgr.BaseDir())
+			}
+			if err := replInstance.RunSinglePromptJSON(ctx, *prompt, os.Stdout); err != nil {
+				exitOnError(ctx, err)
+			}
+			return
+		}
 		if *debug {
 			fmt.Printf("Model: %s | Tools: %d | Deployments folder: %s\n", cfg.Agent.Model, len(kubeTools.All()), manifestMgr.BaseDir())
 			fmt.Printf("Prompt: %s\n\n", *prompt)
 		}
 		if err := replInstance.RunSinglePrompt(ctx, *prompt); err != nil {
-			log.Fatalf("Error: %v", err)
+			exitOnError(ctx, err)
 		}
 		return
 	}
@@ -194,34 +509,111 @@ func main() {
 	}
 }
 
-// initKubeClient initializes a Kubernetes clientset and dynamic client.
-func initKubeClient(kubeconfig, kubecontext string) (*kubernetes.Clientset, dynamic.Interface, error) {
-	// Use default kubeconfig path if not specified
-	if kubeconfig == "" {
-		if home := homedir.HomeDir(); home != "" {
-			kubeconfig = filepath.Join(home, ".kube", "config")
+// exitOnError exits after a failed prompt run, distinguishing signal
+// cancellation (exit status 130) from real failures.
+func exitOnError(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "Interrupted.")
+		os.Exit(130)
+	}
+	log.Fatalf("Error: %v", err)
+}
+
+// loadKubeConfig builds the client configuration from the kubeconfig,
+// with optional context override, or in a pod without one from its service
+// account. If impersonate names a user, every request is made as that user
+// and groups.
+func loadKubeConfig(kubeconfig, kubecontext string, impersonate rest.ImpersonationConfig) (*rest.Config, error) {
+	if inCluster(kubeconfig) {
+		config, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, fmt.Errorf("building in-cluster config: %w", err)
 		}
+		config.Impersonate = impersonate
+		return config, nil
 	}
 
-	// Build config with optional context override
-	loadingRules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig}
+	loadingRules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfigPath(kubeconfig)}
 	configOverrides := &clientcmd.ConfigOverrides{}
 	if kubecontext != "" {
 		configOverrides.CurrentContext = kubecontext
 	}
+	configOverrides.AuthInfo.Impersonate = impersonate.UserName
+	configOverrides.AuthInfo.ImpersonateGroups = impersonate.Groups
 
 	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
 		loadingRules, configOverrides).ClientConfig()
 	if err != nil {
-		return nil, nil, fmt.Errorf("building kubeconfig: %w", err)
+		return nil, fmt.Errorf("building kubeconfig: %w", err)
+	}
+	return config, nil
+}
+
+// kubeconfigPath returns kubeconfig, or the default kubeconfig path if it
+// is empty.
+func kubeconfigPath(kubeconfig string) string {
+	if kubeconfig == "" {
+		if home := homedir.HomeDir(); home != "" {
+			kubeconfig = filepath.Join(home, ".kube", "config")
+		}
+	}
+	return kubeconfig
+}
+
+// inCluster reports whether kasa runs in a pod without a kubeconfig, and so
+// uses the pod's service account.
+func inCluster(kubeconfig string) bool {
+	if kubeconfig != "" || os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return false
+	}
+	_, err := os.Stat(kubeconfigPath(""))
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// runsAgent reports whether the subcommand runs the agent. watch, sync and
+// replay only use the cluster and the stored manifests.
+func runsAgent(command string) bool {
+	switch command {
+	case "watch", "sync", "replay":
+		return false
+	}
+	return true
+}
+
+// currentCluster returns the context kasa uses and its cluster, as far as
+// the kubeconfig tells.
+func currentCluster(kubeconfig, kubecontext string) clusterInfo {
+	raw, err := clientcmd.LoadFromFile(kubeconfigPath(kubeconfig))
+	if err != nil {
+		return clusterInfo{Context: kubecontext}
+	}
+	if kubecontext == "" {
+		kubecontext = raw.CurrentContext
+	}
+	info := clusterInfo{Context: kubecontext}
+	if c, ok := raw.Contexts[kubecontext]; ok {
+		info.Cluster = c.Cluster
+	}
+	return info
+}
+
+// initKubeClient initializes a Kubernetes clientset and dynamic client that
+// share a rate limit and retry throttled requests as configured by api, and
+// retry with credentials from creds when theirs expire.
+func initKubeClient(config *rest.Config, api tools.APIOptions, creds *tools.CredentialRefresher) (*kubernetes.Clientset, dynamic.Interface, error) {
+	tools.ConfigureAPIClient(config, api)
+
+	httpClient, err := creds.HTTPClient(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating HTTP client: %w", err)
 	}
 
-	clientset, err := kubernetes.NewForConfig(config)
+	clientset, err := kubernetes.NewForConfigAndClient(config, httpClient)
 	if err != nil {
 		return nil, nil, fmt.Errorf("creating kubernetes client: %w", err)
 	}
 
-	dynamicClient, err := dynamic.NewForConfig(config)
+	dynamicClient, err := dynamic.NewForConfigAndClient(config, httpClient)
 	if err != nil {
 		return nil, nil, fmt.Errorf("creating dynamic client: %w", err)
 	}
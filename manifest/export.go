@@ -0,0 +1,187 @@
+package manifest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Export formats.
+const (
+	ExportYAML = "yaml" // one multi-document YAML file
+	ExportTar  = "tar"  // a gzipped tarball with the store's layout
+	ExportHelm = "helm" // a gzipped Helm chart with the manifests as templates
+)
+
+// ExportFormats lists the formats Export writes.
+var ExportFormats = []string{ExportYAML, ExportTar, ExportHelm}
+
+// exportKindOrder is the order manifests are written in, so applying a
+// bundle creates what others refer to first. Other kinds follow.
+var exportKindOrder = []string{
+	"namespace", "serviceaccount", "role", "rolebinding", "configmap", "secret",
+	"persistentvolumeclaim", "service", "deployment", "statefulset", "daemonset",
+	"cronjob", "job", "poddisruptionbudget", "horizontalpodautoscaler",
+	"networkpolicy", "ingress", "gateway", "httproute", "grpcroute", "certificate",
+}
+
+// ExportOptions selects what Export writes.
+type ExportOptions struct {
+	Namespace      string
+	App            string // empty exports every app in the namespace
+	Format         string // one of ExportFormats, default ExportYAML
+	IncludeSecrets bool   // Secrets are left out unless set
+}
+
+// ExportResult lists what Export wrote and left out.
+type ExportResult struct {
+	Name      string         // of the bundle: the app, or the namespace
+	Manifests []ManifestInfo // in the order written
+	Skipped   []ManifestInfo // Secrets, unless IncludeSecrets is set
+}
+
+// Export writes the stored manifests of an app, or of a namespace, to w as
+// a bundle for people who don't use kasa.
+func (m *Manager) Export(w io.Writer, opts ExportOptions) (*ExportResult, error) {
+	if opts.Namespace == "" {
+		return nil, fmt.Errorf("namespace is required")
+	}
+	if opts.Format == "" {
+		opts.Format = ExportYAML
+	}
+	if !slices.Contains(ExportFormats, opts.Format) {
+		return nil, fmt.Errorf("unknown format %q (use %s)", opts.Format, strings.Join(ExportFormats, ", "))
+	}
+
+	infos, err := m.ListManifests(opts.Namespace, opts.App)
+	if err != nil {
+		return nil, err
+	}
+	result := &ExportResult{Name: opts.App}
+	if result.Name == "" {
+		result.Name = opts.Namespace
+	}
+	for _, info := range infos {
+		if info.Type == "secret" && !opts.IncludeSecrets {
+			result.Skipped = append(result.Skipped, info)
+			continue
+		}
+		result.Manifests = append(result.Manifests, info)
+	}
+	if len(result.Manifests) == 0 {
+		if opts.App != "" {
+			return nil, fmt.Errorf("no manifests stored for %s/%s", opts.Namespace, opts.App)
+		}
+		return nil, fmt.Errorf("no manifests stored in namespace %s", opts.Namespace)
+	}
+	slices.SortStableFunc(result.Manifests, func(a, b ManifestInfo) int {
+		if d := exportRank(a.Type) - exportRank(b.Type); d != 0 {
+			return d
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	contents := make([][]byte, len(result.Manifests))
+	for i, info := range result.Manifests {
+		if contents[i], err = m.ReadManifest(info.Namespace, info.App, info.Type); err != nil {
+			return nil, err
+		}
+	}
+
+	switch opts.Format {
+	case ExportYAML:
+		err = writeBundle(w, result.Manifests, contents)
+	case ExportTar:
+		err = writeTarball(w, result.Name, func(add func(name string, content []byte) error) error {
+			for i, info := range result.Manifests {
+				if err := add(path.Join(info.Namespace, info.App, info.Type+".yaml"), contents[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	case ExportHelm:
+		err = writeTarball(w, result.Name, func(add func(name string, content []byte) error) error {
+			if err := add("Chart.yaml", helmChart(result.Name)); err != nil {
+				return err
+			}
+			if err := add("values.yaml", []byte("# Values for the templates. The manifests were exported as they are,\n# so none are used yet.\n")); err != nil {
+				return err
+			}
+			for i, info := range result.Manifests {
+				name := info.Type + ".yaml"
+				if opts.App == "" {
+					name = info.App + "-" + name
+				}
+				if err := add(path.Join("templates", name), contents[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("writing %s export: %w", opts.Format, err)
+	}
+	return result, nil
+}
+
+// exportRank returns the position of a kind in exportKindOrder.
+func exportRank(resourceType string) int {
+	if i := slices.Index(exportKindOrder, resourceType); i >= 0 {
+		return i
+	}
+	return len(exportKindOrder)
+}
+
+// writeBundle writes the manifests as one multi-document YAML, each
+// document headed by the file it came from.
+func writeBundle(w io.Writer, infos []ManifestInfo, contents [][]byte) error {
+	var b bytes.Buffer
+	for i, info := range infos {
+		fmt.Fprintf(&b, "---\n# Source: %s\n", info.Path)
+		content := bytes.TrimPrefix(bytes.TrimSpace(contents[i]), []byte("---\n"))
+		b.Write(content)
+		b.WriteByte('\n')
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writeTarball writes a gzipped tarball whose files, added by fill, are
+// under a directory called name.
+func writeTarball(w io.Writer, name string, fill func(add func(name string, content []byte) error) error) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	add := func(file string, content []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    path.Join(name, file),
+			Mode:    0644,
+			Size:    int64(len(content)),
+			ModTime: now,
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(content)
+		return err
+	}
+	if err := fill(add); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// helmChart returns the Chart.yaml of an exported chart.
+func helmChart(name string) []byte {
+	return fmt.Appendf(nil, "apiVersion: v2\nname: %s\ndescription: Manifests of %s, exported from kasa\ntype: application\nversion: 0.1.0\n", name, name)
+}
@@ -0,0 +1,153 @@
+package manifest
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CommitInfo describes the last commit that touched a manifest.
+type CommitInfo struct {
+	Hash    string    `json:"hash"`
+	Date    time.Time `json:"date"`
+	Message string    `json:"message"`
+}
+
+// Head returns the abbreviated hash of the current commit, or "" if the
+// repository has none yet.
+func (m *Manager) Head() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", "--short", "HEAD")
+	cmd.Dir = m.baseDir
+	output, err := cmd.Output()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return "", nil
+		}
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ReadManifestAt returns the content of a manifest file as of a commit.
+func (m *Manager) ReadManifestAt(commit, namespace, app, resourceType string) ([]byte, error) {
+	path := filepath.Join(namespace, app, resourceType+".yaml")
+	cmd := exec.Command("git", "show", commit+":"+filepath.ToSlash(path))
+	cmd.Dir = m.baseDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("manifest %s not found at %s", path, commit)
+	}
+	return output, nil
+}
+
+// LastCommits returns the most recent commit for every manifest path
+// (relative to baseDir) in a single walk of the history. Paths that were
+// never committed are absent.
+func (m *Manager) LastCommits() (map[string]CommitInfo, error) {
+	commits := make(map[string]CommitInfo)
+
+	// A repository without commits has no history to report
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", "HEAD")
+	cmd.Dir = m.baseDir
+	if err := cmd.Run(); err != nil {
+		return commits, nil
+	}
+
+	cmd = exec.Command("git", "log", "--no-renames", "--name-only", "--format=%x00%h%x00%cI%x00%s")
+	cmd.Dir = m.baseDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %w", err)
+	}
+
+	var current CommitInfo
+	for line := range strings.SplitSeq(string(output), "\n") {
+		if header, ok := strings.CutPrefix(line, "\x00"); ok {
+			parts := strings.SplitN(header, "\x00", 3)
+			if len(parts) != 3 {
+				continue
+			}
+			date, _ := time.Parse(time.RFC3339, parts[1])
+			current = CommitInfo{Hash: parts[0], Date: date, Message: parts[2]}
+			continue
+		}
+		if line == "" || current.Hash == "" {
+			continue
+		}
+		// Newest first, so the first commit seen for a path is its last
+		if _, seen := commits[line]; !seen {
+			commits[line] = current
+		}
+	}
+	return commits, nil
+}
+
+// UncommittedPaths returns the paths (relative to baseDir) with staged,
+// unstaged or untracked changes, mapped to their two-letter git status
+// (e.g. "A ", " M", "??").
+func (m *Manager) UncommittedPaths() (map[string]string, error) {
+	cmd := exec.Command("git", "status", "--porcelain", "--untracked-files=all")
+	cmd.Dir = m.baseDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git status failed: %w", err)
+	}
+
+	paths := make(map[string]string)
+	for line := range strings.SplitSeq(string(output), "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		path := line[3:]
+		// Renames are reported as "old -> new"
+		if _, after, ok := strings.Cut(path, " -> "); ok {
+			path = after
+		}
+		paths[path] = line[:2]
+	}
+	return paths, nil
+}
+
+// Commit is a commit in the manifest history and the files it changed.
+type Commit struct {
+	CommitInfo
+	Files []string `json:"files"` // relative to baseDir
+}
+
+// History returns the commits since the given time that changed the
+// manifests of an app, newest first.
+func (m *Manager) History(namespace, app string, since time.Time) ([]Commit, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", "HEAD")
+	cmd.Dir = m.baseDir
+	if err := cmd.Run(); err != nil {
+		return nil, nil
+	}
+
+	cmd = exec.Command("git", "log", "--no-renames", "--name-only", "--format=%x00%h%x00%cI%x00%s",
+		"--since="+since.Format(time.RFC3339), "--", filepath.Join(namespace, app))
+	cmd.Dir = m.baseDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %w", err)
+	}
+
+	var commits []Commit
+	for line := range strings.SplitSeq(string(output), "\n") {
+		if header, ok := strings.CutPrefix(line, "\x00"); ok {
+			parts := strings.SplitN(header, "\x00", 3)
+			if len(parts) != 3 {
+				continue
+			}
+			date, _ := time.Parse(time.RFC3339, parts[1])
+			commits = append(commits, Commit{CommitInfo: CommitInfo{Hash: parts[0], Date: date, Message: parts[2]}})
+			continue
+		}
+		if line != "" && len(commits) > 0 {
+			last := &commits[len(commits)-1]
+			last.Files = append(last.Files, line)
+		}
+	}
+	return commits, nil
+}
@@ -8,9 +8,15 @@ import (
 	"strings"
 )
 
+// ClusterNamespace is the pseudo-namespace under which manifests for
+// cluster-scoped resources (ClusterRoles, GatewayClasses, ClusterIssuers, ...)
+// are stored: <baseDir>/_cluster/<app>/<type>.yaml.
+const ClusterNamespace = "_cluster"
+
 // Manager handles manifest file storage and git operations.
 type Manager struct {
 	baseDir string
+	dryRun  bool // report changes without writing, deleting, committing or pushing
 }
 
 // ManifestInfo contains metadata about a manifest file.
@@ -53,6 +59,17 @@ func (m *Manager) BaseDir() string {
 	return m.baseDir
 }
 
+// DryRun returns a manager for the same directory that reports what it would
+// write or delete without touching the directory, git index or remote.
+func (m *Manager) DryRun() *Manager {
+	return &Manager{baseDir: m.baseDir, dryRun: true}
+}
+
+// IsDryRun reports whether the manager was created by DryRun.
+func (m *Manager) IsDryRun() bool {
+	return m.dryRun
+}
+
 // EnsureGitInit ensures the base directory is a git repository.
 // If .git/ doesn't exist, it runs git init.
 func (m *Manager) EnsureGitInit() error {
@@ -77,8 +94,12 @@ func (m *Manager) EnsureGitInit() error {
 // The file is saved to <baseDir>/<namespace>/<appName>/<resourceType>.yaml
 // Returns the path to the saved file.
 func (m *Manager) SaveManifest(namespace, appName, resourceType string, content []byte) (string, error) {
-	// Create directory structure
 	dir := filepath.Join(m.baseDir, namespace, appName)
+	if m.dryRun {
+		return filepath.Join(dir, resourceType+".yaml"), nil
+	}
+
+	// Create directory structure
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return "", fmt.Errorf("creating manifest directory: %w", err)
 	}
@@ -119,6 +140,10 @@ func (m *Manager) stageFile(path string) error {
 // Commit creates a git commit with the given message.
 // Only commits if there are staged changes.
 func (m *Manager) Commit(message string) error {
+	if m.dryRun {
+		return nil
+	}
+
 	// Check if there are staged changes
 	cmd := exec.Command("git", "diff", "--cached", "--quiet")
 	cmd.Dir = m.baseDir
@@ -232,6 +257,10 @@ func (m *Manager) ReadManifest(namespace, app, resourceType string) ([]byte, err
 // If resourceType is empty, deletes all manifests for the app.
 // Returns the list of deleted file paths.
 func (m *Manager) DeleteManifest(namespace, app, resourceType string) ([]string, error) {
+	if m.dryRun {
+		return m.pendingDeletions(namespace, app, resourceType)
+	}
+
 	var deleted []string
 
 	if resourceType != "" {
@@ -298,6 +327,27 @@ func (m *Manager) DeleteManifest(namespace, app, resourceType string) ([]string,
 	return deleted, nil
 }
 
+// pendingDeletions lists the manifests DeleteManifest would remove.
+func (m *Manager) pendingDeletions(namespace, app, resourceType string) ([]string, error) {
+	manifests, err := m.ListManifests(namespace, app)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, mf := range manifests {
+		if resourceType == "" || mf.Type == resourceType {
+			paths = append(paths, mf.Path)
+		}
+	}
+	if len(paths) == 0 {
+		if resourceType != "" {
+			return nil, fmt.Errorf("manifest not found: %s", filepath.Join(namespace, app, resourceType+".yaml"))
+		}
+		return nil, fmt.Errorf("app directory not found: %s/%s", namespace, app)
+	}
+	return paths, nil
+}
+
 // stageDeletion stages a file deletion in git.
 func (m *Manager) stageDeletion(relPath string) error {
 	cmd := exec.Command("git", "add", relPath)
@@ -364,7 +414,7 @@ func (m *Manager) HasRemote() bool {
 // If no remote is configured, this is a no-op.
 // Returns an error if fast-forward is not possible (diverged history).
 func (m *Manager) Pull() error {
-	if !m.HasRemote() {
+	if m.dryRun || !m.HasRemote() {
 		return nil
 	}
 
@@ -380,7 +430,7 @@ func (m *Manager) Pull() error {
 // Push pushes the current branch to the remote.
 // If no remote is configured, this is a no-op.
 func (m *Manager) Push() error {
-	if !m.HasRemote() {
+	if m.dryRun || !m.HasRemote() {
 		return nil
 	}
 
@@ -412,6 +462,17 @@ func (m *Manager) DeleteNamespace(namespace string) ([]string, error) {
 		return nil, nil // No manifests to delete
 	}
 
+	if m.dryRun {
+		manifests, err := m.ListManifests(namespace, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, mf := range manifests {
+			deleted = append(deleted, mf.Path)
+		}
+		return deleted, nil
+	}
+
 	// Walk the namespace directory and delete all yaml files
 	err := filepath.Walk(nsDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -0,0 +1,235 @@
+// Package metrics exposes Prometheus metrics of kasa running headless
+// ('kasa serve' and 'kasa sync'): tool calls by outcome, model calls and
+// token usage, plan decisions, Kubernetes API requests by status code and
+// sync passes, so operators can alert when the agent starts failing or
+// burning tokens.
+//
+// Metrics are off unless enabled in config.yaml. Labels carry tool names,
+// HTTP methods and status codes, never arguments or resource names.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/perbu/kasa/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+)
+
+// DefaultListen is where 'kasa sync' serves /metrics unless metrics.listen
+// is set.
+const DefaultListen = ":9090"
+
+// Config configures the metrics endpoint (metrics in config.yaml).
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+	// Listen is the address 'kasa sync' serves /metrics on (default
+	// :9090). 'kasa serve' serves it on its API listener.
+	Listen string `yaml:"listen"`
+}
+
+// Validate checks the listen address.
+func (c Config) Validate() error {
+	if c.Listen == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		return fmt.Errorf("listen %q must be host:port or :port", c.Listen)
+	}
+	return nil
+}
+
+// Metrics holds kasa's metrics. A nil *Metrics records nothing, so callers
+// don't need to check whether metrics are enabled.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	toolCalls   *prometheus.CounterVec
+	modelCalls  *prometheus.CounterVec
+	tokens      *prometheus.CounterVec
+	decisions   *prometheus.CounterVec
+	apiRequests *prometheus.CounterVec
+
+	syncPasses       *prometheus.CounterVec
+	syncManifests    *prometheus.GaugeVec
+	syncRemediations *prometheus.CounterVec
+}
+
+// New returns the metrics for cfg, or nil if they are disabled.
+func New(cfg Config) *Metrics {
+	if !cfg.Enabled {
+		return nil
+	}
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kasa_tool_calls_total",
+			Help: "Tool calls by tool and outcome (success or error).",
+		}, []string{"tool", "outcome"}),
+		modelCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kasa_model_calls_total",
+			Help: "Model calls by outcome (success or error).",
+		}, []string{"outcome"}),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kasa_model_tokens_total",
+			Help: "Tokens used by model calls, by type (input or output).",
+		}, []string{"type"}),
+		decisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kasa_plan_decisions_total",
+			Help: "Decisions on plans and dry-run changes, by kind (plan or dry_run) and decision (approved or rejected).",
+		}, []string{"kind", "decision"}),
+		apiRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kasa_kubernetes_requests_total",
+			Help: "Kubernetes API requests by method and status code; code is \"error\" when no response arrived.",
+		}, []string{"method", "code"}),
+		syncPasses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kasa_sync_passes_total",
+			Help: "Reconcile passes of kasa sync by outcome (ok or failed).",
+		}, []string{"outcome"}),
+		syncManifests: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kasa_sync_manifests",
+			Help: "Stored manifests by state (in_sync, drifted, missing or error) after the last reconcile pass.",
+		}, []string{"state"}),
+		syncRemediations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kasa_sync_remediations_total",
+			Help: "Resources kasa sync re-applied, by outcome (applied or failed).",
+		}, []string{"outcome"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.toolCalls, m.modelCalls, m.tokens, m.decisions, m.apiRequests,
+		m.syncPasses, m.syncManifests, m.syncRemediations,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ListenAndServe serves /metrics on addr until ctx is cancelled.
+func (m *Metrics) ListenAndServe(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+// Tools is middleware (see tools.KubeTools.Use) that counts tool calls by
+// outcome. A result with an error counts as an error.
+func (m *Metrics) Tools(t tools.ToolInfo, next tools.RunFunc) tools.RunFunc {
+	if m == nil {
+		return next
+	}
+	return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		result, err := next(ctx, args)
+		outcome := "success"
+		if err != nil || result["error"] != nil {
+			outcome = "error"
+		}
+		m.toolCalls.WithLabelValues(t.Name, outcome).Inc()
+		return result, err
+	}
+}
+
+// ModelResponse records a model call and the tokens it used. Streamed
+// partial responses carry no usage and aren't calls of their own.
+func (m *Metrics) ModelResponse(resp *model.LLMResponse, err error) {
+	if m == nil {
+		return
+	}
+	if err != nil || (resp != nil && resp.ErrorCode != "") {
+		m.modelCalls.WithLabelValues("error").Inc()
+		return
+	}
+	if resp == nil || resp.Partial {
+		return
+	}
+	m.modelCalls.WithLabelValues("success").Inc()
+	if u := resp.UsageMetadata; u != nil {
+		m.tokens.WithLabelValues("input").Add(float64(u.PromptTokenCount))
+		m.tokens.WithLabelValues("output").Add(float64(u.CandidatesTokenCount))
+	}
+}
+
+// PlanDecision records the approval or rejection of a plan ("plan") or of
+// dry-run changes ("dry_run").
+func (m *Metrics) PlanDecision(kind string, approved bool) {
+	if m == nil {
+		return
+	}
+	decision := "rejected"
+	if approved {
+		decision = "approved"
+	}
+	m.decisions.WithLabelValues(kind, decision).Inc()
+}
+
+// SyncPass records a reconcile pass: the stored manifests by state, and
+// how many resources were re-applied or failed to. A nil states means the
+// drift scan itself failed.
+func (m *Metrics) SyncPass(states map[string]int, applied, failed int) {
+	if m == nil {
+		return
+	}
+	if states == nil {
+		m.syncPasses.WithLabelValues("failed").Inc()
+		return
+	}
+	m.syncPasses.WithLabelValues("ok").Inc()
+	for state, n := range states {
+		m.syncManifests.WithLabelValues(state).Set(float64(n))
+	}
+	m.syncRemediations.WithLabelValues("applied").Add(float64(applied))
+	m.syncRemediations.WithLabelValues("failed").Add(float64(failed))
+}
+
+// Transport wraps the transport of the Kubernetes clients (see
+// rest.Config.Wrap) to count API requests by status code.
+func (m *Metrics) Transport(rt http.RoundTripper) http.RoundTripper {
+	if m == nil {
+		return rt
+	}
+	return roundTripper{next: rt, requests: m.apiRequests}
+}
+
+type roundTripper struct {
+	next     http.RoundTripper
+	requests *prometheus.CounterVec
+}
+
+func (t roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	t.requests.WithLabelValues(req.Method, code).Inc()
+	return resp, err
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/perbu/kasa/tools"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+func TestDisabledMetricsRecordNothing(t *testing.T) {
+	m := New(Config{})
+	if m != nil {
+		t.Fatal("expected nil metrics when disabled")
+	}
+	// None of these may panic
+	m.ModelResponse(&model.LLMResponse{}, nil)
+	m.PlanDecision("plan", true)
+	m.SyncPass(nil, 0, 0)
+	next := func(ctx tool.Context, args map[string]any) (map[string]any, error) { return nil, nil }
+	if _, err := m.Tools(tools.ToolInfo{Name: "list_pods"}, next)(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if m.Transport(http.DefaultTransport) != http.DefaultTransport {
+		t.Error("expected the transport to be left alone")
+	}
+}
+
+func TestToolCalls(t *testing.T) {
+	m := New(Config{Enabled: true})
+	results := []map[string]any{{"pods": []any{}}, {"error": "forbidden"}, {"pods": []any{}}}
+	run := m.Tools(tools.ToolInfo{Name: "list_pods"}, func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		result := results[0]
+		results = results[1:]
+		return result, nil
+	})
+	for range 3 {
+		run(nil, map[string]any{})
+	}
+	if got := testutil.ToFloat64(m.toolCalls.WithLabelValues("list_pods", "success")); got != 2 {
+		t.Errorf("expected 2 successful calls, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.toolCalls.WithLabelValues("list_pods", "error")); got != 1 {
+		t.Errorf("expected 1 failed call, got %v", got)
+	}
+}
+
+func TestModelResponse(t *testing.T) {
+	m := New(Config{Enabled: true})
+	usage := &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 1200, CandidatesTokenCount: 80}
+	m.ModelResponse(&model.LLMResponse{UsageMetadata: usage}, nil)
+	m.ModelResponse(&model.LLMResponse{Partial: true}, nil)
+	m.ModelResponse(nil, errors.New("quota exceeded"))
+
+	if got := testutil.ToFloat64(m.tokens.WithLabelValues("input")); got != 1200 {
+		t.Errorf("expected 1200 input tokens, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.tokens.WithLabelValues("output")); got != 80 {
+		t.Errorf("expected 80 output tokens, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.modelCalls.WithLabelValues("success")); got != 1 {
+		t.Errorf("expected 1 successful model call, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.modelCalls.WithLabelValues("error")); got != 1 {
+		t.Errorf("expected 1 failed model call, got %v", got)
+	}
+}
+
+func TestTransportAndHandler(t *testing.T) {
+	m := New(Config{Enabled: true})
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	client := &http.Client{Transport: m.Transport(http.DefaultTransport)}
+	for _, path := range []string{"/", "/missing"} {
+		resp, err := client.Get(api.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	m.PlanDecision("plan", false)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`kasa_kubernetes_requests_total{code="200",method="GET"} 1`,
+		`kasa_kubernetes_requests_total{code="404",method="GET"} 1`,
+		`kasa_plan_decisions_total{decision="rejected",kind="plan"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in the metrics, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for listen, valid := range map[string]bool{"": true, ":9090": true, "127.0.0.1:9464": true, "9090": false} {
+		if err := (Config{Enabled: true, Listen: listen}).Validate(); (err == nil) != valid {
+			t.Errorf("listen %q: got %v, want valid=%v", listen, err, valid)
+		}
+	}
+}
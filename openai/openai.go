@@ -0,0 +1,374 @@
+// Package openai implements the ADK model interface for servers with an
+// OpenAI-compatible chat completions API, such as llama.cpp's server, vLLM
+// or Ollama, so kasa can run against a model inside the cluster's network.
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// defaultTimeout bounds one completion; local models on CPUs are slow.
+const defaultTimeout = 10 * time.Minute
+
+// Config configures the connection to the server.
+type Config struct {
+	// BaseURL is the API root, e.g. http://localhost:8080/v1; requests go
+	// to BaseURL/chat/completions.
+	BaseURL string
+	// APIKey is sent as a bearer token if set.
+	APIKey string
+	// Timeout bounds one completion (default 10m).
+	Timeout time.Duration
+	// Declaration, if set, rewrites each function declaration before it is
+	// sent, e.g. to shrink the schemas for a model with a small context.
+	Declaration func(*genai.FunctionDeclaration) *genai.FunctionDeclaration
+}
+
+// APIError is an error status returned by the server.
+type APIError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("model server returned %s: %s", e.Status, e.Message)
+}
+
+type openAIModel struct {
+	name   string
+	cfg    Config
+	client *http.Client
+}
+
+// NewModel returns a [model.LLM] that sends requests for modelName to the
+// server at cfg.BaseURL.
+func NewModel(modelName string, cfg Config) model.LLM {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &openAIModel{
+		name:   modelName,
+		cfg:    cfg,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name returns the model name.
+func (m *openAIModel) Name() string {
+	return m.name
+}
+
+// GenerateContent calls the model. Responses are not streamed: the
+// complete response is returned once, also when stream is set.
+func (m *openAIModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		yield(m.generate(ctx, req))
+	}
+}
+
+// chatRequest is the body of a chat completions request.
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Tools       []chatTool    `json:"tools,omitempty"`
+	Temperature *float32      `json:"temperature,omitempty"`
+	TopP        *float32      `json:"top_p,omitempty"`
+	MaxTokens   int32         `json:"max_tokens,omitempty"`
+	Stop        []string      `json:"stop,omitempty"`
+}
+
+type chatMessage struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []toolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	Name       string     `json:"name,omitempty"`
+}
+
+type toolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatTool struct {
+	Type     string       `json:"type"`
+	Function chatFunction `json:"function"`
+}
+
+type chatFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+// chatResponse is the part of a chat completions response kasa uses.
+type chatResponse struct {
+	Choices []struct {
+		Message      chatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int32 `json:"prompt_tokens"`
+		CompletionTokens int32 `json:"completion_tokens"`
+		TotalTokens      int32 `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// generate sends one chat completions request and converts the first
+// choice.
+func (m *openAIModel) generate(ctx context.Context, req *model.LLMRequest) (*model.LLMResponse, error) {
+	body, err := json.Marshal(m.chatRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+	url := strings.TrimSuffix(m.cfg.BaseURL, "/") + "/chat/completions"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if m.cfg.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+m.cfg.APIKey)
+	}
+
+	resp, err := m.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call model: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	var chat chatResponse
+	jsonErr := json.Unmarshal(data, &chat)
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Status: resp.Status, Message: truncate(string(data), 500)}
+		if jsonErr == nil && chat.Error != nil {
+			apiErr.Message = chat.Error.Message
+		}
+		return nil, apiErr
+	}
+	if jsonErr != nil {
+		return nil, fmt.Errorf("decoding response: %w", jsonErr)
+	}
+	if len(chat.Choices) == 0 {
+		return nil, fmt.Errorf("empty response")
+	}
+	return llmResponse(chat), nil
+}
+
+// chatRequest converts an ADK request: the system instruction becomes the
+// system message, function calls become tool calls and function responses
+// tool messages.
+func (m *openAIModel) chatRequest(req *model.LLMRequest) chatRequest {
+	out := chatRequest{Model: m.name}
+	cfg := req.Config
+	if cfg == nil {
+		cfg = &genai.GenerateContentConfig{}
+	}
+	if system := contentText(cfg.SystemInstruction); system != "" {
+		out.Messages = append(out.Messages, chatMessage{Role: "system", Content: system})
+	}
+	for _, content := range req.Contents {
+		out.Messages = append(out.Messages, chatMessages(content)...)
+	}
+	// Like the Gemini model, end with a user turn so the model answers
+	if len(out.Messages) == 0 || out.Messages[len(out.Messages)-1].Role == "assistant" {
+		out.Messages = append(out.Messages, chatMessage{Role: "user", Content: "Continue processing previous requests as instructed. Exit or provide a summary if no more outputs are needed."})
+	}
+
+	for _, t := range cfg.Tools {
+		if t == nil {
+			continue
+		}
+		for _, decl := range t.FunctionDeclarations {
+			if m.cfg.Declaration != nil {
+				decl = m.cfg.Declaration(decl)
+			}
+			fn := chatFunction{Name: decl.Name, Description: decl.Description, Parameters: decl.ParametersJsonSchema}
+			if decl.Parameters != nil {
+				fn.Parameters = jsonSchema(decl.Parameters)
+			}
+			if fn.Parameters == nil {
+				fn.Parameters = map[string]any{"type": "object", "properties": map[string]any{}}
+			}
+			out.Tools = append(out.Tools, chatTool{Type: "function", Function: fn})
+		}
+	}
+
+	out.Temperature = cfg.Temperature
+	out.TopP = cfg.TopP
+	out.MaxTokens = cfg.MaxOutputTokens
+	out.Stop = cfg.StopSequences
+	return out
+}
+
+// chatMessages converts one content. A user content with function
+// responses becomes one tool message per response, followed by a user
+// message for any text.
+func chatMessages(content *genai.Content) []chatMessage {
+	if content == nil {
+		return nil
+	}
+	if content.Role == genai.RoleModel {
+		msg := chatMessage{Role: "assistant"}
+		var text []string
+		for _, part := range content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				args, _ := json.Marshal(part.FunctionCall.Args)
+				call := toolCall{ID: part.FunctionCall.ID, Type: "function"}
+				call.Function.Name = part.FunctionCall.Name
+				call.Function.Arguments = string(args)
+				msg.ToolCalls = append(msg.ToolCalls, call)
+			case part.Text != "" && !part.Thought:
+				text = append(text, part.Text)
+			}
+		}
+		msg.Content = strings.Join(text, "\n")
+		return []chatMessage{msg}
+	}
+
+	var msgs []chatMessage
+	var text []string
+	for _, part := range content.Parts {
+		switch {
+		case part.FunctionResponse != nil:
+			response, _ := json.Marshal(part.FunctionResponse.Response)
+			msgs = append(msgs, chatMessage{
+				Role:       "tool",
+				ToolCallID: part.FunctionResponse.ID,
+				Name:       part.FunctionResponse.Name,
+				Content:    string(response),
+			})
+		case part.Text != "":
+			text = append(text, part.Text)
+		}
+	}
+	if len(text) > 0 {
+		msgs = append(msgs, chatMessage{Role: "user", Content: strings.Join(text, "\n")})
+	}
+	return msgs
+}
+
+// llmResponse converts the first choice of a response.
+func llmResponse(chat chatResponse) *model.LLMResponse {
+	choice := chat.Choices[0]
+	content := &genai.Content{Role: genai.RoleModel}
+	if choice.Message.Content != "" {
+		content.Parts = append(content.Parts, genai.NewPartFromText(choice.Message.Content))
+	}
+	for i, call := range choice.Message.ToolCalls {
+		// Small models sometimes produce arguments that are not JSON; the
+		// tool then reports the missing arguments back to the model
+		args := map[string]any{}
+		if call.Function.Arguments != "" {
+			_ = json.Unmarshal([]byte(call.Function.Arguments), &args)
+		}
+		id := call.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", i)
+		}
+		content.Parts = append(content.Parts, &genai.Part{FunctionCall: &genai.FunctionCall{ID: id, Name: call.Function.Name, Args: args}})
+	}
+
+	resp := &model.LLMResponse{Content: content, FinishReason: finishReason(choice.FinishReason)}
+	if chat.Usage != nil {
+		resp.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:     chat.Usage.PromptTokens,
+			CandidatesTokenCount: chat.Usage.CompletionTokens,
+			TotalTokenCount:      chat.Usage.TotalTokens,
+		}
+	}
+	return resp
+}
+
+// finishReason maps an OpenAI finish reason to the Gemini one.
+func finishReason(reason string) genai.FinishReason {
+	switch reason {
+	case "stop", "tool_calls", "function_call":
+		return genai.FinishReasonStop
+	case "length":
+		return genai.FinishReasonMaxTokens
+	case "content_filter":
+		return genai.FinishReasonSafety
+	case "":
+		return genai.FinishReasonUnspecified
+	}
+	return genai.FinishReasonOther
+}
+
+// jsonSchema converts a Gemini schema to JSON Schema.
+func jsonSchema(s *genai.Schema) map[string]any {
+	out := map[string]any{}
+	if s.Type != "" {
+		out["type"] = strings.ToLower(string(s.Type))
+	}
+	if s.Description != "" {
+		out["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		out["enum"] = s.Enum
+	}
+	if s.Items != nil {
+		out["items"] = jsonSchema(s.Items)
+	}
+	if len(s.Properties) > 0 {
+		props := make(map[string]any, len(s.Properties))
+		for name, prop := range s.Properties {
+			props[name] = jsonSchema(prop)
+		}
+		out["properties"] = props
+	}
+	if len(s.Required) > 0 {
+		out["required"] = s.Required
+	}
+	if s.Nullable != nil && *s.Nullable {
+		out["nullable"] = true
+	}
+	return out
+}
+
+// contentText joins the text parts of a content.
+func contentText(content *genai.Content) string {
+	if content == nil {
+		return ""
+	}
+	var text []string
+	for _, part := range content.Parts {
+		if part.Text != "" {
+			text = append(text, part.Text)
+		}
+	}
+	return strings.Join(text, "\n")
+}
+
+// truncate shortens s to at most n bytes for error messages.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,103 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestGenerateContent(t *testing.T) {
+	var got chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("unexpected request %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Checking.","tool_calls":[{"id":"","type":"function","function":{"name":"list_pods","arguments":"{\"namespace\":\"shop\"}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":120,"completion_tokens":15,"total_tokens":135}}`))
+	}))
+	defer server.Close()
+
+	shrink := func(decl *genai.FunctionDeclaration) *genai.FunctionDeclaration {
+		return &genai.FunctionDeclaration{Name: decl.Name, Parameters: decl.Parameters}
+	}
+	llm := NewModel("qwen2.5-coder", Config{BaseURL: server.URL + "/v1/", APIKey: "secret", Declaration: shrink})
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{
+			genai.NewContentFromText("What runs in shop?", genai.RoleUser),
+			{Role: genai.RoleModel, Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{ID: "c1", Name: "list_pods", Args: map[string]any{"namespace": "shop"}}}}},
+			{Role: genai.RoleUser, Parts: []*genai.Part{{FunctionResponse: &genai.FunctionResponse{ID: "c1", Name: "list_pods", Response: map[string]any{"count": 0}}}}},
+		},
+		Config: &genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText("You are Kasa.", genai.RoleUser),
+			Tools: []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{{
+				Name:        "list_pods",
+				Description: "List pods in a namespace.",
+				Parameters: &genai.Schema{
+					Type:       "object",
+					Properties: map[string]*genai.Schema{"namespace": {Type: genai.TypeString, Description: "The namespace"}},
+					Required:   []string{"namespace"},
+				},
+			}}}},
+		},
+	}
+
+	var resp *model.LLMResponse
+	for r, err := range llm.GenerateContent(context.Background(), req, true) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp = r
+	}
+
+	roles := make([]string, len(got.Messages))
+	for i, msg := range got.Messages {
+		roles[i] = msg.Role
+	}
+	if strings.Join(roles, ",") != "system,user,assistant,tool" || got.Model != "qwen2.5-coder" {
+		t.Fatalf("unexpected messages %+v", got)
+	}
+	if call := got.Messages[2].ToolCalls; len(call) != 1 || call[0].ID != "c1" || call[0].Function.Arguments != `{"namespace":"shop"}` {
+		t.Errorf("unexpected tool call %+v", call)
+	}
+	if tool := got.Messages[3]; tool.ToolCallID != "c1" || tool.Content != `{"count":0}` {
+		t.Errorf("unexpected tool message %+v", tool)
+	}
+	if len(got.Tools) != 1 || got.Tools[0].Function.Description != "" {
+		t.Fatalf("expected the shrunk declaration, got %+v", got.Tools)
+	}
+	params, _ := json.Marshal(got.Tools[0].Function.Parameters)
+	if string(params) != `{"properties":{"namespace":{"description":"The namespace","type":"string"}},"required":["namespace"],"type":"object"}` {
+		t.Errorf("unexpected parameters %s", params)
+	}
+
+	if resp.FinishReason != genai.FinishReasonStop || resp.UsageMetadata.PromptTokenCount != 120 || len(resp.Content.Parts) != 2 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	call := resp.Content.Parts[1].FunctionCall
+	if call == nil || call.ID == "" || call.Name != "list_pods" || call.Args["namespace"] != "shop" {
+		t.Errorf("unexpected function call %+v", call)
+	}
+}
+
+func TestGenerateContentError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"context length exceeded"}}`))
+	}))
+	defer server.Close()
+
+	llm := NewModel("llama", Config{BaseURL: server.URL})
+	for _, err := range llm.GenerateContent(context.Background(), &model.LLMRequest{}, false) {
+		if err == nil || !strings.Contains(err.Error(), "context length exceeded") {
+			t.Errorf("expected the server's error, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultRulesFile holds house rules loaded when prompts.rules is not set.
+const defaultRulesFile = "~/.kasa/rules.md"
+
+// promptPlaceholders are the variables the system prompt may contain.
+var promptPlaceholders = []string{
+	"{{TOOL_DOCS}}", "{{CLUSTER}}", "{{CONTEXT}}", "{{NAMESPACES}}", "{{CONVENTIONS}}", "{{HOUSE_RULES}}",
+}
+
+// clusterInfo describes the cluster kasa is connected to, for the prompt.
+type clusterInfo struct {
+	Context string // kubeconfig context
+	Cluster string // cluster of that context
+}
+
+// renderSystemPrompt fills in the placeholders of the system prompt.
+// Conventions and house rules are appended if the prompt has no
+// placeholder for them, so they reach the agent either way.
+func renderSystemPrompt(cfg *Config, toolDocs string, cluster clusterInfo) (string, error) {
+	rules, err := loadHouseRules(cfg.Prompts.Rules)
+	if err != nil {
+		return "", err
+	}
+	conventions := strings.TrimSpace(cfg.Prompts.Conventions)
+
+	namespaces := "all namespaces"
+	if !cfg.Kubernetes.Namespaces.IsEmpty() {
+		namespaces = cfg.Kubernetes.Namespaces.String()
+	}
+
+	prompt := cfg.Prompts.System
+	if conventions != "" && !strings.Contains(prompt, "{{CONVENTIONS}}") {
+		prompt = strings.TrimRight(prompt, "\n") + "\n\n## Conventions\n{{CONVENTIONS}}\n"
+	}
+	if rules != "" && !strings.Contains(prompt, "{{HOUSE_RULES}}") {
+		prompt = strings.TrimRight(prompt, "\n") + "\n\n## House Rules\n{{HOUSE_RULES}}\n"
+	}
+
+	return strings.NewReplacer(
+		"{{TOOL_DOCS}}", toolDocs,
+		"{{CLUSTER}}", orUnknown(cluster.Cluster),
+		"{{CONTEXT}}", orUnknown(cluster.Context),
+		"{{NAMESPACES}}", namespaces,
+		"{{CONVENTIONS}}", conventions,
+		"{{HOUSE_RULES}}", rules,
+	).Replace(prompt), nil
+}
+
+// loadHouseRules concatenates the rules files. Without any configured, the
+// default file is read if it exists.
+func loadHouseRules(files []string) (string, error) {
+	optional := len(files) == 0
+	if optional {
+		files = []string{defaultRulesFile}
+	}
+	var parts []string
+	for _, file := range files {
+		data, err := os.ReadFile(expandHome(file))
+		if err != nil {
+			if optional && os.IsNotExist(err) {
+				continue
+			}
+			return "", fmt.Errorf("reading house rules: %w", err)
+		}
+		if text := strings.TrimSpace(string(data)); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,199 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+	"google.golang.org/genai"
+)
+
+// Activity panel limits: tool calls listed outside a plan, and plan steps
+// listed around the current one.
+const (
+	maxActivityCalls = 6
+	maxActivitySteps = 10
+)
+
+// Tool call states in the activity panel.
+const (
+	callRunning = "running"
+	callDone    = "done"
+	callFailed  = "failed"
+)
+
+// toolCall is a tool call of the current agent run.
+type toolCall struct {
+	id, name, reason string
+	args             map[string]any
+	started          time.Time
+	elapsed          time.Duration
+	status           string
+	err              string
+}
+
+// activity records what the agent does during a run, for the panel above
+// the input.
+type activity struct {
+	calls     []toolCall
+	stepTimes map[int]time.Duration // duration of the call that finished a plan step
+	tokensIn  int64                 // summed over the run's model calls
+	tokensOut int64
+}
+
+// reset starts a new run.
+func (a *activity) reset() {
+	*a = activity{stepTimes: make(map[int]time.Duration)}
+}
+
+// start records a tool call.
+func (a *activity) start(call *genai.FunctionCall) {
+	a.calls = append(a.calls, toolCall{
+		id:      call.ID,
+		name:    call.Name,
+		reason:  extractReason(call.Args),
+		args:    call.Args,
+		started: time.Now(),
+		status:  callRunning,
+	})
+}
+
+// finish records the result of a tool call and returns the call, or nil if
+// it wasn't seen starting.
+func (a *activity) finish(resp *genai.FunctionResponse) *toolCall {
+	for i := len(a.calls) - 1; i >= 0; i-- {
+		c := &a.calls[i]
+		if c.status != callRunning || c.name != resp.Name || (c.id != "" && resp.ID != "" && c.id != resp.ID) {
+			continue
+		}
+		c.elapsed = time.Since(c.started)
+		c.status = callDone
+		if errVal, ok := resp.Response["error"]; ok && errVal != nil {
+			c.status = callFailed
+			c.err = fmt.Sprintf("%v", errVal)
+		}
+		return c
+	}
+	return nil
+}
+
+// arguments returns the arguments of the call, or nil for a call that
+// wasn't seen starting.
+func (c *toolCall) arguments() map[string]any {
+	if c == nil {
+		return nil
+	}
+	return c.args
+}
+
+// running returns the latest call still running, or nil.
+func (a *activity) running() *toolCall {
+	for i := len(a.calls) - 1; i >= 0; i-- {
+		if a.calls[i].status == callRunning {
+			return &a.calls[i]
+		}
+	}
+	return nil
+}
+
+// addUsage adds the tokens of a model call.
+func (a *activity) addUsage(usage *genai.GenerateContentResponseUsageMetadata) {
+	a.tokensIn += int64(usage.PromptTokenCount)
+	a.tokensOut += int64(usage.CandidatesTokenCount)
+}
+
+// buildActivityPanel renders the steps of the plan being executed, or the
+// run's latest tool calls, and the tokens used, above the status line.
+func (m *model) buildActivityPanel() string {
+	var lines []string
+	if m.journal != nil && m.state.Mode == ModeExecuting {
+		lines = m.planLines()
+	} else {
+		calls := m.activity.calls
+		if len(calls) > maxActivityCalls {
+			lines = append(lines, fmt.Sprintf("  … %d earlier tool calls", len(calls)-maxActivityCalls))
+			calls = calls[len(calls)-maxActivityCalls:]
+		}
+		for _, c := range calls {
+			lines = append(lines, "  "+formatCall(c))
+		}
+	}
+	if m.activity.tokensIn > 0 || m.activity.tokensOut > 0 {
+		lines = append(lines, fmt.Sprintf("  tokens this turn: %d↑ %d↓", m.activity.tokensIn, m.activity.tokensOut))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	for i, l := range lines {
+		if m.width > 0 {
+			lines[i] = ansi.Truncate(l, m.width-1, "...")
+		}
+	}
+	return statusStyle.Render(strings.Join(lines, "\n")) + "\n"
+}
+
+// planLines renders the plan's steps with their live state.
+func (m *model) planLines() []string {
+	j := m.journal
+	current := -1
+	if c := m.activity.running(); c != nil {
+		current = j.StepFor(c.name, c.args)
+	}
+	lines := []string{fmt.Sprintf("  Plan: %s (%s)", j.Description, j.Summary())}
+
+	// Show a window of steps around the first outstanding one
+	first := 0
+	if next := j.NextStep(); next > maxActivitySteps/2 {
+		first = min(next-maxActivitySteps/2, max(len(j.Steps)-maxActivitySteps, 0))
+	}
+	last := min(first+maxActivitySteps, len(j.Steps))
+	if first > 0 {
+		lines = append(lines, fmt.Sprintf("    … %d steps done", first))
+	}
+	for i := first; i < last; i++ {
+		s := j.Steps[i]
+		var state string
+		switch {
+		case i == current:
+			state = fmt.Sprintf("● running %s", formatElapsed(time.Since(m.activity.running().started)))
+		case s.Status == StepCompleted:
+			state = "✓ done"
+			if d, ok := m.activity.stepTimes[i]; ok {
+				state += " " + formatElapsed(d)
+			}
+		case s.Status == StepFailed:
+			state = "✗ failed: " + s.Error
+		default:
+			state = "○ pending"
+		}
+		lines = append(lines, fmt.Sprintf("    %d. %-24s %s", i+1, s.Action.Tool, state))
+	}
+	if last < len(j.Steps) {
+		lines = append(lines, fmt.Sprintf("    … %d more steps", len(j.Steps)-last))
+	}
+	return lines
+}
+
+// formatCall renders a tool call of the run.
+func formatCall(c toolCall) string {
+	var state string
+	switch c.status {
+	case callRunning:
+		state = "● running " + formatElapsed(time.Since(c.started))
+	case callFailed:
+		state = fmt.Sprintf("✗ failed after %s: %s", formatElapsed(c.elapsed), c.err)
+	default:
+		state = "✓ " + formatElapsed(c.elapsed)
+	}
+	line := fmt.Sprintf("%-24s %s", c.name, state)
+	if c.reason != "" {
+		line += " · " + c.reason
+	}
+	return line
+}
+
+// formatElapsed renders a duration to a tenth of a second.
+func formatElapsed(d time.Duration) string {
+	return d.Round(100 * time.Millisecond).String()
+}
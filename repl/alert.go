@@ -0,0 +1,84 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultAlertAfter is how long a tool call runs before its end is worth an
+// alert.
+const defaultAlertAfter = 30 * time.Second
+
+// AlertConfig controls how the REPL gets the user's attention when a long
+// tool call, such as wait_for_condition during a rollout, finishes while the
+// terminal is in the background.
+type AlertConfig struct {
+	// After is how long a tool call must have run (default 30s).
+	After time.Duration `yaml:"after"`
+	// Desktop also shows a desktop notification, with notify-send on Linux
+	// and osascript on macOS.
+	Desktop bool `yaml:"desktop"`
+	// Disabled turns off the bell and notifications.
+	Disabled bool `yaml:"disabled"`
+}
+
+// Validate checks for a negative duration.
+func (c AlertConfig) Validate() error {
+	if c.After < 0 {
+		return fmt.Errorf("after must not be negative (0 for the default of %s)", defaultAlertAfter)
+	}
+	return nil
+}
+
+// after returns the configured threshold or the default.
+func (c AlertConfig) after() time.Duration {
+	if c.After == 0 {
+		return defaultAlertAfter
+	}
+	return c.After
+}
+
+// toolAlert returns a command that alerts the user that a tool call ended,
+// if it ran long enough and the terminal isn't focused.
+func (m *model) toolAlert(c *toolCall) tea.Cmd {
+	if c == nil || m.alerts.Disabled || m.focused || c.elapsed < m.alerts.after() {
+		return nil
+	}
+	message := fmt.Sprintf("%s finished after %s", c.name, formatElapsed(c.elapsed))
+	if c.status == callFailed {
+		message = fmt.Sprintf("%s failed after %s", c.name, formatElapsed(c.elapsed))
+	}
+	desktop := m.alerts.Desktop
+	return func() tea.Msg {
+		alert(message, desktop)
+		return nil
+	}
+}
+
+// alert rings the terminal bell and sends an OSC 9 notification, which
+// terminals such as iTerm2, WezTerm, kitty and Windows Terminal show, and
+// optionally a desktop notification.
+func alert(message string, desktop bool) {
+	fmt.Fprintf(os.Stdout, "\a\x1b]9;kasa: %s\x07", message)
+	if !desktop {
+		return
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		quoted := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(message)
+		cmd = exec.Command("osascript", "-e", fmt.Sprintf(`display notification "%s" with title "kasa"`, quoted))
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.Command("notify-send", "kasa", message)
+	default:
+		return
+	}
+	// A missing notifier only costs the desktop notification
+	_ = cmd.Run()
+}
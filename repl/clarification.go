@@ -2,9 +2,12 @@ package repl
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
+	"google.golang.org/genai"
 )
 
 // RenderClarification renders a clarification to a string using glamour markdown rendering.
@@ -48,17 +51,115 @@ func buildClarificationMarkdown(c *Clarification) string {
 
 		if len(q.Options) > 0 {
 			for j, opt := range q.Options {
-				md.WriteString(fmt.Sprintf("  %c) %s\n", 'a'+rune(j), opt))
+				md.WriteString(fmt.Sprintf("%d. %s\n", j+1, opt))
 			}
 			md.WriteString("\n")
 		}
 	}
 
 	md.WriteString("---\n\n")
-	md.WriteString("Type your answers to continue.\n")
+	md.WriteString("Answer each question with an option number or in your own words · `/skip` leave the rest to the agent\n")
 	return md.String()
 }
 
+// Current returns the question awaiting an answer, or nil when all are
+// answered.
+func (c *Clarification) Current() *ClarificationQuestion {
+	if len(c.Answers) >= len(c.Questions) {
+		return nil
+	}
+	return &c.Questions[len(c.Answers)]
+}
+
+// Answer records input as the answer to the current question: the option
+// it numbers, or free text. It reports whether all questions are answered.
+func (c *Clarification) Answer(input string) bool {
+	q := c.Current()
+	if q == nil {
+		return true
+	}
+	answer := ClarificationAnswer{Question: q.Question, Answer: input}
+	if n, err := strconv.Atoi(strings.TrimSpace(input)); err == nil && n >= 1 && n <= len(q.Options) {
+		answer.Answer = q.Options[n-1]
+		answer.Option = n
+	}
+	c.Answers = append(c.Answers, answer)
+	return c.Current() == nil
+}
+
+// Skip leaves the unanswered questions to the agent.
+func (c *Clarification) Skip() {
+	for q := c.Current(); q != nil; q = c.Current() {
+		c.Answers = append(c.Answers, ClarificationAnswer{Question: q.Question, Skipped: true})
+	}
+}
+
+// ClarificationResponse returns the message that gives the agent the
+// answers: the response to its ask_clarification call, or a plain message
+// when the call had no ID.
+func ClarificationResponse(c *Clarification) *genai.Content {
+	if c.CallID == "" {
+		return genai.NewContentFromText(FormatClarificationAnswers(c), genai.RoleUser)
+	}
+	answers := make([]any, len(c.Answers))
+	for i, a := range c.Answers {
+		answer := map[string]any{"question": a.Question}
+		if a.Skipped {
+			answer["skipped"] = true
+		} else {
+			answer["answer"] = a.Answer
+		}
+		if a.Option > 0 {
+			answer["option"] = a.Option
+		}
+		answers[i] = answer
+	}
+	return &genai.Content{Role: genai.RoleUser, Parts: []*genai.Part{{
+		FunctionResponse: &genai.FunctionResponse{
+			ID:   c.CallID,
+			Name: "ask_clarification",
+			Response: map[string]any{
+				"status":  "answered",
+				"answers": answers,
+				"message": "The user answered. Skipped questions are left to your judgement.",
+			},
+		},
+	}}}
+}
+
+// FormatClarificationAnswers creates a prompt giving the agent the answers.
+func FormatClarificationAnswers(c *Clarification) string {
+	var sb strings.Builder
+	sb.WriteString("Answers to your clarification questions:\n")
+	for i, a := range c.Answers {
+		answer := a.Answer
+		if a.Skipped {
+			answer = "(no preference, use your judgement)"
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s %s\n", i+1, a.Question, answer))
+	}
+	return sb.String()
+}
+
+// answerClarification handles input while questions are pending.
+func (m *model) answerClarification(input string) tea.Cmd {
+	c := m.state.PendingClarification
+	if !c.Answer(input) {
+		m.println(fmt.Sprintf("%d. %s", len(c.Answers)+1, c.Current().Question))
+		m.updatePrompt()
+		return nil
+	}
+	return m.sendClarification()
+}
+
+// sendClarification gives the agent the answers to the pending questions.
+func (m *model) sendClarification() tea.Cmd {
+	c := m.state.PendingClarification
+	m.state.PendingClarification = nil
+	m.updatePrompt()
+	return m.startAgentWith(ClarificationResponse(c))
+}
+
 // ParseClarificationFromResponse extracts a Clarification from the ask_clarification tool args.
 func ParseClarificationFromResponse(args map[string]any) *Clarification {
 	contextStr, _ := args["context"].(string)
@@ -0,0 +1,252 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/perbu/kasa/agents"
+)
+
+// command is a command of the interactive REPL.
+type command struct {
+	name    string   // starts with a slash
+	aliases []string // words that run the command when typed on their own
+	args    string   // synopsis of the argument for /help, empty if it takes none
+	help    string
+	// complete returns the values tab completion offers for the argument
+	complete func(m *model) []string
+	// run handles the command; arg is the text after the name
+	run func(m *model, arg string) tea.Cmd
+}
+
+// commands is the registry of REPL commands, in the order /help lists them.
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "/approve", aliases: []string{"yes", "y"}, help: "Approve the pending plan or proposed manifest edit, or apply dry-run changes or an edited manifest", run: (*model).approve},
+		{name: "/reject", aliases: []string{"no", "n"}, help: "Reject the pending plan, dry-run changes or proposed manifest edit", run: (*model).reject},
+		{name: "/plan", help: "Show the pending plan again", run: (*model).showPlan},
+		{name: "/skip", help: "Leave the remaining clarification questions to the agent", run: (*model).skipClarification},
+		{name: "/resume", help: "Resume an interrupted plan from the first incomplete step", run: (*model).resume},
+		{name: "/discard", help: "Drop an interrupted plan", run: (*model).discard},
+		{name: "/mode", args: "[name]", help: "Show or switch the agent that handles messages", complete: modeNames, run: (*model).mode},
+		{name: "/usage", args: "[reset]", help: "Show tokens used and estimated cost, or clear them", complete: func(*model) []string { return []string{"reset"} }, run: (*model).showUsage},
+		{name: "/search", args: "<text>", help: "Search the session output (PgUp scrolls back)", run: (*model).searchOutput},
+		{name: "/edit", args: "[namespace/app/type]", help: "Edit a stored manifest in $EDITOR, or review the agent's proposed changes", complete: editManifestNames, run: (*model).editManifest},
+		{name: "/watch", args: "[namespace|all] [kind] | stop", help: "Show the cluster's Warning events above the prompt as they happen, or stop showing them", complete: watchArgs, run: (*model).watch},
+		{name: "/view", args: "[number]", help: "Show YAML from the agent's tools, highlighted (e opens it in $EDITOR)", run: (*model).view},
+		{name: "/export", args: "[file]", help: "Save the transcript as markdown, or JSON for a .json file", run: (*model).export},
+		{name: "/help", help: "List the commands", run: (*model).help},
+	}
+}
+
+// findCommand returns the command input runs and its argument. Aliases
+// only match input that is nothing but the alias.
+func findCommand(input string) (*command, string) {
+	name, arg, _ := strings.Cut(input, " ")
+	name = strings.ToLower(name)
+	for i := range commands {
+		c := &commands[i]
+		if c.name == name {
+			return c, strings.TrimSpace(arg)
+		}
+		for _, alias := range c.aliases {
+			if strings.EqualFold(input, alias) {
+				return c, ""
+			}
+		}
+	}
+	return nil, ""
+}
+
+// runCommand runs c, or prints its usage if it got an argument it doesn't take.
+func (m *model) runCommand(c *command, arg string) tea.Cmd {
+	if arg != "" && c.args == "" {
+		m.println("Usage: " + c.name)
+		return nil
+	}
+	return c.run(m, arg)
+}
+
+// println prints a line above the input area.
+func (m *model) println(s string) {
+	if m.program != nil {
+		m.program.Println(s)
+	}
+}
+
+func (m *model) approve(string) tea.Cmd {
+	if m.state.HasPendingConfirmation() {
+		confirmations := m.state.TakeConfirmations()
+		for _, err := range ApplyConfirmations(m.confirmer, confirmations) {
+			m.println(fmt.Sprintf("Warning: %v", err))
+		}
+		m.println("Confirmed. Applying for real...")
+		m.updatePrompt()
+		return m.startAgent(FormatConfirmationPrompt(confirmations))
+	}
+	if m.state.HasPendingPlan() {
+		// Deletions are confirmed by name before the plan runs
+		deletions, err := PlanDeletions(m.state.PendingPlan)
+		if err != nil {
+			m.println(deleteStyle.Render(fmt.Sprintf("Cannot approve: %v. /reject the plan and ask for one that names everything it deletes.", err)))
+			return nil
+		}
+		if len(deletions) > 0 {
+			m.deletions = &deletionCheck{deletions: deletions}
+			m.println(RenderDeletions(deletions))
+			m.updatePrompt()
+			return nil
+		}
+		return m.executePlan()
+	}
+	if m.editOffer != "" {
+		return m.applyEdit()
+	}
+	if m.edit != nil && m.edit.callID != "" {
+		return m.acceptEdit()
+	}
+	m.println("No pending plan to approve.")
+	return nil
+}
+
+// executePlan approves the pending plan and has the agent carry it out.
+func (m *model) executePlan() tea.Cmd {
+	plan := m.state.ApprovePlan()
+	m.println("Plan approved. Executing...")
+	m.journal = NewJournal(plan, m.kubeContext)
+	m.saveJournal()
+	return m.startAgent(FormatExecutionPrompt(plan))
+}
+
+func (m *model) reject(string) tea.Cmd {
+	m.deletions = nil
+	if m.state.HasPendingConfirmation() {
+		m.state.TakeConfirmations()
+		m.println("Dry-run changes were not applied.")
+		m.finishJournal()
+		m.state.Reset()
+		m.updatePrompt()
+		return nil
+	}
+	if m.state.HasPendingPlan() {
+		m.state.RejectPlan()
+		m.println("Plan rejected.")
+		m.updatePrompt()
+		return nil
+	}
+	if m.edit != nil && m.edit.callID != "" {
+		return m.declineEdit()
+	}
+	if m.edit != nil {
+		m.println(fmt.Sprintf("Dropped the edit of %s.", m.edit.path()))
+		m.dropEdit()
+		return nil
+	}
+	if m.editOffer != "" {
+		m.println(fmt.Sprintf("%s stays staged but is not applied.", m.editOffer))
+		m.editOffer = ""
+		m.updatePrompt()
+		return nil
+	}
+	m.println("No pending plan to reject.")
+	return nil
+}
+
+func (m *model) showPlan(string) tea.Cmd {
+	if m.state.HasPendingPlan() {
+		m.println(RenderPlan(m.state.PendingPlan))
+	} else {
+		m.println("No pending plan.")
+	}
+	return nil
+}
+
+func (m *model) skipClarification(string) tea.Cmd {
+	if !m.state.HasPendingClarification() {
+		m.println("No pending questions.")
+		return nil
+	}
+	m.state.PendingClarification.Skip()
+	return m.sendClarification()
+}
+
+func (m *model) resume(string) tea.Cmd {
+	if m.journal == nil {
+		m.println("No interrupted plan to resume.")
+		return nil
+	}
+	if err := m.journal.ResumableIn(m.kubeContext); err != nil {
+		m.println(fmt.Sprintf("Cannot resume: %v.", err))
+		return nil
+	}
+	m.println(fmt.Sprintf("Resuming plan: %s", m.journal.Summary()))
+	m.state.Mode = ModeExecuting
+	return m.startAgent(FormatResumePrompt(m.journal))
+}
+
+func (m *model) discard(string) tea.Cmd {
+	if m.journal == nil {
+		m.println("No interrupted plan to discard.")
+		return nil
+	}
+	m.journal.Remove()
+	m.journal = nil
+	m.println("Interrupted plan discarded.")
+	return nil
+}
+
+func (m *model) mode(arg string) tea.Cmd {
+	if arg == "" {
+		m.println(m.describeModes())
+		return nil
+	}
+	m.println(m.switchMode(arg))
+	m.updatePrompt()
+	return nil
+}
+
+func (m *model) showUsage(arg string) tea.Cmd {
+	m.println(handleUsageCommand(m.usage, strings.ToLower(arg)))
+	return nil
+}
+
+func (m *model) searchOutput(arg string) tea.Cmd {
+	if arg == "" {
+		m.println("Usage: /search <text>")
+		return nil
+	}
+	return m.openPager(arg)
+}
+
+func (m *model) export(arg string) tea.Cmd {
+	m.println(exportTranscript(m.baseCtx, m.sessions, arg))
+	return nil
+}
+
+func (m *model) help(string) tea.Cmd {
+	var sb strings.Builder
+	sb.WriteString("Commands:\n")
+	for _, c := range commands {
+		names := strings.Join(append([]string{strings.TrimSpace(c.name + " " + c.args)}, c.aliases...), ", ")
+		fmt.Fprintf(&sb, "  %-22s %s\n", names, c.help)
+	}
+	fmt.Fprintf(&sb, "  %-22s %s\n", "exit, quit", "Leave kasa")
+	sb.WriteString("Tab completes commands, namespaces, app names and kinds. PgUp scrolls back through the output. Ctrl+O shows or hides the activity panel while the agent works. Alt+Enter inserts a newline.")
+	m.println(sb.String())
+	return nil
+}
+
+// modeNames completes /mode.
+func modeNames(m *model) []string {
+	if m.modes == nil {
+		return nil
+	}
+	names := make([]string, len(agents.Modes))
+	for i, mode := range agents.Modes {
+		names[i] = string(mode)
+	}
+	return names
+}
@@ -0,0 +1,102 @@
+package repl
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxCompletionsShown limits the candidates printed when Tab is ambiguous.
+const maxCompletionsShown = 40
+
+// Completer supplies the cluster words Tab completes besides commands.
+type Completer interface {
+	Namespaces() []string
+	Apps() []string // from the manifest store
+	Kinds() []string
+}
+
+var commandName = regexp.MustCompile(`^/[a-z]+$`)
+
+// isCommandName reports whether word looks like a slash command rather than,
+// say, a path.
+func isCommandName(word string) bool {
+	return commandName.MatchString(word)
+}
+
+// complete completes the word at the end of the input: a command name, the
+// argument of a command, or a namespace, app name or kind anywhere in a
+// message. A unique match is filled in; otherwise the common prefix is, and
+// if there is none the candidates are listed.
+func (m *model) complete() {
+	value := m.textarea.Value()
+	start := strings.LastIndexAny(value, " \n") + 1
+	word := value[start:]
+
+	var candidates []string
+	first, _, hasArg := strings.Cut(value, " ")
+	switch {
+	case !hasArg && strings.HasPrefix(word, "/"):
+		for _, c := range commands {
+			candidates = append(candidates, c.name)
+		}
+	case hasArg && start == len(first)+1 && strings.HasPrefix(first, "/"):
+		if c, _ := findCommand(first); c != nil && c.complete != nil {
+			candidates = c.complete(m)
+		}
+	case word != "" && m.completer != nil:
+		candidates = slices.Concat(m.completer.Namespaces(), m.completer.Apps(), m.completer.Kinds())
+	}
+
+	matches := completions(candidates, word)
+	switch {
+	case len(matches) == 0:
+		return
+	case len(matches) == 1:
+		m.textarea.SetValue(value[:start] + matches[0] + " ")
+	default:
+		prefix := commonPrefix(matches)
+		if len(prefix) > len(word) {
+			m.textarea.SetValue(value[:start] + prefix)
+			break
+		}
+		shown := matches
+		if len(shown) > maxCompletionsShown {
+			shown = shown[:maxCompletionsShown]
+		}
+		line := strings.Join(shown, "  ")
+		if len(matches) > len(shown) {
+			line += "  ..."
+		}
+		m.println(line)
+	}
+	m.textarea.CursorEnd()
+	m.resizeTextarea()
+}
+
+// completions returns the distinct candidates that start with word,
+// ignoring case, sorted.
+func completions(candidates []string, word string) []string {
+	var matches []string
+	for _, c := range candidates {
+		if len(c) >= len(word) && strings.EqualFold(c[:len(word)], word) {
+			matches = append(matches, c)
+		}
+	}
+	slices.Sort(matches)
+	return slices.Compact(matches)
+}
+
+// commonPrefix returns the longest prefix shared by all words, ignoring
+// case, in the spelling of the first.
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		n := 0
+		for n < len(prefix) && n < len(w) && strings.EqualFold(prefix[n:n+1], w[n:n+1]) {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return prefix
+}
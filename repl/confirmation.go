@@ -0,0 +1,186 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+)
+
+// DryRunConfirmer grants permission to apply a change that was only dry-run
+// because it falls under the enforced dry-run policy.
+type DryRunConfirmer interface {
+	// ConfirmDryRun lets the next call to toolName in namespace with the
+	// arguments of the dry run, identified by key, apply for real.
+	ConfirmDryRun(toolName, namespace, key string)
+}
+
+// WindowOverrider grants exceptions to the maintenance window policy. A
+// DryRunConfirmer may also implement it.
+type WindowOverrider interface {
+	// GrantWindowOverride lets mutating tools change namespace outside its
+	// maintenance windows, recording the justification.
+	GrantWindowOverride(namespace, justification string) error
+}
+
+// ConfirmationWindowOverride marks a confirmation that asks the user to
+// approve a maintenance window override rather than a dry-run change.
+const ConfirmationWindowOverride = "window_override"
+
+// DryRunConfirmation is a dry-run tool call awaiting a second user
+// confirmation, or a request to override a maintenance window.
+type DryRunConfirmation struct {
+	Tool          string `json:"tool"`
+	Namespace     string `json:"namespace,omitempty"`
+	Key           string `json:"key,omitempty"` // confirmation_key of the dry run: the arguments confirmed
+	Message       string `json:"message,omitempty"`
+	Kind          string `json:"kind,omitempty"` // empty for dry-run, or ConfirmationWindowOverride
+	Justification string `json:"justification,omitempty"`
+}
+
+// IsWindowOverride reports whether c asks to override a maintenance window.
+func (c DryRunConfirmation) IsWindowOverride() bool {
+	return c.Kind == ConfirmationWindowOverride
+}
+
+// ParseDryRunConfirmation extracts a DryRunConfirmation from a tool response
+// that was forced into dry-run. Returns nil for ordinary responses.
+func ParseDryRunConfirmation(response map[string]any) *DryRunConfirmation {
+	if required, _ := response["confirmation_required"].(bool); !required {
+		return nil
+	}
+	c := &DryRunConfirmation{
+		Tool:      getString(response, "tool"),
+		Namespace: getString(response, "namespace"),
+		Key:       getString(response, "confirmation_key"),
+		Message:   getString(response, "message"),
+
+		Kind:          getString(response, "confirmation"),
+		Justification: getString(response, "justification"),
+	}
+	if c.Tool == "" {
+		return nil
+	}
+	return c
+}
+
+// ApplyConfirmations passes the user's approval of confirmations on to
+// confirmer. A window override that could not be recorded is not granted,
+// and its error is returned.
+func ApplyConfirmations(confirmer DryRunConfirmer, confirmations []DryRunConfirmation) []error {
+	if confirmer == nil {
+		return nil
+	}
+	var errs []error
+	for _, c := range confirmations {
+		if !c.IsWindowOverride() {
+			confirmer.ConfirmDryRun(c.Tool, c.Namespace, c.Key)
+			continue
+		}
+		if o, ok := confirmer.(WindowOverrider); ok {
+			if err := o.GrantWindowOverride(c.Namespace, c.Justification); err != nil {
+				errs = append(errs, fmt.Errorf("window override for %s not granted: %w", overrideScope(c.Namespace), err))
+			}
+		}
+	}
+	return errs
+}
+
+// RenderConfirmation renders pending dry-run confirmations using glamour.
+func RenderConfirmation(confirmations []DryRunConfirmation) string {
+	md := buildConfirmationMarkdown(confirmations)
+
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithStandardStyle("dark"),
+		glamour.WithWordWrap(80),
+	)
+	if err != nil {
+		return md
+	}
+
+	out, err := renderer.Render(md)
+	if err != nil {
+		return md
+	}
+	return out
+}
+
+// buildConfirmationMarkdown builds the markdown string for pending confirmations.
+func buildConfirmationMarkdown(confirmations []DryRunConfirmation) string {
+	var dryRuns, overrides []DryRunConfirmation
+	for _, c := range confirmations {
+		if c.IsWindowOverride() {
+			overrides = append(overrides, c)
+		} else {
+			dryRuns = append(dryRuns, c)
+		}
+	}
+
+	var md strings.Builder
+	if len(overrides) > 0 {
+		md.WriteString("# Maintenance Window Override\n\n")
+		md.WriteString("The agent asks to make changes **outside the maintenance window**:\n\n")
+		for _, c := range overrides {
+			md.WriteString(fmt.Sprintf("- %s: %s\n", overrideScope(c.Namespace), c.Justification))
+		}
+		md.WriteString("\nApproving lifts the window for one hour and records the justification.\n\n")
+	}
+	if len(dryRuns) > 0 {
+		md.WriteString("# Dry Run Only\n\n")
+		md.WriteString("These changes passed a server-side dry-run but were **not applied**:\n\n")
+	}
+	for _, c := range dryRuns {
+		if c.Namespace != "" {
+			md.WriteString(fmt.Sprintf("- `%s` in namespace `%s`\n", c.Tool, c.Namespace))
+		} else {
+			md.WriteString(fmt.Sprintf("- `%s`\n", c.Tool))
+		}
+	}
+	md.WriteString("\n---\n\n")
+	if len(dryRuns) == 0 {
+		md.WriteString("Type **yes** to approve the override, or **no** to stop.\n")
+	} else {
+		md.WriteString("Type **yes** to apply for real, or **no** to stop.\n")
+	}
+	return md.String()
+}
+
+// FormatConfirmationPrompt creates a prompt telling the agent the user
+// confirmed the dry-run changes and they may now be applied, or approved
+// overriding a maintenance window.
+func FormatConfirmationPrompt(confirmations []DryRunConfirmation) string {
+	var sb strings.Builder
+	var dryRuns []DryRunConfirmation
+	for _, c := range confirmations {
+		if c.IsWindowOverride() {
+			sb.WriteString(fmt.Sprintf("The user has APPROVED overriding the maintenance window of %s for one hour (justification: %s).\n", overrideScope(c.Namespace), c.Justification))
+		} else {
+			dryRuns = append(dryRuns, c)
+		}
+	}
+	if len(dryRuns) == 0 {
+		sb.WriteString("\nRetry the tool calls that were refused as outside the window with exactly the same parameters, then continue with any remaining steps of the approved plan.")
+		return sb.String()
+	}
+
+	if sb.Len() > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("The user has CONFIRMED applying the following dry-run changes for real:\n\n")
+	for i, c := range dryRuns {
+		if c.Namespace != "" {
+			sb.WriteString(fmt.Sprintf("%d. %s in namespace %s\n", i+1, c.Tool, c.Namespace))
+		} else {
+			sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, c.Tool))
+		}
+	}
+	sb.WriteString("\nCall each of these tools again with exactly the same parameters as the dry run, then continue with any remaining steps of the approved plan.")
+	return sb.String()
+}
+
+func overrideScope(namespace string) string {
+	if namespace == "" {
+		return "cluster-scoped resources"
+	}
+	return "namespace `" + namespace + "`"
+}
@@ -0,0 +1,147 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// deleteStyle is the red of the deletion summary.
+var deleteStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+// Deletion is something an approved plan would delete from the cluster,
+// worked out from the action's tool and parameters rather than from what
+// the agent wrote about it.
+type Deletion struct {
+	Step      int    // 1-based index of the action in the plan
+	What      string // kind, "namespace" or "app"
+	Name      string // what the user types to confirm
+	Namespace string // empty for cluster-scoped resources and namespaces
+	Effects   []string
+}
+
+// PlanDeletions returns what the plan's delete_resource, delete_namespace
+// and delete_manifest actions remove from the cluster. It returns an error
+// for a plan with deleting actions that don't name what they delete, such
+// as a delete_resource without a name: the agent would pick it only while
+// the plan runs, after the user confirmed the names.
+func PlanDeletions(plan *Plan) ([]Deletion, error) {
+	var deletions []Deletion
+	var unnamed []string
+	for i, a := range plan.Actions {
+		p := a.Parameters
+		str := func(key string) string {
+			s, _ := p[key].(string)
+			return s
+		}
+		// Boolean parameters default to true in all three tools
+		flag := func(key string) bool {
+			b, ok := p[key].(bool)
+			return !ok || b
+		}
+
+		d := Deletion{Step: i + 1}
+		var missing []string
+		require := func(keys ...string) {
+			for _, key := range keys {
+				if str(key) == "" {
+					missing = append(missing, key)
+				}
+			}
+		}
+		switch a.Tool {
+		case "delete_resource":
+			require("type", "name")
+			d.What, d.Name, d.Namespace = str("type"), str("name"), str("namespace")
+			d.Effects = append(d.Effects, "objects it owns, such as ReplicaSets and Pods, are deleted first (foreground cascade)")
+			if flag("delete_manifest") {
+				d.Effects = append(d.Effects, "its stored manifest is deleted too")
+			}
+		case "delete_namespace":
+			require("name")
+			d.What, d.Name = "namespace", str("name")
+			d.Effects = append(d.Effects, "EVERY resource in the namespace is deleted with it")
+			if force, _ := p["force"].(bool); force {
+				d.Effects = append(d.Effects, "force: even while it still runs pods or deployments")
+			}
+			if flag("delete_manifests") {
+				d.Effects = append(d.Effects, fmt.Sprintf("all stored manifests under %s/ are deleted too", d.Name))
+			}
+		case "delete_manifest":
+			if !flag("delete_from_cluster") {
+				continue
+			}
+			require("namespace", "app")
+			d.What, d.Name, d.Namespace = "app", str("app"), str("namespace")
+			if t := str("type"); t != "" {
+				d.Effects = append(d.Effects, fmt.Sprintf("its %s is deleted from the cluster (foreground cascade)", t))
+			} else {
+				d.Effects = append(d.Effects, "every resource it has a stored manifest for is deleted from the cluster (foreground cascade)")
+			}
+			d.Effects = append(d.Effects, "the stored manifests are deleted")
+		default:
+			continue
+		}
+		if len(missing) > 0 {
+			unnamed = append(unnamed, fmt.Sprintf("step %d (%s) has no %s", d.Step, a.Tool, strings.Join(missing, " or ")))
+			continue
+		}
+		deletions = append(deletions, d)
+	}
+	if len(unnamed) > 0 {
+		return nil, fmt.Errorf("the plan doesn't say what it deletes: %s", strings.Join(unnamed, "; "))
+	}
+	return deletions, nil
+}
+
+// RenderDeletions renders the deletion summary shown before a destructive
+// plan runs.
+func RenderDeletions(deletions []Deletion) string {
+	var sb strings.Builder
+	sb.WriteString("This plan deletes:\n")
+	for _, d := range deletions {
+		target := d.What + " " + d.Name
+		if d.Namespace != "" {
+			target += " in namespace " + d.Namespace
+		}
+		fmt.Fprintf(&sb, "  step %d: %s\n", d.Step, target)
+		for _, e := range d.Effects {
+			fmt.Fprintf(&sb, "    - %s\n", e)
+		}
+	}
+	sb.WriteString("Type each name to confirm; anything else keeps the plan pending.")
+	return deleteStyle.Render(sb.String())
+}
+
+// deletionCheck is the confirmation of a destructive plan's deletions by
+// name, one after the other.
+type deletionCheck struct {
+	deletions []Deletion
+	confirmed int
+}
+
+// current returns the deletion whose name is asked for next.
+func (c *deletionCheck) current() *Deletion {
+	return &c.deletions[c.confirmed]
+}
+
+// confirmDeletion takes a typed name; once every deletion is confirmed the
+// plan is approved.
+func (m *model) confirmDeletion(input string) tea.Cmd {
+	c := m.deletions
+	if input != c.current().Name {
+		m.deletions = nil
+		m.println(fmt.Sprintf("%q does not match %q. The plan is still pending: /approve to try again, /reject to drop it.", input, c.current().Name))
+		m.updatePrompt()
+		return nil
+	}
+	c.confirmed++
+	if c.confirmed < len(c.deletions) {
+		m.updatePrompt()
+		return nil
+	}
+	m.deletions = nil
+	return m.executePlan()
+}
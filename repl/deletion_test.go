@@ -0,0 +1,115 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlanDeletions(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  PlannedAction
+		want    *Deletion // nil when the action deletes nothing
+		effects []string  // substrings of the listed effects
+		wantErr string
+	}{
+		{
+			name:    "delete_resource",
+			action:  PlannedAction{Tool: "delete_resource", Parameters: map[string]any{"type": "deployment", "name": "web", "namespace": "shop"}},
+			want:    &Deletion{What: "deployment", Name: "web", Namespace: "shop"},
+			effects: []string{"foreground cascade", "stored manifest is deleted too"},
+		},
+		{
+			name:    "delete_resource cluster-scoped, keeping the manifest",
+			action:  PlannedAction{Tool: "delete_resource", Parameters: map[string]any{"type": "clusterrole", "name": "reader", "delete_manifest": false}},
+			want:    &Deletion{What: "clusterrole", Name: "reader"},
+			effects: []string{"foreground cascade"},
+		},
+		{
+			name:    "delete_resource without a name",
+			action:  PlannedAction{Tool: "delete_resource", Parameters: map[string]any{"type": "pod", "namespace": "shop"}},
+			wantErr: "step 1 (delete_resource) has no name",
+		},
+		{
+			name:    "delete_namespace",
+			action:  PlannedAction{Tool: "delete_namespace", Parameters: map[string]any{"name": "shop", "force": true}},
+			want:    &Deletion{What: "namespace", Name: "shop"},
+			effects: []string{"EVERY resource", "force", "manifests under shop/"},
+		},
+		{
+			name:    "delete_namespace without a name",
+			action:  PlannedAction{Tool: "delete_namespace", Parameters: map[string]any{}},
+			wantErr: "step 1 (delete_namespace) has no name",
+		},
+		{
+			name:    "delete_manifest of one type",
+			action:  PlannedAction{Tool: "delete_manifest", Parameters: map[string]any{"namespace": "shop", "app": "web", "type": "service"}},
+			want:    &Deletion{What: "app", Name: "web", Namespace: "shop"},
+			effects: []string{"its service is deleted from the cluster", "stored manifests are deleted"},
+		},
+		{
+			name:    "delete_manifest of every type",
+			action:  PlannedAction{Tool: "delete_manifest", Parameters: map[string]any{"namespace": "shop", "app": "web"}},
+			want:    &Deletion{What: "app", Name: "web", Namespace: "shop"},
+			effects: []string{"every resource it has a stored manifest for"},
+		},
+		{
+			name:    "delete_manifest without a namespace",
+			action:  PlannedAction{Tool: "delete_manifest", Parameters: map[string]any{"app": "web"}},
+			wantErr: "step 1 (delete_manifest) has no namespace",
+		},
+		{
+			name:   "delete_manifest keeping the cluster's resources",
+			action: PlannedAction{Tool: "delete_manifest", Parameters: map[string]any{"app": "web", "delete_from_cluster": false}},
+		},
+		{
+			name:   "non-deleting tool",
+			action: PlannedAction{Tool: "set_image", Parameters: map[string]any{"deployment": "web"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deletions, err := PlanDeletions(&Plan{Actions: []PlannedAction{tt.action}})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if len(deletions) != 0 {
+					t.Errorf("deletions = %+v, want none", deletions)
+				}
+				return
+			}
+			if len(deletions) != 1 {
+				t.Fatalf("deletions = %+v, want one", deletions)
+			}
+			d := deletions[0]
+			if d.Step != 1 || d.What != tt.want.What || d.Name != tt.want.Name || d.Namespace != tt.want.Namespace {
+				t.Errorf("deletion = %+v, want %+v", d, *tt.want)
+			}
+			effects := strings.Join(d.Effects, "\n")
+			for _, e := range tt.effects {
+				if !strings.Contains(effects, e) {
+					t.Errorf("effects %q don't mention %q", d.Effects, e)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanDeletions_Unnamed(t *testing.T) {
+	// One unnamed deletion refuses the whole plan, named ones included
+	plan := &Plan{Actions: []PlannedAction{
+		{Tool: "delete_resource", Parameters: map[string]any{"type": "deployment", "name": "web", "namespace": "shop"}},
+		{Tool: "delete_resource", Parameters: map[string]any{"type": "pod", "namespace": "shop"}},
+	}}
+	deletions, err := PlanDeletions(plan)
+	if err == nil || deletions != nil || !strings.Contains(err.Error(), "step 2 (delete_resource) has no name") {
+		t.Errorf("PlanDeletions = %+v, %v; want the plan refused", deletions, err)
+	}
+}
@@ -0,0 +1,262 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"google.golang.org/genai"
+)
+
+// ManifestEditor reads and saves stored manifests for /edit.
+type ManifestEditor interface {
+	ReadManifest(namespace, app, resourceType string) ([]byte, error)
+	// SaveManifest validates content, writes it and stages it in git,
+	// returning the manifest's path in the store.
+	SaveManifest(namespace, app, resourceType string, content []byte) (string, error)
+	// Manifests lists the stored manifests as namespace/app/type.
+	Manifests() []string
+}
+
+// manifestEdit is a stored manifest being edited in $EDITOR, on the user's
+// initiative or to review an edit_manifest proposal.
+type manifestEdit struct {
+	namespace, app, resourceType string
+
+	file     string // temporary copy the editor opens
+	proposed string // content the agent proposed, empty for /edit
+	callID   string // the edit_manifest call the outcome answers
+}
+
+// path returns the manifest's path in the store.
+func (e *manifestEdit) path() string {
+	return e.namespace + "/" + e.app + "/" + e.resourceType + ".yaml"
+}
+
+// manifestEditedMsg reports that the editor opened by /edit exited.
+type manifestEditedMsg struct {
+	err error
+}
+
+// parseManifestRef splits namespace/app/type, allowing a .yaml suffix.
+func parseManifestRef(ref string) (namespace, app, resourceType string, ok bool) {
+	parts := strings.Split(strings.TrimSuffix(ref, ".yaml"), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// proposeEdit records an edit_manifest call for the user to review.
+func (m *model) proposeEdit(call *genai.FunctionCall) {
+	namespace, _ := call.Args["namespace"].(string)
+	app, _ := call.Args["app"].(string)
+	resourceType, _ := call.Args["type"].(string)
+	content, _ := call.Args["content"].(string)
+	if m.manifests == nil || namespace == "" || app == "" || resourceType == "" || content == "" {
+		return
+	}
+	m.dropEdit()
+	m.edit = &manifestEdit{namespace: namespace, app: app, resourceType: resourceType, proposed: content, callID: call.ID}
+	msg := fmt.Sprintf("The agent proposes changes to %s", m.edit.path())
+	if reason := extractReason(call.Args); reason != "" {
+		msg += ": " + reason
+	}
+	m.println(msg + ". Type 'yes' to accept them, /edit to review them in your editor, or 'no' to decline.")
+	m.updatePrompt()
+}
+
+// editManifest opens a stored manifest, or the pending edit, in $EDITOR.
+func (m *model) editManifest(arg string) tea.Cmd {
+	if arg == "" {
+		if m.edit == nil {
+			m.println("Usage: /edit <namespace>/<app>/<type>")
+			return nil
+		}
+		return m.openEditor()
+	}
+	if m.edit != nil && m.edit.callID != "" {
+		m.println(fmt.Sprintf("The agent's changes to %s are waiting: 'yes' to accept them, /edit to review them, 'no' to decline.", m.edit.path()))
+		return nil
+	}
+	if m.manifests == nil {
+		m.println("No manifest store to edit.")
+		return nil
+	}
+	namespace, app, resourceType, ok := parseManifestRef(arg)
+	if !ok {
+		m.println("Usage: /edit <namespace>/<app>/<type>")
+		return nil
+	}
+	m.dropEdit()
+	m.edit = &manifestEdit{namespace: namespace, app: app, resourceType: resourceType}
+	return m.openEditor()
+}
+
+// openEditor runs the editor on the pending edit.
+func (m *model) openEditor() tea.Cmd {
+	if err := m.writeEdit(); err != nil {
+		m.println(fmt.Sprintf("Error: %v", err))
+		return nil
+	}
+	return tea.ExecProcess(editorCommand(m.edit.file), func(err error) tea.Msg {
+		return manifestEditedMsg{err: err}
+	})
+}
+
+// acceptEdit saves the agent's proposal as it is.
+func (m *model) acceptEdit() tea.Cmd {
+	if err := m.writeEdit(); err != nil {
+		m.println(fmt.Sprintf("Error: %v", err))
+		return nil
+	}
+	return m.manifestEdited(manifestEditedMsg{})
+}
+
+// writeEdit writes the proposal or the stored manifest to a temporary file,
+// unless an earlier attempt left one.
+func (m *model) writeEdit() error {
+	e := m.edit
+	if e.file != "" {
+		return nil
+	}
+	content := []byte(e.proposed)
+	if e.proposed == "" {
+		var err error
+		if content, err = m.manifests.ReadManifest(e.namespace, e.app, e.resourceType); err != nil {
+			m.edit = nil
+			return err
+		}
+	}
+	file, err := writeTemp(content)
+	if err != nil {
+		return err
+	}
+	e.file = file
+	return nil
+}
+
+// manifestEdited validates and saves the edited file. Invalid YAML leaves
+// the edit pending so /edit reopens it.
+func (m *model) manifestEdited(msg manifestEditedMsg) tea.Cmd {
+	e := m.edit
+	if e == nil {
+		return nil
+	}
+	if msg.err != nil {
+		m.println(fmt.Sprintf("Warning: editor: %v. /edit to try again.", msg.err))
+		return nil
+	}
+	content, err := os.ReadFile(e.file)
+	if err != nil {
+		m.println(fmt.Sprintf("Error: %v", err))
+		return nil
+	}
+	if e.callID == "" {
+		stored, _ := m.manifests.ReadManifest(e.namespace, e.app, e.resourceType)
+		if string(stored) == string(content) {
+			m.dropEdit()
+			m.println("No changes.")
+			return nil
+		}
+	}
+	path, err := m.manifests.SaveManifest(e.namespace, e.app, e.resourceType, content)
+	if err != nil {
+		m.println(fmt.Sprintf("%s was not saved: %v. /edit to fix it, /reject to drop the edit.", e.path(), err))
+		return nil
+	}
+	m.dropEdit()
+
+	if e.callID != "" {
+		m.println(fmt.Sprintf("Saved and staged %s.", path))
+		response := map[string]any{
+			"status":  "saved",
+			"path":    path,
+			"message": "The user accepted the proposal and it is staged, not applied. Propose a plan with apply_manifest to apply it.",
+		}
+		if string(content) != e.proposed {
+			response["edited_by_user"] = true
+			response["content"] = string(content)
+			response["message"] = "The user changed the proposal before saving; content is what is staged, not applied. Propose a plan with apply_manifest to apply it."
+		}
+		return m.startAgentWith(editResponse(e.callID, response))
+	}
+
+	m.editOffer = path
+	m.println(fmt.Sprintf("Saved and staged %s. Dry-run and apply it to the cluster? Type 'yes' or 'no'.", path))
+	m.updatePrompt()
+	return nil
+}
+
+// declineEdit answers a pending edit_manifest call with the user's refusal.
+func (m *model) declineEdit() tea.Cmd {
+	e := m.edit
+	m.dropEdit()
+	m.println(fmt.Sprintf("Declined the changes to %s.", e.path()))
+	return m.startAgentWith(editResponse(e.callID, map[string]any{
+		"status":  "declined",
+		"path":    e.path(),
+		"message": "The user declined the proposal; the manifest is unchanged.",
+	}))
+}
+
+// editResponse answers an edit_manifest call.
+func editResponse(callID string, response map[string]any) *genai.Content {
+	return &genai.Content{Role: genai.RoleUser, Parts: []*genai.Part{{
+		FunctionResponse: &genai.FunctionResponse{ID: callID, Name: "edit_manifest", Response: response},
+	}}}
+}
+
+// applyEdit asks the agent to apply a manifest the user edited.
+func (m *model) applyEdit() tea.Cmd {
+	path := m.editOffer
+	m.editOffer = ""
+	return m.startAgent(fmt.Sprintf("I edited the stored manifest %s and it is staged. Apply it to the cluster with apply_manifest: dry-run it first and show me what changes.", path))
+}
+
+// dropEdit forgets the pending edit and removes its temporary file.
+func (m *model) dropEdit() {
+	if m.edit != nil && m.edit.file != "" {
+		os.Remove(m.edit.file)
+	}
+	m.edit = nil
+}
+
+// editManifestNames completes /edit.
+func editManifestNames(m *model) []string {
+	if m.manifests == nil {
+		return nil
+	}
+	return m.manifests.Manifests()
+}
+
+// writeTemp writes content to a new temporary YAML file.
+func writeTemp(content []byte) (string, error) {
+	f, err := os.CreateTemp("", "kasa-*.yaml")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// editorCommand runs $VISUAL or $EDITOR, or vi, on file.
+func editorCommand(file string) *exec.Cmd {
+	editor := strings.Fields(os.Getenv("VISUAL"))
+	if len(editor) == 0 {
+		editor = strings.Fields(os.Getenv("EDITOR"))
+	}
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	return exec.Command(editor[0], append(editor[1:], file)...)
+}
@@ -0,0 +1,298 @@
+package repl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/util/homedir"
+)
+
+// Journal step statuses.
+const (
+	StepPending   = "pending"
+	StepCompleted = "completed"
+	StepFailed    = "failed"
+)
+
+// JournalStep records the progress of a single planned action.
+type JournalStep struct {
+	Action      PlannedAction `json:"action"`
+	Status      string        `json:"status"`
+	Error       string        `json:"error,omitempty"`
+	CompletedAt time.Time     `json:"completed_at,omitzero"`
+}
+
+// Journal persists the progress of an approved plan to disk so an interrupted
+// execution can be inspected and resumed after a restart. There is one
+// journal for every cluster, so it records the kube context the plan was
+// made for.
+type Journal struct {
+	Description string        `json:"description"`
+	Context     string        `json:"context,omitempty"` // kube context the plan runs against
+	Steps       []JournalStep `json:"steps"`
+	StartedAt   time.Time     `json:"started_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+
+	file string
+}
+
+// journalPath returns the location of the journal file (~/.kasa/journal.json).
+func journalPath() string {
+	home := homedir.HomeDir()
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".kasa", "journal.json")
+}
+
+// NewJournal creates a journal for an approved plan against kubeContext with
+// all steps pending.
+func NewJournal(plan *Plan, kubeContext string) *Journal {
+	steps := make([]JournalStep, len(plan.Actions))
+	for i, a := range plan.Actions {
+		steps[i] = JournalStep{Action: a, Status: StepPending}
+	}
+	now := time.Now()
+	return &Journal{
+		Description: plan.Description,
+		Context:     kubeContext,
+		Steps:       steps,
+		StartedAt:   now,
+		UpdatedAt:   now,
+		file:        journalPath(),
+	}
+}
+
+// LoadJournal reads the journal left behind by a previous run.
+// Returns nil if there is no journal on disk or it cannot be parsed.
+func LoadJournal() *Journal {
+	path := journalPath()
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil
+	}
+	j.file = path
+	return &j
+}
+
+// Save writes the journal to disk. The file is written atomically so a crash
+// mid-write never leaves a truncated journal behind.
+func (j *Journal) Save() error {
+	if j.file == "" {
+		return nil
+	}
+	j.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding journal: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(j.file), 0755); err != nil {
+		return fmt.Errorf("writing journal: %w", err)
+	}
+	tmp := j.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing journal: %w", err)
+	}
+	if err := os.Rename(tmp, j.file); err != nil {
+		return fmt.Errorf("writing journal: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the journal from disk.
+func (j *Journal) Remove() {
+	if j.file == "" {
+		return
+	}
+	_ = os.Remove(j.file)
+}
+
+// ResumableIn returns an error if the plan was made for another kube
+// context than kubeContext, where resuming it would change the wrong
+// cluster.
+func (j *Journal) ResumableIn(kubeContext string) error {
+	if j.Context == kubeContext {
+		return nil
+	}
+	recorded := j.Context
+	if recorded == "" {
+		recorded = "an unrecorded context"
+	} else {
+		recorded = fmt.Sprintf("context %q", recorded)
+	}
+	return fmt.Errorf("the interrupted plan was made for %s, but kasa is using context %q; "+
+		"switch to that context to resume it, or /discard it", recorded, kubeContext)
+}
+
+// RecordResult marks the step that a call to toolName with args carried out
+// (see StepFor) as completed or failed based on the tool response. Tools
+// report failures by returning an "error" key, so its presence marks the
+// step as failed. Calls forced into dry-run or refused outside a maintenance
+// window leave the step pending until the user confirms them or the window
+// opens.
+// Returns false if the tool call does not correspond to a planned step.
+func (j *Journal) RecordResult(toolName string, args, response map[string]any) bool {
+	if ParseDryRunConfirmation(response) != nil {
+		return false
+	}
+	if closed, _ := response["window_closed"].(bool); closed {
+		return false
+	}
+	i := j.StepFor(toolName, args)
+	if i < 0 {
+		return false
+	}
+	step := &j.Steps[i]
+	if errVal, ok := response["error"]; ok && errVal != nil {
+		step.Status = StepFailed
+		step.Error = fmt.Sprintf("%v", errVal)
+	} else {
+		step.Status = StepCompleted
+		step.Error = ""
+		step.CompletedAt = time.Now()
+	}
+	return true
+}
+
+// StepFor returns the index of the outstanding step that a call to
+// toolName with args carries out: the first one whose parameters the call
+// has, or else the only outstanding step calling toolName, as the model may
+// pass a parameter differently than planned. It returns -1 if there is no
+// such step, or several that the arguments don't tell apart.
+func (j *Journal) StepFor(toolName string, args map[string]any) int {
+	only := -1
+	for i, s := range j.Steps {
+		if s.Status == StepCompleted || s.Action.Tool != toolName {
+			continue
+		}
+		if s.Action.calledWith(args) {
+			return i
+		}
+		if only != -1 {
+			return -1
+		}
+		only = i
+	}
+	return only
+}
+
+// calledWith returns true if args has every parameter of the action, with
+// the same value. Other arguments, such as reason, don't matter.
+func (a PlannedAction) calledWith(args map[string]any) bool {
+	for k, v := range a.Parameters {
+		got, ok := args[k]
+		if !ok {
+			return false
+		}
+		want, err1 := json.Marshal(v)
+		have, err2 := json.Marshal(got)
+		if err1 != nil || err2 != nil || string(want) != string(have) {
+			return false
+		}
+	}
+	return true
+}
+
+// CompletedCount returns the number of completed steps.
+func (j *Journal) CompletedCount() int {
+	n := 0
+	for _, s := range j.Steps {
+		if s.Status == StepCompleted {
+			n++
+		}
+	}
+	return n
+}
+
+// IsComplete returns true when every step has completed.
+func (j *Journal) IsComplete() bool {
+	return j.CompletedCount() == len(j.Steps)
+}
+
+// NextStep returns the index of the first step that has not completed, or -1.
+func (j *Journal) NextStep() int {
+	for i, s := range j.Steps {
+		if s.Status != StepCompleted {
+			return i
+		}
+	}
+	return -1
+}
+
+// Summary returns a one-line progress description, e.g. "3 of 6 steps completed".
+func (j *Journal) Summary() string {
+	s := fmt.Sprintf("%d of %d steps completed", j.CompletedCount(), len(j.Steps))
+	if next := j.NextStep(); next >= 0 && j.Steps[next].Status == StepFailed {
+		s += fmt.Sprintf(", step %d (%s) failed: %s", next+1, j.Steps[next].Action.Tool, j.Steps[next].Error)
+	}
+	return s
+}
+
+// FormatJournal formats the journal as a plain-text step list for display.
+func FormatJournal(j *Journal) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Interrupted plan: %s\n", j.Description))
+	if j.Context != "" {
+		sb.WriteString(fmt.Sprintf("Kube context: %s\n", j.Context))
+	}
+	sb.WriteString(fmt.Sprintf("Started %s — %s\n", j.StartedAt.Format(time.RFC3339), j.Summary()))
+	for i, s := range j.Steps {
+		marker := "[ ]"
+		switch s.Status {
+		case StepCompleted:
+			marker = "[x]"
+		case StepFailed:
+			marker = "[!]"
+		}
+		sb.WriteString(fmt.Sprintf("  %s %d. %s — %s\n", marker, i+1, s.Action.Tool, s.Action.Reason))
+	}
+	sb.WriteString("Type /resume to continue from the first incomplete step, or /discard to drop it.\n")
+	return sb.String()
+}
+
+// FormatResumePrompt creates a prompt instructing the agent to execute only the
+// steps of a journaled plan that have not yet completed.
+func FormatResumePrompt(j *Journal) string {
+	var sb strings.Builder
+	sb.WriteString("The user has APPROVED resuming a previously interrupted plan. ")
+	sb.WriteString("Some steps already completed and must NOT be repeated.\n\n")
+	sb.WriteString("Plan: ")
+	sb.WriteString(j.Description)
+	sb.WriteString("\n\nAlready completed:\n")
+
+	for i, s := range j.Steps {
+		if s.Status == StepCompleted {
+			sb.WriteString(fmt.Sprintf("%d. %s (done)\n", i+1, s.Action.Tool))
+		}
+	}
+
+	sb.WriteString("\nActions to execute now:\n")
+	for i, s := range j.Steps {
+		if s.Status == StepCompleted {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%d. Call %s with parameters: ", i+1, s.Action.Tool))
+		for k, v := range s.Action.Parameters {
+			sb.WriteString(fmt.Sprintf("%s=%v ", k, v))
+		}
+		sb.WriteString(fmt.Sprintf("(Reason: %s)", s.Action.Reason))
+		if s.Status == StepFailed {
+			sb.WriteString(fmt.Sprintf(" [previous attempt failed: %s]", s.Error))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\nCheck the current state of any previously failed step before retrying it. Execute these actions in order. Do not call propose_plan again - proceed directly with the mutating tools. Pass each action's reason as change_cause to tools that accept it, so rollout history records why the workload changed.")
+	return sb.String()
+}
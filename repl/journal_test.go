@@ -0,0 +1,110 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+)
+
+func testJournal() *Journal {
+	return NewJournal(&Plan{
+		Description: "Roll out v2",
+		Actions: []PlannedAction{
+			{Tool: "set_image", Parameters: map[string]any{"deployment": "web", "image": "web:v2"}},
+			{Tool: "set_image", Parameters: map[string]any{"deployment": "worker", "image": "worker:v2"}},
+			{Tool: "scale_deployment", Parameters: map[string]any{"deployment": "web", "replicas": 3}},
+		},
+	}, "prod")
+}
+
+func TestJournal_StepFor(t *testing.T) {
+	j := testJournal()
+	tests := []struct {
+		name string
+		tool string
+		args map[string]any
+		want int
+	}{
+		{"matching arguments", "set_image", map[string]any{"deployment": "worker", "image": "worker:v2", "reason": "v2"}, 1},
+		{"numbers decoded from JSON", "scale_deployment", map[string]any{"deployment": "web", "replicas": float64(3)}, 2},
+		{"only step of the tool", "scale_deployment", map[string]any{"deployment": "web", "replicas": 4}, 2},
+		{"ambiguous", "set_image", map[string]any{"deployment": "api", "image": "api:v2"}, -1},
+		{"unplanned tool", "delete_resource", nil, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := j.StepFor(tt.tool, tt.args); got != tt.want {
+				t.Errorf("StepFor(%s, %v) = %d, want %d", tt.tool, tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJournal_RecordResult(t *testing.T) {
+	j := testJournal()
+
+	// The second set_image finishing first completes its own step
+	worker := map[string]any{"deployment": "worker", "image": "worker:v2"}
+	if !j.RecordResult("set_image", worker, map[string]any{"success": true}) {
+		t.Fatal("result of a planned call not recorded")
+	}
+	if j.Steps[0].Status != StepPending || j.Steps[1].Status != StepCompleted {
+		t.Errorf("statuses = %s, %s; want the worker step completed", j.Steps[0].Status, j.Steps[1].Status)
+	}
+
+	web := map[string]any{"deployment": "web", "image": "web:v2"}
+	j.RecordResult("set_image", web, map[string]any{"error": "image pull failed"})
+	if j.Steps[0].Status != StepFailed || j.Steps[0].Error != "image pull failed" {
+		t.Errorf("step 1 = %+v; want it failed", j.Steps[0])
+	}
+
+	// Dry runs and closed windows leave the step as it was
+	if j.RecordResult("set_image", web, map[string]any{"window_closed": true}) {
+		t.Error("refused call recorded")
+	}
+	if j.RecordResult("set_image", web, map[string]any{"confirmation_required": true, "tool": "set_image"}) {
+		t.Error("dry run recorded")
+	}
+	if j.RecordResult("rollback_deployment", nil, map[string]any{"success": true}) {
+		t.Error("unplanned call recorded")
+	}
+
+	j.RecordResult("set_image", web, map[string]any{"success": true})
+	if j.Steps[0].Status != StepCompleted || j.Steps[0].Error != "" || j.Steps[0].CompletedAt.IsZero() {
+		t.Errorf("step 1 = %+v; want the retry completed", j.Steps[0])
+	}
+}
+
+func TestJournal_Summary(t *testing.T) {
+	j := testJournal()
+	if got := j.Summary(); got != "0 of 3 steps completed" {
+		t.Errorf("Summary() = %q", got)
+	}
+	j.RecordResult("set_image", map[string]any{"deployment": "web", "image": "web:v2"}, map[string]any{"success": true})
+	j.RecordResult("set_image", map[string]any{"deployment": "worker", "image": "worker:v2"}, map[string]any{"error": "quota exceeded"})
+	if got, want := j.Summary(), "1 of 3 steps completed, step 2 (set_image) failed: quota exceeded"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	if j.IsComplete() {
+		t.Error("IsComplete() with a failed step")
+	}
+}
+
+func TestJournal_Context(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := testJournal().Save(); err != nil {
+		t.Fatal(err)
+	}
+	j := LoadJournal()
+	if j == nil || j.Context != "prod" {
+		t.Fatalf("LoadJournal() = %+v; want the journal of context prod", j)
+	}
+	if err := j.ResumableIn("prod"); err != nil {
+		t.Errorf("ResumableIn(prod) = %v", err)
+	}
+	if err := j.ResumableIn("staging"); err == nil || !strings.Contains(err.Error(), `context "prod"`) {
+		t.Errorf("ResumableIn(staging) = %v; want it refused", err)
+	}
+	if !strings.Contains(FormatJournal(j), "Kube context: prod") {
+		t.Errorf("FormatJournal doesn't show the context:\n%s", FormatJournal(j))
+	}
+}
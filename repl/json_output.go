@@ -0,0 +1,148 @@
+package repl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/perbu/kasa/tracing"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// JSONEvent is a single line in the machine-readable output stream.
+// Which payload fields are set depends on Type.
+type JSONEvent struct {
+	Type          string         `json:"type"` // "tool_call", "tool_result", "text", "plan", "clarification", "pick", "final", "error"
+	Timestamp     string         `json:"timestamp"`
+	Author        string         `json:"author,omitempty"`
+	Tool          string         `json:"tool,omitempty"`
+	Args          map[string]any `json:"args,omitempty"`
+	Response      map[string]any `json:"response,omitempty"`
+	Text          string         `json:"text,omitempty"`
+	Plan          *Plan          `json:"plan,omitempty"`
+	Clarification *Clarification `json:"clarification,omitempty"`
+	Pick          *Picker        `json:"pick,omitempty"`
+	Error         string         `json:"error,omitempty"`
+	Usage         *JSONUsage     `json:"usage,omitempty"`
+}
+
+// JSONUsage reports token counts on the final event.
+type JSONUsage struct {
+	InputTokens  int32 `json:"input_tokens"`
+	OutputTokens int32 `json:"output_tokens"`
+}
+
+// jsonEmitter writes newline-delimited JSON events.
+type jsonEmitter struct {
+	enc *json.Encoder
+}
+
+func newJSONEmitter(w io.Writer) *jsonEmitter {
+	return &jsonEmitter{enc: json.NewEncoder(w)}
+}
+
+func (e *jsonEmitter) emit(ev JSONEvent) error {
+	ev.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	return e.enc.Encode(ev)
+}
+
+// RunSinglePromptJSON runs the agent with a single prompt and writes a stream of
+// newline-delimited JSON events to w instead of rendered markdown. The stream
+// always ends with either a "final" event carrying the concatenated answer text
+// or an "error" event.
+func (r *REPL) RunSinglePromptJSON(ctx context.Context, prompt string, w io.Writer) error {
+	out := newJSONEmitter(w)
+	userMessage := genai.NewContentFromText(prompt, genai.RoleUser)
+
+	var finalText strings.Builder
+	var usage JSONUsage
+
+	for event, err := range tracing.Run(ctx, r.runner, "user1", "session1", userMessage, agent.RunConfig{}) {
+		if err != nil {
+			_ = out.emit(JSONEvent{Type: "error", Error: err.Error()})
+			return fmt.Errorf("agent execution failed: %w", err)
+		}
+		if event == nil {
+			continue
+		}
+
+		if event.UsageMetadata != nil {
+			usage.InputTokens = event.UsageMetadata.PromptTokenCount
+			usage.OutputTokens = event.UsageMetadata.CandidatesTokenCount
+		}
+
+		for _, ev := range ToJSONEvents(event) {
+			if ev.Type == "text" {
+				finalText.WriteString(ev.Text)
+			}
+			if err := out.emit(ev); err != nil {
+				return err
+			}
+		}
+	}
+
+	return out.emit(JSONEvent{
+		Type:  "final",
+		Text:  finalText.String(),
+		Usage: &usage,
+	})
+}
+
+// ToJSONEvents converts an ADK event into zero or more JSONEvents. Tool calls
+// to propose_plan, ask_clarification and pick_resource additionally yield a
+// parsed "plan", "clarification" or "pick" event. Partial (streaming) text fragments are skipped since
+// the full text arrives in a later event. Timestamps are left for the writer.
+func ToJSONEvents(event *session.Event) []JSONEvent {
+	if event == nil || event.Content == nil {
+		return nil
+	}
+
+	var events []JSONEvent
+	for _, part := range event.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			events = append(events, JSONEvent{
+				Type:   "tool_call",
+				Author: event.Author,
+				Tool:   part.FunctionCall.Name,
+				Args:   part.FunctionCall.Args,
+			})
+
+			switch part.FunctionCall.Name {
+			case "propose_plan":
+				if plan := ParsePlanFromResponse(part.FunctionCall.Args); plan != nil {
+					events = append(events, JSONEvent{Type: "plan", Plan: plan})
+				}
+			case "ask_clarification":
+				if c := ParseClarificationFromResponse(part.FunctionCall.Args); c != nil {
+					events = append(events, JSONEvent{Type: "clarification", Clarification: c})
+				}
+			case "pick_resource":
+				if p := ParsePickerFromResponse(part.FunctionCall.Args); p != nil {
+					events = append(events, JSONEvent{Type: "pick", Pick: p})
+				}
+			}
+
+		case part.FunctionResponse != nil:
+			events = append(events, JSONEvent{
+				Type:     "tool_result",
+				Author:   event.Author,
+				Tool:     part.FunctionResponse.Name,
+				Response: part.FunctionResponse.Response,
+			})
+
+		case part.Text != "" && !event.Partial:
+			events = append(events, JSONEvent{
+				Type:   "text",
+				Author: event.Author,
+				Text:   part.Text,
+			})
+		}
+	}
+	return events
+}
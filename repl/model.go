@@ -11,6 +11,9 @@ import (
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
+	"github.com/perbu/kasa/agents"
+	"github.com/perbu/kasa/fallback"
+	"github.com/perbu/kasa/tracing"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/runner"
 	"google.golang.org/adk/session"
@@ -27,11 +30,13 @@ type agentEventMsg struct {
 // programRef holds a reference to the tea.Program, set after creation.
 // This allows the model (passed by value) to access the program for Println.
 type programRef struct {
-	p *tea.Program
+	p      *tea.Program
+	output *scrollback // everything printed, for the pager
 }
 
 func (r *programRef) Println(args ...interface{}) {
 	if r != nil && r.p != nil {
+		r.output.add(fmt.Sprint(args...))
 		r.p.Println(args...)
 	}
 }
@@ -42,8 +47,22 @@ type model struct {
 	spinner  spinner.Model
 	history  *History
 	state    *SessionState
+	journal  *Journal // progress of the plan being executed, nil when idle
+	notifier Notifier // optional, nil when no integrations are configured
+
+	kubeContext string // the context journals are recorded for
+
+	confirmer DryRunConfirmer // optional, nil when no dry-run policy is configured
+	usage     UsageReporter   // optional, nil when token usage isn't tracked
+	modes     *agents.Router  // optional, nil when a single agent does everything
+	sessions  session.Service // optional, nil when /export is unavailable
+	completer Completer       // optional, nil completes commands only
+	manifests ManifestEditor  // optional, nil disables /edit
+	events    EventWatcher    // optional, nil disables /watch
+	alerts    AlertConfig
 
 	runner     *runner.Runner
+	baseCtx    context.Context // parent of every agent run; cancelled on shutdown signals
 	debug      bool
 	mdRenderer *glamour.TermRenderer
 	program    *programRef // shared pointer, set after program creation
@@ -52,6 +71,8 @@ type model struct {
 	agentBusy   bool
 	agentCancel context.CancelFunc
 	eventCh     chan agentEventMsg
+	agentName   string     // the agent that produced the latest event
+	reauth      *reauthMsg // a tool call waiting for the user to log in again
 
 	// status display
 	statusText   string
@@ -59,21 +80,39 @@ type model struct {
 	toolReason   string
 	inputTokens  int32
 	outputTokens int32
+	activity     activity    // tool calls and tokens of the current run
+	showActivity bool        // expand the status line into the activity panel (Ctrl+O)
+	stream       *streamText // the reply being streamed, nil when none
 
 	// terminal dimensions
 	width  int
 	height int
 
+	focused bool // whether the terminal has focus, for alerts
+
 	// saved textarea content when navigating history
 	savedInput string
 
+	output *scrollback // session output, shared with program
+	pager  pager       // full-screen scrollback, see PgUp and /search
+
+	docs     []yamlDoc // YAML from tools and long replies, for /view
+	docCount int       // documents numbered so far
+
+	deletions *deletionCheck      // names to type before a destructive plan runs
+	edit      *manifestEdit       // manifest open in, or waiting for, the editor
+	editCall  *genai.FunctionCall // latest edit_manifest call, until its result arrives
+	editOffer string              // manifest the user edited, offered for applying
+
+	eventWatch *eventWatch // the running /watch, nil when none
+
 	quitting bool
 }
 
 // statusStyle is the dim style for the status line.
 var statusStyle = lipgloss.NewStyle().Faint(true)
 
-func newModel(r *runner.Runner, debug bool) model {
+func newModel(ctx context.Context, r *runner.Runner, debug bool) model {
 	ta := textarea.New()
 	ta.Placeholder = "Type a message..."
 	ta.Prompt = "> "
@@ -110,16 +149,23 @@ func newModel(r *runner.Runner, debug bool) model {
 		glamour.WithWordWrap(80),
 	)
 
+	output := &scrollback{}
 	return model{
 		textarea:   ta,
 		spinner:    s,
 		history:    NewHistory(),
 		state:      NewSessionState(),
+		journal:    LoadJournal(),
 		runner:     r,
+		baseCtx:    ctx,
 		debug:      debug,
 		mdRenderer: md,
-		program:    &programRef{}, // populated after tea.NewProgram
+		program:    &programRef{output: output}, // populated after tea.NewProgram
+		output:     output,
 		eventCh:    make(chan agentEventMsg, 64),
+
+		showActivity: true,
+		focused:      true,
 	}
 }
 
@@ -139,6 +185,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		m.textarea.SetWidth(msg.Width)
+		m.pager.view.Width = msg.Width
+		m.pager.view.Height = max(msg.Height-1, 1)
 		if m.mdRenderer != nil {
 			m.mdRenderer, _ = glamour.NewTermRenderer(
 				glamour.WithStandardStyle("dark"),
@@ -148,6 +196,33 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
+		// A tool call is waiting for the user to log in to the cluster again
+		if m.reauth != nil {
+			switch msg.String() {
+			case "enter":
+				m.answerReauth(true)
+			case "ctrl+c":
+				m.answerReauth(false)
+			}
+			return m, nil
+		}
+
+		if m.pager.open {
+			return m, m.updatePager(msg)
+		}
+		if msg.String() == "pgup" {
+			return m, m.openPager("")
+		}
+
+		// The agent waits for the user to pick from a list
+		if m.state.PendingPick != nil && !m.agentBusy {
+			return m, m.updatePicker(msg)
+		}
+		if msg.String() == "ctrl+o" {
+			m.showActivity = !m.showActivity
+			return m, nil
+		}
+
 		// Ctrl+C: cancel agent or quit
 		if msg.String() == "ctrl+c" {
 			if m.agentBusy && m.agentCancel != nil {
@@ -169,6 +244,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "enter":
 			return m.handleSubmit()
 
+		case "tab":
+			m.complete()
+			return m, nil
+
 		case "up":
 			// If cursor is on first line, navigate history
 			if m.textarea.Line() == 0 {
@@ -217,8 +296,40 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case streamRenderMsg:
+		m.renderStream()
+		return m, nil
+
 	case agentEventMsg:
-		return m.handleAgentEvent(msg)
+		next, cmd := m.handleAgentEvent(msg)
+		if nm, ok := next.(model); ok && nm.pager.open {
+			nm.refreshPager()
+			return nm, cmd
+		}
+		return next, cmd
+
+	case tea.FocusMsg:
+		m.focused = true
+		return m, nil
+
+	case tea.BlurMsg:
+		m.focused = false
+		return m, nil
+
+	case manifestEditedMsg:
+		return m, m.manifestEdited(msg)
+
+	case editorDoneMsg:
+		if text := editorDone(msg); text != "" {
+			m.println(text)
+		}
+		return m, nil
+
+	case reauthMsg:
+		return m.startReauth(msg)
+
+	case watchEventMsg:
+		return m, m.watchEvent(msg)
 	}
 
 	return m, nil
@@ -228,16 +339,31 @@ func (m model) View() string {
 	if m.quitting {
 		return ""
 	}
+	if m.pager.open {
+		return m.pagerView()
+	}
 
 	var sb strings.Builder
 
-	// Status line when agent is busy
+	// The reply being streamed, then the status line, when agent is busy
 	if m.agentBusy {
+		var panel string
+		if m.showActivity {
+			panel = m.buildActivityPanel()
+		}
+		sb.WriteString(m.streamView(strings.Count(panel, "\n") + 1 + m.textarea.Height()))
+		sb.WriteString(panel)
 		status := m.buildStatusLine()
 		sb.WriteString(statusStyle.Render(status))
 		sb.WriteString("\n")
 	}
 
+	// The list to pick from replaces the input until something is picked
+	if m.state.PendingPick != nil && !m.agentBusy {
+		sb.WriteString(renderPicker(m.state.PendingPick, m.width))
+		return sb.String()
+	}
+
 	// Textarea (input area)
 	sb.WriteString(m.textarea.View())
 
@@ -275,46 +401,31 @@ func (m model) handleSubmit() (tea.Model, tea.Cmd) {
 		return m, tea.Quit
 	}
 
-	// Handle plan approval commands
-	switch strings.ToLower(input) {
-	case "yes", "y", "/approve":
-		if m.state.HasPendingPlan() {
-			plan := m.state.ApprovePlan()
-			if m.program != nil {
-				m.program.Println("Plan approved. Executing...")
-			}
-			execPrompt := FormatExecutionPrompt(plan)
-			return m, m.startAgent(execPrompt)
-		}
-		if m.program != nil {
-			m.program.Println("No pending plan to approve.")
-		}
-		return m, nil
+	// Pending questions take the input as answers
+	if m.state.HasPendingClarification() && !strings.HasPrefix(input, "/") {
+		return m, m.answerClarification(input)
+	}
 
-	case "no", "n", "/reject":
-		if m.state.HasPendingPlan() {
-			m.state.RejectPlan()
-			if m.program != nil {
-				m.program.Println("Plan rejected.")
-			}
-			m.updatePrompt()
-		} else if m.program != nil {
-			m.program.Println("No pending plan to reject.")
-		}
+	// A destructive plan waits for the names of what it deletes
+	if m.deletions != nil && !strings.HasPrefix(input, "/") {
+		return m, m.confirmDeletion(input)
+	}
+
+	if c, arg := findCommand(input); c != nil {
+		return m, m.runCommand(c, arg)
+	}
+	if name, _, _ := strings.Cut(input, " "); isCommandName(name) {
+		m.println(fmt.Sprintf("Unknown command %s. Type /help for the list.", name))
 		return m, nil
+	}
 
-	case "/plan":
-		if m.state.HasPendingPlan() {
-			if m.program != nil {
-				m.program.Println(RenderPlan(m.state.PendingPlan))
-			}
-		} else if m.program != nil {
-			m.program.Println("No pending plan.")
+	// If there's a pending plan or confirmation, warn
+	if m.state.HasPendingConfirmation() {
+		if m.program != nil {
+			m.program.Println("Dry-run changes are awaiting confirmation. Type 'yes' to apply them or 'no' to stop.")
 		}
 		return m, nil
 	}
-
-	// If there's a pending plan, warn
 	if m.state.HasPendingPlan() {
 		if m.program != nil {
 			m.program.Println("You have a pending plan. Type 'yes' to approve, 'no' to reject, or '/plan' to review.")
@@ -328,16 +439,33 @@ func (m model) handleSubmit() (tea.Model, tea.Cmd) {
 
 // startAgent launches the agent in a goroutine and returns a Cmd to wait for events.
 func (m *model) startAgent(prompt string) tea.Cmd {
+	return m.startAgentWith(genai.NewContentFromText(prompt, genai.RoleUser))
+}
+
+// startAgentWith launches the agent with a message that may carry function
+// responses rather than text.
+func (m *model) startAgentWith(userMessage *genai.Content) tea.Cmd {
 	m.agentBusy = true
 	m.statusText = "Thinking..."
 	m.toolName = ""
 	m.toolReason = ""
 	m.inputTokens = 0
 	m.outputTokens = 0
+	m.activity.reset()
+	m.stream = nil
 	m.textarea.Blur()
+	// Questions left unanswered are dropped when the conversation moves on
+	m.state.PendingClarification = nil
+	m.state.PendingPick = nil
+	m.editOffer = ""
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(m.baseCtx)
 	m.agentCancel = cancel
+	m.agentName = ""
+	// Approved plans are carried out by the deployer in every mode
+	if m.state.Mode == ModeExecuting {
+		ctx = agents.WithMode(ctx, agents.ModeDeploy)
+	}
 
 	ch := m.eventCh
 
@@ -346,8 +474,7 @@ func (m *model) startAgent(prompt string) tea.Cmd {
 			ch <- agentEventMsg{done: true}
 		}()
 
-		userMessage := genai.NewContentFromText(prompt, genai.RoleUser)
-		for event, err := range m.runner.Run(ctx, "user1", "session1", userMessage, agent.RunConfig{}) {
+		for event, err := range tracing.Run(ctx, m.runner, "user1", "session1", userMessage, agent.RunConfig{StreamingMode: agent.StreamingModeSSE}) {
 			if err != nil {
 				ch <- agentEventMsg{err: err}
 				return
@@ -368,6 +495,10 @@ func waitForAgent(ch chan agentEventMsg) tea.Cmd {
 
 // handleAgentEvent processes a single event from the agent.
 func (m model) handleAgentEvent(msg agentEventMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil || msg.done {
+		m.answerReauth(false)
+		m.flushStream()
+	}
 	if msg.err != nil {
 		m.agentBusy = false
 		m.agentCancel = nil
@@ -376,6 +507,7 @@ func (m model) handleAgentEvent(msg agentEventMsg) (tea.Model, tea.Cmd) {
 		if m.program != nil {
 			m.program.Println(fmt.Sprintf("Error: %v", msg.err))
 		}
+		m.finishJournal()
 		return m, focusCmd
 	}
 
@@ -384,12 +516,15 @@ func (m model) handleAgentEvent(msg agentEventMsg) (tea.Model, tea.Cmd) {
 		m.agentCancel = nil
 		focusCmd := m.textarea.Focus()
 
-		// Display pending clarification
-		if m.state.PendingClarification != nil {
-			if m.program != nil {
-				m.program.Println(RenderClarification(m.state.PendingClarification))
+		if m.usage != nil && m.program != nil {
+			if warning := m.usage.BudgetWarning(); warning != "" {
+				m.program.Println(warning)
 			}
-			m.state.PendingClarification = nil
+		}
+
+		// Display pending clarification; it stays pending until answered
+		if m.state.HasPendingClarification() && m.program != nil {
+			m.program.Println(RenderClarification(m.state.PendingClarification))
 		}
 
 		// Display pending plan
@@ -397,10 +532,19 @@ func (m model) handleAgentEvent(msg agentEventMsg) (tea.Model, tea.Cmd) {
 			if m.program != nil {
 				m.program.Println(RenderPlan(m.state.PendingPlan))
 			}
+			if m.notifier != nil {
+				go m.notifier.PlanProposed("", m.state.PendingPlan)
+			}
+		}
+
+		// Display dry-run changes awaiting a second confirmation
+		if m.state.HasPendingConfirmation() && m.program != nil {
+			m.program.Println(RenderConfirmation(m.state.PendingConfirmations))
 		}
 
 		// After plan execution, reset if no new plan was proposed
-		if m.state.Mode == ModeExecuting && !m.state.HasPendingPlan() {
+		if m.state.Mode == ModeExecuting && !m.state.HasPendingPlan() && !m.state.HasPendingConfirmation() {
+			m.finishJournal()
 			m.state.Reset()
 		}
 
@@ -413,13 +557,34 @@ func (m model) handleAgentEvent(msg agentEventMsg) (tea.Model, tea.Cmd) {
 		return m, waitForAgent(m.eventCh)
 	}
 
+	// Say which agent the coordinator picked
+	if event.Author != m.agentName && agents.IsSpecialist(event.Author) && m.program != nil {
+		m.program.Println(statusStyle.Render("[" + event.Author + "]"))
+	}
+	m.agentName = event.Author
+
+	// Partial text is shown as it arrives; the complete reply follows as
+	// a final event with the whole text and the usage
+	if event.Partial {
+		return m, tea.Batch(m.appendStream(event), waitForAgent(m.eventCh))
+	}
+
+	// Say when a fallback model answered, or the model only after retries
+	if m.debug && m.program != nil {
+		if note := fallback.Note(event.CustomMetadata); note != "" {
+			m.program.Println(statusStyle.Render("[DEBUG] " + note))
+		}
+	}
+
 	// Update token counts
 	if event.UsageMetadata != nil {
 		m.inputTokens = event.UsageMetadata.PromptTokenCount
 		m.outputTokens = event.UsageMetadata.CandidatesTokenCount
+		m.activity.addUsage(event.UsageMetadata)
 	}
 
 	// Process content parts
+	var cmds []tea.Cmd
 	if event.Content != nil {
 		for _, part := range event.Content.Parts {
 			// Detect propose_plan
@@ -437,35 +602,105 @@ func (m model) handleAgentEvent(msg agentEventMsg) (tea.Model, tea.Cmd) {
 				if part.FunctionCall.Args != nil {
 					clarification := ParseClarificationFromResponse(part.FunctionCall.Args)
 					if clarification != nil {
+						clarification.CallID = part.FunctionCall.ID
 						m.state.PendingClarification = clarification
 					}
 				}
 			}
 
+			// Detect pick_resource
+			if part.FunctionCall != nil && part.FunctionCall.Name == "pick_resource" {
+				if picker := ParsePickerFromResponse(part.FunctionCall.Args); picker != nil {
+					picker.CallID = part.FunctionCall.ID
+					m.state.PendingPick = picker
+				}
+			}
+
+			if part.FunctionCall != nil && part.FunctionCall.Name == "edit_manifest" {
+				m.editCall = part.FunctionCall
+			}
+
 			// Update status for function calls
 			if part.FunctionCall != nil {
+				m.activity.start(part.FunctionCall)
 				m.toolName = part.FunctionCall.Name
 				m.toolReason = extractReason(part.FunctionCall.Args)
 				m.statusText = ""
 			}
 
 			if part.FunctionResponse != nil {
+				if c := ParseDryRunConfirmation(part.FunctionResponse.Response); c != nil {
+					m.state.AddPendingConfirmation(*c)
+				}
+				if part.FunctionResponse.Name == "propose_plan" && m.state.HasPendingPlan() {
+					m.state.PendingPlan.ApplyResponse(part.FunctionResponse.Response)
+				}
+				if m.notifier != nil {
+					go m.notifier.ToolExecuted(part.FunctionResponse.Name, part.FunctionResponse.Response)
+				}
+				if part.FunctionResponse.Name == "edit_manifest" && m.editCall != nil &&
+					part.FunctionResponse.Response["status"] == "awaiting_review" {
+					m.proposeEdit(m.editCall)
+					m.editCall = nil
+				}
+				if d, ok := yamlFromResponse(part.FunctionResponse.Name, part.FunctionResponse.Response); ok {
+					m.addDoc(d)
+				}
+				call := m.activity.finish(part.FunctionResponse)
+				cmds = append(cmds, m.toolAlert(call))
+				if m.journal != nil && m.state.Mode == ModeExecuting {
+					step := m.journal.StepFor(part.FunctionResponse.Name, call.arguments())
+					if m.journal.RecordResult(part.FunctionResponse.Name, call.arguments(), part.FunctionResponse.Response) {
+						if call != nil {
+							m.activity.stepTimes[step] = call.elapsed
+						}
+						m.saveJournal()
+					}
+				}
 				m.toolName = ""
 				m.toolReason = ""
 				m.statusText = "Thinking..."
 			}
 
-			// Print text output
+			// Print text output, replacing the streamed reply
 			if part.Text != "" {
+				m.stream = nil
 				if m.program != nil {
-					rendered := m.renderMarkdown(part.Text)
+					rendered := m.renderMarkdown(m.collapseYAML(part.Text))
 					m.program.Println(rendered)
 				}
 			}
 		}
 	}
 
-	return m, waitForAgent(m.eventCh)
+	return m, tea.Batch(append(cmds, waitForAgent(m.eventCh))...)
+}
+
+// saveJournal persists the journal, reporting failures without interrupting execution.
+func (m *model) saveJournal() {
+	if m.journal == nil {
+		return
+	}
+	if err := m.journal.Save(); err != nil && m.program != nil {
+		m.program.Println(fmt.Sprintf("Warning: %v", err))
+	}
+}
+
+// finishJournal removes the journal once every step has completed, or reports
+// progress and leaves it on disk so the remaining steps can be resumed.
+func (m *model) finishJournal() {
+	if m.journal == nil || m.state.Mode != ModeExecuting {
+		return
+	}
+	if m.journal.IsComplete() {
+		m.journal.Remove()
+		m.journal = nil
+		return
+	}
+	m.saveJournal()
+	if m.program != nil {
+		m.program.Println(fmt.Sprintf("Plan incomplete: %s. Type /resume to continue or /discard to drop it.", m.journal.Summary()))
+	}
 }
 
 // renderMarkdown renders text through glamour, falling back to plain text.
@@ -484,7 +719,9 @@ func (m *model) buildStatusLine() string {
 	var status string
 	spin := m.spinner.View()
 
-	if m.toolName != "" {
+	if m.reauth != nil {
+		status = fmt.Sprintf("%s Waiting for you to log in to the cluster again (Enter to retry, Ctrl+C to give up)", spin)
+	} else if m.toolName != "" {
 		if m.toolReason != "" {
 			status = fmt.Sprintf("%s %s: %s", spin, m.toolName, m.toolReason)
 		} else {
@@ -527,8 +764,20 @@ func (m *model) resizeTextarea() {
 
 // updatePrompt sets the textarea prompt based on session state.
 func (m *model) updatePrompt() {
-	if m.state.HasPendingPlan() {
+	if m.state.HasPendingConfirmation() {
+		m.textarea.Prompt = "confirm> "
+	} else if m.deletions != nil {
+		m.textarea.Prompt = fmt.Sprintf("type %q to delete> ", m.deletions.current().Name)
+	} else if m.state.HasPendingPlan() {
 		m.textarea.Prompt = "approve> "
+	} else if c := m.state.PendingClarification; c != nil && c.Current() != nil {
+		m.textarea.Prompt = fmt.Sprintf("answer %d/%d> ", len(c.Answers)+1, len(c.Questions))
+	} else if m.edit != nil && m.edit.callID != "" {
+		m.textarea.Prompt = "review> "
+	} else if m.editOffer != "" {
+		m.textarea.Prompt = "apply> "
+	} else if m.modes != nil && m.modes.Mode() != agents.ModeAuto {
+		m.textarea.Prompt = string(m.modes.Mode()) + "> "
 	} else {
 		m.textarea.Prompt = "> "
 	}
@@ -0,0 +1,51 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/perbu/kasa/agents"
+)
+
+// modeDescriptions explain the modes in /mode.
+var modeDescriptions = map[agents.Mode]string{
+	agents.ModeAuto:   "a coordinator hands each message to the agent that suits it",
+	agents.ModeDeploy: "the deployer makes changes through plans you approve",
+	agents.ModeDebug:  "the debugger investigates problems, read-only",
+	agents.ModeReview: "the security reviewer checks RBAC, secrets and pod security, read-only",
+}
+
+// describeModes returns the output of "/mode".
+func (m *model) describeModes() string {
+	if m.modes == nil {
+		return "A single agent handles everything (agent.mode is single)."
+	}
+	current := m.modes.Mode()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Mode: %s\n", current)
+	for _, mode := range agents.Modes {
+		marker := " "
+		if mode == current {
+			marker = "*"
+		}
+		fmt.Fprintf(&sb, " %s %-7s %s\n", marker, mode, modeDescriptions[mode])
+	}
+	sb.WriteString("Switch with /mode <name>.")
+	return sb.String()
+}
+
+// switchMode handles "/mode <name>".
+func (m *model) switchMode(name string) string {
+	if m.modes == nil {
+		return "A single agent handles everything (agent.mode is single); there is no mode to switch to."
+	}
+	mode, err := agents.ParseMode(name)
+	if err != nil {
+		return err.Error()
+	}
+	m.modes.SetMode(mode)
+	if mode == agents.ModeAuto {
+		return "Mode: auto. Each message goes to the agent that suits it."
+	}
+	return fmt.Sprintf("Mode: %s. Messages go to the %s.", mode, agents.AgentName(mode))
+}
@@ -0,0 +1,13 @@
+package repl
+
+// Notifier receives notifications about agent activity, e.g. to forward them
+// to a chat integration. Implementations must be safe for concurrent use;
+// calls are made from background goroutines so they never block the UI.
+type Notifier interface {
+	// PlanProposed is called when the agent proposes a plan. sessionID
+	// identifies the API session when running under `kasa serve`, and is
+	// empty for the interactive REPL where approval happens locally.
+	PlanProposed(sessionID string, plan *Plan)
+	// ToolExecuted is called with the result of every tool call.
+	ToolExecuted(toolName string, result map[string]any)
+}
@@ -0,0 +1,208 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"google.golang.org/genai"
+)
+
+// pickerCursorStyle highlights the item under the cursor.
+var pickerCursorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true)
+
+// ParsePickerFromResponse extracts a Picker from the pick_resource tool args.
+func ParsePickerFromResponse(args map[string]any) *Picker {
+	itemsRaw, ok := args["items"].([]any)
+	if !ok {
+		return nil
+	}
+	var items []PickerItem
+	for _, raw := range itemsRaw {
+		itemMap, ok := raw.(map[string]any)
+		if !ok || getString(itemMap, "name") == "" {
+			continue
+		}
+		items = append(items, PickerItem{
+			Name:      getString(itemMap, "name"),
+			Namespace: getString(itemMap, "namespace"),
+			Kind:      getString(itemMap, "kind"),
+			Detail:    getString(itemMap, "detail"),
+		})
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	multiple, _ := args["multiple"].(bool)
+	return &Picker{
+		Prompt:   getString(args, "prompt"),
+		Items:    items,
+		Multiple: multiple,
+		Selected: map[int]bool{},
+	}
+}
+
+// Move moves the cursor by delta items, wrapping around the list.
+func (p *Picker) Move(delta int) {
+	n := len(p.Items)
+	p.Cursor = ((p.Cursor+delta)%n + n) % n
+}
+
+// Toggle picks or unpicks the item under the cursor when several can be
+// picked.
+func (p *Picker) Toggle() {
+	if !p.Multiple {
+		return
+	}
+	if p.Selected == nil {
+		p.Selected = map[int]bool{}
+	}
+	p.Selected[p.Cursor] = !p.Selected[p.Cursor]
+}
+
+// Selection returns the picked items in list order: the toggled ones, or
+// the one under the cursor if none are.
+func (p *Picker) Selection() []PickerItem {
+	var items []PickerItem
+	for i, item := range p.Items {
+		if p.Selected[i] {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		items = []PickerItem{p.Items[p.Cursor]}
+	}
+	return items
+}
+
+// label returns how an item is listed: kind/name, namespace and detail.
+func (item PickerItem) label() string {
+	label := item.Name
+	if item.Kind != "" {
+		label = item.Kind + "/" + label
+	}
+	if item.Namespace != "" {
+		label += " (" + item.Namespace + ")"
+	}
+	if item.Detail != "" {
+		label += "  " + statusStyle.Render(item.Detail)
+	}
+	return label
+}
+
+// renderPicker draws the list in place of the input, with the cursor and,
+// when several can be picked, checkboxes. Lines are cut to width.
+func renderPicker(p *Picker, width int) string {
+	var sb strings.Builder
+	sb.WriteString(p.Prompt)
+	sb.WriteString("\n")
+	for i, item := range p.Items {
+		line := "  "
+		if p.Multiple {
+			if p.Selected[i] {
+				line += "[x] "
+			} else {
+				line += "[ ] "
+			}
+		}
+		line += item.label()
+		if i == p.Cursor {
+			line = pickerCursorStyle.Render("›") + line[1:]
+		}
+		if width > 0 {
+			line = ansi.Truncate(line, width-1, "...")
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	help := "↑/↓ move · enter pick · esc cancel"
+	if p.Multiple {
+		help = "↑/↓ move · space toggle · enter pick · esc cancel"
+	}
+	sb.WriteString(statusStyle.Render(help))
+	return sb.String()
+}
+
+// PickerResponse returns the message that gives the agent the selection:
+// the response to its pick_resource call, or a plain message when the call
+// had no ID. A nil selection means the user cancelled.
+func PickerResponse(p *Picker, selection []PickerItem) *genai.Content {
+	if p.CallID == "" {
+		if selection == nil {
+			return genai.NewContentFromText("I don't want to pick any of them.", genai.RoleUser)
+		}
+		names := make([]string, len(selection))
+		for i, item := range selection {
+			names[i] = item.Name
+		}
+		return genai.NewContentFromText("I picked: "+strings.Join(names, ", "), genai.RoleUser)
+	}
+
+	response := map[string]any{
+		"status":  "cancelled",
+		"message": "The user picked nothing. Don't pick for them; ask what they want instead.",
+	}
+	if selection != nil {
+		selected := make([]any, len(selection))
+		for i, item := range selection {
+			selected[i] = map[string]any{"name": item.Name, "namespace": item.Namespace, "kind": item.Kind}
+		}
+		response = map[string]any{
+			"status":   "selected",
+			"selected": selected,
+			"message":  "The user picked these; continue with them.",
+		}
+	}
+	return &genai.Content{Role: genai.RoleUser, Parts: []*genai.Part{{
+		FunctionResponse: &genai.FunctionResponse{
+			ID:       p.CallID,
+			Name:     "pick_resource",
+			Response: response,
+		},
+	}}}
+}
+
+// updatePicker handles a key while the list is shown.
+func (m *model) updatePicker(msg tea.KeyMsg) tea.Cmd {
+	p := m.state.PendingPick
+	switch msg.String() {
+	case "up", "k", "shift+tab":
+		p.Move(-1)
+	case "down", "j", "tab":
+		p.Move(1)
+	case " ", "x":
+		p.Toggle()
+	case "enter":
+		return m.sendPick(p.Selection())
+	case "esc", "ctrl+c":
+		return m.sendPick(nil)
+	}
+	return nil
+}
+
+// sendPick gives the agent the selection, nil when the user cancelled.
+func (m *model) sendPick(selection []PickerItem) tea.Cmd {
+	p := m.state.PendingPick
+	m.state.PendingPick = nil
+	if m.program != nil {
+		m.program.Println(p.Prompt)
+		if selection == nil {
+			m.program.Println(statusStyle.Render("  (cancelled)"))
+		}
+		for _, item := range selection {
+			m.program.Println("  › " + item.label())
+		}
+	}
+	return m.startAgentWith(PickerResponse(p, selection))
+}
+
+// DisplayPicker prints the list for the one-shot mode, where there is no
+// interactive UI to pick from.
+func DisplayPicker(p *Picker) {
+	fmt.Println(p.Prompt)
+	for i, item := range p.Items {
+		fmt.Printf("%d. %s\n", i+1, item.label())
+	}
+}
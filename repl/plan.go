@@ -41,6 +41,9 @@ func buildPlanMarkdown(plan *Plan) string {
 	var md strings.Builder
 	md.WriteString("# Proposed Plan\n\n")
 	md.WriteString(plan.Description)
+	if plan.ID != "" {
+		md.WriteString(fmt.Sprintf("\n\n*Recorded on changed workloads as `%s`*", plan.ID))
+	}
 	md.WriteString("\n\n## Actions\n\n")
 
 	for i, action := range plan.Actions {
@@ -86,6 +89,14 @@ func buildPlanMarkdown(plan *Plan) string {
 		}
 	}
 
+	if len(plan.Warnings) > 0 {
+		md.WriteString("## Warnings\n\n")
+		for _, w := range plan.Warnings {
+			md.WriteString(fmt.Sprintf("- ⚠ %s\n", w))
+		}
+		md.WriteString("\n")
+	}
+
 	md.WriteString("---\n\n")
 	md.WriteString("**Commands:** `yes` approve · `no` reject · `/plan` show again\n")
 	return md.String()
@@ -147,6 +158,23 @@ func ParsePlanFromResponse(args map[string]any) *Plan {
 	}
 }
 
+// ApplyResponse adds what the propose_plan tool result tells about the
+// plan its call proposed: the plan ID and any warnings.
+func (p *Plan) ApplyResponse(response map[string]any) {
+	p.ID, _ = response["plan_id"].(string)
+	switch w := response["image_warnings"].(type) {
+	case []string:
+		p.Warnings = w
+	case []any:
+		p.Warnings = make([]string, 0, len(w))
+		for _, v := range w {
+			if s, ok := v.(string); ok {
+				p.Warnings = append(p.Warnings, s)
+			}
+		}
+	}
+}
+
 // getString safely extracts a string from a map.
 func getString(m map[string]any, key string) string {
 	if v, ok := m[key].(string); ok {
@@ -161,6 +189,10 @@ func FormatExecutionPrompt(plan *Plan) string {
 	sb.WriteString("The user has APPROVED your plan. Execute the following actions now:\n\n")
 	sb.WriteString("Plan: ")
 	sb.WriteString(plan.Description)
+	if plan.ID != "" {
+		sb.WriteString("\nPlan ID: ")
+		sb.WriteString(plan.ID)
+	}
 	sb.WriteString("\n\nActions to execute:\n")
 
 	for i, action := range plan.Actions {
@@ -171,6 +203,6 @@ func FormatExecutionPrompt(plan *Plan) string {
 		sb.WriteString(fmt.Sprintf("(Reason: %s)\n", action.Reason))
 	}
 
-	sb.WriteString("\nExecute these actions in order. Do not call propose_plan again - proceed directly with the mutating tools.")
+	sb.WriteString("\nExecute these actions in order. Do not call propose_plan again - proceed directly with the mutating tools. Pass each action's reason as change_cause to tools that accept it, so rollout history records why the workload changed.")
 	return sb.String()
 }
@@ -0,0 +1,60 @@
+package repl
+
+import (
+	"context"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// reauthMsg asks the user to log in to the cluster again while a tool call
+// waits for the answer on reply.
+type reauthMsg struct {
+	reason string
+	reply  chan bool
+}
+
+// PromptReauthentication tells the user the cluster credentials were
+// rejected and waits until they logged in again and pressed Enter (true) or
+// gave up with Ctrl+C (false). Outside the interactive REPL nobody can be
+// asked, so it returns false.
+func (r *REPL) PromptReauthentication(ctx context.Context, reason string) bool {
+	p := r.program.Load()
+	if p == nil {
+		return false
+	}
+	reply := make(chan bool, 1)
+	p.Send(reauthMsg{reason: reason, reply: reply})
+	select {
+	case ok := <-reply:
+		return ok
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// startReauth shows the login request.
+func (m model) startReauth(msg reauthMsg) (tea.Model, tea.Cmd) {
+	if m.reauth != nil {
+		// Only one request at a time is made; answer a stray one
+		msg.reply <- false
+		return m, nil
+	}
+	m.reauth = &msg
+	if m.program != nil {
+		m.program.Println(fmt.Sprintf("Warning: %s.\nLog in to the cluster again in another terminal, then press Enter to retry, or Ctrl+C to give up.", msg.reason))
+	}
+	return m, nil
+}
+
+// answerReauth answers the pending login request, if any.
+func (m *model) answerReauth(retry bool) {
+	if m.reauth == nil {
+		return
+	}
+	m.reauth.reply <- retry
+	m.reauth = nil
+	if m.program != nil && retry {
+		m.program.Println("Retrying with new credentials...")
+	}
+}
@@ -2,22 +2,41 @@ package repl
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sync/atomic"
 	"syscall"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
+	"github.com/perbu/kasa/agents"
+	"github.com/perbu/kasa/fallback"
+	"github.com/perbu/kasa/tracing"
 	"golang.org/x/term"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
 	"google.golang.org/genai"
 )
 
 // REPL manages the interactive read-eval-print loop.
 type REPL struct {
-	runner *runner.Runner
-	debug  bool
+	runner    *runner.Runner
+	debug     bool
+	notifier  Notifier
+	confirmer DryRunConfirmer
+	usage     UsageReporter
+	modes     *agents.Router
+	sessions  session.Service
+	completer Completer
+	manifests ManifestEditor
+	events    EventWatcher
+	alerts    AlertConfig
+
+	kubeContext string
+
+	program atomic.Pointer[tea.Program] // the running interactive REPL, if any
 }
 
 // New creates a new REPL instance.
@@ -28,6 +47,61 @@ func New(r *runner.Runner, debug bool) *REPL {
 	}
 }
 
+// SetNotifier registers a Notifier for plans and tool results.
+func (r *REPL) SetNotifier(n Notifier) {
+	r.notifier = n
+}
+
+// SetDryRunConfirmer registers the DryRunConfirmer that is told when the user
+// confirms changes that were forced into dry-run.
+func (r *REPL) SetDryRunConfirmer(c DryRunConfirmer) {
+	r.confirmer = c
+}
+
+// SetUsageReporter registers the UsageReporter behind the /usage command.
+func (r *REPL) SetUsageReporter(u UsageReporter) {
+	r.usage = u
+}
+
+// SetAgentModes registers the router behind the /mode command.
+func (r *REPL) SetAgentModes(modes *agents.Router) {
+	r.modes = modes
+}
+
+// SetSessionService registers the session service /export reads the
+// conversation from.
+func (r *REPL) SetSessionService(s session.Service) {
+	r.sessions = s
+}
+
+// SetCompleter registers the source of the namespaces, app names and kinds
+// Tab completes.
+func (r *REPL) SetCompleter(c Completer) {
+	r.completer = c
+}
+
+// SetManifestEditor registers the manifest store behind /edit.
+func (r *REPL) SetManifestEditor(e ManifestEditor) {
+	r.manifests = e
+}
+
+// SetEventWatcher registers the source of the Warning events /watch shows.
+func (r *REPL) SetEventWatcher(w EventWatcher) {
+	r.events = w
+}
+
+// SetAlerts configures the bell and notifications for long tool calls that
+// finish while the terminal is in the background.
+func (r *REPL) SetAlerts(c AlertConfig) {
+	r.alerts = c
+}
+
+// SetKubeContext sets the kube context kasa acts on, which journals of
+// approved plans record; /resume refuses a plan made for another one.
+func (r *REPL) SetKubeContext(name string) {
+	r.kubeContext = name
+}
+
 // Run starts the interactive REPL loop using bubbletea.
 func (r *REPL) Run(ctx context.Context) error {
 	// Drain any stale terminal query responses (OSC, CPR) from stdin.
@@ -36,15 +110,46 @@ func (r *REPL) Run(ctx context.Context) error {
 	// late end up in stdin and get interpreted as user input by bubbletea.
 	drainStdin()
 
-	m := newModel(r.runner, r.debug)
-	p := tea.NewProgram(m, tea.WithContext(ctx))
+	m := newModel(ctx, r.runner, r.debug)
+	m.notifier = r.notifier
+	m.confirmer = r.confirmer
+	m.usage = r.usage
+	m.modes = r.modes
+	m.sessions = r.sessions
+	m.completer = r.completer
+	m.manifests = r.manifests
+	m.events = r.events
+	m.alerts = r.alerts
+	m.kubeContext = r.kubeContext
+	m.updatePrompt()
+
+	// Report a plan left unfinished by a previous run.
+	if m.journal != nil {
+		fmt.Print(FormatJournal(m.journal))
+	}
+	p := tea.NewProgram(m, tea.WithContext(ctx), tea.WithReportFocus())
 
 	// Store program reference so the model can call Println.
 	// m.program is a *programRef (shared pointer), so this propagates
 	// to the copy held inside the tea.Program.
 	m.program.p = p
+	r.program.Store(p)
+
+	final, err := p.Run()
+	r.program.Store(nil)
+
+	// Whether we quit normally, got SIGTERM, or the context was cancelled,
+	// stop the agent and persist session state before exiting.
+	if fm, ok := final.(model); ok {
+		fm.shutdown(shutdownGracePeriod)
+	}
 
-	_, err := p.Run()
+	if errors.Is(err, tea.ErrProgramKilled) && ctx.Err() != nil {
+		return nil
+	}
+	if errors.Is(err, tea.ErrInterrupted) {
+		return nil
+	}
 	return err
 }
 
@@ -70,7 +175,7 @@ func (r *REPL) runAgentSync(ctx context.Context, state *SessionState, prompt str
 	status := NewStatusLine()
 	status.Start()
 
-	for event, err := range r.runner.Run(ctx, "user1", "session1", userMessage, agent.RunConfig{}) {
+	for event, err := range tracing.Run(ctx, r.runner, "user1", "session1", userMessage, agent.RunConfig{}) {
 		if err != nil {
 			status.Stop()
 			return fmt.Errorf("agent execution failed: %w", err)
@@ -78,6 +183,13 @@ func (r *REPL) runAgentSync(ctx context.Context, state *SessionState, prompt str
 
 		status.Update(event)
 
+		if r.debug && event != nil {
+			if note := fallback.Note(event.CustomMetadata); note != "" {
+				status.ClearForOutput()
+				fmt.Printf("[DEBUG] %s\n", note)
+			}
+		}
+
 		if event != nil && event.Content != nil {
 			for _, part := range event.Content.Parts {
 				if part.FunctionCall != nil && part.FunctionCall.Name == "propose_plan" {
@@ -98,6 +210,12 @@ func (r *REPL) runAgentSync(ctx context.Context, state *SessionState, prompt str
 					}
 				}
 
+				if part.FunctionCall != nil && part.FunctionCall.Name == "pick_resource" {
+					if state != nil {
+						state.PendingPick = ParsePickerFromResponse(part.FunctionCall.Args)
+					}
+				}
+
 				if part.Text != "" {
 					status.ClearForOutput()
 					if mdRenderer != nil {
@@ -121,6 +239,11 @@ func (r *REPL) runAgentSync(ctx context.Context, state *SessionState, prompt str
 		state.PendingClarification = nil
 	}
 
+	if state != nil && state.PendingPick != nil {
+		DisplayPicker(state.PendingPick)
+		state.PendingPick = nil
+	}
+
 	if state != nil && state.HasPendingPlan() {
 		DisplayPlan(state.PendingPlan)
 	}
@@ -140,8 +263,8 @@ func (r *REPL) PrintWelcome(version, model string, toolCount int, deploymentsDir
 | Tools | %d |
 | Deployments folder | %s |
 
-Commands: **yes**/**no** to approve/reject plans, **exit** to quit.
-`, version, model, toolCount, deploymentsDir)
+Commands: **yes**/**no** to approve/reject plans, **/help** for all commands, **Tab** to complete names, %s**exit** to quit.
+`, version, model, toolCount, deploymentsDir, r.modeHint())
 
 	renderer, err := setupMarkdownRenderer()
 	if err != nil {
@@ -162,6 +285,14 @@ Commands: **yes**/**no** to approve/reject plans, **exit** to quit.
 	fmt.Print(rendered)
 }
 
+// modeHint mentions /mode in the welcome message when there are modes.
+func (r *REPL) modeHint() string {
+	if r.modes == nil {
+		return ""
+	}
+	return fmt.Sprintf("**/mode** to pick an agent (now %s), ", r.modes.Mode())
+}
+
 // setupMarkdownRenderer creates a glamour renderer configured for the terminal.
 func setupMarkdownRenderer() (*glamour.TermRenderer, error) {
 	width := 80
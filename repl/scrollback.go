@@ -0,0 +1,247 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// maxScrollbackLines bounds the session output kept for the pager.
+const maxScrollbackLines = 10000
+
+// scrollback keeps the lines printed above the input, for the pager.
+type scrollback struct {
+	mu    sync.Mutex
+	lines []string
+	total int // lines ever added, including ones dropped from the front
+}
+
+// add appends printed text.
+func (s *scrollback) add(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lines := strings.Split(text, "\n")
+	s.lines = append(s.lines, lines...)
+	s.total += len(lines)
+	if over := len(s.lines) - maxScrollbackLines; over > 0 {
+		s.lines = append([]string(nil), s.lines[over:]...)
+	}
+}
+
+// snapshot returns the kept lines and the running total.
+func (s *scrollback) snapshot() ([]string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.lines...), s.total
+}
+
+// since returns the lines added after the running total was seen.
+func (s *scrollback) since(seen int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := min(s.total-seen, len(s.lines))
+	return append([]string(nil), s.lines[len(s.lines)-n:]...)
+}
+
+// matchStyle highlights the current search match.
+var matchStyle = lipgloss.NewStyle().Reverse(true)
+
+// pager shows the session output full screen, for scrolling back and
+// searching. Output printed while it is open is kept and printed when it
+// closes, since nothing is printed above the program on the alternate screen.
+type pager struct {
+	open bool
+	view viewport.Model
+	seen int      // scrollback total when opened
+	doc  *yamlDoc // shown instead of the scrollback, see /view
+
+	lines     []string
+	plain     []string // lines without escape sequences, for searching
+	searching bool     // typing a query
+	query     string
+	matches   []int // line numbers matching query
+	current   int   // index into matches
+}
+
+// openPager shows the scrollback, scrolled to the end, and searches for
+// query if it isn't empty.
+func (m *model) openPager(query string) tea.Cmd {
+	lines, total := m.output.snapshot()
+	m.pager = pager{open: true, seen: total, view: viewport.New(m.width, max(m.height-1, 1))}
+	m.pager.setLines(lines)
+	m.pager.view.GotoBottom()
+	if query != "" {
+		m.pager.search(query)
+	}
+	return tea.EnterAltScreen
+}
+
+// closePager returns to the prompt and prints what arrived meanwhile.
+func (m *model) closePager() tea.Cmd {
+	m.pager.open = false
+	missed := m.output.since(m.pager.seen)
+	if len(missed) == 0 {
+		return tea.ExitAltScreen
+	}
+	return tea.Sequence(tea.ExitAltScreen, tea.Println(strings.Join(missed, "\n")))
+}
+
+// refreshPager shows output that arrived while the pager is open, following
+// it if the view is at the end.
+func (m *model) refreshPager() {
+	if m.pager.doc != nil {
+		return
+	}
+	lines, _ := m.output.snapshot()
+	follow := m.pager.view.AtBottom()
+	m.pager.setLines(lines)
+	if follow {
+		m.pager.view.GotoBottom()
+	}
+}
+
+// setLines replaces the content, keeping the query's matches up to date.
+func (p *pager) setLines(lines []string) {
+	p.lines = lines
+	p.plain = make([]string, len(lines))
+	for i, l := range lines {
+		p.plain[i] = ansi.Strip(l)
+	}
+	if p.query != "" {
+		p.findMatches()
+	}
+	p.render()
+}
+
+// render sets the viewport content with the current match highlighted.
+func (p *pager) render() {
+	content := p.lines
+	if len(p.matches) > 0 {
+		content = append([]string(nil), p.lines...)
+		n := p.matches[p.current]
+		content[n] = matchStyle.Render(p.plain[n])
+	}
+	p.view.SetContent(strings.Join(content, "\n"))
+}
+
+// findMatches collects the lines containing the query, ignoring case.
+func (p *pager) findMatches() {
+	p.matches = p.matches[:0]
+	q := strings.ToLower(p.query)
+	for i, l := range p.plain {
+		if strings.Contains(strings.ToLower(l), q) {
+			p.matches = append(p.matches, i)
+		}
+	}
+	p.current = min(p.current, max(len(p.matches)-1, 0))
+}
+
+// search finds query and shows the last match above the bottom of the
+// view, searching backwards from where the user is reading.
+func (p *pager) search(query string) {
+	p.query = query
+	p.current = 0
+	p.findMatches()
+	if len(p.matches) == 0 {
+		p.render()
+		return
+	}
+	bottom := p.view.YOffset + p.view.Height
+	for i, n := range p.matches {
+		if n < bottom {
+			p.current = i
+		}
+	}
+	p.show()
+}
+
+// next moves to the next (or previous) match, wrapping around.
+func (p *pager) next(step int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.current = (p.current + step + len(p.matches)) % len(p.matches)
+	p.show()
+}
+
+// show scrolls the current match into the middle of the view.
+func (p *pager) show() {
+	p.render()
+	p.view.SetYOffset(p.matches[p.current] - p.view.Height/2)
+}
+
+// updatePager handles a key while the pager is open.
+func (m *model) updatePager(msg tea.KeyMsg) tea.Cmd {
+	p := &m.pager
+	if p.searching {
+		switch msg.Type {
+		case tea.KeyEnter:
+			p.searching = false
+			p.search(p.query)
+		case tea.KeyEsc:
+			p.searching = false
+			p.query = ""
+			p.matches = nil
+			p.render()
+		case tea.KeyBackspace:
+			_, size := utf8.DecodeLastRuneInString(p.query)
+			p.query = p.query[:len(p.query)-size]
+		case tea.KeyRunes, tea.KeySpace:
+			p.query += string(msg.Runes)
+		}
+		return nil
+	}
+
+	switch msg.String() {
+	case "q", "esc", "ctrl+c":
+		return m.closePager()
+	case "/":
+		p.searching = true
+		p.query = ""
+		return nil
+	case "n":
+		p.next(1)
+		return nil
+	case "N":
+		p.next(-1)
+		return nil
+	case "g", "home":
+		p.view.GotoTop()
+		return nil
+	case "G", "end":
+		p.view.GotoBottom()
+		return nil
+	case "e":
+		if p.doc != nil {
+			return editDoc(p.doc)
+		}
+	}
+	var cmd tea.Cmd
+	p.view, cmd = p.view.Update(msg)
+	return cmd
+}
+
+// pagerView renders the pager and its status line.
+func (m *model) pagerView() string {
+	p := &m.pager
+	var status string
+	switch {
+	case p.searching:
+		status = "/" + p.query
+	case p.query != "" && len(p.matches) == 0:
+		status = fmt.Sprintf("%q not found · / search · q close", p.query)
+	case p.query != "":
+		status = fmt.Sprintf("%q %d/%d · n/N next/previous · / search · q close", p.query, p.current+1, len(p.matches))
+	case p.doc != nil:
+		status = fmt.Sprintf("%s · %d%% · / search · e open in $EDITOR · q close", p.doc.title, int(p.view.ScrollPercent()*100))
+	default:
+		status = fmt.Sprintf("%d%% · PgUp/PgDn scroll · / search · q close", int(p.view.ScrollPercent()*100))
+	}
+	return p.view.View() + "\n" + statusStyle.Render(status)
+}
@@ -19,8 +19,12 @@ type PlannedAction struct {
 
 // Plan represents a proposed set of actions awaiting approval.
 type Plan struct {
+	ID          string          `json:"id,omitempty"` // set by the propose_plan result
 	Description string          `json:"description"`
 	Actions     []PlannedAction `json:"actions"`
+	// Warnings are problems propose_plan found with the plan, such as an
+	// image that can't be pulled.
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // ClarificationQuestion represents a single question in a clarification request.
@@ -33,6 +37,41 @@ type ClarificationQuestion struct {
 type Clarification struct {
 	Context   string                  `json:"context"`
 	Questions []ClarificationQuestion `json:"questions"`
+
+	// CallID is the ID of the ask_clarification call the answers respond to.
+	CallID string `json:"-"`
+	// Answers holds the answers given so far, in question order.
+	Answers []ClarificationAnswer `json:"-"`
+}
+
+// ClarificationAnswer is the user's answer to one question.
+type ClarificationAnswer struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer,omitempty"`
+	Option   int    `json:"option,omitempty"`  // 1-based, when one of the options was picked
+	Skipped  bool   `json:"skipped,omitempty"` // left to the agent
+}
+
+// PickerItem is one resource the user can pick.
+type PickerItem struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// Picker is a list of resources the agent asked the user to pick from.
+type Picker struct {
+	Prompt   string       `json:"prompt"`
+	Items    []PickerItem `json:"items"`
+	Multiple bool         `json:"multiple,omitempty"`
+
+	// CallID is the ID of the pick_resource call the selection responds to.
+	CallID string `json:"-"`
+	// Cursor is the highlighted item; Selected the items picked so far
+	// when Multiple is set.
+	Cursor   int          `json:"-"`
+	Selected map[int]bool `json:"-"`
 }
 
 // SessionState tracks the execution state for plan/approval workflow.
@@ -40,6 +79,8 @@ type SessionState struct {
 	Mode                 ExecutionMode
 	PendingPlan          *Plan
 	PendingClarification *Clarification
+	PendingPick          *Picker
+	PendingConfirmations []DryRunConfirmation
 }
 
 // NewSessionState creates a new session state in planning mode.
@@ -72,13 +113,36 @@ func (s *SessionState) RejectPlan() {
 	s.Mode = ModePlanning
 }
 
+// HasPendingClarification returns true if questions await the user's answers.
+func (s *SessionState) HasPendingClarification() bool {
+	return s.PendingClarification != nil
+}
+
 // HasPendingPlan returns true if there's a plan awaiting approval.
 func (s *SessionState) HasPendingPlan() bool {
 	return s.PendingPlan != nil
 }
 
-// Reset clears any pending plan and returns to planning mode.
+// AddPendingConfirmation records a dry-run change awaiting user confirmation.
+func (s *SessionState) AddPendingConfirmation(c DryRunConfirmation) {
+	s.PendingConfirmations = append(s.PendingConfirmations, c)
+}
+
+// HasPendingConfirmation returns true if dry-run changes await confirmation.
+func (s *SessionState) HasPendingConfirmation() bool {
+	return len(s.PendingConfirmations) > 0
+}
+
+// TakeConfirmations returns and clears the pending confirmations.
+func (s *SessionState) TakeConfirmations() []DryRunConfirmation {
+	c := s.PendingConfirmations
+	s.PendingConfirmations = nil
+	return c
+}
+
+// Reset clears any pending plan or confirmation and returns to planning mode.
 func (s *SessionState) Reset() {
 	s.PendingPlan = nil
+	s.PendingConfirmations = nil
 	s.Mode = ModePlanning
 }
@@ -0,0 +1,68 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// shutdownGracePeriod bounds how long shutdown waits for an in-flight tool
+// call to return after its context has been cancelled.
+const shutdownGracePeriod = 5 * time.Second
+
+// shutdown cancels any running agent, waits briefly for the in-flight tool
+// call to finish so its result can be journaled, then flushes history and the
+// plan journal and reports which plan steps completed. It runs after the
+// bubbletea program has exited, so output goes straight to stderr.
+func (m model) shutdown(grace time.Duration) {
+	m.stopWatch()
+	if m.agentBusy && m.agentCancel != nil {
+		m.agentCancel()
+		m.drainAgentEvents(grace)
+	}
+
+	m.history.Save()
+
+	if m.journal == nil || m.state.Mode != ModeExecuting {
+		return
+	}
+
+	if m.journal.IsComplete() {
+		m.journal.Remove()
+		return
+	}
+
+	if err := m.journal.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	fmt.Fprintf(os.Stderr, "\nInterrupted during plan execution.\n%s", FormatJournal(m.journal))
+}
+
+// drainAgentEvents consumes events from the cancelled agent until its goroutine
+// reports completion or the grace period expires, recording tool results that
+// arrive in the meantime.
+func (m model) drainAgentEvents(grace time.Duration) {
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	for {
+		select {
+		case msg := <-m.eventCh:
+			if msg.done || msg.err != nil {
+				return
+			}
+			if m.journal == nil || msg.event == nil || msg.event.Content == nil {
+				continue
+			}
+			for _, part := range msg.event.Content.Parts {
+				if part.FunctionResponse != nil {
+					call := m.activity.finish(part.FunctionResponse)
+					m.journal.RecordResult(part.FunctionResponse.Name, call.arguments(), part.FunctionResponse.Response)
+				}
+			}
+		case <-timer.C:
+			fmt.Fprintln(os.Stderr, "Timed out waiting for the running tool call to finish.")
+			return
+		}
+	}
+}
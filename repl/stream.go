@@ -0,0 +1,90 @@
+package repl
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"google.golang.org/adk/session"
+)
+
+// streamRenderInterval limits how often the streamed reply is rendered as
+// markdown again; glamour renders the whole text every time.
+const streamRenderInterval = 50 * time.Millisecond
+
+// streamText is the reply being streamed. It is shown above the status
+// line as it arrives and replaced by the complete reply, which is printed
+// like any other, once the model has finished it.
+type streamText struct {
+	text       strings.Builder
+	rendered   string
+	renderedAt time.Time
+	pending    bool // a streamRenderMsg is on its way
+}
+
+// streamRenderMsg renders the deltas that arrived since the last render.
+type streamRenderMsg struct{}
+
+// appendStream adds the text of a partial event to the streamed reply and
+// renders it, or schedules a render if the last one was too recent.
+func (m *model) appendStream(event *session.Event) tea.Cmd {
+	if event.Content == nil {
+		return nil
+	}
+	if m.stream == nil {
+		m.stream = &streamText{}
+	}
+	for _, part := range event.Content.Parts {
+		if part.Text != "" && !part.Thought {
+			m.stream.text.WriteString(part.Text)
+		}
+	}
+
+	wait := streamRenderInterval - time.Since(m.stream.renderedAt)
+	if wait <= 0 {
+		m.renderStream()
+		return nil
+	}
+	if m.stream.pending {
+		return nil
+	}
+	m.stream.pending = true
+	return tea.Tick(wait, func(time.Time) tea.Msg { return streamRenderMsg{} })
+}
+
+// renderStream renders the streamed reply so far. YAML is only collapsed
+// in the complete reply, where it is numbered for /view.
+func (m *model) renderStream() {
+	if m.stream == nil {
+		return
+	}
+	m.stream.rendered = m.renderMarkdown(m.stream.text.String())
+	m.stream.renderedAt = time.Now()
+	m.stream.pending = false
+}
+
+// flushStream prints what was streamed of a reply the model didn't finish,
+// because the run failed or was cancelled, so it isn't lost.
+func (m *model) flushStream() {
+	if m.stream == nil {
+		return
+	}
+	if text := m.stream.text.String(); text != "" && m.program != nil {
+		m.program.Println(m.renderMarkdown(text))
+	}
+	m.stream = nil
+}
+
+// streamView returns the streamed reply as shown above the status line:
+// its last lines, leaving the reserved lines of the terminal for the
+// activity panel, the status line and the input.
+func (m *model) streamView(reserved int) string {
+	if m.stream == nil || m.stream.rendered == "" {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(m.stream.rendered, "\n"), "\n")
+	if limit := max(m.height-reserved, 1); m.height > 0 && len(lines) > limit {
+		lines = lines[len(lines)-limit:]
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
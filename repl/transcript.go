@@ -0,0 +1,202 @@
+package repl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/session"
+)
+
+// transcriptVersion is the format version written by /export.
+const transcriptVersion = 1
+
+// Transcript entry types.
+const (
+	EntryMessage    = "message"
+	EntryToolCall   = "tool_call"
+	EntryToolResult = "tool_result"
+	EntryPlan       = "plan"
+)
+
+// TranscriptEntry is one message, tool call, tool result or proposed plan.
+type TranscriptEntry struct {
+	Time   time.Time      `json:"time"`
+	Author string         `json:"author"`
+	Type   string         `json:"type"`
+	Text   string         `json:"text,omitempty"`
+	Tool   string         `json:"tool,omitempty"`
+	ID     string         `json:"id,omitempty"` // pairs a tool call with its result
+	Args   map[string]any `json:"args,omitempty"`
+	Result map[string]any `json:"result,omitempty"`
+	Plan   *Plan          `json:"plan,omitempty"`
+}
+
+// Transcript is the exported history of a session.
+type Transcript struct {
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Entries    []TranscriptEntry `json:"entries"`
+}
+
+// NewTranscript builds a transcript from session events.
+func NewTranscript(events session.Events) *Transcript {
+	t := &Transcript{Version: transcriptVersion, ExportedAt: time.Now()}
+	for event := range events.All() {
+		if event == nil || event.Partial || event.Content == nil {
+			continue
+		}
+		for _, part := range event.Content.Parts {
+			entry := TranscriptEntry{Time: event.Timestamp, Author: event.Author}
+			switch {
+			case part.FunctionCall != nil:
+				entry.Type = EntryToolCall
+				entry.Tool = part.FunctionCall.Name
+				entry.ID = part.FunctionCall.ID
+				entry.Args = part.FunctionCall.Args
+				if entry.Tool == "propose_plan" && entry.Args != nil {
+					entry.Plan = ParsePlanFromResponse(entry.Args)
+					if entry.Plan != nil {
+						entry.Type = EntryPlan
+					}
+				}
+			case part.FunctionResponse != nil:
+				entry.Type = EntryToolResult
+				entry.Tool = part.FunctionResponse.Name
+				entry.ID = part.FunctionResponse.ID
+				entry.Result = part.FunctionResponse.Response
+			case part.Text != "" && !part.Thought:
+				entry.Type = EntryMessage
+				entry.Text = part.Text
+			default:
+				continue
+			}
+			t.Entries = append(t.Entries, entry)
+		}
+	}
+	return t
+}
+
+// LoadTranscript reads a transcript written by /export in JSON.
+func LoadTranscript(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t Transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if t.Version != transcriptVersion {
+		return nil, fmt.Errorf("%s: unsupported transcript version %d (export it as .json with /export)", path, t.Version)
+	}
+	return &t, nil
+}
+
+// Write saves the transcript to path: JSON if it ends in .json, markdown
+// otherwise.
+func (t *Transcript) Write(path string) error {
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var err error
+		data, err = json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	} else {
+		data = []byte(t.Markdown())
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Markdown renders the transcript for reading.
+func (t *Transcript) Markdown() string {
+	var md strings.Builder
+	fmt.Fprintf(&md, "# Kasa transcript\n\nExported %s.\n", t.ExportedAt.Format(time.RFC1123))
+	for _, e := range t.Entries {
+		stamp := e.Time.Format("15:04:05")
+		switch e.Type {
+		case EntryMessage:
+			fmt.Fprintf(&md, "\n## %s (%s)\n\n%s\n", e.Author, stamp, strings.TrimSpace(e.Text))
+		case EntryToolCall:
+			fmt.Fprintf(&md, "\n**Tool call** `%s` (%s)\n\n", e.Tool, stamp)
+			writeJSONBlock(&md, e.Args)
+		case EntryToolResult:
+			fmt.Fprintf(&md, "\n**Result** `%s`\n\n", e.Tool)
+			writeJSONBlock(&md, e.Result)
+		case EntryPlan:
+			fmt.Fprintf(&md, "\n## Plan proposed by %s (%s)\n\n%s\n", e.Author, stamp, e.Plan.Description)
+			for i, a := range e.Plan.Actions {
+				fmt.Fprintf(&md, "\n%d. `%s`: %s\n\n", i+1, a.Tool, a.Reason)
+				writeJSONBlock(&md, a.Parameters)
+			}
+		}
+	}
+	return md.String()
+}
+
+// writeJSONBlock writes v as an indented JSON code block.
+func writeJSONBlock(md *strings.Builder, v map[string]any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		data = []byte(fmt.Sprintf("%v", v))
+	}
+	fmt.Fprintf(md, "```json\n%s\n```\n", data)
+}
+
+// ReplayActions returns the changes made in the recorded session: the calls
+// to mutating tools that succeeded, in order. Failed, refused and dry-run
+// calls are left out, and so are calls the dry-run policy held back for
+// confirmation.
+func (t *Transcript) ReplayActions(isMutating func(string) bool) []PlannedAction {
+	results := make(map[string]map[string]any)
+	for _, e := range t.Entries {
+		if e.Type == EntryToolResult && e.ID != "" {
+			results[e.ID] = e.Result
+		}
+	}
+
+	var actions []PlannedAction
+	for _, e := range t.Entries {
+		if e.Type != EntryToolCall || !isMutating(e.Tool) {
+			continue
+		}
+		if dryRun, _ := e.Args["dry_run"].(bool); dryRun {
+			continue
+		}
+		result, ok := results[e.ID]
+		if !ok || result["error"] != nil || result["dry_run"] == true || result["confirmation_required"] == true {
+			continue
+		}
+		reason, _ := e.Args["change_cause"].(string)
+		if reason == "" {
+			reason = fmt.Sprintf("Recorded at %s", e.Time.Format(time.DateTime))
+		}
+		actions = append(actions, PlannedAction{Tool: e.Tool, Parameters: e.Args, Reason: reason})
+	}
+	return actions
+}
+
+// exportTranscript handles "/export [path]".
+func exportTranscript(ctx context.Context, sessions session.Service, path string) string {
+	if sessions == nil {
+		return "Export is not available in this session."
+	}
+	resp, err := sessions.Get(ctx, &session.GetRequest{AppName: "kasa", UserID: "user1", SessionID: "session1"})
+	if err != nil {
+		return fmt.Sprintf("Failed to read the session: %v", err)
+	}
+	if path == "" {
+		path = fmt.Sprintf("kasa-transcript-%s.md", time.Now().Format("20060102-150405"))
+	}
+	t := NewTranscript(resp.Session.Events())
+	if err := t.Write(path); err != nil {
+		return fmt.Sprintf("Export failed: %v", err)
+	}
+	return fmt.Sprintf("Exported %d entries to %s.", len(t.Entries), path)
+}
@@ -0,0 +1,32 @@
+package repl
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTranscript_ReplayActions(t *testing.T) {
+	call := func(id string, args map[string]any) TranscriptEntry {
+		return TranscriptEntry{Type: EntryToolCall, Tool: "set_image", ID: id, Args: args}
+	}
+	result := func(id string, result map[string]any) TranscriptEntry {
+		return TranscriptEntry{Type: EntryToolResult, Tool: "set_image", ID: id, Result: result}
+	}
+	transcript := &Transcript{Entries: []TranscriptEntry{
+		call("applied", map[string]any{"name": "web"}), result("applied", map[string]any{"success": true}),
+		call("failed", map[string]any{"name": "api"}), result("failed", map[string]any{"error": "not found"}),
+		call("dry", map[string]any{"name": "web", "dry_run": true}), result("dry", map[string]any{"success": true}),
+		call("held", map[string]any{"name": "worker"}), result("held", map[string]any{"confirmation_required": true, "tool": "set_image"}),
+		call("unanswered", map[string]any{"name": "cron"}),
+		{Type: EntryToolCall, Tool: "list_pods", ID: "read", Args: map[string]any{}}, {Type: EntryToolResult, Tool: "list_pods", ID: "read"},
+	}}
+
+	actions := transcript.ReplayActions(func(name string) bool { return name == "set_image" })
+	var names []string
+	for _, a := range actions {
+		names = append(names, a.Parameters["name"].(string))
+	}
+	if !slices.Equal(names, []string{"web"}) {
+		t.Errorf("replayed %v, want only the applied call", names)
+	}
+}
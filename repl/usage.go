@@ -0,0 +1,29 @@
+package repl
+
+// UsageReporter reports the token usage of the session for the /usage
+// command. Implementations must be safe for concurrent use.
+type UsageReporter interface {
+	// FormatUsage renders cumulative tokens and estimated cost.
+	FormatUsage() string
+	// BudgetWarning returns a warning once usage nears the token budget,
+	// and "" otherwise.
+	BudgetWarning() string
+	// Reset clears the counters.
+	Reset()
+}
+
+// handleUsageCommand returns the output of "/usage" or "/usage reset".
+func handleUsageCommand(u UsageReporter, arg string) string {
+	if u == nil {
+		return "Token usage is not tracked."
+	}
+	switch arg {
+	case "":
+		return u.FormatUsage()
+	case "reset":
+		u.Reset()
+		return "Token usage cleared."
+	default:
+		return "Usage: /usage [reset]"
+	}
+}
@@ -0,0 +1,172 @@
+package repl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// watchAlertInterval is the least time between two alerts for watched
+// events, so a crash loop rings the bell once rather than on every restart.
+const watchAlertInterval = time.Minute
+
+// EventWatcher streams the cluster's Warning events for /watch.
+type EventWatcher interface {
+	// WatchWarnings calls fn with each new Warning event in namespace (""
+	// for all) about objects of kind ("" for any), on one line, until ctx
+	// is cancelled.
+	WatchWarnings(ctx context.Context, namespace, kind string, fn func(string)) error
+}
+
+// eventWatch is the /watch running in the background.
+type eventWatch struct {
+	desc      string // what is watched, e.g. "Warning events in prod"
+	cancel    context.CancelFunc
+	ch        chan watchEventMsg
+	lastAlert time.Time
+}
+
+// watchEventMsg is a Warning event seen by a watch, or the end of the watch.
+type watchEventMsg struct {
+	watch *eventWatch
+	line  string
+	err   error
+	done  bool
+}
+
+// watchStyle is the style of watched events.
+var watchStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+
+// watch starts, stops or shows the event watch.
+func (m *model) watch(arg string) tea.Cmd {
+	if m.events == nil {
+		m.println("Watching events is not available.")
+		return nil
+	}
+	fields := strings.Fields(arg)
+	switch {
+	case len(fields) == 0 && m.eventWatch != nil:
+		m.println(fmt.Sprintf("Watching %s. /watch stop ends it.", m.eventWatch.desc))
+		return nil
+	case len(fields) == 1 && fields[0] == "stop":
+		if m.eventWatch == nil {
+			m.println("No watch is running.")
+			return nil
+		}
+		m.println(fmt.Sprintf("Stopped watching %s.", m.eventWatch.desc))
+		m.stopWatch()
+		return nil
+	case len(fields) > 2:
+		m.println("Usage: /watch [namespace|all] [kind] | stop")
+		return nil
+	}
+
+	namespace, kind := "", ""
+	if len(fields) > 0 && fields[0] != "all" {
+		namespace = fields[0]
+	}
+	if len(fields) > 1 {
+		kind = fields[1]
+	}
+	m.stopWatch()
+
+	ctx, cancel := context.WithCancel(m.baseCtx)
+	w := &eventWatch{
+		desc:   describeWatch(namespace, kind),
+		cancel: cancel,
+		ch:     make(chan watchEventMsg, 64),
+	}
+	m.eventWatch = w
+	m.println(fmt.Sprintf("Watching %s. /watch stop ends it.", w.desc))
+
+	events := m.events
+	go func() {
+		err := events.WatchWarnings(ctx, namespace, kind, func(line string) {
+			select {
+			case w.ch <- watchEventMsg{watch: w, line: line}:
+			case <-ctx.Done():
+			}
+		})
+		// Nobody reads the channel once the watch was replaced or stopped
+		select {
+		case w.ch <- watchEventMsg{watch: w, err: err, done: true}:
+		case <-ctx.Done():
+		}
+		close(w.ch)
+	}()
+	return waitForWatch(w)
+}
+
+// waitForWatch returns a command that waits for the next message of w.
+func waitForWatch(w *eventWatch) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-w.ch
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// stopWatch ends the running event watch, if any.
+func (m *model) stopWatch() {
+	if m.eventWatch != nil {
+		m.eventWatch.cancel()
+		m.eventWatch = nil
+	}
+}
+
+// watchEvent prints a watched event above the prompt and, if the terminal is
+// in the background, alerts the user.
+func (m *model) watchEvent(msg watchEventMsg) tea.Cmd {
+	w := msg.watch
+	if w != m.eventWatch {
+		return nil // the watch was stopped or replaced
+	}
+	if msg.done {
+		m.eventWatch = nil
+		w.cancel()
+		if msg.err != nil {
+			m.println(fmt.Sprintf("Stopped watching %s: %v", w.desc, msg.err))
+		}
+		return nil
+	}
+
+	m.println(watchStyle.Render("⚠ " + msg.line))
+	cmd := waitForWatch(w)
+	if m.alerts.Disabled || m.focused || time.Since(w.lastAlert) < watchAlertInterval {
+		return cmd
+	}
+	w.lastAlert = time.Now()
+	message, desktop := msg.line, m.alerts.Desktop
+	return tea.Batch(cmd, func() tea.Msg {
+		alert(message, desktop)
+		return nil
+	})
+}
+
+// describeWatch describes what a watch reports, e.g. "Warning events about
+// Pods in prod".
+func describeWatch(namespace, kind string) string {
+	desc := "Warning events"
+	if kind != "" {
+		desc = "Warning events about " + kind + "s"
+	}
+	if namespace == "" {
+		return desc + " in all namespaces"
+	}
+	return desc + " in " + namespace
+}
+
+// watchArgs completes /watch with namespaces.
+func watchArgs(m *model) []string {
+	args := []string{"stop", "all"}
+	if m.completer != nil {
+		args = append(args, m.completer.Namespaces()...)
+	}
+	return args
+}
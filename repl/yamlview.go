@@ -0,0 +1,191 @@
+package repl
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/quick"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"sigs.k8s.io/yaml"
+)
+
+// YAML viewer limits: documents kept for /view, and the length above which a
+// YAML block in the agent's reply is collapsed into a link to the viewer.
+const (
+	maxYAMLDocs        = 20
+	maxInlineYAMLLines = 20
+)
+
+// yamlDoc is a manifest or resource shown in the viewer.
+type yamlDoc struct {
+	number  int // what /view takes, counting from 1 over the session
+	title   string
+	content string
+}
+
+// lines returns the number of lines of the document.
+func (d *yamlDoc) lines() int {
+	return strings.Count(strings.TrimRight(d.content, "\n"), "\n") + 1
+}
+
+// yamlFromResponse returns the YAML of a get_resource or read_manifest result.
+func yamlFromResponse(tool string, resp map[string]any) (yamlDoc, bool) {
+	if errVal, ok := resp["error"]; ok && errVal != nil {
+		return yamlDoc{}, false
+	}
+	switch tool {
+	case "get_resource":
+		resource, ok := resp["resource"].(map[string]any)
+		if !ok {
+			return yamlDoc{}, false
+		}
+		data, err := yaml.Marshal(resource)
+		if err != nil {
+			return yamlDoc{}, false
+		}
+		return yamlDoc{title: resourceTitle(resource), content: string(data)}, true
+	case "read_manifest":
+		content, _ := resp["content"].(string)
+		if content == "" {
+			return yamlDoc{}, false
+		}
+		title, _ := resp["path"].(string)
+		if p, _ := resp["yaml_path"].(string); p != "" {
+			title += " " + p
+		}
+		if truncated, _ := resp["truncated"].(bool); truncated {
+			title += fmt.Sprintf(" (lines %v-%v of %v)", resp["start_line"], resp["end_line"], resp["total_lines"])
+		}
+		return yamlDoc{title: title, content: content}, true
+	}
+	return yamlDoc{}, false
+}
+
+// resourceTitle names a resource as Kind namespace/name.
+func resourceTitle(obj map[string]any) string {
+	kind, _ := obj["kind"].(string)
+	meta, _ := obj["metadata"].(map[string]any)
+	name, _ := meta["name"].(string)
+	if ns, _ := meta["namespace"].(string); ns != "" {
+		name = ns + "/" + name
+	}
+	if kind == "" {
+		kind = "YAML"
+	}
+	return strings.TrimSpace(kind + " " + name)
+}
+
+// keepDoc numbers d and keeps it for /view, dropping the oldest documents.
+func (m *model) keepDoc(d yamlDoc) yamlDoc {
+	m.docCount++
+	d.number = m.docCount
+	m.docs = append(m.docs, d)
+	if len(m.docs) > maxYAMLDocs {
+		m.docs = m.docs[len(m.docs)-maxYAMLDocs:]
+	}
+	return d
+}
+
+// addDoc keeps a tool's YAML for /view and prints a line pointing at it.
+func (m *model) addDoc(d yamlDoc) {
+	d = m.keepDoc(d)
+	m.println(statusStyle.Render(fmt.Sprintf("[%d] %s, %d lines · /view %d", d.number, d.title, d.lines(), d.number)))
+}
+
+// yamlBlock matches a fenced YAML block in markdown.
+var yamlBlock = regexp.MustCompile("(?s)```ya?ml\n(.*?)```")
+
+// collapseYAML moves long YAML blocks of the agent's reply to the viewer,
+// leaving a line that says how to open them.
+func (m *model) collapseYAML(text string) string {
+	return yamlBlock.ReplaceAllStringFunc(text, func(block string) string {
+		content := yamlBlock.FindStringSubmatch(block)[1]
+		if strings.Count(content, "\n") <= maxInlineYAMLLines {
+			return block
+		}
+		var obj map[string]any
+		title := "YAML"
+		if yaml.Unmarshal([]byte(content), &obj) == nil && obj != nil {
+			title = resourceTitle(obj)
+		}
+		d := m.keepDoc(yamlDoc{title: title, content: content})
+		return fmt.Sprintf("*[%d] %s, %d lines · `/view %d`*", d.number, d.title, d.lines(), d.number)
+	})
+}
+
+// highlightYAML colors YAML for the terminal, falling back to plain text.
+func highlightYAML(content string) string {
+	var buf bytes.Buffer
+	if err := quick.Highlight(&buf, content, "yaml", "terminal256", "monokai"); err != nil {
+		return content
+	}
+	return buf.String()
+}
+
+// openDoc shows d full screen in the pager, highlighted.
+func (m *model) openDoc(d *yamlDoc) tea.Cmd {
+	_, total := m.output.snapshot()
+	m.pager = pager{open: true, seen: total, doc: d, view: viewport.New(m.width, max(m.height-1, 1))}
+	m.pager.setLines(strings.Split(strings.TrimRight(highlightYAML(d.content), "\n"), "\n"))
+	return tea.EnterAltScreen
+}
+
+// editorDoneMsg reports that the editor opened from the viewer exited.
+type editorDoneMsg struct {
+	path     string
+	original string
+	err      error
+}
+
+// editDoc opens a copy of d in $VISUAL or $EDITOR.
+func editDoc(d *yamlDoc) tea.Cmd {
+	file, err := writeTemp([]byte(d.content))
+	if err != nil {
+		return tea.Println(fmt.Sprintf("Warning: %v", err))
+	}
+	return tea.ExecProcess(editorCommand(file), func(err error) tea.Msg {
+		return editorDoneMsg{path: file, original: d.content, err: err}
+	})
+}
+
+// editorDone removes the copy unless it was changed, and says where it is.
+func editorDone(msg editorDoneMsg) string {
+	if msg.err != nil {
+		os.Remove(msg.path)
+		return fmt.Sprintf("Warning: editor: %v", msg.err)
+	}
+	data, err := os.ReadFile(msg.path)
+	if err != nil || string(data) == msg.original {
+		os.Remove(msg.path)
+		return ""
+	}
+	return fmt.Sprintf("Your edited copy is in %s; ask the agent to apply it if you want the change.", msg.path)
+}
+
+// view opens the document numbered arg, or the latest, in the viewer.
+func (m *model) view(arg string) tea.Cmd {
+	if len(m.docs) == 0 {
+		m.println("No YAML to show yet.")
+		return nil
+	}
+	if arg == "" {
+		return m.openDoc(&m.docs[len(m.docs)-1])
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	if err != nil {
+		m.println("Usage: /view [number]")
+		return nil
+	}
+	for i := range m.docs {
+		if m.docs[i].number == n {
+			return m.openDoc(&m.docs[i])
+		}
+	}
+	m.println(fmt.Sprintf("No YAML numbered %d; the last %d are kept.", n, maxYAMLDocs))
+	return nil
+}
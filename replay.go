@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/perbu/kasa/repl"
+	"github.com/perbu/kasa/tools"
+	"google.golang.org/adk/tool"
+)
+
+// namespaceMap is a repeatable -map-namespace from=to flag.
+type namespaceMap map[string]string
+
+func (m namespaceMap) String() string {
+	pairs := make([]string, 0, len(m))
+	for from, to := range m {
+		pairs = append(pairs, from+"="+to)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m namespaceMap) Set(value string) error {
+	from, to, ok := strings.Cut(value, "=")
+	if !ok || from == "" || to == "" {
+		return fmt.Errorf("expected from=to, got %q", value)
+	}
+	m[from] = to
+	return nil
+}
+
+// apply rewrites the namespace argument and the namespace fields of
+// manifests passed as yaml.
+func (m namespaceMap) apply(params map[string]any) map[string]any {
+	if len(m) == 0 {
+		return params
+	}
+	mapped := make(map[string]any, len(params))
+	for k, v := range params {
+		mapped[k] = v
+	}
+	if ns, ok := mapped["namespace"].(string); ok && m[ns] != "" {
+		mapped["namespace"] = m[ns]
+	}
+	if manifest, ok := mapped["yaml"].(string); ok {
+		mapped["yaml"] = yamlNamespace.ReplaceAllStringFunc(manifest, func(line string) string {
+			parts := yamlNamespace.FindStringSubmatch(line)
+			if to := m[parts[2]]; to != "" {
+				return parts[1] + to
+			}
+			return line
+		})
+	}
+	return mapped
+}
+
+var yamlNamespace = regexp.MustCompile(`(?m)^(\s*namespace:\s*)"?([a-z0-9.-]+)"?[ \t]*$`)
+
+// runReplay implements "kasa replay": it re-executes the changes recorded in
+// a transcript exported with /export against the cluster kasa is connected
+// to, after the user approves them. The calls run through the same tools
+// and policies as in the REPL, without the model.
+func runReplay(ctx context.Context, args []string, kubeTools *tools.KubeTools, cluster clusterInfo) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	namespaces := namespaceMap{}
+	fs.Var(namespaces, "map-namespace", "Replay changes in namespace from into namespace to (from=to, repeatable)")
+	yes := fs.Bool("yes", false, "Apply without asking for approval")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: kasa replay [-map-namespace from=to]... [-yes] <transcript.json>")
+		return 2
+	}
+
+	transcript, err := repl.LoadTranscript(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	actions := transcript.ReplayActions(kubeTools.IsMutatingName)
+	if len(actions) == 0 {
+		fmt.Println("The transcript records no changes to replay.")
+		return 0
+	}
+	for i := range actions {
+		actions[i].Parameters = namespaces.apply(actions[i].Parameters)
+	}
+
+	repl.DisplayPlan(&repl.Plan{
+		Description: fmt.Sprintf("Replay %d change(s) from %s against cluster %s (context %s).", len(actions), fs.Arg(0), cluster.Cluster, cluster.Context),
+		Actions:     actions,
+	})
+	if !*yes && !confirmReplay() {
+		fmt.Println("Nothing was applied.")
+		return 1
+	}
+
+	byName := make(map[string]tool.Tool)
+	for _, t := range kubeTools.All() {
+		byName[t.Name()] = t
+	}
+	// Later changes may depend on earlier ones, so stop at the first failure
+	for i, action := range actions {
+		if ctx.Err() != nil {
+			fmt.Printf("Interrupted; %d of %d change(s) not applied.\n", len(actions)-i, len(actions))
+			return 1
+		}
+		var failure any
+		t, ok := byName[action.Tool].(interface {
+			Run(tool.Context, any) (map[string]any, error)
+		})
+		if !ok {
+			failure = "no such tool"
+		} else if result, err := t.Run(tools.WithContext(nil, ctx), action.Parameters); err != nil {
+			failure = err
+		} else if result["error"] != nil {
+			failure = result["error"]
+		} else if result["confirmation_required"] == true {
+			failure = "not applied: the dry-run policy requires confirming the change, which replay can't do"
+		}
+		if failure != nil {
+			fmt.Printf("%d. %s: FAILED: %v\n", i+1, action.Tool, failure)
+			fmt.Printf("Stopped; %d of %d change(s) not applied.\n", len(actions)-i, len(actions))
+			return 1
+		}
+		fmt.Printf("%d. %s: ok\n", i+1, action.Tool)
+	}
+	return 0
+}
+
+// confirmReplay asks the user to approve the replay on stdin.
+func confirmReplay() bool {
+	fmt.Print("Apply these changes? [y/N] ")
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
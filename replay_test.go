@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/perbu/kasa/manifest"
+	"github.com/perbu/kasa/repl"
+	"github.com/perbu/kasa/tools"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const registryPolicy = `apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: trusted-registry
+spec:
+  validationFailureAction: Enforce
+  rules:
+  - name: registry
+    match:
+      any:
+      - resources:
+          kinds: [Pod]
+    validate:
+      message: "Images must come from registry.example.com."
+      pattern:
+        spec:
+          containers:
+          - image: "registry.example.com/*"
+`
+
+// replayTools returns tools against an API server that serves one
+// deployment, shop/web, with the trusted-registry policy enforced, and
+// their client config. writes counts the write requests the server
+// receives, dry runs excepted.
+func replayTools(t *testing.T, writes *int) (*tools.KubeTools, *rest.Config) {
+	t.Helper()
+	live := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "web", Image: "registry.example.com/web:1.0"}},
+		}}},
+		Status: appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.URL.Query().Get("dryRun") == "" {
+			*writes++
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(live)
+	}))
+	t.Cleanup(srv.Close)
+	config := &rest.Config{Host: srv.URL}
+	tools.ConfigureAPIClient(config, tools.APIOptions{MaxRetries: -1})
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	mgr, err := manifest.NewManager(filepath.Join(dir, "manifests"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.EnsureGitInit(); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"user.email", "test@test.com"}, {"user.name", "Test"}} {
+		cmd := exec.Command("git", append([]string{"config"}, args...)...)
+		cmd.Dir = filepath.Join(dir, "manifests")
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stored := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: registry.example.com/web:1.0
+`
+	if _, err := mgr.SaveManifest("shop", "web", "deployment", []byte(stored)); err != nil {
+		t.Fatal(err)
+	}
+
+	policies := filepath.Join(dir, "policies")
+	if err := os.Mkdir(policies, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(policies, "registry.yaml"), []byte(registryPolicy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	k := tools.NewKubeTools(clientset, dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), mgr, "", "")
+	if err := k.SetPolicies(tools.PolicyConfig{Path: policies}); err != nil {
+		t.Fatal(err)
+	}
+	return k, config
+}
+
+// writeTranscript exports a transcript of one successful call to path.
+func writeTranscript(t *testing.T, toolName string, args map[string]any) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	transcript := &repl.Transcript{Version: 1, Entries: []repl.TranscriptEntry{
+		{Type: repl.EntryToolCall, Tool: toolName, ID: "call_0", Args: args},
+		{Type: repl.EntryToolResult, Tool: toolName, ID: "call_0", Result: map[string]any{"success": true}},
+	}}
+	if err := transcript.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReplay_PolicyDenied(t *testing.T) {
+	var writes int
+	k, _ := replayTools(t, &writes)
+	path := writeTranscript(t, "set_image", map[string]any{"namespace": "shop", "name": "web", "image": "docker.io/web:2.0"})
+
+	if code := runReplay(context.Background(), []string{"-yes", path}, k, clusterInfo{}); code != 1 {
+		t.Errorf("runReplay = %d, want 1 for a change the policy refuses", code)
+	}
+	if writes != 0 {
+		t.Errorf("the refused change was sent: %d writes", writes)
+	}
+
+	// An image the policy allows is replayed
+	path = writeTranscript(t, "set_image", map[string]any{"namespace": "shop", "name": "web", "image": "registry.example.com/web:2.0"})
+	if code := runReplay(context.Background(), []string{"-yes", path}, k, clusterInfo{}); code != 0 || writes != 1 {
+		t.Errorf("runReplay = %d with %d writes, want the allowed change applied", code, writes)
+	}
+}
+
+func TestReplay_DryRunEnforced(t *testing.T) {
+	var writes int
+	k, config := replayTools(t, &writes)
+	dryClientset, dryDynamic, err := tools.NewDryRunClients(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.SetDryRunPolicy(tools.DryRunPolicy{Namespaces: []string{"shop"}}, dryClientset, dryDynamic)
+	path := writeTranscript(t, "set_image", map[string]any{"namespace": "shop", "name": "web", "image": "registry.example.com/web:2.0"})
+
+	// The change is only validated, so the replay must not report it applied
+	if code := runReplay(context.Background(), []string{"-yes", path}, k, clusterInfo{}); code != 1 {
+		t.Errorf("runReplay = %d, want 1 for a change held back for confirmation", code)
+	}
+	if writes != 0 {
+		t.Errorf("the held back change was sent: %d writes", writes)
+	}
+}
@@ -0,0 +1,682 @@
+// Package server exposes the kasa agent over HTTP so it can be driven by a web
+// UI, chat bot, or other automation without re-implementing the agent loop.
+//
+// Endpoints:
+//
+//	POST /sessions                   create a session
+//	GET  /sessions/{id}              session status and pending plan
+//	POST /sessions/{id}/messages     send a user message (runs asynchronously)
+//	GET  /sessions/{id}/events       Server-Sent Events stream of agent events
+//	POST /sessions/{id}/approve      approve the pending plan and execute it
+//	POST /sessions/{id}/reject       reject the pending plan
+//
+// With SetToken, each of them requires the token as a bearer token.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/perbu/kasa/agents"
+	"github.com/perbu/kasa/budget"
+	"github.com/perbu/kasa/metrics"
+	"github.com/perbu/kasa/repl"
+	"github.com/perbu/kasa/tracing"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// userID is the ADK user all API sessions are created under.
+const userID = "api"
+
+// maxBufferedEvents bounds the per-session event history kept for replay to
+// clients that connect (or reconnect) to the event stream.
+const maxBufferedEvents = 1000
+
+// Server serves the kasa HTTP API.
+type Server struct {
+	runner         *runner.Runner
+	sessionService session.Service
+	appName        string
+
+	mu       sync.Mutex
+	sessions map[string]*apiSession
+
+	baseCtx   context.Context
+	notifier  repl.Notifier
+	confirmer repl.DryRunConfirmer
+	metrics   *metrics.Metrics
+	usage     *budget.Tracker
+	token     string
+	extra     map[string]http.Handler
+}
+
+// New creates a Server backed by the given runner and session service.
+// The runner must have been created with the same session service and app name.
+func New(ctx context.Context, r *runner.Runner, sessionService session.Service, appName string) *Server {
+	return &Server{
+		runner:         r,
+		sessionService: sessionService,
+		appName:        appName,
+		sessions:       make(map[string]*apiSession),
+		baseCtx:        ctx,
+		extra:          make(map[string]http.Handler),
+	}
+}
+
+// SetNotifier registers a Notifier for plans and tool results.
+func (s *Server) SetNotifier(n repl.Notifier) {
+	s.notifier = n
+}
+
+// SetDryRunConfirmer registers the DryRunConfirmer that is told when a client
+// confirms changes that were forced into dry-run.
+func (s *Server) SetDryRunConfirmer(c repl.DryRunConfirmer) {
+	s.confirmer = c
+}
+
+// SetMetrics registers the metrics plan decisions are counted in, and
+// serves them on /metrics.
+func (s *Server) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+	if m != nil {
+		s.Handle("GET /metrics", m.Handler())
+	}
+}
+
+// SetUsage registers the Tracker whose per-session token usage and cost is
+// reported with each session.
+func (s *Server) SetUsage(t *budget.Tracker) {
+	s.usage = t
+}
+
+// SetToken requires every session request, approve and reject included, to
+// carry the token as "Authorization: Bearer <token>". Handlers mounted with
+// Handle authenticate their callers themselves.
+func (s *Server) SetToken(token string) {
+	s.token = token
+}
+
+// Handle mounts an additional handler, e.g. an integration callback endpoint.
+// Must be called before Handler or ListenAndServe.
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.extra[pattern] = h
+}
+
+// Handler returns the HTTP handler for the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("POST /sessions", s.authorize(s.handleCreateSession))
+	mux.Handle("GET /sessions/{id}", s.authorize(s.handleGetSession))
+	mux.Handle("POST /sessions/{id}/messages", s.authorize(s.handleMessage))
+	mux.Handle("GET /sessions/{id}/events", s.authorize(s.handleEvents))
+	mux.Handle("POST /sessions/{id}/approve", s.authorize(s.handleApprove))
+	mux.Handle("POST /sessions/{id}/reject", s.authorize(s.handleReject))
+	for pattern, h := range s.extra {
+		mux.Handle(pattern, h)
+	}
+	return mux
+}
+
+// authorize refuses requests without the token set with SetToken.
+func (s *Server) authorize(h http.HandlerFunc) http.Handler {
+	if s.token == "" {
+		return h
+	}
+	want := []byte("Bearer " + s.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "missing or wrong bearer token")
+			return
+		}
+		h(w, r)
+	})
+}
+
+// Loopback reports whether addr, a listen address such as "127.0.0.1:8080",
+// only accepts connections from this machine.
+func Loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ListenAndServe serves the API on addr until ctx is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		s.cancelAll()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// apiSession tracks a single conversation and its plan/approval state.
+type apiSession struct {
+	id    string
+	mu    sync.Mutex
+	state *repl.SessionState
+
+	busy   bool
+	cancel context.CancelFunc
+
+	events      []sseEvent
+	nextEventID int
+	subscribers map[chan sseEvent]struct{}
+}
+
+// sseEvent is a JSONEvent tagged with a per-session sequence number, used as
+// the SSE id so clients can resume with Last-Event-ID.
+type sseEvent struct {
+	id    int
+	event repl.JSONEvent
+}
+
+// publish records an event and fans it out to subscribers. Slow subscribers
+// drop events rather than stalling the agent; they can reconnect with
+// Last-Event-ID to catch up from the buffer.
+func (a *apiSession) publish(ev repl.JSONEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ev.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	e := sseEvent{id: a.nextEventID, event: ev}
+	a.nextEventID++
+
+	a.events = append(a.events, e)
+	if len(a.events) > maxBufferedEvents {
+		a.events = a.events[len(a.events)-maxBufferedEvents:]
+	}
+
+	for ch := range a.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// subscribe registers a subscriber and returns buffered events after lastID.
+func (a *apiSession) subscribe(lastID int) (chan sseEvent, []sseEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ch := make(chan sseEvent, 64)
+	a.subscribers[ch] = struct{}{}
+
+	var backlog []sseEvent
+	for _, e := range a.events {
+		if e.id > lastID {
+			backlog = append(backlog, e)
+		}
+	}
+	return ch, backlog
+}
+
+func (a *apiSession) unsubscribe(ch chan sseEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.subscribers, ch)
+}
+
+// sessionStatus is the JSON representation of a session.
+type sessionStatus struct {
+	ID          string     `json:"session_id"`
+	Mode        string     `json:"mode"`
+	Busy        bool       `json:"busy"`
+	PendingPlan *repl.Plan `json:"pending_plan,omitempty"`
+
+	PendingConfirmations []repl.DryRunConfirmation `json:"pending_confirmations,omitempty"`
+
+	Usage *sessionUsage `json:"usage,omitempty"`
+}
+
+// sessionUsage is the token usage of a session since the server started.
+type sessionUsage struct {
+	ModelCalls   int      `json:"model_calls"`
+	InputTokens  int64    `json:"input_tokens"`
+	OutputTokens int64    `json:"output_tokens"`
+	Cost         *float64 `json:"cost_usd,omitempty"` // nil if the model's price is unknown
+}
+
+func (a *apiSession) status() sessionStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// Copies, since the status is encoded after the lock is released
+	status := sessionStatus{
+		ID:   a.id,
+		Mode: string(a.state.Mode),
+		Busy: a.busy,
+
+		PendingConfirmations: slices.Clone(a.state.PendingConfirmations),
+	}
+	if a.state.PendingPlan != nil {
+		plan := *a.state.PendingPlan
+		status.PendingPlan = &plan
+	}
+	return status
+}
+
+func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	id := uuid.NewString()
+	_, err := s.sessionService.Create(r.Context(), &session.CreateRequest{
+		AppName:   s.appName,
+		UserID:    userID,
+		SessionID: id,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("creating session: %v", err))
+		return
+	}
+
+	sess := &apiSession{
+		id:          id,
+		state:       repl.NewSessionState(),
+		subscribers: make(map[chan sseEvent]struct{}),
+	}
+
+	s.mu.Lock()
+	s.sessions[id] = sess
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, sess.status())
+}
+
+func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	sess := s.lookup(w, r)
+	if sess == nil {
+		return
+	}
+	status := sess.status()
+	if s.usage != nil {
+		u := s.usage.Session(sess.id)
+		status.Usage = &sessionUsage{ModelCalls: u.ModelCalls, InputTokens: u.InputTokens, OutputTokens: u.OutputTokens}
+		if cost, ok := s.usage.Cost(u); ok {
+			status.Usage.Cost = &cost
+		}
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
+	sess := s.lookup(w, r)
+	if sess == nil {
+		return
+	}
+
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Text == "" {
+		writeError(w, http.StatusBadRequest, `body must be JSON with a non-empty "text" field`)
+		return
+	}
+
+	sess.mu.Lock()
+	if sess.state.HasPendingPlan() || sess.state.HasPendingConfirmation() {
+		sess.mu.Unlock()
+		writeError(w, http.StatusConflict, "session has a pending plan or dry-run confirmation; approve or reject it first")
+		return
+	}
+	sess.mu.Unlock()
+
+	if !s.start(sess, body.Text) {
+		writeError(w, http.StatusConflict, "agent is busy with a previous message")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, sess.status())
+}
+
+func (s *Server) handleApprove(w http.ResponseWriter, r *http.Request) {
+	sess := s.lookup(w, r)
+	if sess == nil {
+		return
+	}
+	if err := s.approve(sess, ""); err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, sess.status())
+}
+
+func (s *Server) handleReject(w http.ResponseWriter, r *http.Request) {
+	sess := s.lookup(w, r)
+	if sess == nil {
+		return
+	}
+	if err := s.reject(sess, ""); err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, sess.status())
+}
+
+// Approve approves the pending plan of a session and starts executing it. It
+// refuses if planID isn't the ID of the pending plan, so a decision made on a
+// plan that has since been replaced isn't applied to the new one.
+func (s *Server) Approve(sessionID, planID string) error {
+	sess := s.get(sessionID)
+	if sess == nil {
+		return fmt.Errorf("session %q not found", sessionID)
+	}
+	if planID == "" {
+		return fmt.Errorf("no plan ID given")
+	}
+	return s.approve(sess, planID)
+}
+
+// Reject rejects the pending plan of a session if its ID is planID.
+func (s *Server) Reject(sessionID, planID string) error {
+	sess := s.get(sessionID)
+	if sess == nil {
+		return fmt.Errorf("session %q not found", sessionID)
+	}
+	if planID == "" {
+		return fmt.Errorf("no plan ID given")
+	}
+	return s.reject(sess, planID)
+}
+
+// pending returns an error unless the plan with planID is what awaits a
+// decision. An empty planID accepts whatever is pending. Must be called with
+// sess.mu held.
+func pending(sess *apiSession, planID string) error {
+	if planID == "" {
+		return nil
+	}
+	if sess.state.HasPendingConfirmation() || !sess.state.HasPendingPlan() || sess.state.PendingPlan.ID != planID {
+		return fmt.Errorf("plan %s is no longer pending", planID)
+	}
+	return nil
+}
+
+func (s *Server) approve(sess *apiSession, planID string) error {
+	sess.mu.Lock()
+	if sess.busy {
+		sess.mu.Unlock()
+		return fmt.Errorf("agent is busy")
+	}
+	if err := pending(sess, planID); err != nil {
+		sess.mu.Unlock()
+		return err
+	}
+	if sess.state.HasPendingConfirmation() {
+		confirmations := sess.state.TakeConfirmations()
+		sess.mu.Unlock()
+		s.metrics.PlanDecision("dry_run", true)
+		if errs := repl.ApplyConfirmations(s.confirmer, confirmations); len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+		if !s.start(sess, repl.FormatConfirmationPrompt(confirmations)) {
+			return fmt.Errorf("agent is busy")
+		}
+		return nil
+	}
+	plan := sess.state.ApprovePlan()
+	sess.mu.Unlock()
+
+	if plan == nil {
+		return fmt.Errorf("no pending plan to approve")
+	}
+	s.metrics.PlanDecision("plan", true)
+	if !s.start(sess, repl.FormatExecutionPrompt(plan)) {
+		return fmt.Errorf("agent is busy")
+	}
+	return nil
+}
+
+func (s *Server) reject(sess *apiSession, planID string) error {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if err := pending(sess, planID); err != nil {
+		return err
+	}
+	if sess.state.HasPendingConfirmation() {
+		sess.state.Reset()
+		s.metrics.PlanDecision("dry_run", false)
+		return nil
+	}
+	if !sess.state.HasPendingPlan() {
+		return fmt.Errorf("no pending plan to reject")
+	}
+	sess.state.RejectPlan()
+	s.metrics.PlanDecision("plan", false)
+	return nil
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	sess := s.lookup(w, r)
+	if sess == nil {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	lastID := -1
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			lastID = n
+		}
+	}
+
+	ch, backlog := sess.subscribe(lastID)
+	defer sess.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, e := range backlog {
+		if err := writeSSE(w, e); err != nil {
+			return
+		}
+		lastID = e.id
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-ch:
+			// Skip anything already delivered from the backlog.
+			if e.id <= lastID {
+				continue
+			}
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+			lastID = e.id
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// start launches an agent run for the session. Returns false if one is
+// already in progress.
+func (s *Server) start(sess *apiSession, prompt string) bool {
+	sess.mu.Lock()
+	if sess.busy {
+		sess.mu.Unlock()
+		return false
+	}
+	ctx, cancel := context.WithCancel(s.baseCtx)
+	// Approved plans are carried out by the deployer
+	if sess.state.Mode == repl.ModeExecuting {
+		ctx = agents.WithMode(ctx, agents.ModeDeploy)
+	}
+	sess.busy = true
+	sess.cancel = cancel
+	sess.mu.Unlock()
+
+	go s.run(ctx, sess, prompt)
+	return true
+}
+
+// run drives the runner for one user message, publishing events and tracking
+// proposed plans the same way the interactive REPL does.
+func (s *Server) run(ctx context.Context, sess *apiSession, prompt string) {
+	defer func() {
+		sess.mu.Lock()
+		sess.busy = false
+		sess.cancel()
+		sess.cancel = nil
+		// After plan execution, reset if no new plan was proposed
+		if sess.state.Mode == repl.ModeExecuting && !sess.state.HasPendingPlan() && !sess.state.HasPendingConfirmation() {
+			sess.state.Reset()
+		}
+		sess.mu.Unlock()
+	}()
+
+	var usage repl.JSONUsage
+	userMessage := genai.NewContentFromText(prompt, genai.RoleUser)
+
+	for event, err := range tracing.Run(ctx, s.runner, userID, sess.id, userMessage, agent.RunConfig{}) {
+		if err != nil {
+			sess.publish(repl.JSONEvent{Type: "error", Error: err.Error()})
+			return
+		}
+		if event == nil {
+			continue
+		}
+
+		if event.UsageMetadata != nil {
+			usage.InputTokens = event.UsageMetadata.PromptTokenCount
+			usage.OutputTokens = event.UsageMetadata.CandidatesTokenCount
+		}
+
+		for _, ev := range repl.ToJSONEvents(event) {
+			switch ev.Type {
+			case "plan":
+				// The session keeps its own copy: the propose_plan result
+				// sets its ID and warnings while ev is published
+				plan := *ev.Plan
+				sess.mu.Lock()
+				sess.state.SetPendingPlan(&plan)
+				sess.mu.Unlock()
+			case "tool_result":
+				if c := repl.ParseDryRunConfirmation(ev.Response); c != nil {
+					sess.mu.Lock()
+					sess.state.AddPendingConfirmation(*c)
+					sess.mu.Unlock()
+				}
+				if ev.Tool == "propose_plan" {
+					var proposed *repl.Plan
+					sess.mu.Lock()
+					if sess.state.HasPendingPlan() {
+						sess.state.PendingPlan.ApplyResponse(ev.Response)
+						plan := *sess.state.PendingPlan
+						proposed = &plan
+					}
+					sess.mu.Unlock()
+					// Notify once the plan has its ID, which approvals refer to
+					if proposed != nil && s.notifier != nil {
+						go s.notifier.PlanProposed(sess.id, proposed)
+					}
+				}
+				if s.notifier != nil {
+					go s.notifier.ToolExecuted(ev.Tool, ev.Response)
+				}
+			}
+			sess.publish(ev)
+		}
+	}
+
+	sess.publish(repl.JSONEvent{Type: "final", Usage: &usage})
+}
+
+// cancelAll cancels every in-flight agent run.
+func (s *Server) cancelAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sess := range s.sessions {
+		sess.mu.Lock()
+		if sess.cancel != nil {
+			sess.cancel()
+		}
+		sess.mu.Unlock()
+	}
+}
+
+// get returns the session with the given ID, or nil.
+func (s *Server) get(id string) *apiSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[id]
+}
+
+// lookup finds the session named in the request path, writing a 404 if missing.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) *apiSession {
+	id := r.PathValue("id")
+	sess := s.get(id)
+	if sess == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("session %q not found", id))
+		return nil
+	}
+	return sess
+}
+
+func writeSSE(w http.ResponseWriter, e sseEvent) error {
+	data, err := json.Marshal(e.event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.id, e.event.Type, data)
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
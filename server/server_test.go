@@ -0,0 +1,538 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/perbu/kasa/repl"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// scriptedModel proposes a plan when asked for "plan", calls set_image when
+// asked for "dry run", and otherwise answers with the user's message.
+type scriptedModel struct {
+	mu       sync.Mutex
+	messages []string // the user messages it was sent
+}
+
+func (m *scriptedModel) Name() string { return "scripted" }
+
+func (m *scriptedModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		last := req.Contents[len(req.Contents)-1].Parts[0]
+		if last.FunctionResponse != nil {
+			yield(&model.LLMResponse{Content: genai.NewContentFromText("waiting for you", genai.RoleModel)}, nil)
+			return
+		}
+		m.mu.Lock()
+		m.messages = append(m.messages, last.Text)
+		m.mu.Unlock()
+
+		switch last.Text {
+		case "plan":
+			yield(&model.LLMResponse{Content: genai.NewContentFromFunctionCall("propose_plan", map[string]any{
+				"description": "Roll out web v2",
+				"actions": []any{map[string]any{
+					"tool":       "set_image",
+					"parameters": map[string]any{"namespace": "shop", "name": "web", "image": "web:v2"},
+					"reason":     "release",
+				}},
+			}, genai.RoleModel)}, nil)
+		case "dry run":
+			yield(&model.LLMResponse{Content: genai.NewContentFromFunctionCall("set_image", map[string]any{
+				"namespace": "shop", "name": "web", "image": "web:v2",
+			}, genai.RoleModel)}, nil)
+		default:
+			yield(&model.LLMResponse{Content: genai.NewContentFromText("echo: "+last.Text, genai.RoleModel)}, nil)
+		}
+	}
+}
+
+// lastMessage returns the latest user message the model was sent.
+func (m *scriptedModel) lastMessage() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.messages) == 0 {
+		return ""
+	}
+	return m.messages[len(m.messages)-1]
+}
+
+// stubTool returns a fixed result.
+type stubTool struct {
+	name   string
+	result map[string]any
+}
+
+func (t stubTool) Name() string        { return t.name }
+func (t stubTool) Description() string { return t.name }
+func (t stubTool) IsLongRunning() bool { return false }
+func (t stubTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{Name: t.name, Description: t.name}
+}
+func (t stubTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	return t.result, nil
+}
+func (t stubTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	if req.Tools == nil {
+		req.Tools = make(map[string]any)
+	}
+	req.Tools[t.name] = t
+	return nil
+}
+
+// recordingConfirmer records the dry runs it is told to confirm.
+type recordingConfirmer struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (c *recordingConfirmer) ConfirmDryRun(toolName, namespace, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys = append(c.keys, toolName+" "+namespace+" "+key)
+}
+
+// recordingNotifier records the plans it is told about.
+type recordingNotifier struct {
+	mu    sync.Mutex
+	plans map[string]*repl.Plan // by session ID
+}
+
+func (n *recordingNotifier) PlanProposed(sessionID string, plan *repl.Plan) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.plans[sessionID] = plan
+}
+
+func (n *recordingNotifier) ToolExecuted(toolName string, result map[string]any) {}
+
+// plan returns the plan proposed in the session, once it has been notified.
+func (n *recordingNotifier) plan(sessionID string) *repl.Plan {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		n.mu.Lock()
+		plan := n.plans[sessionID]
+		n.mu.Unlock()
+		if plan != nil {
+			return plan
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return nil
+}
+
+// testServer is a Server on an httptest server, backed by scriptedModel.
+type testServer struct {
+	*testing.T
+	url       string
+	llm       *scriptedModel
+	server    *Server
+	confirmer *recordingConfirmer
+	notifier  *recordingNotifier
+}
+
+func newTestServer(t *testing.T) *testServer {
+	llm := &scriptedModel{}
+	root, err := llmagent.New(llmagent.Config{
+		Name:  "kasa",
+		Model: llm,
+		Tools: []tool.Tool{
+			stubTool{"propose_plan", map[string]any{"status": "awaiting_approval", "plan_id": "plan-1"}},
+			stubTool{"set_image", map[string]any{
+				"confirmation_required": true,
+				"tool":                  "set_image",
+				"namespace":             "shop",
+				"confirmation_key":      "k1",
+				"message":               "dry run only",
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := session.InMemoryService()
+	r, err := runner.New(runner.Config{AppName: "kasa", Agent: root, SessionService: sessions})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := New(ctx, r, sessions, "kasa")
+	confirmer := &recordingConfirmer{}
+	s.SetDryRunConfirmer(confirmer)
+	notifier := &recordingNotifier{plans: make(map[string]*repl.Plan)}
+	s.SetNotifier(notifier)
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(func() {
+		cancel()
+		srv.Close()
+	})
+	return &testServer{T: t, url: srv.URL, server: s, llm: llm, confirmer: confirmer, notifier: notifier}
+}
+
+// post sends a POST with an optional JSON body and decodes the response.
+func (ts *testServer) post(path string, body any) (int, map[string]any) {
+	ts.Helper()
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	resp, err := http.Post(ts.url+path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		ts.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+// status returns the session's status.
+func (ts *testServer) status(id string) map[string]any {
+	ts.Helper()
+	resp, err := http.Get(ts.url + "/sessions/" + id)
+	if err != nil {
+		ts.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return out
+}
+
+// createSession creates a session and returns its ID.
+func (ts *testServer) createSession() string {
+	ts.Helper()
+	code, body := ts.post("/sessions", nil)
+	id, _ := body["session_id"].(string)
+	if code != http.StatusCreated || id == "" {
+		ts.Fatalf("POST /sessions = %d %v", code, body)
+	}
+	return id
+}
+
+// send posts a message and waits for the agent run to finish.
+func (ts *testServer) send(id, text string) {
+	ts.Helper()
+	if code, body := ts.post("/sessions/"+id+"/messages", map[string]any{"text": text}); code != http.StatusAccepted {
+		ts.Fatalf("POST messages %q = %d %v", text, code, body)
+	}
+	ts.waitIdle(id)
+}
+
+// waitIdle waits until the session's agent run has finished.
+func (ts *testServer) waitIdle(id string) {
+	ts.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for ts.status(id)["busy"] == true {
+		if time.Now().After(deadline) {
+			ts.Fatal("agent run didn't finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// sseMessage is one event of the event stream.
+type sseMessage struct {
+	id    int
+	event string
+	data  string
+}
+
+// events opens the session's event stream, resuming after lastID if it
+// isn't empty. The stream is connected once it returns.
+func (ts *testServer) events(id, lastID string) *bufio.Scanner {
+	ts.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ts.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.url+"/sessions/"+id+"/events", nil)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		ts.Fatal(err)
+	}
+	ts.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		ts.Fatalf("GET events = %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	return bufio.NewScanner(resp.Body)
+}
+
+// untilFinal reads events up to and including the next "final" one.
+func (ts *testServer) untilFinal(stream *bufio.Scanner) []sseMessage {
+	ts.Helper()
+	var msgs []sseMessage
+	var msg sseMessage
+	for stream.Scan() {
+		line := stream.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			msg.id, _ = strconv.Atoi(strings.TrimPrefix(line, "id: "))
+		case strings.HasPrefix(line, "event: "):
+			msg.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			msg.data = strings.TrimPrefix(line, "data: ")
+		case line == "" && msg.event != "":
+			msgs = append(msgs, msg)
+			if msg.event == "final" {
+				return msgs
+			}
+			msg = sseMessage{}
+		}
+	}
+	ts.Fatalf("stream ended without a final event (%v) after %v", stream.Err(), msgs)
+	return nil
+}
+
+func ids(msgs []sseMessage) []int {
+	var out []int
+	for _, m := range msgs {
+		out = append(out, m.id)
+	}
+	return out
+}
+
+func TestServer_EventReplay(t *testing.T) {
+	ts := newTestServer(t)
+	id := ts.createSession()
+	ts.send(id, "hello")
+
+	// A client connecting after the run gets the buffered events
+	all := ts.untilFinal(ts.events(id, ""))
+	if len(all) < 2 || all[0].id != 0 || !strings.Contains(all[0].data, "echo: hello") {
+		t.Fatalf("events = %+v; want the reply and the final event from id 0", all)
+	}
+
+	// Reconnecting with Last-Event-ID replays only what came after it
+	replayed := ts.untilFinal(ts.events(id, "0"))
+	if got, want := ids(replayed), ids(all[1:]); !slices.Equal(got, want) {
+		t.Errorf("replayed ids = %v, want %v", got, want)
+	}
+
+	// A client that saw everything gets the next run's events live, once each
+	last := all[len(all)-1].id
+	stream := ts.events(id, strconv.Itoa(last))
+	if code, body := ts.post("/sessions/"+id+"/messages", map[string]any{"text": "again"}); code != http.StatusAccepted {
+		t.Fatalf("POST messages = %d %v", code, body)
+	}
+	live := ts.untilFinal(stream)
+	if len(live) < 2 || live[0].id != last+1 || !strings.Contains(live[0].data, "echo: again") {
+		t.Errorf("live events = %+v; want the new reply from id %d", live, last+1)
+	}
+	for i := 1; i < len(live); i++ {
+		if live[i].id != live[i-1].id+1 {
+			t.Errorf("live ids = %v; want them consecutive", ids(live))
+		}
+	}
+
+	// Unknown sessions are 404
+	resp, err := http.Get(ts.url + "/sessions/nope/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET events of an unknown session = %d", resp.StatusCode)
+	}
+}
+
+func TestServer_ApproveReject(t *testing.T) {
+	ts := newTestServer(t)
+	id := ts.createSession()
+
+	// Nothing to decide yet
+	if code, _ := ts.post("/sessions/"+id+"/approve", nil); code != http.StatusConflict {
+		t.Errorf("approve without a plan = %d, want 409", code)
+	}
+
+	// A proposed plan blocks new messages until it is decided
+	ts.send(id, "plan")
+	if plan, _ := ts.status(id)["pending_plan"].(map[string]any); plan["description"] != "Roll out web v2" {
+		t.Fatalf("pending_plan = %v", ts.status(id)["pending_plan"])
+	}
+	if code, _ := ts.post("/sessions/"+id+"/messages", map[string]any{"text": "hello"}); code != http.StatusConflict {
+		t.Errorf("message with a pending plan = %d, want 409", code)
+	}
+
+	// Rejecting drops it
+	if code, body := ts.post("/sessions/"+id+"/reject", nil); code != http.StatusOK || body["pending_plan"] != nil {
+		t.Errorf("reject = %d %v", code, body)
+	}
+	if code, _ := ts.post("/sessions/"+id+"/reject", nil); code != http.StatusConflict {
+		t.Errorf("second reject = %d, want 409", code)
+	}
+
+	// Approving has the agent execute it, and the session is idle after
+	ts.send(id, "plan")
+	if code, body := ts.post("/sessions/"+id+"/approve", nil); code != http.StatusAccepted {
+		t.Fatalf("approve = %d %v", code, body)
+	}
+	ts.waitIdle(id)
+	if msg := ts.llm.lastMessage(); !strings.Contains(msg, "APPROVED") || !strings.Contains(msg, "Roll out web v2") {
+		t.Errorf("execution prompt = %q", msg)
+	}
+	if st := ts.status(id); st["mode"] != "planning" || st["pending_plan"] != nil {
+		t.Errorf("status after execution = %v", st)
+	}
+
+	// A dry-run confirmation is confirmed by key before the agent retries
+	ts.send(id, "dry run")
+	confirmations, _ := ts.status(id)["pending_confirmations"].([]any)
+	if len(confirmations) != 1 {
+		t.Fatalf("pending_confirmations = %v", ts.status(id)["pending_confirmations"])
+	}
+	if code, body := ts.post("/sessions/"+id+"/approve", nil); code != http.StatusAccepted {
+		t.Fatalf("approve dry run = %d %v", code, body)
+	}
+	ts.waitIdle(id)
+	ts.confirmer.mu.Lock()
+	keys := ts.confirmer.keys
+	ts.confirmer.mu.Unlock()
+	if len(keys) != 1 || keys[0] != "set_image shop k1" {
+		t.Errorf("confirmed = %v, want set_image in shop with key k1", keys)
+	}
+
+	// Rejecting a dry run confirms nothing
+	ts.send(id, "dry run")
+	if code, _ := ts.post("/sessions/"+id+"/reject", nil); code != http.StatusOK {
+		t.Errorf("reject dry run = %d", code)
+	}
+	if st := ts.status(id); st["pending_confirmations"] != nil {
+		t.Errorf("pending_confirmations after reject = %v", st["pending_confirmations"])
+	}
+	ts.confirmer.mu.Lock()
+	defer ts.confirmer.mu.Unlock()
+	if len(ts.confirmer.keys) != 1 {
+		t.Errorf("confirmed = %v after a rejected dry run", ts.confirmer.keys)
+	}
+}
+
+func TestServer_PlanProposed(t *testing.T) {
+	ts := newTestServer(t)
+	id := ts.createSession()
+	ts.send(id, "plan")
+
+	// The notifier is told once propose_plan has given the plan its ID
+	plan := ts.notifier.plan(id)
+	if plan == nil || plan.ID != "plan-1" || plan.Description != "Roll out web v2" {
+		t.Fatalf("notified plan = %+v, want plan-1", plan)
+	}
+	if pending, _ := ts.status(id)["pending_plan"].(map[string]any); pending["id"] != "plan-1" {
+		t.Errorf("pending_plan = %v, want plan-1", ts.status(id)["pending_plan"])
+	}
+
+	// It holds a copy, which rejecting the plan leaves alone
+	if code, _ := ts.post("/sessions/"+id+"/reject", nil); code != http.StatusOK {
+		t.Fatalf("reject = %d", code)
+	}
+	if plan.ID != "plan-1" {
+		t.Errorf("notified plan changed to %+v", plan)
+	}
+}
+
+func TestServer_Token(t *testing.T) {
+	s := New(context.Background(), nil, nil, "kasa")
+	s.SetToken("s3cret")
+	s.Handle("GET /health", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	h := s.Handler()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		want   int
+	}{
+		{"no token", http.MethodGet, "/sessions/x", "", http.StatusUnauthorized},
+		{"wrong token", http.MethodGet, "/sessions/x", "Bearer guess", http.StatusUnauthorized},
+		{"not a bearer token", http.MethodGet, "/sessions/x", "s3cret", http.StatusUnauthorized},
+		{"approve without token", http.MethodPost, "/sessions/x/approve", "", http.StatusUnauthorized},
+		{"reject without token", http.MethodPost, "/sessions/x/reject", "", http.StatusUnauthorized},
+		{"create without token", http.MethodPost, "/sessions", "", http.StatusUnauthorized},
+		{"token", http.MethodGet, "/sessions/x", "Bearer s3cret", http.StatusNotFound},
+		{"approve with token", http.MethodPost, "/sessions/x/approve", "Bearer s3cret", http.StatusNotFound},
+		{"mounted handler", http.MethodGet, "/health", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoopback(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1:8080": true,
+		"localhost:8080": true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"10.0.0.5:8080":  false,
+		"example.com:80": false,
+		"8080":           false,
+	} {
+		if got := Loopback(addr); got != want {
+			t.Errorf("Loopback(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestServer_ApprovePlanID(t *testing.T) {
+	ts := newTestServer(t)
+	id := ts.createSession()
+	ts.send(id, "plan")
+
+	// A decision on another plan, or on none, leaves the pending plan alone
+	for _, planID := range []string{"plan-0", ""} {
+		if err := ts.server.Approve(id, planID); err == nil {
+			t.Errorf("Approve(%q) of plan-1 succeeded", planID)
+		}
+		if err := ts.server.Reject(id, planID); err == nil {
+			t.Errorf("Reject(%q) of plan-1 succeeded", planID)
+		}
+	}
+	if plan, _ := ts.status(id)["pending_plan"].(map[string]any); plan["id"] != "plan-1" {
+		t.Fatalf("pending_plan = %v, want plan-1 still pending", ts.status(id)["pending_plan"])
+	}
+
+	if err := ts.server.Approve(id, "plan-1"); err != nil {
+		t.Fatal(err)
+	}
+	ts.waitIdle(id)
+	if msg := ts.llm.lastMessage(); !strings.Contains(msg, "APPROVED") {
+		t.Errorf("execution prompt = %q", msg)
+	}
+
+	// Dry-run confirmations aren't a plan
+	ts.send(id, "dry run")
+	if err := ts.server.Approve(id, "plan-1"); err == nil {
+		t.Error("Approve(plan-1) confirmed a dry run")
+	}
+}
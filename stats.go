@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/perbu/kasa/metrics"
+	"github.com/perbu/kasa/telemetry"
+	"github.com/perbu/kasa/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+)
+
+// runStatsCommand implements the "kasa stats" CLI, which shows the locally
+// recorded usage statistics. Nothing is sent over the network.
+func runStatsCommand(args []string) int {
+	path := telemetry.StatsPath()
+
+	if len(args) == 1 && args[0] == "reset" {
+		if err := telemetry.Reset(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Println("Usage statistics cleared.")
+		return 0
+	}
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: kasa stats [reset]")
+		return 2
+	}
+
+	stats, err := telemetry.ReadStats(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(stats.ToolCalls) == 0 && stats.ModelCalls == 0 {
+		fmt.Println("No usage statistics recorded. Set telemetry.mode to \"local\" in config.yaml to collect them.")
+		return 0
+	}
+	fmt.Print(telemetry.Format(stats))
+	return 0
+}
+
+// addTelemetryCallbacks records tool usage and model latency for the agent.
+func addTelemetryCallbacks(cfg *llmagent.Config, recorder *telemetry.Recorder) {
+	if recorder == nil {
+		return
+	}
+	cfg.BeforeModelCallbacks = append(cfg.BeforeModelCallbacks,
+		func(ctx agent.CallbackContext, req *model.LLMRequest) (*model.LLMResponse, error) {
+			recorder.ModelStarted(ctx.InvocationID())
+			return nil, nil
+		})
+	cfg.AfterModelCallbacks = append(cfg.AfterModelCallbacks,
+		func(ctx agent.CallbackContext, resp *model.LLMResponse, respErr error) (*model.LLMResponse, error) {
+			recorder.ModelFinished(ctx.InvocationID(), respErr)
+			return nil, nil
+		})
+	cfg.AfterToolCallbacks = append(cfg.AfterToolCallbacks,
+		func(ctx tool.Context, t tool.Tool, args, result map[string]any, err error) (map[string]any, error) {
+			name := t.Name()
+			if tools.IsCustom(t) || tools.IsRegistered(t) {
+				name = telemetry.CustomTool
+			}
+			recorder.ToolCalled(name, result, err)
+			return nil, nil
+		})
+}
+
+// addMetricsCallbacks counts model calls and their tokens for /metrics.
+func addMetricsCallbacks(cfg *llmagent.Config, m *metrics.Metrics) {
+	if m == nil {
+		return
+	}
+	cfg.AfterModelCallbacks = append(cfg.AfterModelCallbacks,
+		func(ctx agent.CallbackContext, resp *model.LLMResponse, respErr error) (*model.LLMResponse, error) {
+			m.ModelResponse(resp, respErr)
+			return nil, nil
+		})
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/perbu/kasa/integrations/slack"
+	"github.com/perbu/kasa/manifest"
+	"github.com/perbu/kasa/metrics"
+	"github.com/perbu/kasa/tools"
+	"k8s.io/client-go/dynamic"
+)
+
+// defaultSyncInterval is used when sync.interval is not set.
+const defaultSyncInterval = 5 * time.Minute
+
+// syncHookTimeout bounds each webhook call and hook command.
+const syncHookTimeout = 30 * time.Second
+
+// syncer runs the reconcile passes for 'kasa sync': every stored manifest
+// that drifted or is missing from the cluster is re-applied.
+type syncer struct {
+	cfg          *Config
+	dynClient    dynamic.Interface
+	resolver     *tools.GVRResolver
+	kubeTools    *tools.KubeTools // re-applies through the safety checks
+	manifest     *manifest.Manager
+	slack        *slack.Client
+	auditLogPath string
+	dryRun       bool
+	metrics      *metrics.Metrics
+
+	lastOutOfSync []string // resources out of sync after the previous pass
+}
+
+// syncReport summarizes one reconcile pass. It is what webhooks and hook
+// commands receive.
+type syncReport struct {
+	Time      time.Time                 `json:"time"`
+	DryRun    bool                      `json:"dry_run"`
+	Total     int                       `json:"total"`
+	InSync    int                       `json:"in_sync"`
+	Drifted   int                       `json:"drifted"`
+	Missing   int                       `json:"missing"`
+	Errors    int                       `json:"errors"`
+	Applied   []tools.RemediationResult `json:"applied,omitempty"`
+	Skipped   []tools.RemediationResult `json:"skipped,omitempty"`     // refused by the safety checks
+	OutOfSync []string                  `json:"out_of_sync,omitempty"` // still out of sync after the pass
+}
+
+// runSync implements "kasa sync": it reconciles the cluster with the
+// manifest store every sync.interval until ctx is cancelled, or once with -once.
+func runSync(ctx context.Context, cfg *Config, args []string, dynClient dynamic.Interface, kubeTools *tools.KubeTools, mgr *manifest.Manager, slackClient *slack.Client, m *metrics.Metrics) int {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	once := fs.Bool("once", false, "Run a single pass and exit; the exit status is 1 if anything is left out of sync")
+	dryRun := fs.Bool("dry-run", cfg.Sync.DryRun, "Validate changes with server-side dry-run instead of applying them")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "usage: kasa sync [-once] [-dry-run]")
+		return 2
+	}
+
+	s := &syncer{
+		cfg:          cfg,
+		dynClient:    dynClient,
+		resolver:     kubeTools.Resolver(),
+		kubeTools:    kubeTools,
+		manifest:     mgr,
+		slack:        slackClient,
+		auditLogPath: remediationLogPath(),
+		dryRun:       *dryRun,
+		metrics:      m,
+	}
+
+	scope := "all namespaces"
+	if len(cfg.Sync.Namespaces) > 0 {
+		scope = "namespaces " + strings.Join(cfg.Sync.Namespaces, ", ")
+	}
+	mode := ""
+	if s.dryRun {
+		mode = " (dry run)"
+	}
+
+	if *once {
+		log.Printf("Syncing %s%s", scope, mode)
+		report := s.pass(ctx)
+		if report == nil || len(report.OutOfSync) > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	interval := cfg.Sync.Interval
+	if interval <= 0 {
+		interval = defaultSyncInterval
+	}
+	log.Printf("Syncing %s every %s%s", scope, interval, mode)
+	if m != nil {
+		listen := cmp.Or(cfg.Metrics.Listen, metrics.DefaultListen)
+		go func() {
+			if err := m.ListenAndServe(ctx, listen); err != nil {
+				log.Printf("Warning: metrics endpoint: %v", err)
+			}
+		}()
+		log.Printf("Serving metrics on http://%s/metrics", listen)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.pass(ctx)
+		select {
+		case <-ctx.Done():
+			log.Printf("Sync stopped")
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+// pass runs one drift scan, re-applies what is out of sync and notifies
+// about changes. Returns nil if the scan itself failed.
+func (s *syncer) pass(ctx context.Context) *syncReport {
+	if s.cfg.Deployments.Remote != "" {
+		if err := s.manifest.Pull(); err != nil {
+			log.Printf("Warning: failed to pull manifests: %v", err)
+		}
+	}
+
+	results, err := tools.RunDriftScan(ctx, s.dynClient, s.resolver, s.manifest, nil)
+	if err != nil {
+		log.Printf("Drift scan failed: %v", err)
+		s.metrics.SyncPass(nil, 0, 0)
+		return nil
+	}
+	report := &syncReport{Time: time.Now(), DryRun: s.dryRun}
+	if results == nil {
+		log.Printf("Sync: no stored manifests")
+		s.metrics.SyncPass(map[string]int{"in_sync": 0, "drifted": 0, "missing": 0, "error": 0}, 0, 0)
+		return report
+	}
+	report.Total, report.InSync, report.Drifted, report.Missing, report.Errors =
+		results.Total, results.InSync, results.Drifted, results.Missing, results.Errors
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	for _, r := range s.kubeTools.SyncDrift(ctx, s.cfg.Sync.Namespaces, s.dryRun, results) {
+		if r.Skipped != "" {
+			log.Printf("Not syncing %s/%s/%s: %s", r.Namespace, r.Name, r.Kind, r.Skipped)
+			report.Skipped = append(report.Skipped, r)
+			continue
+		}
+		report.Applied = append(report.Applied, r)
+	}
+	applied := make(map[string]bool, len(report.Applied))
+	failed := 0
+	for _, r := range report.Applied {
+		if r.Error != "" {
+			log.Printf("Sync failed for %s/%s/%s: %s", r.Namespace, r.Name, r.Kind, r.Error)
+			failed++
+			continue
+		}
+		if r.DryRun {
+			log.Printf("Would sync %s/%s/%s: %s (%s)", r.Namespace, r.Name, r.Kind, r.Action, r.Drift)
+			continue
+		}
+		log.Printf("Synced %s/%s/%s: %s (%s)", r.Namespace, r.Name, r.Kind, r.Action, r.Drift)
+		applied[fmt.Sprintf("%s/%s/%s:%s", r.Namespace, r.Name, r.Kind, r.Drift)] = true
+	}
+	for _, key := range outOfSync(results) {
+		namespace, _, _ := strings.Cut(key, "/")
+		if !applied[key] && (len(s.cfg.Sync.Namespaces) == 0 || slices.Contains(s.cfg.Sync.Namespaces, namespace)) {
+			report.OutOfSync = append(report.OutOfSync, key)
+		}
+	}
+	log.Printf("Sync: %d manifests, %d in sync, %d drifted, %d not in cluster, %d errors; %d applied, %d skipped, %d left out of sync",
+		report.Total, report.InSync, report.Drifted, report.Missing, report.Errors, len(applied), len(report.Skipped), len(report.OutOfSync))
+	s.metrics.SyncPass(map[string]int{
+		"in_sync": report.InSync,
+		"drifted": report.Drifted,
+		"missing": report.Missing,
+		"error":   report.Errors,
+	}, len(applied), failed)
+
+	if !s.dryRun {
+		var audited []tools.RemediationResult
+		for _, r := range report.Applied {
+			if r.Error == "" {
+				audited = append(audited, r)
+			}
+		}
+		if err := appendAuditLog(s.auditLogPath, audited); err != nil {
+			log.Printf("Warning: failed to write remediation audit log: %v", err)
+		}
+	}
+
+	// Notify when something was applied or the set of unsynced resources
+	// changed; a dry run re-reports the same drift every pass otherwise
+	if len(applied) > 0 || !slices.Equal(report.OutOfSync, s.lastOutOfSync) {
+		s.notify(ctx, results, report)
+	}
+	s.lastOutOfSync = report.OutOfSync
+	return report
+}
+
+// notify sends the report to Slack, the webhook and the hook commands.
+// Failures are logged; they never stop the sync loop.
+func (s *syncer) notify(ctx context.Context, results *tools.DriftScanResults, report *syncReport) {
+	if s.slack != nil {
+		if len(report.Applied) > 0 {
+			s.slack.Remediated(report.Applied)
+		} else {
+			s.slack.DriftDetected(results)
+		}
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		log.Printf("Warning: failed to encode sync report: %v", err)
+		return
+	}
+
+	if url := s.cfg.Sync.Webhook; url != "" {
+		if err := postSyncReport(ctx, url, body); err != nil {
+			log.Printf("Warning: sync webhook failed: %v", err)
+		}
+	}
+	for _, hook := range s.cfg.Sync.Hooks {
+		if err := runSyncHook(ctx, hook, body); err != nil {
+			log.Printf("Warning: sync hook %q failed: %v", hook, err)
+		}
+	}
+}
+
+// postSyncReport POSTs the JSON report to url.
+func postSyncReport(ctx context.Context, url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, syncHookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// runSyncHook runs a hook command through the shell with the JSON report on stdin.
+func runSyncHook(ctx context.Context, command string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, syncHookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,489 @@
+// Package telemetry collects anonymous, aggregate usage statistics: how often
+// each tool is called, which categories of errors occur, and how long model
+// calls take. It never records arguments, results, prompts, resource names or
+// error messages.
+//
+// Telemetry is off unless explicitly enabled. In "local" mode counts are only
+// written to ~/.kasa/stats.json for 'kasa stats'; in "report" mode the counts
+// accumulated since the last report are also sent to the configured endpoint.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/homedir"
+)
+
+// Telemetry modes (telemetry.mode in config.yaml).
+const (
+	ModeOff    = "off"
+	ModeLocal  = "local"
+	ModeReport = "report"
+)
+
+// CustomTool is the name custom tools, and the tools registered by
+// extension packages, are counted under: their names are the organization's
+// own and could identify it.
+const CustomTool = "custom"
+
+// reportInterval is how often pending counts are sent in report mode.
+const reportInterval = time.Hour
+
+// Config configures telemetry (telemetry in config.yaml).
+type Config struct {
+	Mode     string `yaml:"mode"`     // off (default), local or report
+	Endpoint string `yaml:"endpoint"` // HTTPS URL that receives reports in report mode
+}
+
+// Enabled reports whether any statistics are collected.
+func (c Config) Enabled() bool {
+	return c.Mode == ModeLocal || c.Mode == ModeReport
+}
+
+// Validate checks the mode and endpoint.
+func (c Config) Validate() error {
+	switch c.Mode {
+	case "", ModeOff, ModeLocal:
+		return nil
+	case ModeReport:
+		if c.Endpoint == "" {
+			return fmt.Errorf("mode %q requires an endpoint", ModeReport)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown mode %q (use off, local or report)", c.Mode)
+	}
+}
+
+// latencyBuckets are the upper bounds of the model latency histogram.
+var latencyBuckets = []time.Duration{
+	time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+}
+
+// Latency is an aggregate of observed durations.
+type Latency struct {
+	Count   int            `json:"count"`
+	TotalMs int64          `json:"total_ms"`
+	MaxMs   int64          `json:"max_ms"`
+	Buckets map[string]int `json:"buckets"` // "<=1s", "<=2s", ..., ">30s"
+}
+
+func (l *Latency) observe(d time.Duration) {
+	l.Count++
+	ms := d.Milliseconds()
+	l.TotalMs += ms
+	l.MaxMs = max(l.MaxMs, ms)
+	if l.Buckets == nil {
+		l.Buckets = make(map[string]int)
+	}
+	l.Buckets[bucketName(d)]++
+}
+
+func (l *Latency) add(o Latency) {
+	l.Count += o.Count
+	l.TotalMs += o.TotalMs
+	l.MaxMs = max(l.MaxMs, o.MaxMs)
+	for k, v := range o.Buckets {
+		if l.Buckets == nil {
+			l.Buckets = make(map[string]int)
+		}
+		l.Buckets[k] += v
+	}
+}
+
+func bucketName(d time.Duration) string {
+	for _, b := range latencyBuckets {
+		if d <= b {
+			return "<=" + b.String()
+		}
+	}
+	return ">" + latencyBuckets[len(latencyBuckets)-1].String()
+}
+
+// Stats holds aggregate counts.
+type Stats struct {
+	Since        time.Time      `json:"since"`
+	ToolCalls    map[string]int `json:"tool_calls"`
+	ToolErrors   map[string]int `json:"tool_errors"` // by error category
+	ModelCalls   int            `json:"model_calls"`
+	ModelErrors  int            `json:"model_errors"`
+	ModelLatency Latency        `json:"model_latency"`
+}
+
+func newStats() Stats {
+	return Stats{
+		Since:      time.Now().UTC(),
+		ToolCalls:  make(map[string]int),
+		ToolErrors: make(map[string]int),
+	}
+}
+
+func (s *Stats) add(o Stats) {
+	for k, v := range o.ToolCalls {
+		s.ToolCalls[k] += v
+	}
+	for k, v := range o.ToolErrors {
+		s.ToolErrors[k] += v
+	}
+	s.ModelCalls += o.ModelCalls
+	s.ModelErrors += o.ModelErrors
+	s.ModelLatency.add(o.ModelLatency)
+}
+
+// file is the on-disk format of stats.json.
+type file struct {
+	Total   Stats `json:"total"`
+	Pending Stats `json:"pending"` // not yet reported
+}
+
+// Recorder accumulates statistics. A nil *Recorder records nothing, so
+// callers don't need to check whether telemetry is enabled.
+type Recorder struct {
+	cfg        Config
+	path       string
+	version    string
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	data        file
+	modelStarts map[string]time.Time
+}
+
+// New returns a Recorder for cfg, or nil if telemetry is off. Existing
+// counts are loaded from ~/.kasa/stats.json.
+func New(cfg Config, version string) *Recorder {
+	if !cfg.Enabled() {
+		return nil
+	}
+	r := &Recorder{
+		cfg:         cfg,
+		path:        StatsPath(),
+		version:     strings.TrimSpace(version),
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		modelStarts: make(map[string]time.Time),
+	}
+	r.data, _ = load(r.path)
+	return r
+}
+
+// StatsPath returns the location of the local statistics file (~/.kasa/stats.json).
+func StatsPath() string {
+	home := homedir.HomeDir()
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".kasa", "stats.json")
+}
+
+// load reads a statistics file. A missing file yields empty stats.
+func load(path string) (file, error) {
+	data := file{Total: newStats(), Pending: newStats()}
+	if path == "" {
+		return data, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return data, nil
+		}
+		return data, err
+	}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return file{Total: newStats(), Pending: newStats()}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for _, s := range []*Stats{&data.Total, &data.Pending} {
+		if s.ToolCalls == nil {
+			s.ToolCalls = make(map[string]int)
+		}
+		if s.ToolErrors == nil {
+			s.ToolErrors = make(map[string]int)
+		}
+	}
+	return data, nil
+}
+
+// ReadStats returns the all-time counts stored at path.
+func ReadStats(path string) (Stats, error) {
+	data, err := load(path)
+	return data.Total, err
+}
+
+// Reset deletes the statistics file at path.
+func Reset(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Format renders stats as a plain-text report for 'kasa stats'.
+func Format(s Stats) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Usage statistics since %s\n\n", s.Since.Local().Format("2006-01-02 15:04"))
+
+	if len(s.ToolCalls) == 0 {
+		sb.WriteString("Tool calls: none\n")
+	} else {
+		sb.WriteString("Tool calls:\n")
+		for _, name := range sortedByCount(s.ToolCalls) {
+			fmt.Fprintf(&sb, "  %-28s %d\n", name, s.ToolCalls[name])
+		}
+	}
+
+	if len(s.ToolErrors) > 0 {
+		sb.WriteString("\nTool errors by category:\n")
+		for _, cat := range sortedByCount(s.ToolErrors) {
+			fmt.Fprintf(&sb, "  %-28s %d\n", cat, s.ToolErrors[cat])
+		}
+	}
+
+	fmt.Fprintf(&sb, "\nModel calls: %d (%d errors)\n", s.ModelCalls, s.ModelErrors)
+	if l := s.ModelLatency; l.Count > 0 {
+		fmt.Fprintf(&sb, "Model latency: avg %s, max %s\n",
+			time.Duration(l.TotalMs/int64(l.Count))*time.Millisecond, time.Duration(l.MaxMs)*time.Millisecond)
+		for _, b := range latencyBuckets {
+			name := "<=" + b.String()
+			fmt.Fprintf(&sb, "  %-8s %d\n", name, l.Buckets[name])
+		}
+		last := ">" + latencyBuckets[len(latencyBuckets)-1].String()
+		fmt.Fprintf(&sb, "  %-8s %d\n", last, l.Buckets[last])
+	}
+	return sb.String()
+}
+
+// sortedByCount returns the keys of m, most frequent first.
+func sortedByCount(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// update applies fn to both the total and pending stats and saves them.
+func (r *Recorder) update(fn func(s *Stats)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(&r.data.Total)
+	fn(&r.data.Pending)
+	_ = r.saveLocked()
+}
+
+// ToolCalled records a tool call. A non-nil err or an "error" key in result
+// counts as an error, categorized without keeping the message. Custom and
+// registered tools must be passed as CustomTool.
+func (r *Recorder) ToolCalled(name string, result map[string]any, err error) {
+	if r == nil {
+		return
+	}
+	category := ""
+	if err != nil {
+		category = CategorizeError(err.Error())
+	} else if msg, ok := result["error"]; ok {
+		category = CategorizeError(fmt.Sprint(msg))
+	}
+	r.update(func(s *Stats) {
+		s.ToolCalls[name]++
+		if category != "" {
+			s.ToolErrors[category]++
+		}
+	})
+}
+
+// ModelStarted marks the start of a model call for an invocation.
+func (r *Recorder) ModelStarted(invocationID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.modelStarts[invocationID] = time.Now()
+}
+
+// ModelFinished records the latency of the model call started for the
+// invocation. Later calls without a matching start (e.g. streamed partial
+// responses) are ignored.
+func (r *Recorder) ModelFinished(invocationID string, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	start, ok := r.modelStarts[invocationID]
+	delete(r.modelStarts, invocationID)
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+	d := time.Since(start)
+	r.update(func(s *Stats) {
+		s.ModelCalls++
+		if err != nil {
+			s.ModelErrors++
+		}
+		s.ModelLatency.observe(d)
+	})
+}
+
+// Run reports pending counts periodically in report mode until ctx is done.
+func (r *Recorder) Run(ctx context.Context) {
+	if r == nil || r.cfg.Mode != ModeReport {
+		return
+	}
+	ticker := time.NewTicker(reportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = r.Report(ctx)
+		}
+	}
+}
+
+// Close saves the counts and, in report mode, sends any pending ones.
+func (r *Recorder) Close() {
+	if r == nil {
+		return
+	}
+	if r.cfg.Mode == ModeReport {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = r.Report(ctx)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.saveLocked()
+}
+
+// report is the payload sent to the endpoint. It identifies nothing beyond
+// the kasa version and platform.
+type report struct {
+	Version string `json:"version"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	Stats   Stats  `json:"stats"`
+}
+
+// Report sends the counts accumulated since the last successful report.
+func (r *Recorder) Report(ctx context.Context) error {
+	if r == nil || r.cfg.Mode != ModeReport || r.cfg.Endpoint == "" {
+		return nil
+	}
+
+	r.mu.Lock()
+	pending := r.data.Pending
+	r.mu.Unlock()
+	if len(pending.ToolCalls) == 0 && pending.ModelCalls == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(report{Version: r.version, OS: runtime.GOOS, Arch: runtime.GOARCH, Stats: pending})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned %s", resp.Status)
+	}
+
+	// Subtract what was sent; counts recorded meanwhile stay pending
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	remaining := newStats()
+	remaining.add(r.data.Pending)
+	subtract(&remaining, pending)
+	r.data.Pending = remaining
+	return r.saveLocked()
+}
+
+// subtract removes sent counts from s.
+func subtract(s *Stats, sent Stats) {
+	for k, v := range sent.ToolCalls {
+		if s.ToolCalls[k] -= v; s.ToolCalls[k] <= 0 {
+			delete(s.ToolCalls, k)
+		}
+	}
+	for k, v := range sent.ToolErrors {
+		if s.ToolErrors[k] -= v; s.ToolErrors[k] <= 0 {
+			delete(s.ToolErrors, k)
+		}
+	}
+	s.ModelCalls -= sent.ModelCalls
+	s.ModelErrors -= sent.ModelErrors
+	l := &s.ModelLatency
+	l.Count -= sent.ModelLatency.Count
+	l.TotalMs -= sent.ModelLatency.TotalMs
+	if l.Count == 0 {
+		l.MaxMs = 0
+	}
+	for k, v := range sent.ModelLatency.Buckets {
+		if l.Buckets[k] -= v; l.Buckets[k] <= 0 {
+			delete(l.Buckets, k)
+		}
+	}
+}
+
+func (r *Recorder) saveLocked() error {
+	if r.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(r.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, content, 0644)
+}
+
+// CategorizeError maps an error message to a coarse category so that no
+// cluster-specific details are kept.
+func CategorizeError(msg string) string {
+	m := strings.ToLower(msg)
+	switch {
+	case strings.Contains(m, "deadline exceeded"), strings.Contains(m, "timeout"), strings.Contains(m, "timed out"):
+		return "timeout"
+	case strings.Contains(m, "not found"):
+		return "not_found"
+	case strings.Contains(m, "forbidden"), strings.Contains(m, "unauthorized"):
+		return "forbidden"
+	case strings.Contains(m, "already exists"), strings.Contains(m, "conflict"):
+		return "conflict"
+	case strings.Contains(m, "is required"), strings.Contains(m, "invalid"), strings.Contains(m, "unsupported"), strings.Contains(m, "failed to parse"):
+		return "invalid_argument"
+	case strings.Contains(m, "connection refused"), strings.Contains(m, "unavailable"), strings.Contains(m, "no such host"):
+		return "unavailable"
+	case strings.Contains(m, "canceled"), strings.Contains(m, "cancelled"):
+		return "canceled"
+	default:
+		return "other"
+	}
+}
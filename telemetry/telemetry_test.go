@@ -0,0 +1,111 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewOffReturnsNil(t *testing.T) {
+	if r := New(Config{}, "v1"); r != nil {
+		t.Error("expected nil recorder when telemetry is off")
+	}
+	// A nil recorder is safe to use
+	var r *Recorder
+	r.ToolCalled("list_pods", nil, nil)
+	r.ModelStarted("inv")
+	r.ModelFinished("inv", nil)
+	r.Close()
+}
+
+func TestRecorderCountsAndPersists(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	r := New(Config{Mode: ModeLocal}, "v1")
+	r.ToolCalled("list_pods", map[string]any{"pods": 3}, nil)
+	r.ToolCalled("list_pods", map[string]any{"error": "pods \"web\" not found"}, nil)
+	r.ToolCalled("apply_resource", nil, errors.New("context deadline exceeded"))
+	r.ModelStarted("inv1")
+	r.ModelFinished("inv1", nil)
+	r.ModelFinished("inv1", nil) // streamed partial without a start is ignored
+
+	stats, err := ReadStats(StatsPath())
+	if err != nil {
+		t.Fatalf("ReadStats: %v", err)
+	}
+	if stats.ToolCalls["list_pods"] != 2 || stats.ToolCalls["apply_resource"] != 1 {
+		t.Errorf("ToolCalls = %v", stats.ToolCalls)
+	}
+	if stats.ToolErrors["not_found"] != 1 || stats.ToolErrors["timeout"] != 1 {
+		t.Errorf("ToolErrors = %v", stats.ToolErrors)
+	}
+	if stats.ModelCalls != 1 || stats.ModelLatency.Count != 1 {
+		t.Errorf("ModelCalls = %d, latency count = %d, want 1, 1", stats.ModelCalls, stats.ModelLatency.Count)
+	}
+
+	out := Format(stats)
+	if !strings.Contains(out, "list_pods") || !strings.Contains(out, "Model calls: 1") {
+		t.Errorf("unexpected report:\n%s", out)
+	}
+}
+
+func TestReportSendsPendingOnly(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var received []report
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var rep report
+		if err := json.NewDecoder(req.Body).Decode(&rep); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		received = append(received, rep)
+	}))
+	defer srv.Close()
+
+	r := New(Config{Mode: ModeReport, Endpoint: srv.URL}, "v1\n")
+	r.ToolCalled("list_pods", nil, nil)
+	if err := r.Report(context.Background()); err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	r.ToolCalled("get_events", nil, nil)
+	if err := r.Report(context.Background()); err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	// Nothing new: no request
+	if err := r.Report(context.Background()); err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("received %d reports, want 2", len(received))
+	}
+	if received[0].Version != "v1" || received[0].Stats.ToolCalls["list_pods"] != 1 {
+		t.Errorf("first report = %+v", received[0])
+	}
+	if _, ok := received[1].Stats.ToolCalls["list_pods"]; ok || received[1].Stats.ToolCalls["get_events"] != 1 {
+		t.Errorf("second report should only contain new counts: %v", received[1].Stats.ToolCalls)
+	}
+
+	// Local totals keep everything
+	stats, _ := ReadStats(StatsPath())
+	if stats.ToolCalls["list_pods"] != 1 || stats.ToolCalls["get_events"] != 1 {
+		t.Errorf("totals = %v", stats.ToolCalls)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, c := range []Config{{}, {Mode: ModeOff}, {Mode: ModeLocal}, {Mode: ModeReport, Endpoint: "https://example.com"}} {
+		if err := c.Validate(); err != nil {
+			t.Errorf("%+v: unexpected error %v", c, err)
+		}
+	}
+	for _, c := range []Config{{Mode: ModeReport}, {Mode: "on"}} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v: expected error", c)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// adoptMaxListedDiffs caps the field paths listed in the commit message.
+const adoptMaxListedDiffs = 20
+
+// AdoptDriftTool provides the adopt_drift tool for the agent.
+type AdoptDriftTool struct {
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+	manifest      *manifest.Manager
+}
+
+// NewAdoptDriftTool creates a new AdoptDriftTool.
+func NewAdoptDriftTool(dynamicClient dynamic.Interface, resolver *GVRResolver, manifest *manifest.Manager) *AdoptDriftTool {
+	return &AdoptDriftTool{
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+		manifest:      manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *AdoptDriftTool) Name() string {
+	return "adopt_drift"
+}
+
+// Description returns the tool description.
+func (t *AdoptDriftTool) Description() string {
+	return "Accept intentional live changes: overwrite the stored manifest with the current (cleaned) cluster resource and commit it to git with a message listing the adopted fields. Use when drift should be kept rather than reverted."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *AdoptDriftTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *AdoptDriftTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *AdoptDriftTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *AdoptDriftTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace (_cluster for cluster-scoped resources)",
+				},
+				"app": {
+					Type:        "string",
+					Description: "The application name",
+				},
+				"type": {
+					Type:        "string",
+					Description: "The resource type (e.g., deployment, service)",
+				},
+				"commit_message": {
+					Type:        "string",
+					Description: "Why the live change is being kept; used as the commit message summary (optional)",
+				},
+			},
+			Required: []string{"namespace", "app", "type"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *AdoptDriftTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+
+	app, ok := argsMap["app"].(string)
+	if !ok || app == "" {
+		return map[string]any{"error": "app is required"}, nil
+	}
+
+	resourceType, ok := argsMap["type"].(string)
+	if !ok || resourceType == "" {
+		return map[string]any{"error": "type is required"}, nil
+	}
+
+	summary, _ := argsMap["commit_message"].(string)
+
+	content, err := t.manifest.ReadManifest(namespace, app, resourceType)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	ignore, err := LoadDriftIgnore(t.manifest.BaseDir())
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	drift := ignore.Apply(CompareManifest(toolContext(ctx), t.dynamicClient, t.resolver, namespace, app, resourceType, content))
+	switch drift.Status {
+	case "in_sync":
+		return map[string]any{
+			"success": true,
+			"status":  "in_sync",
+			"message": fmt.Sprintf("%s/%s/%s is in sync; nothing to adopt", namespace, app, resourceType),
+		}, nil
+	case "missing":
+		return map[string]any{"error": fmt.Sprintf("%s/%s/%s does not exist in the cluster; nothing to adopt", namespace, app, resourceType)}, nil
+	case "error":
+		return map[string]any{"error": drift.Error}, nil
+	}
+
+	// Fetch the live object with the same cleaning used for imports
+	var stored map[string]any
+	if err := yaml.Unmarshal(content, &stored); err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to parse stored manifest: %v", err)}, nil
+	}
+	apiVersion, _ := stored["apiVersion"].(string)
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
+	defer cancel()
+
+	live, err := FetchAndCleanLiveResource(timeoutCtx, t.dynamicClient, t.resolver, namespace, app, resourceType, apiVersion)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	yamlBytes, err := yaml.Marshal(live)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to marshal resource: %v", err)}, nil
+	}
+
+	manifestPath, err := t.manifest.SaveManifest(namespace, app, resourceType, yamlBytes)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to save manifest: %v", err)}, nil
+	}
+
+	paths := make([]string, len(drift.Diffs))
+	for i, d := range drift.Diffs {
+		paths[i] = d.Path
+	}
+
+	message := adoptCommitMessage(namespace, app, resourceType, summary, drift.Diffs)
+	if err := t.manifest.Commit(message); err != nil {
+		return map[string]any{
+			"error":         fmt.Sprintf("manifest updated but commit failed: %v", err),
+			"manifest_path": manifestPath,
+		}, nil
+	}
+
+	result := map[string]any{
+		"success":        true,
+		"namespace":      namespace,
+		"app":            app,
+		"type":           resourceType,
+		"adopted_fields": paths,
+		"manifest_path":  manifestPath,
+		"message":        fmt.Sprintf("Adopted %d live changes into %s and committed", len(paths), manifestPath),
+	}
+
+	// Auto-push if remote is configured
+	if err := t.manifest.Push(); err != nil {
+		result["push_warning"] = err.Error()
+	}
+
+	return result, nil
+}
+
+// adoptCommitMessage builds a commit message describing the adopted fields.
+func adoptCommitMessage(namespace, app, resourceType, summary string, diffs []DiffEntry) string {
+	var sb strings.Builder
+	if summary != "" {
+		sb.WriteString(fmt.Sprintf("Adopt live %s/%s/%s: %s\n\n", namespace, app, resourceType, summary))
+	} else {
+		sb.WriteString(fmt.Sprintf("Adopt live changes to %s/%s/%s\n\n", namespace, app, resourceType))
+	}
+	sb.WriteString("Fields updated from the cluster:\n")
+	for i, d := range diffs {
+		if i == adoptMaxListedDiffs {
+			sb.WriteString(fmt.Sprintf("- ... and %d more\n", len(diffs)-adoptMaxListedDiffs))
+			break
+		}
+		switch d.ChangeType {
+		case "removed":
+			sb.WriteString(fmt.Sprintf("- %s: removed (was %v)\n", d.Path, d.Stored))
+		default:
+			sb.WriteString(fmt.Sprintf("- %s: %v -> %v\n", d.Path, d.Stored, d.Live))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/model"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestAdoptCommitMessage(t *testing.T) {
+	diffs := []DiffEntry{
+		{Path: "spec.replicas", ChangeType: "changed", Stored: 2, Live: 5},
+		{Path: "metadata.labels.tier", ChangeType: "removed", Stored: "web"},
+	}
+	msg := adoptCommitMessage("prod", "web", "deployment", "scaled up for launch", diffs)
+	for _, want := range []string{
+		"Adopt live prod/web/deployment: scaled up for launch\n",
+		"- spec.replicas: 2 -> 5\n",
+		"- metadata.labels.tier: removed (was web)\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("commit message missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestAdoptDrift_CommitMessage(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	if _, err := mgr.SaveManifest("default", "settings", "configmap", []byte(configMapYAML("settings", "stored"))); err != nil {
+		t.Fatal(err)
+	}
+	dynClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), newConfigMap("settings", "changed"))
+	adopt := NewAdoptDriftTool(dynClient, nil, mgr)
+
+	// The commit message isn't replaced by the reason every tool gets
+	req := &model.LLMRequest{}
+	if err := addFunctionTool(req, adopt); err != nil {
+		t.Fatal(err)
+	}
+	param := req.Config.Tools[0].FunctionDeclarations[0].Parameters.Properties["commit_message"]
+	if param == nil || !strings.Contains(param.Description, "commit message") {
+		t.Fatalf("expected the commit_message parameter to be declared, got %+v", param)
+	}
+
+	result, _ := adopt.Run(nil, map[string]any{
+		"namespace":      "default",
+		"app":            "settings",
+		"type":           "configmap",
+		"reason":         "The user asked to keep the live value",
+		"commit_message": "raise the limit for the sale",
+	})
+	if result["success"] != true {
+		t.Fatalf("expected the drift to be adopted, got %v", result)
+	}
+	commits, err := mgr.History("default", "settings", time.Time{})
+	if err != nil || len(commits) == 0 {
+		t.Fatalf("expected a commit, got %v, %v", commits, err)
+	}
+	if want := "Adopt live default/settings/configmap: raise the limit for the sale"; commits[0].Message != want {
+		t.Errorf("commit subject = %q, want %q", commits[0].Message, want)
+	}
+}
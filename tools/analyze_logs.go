@@ -0,0 +1,304 @@
+package tools
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// analyzeMaxPods caps the pods analyze_logs reads for a label selector.
+	analyzeMaxPods = 10
+	// defaultAnalyzeLines is how many lines are read from each pod by default.
+	defaultAnalyzeLines = 5000
+	// defaultAnalyzeSince is the default since of analyze_logs.
+	defaultAnalyzeSince = 15 * time.Minute
+	// defaultAnalyzePatterns is how many of the most frequent patterns are
+	// returned by default.
+	defaultAnalyzePatterns = 25
+	// maxHighlightedPatterns caps the new and the rare patterns returned.
+	maxHighlightedPatterns = 20
+)
+
+// LogPattern is a template of log lines found by analyze_logs.
+type LogPattern struct {
+	Template  string `json:"template"` // variable tokens are <*>
+	Count     int    `json:"count"`
+	FirstSeen string `json:"first_seen,omitempty"`
+	LastSeen  string `json:"last_seen,omitempty"`
+	Example   string `json:"example"`
+	Errors    bool   `json:"errors,omitempty"` // the lines look like errors or warnings
+}
+
+// AnalyzeLogsTool groups container logs by pattern.
+type AnalyzeLogsTool struct {
+	clientset *kubernetes.Clientset
+}
+
+// NewAnalyzeLogsTool creates a new AnalyzeLogsTool.
+func NewAnalyzeLogsTool(clientset *kubernetes.Clientset) *AnalyzeLogsTool {
+	return &AnalyzeLogsTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *AnalyzeLogsTool) Name() string {
+	return "analyze_logs"
+}
+
+// Description returns the tool description.
+func (t *AnalyzeLogsTool) Description() string {
+	return "Summarize the logs of a pod, or of the pods matching a label selector, by grouping lines into patterns " +
+		"(variable parts such as IDs and numbers become <*>) with their counts, first and last occurrence. " +
+		"Highlights patterns that first appeared after 'since' and rare ones, which usually point at what changed. " +
+		"Prefer it over get_logs for long or noisy logs; use get_logs to read the lines around a pattern."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *AnalyzeLogsTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *AnalyzeLogsTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *AnalyzeLogsTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *AnalyzeLogsTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The namespace of the pods",
+				},
+				"pod": {
+					Type:        "string",
+					Description: "The name of the pod. Either pod or label_selector is required.",
+				},
+				"label_selector": {
+					Type:        "string",
+					Description: fmt.Sprintf("Analyze the pods matching this selector together, e.g. 'app=web' (at most %d pods)", analyzeMaxPods),
+				},
+				"container": {
+					Type:        "string",
+					Description: "The name of the container. Optional if the pods have only one container.",
+				},
+				"previous": {
+					Type:        "boolean",
+					Description: "Analyze the logs of the previous terminated container instance",
+				},
+				"since": {
+					Type:        "string",
+					Description: "Patterns first seen after this are reported as new: a duration such as '15m' (default) or an RFC3339 time",
+				},
+				"tail_lines": {
+					Type:        "integer",
+					Description: fmt.Sprintf("Number of lines read from the end of each pod's logs (default %d)", defaultAnalyzeLines),
+				},
+				"max_patterns": {
+					Type:        "integer",
+					Description: fmt.Sprintf("Number of most frequent patterns to return (default %d)", defaultAnalyzePatterns),
+				},
+			},
+			Required: []string{"namespace"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *AnalyzeLogsTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, _ := argsMap["namespace"].(string)
+	if namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+	pod, _ := argsMap["pod"].(string)
+	selector, _ := argsMap["label_selector"].(string)
+	if (pod == "") == (selector == "") {
+		return map[string]any{"error": "exactly one of pod and label_selector is required"}, nil
+	}
+	container, _ := argsMap["container"].(string)
+	previous, _ := argsMap["previous"].(bool)
+
+	now := time.Now()
+	since := now.Add(-defaultAnalyzeSince)
+	if s, _ := argsMap["since"].(string); s != "" {
+		var err error
+		if since, err = parsePromTime(s, now); err != nil {
+			return map[string]any{"error": fmt.Sprintf("invalid since: %v", err)}, nil
+		}
+	}
+	tailLines := int64(defaultAnalyzeLines)
+	if n, ok := argsMap["tail_lines"].(float64); ok && n > 0 {
+		tailLines = int64(n)
+	}
+	maxPatterns := defaultAnalyzePatterns
+	if n, ok := argsMap["max_patterns"].(float64); ok && n > 0 {
+		maxPatterns = int(n)
+	}
+
+	pods := []string{pod}
+	if selector != "" {
+		listCtx, cancel := apiContext(toolContext(ctx))
+		list, err := t.clientset.CoreV1().Pods(namespace).List(listCtx, metav1.ListOptions{LabelSelector: selector})
+		cancel()
+		if err != nil {
+			return map[string]any{"error": fmt.Sprintf("listing pods: %v", err)}, nil
+		}
+		if len(list.Items) == 0 {
+			return map[string]any{"error": fmt.Sprintf("no pods match %q in %s", selector, namespace)}, nil
+		}
+		pods = pods[:0]
+		for _, p := range list.Items {
+			pods = append(pods, p.Name)
+		}
+		sort.Strings(pods)
+	}
+
+	result := map[string]any{"namespace": namespace, "since": since.UTC().Format(time.RFC3339)}
+	if len(pods) > analyzeMaxPods {
+		result["note"] = fmt.Sprintf("%d pods match; only the first %d were analyzed", len(pods), analyzeMaxPods)
+		pods = pods[:analyzeMaxPods]
+	}
+
+	miner := newPatternMiner()
+	lines, before := 0, 0
+	var failed []string
+	limitBytes := int64(maxLogBytes)
+	for _, name := range pods {
+		logCtx, cancel := apiContext(toolContext(ctx))
+		stream, err := t.clientset.CoreV1().Pods(namespace).GetLogs(name, &corev1.PodLogOptions{
+			Container:  container,
+			Previous:   previous,
+			TailLines:  &tailLines,
+			LimitBytes: &limitBytes,
+			Timestamps: true,
+		}).Stream(logCtx)
+		if err != nil {
+			cancel()
+			failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		scanner := bufio.NewScanner(stream)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			at, line := splitLogTimestamp(scanner.Text())
+			if !at.IsZero() && at.Before(since) {
+				before++
+			}
+			miner.add(line, at)
+			lines++
+		}
+		if err := scanner.Err(); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: reading logs: %v", name, err))
+		}
+		stream.Close()
+		cancel()
+	}
+	if len(failed) == len(pods) {
+		return map[string]any{"error": strings.Join(failed, "; "), "namespace": namespace}, nil
+	}
+	if len(failed) > 0 {
+		result["failed_pods"] = failed
+	}
+
+	result["pods"] = pods
+	result["lines"] = lines
+	result["pattern_count"] = len(miner.patterns)
+	if miner.unmatched > 0 {
+		result["unmatched_lines"] = miner.unmatched
+	}
+	sorted := miner.byCount()
+	top := sorted[:min(maxPatterns, len(sorted))]
+	result["patterns"] = logPatterns(top)
+
+	// Patterns are new only if the logs reach back before since
+	var fresh, rare []*logPattern
+	for _, p := range sorted {
+		switch {
+		case before > 0 && !p.first.IsZero() && p.first.After(since):
+			fresh = append(fresh, p)
+		case p.count <= rarePatternCount:
+			rare = append(rare, p)
+		}
+	}
+	if before == 0 && lines > 0 {
+		result["new_patterns_note"] = "the logs read don't reach back before since, so no pattern can be told apart as new; raise tail_lines or move since"
+	} else {
+		result["new_patterns"] = logPatterns(errorsFirst(fresh))
+	}
+	result["rare_patterns"] = logPatterns(errorsFirst(rare))
+	return result, nil
+}
+
+// splitLogTimestamp splits the RFC3339 timestamp the API server puts in
+// front of each line off the line. The time is zero if there is none.
+func splitLogTimestamp(line string) (time.Time, string) {
+	stamp, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		return time.Time{}, line
+	}
+	at, err := time.Parse(time.RFC3339Nano, stamp)
+	if err != nil {
+		return time.Time{}, line
+	}
+	return at, rest
+}
+
+// errorsFirst orders patterns that look like errors before the others, and
+// keeps at most maxHighlightedPatterns.
+func errorsFirst(patterns []*logPattern) []*logPattern {
+	sort.SliceStable(patterns, func(i, j int) bool {
+		return interestingLine.MatchString(patterns[i].example) && !interestingLine.MatchString(patterns[j].example)
+	})
+	return patterns[:min(maxHighlightedPatterns, len(patterns))]
+}
+
+func logPatterns(patterns []*logPattern) []LogPattern {
+	out := make([]LogPattern, 0, len(patterns))
+	for _, p := range patterns {
+		lp := LogPattern{
+			Template: p.template(),
+			Count:    p.count,
+			Example:  p.example,
+			Errors:   interestingLine.MatchString(p.example),
+		}
+		if !p.first.IsZero() {
+			lp.FirstSeen = p.first.UTC().Format(time.RFC3339)
+			lp.LastSeen = p.last.UTC().Format(time.RFC3339)
+		}
+		out = append(out, lp)
+	}
+	return out
+}
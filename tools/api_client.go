@@ -0,0 +1,268 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// Defaults for unset APIOptions fields.
+const (
+	DefaultQPS        = 50
+	DefaultBurst      = 100
+	DefaultMaxRetries = 4
+)
+
+// apiTimeout bounds the Kubernetes API calls of a tool call made without
+// the timeout middleware, including rate limiting and retries.
+const apiTimeout = 30 * time.Second
+
+// Backoff between retries: retryBaseDelay doubled on every attempt, with
+// jitter, and never more than retryMaxDelay unless the server asks for it.
+const (
+	retryBaseDelay = 250 * time.Millisecond
+	retryMaxDelay  = 8 * time.Second
+)
+
+// APIOptions configures how kasa talks to the API server (the kubernetes
+// section of config.yaml). The zero value uses the defaults.
+type APIOptions struct {
+	// QPS and Burst are the client-side rate limit shared by all tools
+	// (defaults 50 and 100).
+	QPS   float32 `yaml:"qps"`
+	Burst int     `yaml:"burst"`
+	// MaxRetries is how often a throttled (429) or unavailable (502, 503,
+	// 504) request is retried with exponential backoff (default 4). -1
+	// disables retries.
+	MaxRetries int `yaml:"max_retries"`
+	// Timeouts bounds tool calls by category.
+	Timeouts Timeouts `yaml:"timeouts"`
+}
+
+// Validate checks for negative settings.
+func (o APIOptions) Validate() error {
+	switch {
+	case o.QPS < 0:
+		return fmt.Errorf("qps must not be negative")
+	case o.Burst < 0:
+		return fmt.Errorf("burst must not be negative")
+	case o.MaxRetries < -1:
+		return fmt.Errorf("max_retries must not be negative, or -1 to disable retries")
+	}
+	return o.Timeouts.Validate()
+}
+
+// ConfigureAPIClient applies opts to config before clients are created from
+// it: a client-side rate limit, and retries with exponential backoff when
+// the API server is throttling or unavailable. Writes of the tool calls
+// policyGuard checks are evaluated against the policies before they are
+// sent. Clients derived from config later, such as the dry-run clients,
+// share the rate limit.
+func ConfigureAPIClient(config *rest.Config, opts APIOptions) {
+	qps, burst := opts.QPS, opts.Burst
+	if qps == 0 {
+		qps = DefaultQPS
+	}
+	if burst == 0 {
+		burst = max(DefaultBurst, int(qps))
+	}
+	config.QPS, config.Burst = qps, burst
+	config.RateLimiter = rateLimiter{RateLimiter: flowcontrol.NewTokenBucketRateLimiter(qps, burst), qps: qps}
+
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return policyTransport{next: rt}
+	})
+
+	retries := opts.MaxRetries
+	if retries == 0 {
+		retries = DefaultMaxRetries
+	}
+	if retries > 0 {
+		config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return &retryTransport{next: rt, maxRetries: retries, baseDelay: retryBaseDelay}
+		})
+	}
+}
+
+// apiContext returns the context for the Kubernetes API calls of one tool
+// call, cancelled when ctx is, e.g. when the user interrupts the agent. The
+// timeout is the request timeout of the call's category (see Timeouts).
+func apiContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, requestTimeout(ctx))
+}
+
+// toolContext returns the context of a tool call as a plain context. Tests
+// and replays run tools without a tool.Context.
+func toolContext(ctx tool.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// sessionless reports whether ctx runs outside an agent session. Tests and
+// replays run tools with a nil tool.Context, or one WithContext made from
+// nil, which only carries a Go context.
+func sessionless(ctx tool.Context) bool {
+	if d, ok := ctx.(derivedContext); ok {
+		return sessionless(d.Context)
+	}
+	return ctx == nil
+}
+
+// rateLimiter explains client-side throttling when it makes a call miss
+// its deadline; the token bucket only says "would exceed context deadline".
+type rateLimiter struct {
+	flowcontrol.RateLimiter
+	qps float32
+}
+
+func (l rateLimiter) Wait(ctx context.Context) error {
+	err := l.RateLimiter.Wait(ctx)
+	if err == nil || errors.Is(ctx.Err(), context.Canceled) {
+		return err
+	}
+	return fmt.Errorf("kasa's own limit of %g API requests per second (kubernetes.qps) delayed this call past its deadline; "+
+		"narrow the query or raise kubernetes.qps and kubernetes.burst: %w", l.qps, err)
+}
+
+// retryTransport retries requests the API server rejected because it is
+// throttling (429) or unavailable (502, 503, 504). Requests rejected with
+// 429 were not processed, so any request is retried; for the 5xx codes only
+// reads are, since a write may have been applied. A Retry-After header
+// overrides the backoff.
+type retryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+	baseDelay  time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 {
+			var err error
+			if r, err = replay(req); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := t.next.RoundTrip(r)
+		if err != nil || !retryable(req, resp.StatusCode) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt, resp)
+		deadline, hasDeadline := req.Context().Deadline()
+		if attempt >= t.maxRetries || (hasDeadline && time.Now().Add(delay).After(deadline)) {
+			return giveUp(req, resp, attempt+1, time.Since(start))
+		}
+
+		discard(resp)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt.
+func (t *retryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	delay := min(t.baseDelay<<attempt, retryMaxDelay)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retryable reports whether a response with the given status is worth
+// retrying. A request whose body can't be replayed is never retried.
+func retryable(req *http.Request, status int) bool {
+	if !replayable(req) {
+		return false
+	}
+	switch status {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return req.Method == http.MethodGet || req.Method == http.MethodHead
+	}
+	return false
+}
+
+// replayable reports whether req can be sent again: its body, if any, can
+// be read a second time.
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// replay returns a copy of req to send again, with a fresh body.
+func replay(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+// discard drains and closes the body of a response that is not returned,
+// so its connection can be reused.
+func discard(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+}
+
+// giveUp ends the retries. Throttling becomes an error that says what
+// happened; other responses are returned as they are, without Retry-After
+// so that client-go doesn't start retrying on its own.
+func giveUp(req *http.Request, resp *http.Response, attempts int, elapsed time.Duration) (*http.Response, error) {
+	if resp.StatusCode != http.StatusTooManyRequests {
+		resp.Header.Del("Retry-After")
+		return resp, nil
+	}
+	_ = resp.Body.Close()
+	return nil, &throttledError{method: req.Method, path: req.URL.Path, attempts: attempts, elapsed: elapsed}
+}
+
+// throttledError is returned when the API server kept answering 429 Too
+// Many Requests.
+type throttledError struct {
+	method   string
+	path     string
+	attempts int
+	elapsed  time.Duration
+}
+
+func (e *throttledError) Error() string {
+	return fmt.Sprintf("the API server is throttling requests (429 Too Many Requests) and still was after %d attempts over %s (%s %s); "+
+		"the cluster is busy or kasa's requests exceed its priority and fairness limits, so wait a minute and retry with fewer or narrower calls",
+		e.attempts, e.elapsed.Round(time.Millisecond), e.method, e.path)
+}
+
+// pause waits for d, returning false early if ctx is cancelled first.
+func pause(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
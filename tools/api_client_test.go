@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// flakyServer answers the first failures requests with status, then 200.
+func flakyServer(t *testing.T, status, failures int) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(calls.Add(1)) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRetryTransport(t *testing.T) {
+	client := &http.Client{Transport: &retryTransport{next: http.DefaultTransport, maxRetries: 3, baseDelay: time.Millisecond}}
+
+	tests := []struct {
+		name      string
+		method    string
+		status    int
+		failures  int
+		wantCalls int32
+		wantCode  int
+		wantErr   string
+	}{
+		{name: "throttled read recovers", method: http.MethodGet, status: http.StatusTooManyRequests, failures: 2, wantCalls: 3, wantCode: http.StatusOK},
+		{name: "throttled write is retried", method: http.MethodPost, status: http.StatusTooManyRequests, failures: 1, wantCalls: 2, wantCode: http.StatusOK},
+		{name: "unavailable read recovers", method: http.MethodGet, status: http.StatusServiceUnavailable, failures: 1, wantCalls: 2, wantCode: http.StatusOK},
+		{name: "unavailable write is not retried", method: http.MethodPost, status: http.StatusServiceUnavailable, failures: 1, wantCalls: 1, wantCode: http.StatusServiceUnavailable},
+		{name: "server errors are not retried", method: http.MethodGet, status: http.StatusInternalServerError, failures: 1, wantCalls: 1, wantCode: http.StatusInternalServerError},
+		{name: "persistent throttling explains itself", method: http.MethodGet, status: http.StatusTooManyRequests, failures: 10, wantCalls: 4, wantErr: "throttling requests (429 Too Many Requests) and still was after 4 attempts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := flakyServer(t, tt.status, tt.failures)
+			req, _ := http.NewRequest(tt.method, srv.URL+"/api/v1/pods", strings.NewReader(`{"kind":"Pod"}`))
+			resp, err := client.Do(req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tt.wantErr, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != tt.wantCode {
+					t.Errorf("want status %d, got %d", tt.wantCode, resp.StatusCode)
+				}
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("want %d calls, got %d", tt.wantCalls, got)
+			}
+		})
+	}
+}
+
+func TestRateLimiterExplainsDeadline(t *testing.T) {
+	l := rateLimiter{RateLimiter: flowcontrol.NewTokenBucketRateLimiter(0.01, 1), qps: 0.01}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx); err != nil {
+		t.Fatalf("first request is within the burst: %v", err)
+	}
+	err := l.Wait(ctx)
+	if err == nil || !strings.Contains(err.Error(), "kubernetes.qps") {
+		t.Errorf("want an error naming kubernetes.qps, got %v", err)
+	}
+}
+
+func TestPauseStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	if pause(ctx, time.Minute) {
+		t.Fatal("expected pause to report the cancellation")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("pause returned after %s; expected it to stop on cancel", elapsed)
+	}
+	if !pause(context.Background(), time.Millisecond) {
+		t.Error("expected an uncancelled pause to complete")
+	}
+}
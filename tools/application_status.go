@@ -0,0 +1,354 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// applicationStatusMaxItems caps the resources and inventory entries listed.
+const applicationStatusMaxItems = 50
+
+// GetApplicationStatusTool provides the get_application_status tool for the agent.
+type GetApplicationStatusTool struct {
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+}
+
+// NewGetApplicationStatusTool creates a new GetApplicationStatusTool.
+func NewGetApplicationStatusTool(dynamicClient dynamic.Interface, resolver *GVRResolver) *GetApplicationStatusTool {
+	return &GetApplicationStatusTool{
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+	}
+}
+
+// Name returns the tool name.
+func (t *GetApplicationStatusTool) Name() string {
+	return "get_application_status"
+}
+
+// Description returns the tool description.
+func (t *GetApplicationStatusTool) Description() string {
+	return "Show the status of one GitOps application: an Argo CD Application (sync and health, out-of-sync or unhealthy resources, last sync operation, history) or a Flux Kustomization/HelmRelease (conditions, source and revision, inventory). Also says where changes must be made so they aren't reverted. Use it when get_resource reports that a resource is managed by GitOps."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *GetApplicationStatusTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *GetApplicationStatusTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *GetApplicationStatusTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *GetApplicationStatusTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"name": {
+					Type:        "string",
+					Description: "Name of the Application, Kustomization or HelmRelease",
+				},
+				"namespace": {
+					Type:        "string",
+					Description: "Its namespace (default: argocd for Applications, flux-system for Flux)",
+				},
+				"kind": {
+					Type:        "string",
+					Description: "application (Argo CD, default), kustomization or helmrelease (Flux)",
+				},
+			},
+			Required: []string{"name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *GetApplicationStatusTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+	kind := "application"
+	if k, ok := argsMap["kind"].(string); ok && k != "" {
+		kind = NormalizeKindName(k)
+	}
+	namespace, _ := argsMap["namespace"].(string)
+
+	switch kind {
+	case "application":
+		if namespace == "" {
+			namespace = "argocd"
+		}
+		return argoApplicationStatus(toolContext(ctx), t.dynamicClient, t.resolver, namespace, name), nil
+	case "kustomization", "helmrelease":
+		if namespace == "" {
+			namespace = "flux-system"
+		}
+		return fluxStatus(toolContext(ctx), t.dynamicClient, t.resolver, kind, namespace, name), nil
+	default:
+		return map[string]any{"error": fmt.Sprintf("kind must be application, kustomization or helmrelease, got %q", kind)}, nil
+	}
+}
+
+// argoApplicationStatus reports on one Argo CD Application.
+func argoApplicationStatus(ctx context.Context, dyn dynamic.Interface, resolver *GVRResolver, namespace, name string) map[string]any {
+	obj, err := getGitOpsObject(ctx, dyn, resolver, "application", "argoproj.io/v1alpha1", namespace, name)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	var app argoApplication
+	if err := decodeUnstructured(obj, &app); err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to decode Application: %v", err)}
+	}
+	s := app.summarize()
+
+	var issues []string
+	if s.Sync != "Synced" {
+		issues = append(issues, fmt.Sprintf("Application is %s", s.Sync))
+	}
+	if s.Health != "Healthy" {
+		issue := fmt.Sprintf("Health is %s", s.Health)
+		if msg := app.Status.Health.Message; msg != "" {
+			issue += ": " + msg
+		}
+		issues = append(issues, issue)
+	}
+	if s.Operation != "" {
+		issues = append(issues, "Last sync operation: "+s.Operation)
+	}
+	for _, c := range app.Status.Conditions {
+		issues = append(issues, fmt.Sprintf("%s: %s", c.Type, c.Message))
+	}
+
+	// Only the resources that need attention; a healthy app can have hundreds
+	var resources []string
+	for _, r := range app.Status.Resources {
+		var states []string
+		if r.Status != "" && r.Status != "Synced" {
+			states = append(states, r.Status)
+		}
+		if r.Health != nil && r.Health.Status != "Healthy" {
+			health := r.Health.Status
+			if r.Health.Message != "" {
+				health += ": " + r.Health.Message
+			}
+			states = append(states, health)
+		}
+		if len(states) == 0 {
+			continue
+		}
+		ref := r.Name
+		if r.Namespace != "" {
+			ref = r.Namespace + "/" + r.Name
+		}
+		resources = append(resources, fmt.Sprintf("%s %s: %s", r.Kind, ref, strings.Join(states, ", ")))
+	}
+
+	var history []string
+	for i := len(app.Status.History) - 1; i >= 0 && len(history) < 3; i-- {
+		h := app.Status.History[i]
+		history = append(history, fmt.Sprintf("%s (%s)", shortRevision(h.Revision), h.DeployedAt))
+	}
+
+	source := s.Repo
+	if s.Path != "" {
+		source += " path " + s.Path
+	}
+	if s.Chart != "" {
+		source += " chart " + s.Chart
+	}
+	if s.TargetRevision != "" {
+		source += " at " + s.TargetRevision
+	}
+	var advice string
+	switch {
+	case s.AutoSync && s.SelfHeal:
+		advice = fmt.Sprintf("Automated sync with self-heal is on: manual changes to its resources are reverted within minutes. Change %s instead.", source)
+	case s.AutoSync:
+		advice = fmt.Sprintf("Automated sync is on: manual changes show up as OutOfSync and are overwritten by the next commit. Change %s instead.", source)
+	default:
+		advice = fmt.Sprintf("Sync is manual: manual changes show up as OutOfSync and are overwritten on the next sync. Change %s instead.", source)
+	}
+
+	result := map[string]any{
+		"application":     s,
+		"resources_total": len(app.Status.Resources),
+		"advice":          advice,
+	}
+	if len(resources) > applicationStatusMaxItems {
+		result["resources_truncated"] = true
+		resources = resources[:applicationStatusMaxItems]
+	}
+	if len(resources) > 0 {
+		result["resources_needing_attention"] = resources
+	}
+	if len(history) > 0 {
+		result["recent_syncs"] = history
+	}
+	if len(issues) > 0 {
+		result["issues"] = issues
+	}
+	return result
+}
+
+// fluxStatus reports on one Flux Kustomization or HelmRelease and its source.
+func fluxStatus(ctx context.Context, dyn dynamic.Interface, resolver *GVRResolver, kind, namespace, name string) map[string]any {
+	obj, err := getGitOpsObject(ctx, dyn, resolver, kind, "", namespace, name)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	var f fluxObject
+	if err := decodeUnstructured(obj, &f); err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to decode %s: %v", obj.GetKind(), err)}
+	}
+	s := f.summarize()
+
+	var conditions, issues []string
+	for _, c := range f.Status.Conditions {
+		conditions = append(conditions, fmt.Sprintf("%s=%s %s: %s", c.Type, c.Status, c.Reason, c.Message))
+	}
+	if s.Ready != "True" {
+		issues = append(issues, fmt.Sprintf("Not ready (%s): %s", s.Reason, s.Message))
+	}
+	if f.Status.LastAttemptedRevision != "" && f.Status.LastAttemptedRevision != f.Status.LastAppliedRevision && f.Status.LastAppliedRevision != "" {
+		issues = append(issues, fmt.Sprintf("Revision %s failed to apply; %s is still running", f.Status.LastAttemptedRevision, f.Status.LastAppliedRevision))
+	}
+
+	result := map[string]any{
+		NormalizeKindName(s.Kind): s,
+	}
+	if len(conditions) > 0 {
+		result["conditions"] = conditions
+	}
+
+	sourceURL := ""
+	if ref := f.sourceRef(); ref != nil {
+		if src, err := getGitOpsObject(ctx, dyn, resolver, strings.ToLower(ref.Kind), "", ref.Namespace, ref.Name); err == nil {
+			sourceURL, _, _ = unstructured.NestedString(src.Object, "spec", "url")
+			revision, _, _ := unstructured.NestedString(src.Object, "status", "artifact", "revision")
+			info := map[string]any{"kind": ref.Kind, "namespace": ref.Namespace, "name": ref.Name, "url": sourceURL, "revision": revision}
+			var srcStatus struct {
+				Status struct {
+					Conditions []metav1.Condition `json:"conditions"`
+				} `json:"status"`
+			}
+			if decodeUnstructured(src, &srcStatus) == nil {
+				if ready := meta.FindStatusCondition(srcStatus.Status.Conditions, "Ready"); ready != nil {
+					info["ready"] = string(ready.Status)
+					if ready.Status != metav1.ConditionTrue {
+						issues = append(issues, fmt.Sprintf("Source %s/%s is not ready: %s", ref.Kind, ref.Name, ready.Message))
+					}
+				}
+			}
+			result["source"] = info
+		}
+	}
+
+	if inv := f.Status.Inventory; inv != nil {
+		var entries []string
+		for _, e := range inv.Entries {
+			if len(entries) == applicationStatusMaxItems {
+				result["inventory_truncated"] = true
+				break
+			}
+			entries = append(entries, inventoryEntry(e.ID))
+		}
+		result["inventory"] = entries
+		result["inventory_count"] = len(inv.Entries)
+	}
+	var history []string
+	for _, h := range f.Status.History {
+		if len(history) == 3 {
+			break
+		}
+		history = append(history, fmt.Sprintf("%s: %s", h.ChartVersion, h.Status))
+	}
+	if len(history) > 0 {
+		result["recent_releases"] = history
+	}
+
+	where := "its source repository"
+	if sourceURL != "" {
+		where = sourceURL
+	}
+	if s.Path != "" {
+		where += " path " + s.Path
+	}
+	if s.Suspended {
+		result["advice"] = fmt.Sprintf("Reconciliation is suspended: manual changes stay until it is resumed, then they are overwritten. Make lasting changes in %s.", where)
+		issues = append(issues, "Reconciliation is suspended")
+	} else {
+		result["advice"] = fmt.Sprintf("Flux re-applies this on every interval, so manual changes to its resources are reverted. Change %s instead.", where)
+	}
+	if len(issues) > 0 {
+		result["issues"] = issues
+	}
+	return result
+}
+
+// getGitOpsObject fetches a GitOps object, reporting a missing CRD as the
+// controller not being installed.
+func getGitOpsObject(ctx context.Context, dyn dynamic.Interface, resolver *GVRResolver, kind, apiVersion, namespace, name string) (*unstructured.Unstructured, error) {
+	if dyn == nil {
+		return nil, fmt.Errorf("dynamic client not available")
+	}
+	gvr, err := resolver.Resolve(kind, apiVersion)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a known kind; is its controller installed? %v", kind, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	obj, err := dyn.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s %s/%s: %v", kind, namespace, name, err)
+	}
+	return obj, nil
+}
+
+// inventoryEntry turns a Flux inventory ID ("<namespace>_<name>_<group>_<kind>")
+// into "Kind namespace/name".
+func inventoryEntry(id string) string {
+	parts := strings.Split(id, "_")
+	if len(parts) != 4 {
+		return id
+	}
+	if parts[0] == "" {
+		return parts[3] + " " + parts[1]
+	}
+	return fmt.Sprintf("%s %s/%s", parts[3], parts[0], parts[1])
+}
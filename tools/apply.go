@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/perbu/kasa/manifest"
 	"google.golang.org/adk/model"
@@ -15,21 +14,26 @@ import (
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/yaml"
 )
 
 // ApplyManifestTool provides the apply_manifest tool for the agent.
 type ApplyManifestTool struct {
-	clientset *kubernetes.Clientset
-	manifest  *manifest.Manager
+	clientset     *kubernetes.Clientset
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+	manifest      *manifest.Manager
 }
 
 // NewApplyManifestTool creates a new ApplyManifestTool.
-func NewApplyManifestTool(clientset *kubernetes.Clientset, manifest *manifest.Manager) *ApplyManifestTool {
+func NewApplyManifestTool(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, resolver *GVRResolver, manifest *manifest.Manager) *ApplyManifestTool {
 	return &ApplyManifestTool{
-		clientset: clientset,
-		manifest:  manifest,
+		clientset:     clientset,
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+		manifest:      manifest,
 	}
 }
 
@@ -68,7 +72,7 @@ func (t *ApplyManifestTool) Declaration() *genai.FunctionDeclaration {
 			Properties: map[string]*genai.Schema{
 				"namespace": {
 					Type:        "string",
-					Description: "The namespace of the manifest",
+					Description: "The namespace of the manifest (_cluster for cluster-scoped resources)",
 				},
 				"app": {
 					Type:        "string",
@@ -82,6 +86,7 @@ func (t *ApplyManifestTool) Declaration() *genai.FunctionDeclaration {
 					Type:        "boolean",
 					Description: "If true, validate without applying (default: false)",
 				},
+				"change_cause": changeCauseSchema,
 			},
 			Required: []string{"namespace", "app", "type"},
 		},
@@ -122,12 +127,11 @@ func (t *ApplyManifestTool) Run(ctx tool.Context, args any) (map[string]any, err
 		dryRun = dr
 	}
 
-	// Normalize resource type
-	resourceType = normalizeKind(resourceType)
-	if resourceType == "" {
-		return map[string]any{
-			"error": "unsupported resource type. Supported: deployment, service, configmap, secret, ingress",
-		}, nil
+	// Normalize resource type; other kinds are applied via the dynamic client
+	if normalized := normalizeKind(resourceType); normalized != "" {
+		resourceType = normalized
+	} else {
+		resourceType = NormalizeKindName(resourceType)
 	}
 
 	// Read manifest from storage
@@ -137,10 +141,11 @@ func (t *ApplyManifestTool) Run(ctx tool.Context, args any) (map[string]any, err
 	}
 
 	// Apply to cluster
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
 	defer cancel()
 
-	action, err := t.applyResource(timeoutCtx, namespace, resourceType, content, dryRun)
+	cause := changeCause(argsMap, fmt.Sprintf("apply_manifest %s/%s/%s", namespace, app, resourceType))
+	action, err := t.applyResource(timeoutCtx, namespace, resourceType, content, dryRun, cause)
 	if err != nil {
 		return map[string]any{
 			"success": false,
@@ -159,6 +164,8 @@ func (t *ApplyManifestTool) Run(ctx tool.Context, args any) (map[string]any, err
 	if dryRun {
 		result["dry_run"] = true
 		result["message"] = fmt.Sprintf("Dry run: %s/%s/%s would be %s", namespace, app, resourceType, action)
+	} else if namespace == manifest.ClusterNamespace {
+		result["message"] = fmt.Sprintf("Successfully %s cluster-scoped %s/%s", action, resourceType, app)
 	} else {
 		result["message"] = fmt.Sprintf("Successfully %s %s/%s in namespace %s", action, resourceType, app, namespace)
 	}
@@ -166,8 +173,9 @@ func (t *ApplyManifestTool) Run(ctx tool.Context, args any) (map[string]any, err
 	return result, nil
 }
 
-// applyResource applies a resource to the cluster.
-func (t *ApplyManifestTool) applyResource(ctx context.Context, namespace, resourceType string, content []byte, dryRun bool) (string, error) {
+// applyResource applies a resource to the cluster. cause is recorded as the
+// change-cause of workloads.
+func (t *ApplyManifestTool) applyResource(ctx context.Context, namespace, resourceType string, content []byte, dryRun bool, cause string) (string, error) {
 	var createOpts metav1.CreateOptions
 	var updateOpts metav1.UpdateOptions
 
@@ -178,7 +186,7 @@ func (t *ApplyManifestTool) applyResource(ctx context.Context, namespace, resour
 
 	switch resourceType {
 	case "deployment":
-		return t.applyDeployment(ctx, namespace, content, createOpts, updateOpts)
+		return t.applyDeployment(ctx, namespace, content, cause, createOpts, updateOpts)
 	case "service":
 		return t.applyService(ctx, namespace, content, createOpts, updateOpts)
 	case "configmap":
@@ -188,16 +196,20 @@ func (t *ApplyManifestTool) applyResource(ctx context.Context, namespace, resour
 	case "ingress":
 		return t.applyIngress(ctx, namespace, content, createOpts, updateOpts)
 	default:
-		return "", fmt.Errorf("unsupported resource type: %s", resourceType)
+		if t.dynamicClient == nil {
+			return "", fmt.Errorf("unsupported resource type: %s", resourceType)
+		}
+		return applyStoredManifest(ctx, t.dynamicClient, t.resolver, namespace, content, dryRun, cause)
 	}
 }
 
-func (t *ApplyManifestTool) applyDeployment(ctx context.Context, namespace string, content []byte, createOpts metav1.CreateOptions, updateOpts metav1.UpdateOptions) (string, error) {
+func (t *ApplyManifestTool) applyDeployment(ctx context.Context, namespace string, content []byte, cause string, createOpts metav1.CreateOptions, updateOpts metav1.UpdateOptions) (string, error) {
 	var deployment appsv1.Deployment
 	if err := yaml.Unmarshal(content, &deployment); err != nil {
 		return "", fmt.Errorf("invalid YAML: %v", err)
 	}
 	deployment.Namespace = namespace
+	setChangeCause(ctx, &deployment, cause)
 
 	existing, err := t.clientset.AppsV1().Deployments(namespace).Get(ctx, deployment.Name, metav1.GetOptions{})
 	if err != nil {
@@ -1,11 +1,9 @@
 package tools
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/perbu/kasa/manifest"
 	"google.golang.org/adk/model"
@@ -19,13 +17,15 @@ import (
 // ApplyResourceTool provides the apply_resource tool for applying any Kubernetes resource.
 type ApplyResourceTool struct {
 	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
 	manifest      *manifest.Manager
 }
 
 // NewApplyResourceTool creates a new ApplyResourceTool.
-func NewApplyResourceTool(dynamicClient dynamic.Interface, manifest *manifest.Manager) *ApplyResourceTool {
+func NewApplyResourceTool(dynamicClient dynamic.Interface, resolver *GVRResolver, manifest *manifest.Manager) *ApplyResourceTool {
 	return &ApplyResourceTool{
 		dynamicClient: dynamicClient,
+		resolver:      resolver,
 		manifest:      manifest,
 	}
 }
@@ -79,6 +79,7 @@ func (t *ApplyResourceTool) Declaration() *genai.FunctionDeclaration {
 					Type:        "boolean",
 					Description: "If true, validate without applying (server-side dry run)",
 				},
+				"change_cause": changeCauseSchema,
 			},
 			Required: []string{"yaml"},
 		},
@@ -130,8 +131,11 @@ func (t *ApplyResourceTool) Run(ctx tool.Context, args any) (map[string]any, err
 		return map[string]any{"error": "YAML must contain a 'kind' field"}, nil
 	}
 
-	// Convert GVK to GVR
-	gvr := GVKToGVR(gvk)
+	// Resolve GVK to GVR via discovery
+	gvr, err := t.resolver.ResolveGVK(gvk)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
 
 	// Determine namespace
 	namespace := obj.GetNamespace()
@@ -141,10 +145,14 @@ func (t *ApplyResourceTool) Run(ctx tool.Context, args any) (map[string]any, err
 	}
 
 	// Check if resource is namespaced
-	namespaced := IsNamespaced(gvk.Kind)
+	namespaced := t.resolver.IsNamespaced(gvr, gvk.Kind)
 	if namespaced && namespace == "" {
 		namespace = "default"
 		obj.SetNamespace(namespace)
+	} else if !namespaced && namespace != "" {
+		// Cluster-scoped objects must not carry a namespace
+		namespace = ""
+		obj.SetNamespace("")
 	}
 
 	name := obj.GetName()
@@ -157,7 +165,7 @@ func (t *ApplyResourceTool) Run(ctx tool.Context, args any) (map[string]any, err
 		appName = name
 	}
 
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
 	defer cancel()
 
 	// Determine resource type for manifest storage (lowercase kind)
@@ -179,6 +187,16 @@ func (t *ApplyResourceTool) Run(ctx tool.Context, args any) (map[string]any, err
 		updateOptions.DryRun = []string{metav1.DryRunAll}
 	}
 
+	// Record why a workload changed on the live object; the stored manifest
+	// keeps the YAML as given, including any change-cause it already sets.
+	if isRolloutKind(gvk.Kind) {
+		fallback := obj.GetAnnotations()[changeCauseAnnotation]
+		if fallback == "" {
+			fallback = fmt.Sprintf("apply_resource %s/%s", gvk.Kind, name)
+		}
+		setChangeCause(toolContext(ctx), obj, changeCause(argsMap, fallback))
+	}
+
 	// Try to get existing resource to determine create vs update
 	existing, err := resourceClient.Get(timeoutCtx, name, metav1.GetOptions{})
 	var resultObj *unstructured.Unstructured
@@ -226,8 +244,12 @@ func (t *ApplyResourceTool) Run(ctx tool.Context, args any) (map[string]any, err
 		result["message"] = fmt.Sprintf("%s %s/%s", actionTitle, gvk.Kind, name)
 
 		// Save manifest to git storage (only on actual apply, not dry run)
-		if t.manifest != nil && namespaced {
-			manifestPath, err := t.manifest.SaveManifest(namespace, appName, resourceType, []byte(yamlContent))
+		if t.manifest != nil {
+			manifestNamespace := namespace
+			if !namespaced {
+				manifestNamespace = manifest.ClusterNamespace
+			}
+			manifestPath, err := t.manifest.SaveManifest(manifestNamespace, appName, resourceType, []byte(yamlContent))
 			if err != nil {
 				result["manifest_warning"] = fmt.Sprintf("Applied to cluster but failed to save manifest: %v", err)
 			} else {
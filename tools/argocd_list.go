@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+// ListArgoCDApplicationsTool provides the list_argocd_applications tool for the agent.
+type ListArgoCDApplicationsTool struct {
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+}
+
+// NewListArgoCDApplicationsTool creates a new ListArgoCDApplicationsTool.
+func NewListArgoCDApplicationsTool(dynamicClient dynamic.Interface, resolver *GVRResolver) *ListArgoCDApplicationsTool {
+	return &ListArgoCDApplicationsTool{
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+	}
+}
+
+// Name returns the tool name.
+func (t *ListArgoCDApplicationsTool) Name() string {
+	return "list_argocd_applications"
+}
+
+// Description returns the tool description.
+func (t *ListArgoCDApplicationsTool) Description() string {
+	return "List Argo CD Applications with their source repository, path and revision, destination namespace, sync and health status, and whether automated sync (self-heal) is on. Use it to see which workloads are deployed by GitOps before changing them."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *ListArgoCDApplicationsTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *ListArgoCDApplicationsTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *ListArgoCDApplicationsTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *ListArgoCDApplicationsTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "Namespace the Applications live in (default: all namespaces; usually argocd)",
+				},
+				"destination_namespace": {
+					Type:        "string",
+					Description: "Only show Applications deploying into this namespace",
+				},
+			},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *ListArgoCDApplicationsTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else if args != nil {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, _ := argsMap["namespace"].(string)
+	destination, _ := argsMap["destination_namespace"].(string)
+	return listArgoApplications(toolContext(ctx), t.dynamicClient, t.resolver, namespace, destination), nil
+}
+
+// listArgoApplications lists Applications, optionally only those deploying
+// into the destination namespace.
+func listArgoApplications(ctx context.Context, dyn dynamic.Interface, resolver *GVRResolver, namespace, destination string) map[string]any {
+	if dyn == nil {
+		return map[string]any{"error": "dynamic client not available"}
+	}
+	gvr, err := resolver.Resolve("application", "argoproj.io/v1alpha1")
+	if err != nil {
+		return map[string]any{"installed": false, "message": fmt.Sprintf("Argo CD does not seem to be installed: %v", err)}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var resourceClient dynamic.ResourceInterface = dyn.Resource(gvr)
+	if namespace != "" {
+		resourceClient = dyn.Resource(gvr).Namespace(namespace)
+	}
+	list, err := resourceClient.List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		return map[string]any{"installed": false, "message": "Argo CD is not installed (no Application CRD)"}
+	}
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to list Applications: %v", err)}
+	}
+
+	apps := []ArgoApplication{}
+	var problems []string
+	for _, item := range list.Items {
+		var app argoApplication
+		if err := decodeUnstructured(&item, &app); err != nil {
+			return map[string]any{"error": fmt.Sprintf("failed to decode Application %s: %v", item.GetName(), err)}
+		}
+		s := app.summarize()
+		if destination != "" && app.Spec.Destination.Namespace != destination {
+			continue
+		}
+		apps = append(apps, s)
+		if s.Sync != "Synced" || (s.Health != "Healthy" && s.Health != "Suspended") || s.Operation != "" {
+			problems = append(problems, fmt.Sprintf("%s/%s: %s, %s", s.Namespace, s.Name, s.Sync, s.Health))
+		}
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		if apps[i].Namespace != apps[j].Namespace {
+			return apps[i].Namespace < apps[j].Namespace
+		}
+		return apps[i].Name < apps[j].Name
+	})
+
+	result := map[string]any{
+		"installed":    true,
+		"applications": apps,
+		"count":        len(apps),
+	}
+	if len(problems) > 0 {
+		result["not_synced_or_healthy"] = problems
+	}
+	return result
+}
@@ -26,9 +26,10 @@ func (t *AskClarificationTool) Description() string {
 	return "Ask the user clarifying questions before proposing a plan. Use this when a mutating request is ambiguous (e.g., namespace, replicas, image version, service type). Keep it focused: 1-3 questions for genuinely ambiguous choices."
 }
 
-// IsLongRunning returns false as this is a quick operation.
+// IsLongRunning returns true: the user's answers arrive later, as a new
+// response to the same call.
 func (t *AskClarificationTool) IsLongRunning() bool {
-	return false
+	return true
 }
 
 // Category returns the tool category.
@@ -81,7 +82,9 @@ func (t *AskClarificationTool) Declaration() *genai.FunctionDeclaration {
 }
 
 // Run executes the tool. This tool does NOT block - it captures the questions
-// for display and returns a status indicating answers are needed.
+// for display and returns a status indicating answers are needed. The REPL
+// sends the answers as the final response to the call, with status
+// "answered" and one entry per question.
 func (t *AskClarificationTool) Run(ctx tool.Context, args any) (map[string]any, error) {
 	argsMap, ok := args.(map[string]any)
 	if !ok {
@@ -117,7 +120,7 @@ func (t *AskClarificationTool) Run(ctx tool.Context, args any) (map[string]any,
 
 	return map[string]any{
 		"status":    "awaiting_answers",
-		"message":   "Questions displayed to user. Their response will follow.",
+		"message":   "Questions displayed to user. Their answers will follow as the response to this call; wait for them before proposing a plan.",
 		"context":   contextStr,
 		"questions": questions,
 	}, nil
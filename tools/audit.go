@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"google.golang.org/adk/tool"
+)
+
+// maxAuditArgBytes is the length string arguments (e.g. YAML) are cut to
+// in the audit log.
+const maxAuditArgBytes = 512
+
+// redactedArgs are arguments whose values are never written to the audit
+// log, by tool; "*" applies to every tool.
+var redactedArgs = map[string][]string{
+	"*":                 {"password", "string_data"},
+	"update_secret_key": {"value"},
+}
+
+// secretManifest matches YAML for a Secret, whose data is redacted too.
+var secretManifest = regexp.MustCompile(`(?m)^kind:\s*Secret\s*$`)
+
+// AuditLogPath returns the location of the audit log of mutating tool
+// calls (~/.kasa/audit.jsonl).
+func AuditLogPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kasa", "audit.jsonl")
+}
+
+// SetAuditLog appends a record of every mutating tool call to path, as
+// JSON lines. An empty path disables the log.
+func (k *KubeTools) SetAuditLog(path string) {
+	k.auditLog = path
+}
+
+// auditRecord is one line of the audit log.
+type auditRecord struct {
+	Time       time.Time      `json:"time"`
+	Tool       string         `json:"tool"`
+	Namespace  string         `json:"namespace,omitempty"`
+	Args       map[string]any `json:"args"`
+	Outcome    string         `json:"outcome"` // applied, dry_run, refused, confirmation_required or failed
+	Error      string         `json:"error,omitempty"`
+	DurationMS int64          `json:"duration_ms"`
+}
+
+// auditMutations returns middleware that records every call to a mutating
+// tool in the audit log at path, including calls refused by a policy.
+func auditMutations(path string) Middleware {
+	var mu sync.Mutex
+	return func(t ToolInfo, next RunFunc) RunFunc {
+		if t.Category != CategoryMutating {
+			return next
+		}
+		return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			begin := time.Now()
+			record := func(outcome, errMsg string) error {
+				mu.Lock()
+				defer mu.Unlock()
+				return appendAuditRecord(path, auditRecord{
+					Time:       begin.UTC(),
+					Tool:       t.Name,
+					Namespace:  targetNamespace(t.Name, args),
+					Args:       auditArgs(t.Name, args),
+					Outcome:    outcome,
+					Error:      errMsg,
+					DurationMS: time.Since(begin).Milliseconds(),
+				})
+			}
+			// A crashed call may have changed the cluster, so record it
+			// before recoverPanics turns it into an error result
+			defer func() {
+				if r := recover(); r != nil {
+					_ = record("failed", fmt.Sprintf("panic: %v", r))
+					panic(r)
+				}
+			}()
+
+			result, err := next(ctx, args)
+			if writeErr := record(auditOutcome(result, err)); writeErr != nil && result != nil {
+				result["audit_log_error"] = writeErr.Error()
+			}
+			return result, err
+		}
+	}
+}
+
+// auditOutcome classifies the result of a mutating call.
+func auditOutcome(result map[string]any, err error) (outcome, errMsg string) {
+	switch {
+	case err != nil:
+		return "failed", err.Error()
+	case result["window_closed"] == true, result["namespace_denied"] == true, result["protected"] == true:
+		return "refused", fmt.Sprint(result["error"])
+	case result["error"] != nil:
+		return "failed", fmt.Sprint(result["error"])
+	case result["dry_run"] == true:
+		return "dry_run", ""
+	case result["confirmation_required"] == true:
+		return "confirmation_required", ""
+	}
+	return "applied", ""
+}
+
+// auditArgs returns a copy of args safe to log: secret values are redacted
+// and long strings cut.
+func auditArgs(toolName string, args map[string]any) map[string]any {
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		if s, ok := v.(string); ok && len(s) > maxAuditArgBytes {
+			v = s[:maxAuditArgBytes] + fmt.Sprintf("... [%d more bytes]", len(s)-maxAuditArgBytes)
+		}
+		out[k] = v
+	}
+	for _, scope := range []string{"*", toolName} {
+		for _, k := range redactedArgs[scope] {
+			if _, ok := out[k]; ok {
+				out[k] = "[redacted]"
+			}
+		}
+	}
+	if s, ok := args["yaml"].(string); ok && secretManifest.MatchString(s) {
+		out["yaml"] = "[redacted Secret manifest]"
+	}
+	return out
+}
+
+func appendAuditRecord(path string, rec auditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating audit log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,489 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"time"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// canaryTrackLabel distinguishes canary pods from the primary's pods.
+	canaryTrackLabel = "kasa.dev/track"
+	// canaryOfAnnotation records the deployment a canary was created for.
+	canaryOfAnnotation = "kasa.dev/canary-of"
+)
+
+// CanaryDeployTool provides the canary_deploy tool for the agent.
+type CanaryDeployTool struct {
+	clientset *kubernetes.Clientset
+	manifest  *manifest.Manager
+}
+
+// NewCanaryDeployTool creates a new CanaryDeployTool.
+func NewCanaryDeployTool(clientset *kubernetes.Clientset, manifest *manifest.Manager) *CanaryDeployTool {
+	return &CanaryDeployTool{
+		clientset: clientset,
+		manifest:  manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *CanaryDeployTool) Name() string {
+	return "canary_deploy"
+}
+
+// Description returns the tool description.
+func (t *CanaryDeployTool) Description() string {
+	return "Roll out a new image to a managed deployment via a canary. Creates a temporary <name>-canary deployment with the new image at a percentage of the primary's replicas, receiving traffic through the same service selector, and monitors it for a window. If the canary stays healthy the primary is updated (as with set_image) and the canary is removed; otherwise the canary is removed and the primary is left untouched. The canary is never stored in git."
+}
+
+// IsLongRunning returns true as this tool monitors the canary.
+func (t *CanaryDeployTool) IsLongRunning() bool {
+	return true
+}
+
+// Category returns the tool category.
+func (t *CanaryDeployTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *CanaryDeployTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *CanaryDeployTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"name": {
+					Type:        "string",
+					Description: "The name of the primary deployment",
+				},
+				"image": {
+					Type:        "string",
+					Description: "The new container image with tag (e.g., nginx:1.27)",
+				},
+				"container": {
+					Type:        "string",
+					Description: "Container to update. Required if the pod has more than one container",
+				},
+				"app": {
+					Type:        "string",
+					Description: "Application name of the stored manifest (default: name)",
+				},
+				"percent": {
+					Type:        "integer",
+					Description: "Canary size as a percentage of the primary's replicas, rounded up to at least one pod (default: 10)",
+				},
+				"window": {
+					Type:        "integer",
+					Description: "Seconds to monitor the canary once it is available (default: 120, max: 900)",
+				},
+				"promote": {
+					Type:        "boolean",
+					Description: "Promote the image to the primary when the canary is healthy (default: true). Set to false to only test the image",
+				},
+				"change_cause": changeCauseSchema,
+			},
+			Required: []string{"namespace", "name", "image"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *CanaryDeployTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+
+	image, ok := argsMap["image"].(string)
+	if !ok || image == "" {
+		return map[string]any{"error": "image is required"}, nil
+	}
+
+	container, _ := argsMap["container"].(string)
+
+	app := name
+	if a, ok := argsMap["app"].(string); ok && a != "" {
+		app = a
+	}
+
+	percent := 10
+	if p, ok := argsMap["percent"].(float64); ok {
+		percent = int(p)
+	}
+	if percent < 1 || percent > 100 {
+		return map[string]any{"error": "percent must be between 1 and 100"}, nil
+	}
+
+	window := 120
+	if w, ok := argsMap["window"].(float64); ok {
+		window = int(w)
+	}
+	window = min(max(window, 10), 900)
+
+	promote := true
+	if p, ok := argsMap["promote"].(bool); ok {
+		promote = p
+	}
+
+	// Promotion goes through the stored manifest, so require it up front
+	content, err := t.manifest.ReadManifest(namespace, app, "deployment")
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("%v (use import_resource to start managing it)", err)}, nil
+	}
+	if container == "" {
+		var stored map[string]any
+		if err := yaml.Unmarshal(content, &stored); err == nil {
+			if container, err = setManifestImage(stored, "", image); err != nil {
+				return map[string]any{"error": err.Error()}, nil
+			}
+		}
+	}
+
+	getCtx, cancel := apiContext(toolContext(ctx))
+	primary, err := t.clientset.AppsV1().Deployments(namespace).Get(getCtx, name, metav1.GetOptions{})
+	cancel()
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to get deployment %s/%s: %v", namespace, name, err)}, nil
+	}
+
+	primaryReplicas := int32(1)
+	if primary.Spec.Replicas != nil {
+		primaryReplicas = *primary.Spec.Replicas
+	}
+	replicas := canaryReplicas(primaryReplicas, percent)
+
+	canary, previous, err := buildCanaryDeployment(primary, container, image, replicas)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	result := map[string]any{
+		"namespace":        namespace,
+		"name":             name,
+		"canary":           canary.Name,
+		"container":        container,
+		"image":            image,
+		"previous_image":   previous,
+		"canary_replicas":  replicas,
+		"primary_replicas": primaryReplicas,
+	}
+
+	if previous == image {
+		return map[string]any{"error": fmt.Sprintf("deployment %s/%s already runs %s", namespace, name, image)}, nil
+	}
+
+	if t.manifest.IsDryRun() {
+		result["success"] = true
+		result["dry_run"] = true
+		next := "remove it"
+		if promote {
+			next = "promote the image to " + name
+		}
+		result["message"] = fmt.Sprintf("Dry run: would create %s with %d replica(s) of %s, monitor it for %ds, then %s", canary.Name, replicas, image, window, next)
+		return result, nil
+	}
+
+	if err := t.createCanary(toolContext(ctx), namespace, canary); err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	healthy, state, reason, warnings := t.monitorCanary(toolContext(ctx), namespace, canary.Name, container, image, time.Duration(window)*time.Second)
+	result["canary_state"] = state
+	if len(warnings) > 0 {
+		result["canary_warnings"] = warnings
+	}
+
+	if !healthy {
+		result["success"] = false
+		result["failure_reason"] = reason
+		result["promoted"] = false
+		if err := t.deleteCanary(context.WithoutCancel(toolContext(ctx)), namespace, canary.Name); err != nil {
+			result["cleanup_error"] = err.Error()
+		}
+		result["message"] = fmt.Sprintf("Canary %s failed (%s) and was removed; %s still runs %s", canary.Name, reason, name, previous)
+		return result, nil
+	}
+
+	if !promote {
+		result["success"] = true
+		result["promoted"] = false
+		if err := t.deleteCanary(context.WithoutCancel(toolContext(ctx)), namespace, canary.Name); err != nil {
+			result["cleanup_error"] = err.Error()
+		}
+		result["message"] = fmt.Sprintf("Canary %s was healthy for %ds and was removed; %s was not changed", canary.Name, window, name)
+		return result, nil
+	}
+
+	// Promote through set_image so the manifest and rollback handling match
+	setImage := &SetImageTool{clientset: t.clientset, manifest: t.manifest}
+	promotion := setImage.setImage(toolContext(ctx), imageUpdate{
+		namespace: namespace,
+		name:      name,
+		kind:      "deployment",
+		container: container,
+		app:       app,
+		image:     image,
+		timeout:   time.Duration(max(180, window)) * time.Second,
+		cause:     changeCause(argsMap, fmt.Sprintf("canary_deploy promoted %s to %s", name, image)),
+	})
+	result["promotion"] = promotion
+
+	if err := t.deleteCanary(context.WithoutCancel(toolContext(ctx)), namespace, canary.Name); err != nil {
+		result["cleanup_error"] = err.Error()
+	}
+
+	if ok, _ := promotion["success"].(bool); !ok {
+		result["success"] = false
+		result["promoted"] = false
+		result["message"] = fmt.Sprintf("Canary %s was healthy but promoting %s failed; the canary was removed", canary.Name, name)
+		return result, nil
+	}
+
+	result["success"] = true
+	result["promoted"] = true
+	result["message"] = fmt.Sprintf("Canary %s was healthy for %ds; promoted %s to %s and removed the canary", canary.Name, window, name, image)
+	if path, ok := promotion["manifest_path"]; ok {
+		result["manifest_path"] = path
+	}
+	return result, nil
+}
+
+// createCanary creates the canary deployment. An existing canary is left
+// alone, since it may belong to a rollout in progress.
+func (t *CanaryDeployTool) createCanary(ctx context.Context, namespace string, canary *appsv1.Deployment) error {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	_, err := t.clientset.AppsV1().Deployments(namespace).Create(ctx, canary, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		return fmt.Errorf("canary deployment %s/%s already exists; remove it with delete_resource if it is left over from an earlier canary", namespace, canary.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create canary deployment: %w", err)
+	}
+	return nil
+}
+
+// deleteCanary removes the canary deployment and its pods.
+func (t *CanaryDeployTool) deleteCanary(ctx context.Context, namespace, name string) error {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	propagation := metav1.DeletePropagationForeground
+	err := t.clientset.AppsV1().Deployments(namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete canary deployment %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// monitorCanary waits for the canary to become available and then watches
+// it for the given window. Returns (healthy, state, reason, warnings).
+func (t *CanaryDeployTool) monitorCanary(ctx context.Context, namespace, name, container, image string, window time.Duration) (bool, string, string, []string) {
+	setImage := &SetImageTool{clientset: t.clientset, manifest: t.manifest}
+	done, state, reason := setImage.waitForRollout(ctx, namespace, name, "deployment", container, image, max(window, 180*time.Second))
+	if !done {
+		return false, state, reason, nil
+	}
+
+	start := time.Now()
+	deadline := start.Add(window)
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	var warnings []string
+	for {
+		reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		healthy, st, why, w := t.checkCanary(reqCtx, namespace, name, start)
+		cancel()
+		state = st
+		if len(w) > 0 {
+			warnings = w
+		}
+		if !healthy {
+			return false, state, why, warnings
+		}
+		if time.Now().After(deadline) {
+			return true, state, "", warnings
+		}
+		select {
+		case <-ctx.Done():
+			return false, state, "cancelled", warnings
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkCanary inspects the canary deployment, its pods and their warning
+// events since the monitoring window started.
+func (t *CanaryDeployTool) checkCanary(ctx context.Context, namespace, name string, since time.Time) (bool, string, string, []string) {
+	dep, err := t.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return false, "", fmt.Sprintf("failed to get canary: %v", err), nil
+	}
+	replicas := int32(1)
+	if dep.Spec.Replicas != nil {
+		replicas = *dep.Spec.Replicas
+	}
+	state := fmt.Sprintf("%d/%d canary replicas available", dep.Status.AvailableReplicas, replicas)
+	if dep.Status.AvailableReplicas < replicas {
+		return false, state, fmt.Sprintf("canary became unavailable (%s)", state), nil
+	}
+
+	sel, err := metav1.LabelSelectorAsSelector(dep.Spec.Selector)
+	if err != nil {
+		return false, state, fmt.Sprintf("invalid canary selector: %v", err), nil
+	}
+	pods, err := t.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: sel.String()})
+	if err != nil {
+		// Transient API errors should not fail the canary
+		return true, state, "", nil
+	}
+	if unhealthy, reason := canaryPodFailure(pods.Items); unhealthy {
+		return false, state, reason, nil
+	}
+
+	podNames := make(map[string]bool, len(pods.Items))
+	for _, pod := range pods.Items {
+		podNames[pod.Name] = true
+	}
+	events, err := t.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{FieldSelector: "type=Warning"})
+	if err != nil {
+		return true, state, "", nil
+	}
+	var warnings []string
+	for _, e := range events.Items {
+		if e.InvolvedObject.Kind != "Pod" || !podNames[e.InvolvedObject.Name] {
+			continue
+		}
+		if eventTime(e).Before(since) {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("%s: %s: %s", e.InvolvedObject.Name, e.Reason, e.Message))
+	}
+	return true, state, "", warnings
+}
+
+// canaryPodFailure reports whether any canary pod has failed or restarted.
+// Canary pods are expected to run cleanly for the whole window, so a single
+// container restart counts as a failure.
+func canaryPodFailure(pods []corev1.Pod) (bool, string) {
+	for _, pod := range pods {
+		if failed, reason := isTerminalPodFailure(&pod); failed {
+			return true, fmt.Sprintf("pod %s: %s", pod.Name, reason)
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.RestartCount > 0 {
+				return true, fmt.Sprintf("pod %s: container %s restarted %d time(s)", pod.Name, cs.Name, cs.RestartCount)
+			}
+		}
+	}
+	return false, ""
+}
+
+// eventTime returns the most recent time an event was observed.
+func eventTime(e corev1.Event) time.Time {
+	if !e.LastTimestamp.IsZero() {
+		return e.LastTimestamp.Time
+	}
+	if !e.EventTime.IsZero() {
+		return e.EventTime.Time
+	}
+	return e.CreationTimestamp.Time
+}
+
+// canaryReplicas returns percent of the primary's replicas, rounded up and
+// never less than one.
+func canaryReplicas(primary int32, percent int) int32 {
+	n := int32(math.Ceil(float64(primary) * float64(percent) / 100))
+	return max(n, 1)
+}
+
+// buildCanaryDeployment derives the canary deployment from the primary. The
+// canary pods keep the primary's labels, so services route traffic to them,
+// and add a track label that keeps the two deployments' selectors apart.
+// Returns the canary and the image the primary currently runs.
+func buildCanaryDeployment(primary *appsv1.Deployment, container, image string, replicas int32) (*appsv1.Deployment, string, error) {
+	if primary.Spec.Selector == nil {
+		return nil, "", fmt.Errorf("deployment %s has no selector", primary.Name)
+	}
+
+	spec := *primary.Spec.DeepCopy()
+	spec.Replicas = &replicas
+	spec.Paused = false
+
+	previous, err := setContainerImage(&spec.Template.Spec, container, image)
+	if err != nil {
+		return nil, "", err
+	}
+
+	spec.Selector.MatchLabels = maps.Clone(spec.Selector.MatchLabels)
+	if spec.Selector.MatchLabels == nil {
+		spec.Selector.MatchLabels = map[string]string{}
+	}
+	spec.Selector.MatchLabels[canaryTrackLabel] = "canary"
+
+	spec.Template.Labels = maps.Clone(spec.Template.Labels)
+	if spec.Template.Labels == nil {
+		spec.Template.Labels = map[string]string{}
+	}
+	spec.Template.Labels[canaryTrackLabel] = "canary"
+
+	labels := maps.Clone(primary.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[canaryTrackLabel] = "canary"
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        primary.Name + "-canary",
+			Namespace:   primary.Namespace,
+			Labels:      labels,
+			Annotations: map[string]string{canaryOfAnnotation: primary.Name},
+		},
+		Spec: spec,
+	}, previous, nil
+}
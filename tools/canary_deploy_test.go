@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestCanaryReplicas(t *testing.T) {
+	tests := []struct {
+		primary int32
+		percent int
+		want    int32
+	}{
+		{10, 10, 1},
+		{10, 25, 3},
+		{4, 50, 2},
+		{1, 10, 1},
+		{0, 10, 1},
+		{3, 100, 3},
+	}
+	for _, tt := range tests {
+		if got := canaryReplicas(tt.primary, tt.percent); got != tt.want {
+			t.Errorf("canaryReplicas(%d, %d) = %d, want %d", tt.primary, tt.percent, got, tt.want)
+		}
+	}
+}
+
+func TestBuildCanaryDeployment(t *testing.T) {
+	replicas := int32(4)
+	primary := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "web"},
+			Annotations: map[string]string{"deployment.kubernetes.io/revision": "3"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{Name: "web", Image: "nginx:1.25"},
+				}},
+			},
+		},
+	}
+
+	canary, previous, err := buildCanaryDeployment(primary, "web", "nginx:1.27", 1)
+	if err != nil {
+		t.Fatalf("buildCanaryDeployment: %v", err)
+	}
+	if previous != "nginx:1.25" {
+		t.Errorf("previous = %q, want nginx:1.25", previous)
+	}
+	if canary.Name != "web-canary" || canary.Namespace != "default" {
+		t.Errorf("canary = %s/%s, want default/web-canary", canary.Namespace, canary.Name)
+	}
+	if *canary.Spec.Replicas != 1 {
+		t.Errorf("replicas = %d, want 1", *canary.Spec.Replicas)
+	}
+	if canary.Spec.Template.Spec.Containers[0].Image != "nginx:1.27" {
+		t.Errorf("canary image = %s", canary.Spec.Template.Spec.Containers[0].Image)
+	}
+	if canary.Annotations[canaryOfAnnotation] != "web" {
+		t.Errorf("missing %s annotation", canaryOfAnnotation)
+	}
+	if _, ok := canary.Annotations["deployment.kubernetes.io/revision"]; ok {
+		t.Error("primary annotations should not be copied")
+	}
+
+	// The primary must be left untouched
+	if primary.Spec.Template.Spec.Containers[0].Image != "nginx:1.25" || len(primary.Spec.Selector.MatchLabels) != 1 || len(primary.Spec.Template.Labels) != 1 {
+		t.Error("primary deployment was modified")
+	}
+
+	// Canary pods match the service selector (the primary's pod labels) but
+	// not the other way round
+	podLabels := labels.Set(canary.Spec.Template.Labels)
+	if !labels.SelectorFromSet(primary.Spec.Template.Labels).Matches(podLabels) {
+		t.Error("canary pods should match the primary's pod labels")
+	}
+	canarySel, _ := metav1.LabelSelectorAsSelector(canary.Spec.Selector)
+	if canarySel.Matches(labels.Set(primary.Spec.Template.Labels)) {
+		t.Error("canary selector should not match primary pods")
+	}
+
+	if _, _, err := buildCanaryDeployment(primary, "sidecar", "x:1", 1); err == nil {
+		t.Error("expected error for unknown container")
+	}
+}
+
+func TestCanaryPodFailure(t *testing.T) {
+	healthy := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "ok"},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "web", Ready: true},
+		}},
+	}
+	restarted := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "flaky"},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "web", Ready: true, RestartCount: 1},
+		}},
+	}
+	pullFailure := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "bad"},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "web", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}},
+		}},
+	}
+
+	if failed, reason := canaryPodFailure([]corev1.Pod{healthy}); failed {
+		t.Errorf("healthy pod reported as failed: %s", reason)
+	}
+	if failed, _ := canaryPodFailure([]corev1.Pod{healthy, restarted}); !failed {
+		t.Error("restarted pod should fail the canary")
+	}
+	if failed, _ := canaryPodFailure([]corev1.Pod{pullFailure}); !failed {
+		t.Error("image pull failure should fail the canary")
+	}
+}
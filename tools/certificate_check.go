@@ -0,0 +1,422 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// The parts of the cert-manager objects check_certificate inspects.
+type (
+	certManagerConditions struct {
+		Conditions []metav1.Condition `json:"conditions"`
+	}
+
+	certificate struct {
+		Spec struct {
+			SecretName string   `json:"secretName"`
+			DNSNames   []string `json:"dnsNames"`
+			IssuerRef  struct {
+				Name  string `json:"name"`
+				Kind  string `json:"kind"`
+				Group string `json:"group"`
+			} `json:"issuerRef"`
+		} `json:"spec"`
+		Status struct {
+			certManagerConditions
+			NotAfter               string `json:"notAfter"`
+			RenewalTime            string `json:"renewalTime"`
+			FailedIssuanceAttempts int    `json:"failedIssuanceAttempts"`
+			LastFailureTime        string `json:"lastFailureTime"`
+		} `json:"status"`
+	}
+
+	certIssuer struct {
+		Spec   map[string]any        `json:"spec"`
+		Status certManagerConditions `json:"status"`
+	}
+
+	acmeOrder struct {
+		Status struct {
+			State  string `json:"state"`
+			Reason string `json:"reason"`
+		} `json:"status"`
+	}
+
+	acmeChallenge struct {
+		Spec struct {
+			Type    string `json:"type"`
+			DNSName string `json:"dnsName"`
+			Token   string `json:"token"`
+		} `json:"spec"`
+		Status struct {
+			State      string `json:"state"`
+			Reason     string `json:"reason"`
+			Presented  bool   `json:"presented"`
+			Processing bool   `json:"processing"`
+		} `json:"status"`
+	}
+)
+
+// IssuanceStep is one object in the Certificate → CertificateRequest → Order
+// → Challenge chain.
+type IssuanceStep struct {
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	State   string `json:"state"`
+	Message string `json:"message,omitempty"`
+}
+
+// CheckCertificateTool provides the check_certificate tool for the agent.
+type CheckCertificateTool struct {
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+}
+
+// NewCheckCertificateTool creates a new CheckCertificateTool.
+func NewCheckCertificateTool(dynamicClient dynamic.Interface, resolver *GVRResolver) *CheckCertificateTool {
+	return &CheckCertificateTool{
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+	}
+}
+
+// Name returns the tool name.
+func (t *CheckCertificateTool) Name() string {
+	return "check_certificate"
+}
+
+// Description returns the tool description.
+func (t *CheckCertificateTool) Description() string {
+	return "Troubleshoot a cert-manager Certificate: follows Certificate → CertificateRequest → Order → Challenge, checks the Issuer, and reports where issuance is stuck (issuer not ready, request denied, failing HTTP01/DNS01 challenges) with hints. Use this instead of listing the four resource types by hand."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *CheckCertificateTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *CheckCertificateTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *CheckCertificateTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *CheckCertificateTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"name": {
+					Type:        "string",
+					Description: "Name of the Certificate",
+				},
+			},
+			Required: []string{"namespace", "name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *CheckCertificateTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+
+	return checkCertificate(toolContext(ctx), t.dynamicClient, t.resolver, namespace, name, time.Now()), nil
+}
+
+// checkCertificate walks a Certificate's issuance chain and reports where
+// it is stuck.
+func checkCertificate(ctx context.Context, dynamicClient dynamic.Interface, resolver *GVRResolver, namespace, name string, now time.Time) map[string]any {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	getter := dynamicGetter{dynamicClient: dynamicClient, resolver: resolver}
+	gvr, err := resolver.Resolve("certificate", "")
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("cert-manager does not seem to be installed: %v", err)}
+	}
+	obj, err := dynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to get Certificate: %v", err)}
+	}
+	var cert certificate
+	if err := decodeUnstructured(obj, &cert); err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to decode Certificate: %v", err)}
+	}
+
+	var issues, hints []string
+	ready := meta.IsStatusConditionTrue(cert.Status.Conditions, "Ready")
+	chain := []IssuanceStep{conditionStep("Certificate", name, meta.FindStatusCondition(cert.Status.Conditions, "Ready"))}
+
+	result := map[string]any{
+		"namespace": namespace,
+		"name":      name,
+		"secret":    cert.Spec.SecretName,
+		"dns_names": cert.Spec.DNSNames,
+		"ready":     ready,
+	}
+
+	issuerStep, issuerIssue := checkIssuer(ctx, getter, namespace, cert)
+	chain = append(chain, issuerStep)
+	if issuerIssue != "" {
+		issues = append(issues, issuerIssue)
+		result["stuck_at"] = issuerStep.Kind
+	}
+
+	if !ready || meta.IsStatusConditionTrue(cert.Status.Conditions, "Issuing") {
+		steps, stuckAt, stepIssues, stepHints := issuanceChain(ctx, getter, namespace, obj)
+		chain = append(chain, steps...)
+		issues = append(issues, stepIssues...)
+		hints = append(hints, stepHints...)
+		if _, ok := result["stuck_at"]; !ok && stuckAt != "" {
+			result["stuck_at"] = stuckAt
+		}
+	}
+
+	// Report the history after the cause, which is more useful as the summary
+	if cert.Status.NotAfter != "" {
+		result["not_after"] = cert.Status.NotAfter
+		if notAfter, err := time.Parse(time.RFC3339, cert.Status.NotAfter); err == nil && notAfter.Before(now) {
+			issues = append(issues, fmt.Sprintf("the certificate in Secret %s expired at %s", cert.Spec.SecretName, cert.Status.NotAfter))
+		}
+	}
+	if cert.Status.RenewalTime != "" {
+		result["renewal_time"] = cert.Status.RenewalTime
+	}
+	if cert.Status.FailedIssuanceAttempts > 0 {
+		result["failed_issuance_attempts"] = cert.Status.FailedIssuanceAttempts
+		issues = append(issues, fmt.Sprintf("issuance has failed %d time(s), last at %s; cert-manager backs off exponentially between attempts",
+			cert.Status.FailedIssuanceAttempts, cert.Status.LastFailureTime))
+	}
+	if !ready && len(issues) == 0 {
+		issues = append(issues, fmt.Sprintf("Certificate is not ready: %s", chain[0].Message))
+	}
+
+	result["chain"] = chain
+	if len(hints) > 0 {
+		result["hints"] = hints
+	}
+	okSummary := fmt.Sprintf("Certificate %s is ready", name)
+	if cert.Status.RenewalTime != "" {
+		okSummary += ", renews at " + cert.Status.RenewalTime
+	}
+	return finishIngressCheck(result, issues, okSummary)
+}
+
+// checkIssuer checks that the Certificate's Issuer or ClusterIssuer exists
+// and is ready.
+func checkIssuer(ctx context.Context, getter dynamicGetter, namespace string, cert certificate) (IssuanceStep, string) {
+	ref := cert.Spec.IssuerRef
+	kind := defaultString(ref.Kind, "Issuer")
+	step := IssuanceStep{Kind: kind, Name: ref.Name}
+	if ref.Group != "" && ref.Group != "cert-manager.io" {
+		step.State = "external"
+		step.Message = "issued by an external issuer (" + ref.Group + "); check its controller"
+		return step, ""
+	}
+
+	issuerNamespace := namespace
+	if kind == "ClusterIssuer" {
+		issuerNamespace = ""
+	}
+	var issuer certIssuer
+	if err := getter.get(ctx, strings.ToLower(kind), issuerNamespace, ref.Name, &issuer); err != nil {
+		step.State = "missing"
+		if apierrors.IsNotFound(err) {
+			step.Message = fmt.Sprintf("%s %s does not exist", kind, ref.Name)
+			if kind == "Issuer" {
+				step.Message += " in " + namespace + " (did you mean a ClusterIssuer?)"
+			}
+		} else {
+			step.Message = fmt.Sprintf("failed to get %s: %v", kind, err)
+		}
+		return step, step.Message
+	}
+
+	for _, t := range []string{"acme", "ca", "selfSigned", "vault", "venafi"} {
+		if _, ok := issuer.Spec[t]; ok {
+			step.Message = t + " issuer"
+		}
+	}
+	ready := meta.FindStatusCondition(issuer.Status.Conditions, "Ready")
+	if ready == nil || ready.Status != metav1.ConditionTrue {
+		step.State = "not ready"
+		reason := "it has no Ready condition yet"
+		if ready != nil {
+			reason = fmt.Sprintf("%s: %s", ready.Reason, ready.Message)
+		}
+		return step, fmt.Sprintf("%s %s is not ready (%s)", kind, ref.Name, reason)
+	}
+	step.State = "ready"
+	return step, ""
+}
+
+// issuanceChain follows the Certificate's newest CertificateRequest and its
+// ACME Order and Challenges, returning the steps, the kind of the object
+// issuance is stuck at, and any issues and hints.
+func issuanceChain(ctx context.Context, getter dynamicGetter, namespace string, cert *unstructured.Unstructured) ([]IssuanceStep, string, []string, []string) {
+	requests, err := getter.listOwnedBy(ctx, "certificaterequest", namespace, cert.GetUID())
+	if err != nil {
+		return nil, "", []string{fmt.Sprintf("failed to list CertificateRequests: %v", err)}, nil
+	}
+	if len(requests) == 0 {
+		return nil, "Certificate", []string{"no CertificateRequest exists yet; check the cert-manager controller logs"}, nil
+	}
+	// The newest revision is the one cert-manager is working on
+	sort.Slice(requests, func(i, j int) bool {
+		return certificateRevision(requests[i]) > certificateRevision(requests[j])
+	})
+	request := requests[0]
+	var crStatus struct {
+		Status certManagerConditions `json:"status"`
+	}
+	if err := decodeUnstructured(&request, &crStatus); err != nil {
+		return nil, "", []string{fmt.Sprintf("failed to decode CertificateRequest: %v", err)}, nil
+	}
+
+	conditions := crStatus.Status.Conditions
+	step := conditionStep("CertificateRequest", request.GetName(), meta.FindStatusCondition(conditions, "Ready"))
+	steps := []IssuanceStep{step}
+	switch {
+	case meta.IsStatusConditionTrue(conditions, "Denied"):
+		c := meta.FindStatusCondition(conditions, "Denied")
+		steps[0].State = "denied"
+		return steps, "CertificateRequest", []string{fmt.Sprintf("CertificateRequest %s was denied: %s", request.GetName(), c.Message)}, nil
+	case meta.IsStatusConditionTrue(conditions, "InvalidRequest"):
+		c := meta.FindStatusCondition(conditions, "InvalidRequest")
+		return steps, "CertificateRequest", []string{fmt.Sprintf("CertificateRequest %s is invalid: %s", request.GetName(), c.Message)}, nil
+	case meta.FindStatusCondition(conditions, "Approved") == nil:
+		steps[0].State = "awaiting approval"
+		return steps, "CertificateRequest", []string{fmt.Sprintf("CertificateRequest %s has not been approved", request.GetName())},
+			[]string{"If cert-manager runs with --controllers=-certificaterequests-approver, an approver such as approver-policy must approve requests"}
+	case step.State == "Failed":
+		return steps, "CertificateRequest", []string{fmt.Sprintf("CertificateRequest %s failed: %s", request.GetName(), step.Message)}, nil
+	}
+
+	orders, err := getter.listOwnedBy(ctx, "order", namespace, request.GetUID())
+	if err != nil || len(orders) == 0 {
+		// Non-ACME issuers sign the request directly
+		if step.State != "Issued" {
+			return steps, "CertificateRequest", []string{fmt.Sprintf("CertificateRequest %s is not issued yet: %s", request.GetName(), step.Message)}, nil
+		}
+		return steps, "", nil, nil
+	}
+	order := orders[0]
+	var o acmeOrder
+	if err := decodeUnstructured(&order, &o); err != nil {
+		return steps, "", []string{fmt.Sprintf("failed to decode Order: %v", err)}, nil
+	}
+	steps = append(steps, IssuanceStep{Kind: "Order", Name: order.GetName(), State: defaultString(o.Status.State, "pending"), Message: o.Status.Reason})
+	var issues, hints []string
+	stuckAt := ""
+	switch o.Status.State {
+	case "valid":
+		return steps, "", nil, nil
+	case "invalid", "errored":
+		stuckAt = "Order"
+		issues = append(issues, fmt.Sprintf("ACME Order %s is %s: %s", order.GetName(), o.Status.State, o.Status.Reason))
+		hints = append(hints, "A failed Order is not retried until the Certificate's backoff expires; fix the cause, then delete the CertificateRequest to retry immediately")
+	}
+
+	challenges, err := getter.listOwnedBy(ctx, "challenge", namespace, order.GetUID())
+	if err != nil {
+		return steps, stuckAt, append(issues, fmt.Sprintf("failed to list Challenges: %v", err)), hints
+	}
+	for i := range challenges {
+		var c acmeChallenge
+		if err := decodeUnstructured(&challenges[i], &c); err != nil {
+			continue
+		}
+		state := defaultString(c.Status.State, "pending")
+		steps = append(steps, IssuanceStep{
+			Kind:    "Challenge",
+			Name:    challenges[i].GetName(),
+			State:   fmt.Sprintf("%s %s for %s", c.Spec.Type, state, c.Spec.DNSName),
+			Message: c.Status.Reason,
+		})
+		if state == "valid" {
+			continue
+		}
+		stuckAt = "Challenge"
+		issues = append(issues, fmt.Sprintf("%s challenge for %s is %s: %s", c.Spec.Type, c.Spec.DNSName, state, defaultString(c.Status.Reason, "no reason reported")))
+		hints = append(hints, challengeHint(c))
+	}
+	if stuckAt == "" && o.Status.State != "valid" {
+		stuckAt = "Order"
+		issues = append(issues, fmt.Sprintf("ACME Order %s is %s", order.GetName(), defaultString(o.Status.State, "pending")))
+	}
+	return steps, stuckAt, issues, hints
+}
+
+// challengeHint explains how to debug a failing ACME challenge.
+func challengeHint(c acmeChallenge) string {
+	switch {
+	case c.Spec.Type == "HTTP-01" && !c.Status.Presented:
+		return fmt.Sprintf("cert-manager hasn't presented the HTTP-01 solver for %s; check that the solver's ingress class or gateway matches a running controller", c.Spec.DNSName)
+	case c.Spec.Type == "HTTP-01":
+		return fmt.Sprintf("The self check GETs http://%s/.well-known/acme-challenge/%s; %s must resolve to the ingress controller and port 80 must be reachable from the internet (check_ingress can verify the solver Ingress)",
+			c.Spec.DNSName, c.Spec.Token, c.Spec.DNSName)
+	case c.Spec.Type == "DNS-01" && !c.Status.Presented:
+		return fmt.Sprintf("cert-manager couldn't create the _acme-challenge.%s TXT record; check the DNS provider credentials Secret referenced by the issuer's solver", c.Spec.DNSName)
+	default:
+		return fmt.Sprintf("The _acme-challenge.%s TXT record is created but not yet visible; check propagation and that the zone's authoritative nameservers are reachable from the cluster", c.Spec.DNSName)
+	}
+}
+
+// conditionStep describes an object by its Ready condition.
+func conditionStep(kind, name string, ready *metav1.Condition) IssuanceStep {
+	step := IssuanceStep{Kind: kind, Name: name, State: "unknown"}
+	if ready != nil {
+		step.State = ready.Reason
+		step.Message = ready.Message
+	}
+	return step
+}
+
+// certificateRevision returns a CertificateRequest's revision annotation.
+func certificateRevision(obj unstructured.Unstructured) int {
+	revision, _ := strconv.Atoi(obj.GetAnnotations()["cert-manager.io/certificate-revision"])
+	return revision
+}
@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func certManagerObject(apiVersion, kind, name string, owner types.UID, fields map[string]any) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: fields}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetName(name)
+	if kind != "ClusterIssuer" {
+		obj.SetNamespace("default")
+	}
+	obj.SetUID(types.UID(name + "-uid"))
+	if owner != "" {
+		obj.Object["metadata"].(map[string]any)["ownerReferences"] = []any{map[string]any{
+			"apiVersion": apiVersion, "kind": "Owner", "name": "owner", "uid": string(owner),
+		}}
+	}
+	return obj
+}
+
+func readyCondition(status, reason, message string) map[string]any {
+	return map[string]any{"conditions": []any{map[string]any{"type": "Ready", "status": status, "reason": reason, "message": message}}}
+}
+
+func TestCheckCertificate(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	listKinds := map[schema.GroupVersionResource]string{
+		{Group: "cert-manager.io", Version: "v1", Resource: "certificaterequests"}: "CertificateRequestList",
+		{Group: "acme.cert-manager.io", Version: "v1", Resource: "orders"}:         "OrderList",
+		{Group: "acme.cert-manager.io", Version: "v1", Resource: "challenges"}:     "ChallengeList",
+	}
+	cert := func(ready bool) *unstructured.Unstructured {
+		status := readyCondition("False", "DoesNotExist", "Issuing certificate as Secret does not exist")
+		if ready {
+			status = readyCondition("True", "Ready", "Certificate is up to date and has not expired")
+			status["renewalTime"] = "2025-07-01T00:00:00Z"
+		}
+		return certManagerObject("cert-manager.io/v1", "Certificate", "web", "", map[string]any{
+			"spec": map[string]any{
+				"secretName": "web-tls",
+				"dnsNames":   []any{"shop.example.com"},
+				"issuerRef":  map[string]any{"name": "letsencrypt", "kind": "ClusterIssuer"},
+			},
+			"status": status,
+		})
+	}
+	issuer := func(ready bool) *unstructured.Unstructured {
+		status := readyCondition("True", "ACMEAccountRegistered", "")
+		if !ready {
+			status = readyCondition("False", "ErrRegisterACMEAccount", "Failed to register ACME account: 400 invalid contact")
+		}
+		return certManagerObject("cert-manager.io/v1", "ClusterIssuer", "letsencrypt", "", map[string]any{
+			"spec":   map[string]any{"acme": map[string]any{"server": "https://acme-v02.api.letsencrypt.org/directory"}},
+			"status": status,
+		})
+	}
+	request := certManagerObject("cert-manager.io/v1", "CertificateRequest", "web-1", "web-uid", map[string]any{
+		"status": map[string]any{"conditions": []any{
+			map[string]any{"type": "Approved", "status": "True", "reason": "cert-manager.io"},
+			map[string]any{"type": "Ready", "status": "False", "reason": "Pending", "message": "Waiting on certificate issuance from order default/web-1-123"},
+		}},
+	})
+	order := certManagerObject("acme.cert-manager.io/v1", "Order", "web-1-123", "web-1-uid", map[string]any{
+		"status": map[string]any{"state": "pending"},
+	})
+	challenge := certManagerObject("acme.cert-manager.io/v1", "Challenge", "web-1-123-456", "web-1-123-uid", map[string]any{
+		"spec": map[string]any{"type": "HTTP-01", "dnsName": "shop.example.com", "token": "abc"},
+		"status": map[string]any{
+			"state":     "pending",
+			"presented": true,
+			"reason":    "Waiting for HTTP-01 challenge propagation: wrong status code '404', expected '200'",
+		},
+	})
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		ok      bool
+		stuckAt string
+		issues  []string
+	}{
+		{
+			name:    "ready",
+			objects: []runtime.Object{cert(true), issuer(true)},
+			ok:      true,
+		},
+		{
+			name:    "issuer not ready",
+			objects: []runtime.Object{cert(false), issuer(false), request},
+			stuckAt: "ClusterIssuer",
+			issues:  []string{"ClusterIssuer letsencrypt is not ready (ErrRegisterACMEAccount"},
+		},
+		{
+			name:    "missing issuer",
+			objects: []runtime.Object{cert(false)},
+			stuckAt: "ClusterIssuer",
+			issues:  []string{"ClusterIssuer letsencrypt does not exist", "no CertificateRequest exists yet"},
+		},
+		{
+			name:    "failing HTTP-01 challenge",
+			objects: []runtime.Object{cert(false), issuer(true), request, order, challenge},
+			stuckAt: "Challenge",
+			issues:  []string{"HTTP-01 challenge for shop.example.com is pending: Waiting for HTTP-01 challenge propagation: wrong status code '404'"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, tt.objects...)
+			result := checkCertificate(context.Background(), dyn, nil, "default", "web", now)
+			if _, failed := result["error"]; failed {
+				t.Fatalf("checkCertificate failed: %v", result["error"])
+			}
+			if result["ok"] != tt.ok {
+				t.Errorf("ok = %v, want %v (issues: %v)", result["ok"], tt.ok, result["issues"])
+			}
+			if stuckAt, _ := result["stuck_at"].(string); stuckAt != tt.stuckAt {
+				t.Errorf("stuck_at = %q, want %q", stuckAt, tt.stuckAt)
+			}
+			issues, _ := result["issues"].([]string)
+			joined := strings.Join(issues, "\n")
+			for _, want := range tt.issues {
+				if !strings.Contains(joined, want) {
+					t.Errorf("issues %q should contain %q", joined, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// changeCauseAnnotation records why a workload was changed. The deployment
+// controller copies it to the new ReplicaSet, so rollout history shows it
+// in the CHANGE-CAUSE column of each revision.
+const changeCauseAnnotation = "kubernetes.io/change-cause"
+
+// Annotations recording where a change came from, written next to the
+// change-cause and copied to the new ReplicaSet the same way, so
+// deployment_history can show them for each revision.
+const (
+	planIDAnnotation    = "kasa.io/plan-id"    // the approved plan
+	gitCommitAnnotation = "kasa.io/git-commit" // manifest repository HEAD at the time
+	promptAnnotation    = "kasa.io/prompt"     // what the user asked for
+)
+
+// maxChangeCauseLength keeps the annotation readable in rollout history.
+const maxChangeCauseLength = 200
+
+// changeCauseSchema declares the optional change_cause parameter of tools
+// that roll out workloads.
+var changeCauseSchema = &genai.Schema{
+	Type:        "string",
+	Description: "Why this change is made, recorded in the kubernetes.io/change-cause annotation shown by rollout history. Use the reason of the plan step (optional; a description of the call is used otherwise)",
+}
+
+// changeCause returns the change_cause argument, or fallback if none was
+// given, as a single line of at most maxChangeCauseLength characters.
+func changeCause(args map[string]any, fallback string) string {
+	cause, _ := args["change_cause"].(string)
+	if cause = annotationLine(cause); cause == "" {
+		cause = annotationLine(fallback)
+	}
+	return cause
+}
+
+// changeRecord is what is known about the origin of a mutating call; see
+// recordChanges.
+type changeRecord struct {
+	planID string
+	commit string
+	prompt string
+}
+
+type changeRecordKey struct{}
+
+// withChangeRecord returns ctx carrying r.
+func withChangeRecord(ctx context.Context, r changeRecord) context.Context {
+	return context.WithValue(ctx, changeRecordKey{}, r)
+}
+
+// changeAnnotations returns the annotations recording cause and the change
+// record of ctx. Parts of the record that are unknown map to nil, so a
+// merge patch removes what an earlier change left behind.
+func changeAnnotations(ctx context.Context, cause string) map[string]any {
+	r, _ := ctx.Value(changeRecordKey{}).(changeRecord)
+	annotations := map[string]any{changeCauseAnnotation: cause}
+	for key, value := range map[string]string{planIDAnnotation: r.planID, gitCommitAnnotation: r.commit, promptAnnotation: r.prompt} {
+		if value != "" {
+			annotations[key] = value
+		} else {
+			annotations[key] = nil
+		}
+	}
+	return annotations
+}
+
+// setChangeCause records cause and the change record of ctx on an object.
+// An empty cause leaves the object alone.
+func setChangeCause(ctx context.Context, obj metav1.Object, cause string) {
+	if cause == "" {
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for key, value := range changeAnnotations(ctx, cause) {
+		if value == nil {
+			delete(annotations, key)
+		} else {
+			annotations[key] = value.(string)
+		}
+	}
+	obj.SetAnnotations(annotations)
+}
+
+// annotationLine makes s fit an annotation shown in rollout history: one
+// line of at most maxChangeCauseLength characters.
+func annotationLine(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > maxChangeCauseLength {
+		s = string(r[:maxChangeCauseLength-3]) + "..."
+	}
+	return s
+}
+
+// isRolloutKind reports whether changes to kind create a new revision that
+// rollout history tracks.
+func isRolloutKind(kind string) bool {
+	switch NormalizeKindName(kind) {
+	case "deployment", "statefulset", "daemonset":
+		return true
+	}
+	return false
+}
+
+// Session state keys under which propose_plan leaves the plan for the
+// mutating calls that carry it out.
+const (
+	planIDStateKey     = "kasa_plan_id"
+	planPromptStateKey = "kasa_plan_prompt"
+)
+
+// recordChanges puts the change record of each mutating call in its
+// context: the plan the user approved and what they asked for, as
+// propose_plan left them in the session, and the manifest repository HEAD.
+// The plan only counts while it is being executed, that is, when the
+// message that started the call names its ID; otherwise that message is
+// the prompt.
+func (k *KubeTools) recordChanges(t ToolInfo, next RunFunc) RunFunc {
+	if t.Category != CategoryMutating {
+		return next
+	}
+	return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		if sessionless(ctx) {
+			return next(ctx, args)
+		}
+		text := userText(ctx.UserContent())
+		r := changeRecord{prompt: annotationLine(text)}
+		id, _ := ctx.ReadonlyState().Get(planIDStateKey)
+		if planID, _ := id.(string); planID != "" && strings.Contains(text, planID) {
+			r.planID = planID
+			prompt, _ := ctx.ReadonlyState().Get(planPromptStateKey)
+			r.prompt, _ = prompt.(string)
+		}
+		if k.manifest != nil {
+			r.commit, _ = k.manifest.Head()
+		}
+		return next(derivedContext{Context: ctx, ctx: withChangeRecord(ctx, r)}, args)
+	}
+}
+
+// userText returns the text of a user message.
+func userText(content *genai.Content) string {
+	if content == nil {
+		return ""
+	}
+	var parts []string
+	for _, part := range content.Parts {
+		if part.Text != "" {
+			parts = append(parts, part.Text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
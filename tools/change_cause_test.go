@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"context"
+	"iter"
+	"maps"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestChangeCause(t *testing.T) {
+	if got := changeCause(map[string]any{}, "set_image web to nginx:1.27"); got != "set_image web to nginx:1.27" {
+		t.Errorf("missing change_cause should use the fallback, got %q", got)
+	}
+	if got := changeCause(map[string]any{"change_cause": "  Fix OOM\n kills "}, "fallback"); got != "Fix OOM kills" {
+		t.Errorf("change_cause should be a single line, got %q", got)
+	}
+	long := changeCause(map[string]any{"change_cause": strings.Repeat("å", 500)}, "")
+	if n := len([]rune(long)); n != maxChangeCauseLength || !strings.HasSuffix(long, "...") {
+		t.Errorf("long change_cause should be truncated to %d runes, got %d", maxChangeCauseLength, n)
+	}
+}
+
+func TestSetChangeCause(t *testing.T) {
+	dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"team": "web"}}}
+	setChangeCause(context.Background(), dep, "roll out 1.27")
+	if dep.Annotations[changeCauseAnnotation] != "roll out 1.27" || dep.Annotations["team"] != "web" {
+		t.Errorf("annotations = %v", dep.Annotations)
+	}
+
+	empty := &appsv1.Deployment{}
+	setChangeCause(context.Background(), empty, "")
+	if empty.Annotations != nil {
+		t.Errorf("empty cause should leave annotations alone, got %v", empty.Annotations)
+	}
+}
+
+func TestSetChangeCause_Record(t *testing.T) {
+	dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{planIDAnnotation: "plan-old"}}}
+	ctx := withChangeRecord(context.Background(), changeRecord{commit: "abc1234", prompt: "bump nginx"})
+	setChangeCause(ctx, dep, "roll out 1.27")
+	want := map[string]string{changeCauseAnnotation: "roll out 1.27", gitCommitAnnotation: "abc1234", promptAnnotation: "bump nginx"}
+	if !maps.Equal(dep.Annotations, want) {
+		t.Errorf("expected the stale plan ID to be removed, got %v", dep.Annotations)
+	}
+	if patch := changeAnnotations(ctx, "pause"); patch[planIDAnnotation] != nil || patch[gitCommitAnnotation] != "abc1234" {
+		t.Errorf("expected a merge patch removing the plan ID, got %v", patch)
+	}
+}
+
+// stateContext is a tool.Context with session state and a user message.
+type stateContext struct {
+	tool.Context
+	state   map[string]any
+	message string
+}
+
+func (c stateContext) ReadonlyState() session.ReadonlyState { return mapState(c.state) }
+func (c stateContext) UserContent() *genai.Content {
+	return genai.NewContentFromText(c.message, genai.RoleUser)
+}
+
+type mapState map[string]any
+
+func (s mapState) Get(key string) (any, error) {
+	if v, ok := s[key]; ok {
+		return v, nil
+	}
+	return nil, session.ErrStateKeyNotExist
+}
+
+func (s mapState) All() iter.Seq2[string, any] { return maps.All(s) }
+
+func TestRecordChanges(t *testing.T) {
+	var got changeRecord
+	next := func(ctx tool.Context, _ map[string]any) (map[string]any, error) {
+		got, _ = ctx.Value(changeRecordKey{}).(changeRecord)
+		return nil, nil
+	}
+	k := &KubeTools{manifest: newTestManifestManager(t)}
+	run := k.recordChanges(ToolInfo{Name: "set_image", Category: CategoryMutating}, next)
+	state := map[string]any{planIDStateKey: "plan-1a2b3c4d", planPromptStateKey: "upgrade nginx"}
+
+	run(stateContext{state: state, message: "The user has APPROVED your plan.\nPlan ID: plan-1a2b3c4d"}, nil)
+	if got.planID != "plan-1a2b3c4d" || got.prompt != "upgrade nginx" {
+		t.Errorf("expected the plan being executed, got %+v", got)
+	}
+	run(stateContext{state: state, message: "  scale web\nto 3 "}, nil)
+	if got.planID != "" || got.prompt != "scale web to 3" {
+		t.Errorf("expected a finished plan to be ignored, got %+v", got)
+	}
+
+	var wrapped bool
+	k.recordChanges(ToolInfo{Name: "list_pods", Category: CategoryReadOnly}, func(ctx tool.Context, _ map[string]any) (map[string]any, error) {
+		_, wrapped = ctx.(derivedContext)
+		return nil, nil
+	})(stateContext{state: state, message: "plan-1a2b3c4d"}, nil)
+	if wrapped {
+		t.Error("expected read-only tools to be left alone")
+	}
+}
@@ -60,5 +60,9 @@ func shouldRemoveAnnotation(key string) bool {
 	if key == "kubernetes.io/change-cause" {
 		return true
 	}
+	// Remove the change records kasa sets with it
+	if key == planIDAnnotation || key == gitCommitAnnotation || key == promptAnnotation {
+		return true
+	}
 	return false
 }
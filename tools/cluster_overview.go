@@ -0,0 +1,391 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// overviewMaxItems caps each problem list in the cluster overview.
+const overviewMaxItems = 25
+
+// OverviewNodeProblem describes a node that is not healthy.
+type OverviewNodeProblem struct {
+	Name          string   `json:"name"`
+	Ready         bool     `json:"ready"`
+	Unschedulable bool     `json:"unschedulable,omitempty"`
+	Conditions    []string `json:"conditions,omitempty"` // e.g. "MemoryPressure: <message>"
+}
+
+// OverviewPodProblem describes a pod that is not running and ready.
+type OverviewPodProblem struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Phase     string `json:"phase"`
+	Reason    string `json:"reason,omitempty"`
+	Restarts  int32  `json:"restarts,omitempty"`
+	Age       string `json:"age"`
+}
+
+// OverviewDeploymentProblem describes a deployment below its desired replicas.
+type OverviewDeploymentProblem struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Desired   int32  `json:"desired"`
+	Ready     int32  `json:"ready"`
+	Available int32  `json:"available"`
+}
+
+// OverviewEvent is a recent Warning event.
+type OverviewEvent struct {
+	Namespace string `json:"namespace"`
+	Object    string `json:"object"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message"`
+	Count     int32  `json:"count,omitempty"`
+	Age       string `json:"age"`
+}
+
+// OverviewPVCProblem describes a PVC that is not Bound.
+type OverviewPVCProblem struct {
+	Namespace    string `json:"namespace"`
+	Name         string `json:"name"`
+	Phase        string `json:"phase"`
+	StorageClass string `json:"storage_class,omitempty"`
+}
+
+// ClusterOverviewTool provides the cluster_overview tool for the agent.
+type ClusterOverviewTool struct {
+	clientset *kubernetes.Clientset
+	cache     *ResourceCache // nil reads from the API server
+}
+
+// NewClusterOverviewTool creates a new ClusterOverviewTool.
+func NewClusterOverviewTool(clientset *kubernetes.Clientset) *ClusterOverviewTool {
+	return &ClusterOverviewTool{
+		clientset: clientset,
+	}
+}
+
+// useCache makes the tool read through the resource cache.
+func (t *ClusterOverviewTool) useCache(c *ResourceCache) {
+	t.cache = c
+}
+
+// Name returns the tool name.
+func (t *ClusterOverviewTool) Name() string {
+	return "cluster_overview"
+}
+
+// Description returns the tool description.
+func (t *ClusterOverviewTool) Description() string {
+	return "Summarize cluster health in one call: unhealthy nodes, pods that are not running and ready, deployments below their desired replicas, recent Warning events, PVCs that are not Bound, and multi-replica deployments without a PodDisruptionBudget. Use this first for questions like 'how does the cluster look?', then drill down with the specific tools."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *ClusterOverviewTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *ClusterOverviewTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *ClusterOverviewTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *ClusterOverviewTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "Limit pods, deployments, events and PVCs to one namespace (optional, default: all namespaces)",
+				},
+				"event_minutes": {
+					Type:        "integer",
+					Description: "How far back to look for Warning events, in minutes (default: 60)",
+				},
+			},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *ClusterOverviewTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			argsMap = map[string]any{}
+		}
+	}
+
+	namespace, _ := argsMap["namespace"].(string)
+
+	eventMinutes := 60
+	if m, ok := argsMap["event_minutes"].(float64); ok && m > 0 {
+		eventMinutes = int(m)
+	}
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
+	defer cancel()
+
+	result := map[string]any{}
+	var errs []string
+
+	if nodes, err := t.clientset.CoreV1().Nodes().List(timeoutCtx, metav1.ListOptions{}); err != nil {
+		errs = append(errs, fmt.Sprintf("nodes: %v", err))
+	} else {
+		problems := nodeProblems(nodes.Items)
+		result["nodes_total"] = len(nodes.Items)
+		result["nodes_not_ready"] = countNotReady(problems)
+		result["node_problems"] = truncateList(problems, overviewMaxItems, result, "node_problems")
+	}
+
+	if pods, err := t.cache.listPods(timeoutCtx, t.clientset, namespace, metav1.ListOptions{}); err != nil {
+		errs = append(errs, fmt.Sprintf("pods: %v", err))
+	} else {
+		problems := podProblems(pods.Items, time.Now())
+		result["pods_total"] = len(pods.Items)
+		result["pods_unhealthy"] = len(problems)
+		result["pod_problems"] = truncateList(problems, overviewMaxItems, result, "pod_problems")
+	}
+
+	if deps, err := t.cache.listDeployments(timeoutCtx, t.clientset, namespace, metav1.ListOptions{}); err != nil {
+		errs = append(errs, fmt.Sprintf("deployments: %v", err))
+	} else {
+		problems := deploymentProblems(deps.Items)
+		result["deployments_total"] = len(deps.Items)
+		result["deployments_degraded"] = len(problems)
+		result["deployment_problems"] = truncateList(problems, overviewMaxItems, result, "deployment_problems")
+
+		// A warning rather than a problem: the deployments run fine until a node drain
+		if pdbs, err := t.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(timeoutCtx, metav1.ListOptions{}); err != nil {
+			errs = append(errs, fmt.Sprintf("poddisruptionbudgets: %v", err))
+		} else if uncovered := deploymentsWithoutPDB(deps.Items, pdbs.Items); len(uncovered) > 0 {
+			result["deployments_without_pdb"] = truncateList(uncovered, overviewMaxItems, result, "deployments_without_pdb")
+		}
+	}
+
+	if events, err := t.cache.listEvents(timeoutCtx, t.clientset, namespace, metav1.ListOptions{FieldSelector: "type=Warning"}); err != nil {
+		errs = append(errs, fmt.Sprintf("events: %v", err))
+	} else {
+		recent := recentWarnings(events.Items, time.Now().Add(-time.Duration(eventMinutes)*time.Minute), time.Now())
+		result["warning_events"] = len(recent)
+		result["recent_warnings"] = truncateList(recent, overviewMaxItems, result, "recent_warnings")
+	}
+
+	if pvcs, err := t.clientset.CoreV1().PersistentVolumeClaims(namespace).List(timeoutCtx, metav1.ListOptions{}); err != nil {
+		errs = append(errs, fmt.Sprintf("pvcs: %v", err))
+	} else {
+		problems := pvcProblems(pvcs.Items)
+		result["pvcs_total"] = len(pvcs.Items)
+		result["pvcs_unbound"] = len(problems)
+		result["pvc_problems"] = truncateList(problems, overviewMaxItems, result, "pvc_problems")
+	}
+
+	if len(errs) > 0 {
+		result["errors"] = errs
+	}
+
+	healthy := len(errs) == 0
+	for _, key := range []string{"nodes_not_ready", "pods_unhealthy", "deployments_degraded", "pvcs_unbound"} {
+		if n, _ := result[key].(int); n > 0 {
+			healthy = false
+		}
+	}
+	if problems, _ := result["node_problems"].([]OverviewNodeProblem); len(problems) > 0 {
+		healthy = false
+	}
+	result["healthy"] = healthy
+
+	scope := "cluster"
+	if namespace != "" {
+		scope = fmt.Sprintf("namespace %s", namespace)
+	}
+	if healthy {
+		result["message"] = fmt.Sprintf("No problems found in %s (%d warning events in the last %d minutes)", scope, result["warning_events"], eventMinutes)
+	} else {
+		result["message"] = fmt.Sprintf("Problems found in %s", scope)
+	}
+
+	return result, nil
+}
+
+// truncateList caps items at limit, recording the number of omitted entries
+// in result under "<key>_omitted".
+func truncateList[T any](items []T, limit int, result map[string]any, key string) []T {
+	if items == nil {
+		items = []T{}
+	}
+	if len(items) > limit {
+		result[key+"_omitted"] = len(items) - limit
+		return items[:limit]
+	}
+	return items
+}
+
+// nodeProblems returns the nodes that are not ready, are cordoned or report
+// a pressure condition.
+func nodeProblems(nodes []corev1.Node) []OverviewNodeProblem {
+	var problems []OverviewNodeProblem
+	for _, node := range nodes {
+		p := OverviewNodeProblem{Name: node.Name, Unschedulable: node.Spec.Unschedulable}
+		for _, cond := range node.Status.Conditions {
+			switch {
+			case cond.Type == corev1.NodeReady:
+				p.Ready = cond.Status == corev1.ConditionTrue
+				if !p.Ready {
+					p.Conditions = append(p.Conditions, fmt.Sprintf("NotReady: %s", cond.Message))
+				}
+			case cond.Status == corev1.ConditionTrue:
+				// MemoryPressure, DiskPressure, PIDPressure, NetworkUnavailable
+				p.Conditions = append(p.Conditions, fmt.Sprintf("%s: %s", cond.Type, cond.Message))
+			}
+		}
+		if !p.Ready || p.Unschedulable || len(p.Conditions) > 0 {
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
+// countNotReady counts the problem nodes that are not ready.
+func countNotReady(problems []OverviewNodeProblem) int {
+	n := 0
+	for _, p := range problems {
+		if !p.Ready {
+			n++
+		}
+	}
+	return n
+}
+
+// podProblems returns pods that are neither completed nor running and ready.
+func podProblems(pods []corev1.Pod, now time.Time) []OverviewPodProblem {
+	var problems []OverviewPodProblem
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded {
+			continue
+		}
+		ready := false
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+				ready = true
+			}
+		}
+		if pod.Status.Phase == corev1.PodRunning && ready {
+			continue
+		}
+
+		p := OverviewPodProblem{
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+			Phase:     string(pod.Status.Phase),
+			Reason:    pod.Status.Reason,
+			Age:       formatDuration(now.Sub(pod.CreationTimestamp.Time)),
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			p.Restarts += cs.RestartCount
+			if p.Reason == "" && cs.State.Waiting != nil {
+				p.Reason = cs.State.Waiting.Reason
+			}
+		}
+		if p.Reason == "" && pod.Status.Phase == corev1.PodRunning {
+			p.Reason = "NotReady"
+		}
+		problems = append(problems, p)
+	}
+	sort.Slice(problems, func(i, j int) bool {
+		if problems[i].Namespace != problems[j].Namespace {
+			return problems[i].Namespace < problems[j].Namespace
+		}
+		return problems[i].Name < problems[j].Name
+	})
+	return problems
+}
+
+// deploymentProblems returns deployments with fewer ready replicas than desired.
+func deploymentProblems(deps []appsv1.Deployment) []OverviewDeploymentProblem {
+	var problems []OverviewDeploymentProblem
+	for _, dep := range deps {
+		desired := int32(1)
+		if dep.Spec.Replicas != nil {
+			desired = *dep.Spec.Replicas
+		}
+		if dep.Status.ReadyReplicas >= desired {
+			continue
+		}
+		problems = append(problems, OverviewDeploymentProblem{
+			Namespace: dep.Namespace,
+			Name:      dep.Name,
+			Desired:   desired,
+			Ready:     dep.Status.ReadyReplicas,
+			Available: dep.Status.AvailableReplicas,
+		})
+	}
+	return problems
+}
+
+// recentWarnings returns Warning events seen since the given time, newest first.
+func recentWarnings(events []corev1.Event, since, now time.Time) []OverviewEvent {
+	sort.Slice(events, func(i, j int) bool {
+		return eventTime(events[i]).After(eventTime(events[j]))
+	})
+	var recent []OverviewEvent
+	for _, e := range events {
+		if e.Type != corev1.EventTypeWarning || eventTime(e).Before(since) {
+			continue
+		}
+		recent = append(recent, OverviewEvent{
+			Namespace: e.Namespace,
+			Object:    fmt.Sprintf("%s/%s", strings.ToLower(e.InvolvedObject.Kind), e.InvolvedObject.Name),
+			Reason:    e.Reason,
+			Message:   e.Message,
+			Count:     e.Count,
+			Age:       formatDuration(now.Sub(eventTime(e))),
+		})
+	}
+	return recent
+}
+
+// pvcProblems returns PVCs that are not Bound.
+func pvcProblems(pvcs []corev1.PersistentVolumeClaim) []OverviewPVCProblem {
+	var problems []OverviewPVCProblem
+	for _, pvc := range pvcs {
+		if pvc.Status.Phase == corev1.ClaimBound {
+			continue
+		}
+		p := OverviewPVCProblem{
+			Namespace: pvc.Namespace,
+			Name:      pvc.Name,
+			Phase:     string(pvc.Status.Phase),
+		}
+		if pvc.Spec.StorageClassName != nil {
+			p.StorageClass = *pvc.Spec.StorageClassName
+		}
+		problems = append(problems, p)
+	}
+	return problems
+}
@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeProblems(t *testing.T) {
+	nodes := []corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "healthy"},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+				{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pressured"},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+				{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue, Message: "disk full"},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "down"},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionUnknown, Message: "kubelet stopped posting"},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "cordoned"},
+			Spec:       corev1.NodeSpec{Unschedulable: true},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+			}},
+		},
+	}
+
+	problems := nodeProblems(nodes)
+	if len(problems) != 3 {
+		t.Fatalf("got %d problems, want 3: %+v", len(problems), problems)
+	}
+	if countNotReady(problems) != 1 {
+		t.Errorf("countNotReady = %d, want 1", countNotReady(problems))
+	}
+	if problems[0].Name != "pressured" || problems[0].Conditions[0] != "DiskPressure: disk full" {
+		t.Errorf("unexpected first problem: %+v", problems[0])
+	}
+}
+
+func TestPodProblems(t *testing.T) {
+	now := time.Now()
+	ready := []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "ok", Namespace: "a"}, Status: corev1.PodStatus{Phase: corev1.PodRunning, Conditions: ready}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "done", Namespace: "a"}, Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "unready", Namespace: "b"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "crashing", Namespace: "a"}, Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				RestartCount: 4,
+				State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			}},
+		}},
+	}
+
+	problems := podProblems(pods, now)
+	if len(problems) != 2 {
+		t.Fatalf("got %d problems, want 2: %+v", len(problems), problems)
+	}
+	if problems[0].Name != "crashing" || problems[0].Reason != "CrashLoopBackOff" || problems[0].Restarts != 4 {
+		t.Errorf("unexpected problem: %+v", problems[0])
+	}
+	if problems[1].Name != "unready" || problems[1].Reason != "NotReady" {
+		t.Errorf("unexpected problem: %+v", problems[1])
+	}
+}
+
+func TestDeploymentAndPVCProblems(t *testing.T) {
+	three := int32(3)
+	deps := []appsv1.Deployment{
+		{ObjectMeta: metav1.ObjectMeta{Name: "full"}, Spec: appsv1.DeploymentSpec{Replicas: &three}, Status: appsv1.DeploymentStatus{ReadyReplicas: 3}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "short"}, Spec: appsv1.DeploymentSpec{Replicas: &three}, Status: appsv1.DeploymentStatus{ReadyReplicas: 1}},
+	}
+	if problems := deploymentProblems(deps); len(problems) != 1 || problems[0].Name != "short" || problems[0].Desired != 3 {
+		t.Errorf("unexpected deployment problems: %+v", problems)
+	}
+
+	pvcs := []corev1.PersistentVolumeClaim{
+		{ObjectMeta: metav1.ObjectMeta{Name: "bound"}, Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pending"}, Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending}},
+	}
+	if problems := pvcProblems(pvcs); len(problems) != 1 || problems[0].Name != "pending" {
+		t.Errorf("unexpected pvc problems: %+v", problems)
+	}
+}
+
+func TestRecentWarnings(t *testing.T) {
+	now := time.Now()
+	event := func(name, typ string, age time.Duration) corev1.Event {
+		return corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: name},
+			Type:           typ,
+			Reason:         "BackOff",
+			LastTimestamp:  metav1.NewTime(now.Add(-age)),
+		}
+	}
+	events := []corev1.Event{
+		event("older", corev1.EventTypeWarning, 30*time.Minute),
+		event("normal", corev1.EventTypeNormal, time.Minute),
+		event("newest", corev1.EventTypeWarning, time.Minute),
+		event("stale", corev1.EventTypeWarning, 2*time.Hour),
+	}
+
+	recent := recentWarnings(events, now.Add(-time.Hour), now)
+	if len(recent) != 2 || recent[0].Object != "pod/newest" || recent[1].Object != "pod/older" {
+		t.Errorf("unexpected warnings: %+v", recent)
+	}
+}
+
+func TestTruncateList(t *testing.T) {
+	result := map[string]any{}
+	got := truncateList([]int{1, 2, 3, 4}, 2, result, "items")
+	if len(got) != 2 || result["items_omitted"] != 2 {
+		t.Errorf("got %v, result %v", got, result)
+	}
+	if got := truncateList[int](nil, 2, result, "empty"); got == nil || len(got) != 0 {
+		t.Errorf("nil list should become empty, got %v", got)
+	}
+}
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"sort"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// FormatCompactToolDocs lists the given tools by name only, grouped by
+// category, for models with small context windows. What each tool does is
+// left to its declaration, which CompactDeclaration shortens.
+func FormatCompactToolDocs(all []tool.Tool) string {
+	var readOnly, mutating, planning []string
+	for _, t := range all {
+		ft, ok := t.(functionTool)
+		if !ok {
+			continue
+		}
+		switch ft.Category() {
+		case CategoryReadOnly:
+			readOnly = append(readOnly, ft.Name())
+		case CategoryMutating:
+			mutating = append(mutating, ft.Name())
+		case CategoryPlanning:
+			planning = append(planning, ft.Name())
+		}
+	}
+
+	var lines []string
+	for _, group := range []struct {
+		label string
+		names []string
+	}{
+		{"Read-only (use freely)", readOnly},
+		{"Mutating (require plan approval)", mutating},
+		{"Planning", planning},
+	} {
+		if len(group.names) > 0 {
+			sort.Strings(group.names)
+			lines = append(lines, group.label+": "+strings.Join(group.names, ", "))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "### Tools\n" + strings.Join(lines, "\n")
+}
+
+// CompactDeclaration returns a copy of decl with smaller schemas: the tool
+// and parameter descriptions are cut to their first sentence and the
+// optional timeout_seconds parameter is left out. The reason parameter is
+// kept, as the REPL shows it.
+func CompactDeclaration(decl *genai.FunctionDeclaration) *genai.FunctionDeclaration {
+	compact := *decl
+	compact.Description = firstSentence(decl.Description)
+	if decl.Parameters != nil {
+		compact.Parameters = compactSchema(decl.Parameters)
+		delete(compact.Parameters.Properties, "timeout_seconds")
+	}
+	return &compact
+}
+
+// compactSchema copies s with shortened descriptions.
+func compactSchema(s *genai.Schema) *genai.Schema {
+	compact := *s
+	compact.Description = firstSentence(s.Description)
+	if s.Items != nil {
+		compact.Items = compactSchema(s.Items)
+	}
+	if s.Properties != nil {
+		compact.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, prop := range s.Properties {
+			compact.Properties[name] = compactSchema(prop)
+		}
+	}
+	return &compact
+}
+
+// firstSentence returns text up to the end of its first sentence. A period
+// after an abbreviation such as "e.g." does not end it.
+func firstSentence(text string) string {
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\n':
+			return strings.TrimSpace(text[:i])
+		case text[i] == '.' && (i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\n'):
+			if before := text[:i]; !strings.HasSuffix(before, "e.g") && !strings.HasSuffix(before, "i.e") && !strings.HasSuffix(before, "etc") {
+				return text[:i+1]
+			}
+		}
+	}
+	return text
+}
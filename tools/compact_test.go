@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+)
+
+func TestFormatCompactToolDocs(t *testing.T) {
+	docs := FormatCompactToolDocs([]tool.Tool{NewPickResourceTool(), NewDeploymentHistoryTool(nil), NewRollbackDeploymentTool(nil, nil)})
+	want := "### Tools\nRead-only (use freely): deployment_history, pick_resource\nMutating (require plan approval): rollback_deployment"
+	if docs != want {
+		t.Errorf("got %q, want %q", docs, want)
+	}
+	if FormatCompactToolDocs(nil) != "" {
+		t.Error("expected no docs without tools")
+	}
+}
+
+func TestCompactDeclaration(t *testing.T) {
+	req := &model.LLMRequest{}
+	if err := addFunctionTool(req, NewPickResourceTool()); err != nil {
+		t.Fatal(err)
+	}
+	decl := req.Config.Tools[0].FunctionDeclarations[0]
+	compact := CompactDeclaration(decl)
+
+	if compact.Description != "Let the user pick one or more resources from a list, e.g. which pod of a deployment to get logs from or which of several matching deployments they mean." {
+		t.Errorf("expected the first sentence, got %q", compact.Description)
+	}
+	if _, ok := compact.Parameters.Properties["timeout_seconds"]; ok {
+		t.Error("expected timeout_seconds to be left out")
+	}
+	if _, ok := compact.Parameters.Properties["reason"]; !ok {
+		t.Error("expected reason to be kept")
+	}
+	detail := compact.Parameters.Properties["items"].Items.Properties["detail"]
+	if detail.Description != "A short description to tell the items apart, e.g. 'Running, 3 restarts, node-2'" {
+		t.Errorf("unexpected nested description %q", detail.Description)
+	}
+	if _, ok := decl.Parameters.Properties["timeout_seconds"]; !ok || !strings.Contains(decl.Description, "Use it instead") {
+		t.Error("expected the original declaration to be left alone")
+	}
+}
+
+func TestFirstSentence(t *testing.T) {
+	tests := map[string]string{
+		"Scale a deployment. Requires approval.": "Scale a deployment.",
+		"Pods, e.g. web-1. Or all of them.":      "Pods, e.g. web-1.",
+		"One line\nanother line":                 "One line",
+		"No period":                              "No period",
+		"Version 1.2 of it. More":                "Version 1.2 of it.",
+	}
+	for in, want := range tests {
+		if got := firstSentence(in); got != want {
+			t.Errorf("firstSentence(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// completionTTL is how long the namespaces offered for tab completion are
+// reused before the API server is asked again.
+const completionTTL = 30 * time.Second
+
+// completionTimeout bounds the namespace lookup so a slow cluster doesn't
+// freeze the prompt.
+const completionTimeout = 2 * time.Second
+
+// namespaceList caches the namespace names for tab completion.
+type namespaceList struct {
+	mu      sync.Mutex
+	names   []string
+	fetched time.Time
+}
+
+// Namespaces returns the namespaces for tab completion: those of the
+// cluster that the namespace policy allows, sorted.
+func (k *KubeTools) Namespaces() []string {
+	if k.namespaces == nil || k.clientset == nil {
+		return nil
+	}
+	k.namespaces.mu.Lock()
+	defer k.namespaces.mu.Unlock()
+	if k.namespaces.names != nil && time.Since(k.namespaces.fetched) < completionTTL {
+		return k.namespaces.names
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	list, err := k.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return k.namespaces.names
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, ns := range list.Items {
+		if k.namespaceGuard == nil || k.namespaceGuard.policy.Allows(ns.Name) {
+			names = append(names, ns.Name)
+		}
+	}
+	slices.Sort(names)
+	k.namespaces.names = names
+	k.namespaces.fetched = time.Now()
+	return names
+}
+
+// Apps returns the names of the apps in the manifest store, sorted.
+func (k *KubeTools) Apps() []string {
+	if k.manifest == nil {
+		return nil
+	}
+	manifests, err := k.manifest.ListManifests("", "")
+	if err != nil {
+		return nil
+	}
+	var apps []string
+	for _, m := range manifests {
+		apps = append(apps, m.App)
+	}
+	slices.Sort(apps)
+	return slices.Compact(apps)
+}
+
+// Kinds returns the resource kinds the cluster serves, sorted.
+func (k *KubeTools) Kinds() []string {
+	return k.resolver.Kinds()
+}
@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompletion(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	for _, m := range []struct{ ns, app, kind string }{
+		{"default", "web", "deployment"},
+		{"default", "web", "service"},
+		{"prod", "api", "deployment"},
+		{"prod", "web", "deployment"},
+	} {
+		if _, err := mgr.SaveManifest(m.ns, m.app, m.kind, []byte("kind: x\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	k := &KubeTools{manifest: mgr}
+	if got := k.Apps(); !slices.Equal(got, []string{"api", "web"}) {
+		t.Errorf("expected each app once, sorted, got %v", got)
+	}
+	if got := k.Kinds(); !slices.Contains(got, "deployment") || !slices.IsSorted(got) {
+		t.Errorf("expected the static kinds without a resolver, got %v", got)
+	}
+	if got := k.Namespaces(); got != nil {
+		t.Errorf("expected no namespaces without a cluster, got %v", got)
+	}
+}
@@ -0,0 +1,354 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/perbu/kasa/manifest"
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// configKeyUpdate describes a change to a single key of a ConfigMap or Secret.
+type configKeyUpdate struct {
+	kind            string // "configmap" or "secret"
+	namespace, name string
+	app             string // stored manifest app name
+	key, value      string
+	remove          bool
+	restart         bool   // restart deployments that consume the object
+	cause           string // change-cause for restarted deployments
+}
+
+// configKeyProperties returns the parameters shared by update_configmap_key
+// and update_secret_key.
+func configKeyProperties(kind string) map[string]*genai.Schema {
+	return map[string]*genai.Schema{
+		"namespace": {
+			Type:        "string",
+			Description: "The Kubernetes namespace",
+		},
+		"name": {
+			Type:        "string",
+			Description: fmt.Sprintf("The name of the %s", kind),
+		},
+		"key": {
+			Type:        "string",
+			Description: "The data key to set or remove, e.g. LOG_LEVEL or app.properties",
+		},
+		"value": {
+			Type:        "string",
+			Description: "The new value. Required unless remove is set",
+		},
+		"remove": {
+			Type:        "boolean",
+			Description: "Remove the key instead of setting it (default: false)",
+		},
+		"app": {
+			Type:        "string",
+			Description: "Application name of the stored manifest (default: name)",
+		},
+		"restart": {
+			Type:        "boolean",
+			Description: fmt.Sprintf("Rollout restart the deployments that use this %s so they pick up the change (default: false)", kind),
+		},
+		"change_cause": changeCauseSchema,
+	}
+}
+
+// parseConfigKeyArgs parses the arguments shared by update_configmap_key and
+// update_secret_key. On failure it returns a tool error result.
+func parseConfigKeyArgs(args any, kind string) (configKeyUpdate, map[string]any) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return configKeyUpdate{}, map[string]any{"error": "invalid arguments format"}
+			}
+		} else {
+			return configKeyUpdate{}, map[string]any{"error": "invalid arguments type"}
+		}
+	}
+
+	u := configKeyUpdate{kind: kind}
+	if u.namespace, _ = argsMap["namespace"].(string); u.namespace == "" {
+		return u, map[string]any{"error": "namespace is required"}
+	}
+	if u.name, _ = argsMap["name"].(string); u.name == "" {
+		return u, map[string]any{"error": "name is required"}
+	}
+	if u.key, _ = argsMap["key"].(string); u.key == "" {
+		return u, map[string]any{"error": "key is required"}
+	}
+
+	u.remove, _ = argsMap["remove"].(bool)
+	value, hasValue := argsMap["value"].(string)
+	if !hasValue {
+		// Models sometimes send numbers and booleans unquoted
+		if v, ok := argsMap["value"]; ok && v != nil {
+			value, hasValue = fmt.Sprint(v), true
+		}
+	}
+	if !u.remove && !hasValue {
+		return u, map[string]any{"error": "value is required unless remove is set"}
+	}
+	u.value = value
+
+	u.app = u.name
+	if a, ok := argsMap["app"].(string); ok && a != "" {
+		u.app = a
+	}
+	u.restart, _ = argsMap["restart"].(bool)
+	u.cause = changeCause(argsMap, fmt.Sprintf("restart after %s %s key %s changed", kind, u.name, u.key))
+	return u, nil
+}
+
+// updateConfigKey changes one key in the stored manifest and the live object
+// and optionally restarts the deployments using it. Returns the tool response.
+func updateConfigKey(ctx context.Context, clientset kubernetes.Interface, mgr *manifest.Manager, u configKeyUpdate) map[string]any {
+	secret := u.kind == "secret"
+
+	content, err := mgr.ReadManifest(u.namespace, u.app, u.kind)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("%v (use import_resource to start managing it)", err)}
+	}
+	updated, err := setManifestDataKey(content, secret, u.key, u.value, u.remove)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	// Only the changed key is sent, so concurrent edits to other keys survive
+	var patchValue any = u.value
+	if u.remove {
+		patchValue = nil
+	} else if secret {
+		patchValue = base64.StdEncoding.EncodeToString([]byte(u.value))
+	}
+	patch, err := json.Marshal(map[string]any{"data": map[string]any{u.key: patchValue}})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to build patch: %v", err)}
+	}
+
+	result := map[string]any{
+		"namespace": u.namespace,
+		"name":      u.name,
+		"key":       u.key,
+	}
+	if secret {
+		_, err = clientset.CoreV1().Secrets(u.namespace).Patch(ctx, u.name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	} else {
+		var previous *corev1.ConfigMap
+		if previous, err = clientset.CoreV1().ConfigMaps(u.namespace).Get(ctx, u.name, metav1.GetOptions{}); err == nil {
+			if v, ok := previous.Data[u.key]; ok {
+				result["previous_value"] = v
+			}
+			_, err = clientset.CoreV1().ConfigMaps(u.namespace).Patch(ctx, u.name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+		}
+	}
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to update %s %s/%s: %v", u.kind, u.namespace, u.name, err)}
+	}
+
+	manifestPath, err := mgr.SaveManifest(u.namespace, u.app, u.kind, updated)
+	if err != nil {
+		result["manifest_warning"] = fmt.Sprintf("Updated in the cluster but failed to save manifest: %v", err)
+	} else {
+		result["manifest_path"] = manifestPath
+	}
+
+	action := "Set"
+	if u.remove {
+		action = "Removed"
+	}
+	message := fmt.Sprintf("%s key %s in %s %s/%s", action, u.key, u.kind, u.namespace, u.name)
+	if mgr.IsDryRun() {
+		result["dry_run"] = true
+		message = "Dry run: " + message
+	}
+
+	consumers, err := consumingDeployments(ctx, clientset, u.namespace, secret, u.name)
+	if err != nil {
+		result["consumers_warning"] = fmt.Sprintf("failed to find deployments using the %s: %v", u.kind, err)
+	}
+	result["consumers"] = consumers
+
+	if u.restart && len(consumers) > 0 {
+		restarted := []string{}
+		for _, dep := range consumers {
+			if err := restartDeployment(ctx, clientset, u.namespace, dep, u.cause); err != nil {
+				result["restart_error"] = fmt.Sprintf("failed to restart deployment %s: %v", dep, err)
+				break
+			}
+			restarted = append(restarted, dep)
+		}
+		result["restarted"] = restarted
+		message += fmt.Sprintf("; restarted %d deployment(s)", len(restarted))
+	} else if len(consumers) > 0 {
+		message += fmt.Sprintf("; deployments using it (%s) keep the old value until restarted, except for files from volume mounts, which update within a minute or two", strings.Join(consumers, ", "))
+	}
+
+	result["success"] = true
+	result["message"] = message
+	return result
+}
+
+// setManifestDataKey sets or removes key in a stored ConfigMap or Secret
+// manifest, leaving the rest of the document as it is. Secret values go into
+// stringData when the manifest uses it and are base64-encoded in data otherwise.
+func setManifestDataKey(content []byte, secret bool, key, value string, remove bool) ([]byte, error) {
+	doc, root, err := parseYAMLDocument(content)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []string{"data", "binaryData"}
+	if secret {
+		fields = []string{"stringData", "data"}
+	}
+
+	if remove {
+		found := false
+		for _, field := range fields {
+			if _, err := removeYAMLPath(root, []yamlPathSegment{{key: field}, {key: key}}); err == nil {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("key %s not found in the stored manifest", key)
+		}
+	} else {
+		field, encoded := "data", value
+		if secret && useStringData(root, key) {
+			field = "stringData"
+		} else if secret {
+			encoded = base64.StdEncoding.EncodeToString([]byte(value))
+		}
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: encoded}
+		if strings.Contains(encoded, "\n") {
+			node.Style = yaml.LiteralStyle
+		}
+		if _, err := setYAMLPath(root, []yamlPathSegment{{key: field}, {key: key}}, node); err != nil {
+			return nil, err
+		}
+	}
+
+	return encodeYAMLDocument(doc, usesCompactSequences(content))
+}
+
+// useStringData reports whether a Secret manifest should get key in
+// stringData: it is already there, or stringData is used and data doesn't
+// hold the key.
+func useStringData(root *yaml.Node, key string) bool {
+	stringData, _, _ := yamlChild(root, yamlPathSegment{key: "stringData"})
+	if stringData == nil {
+		return false
+	}
+	if v, _, _ := yamlChild(stringData, yamlPathSegment{key: key}); v != nil {
+		return true
+	}
+	data, _, _ := yamlChild(root, yamlPathSegment{key: "data"})
+	if data == nil {
+		return true
+	}
+	v, _, _ := yamlChild(data, yamlPathSegment{key: key})
+	return v == nil
+}
+
+// consumingDeployments returns the deployments in namespace whose pods use
+// the named ConfigMap (or Secret) through volumes, env or envFrom.
+func consumingDeployments(ctx context.Context, clientset kubernetes.Interface, namespace string, secret bool, name string) ([]string, error) {
+	deps, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	consumers := []string{}
+	for _, dep := range deps.Items {
+		if podSpecReferences(&dep.Spec.Template.Spec, secret, name) {
+			consumers = append(consumers, dep.Name)
+		}
+	}
+	return consumers, nil
+}
+
+// podSpecReferences reports whether a pod spec uses the named ConfigMap (or
+// Secret) through volumes, projected volumes, env or envFrom.
+func podSpecReferences(spec *corev1.PodSpec, secret bool, name string) bool {
+	for _, v := range spec.Volumes {
+		if secret && v.Secret != nil && v.Secret.SecretName == name {
+			return true
+		}
+		if !secret && v.ConfigMap != nil && v.ConfigMap.Name == name {
+			return true
+		}
+		if v.Projected != nil {
+			for _, src := range v.Projected.Sources {
+				if secret && src.Secret != nil && src.Secret.Name == name {
+					return true
+				}
+				if !secret && src.ConfigMap != nil && src.ConfigMap.Name == name {
+					return true
+				}
+			}
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, c := range containers {
+		for _, from := range c.EnvFrom {
+			if secret && from.SecretRef != nil && from.SecretRef.Name == name {
+				return true
+			}
+			if !secret && from.ConfigMapRef != nil && from.ConfigMapRef.Name == name {
+				return true
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if secret && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == name {
+				return true
+			}
+			if !secret && env.ValueFrom.ConfigMapKeyRef != nil && env.ValueFrom.ConfigMapKeyRef.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// restartDeployment triggers a rolling restart the way 'kubectl rollout
+// restart' does, by stamping the pod template with the current time.
+func restartDeployment(ctx context.Context, clientset kubernetes.Interface, namespace, name, cause string) error {
+	patchObj := map[string]any{
+		"spec": map[string]any{
+			"template": map[string]any{
+				"metadata": map[string]any{
+					"annotations": map[string]string{
+						"kubectl.kubernetes.io/restartedAt": time.Now().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	}
+	if cause != "" {
+		patchObj["metadata"] = map[string]any{"annotations": changeAnnotations(ctx, cause)}
+	}
+	patch, err := json.Marshal(patchObj)
+	if err != nil {
+		return err
+	}
+	_, err = clientset.AppsV1().Deployments(namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
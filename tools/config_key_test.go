@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const configKeyTestConfigMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-config
+  namespace: default
+data:
+  LOG_LEVEL: info # raise while debugging
+  app.properties: |
+    port=8080
+`
+
+func TestSetManifestDataKeyConfigMap(t *testing.T) {
+	updated, err := setManifestDataKey([]byte(configKeyTestConfigMap), false, "LOG_LEVEL", "debug", false)
+	if err != nil {
+		t.Fatalf("setManifestDataKey: %v", err)
+	}
+	want := strings.Replace(configKeyTestConfigMap, "LOG_LEVEL: info", "LOG_LEVEL: debug", 1)
+	if string(updated) != want {
+		t.Errorf("only LOG_LEVEL should change, got:\n%s", updated)
+	}
+
+	updated, err = setManifestDataKey([]byte(configKeyTestConfigMap), false, "FEATURES", "a\nb\n", false)
+	if err != nil {
+		t.Fatalf("setManifestDataKey: %v", err)
+	}
+	if !strings.Contains(string(updated), "  FEATURES: |\n    a\n    b\n") {
+		t.Errorf("multi-line value should be a literal block, got:\n%s", updated)
+	}
+
+	updated, err = setManifestDataKey([]byte(configKeyTestConfigMap), false, "app.properties", "", true)
+	if err != nil {
+		t.Fatalf("setManifestDataKey remove: %v", err)
+	}
+	if strings.Contains(string(updated), "app.properties") || !strings.Contains(string(updated), "LOG_LEVEL: info") {
+		t.Errorf("only app.properties should be removed, got:\n%s", updated)
+	}
+
+	if _, err := setManifestDataKey([]byte(configKeyTestConfigMap), false, "MISSING", "", true); err == nil {
+		t.Error("removing a missing key should fail")
+	}
+}
+
+func TestSetManifestDataKeySecret(t *testing.T) {
+	stringData := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\nstringData:\n  user: admin\n"
+	updated, err := setManifestDataKey([]byte(stringData), true, "password", "s3cret", false)
+	if err != nil {
+		t.Fatalf("setManifestDataKey: %v", err)
+	}
+	if !strings.Contains(string(updated), "stringData:\n  user: admin\n  password: s3cret\n") {
+		t.Errorf("key should be added to stringData, got:\n%s", updated)
+	}
+
+	data := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\ndata:\n  user: YWRtaW4=\n"
+	updated, err = setManifestDataKey([]byte(data), true, "password", "s3cret", false)
+	if err != nil {
+		t.Fatalf("setManifestDataKey: %v", err)
+	}
+	if !strings.Contains(string(updated), "  password: czNjcmV0\n") {
+		t.Errorf("key should be base64-encoded in data, got:\n%s", updated)
+	}
+}
+
+func TestPodSpecReferences(t *testing.T) {
+	spec := &corev1.PodSpec{
+		Volumes: []corev1.Volume{{
+			Name:         "config",
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web-config"}}},
+		}},
+		Containers: []corev1.Container{{
+			Name: "web",
+			Env: []corev1.EnvVar{{
+				Name:      "DB_PASSWORD",
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}, Key: "password"}},
+			}},
+		}},
+	}
+
+	tests := []struct {
+		secret bool
+		name   string
+		want   bool
+	}{
+		{false, "web-config", true},
+		{true, "db", true},
+		{true, "web-config", false},
+		{false, "db", false},
+	}
+	for _, tt := range tests {
+		if got := podSpecReferences(spec, tt.secret, tt.name); got != tt.want {
+			t.Errorf("podSpecReferences(secret=%v, %q) = %v, want %v", tt.secret, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateConfigKey(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	if _, err := mgr.SaveManifest("default", "web-config", "configmap", []byte(configKeyTestConfigMap)); err != nil {
+		t.Fatal(err)
+	}
+
+	clientset := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-config", Namespace: "default"},
+			Data:       map[string]string{"LOG_LEVEL": "info", "app.properties": "port=8080\n"},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:    "web",
+					EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web-config"}}}},
+				}},
+			}}},
+		},
+	)
+
+	result := updateConfigKey(context.Background(), clientset, mgr, configKeyUpdate{
+		kind: "configmap", namespace: "default", name: "web-config", app: "web-config",
+		key: "LOG_LEVEL", value: "debug", restart: true, cause: "debug logging",
+	})
+	if ok, _ := result["success"].(bool); !ok {
+		t.Fatalf("updateConfigKey failed: %v", result)
+	}
+	if result["previous_value"] != "info" {
+		t.Errorf("previous_value = %v, want info", result["previous_value"])
+	}
+
+	cm, _ := clientset.CoreV1().ConfigMaps("default").Get(context.Background(), "web-config", metav1.GetOptions{})
+	if cm.Data["LOG_LEVEL"] != "debug" || cm.Data["app.properties"] != "port=8080\n" {
+		t.Errorf("live data = %v", cm.Data)
+	}
+
+	stored, _ := mgr.ReadManifest("default", "web-config", "configmap")
+	if !strings.Contains(string(stored), "LOG_LEVEL: debug # raise while debugging") {
+		t.Errorf("stored manifest not updated:\n%s", stored)
+	}
+
+	dep, _ := clientset.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+	if dep.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"] == "" || dep.Annotations[changeCauseAnnotation] != "debug logging" {
+		t.Errorf("deployment should be restarted with a change-cause, annotations: %v / %v", dep.Annotations, dep.Spec.Template.Annotations)
+	}
+}
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	"k8s.io/client-go/kubernetes"
+)
+
+// UpdateConfigMapKeyTool provides the update_configmap_key tool for the agent.
+type UpdateConfigMapKeyTool struct {
+	clientset *kubernetes.Clientset
+	manifest  *manifest.Manager
+}
+
+// NewUpdateConfigMapKeyTool creates a new UpdateConfigMapKeyTool.
+func NewUpdateConfigMapKeyTool(clientset *kubernetes.Clientset, manifest *manifest.Manager) *UpdateConfigMapKeyTool {
+	return &UpdateConfigMapKeyTool{
+		clientset: clientset,
+		manifest:  manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *UpdateConfigMapKeyTool) Name() string {
+	return "update_configmap_key"
+}
+
+// Description returns the tool description.
+func (t *UpdateConfigMapKeyTool) Description() string {
+	return "Set or remove a single key in an existing ConfigMap with a strategic merge patch, leaving its other keys alone, and update the stored manifest to match. Lists the deployments using the ConfigMap and can rollout restart them so they pick up the change."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *UpdateConfigMapKeyTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *UpdateConfigMapKeyTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *UpdateConfigMapKeyTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *UpdateConfigMapKeyTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type:       "object",
+			Properties: configKeyProperties("ConfigMap"),
+			Required:   []string{"namespace", "name", "key"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *UpdateConfigMapKeyTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	u, errResult := parseConfigKeyArgs(args, "configmap")
+	if errResult != nil {
+		return errResult, nil
+	}
+	return updateConfigKey(toolContext(ctx), t.clientset, t.manifest, u), nil
+}
@@ -0,0 +1,437 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/perbu/kasa/manifest"
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// Container types accepted by add_container.
+const (
+	containerTypeSidecar       = "sidecar"
+	containerTypeInit          = "init"
+	containerTypeNativeSidecar = "native_sidecar"
+)
+
+// AddContainerTool provides the add_container tool for the agent.
+type AddContainerTool struct {
+	clientset *kubernetes.Clientset
+	manifest  *manifest.Manager
+}
+
+// NewAddContainerTool creates a new AddContainerTool.
+func NewAddContainerTool(clientset *kubernetes.Clientset, manifest *manifest.Manager) *AddContainerTool {
+	return &AddContainerTool{
+		clientset: clientset,
+		manifest:  manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *AddContainerTool) Name() string {
+	return "add_container"
+}
+
+// Description returns the tool description.
+func (t *AddContainerTool) Description() string {
+	return "Add a sidecar or init container to an existing deployment, or replace one added earlier with the same name. Edits the stored manifest in place, so the application container and the rest of the deployment are kept as they are, and applies the change to the cluster. Use this instead of regenerating the deployment with create_deployment."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *AddContainerTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *AddContainerTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *AddContainerTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *AddContainerTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The namespace of the deployment",
+				},
+				"name": {
+					Type:        "string",
+					Description: "The name of the deployment",
+				},
+				"app": {
+					Type:        "string",
+					Description: "Application name of the stored manifest (default: name)",
+				},
+				"container": {
+					Type:        "string",
+					Description: "Name of the container to add",
+				},
+				"image": {
+					Type:        "string",
+					Description: "The container image with tag (e.g., fluent/fluent-bit:3.1)",
+				},
+				"type": {
+					Type:        "string",
+					Description: "sidecar (default; runs alongside the application), init (runs to completion before it starts) or native_sidecar (an init container that keeps running, Kubernetes 1.29+)",
+				},
+				"env": {
+					Type:        "object",
+					Description: "Environment variables as key-value pairs",
+				},
+				"volumes": {
+					Type:        "array",
+					Description: "Volumes to mount. Give only name and mount_path to mount a volume the pod already has (e.g. one shared with the application container); otherwise set one of config_map, secret, pvc or empty_dir to add a new volume",
+					Items: &genai.Schema{
+						Type: "object",
+						Properties: map[string]*genai.Schema{
+							"name":       {Type: "string", Description: "Volume name"},
+							"mount_path": {Type: "string", Description: "Path in the container"},
+							"config_map": {Type: "string", Description: "ConfigMap to mount"},
+							"secret":     {Type: "string", Description: "Secret to mount"},
+							"pvc":        {Type: "string", Description: "PersistentVolumeClaim to mount"},
+							"empty_dir":  {Type: "boolean", Description: "Add an empty scratch directory"},
+							"sub_path":   {Type: "string", Description: "Mount a single key or subdirectory (optional)"},
+							"read_only":  {Type: "boolean", Description: "Mount read-only"},
+						},
+						Required: []string{"mount_path"},
+					},
+				},
+				"command":        deploymentOptionSchemas["command"],
+				"args":           deploymentOptionSchemas["args"],
+				"ports":          deploymentOptionSchemas["ports"],
+				"cpu_request":    deploymentOptionSchemas["cpu_request"],
+				"cpu_limit":      deploymentOptionSchemas["cpu_limit"],
+				"memory_request": deploymentOptionSchemas["memory_request"],
+				"memory_limit":   deploymentOptionSchemas["memory_limit"],
+				"change_cause":   changeCauseSchema,
+			},
+			Required: []string{"namespace", "name", "container", "image"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *AddContainerTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	var a containerAddition
+	a.namespace, _ = argsMap["namespace"].(string)
+	if a.namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+	a.name, _ = argsMap["name"].(string)
+	if a.name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+	a.app = a.name
+	if app, ok := argsMap["app"].(string); ok && app != "" {
+		a.app = app
+	}
+
+	a.container.Name, _ = argsMap["container"].(string)
+	if a.container.Name == "" {
+		return map[string]any{"error": "container is required"}, nil
+	}
+	a.container.Image, _ = argsMap["image"].(string)
+	if a.container.Image == "" {
+		return map[string]any{"error": "image is required"}, nil
+	}
+
+	a.kind = containerTypeSidecar
+	if k, ok := argsMap["type"].(string); ok && k != "" {
+		a.kind = k
+	}
+	switch a.kind {
+	case containerTypeSidecar, containerTypeInit:
+	case containerTypeNativeSidecar:
+		always := corev1.ContainerRestartPolicyAlways
+		a.container.RestartPolicy = &always
+	default:
+		return map[string]any{"error": fmt.Sprintf("type must be sidecar, init or native_sidecar, got %q", a.kind)}, nil
+	}
+
+	if env, ok := argsMap["env"].(map[string]any); ok {
+		for _, k := range sortedKeys(env) {
+			if vs, ok := env[k].(string); ok {
+				a.container.Env = append(a.container.Env, corev1.EnvVar{Name: k, Value: vs})
+			}
+		}
+	}
+	if err := applyContainerOptions(&a.container, argsMap); err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	volumes, _ := argsMap["volumes"].([]any)
+	for _, raw := range volumes {
+		v, _ := raw.(map[string]any)
+		if hasVolumeSource(v) {
+			volume, mount, err := deploymentVolume(v)
+			if err != nil {
+				return map[string]any{"error": err.Error()}, nil
+			}
+			a.volumes = append(a.volumes, volume)
+			a.container.VolumeMounts = append(a.container.VolumeMounts, mount)
+			continue
+		}
+		// A mount of a volume the pod already has
+		mount := corev1.VolumeMount{}
+		mount.Name, _ = v["name"].(string)
+		mount.MountPath, _ = v["mount_path"].(string)
+		mount.SubPath, _ = v["sub_path"].(string)
+		mount.ReadOnly, _ = v["read_only"].(bool)
+		if mount.Name == "" || mount.MountPath == "" {
+			return map[string]any{"error": "each entry in volumes needs a mount_path and either a name or a volume source"}, nil
+		}
+		a.container.VolumeMounts = append(a.container.VolumeMounts, mount)
+	}
+
+	a.cause = changeCause(argsMap, fmt.Sprintf("add_container %s %s to %s", a.kind, a.container.Name, a.name))
+
+	return addContainer(toolContext(ctx), t.clientset, t.manifest, a), nil
+}
+
+// containerAddition describes an add_container call.
+type containerAddition struct {
+	namespace, name string
+	app             string // stored manifest app name
+	kind            string // sidecar, init or native_sidecar
+	container       corev1.Container
+	volumes         []corev1.Volume // new volumes the container mounts
+	cause           string
+}
+
+// list returns the pod spec field the container goes in.
+func (a containerAddition) list() string {
+	if a.kind == containerTypeSidecar {
+		return "containers"
+	}
+	return "initContainers"
+}
+
+// addContainer adds the container and its new volumes to the stored manifest
+// and the live deployment. Returns the tool response.
+func addContainer(ctx context.Context, clientset kubernetes.Interface, mgr *manifest.Manager, a containerAddition) map[string]any {
+	content, err := mgr.ReadManifest(a.namespace, a.app, "deployment")
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("%v (use import_resource to start managing it)", err)}
+	}
+
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	// Check against the live pod spec first; it is what the API server validates
+	dep, err := clientset.AppsV1().Deployments(a.namespace).Get(ctx, a.name, metav1.GetOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to get deployment %s/%s: %v", a.namespace, a.name, err)}
+	}
+	action, err := addPodContainer(&dep.Spec.Template.Spec, a)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+
+	updated, err := manifestAddContainer(content, a)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		dep, err := clientset.AppsV1().Deployments(a.namespace).Get(ctx, a.name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if _, err := addPodContainer(&dep.Spec.Template.Spec, a); err != nil {
+			return err
+		}
+		setChangeCause(ctx, dep, a.cause)
+		_, err = clientset.AppsV1().Deployments(a.namespace).Update(ctx, dep, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to update deployment %s/%s: %v", a.namespace, a.name, err)}
+	}
+
+	result := map[string]any{
+		"namespace": a.namespace,
+		"name":      a.name,
+		"container": a.container.Name,
+		"image":     a.container.Image,
+		"type":      a.kind,
+		"action":    action,
+	}
+	manifestPath, err := mgr.SaveManifest(a.namespace, a.app, "deployment", updated)
+	if err != nil {
+		result["manifest_warning"] = fmt.Sprintf("Updated in the cluster but failed to save manifest: %v", err)
+	} else {
+		result["manifest_path"] = manifestPath
+	}
+
+	message := fmt.Sprintf("Container %s (%s) %s in deployment %s/%s; a rollout has started (use check_deployment_health to follow it)",
+		a.container.Name, a.kind, action, a.namespace, a.name)
+	if mgr.IsDryRun() {
+		result["dry_run"] = true
+		message = fmt.Sprintf("Dry run: would add %s container %s to deployment %s/%s", a.kind, a.container.Name, a.namespace, a.name)
+	}
+	result["success"] = true
+	result["message"] = message
+	return result
+}
+
+// addPodContainer adds the container to spec, replacing one of the same name
+// in the same list, and adds its new volumes. Returns "added" or "replaced".
+func addPodContainer(spec *corev1.PodSpec, a containerAddition) (string, error) {
+	list, other := &spec.Containers, &spec.InitContainers
+	if a.list() == "initContainers" {
+		list, other = other, list
+	}
+	if slices.ContainsFunc(*other, func(c corev1.Container) bool { return c.Name == a.container.Name }) {
+		return "", fmt.Errorf("container %s already exists in %s; pick another name", a.container.Name, otherList(a.list()))
+	}
+	if len(spec.Containers) > 0 && spec.Containers[0].Name == a.container.Name {
+		return "", fmt.Errorf("container %s is the application container; use set_image or set_resources to change it", a.container.Name)
+	}
+
+	for _, v := range a.volumes {
+		i := slices.IndexFunc(spec.Volumes, func(existing corev1.Volume) bool { return existing.Name == v.Name })
+		if i < 0 {
+			spec.Volumes = append(spec.Volumes, v)
+		} else if !volumeSourcesEqual(spec.Volumes[i], v) {
+			return "", fmt.Errorf("the pod already has a different volume named %s; give the new volume another name", v.Name)
+		}
+	}
+	for _, m := range a.container.VolumeMounts {
+		if !slices.ContainsFunc(spec.Volumes, func(v corev1.Volume) bool { return v.Name == m.Name }) {
+			return "", fmt.Errorf("the pod has no volume named %s to mount at %s", m.Name, m.MountPath)
+		}
+	}
+
+	action := "added"
+	if i := slices.IndexFunc(*list, func(c corev1.Container) bool { return c.Name == a.container.Name }); i >= 0 {
+		(*list)[i] = a.container
+		action = "replaced"
+	} else {
+		*list = append(*list, a.container)
+	}
+	return action, nil
+}
+
+// manifestAddContainer applies the same change to a stored deployment
+// manifest, leaving the rest of the document as it is.
+func manifestAddContainer(content []byte, a containerAddition) ([]byte, error) {
+	doc, root, err := parseYAMLDocument(content)
+	if err != nil {
+		return nil, err
+	}
+	spec := []yamlPathSegment{{key: "spec"}, {key: "template"}, {key: "spec"}}
+	if _, err := lookupYAMLPath(root, spec); err != nil {
+		return nil, fmt.Errorf("the stored manifest has no pod template")
+	}
+
+	container, err := yamlNodeOf(a.container)
+	if err != nil {
+		return nil, err
+	}
+	if err := upsertYAMLListItem(root, spec, a.list(), a.container.Name, container); err != nil {
+		return nil, err
+	}
+	for _, v := range a.volumes {
+		volumes := append(append([]yamlPathSegment{}, spec...), yamlPathSegment{key: "volumes"})
+		if node, err := lookupYAMLPath(root, append(volumes, yamlPathSegment{key: "name", value: v.Name, isSelector: true})); err == nil && node != nil {
+			continue
+		}
+		node, err := yamlNodeOf(v)
+		if err != nil {
+			return nil, err
+		}
+		if err := upsertYAMLListItem(root, spec, "volumes", v.Name, node); err != nil {
+			return nil, err
+		}
+	}
+	return encodeYAMLDocument(doc, usesCompactSequences(content))
+}
+
+// upsertYAMLListItem replaces the item with the given name in the list at
+// base.key, or appends it, creating the list if needed.
+func upsertYAMLListItem(root *yaml.Node, base []yamlPathSegment, key, name string, item *yaml.Node) error {
+	listPath := append(append([]yamlPathSegment{}, base...), yamlPathSegment{key: key})
+	list, err := lookupYAMLPath(root, listPath)
+	if err != nil {
+		_, err := setYAMLPath(root, listPath, &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{item}})
+		return err
+	}
+	if list.Kind != yaml.SequenceNode {
+		return fmt.Errorf("%s in the stored manifest is not a list", formatYAMLPath(listPath))
+	}
+	itemPath := append(listPath, yamlPathSegment{key: "name", value: name, isSelector: true})
+	if existing, _ := lookupYAMLPath(root, itemPath); existing == nil {
+		itemPath = append(listPath, yamlPathSegment{index: len(list.Content), isIndex: true})
+	}
+	_, err = setYAMLPath(root, itemPath, item)
+	return err
+}
+
+// yamlNodeOf converts an API object to a block-style YAML node.
+func yamlNodeOf(obj any) (*yaml.Node, error) {
+	out, err := sigsyaml.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return parseYAMLValue(string(out))
+}
+
+// volumeSourcesEqual reports whether two volumes have the same source.
+func volumeSourcesEqual(a, b corev1.Volume) bool {
+	ja, _ := json.Marshal(a.VolumeSource)
+	jb, _ := json.Marshal(b.VolumeSource)
+	return string(ja) == string(jb)
+}
+
+// hasVolumeSource reports whether a volumes entry names a source to create
+// the volume from, rather than an existing pod volume.
+func hasVolumeSource(v map[string]any) bool {
+	for _, key := range []string{"config_map", "secret", "pvc"} {
+		if s, _ := v[key].(string); s != "" {
+			return true
+		}
+	}
+	emptyDir, _ := v["empty_dir"].(bool)
+	return emptyDir
+}
+
+// otherList names the container list a container is not going into.
+func otherList(list string) string {
+	if list == "containers" {
+		return "initContainers"
+	}
+	return "containers"
+}
@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const addContainerTestDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.27 # pinned
+        volumeMounts:
+        - name: logs
+          mountPath: /var/log/nginx
+      volumes:
+      - name: logs
+        emptyDir: {}
+`
+
+func addContainerFixture(t *testing.T) *fake.Clientset {
+	t.Helper()
+	return fake.NewSimpleClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:         "web",
+				Image:        "nginx:1.27",
+				VolumeMounts: []corev1.VolumeMount{{Name: "logs", MountPath: "/var/log/nginx"}},
+			}},
+			Volumes: []corev1.Volume{{Name: "logs", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+		}}},
+	})
+}
+
+func TestAddContainer(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	if _, err := mgr.SaveManifest("default", "web", "deployment", []byte(addContainerTestDeployment)); err != nil {
+		t.Fatal(err)
+	}
+	clientset := addContainerFixture(t)
+
+	sidecar := containerAddition{
+		namespace: "default", name: "web", app: "web", kind: containerTypeSidecar, cause: "ship logs",
+		container: corev1.Container{
+			Name:         "log-shipper",
+			Image:        "fluent/fluent-bit:3.1",
+			VolumeMounts: []corev1.VolumeMount{{Name: "logs", MountPath: "/logs", ReadOnly: true}, {Name: "configmap-fluent-bit", MountPath: "/fluent-bit/etc", ReadOnly: true}},
+		},
+		volumes: []corev1.Volume{{Name: "configmap-fluent-bit", VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "fluent-bit"}},
+		}}},
+	}
+	result := addContainer(context.Background(), clientset, mgr, sidecar)
+	if ok, _ := result["success"].(bool); !ok || result["action"] != "added" {
+		t.Fatalf("addContainer failed: %v", result)
+	}
+
+	dep, _ := clientset.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+	spec := dep.Spec.Template.Spec
+	if len(spec.Containers) != 2 || spec.Containers[0].Name != "web" || spec.Containers[1].Name != "log-shipper" {
+		t.Errorf("live containers = %+v", spec.Containers)
+	}
+	if len(spec.Volumes) != 2 {
+		t.Errorf("live volumes = %+v", spec.Volumes)
+	}
+	if dep.Annotations[changeCauseAnnotation] != "ship logs" {
+		t.Errorf("change-cause = %q", dep.Annotations[changeCauseAnnotation])
+	}
+
+	stored, _ := mgr.ReadManifest("default", "web", "deployment")
+	for _, want := range []string{
+		"image: nginx:1.27 # pinned",
+		"      - image: fluent/fluent-bit:3.1\n        name: log-shipper\n",
+		"      - name: logs\n        emptyDir: {}\n",
+		"      - configMap:\n          name: fluent-bit\n        name: configmap-fluent-bit\n",
+	} {
+		if !strings.Contains(string(stored), want) {
+			t.Errorf("stored manifest lacks %q:\n%s", want, stored)
+		}
+	}
+
+	// An init container goes in a new initContainers list
+	always := corev1.ContainerRestartPolicyAlways
+	native := containerAddition{
+		namespace: "default", name: "web", app: "web", kind: containerTypeNativeSidecar,
+		container: corev1.Container{Name: "proxy", Image: "envoyproxy/envoy:v1.31", RestartPolicy: &always},
+	}
+	if result := addContainer(context.Background(), clientset, mgr, native); result["success"] != true {
+		t.Fatalf("addContainer init failed: %v", result)
+	}
+	stored, _ = mgr.ReadManifest("default", "web", "deployment")
+	if !strings.Contains(string(stored), "      initContainers:\n      - image: envoyproxy/envoy:v1.31\n        name: proxy\n") || !strings.Contains(string(stored), "restartPolicy: Always") {
+		t.Errorf("stored manifest lacks the init container:\n%s", stored)
+	}
+
+	// Adding the same sidecar again replaces it rather than duplicating it
+	sidecar.container.Image = "fluent/fluent-bit:3.2"
+	if result := addContainer(context.Background(), clientset, mgr, sidecar); result["action"] != "replaced" {
+		t.Fatalf("addContainer again = %v, want replaced", result)
+	}
+	stored, _ = mgr.ReadManifest("default", "web", "deployment")
+	if strings.Count(string(stored), "name: log-shipper") != 1 || !strings.Contains(string(stored), "fluent-bit:3.2") {
+		t.Errorf("sidecar should be replaced in place:\n%s", stored)
+	}
+}
+
+func TestAddContainerRejected(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	if _, err := mgr.SaveManifest("default", "web", "deployment", []byte(addContainerTestDeployment)); err != nil {
+		t.Fatal(err)
+	}
+	clientset := addContainerFixture(t)
+
+	for _, tt := range []struct {
+		name string
+		a    containerAddition
+		want string
+	}{
+		{
+			name: "application container",
+			a:    containerAddition{kind: containerTypeSidecar, container: corev1.Container{Name: "web", Image: "nginx:1.28"}},
+			want: "application container",
+		},
+		{
+			name: "unknown volume",
+			a: containerAddition{kind: containerTypeInit, container: corev1.Container{
+				Name: "migrate", Image: "app:1", VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+			}},
+			want: "no volume named data",
+		},
+		{
+			name: "conflicting volume",
+			a: containerAddition{kind: containerTypeSidecar, container: corev1.Container{Name: "shipper", Image: "app:1"},
+				volumes: []corev1.Volume{{Name: "logs", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "logs"}}}}},
+			want: "different volume named logs",
+		},
+	} {
+		tt.a.namespace, tt.a.name, tt.a.app = "default", "web", "web"
+		result := addContainer(context.Background(), clientset, mgr, tt.a)
+		if errMsg, _ := result["error"].(string); !strings.Contains(errMsg, tt.want) {
+			t.Errorf("%s: error = %q, want it to mention %q", tt.name, errMsg, tt.want)
+		}
+	}
+
+	stored, _ := mgr.ReadManifest("default", "web", "deployment")
+	if string(stored) != addContainerTestDeployment {
+		t.Errorf("stored manifest changed by rejected calls:\n%s", stored)
+	}
+}
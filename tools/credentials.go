@@ -0,0 +1,251 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/adk/tool"
+	"k8s.io/client-go/rest"
+)
+
+// CredentialRefresher recovers from cluster credentials that expire during
+// a long session, such as OIDC tokens and those of exec plugins
+// (aws eks get-token, gke-gcloud-auth-plugin, kubelogin).
+//
+// Requests the API server rejects with 401 Unauthorized are retried, first
+// once the exec plugin has fetched new credentials (client-go does that on
+// a 401), then with the credentials of a freshly loaded kubeconfig. If they
+// are still rejected, the tool call fails with an error saying so, and the
+// middleware asks the user to log in again, if someone can be asked, and
+// runs the call again if it is read-only.
+type CredentialRefresher struct {
+	reload func() (*rest.Config, error)
+	prompt func(ctx context.Context, reason string) bool
+
+	refreshing sync.Mutex // held while reloading or asking the user
+
+	mu      sync.Mutex
+	fresh   *rest.Config // credentials of the latest reload; nil until then
+	epoch   int          // incremented on every reload
+	lastErr error        // why the latest reload failed
+}
+
+// authFailureKey holds, in the context of a tool call, the flag its
+// requests set when they stay unauthorized.
+type authFailureKey struct{}
+
+// NewCredentialRefresher returns a refresher that gets new credentials from
+// reload, which should load the kubeconfig again.
+func NewCredentialRefresher(reload func() (*rest.Config, error)) *CredentialRefresher {
+	return &CredentialRefresher{reload: reload}
+}
+
+// SetPrompt registers how to ask the user to log in to the cluster again.
+// prompt returns once the user has done so, or false if they won't; the
+// interactive REPL sets it, the server and single-prompt modes don't.
+func (r *CredentialRefresher) SetPrompt(prompt func(ctx context.Context, reason string) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prompt = prompt
+}
+
+// SetCredentialRefresher makes tool calls that failed because the cluster
+// credentials expired ask the user to log in again and run once more.
+func (k *KubeTools) SetCredentialRefresher(r *CredentialRefresher) {
+	k.credentials = r
+}
+
+// HTTPClient returns the HTTP client for clients created from config (see
+// kubernetes.NewForConfigAndClient), which retries unauthorized requests
+// with refreshed credentials. A nil refresher returns a plain client.
+func (r *CredentialRefresher) HTTPClient(config *rest.Config) (*http.Client, error) {
+	if r == nil {
+		return rest.HTTPClientFor(config)
+	}
+	rt, err := rest.TransportFor(config)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: &reauthTransport{refresher: r, config: rest.CopyConfig(config), rt: rt},
+		Timeout:   config.Timeout,
+	}, nil
+}
+
+// state returns the latest credentials and their epoch.
+func (r *CredentialRefresher) state() (fresh *rest.Config, epoch int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fresh, r.epoch
+}
+
+// refresh loads the kubeconfig again, unless that already happened since
+// epoch seen. With ask set, the user is asked to log in first. One refresh
+// runs at a time; requests keep using the old credentials meanwhile.
+func (r *CredentialRefresher) refresh(ctx context.Context, seen int, ask bool) bool {
+	r.refreshing.Lock()
+	defer r.refreshing.Unlock()
+	r.mu.Lock()
+	epoch, prompt, lastErr := r.epoch, r.prompt, r.lastErr
+	r.mu.Unlock()
+	if epoch != seen {
+		return true
+	}
+	if ask {
+		if prompt == nil {
+			return false
+		}
+		reason := "the Kubernetes API server rejected kasa's credentials (401 Unauthorized); they have probably expired"
+		if lastErr != nil {
+			reason += fmt.Sprintf(" (reloading the kubeconfig failed: %v)", lastErr)
+		}
+		if !prompt(ctx, reason) {
+			return false
+		}
+	}
+
+	fresh, err := r.reload()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.lastErr = err
+		return false
+	}
+	r.fresh, r.lastErr = fresh, nil
+	r.epoch++
+	return true
+}
+
+// failed records a request that stayed unauthorized with the tool call it
+// was made for.
+func (r *CredentialRefresher) failed(ctx context.Context) error {
+	if failed, ok := ctx.Value(authFailureKey{}).(*atomic.Bool); ok {
+		failed.Store(true)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &unauthorizedError{reloadErr: r.lastErr}
+}
+
+// middleware asks the user to log in again if a request of a tool call
+// stayed unauthorized, and then runs a read-only call again. Other calls may
+// have changed something before the failing request, which running them
+// again would repeat, so the model is told to check before retrying.
+func (r *CredentialRefresher) middleware(t ToolInfo, next RunFunc) RunFunc {
+	return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		// Only the call's own requests count: other sessions' calls may
+		// fail meanwhile, and have to ask themselves
+		failed := new(atomic.Bool)
+		result, err := next(WithContext(ctx, context.WithValue(toolContext(ctx), authFailureKey{}, failed)), args)
+		if !failed.Load() {
+			return result, err
+		}
+		_, epoch := r.state()
+		if !r.refresh(toolContext(ctx), epoch, true) {
+			return result, err
+		}
+		if t.Category == CategoryReadOnly {
+			return next(ctx, args)
+		}
+		if result == nil {
+			if err == nil {
+				return nil, nil
+			}
+			result, err = map[string]any{"error": err.Error()}, nil
+		}
+		result["credentials_refreshed"] = fmt.Sprintf("The user has logged in again, but %s was not run again: "+
+			"it may have made some of its changes before its credentials were rejected. "+
+			"Check the current state of what it changes, then call it again for what is left", t.Name)
+		return result, err
+	}
+}
+
+// reauthTransport retries requests rejected with 401 Unauthorized with
+// refreshed credentials. It wraps the complete transport of a client, as
+// the exec plugin only refreshes its credentials once a rejected response
+// has passed through it.
+type reauthTransport struct {
+	refresher *CredentialRefresher
+	config    *rest.Config // the client's config, for rebuilding rt
+
+	mu    sync.Mutex
+	rt    http.RoundTripper
+	epoch int // of the credentials rt uses
+}
+
+func (t *reauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt, epoch := t.current()
+	resp, err := rt.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !replayable(req) {
+		return resp, err
+	}
+
+	// Retry once with whatever the exec plugin fetched on the 401, then
+	// reload the kubeconfig, which a login elsewhere may have updated, and
+	// retry twice: the exec plugin may need one more 401 to pick it up.
+	for _, tries := range []int{1, 2} {
+		if tries == 2 {
+			if !t.refresher.refresh(req.Context(), epoch, false) {
+				break
+			}
+			rt, epoch = t.current()
+		}
+		for range tries {
+			discard(resp)
+			r, err := replay(req)
+			if err != nil {
+				return nil, err
+			}
+			resp, err = rt.RoundTrip(r)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+		}
+	}
+	discard(resp)
+	return nil, t.refresher.failed(req.Context())
+}
+
+// current returns the transport to use, rebuilt with the latest credentials
+// after a reload.
+func (t *reauthTransport) current() (http.RoundTripper, int) {
+	fresh, epoch := t.refresher.state()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if epoch != t.epoch && fresh != nil {
+		if rt, err := rest.TransportFor(withCredentials(t.config, fresh)); err == nil {
+			t.rt = rt
+		}
+		t.epoch = epoch
+	}
+	return t.rt, t.epoch
+}
+
+// withCredentials returns a copy of config that authenticates like fresh.
+func withCredentials(config, fresh *rest.Config) *rest.Config {
+	c := rest.CopyConfig(config)
+	c.BearerToken, c.BearerTokenFile = fresh.BearerToken, fresh.BearerTokenFile
+	c.Username, c.Password = fresh.Username, fresh.Password
+	c.ExecProvider, c.AuthProvider, c.AuthConfigPersister = fresh.ExecProvider, fresh.AuthProvider, fresh.AuthConfigPersister
+	c.CertFile, c.KeyFile = fresh.CertFile, fresh.KeyFile
+	c.CertData, c.KeyData = fresh.CertData, fresh.KeyData
+	return c
+}
+
+// unauthorizedError is returned when the API server kept rejecting kasa's
+// credentials.
+type unauthorizedError struct {
+	reloadErr error
+}
+
+func (e *unauthorizedError) Error() string {
+	msg := "the Kubernetes API server rejected kasa's credentials (401 Unauthorized) even after refreshing them; " +
+		"the login to the cluster has probably expired"
+	if e.reloadErr != nil {
+		msg += fmt.Sprintf(" (reloading the kubeconfig failed: %v)", e.reloadErr)
+	}
+	return msg + ". Ask the user to log in again (e.g. with their cloud provider's CLI or kubectl oidc-login), then retry the call"
+}
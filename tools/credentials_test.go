@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/adk/tool"
+	"k8s.io/client-go/rest"
+)
+
+func TestCredentialRefresher(t *testing.T) {
+	// The API server accepts only the current token
+	var valid atomic.Value
+	valid.Store("token-1")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+valid.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	}))
+	t.Cleanup(srv.Close)
+
+	// The kubeconfig holds whatever token the last login wrote
+	var kubeconfigToken atomic.Value
+	kubeconfigToken.Store("token-1")
+	refresher := NewCredentialRefresher(func() (*rest.Config, error) {
+		return &rest.Config{Host: srv.URL, BearerToken: kubeconfigToken.Load().(string)}, nil
+	})
+	client, err := refresher.HTTPClient(&rest.Config{Host: srv.URL, BearerToken: "token-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(ctx context.Context) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/v1/pods", nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// A login elsewhere updated the kubeconfig: picked up transparently
+	valid.Store("token-2")
+	kubeconfigToken.Store("token-2")
+	if err := get(context.Background()); err != nil {
+		t.Fatalf("expected the reloaded credentials to be used, got %v", err)
+	}
+
+	// Expired everywhere: the call fails with an explanation, and the
+	// middleware asks the user to log in and runs it again
+	valid.Store("token-3")
+	prompts := 0
+	refresher.SetPrompt(func(ctx context.Context, reason string) bool {
+		prompts++
+		kubeconfigToken.Store("token-3")
+		return true
+	})
+	var errs []string
+	runTool := func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		if err := get(toolContext(ctx)); err != nil {
+			errs = append(errs, err.Error())
+			return map[string]any{"error": err.Error()}, nil
+		}
+		return map[string]any{"success": true}, nil
+	}
+	run := refresher.middleware(ToolInfo{Name: "list_pods", Category: CategoryReadOnly}, runTool)
+	result, _ := run(nil, map[string]any{})
+	if result["success"] != true || prompts != 1 {
+		t.Fatalf("expected one prompt and a successful retry, got %v after %d prompts", result, prompts)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0], "401 Unauthorized") || !strings.Contains(errs[0], "log in again") {
+		t.Errorf("the first attempt should explain the expired login, got %q", errs)
+	}
+
+	// A mutating call isn't run again, as it may have changed something
+	// before its credentials were rejected
+	valid.Store("token-4")
+	refresher.SetPrompt(func(ctx context.Context, reason string) bool {
+		kubeconfigToken.Store("token-4")
+		return true
+	})
+	errs = nil
+	result, _ = refresher.middleware(ToolInfo{Name: "set_image", Category: CategoryMutating}, runTool)(nil, map[string]any{})
+	if len(errs) != 1 || result["success"] == true {
+		t.Errorf("expected the mutating call to run once, got %d failed runs and %v", len(errs), result)
+	}
+	if note, _ := result["credentials_refreshed"].(string); !strings.Contains(note, "set_image was not run again") || result["error"] == nil {
+		t.Errorf("expected the error and a note that set_image wasn't run again, got %v", result)
+	}
+	if result, _ = run(nil, map[string]any{}); result["success"] != true {
+		t.Errorf("expected the next call to use the new login, got %v", result)
+	}
+
+	// Requests made for something else, such as another session's call,
+	// failing meanwhile don't make a call that succeeded ask
+	prompts = 0
+	refresher.SetPrompt(func(ctx context.Context, reason string) bool {
+		prompts++
+		return true
+	})
+	valid.Store("token-5")
+	result, _ = refresher.middleware(ToolInfo{Name: "get_events", Category: CategoryReadOnly}, func(tool.Context, map[string]any) (map[string]any, error) {
+		if err := get(context.Background()); err == nil {
+			t.Error("expected the other request to stay unauthorized")
+		}
+		return map[string]any{"success": true}, nil
+	})(nil, map[string]any{})
+	if result["success"] != true || prompts != 0 {
+		t.Errorf("expected the call to succeed without asking, got %v after %d prompts", result, prompts)
+	}
+
+	// Nobody to ask: the explanation reaches the model
+	refresher.SetPrompt(nil)
+	result, _ = run(nil, map[string]any{})
+	if msg, _ := result["error"].(string); !strings.Contains(msg, "401 Unauthorized") {
+		t.Errorf("expected the unauthorized error, got %v", result)
+	}
+}
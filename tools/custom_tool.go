@@ -0,0 +1,273 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// defaultCustomToolTimeout bounds a custom tool's command or request.
+const defaultCustomToolTimeout = time.Minute
+
+// CustomToolConfig declares a team-specific tool, backed by a shell command
+// or an HTTP endpoint, that the agent can call like any other.
+type CustomToolConfig struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Parameters is the JSON schema of the arguments (type: object).
+	Parameters map[string]any `yaml:"parameters"`
+	// Command runs with sh -c, the arguments as JSON on stdin and each
+	// top-level argument in $KASA_ARG_<NAME>.
+	Command string `yaml:"command"`
+	// URL receives the arguments as a JSON POST.
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	// ReadOnly tools run without plan approval; others are mutating.
+	ReadOnly bool          `yaml:"read_only"`
+	Timeout  time.Duration `yaml:"timeout"` // default 1m
+}
+
+var customToolName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Validate checks that the tool has a usable name, schema and backend.
+func (c CustomToolConfig) Validate() error {
+	if !customToolName.MatchString(c.Name) {
+		return fmt.Errorf("name %q must be lowercase letters, digits and underscores", c.Name)
+	}
+	if strings.TrimSpace(c.Description) == "" {
+		return fmt.Errorf("%s: description is required; the agent decides when to call the tool from it", c.Name)
+	}
+	if (c.Command == "") == (c.URL == "") {
+		return fmt.Errorf("%s: set exactly one of command and url", c.Name)
+	}
+	if c.URL != "" && !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+		return fmt.Errorf("%s: url must start with http:// or https://", c.Name)
+	}
+	if _, err := c.schema(); err != nil {
+		return fmt.Errorf("%s: parameters: %w", c.Name, err)
+	}
+	return nil
+}
+
+// schema converts the JSON schema of the parameters.
+func (c CustomToolConfig) schema() (*genai.Schema, error) {
+	if c.Parameters == nil {
+		return &genai.Schema{Type: "object", Properties: map[string]*genai.Schema{}}, nil
+	}
+	data, err := json.Marshal(c.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	var schema genai.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	if strings.ToLower(string(schema.Type)) != "object" {
+		return nil, fmt.Errorf("type must be object, got %q", schema.Type)
+	}
+	return &schema, nil
+}
+
+// SetCustomTools registers the tools declared in configs alongside the
+// built-in ones.
+func (k *KubeTools) SetCustomTools(configs []CustomToolConfig) error {
+	builtin := make(map[string]bool)
+	for _, t := range k.baseTools() {
+		builtin[t.Name()] = true
+	}
+	seen := make(map[string]bool)
+	custom := make([]*CustomTool, 0, len(configs))
+	for _, c := range configs {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		if builtin[c.Name] || seen[c.Name] {
+			return fmt.Errorf("%s: a tool with this name already exists", c.Name)
+		}
+		seen[c.Name] = true
+		custom = append(custom, NewCustomTool(c))
+	}
+	k.customTools = custom
+	return nil
+}
+
+// IsCustom reports whether t, as returned by All, was declared in the
+// config rather than built in.
+func IsCustom(t tool.Tool) bool {
+	if chained, ok := t.(*chainedTool); ok {
+		t = chained.runnableTool
+	}
+	_, ok := t.(*CustomTool)
+	return ok
+}
+
+// CustomTool runs a tool declared in the config.
+type CustomTool struct {
+	config CustomToolConfig
+	schema *genai.Schema
+	client *http.Client
+}
+
+// NewCustomTool creates a CustomTool from a validated config.
+func NewCustomTool(config CustomToolConfig) *CustomTool {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultCustomToolTimeout
+	}
+	schema, _ := config.schema()
+	return &CustomTool{config: config, schema: schema, client: &http.Client{Timeout: config.Timeout}}
+}
+
+// Name returns the tool name.
+func (t *CustomTool) Name() string {
+	return t.config.Name
+}
+
+// Description returns the tool description.
+func (t *CustomTool) Description() string {
+	return t.config.Description
+}
+
+// IsLongRunning returns false.
+func (t *CustomTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *CustomTool) Category() ToolCategory {
+	if t.config.ReadOnly {
+		return CategoryReadOnly
+	}
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *CustomTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *CustomTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters:  t.schema,
+	}
+}
+
+// Run executes the command or calls the endpoint.
+func (t *CustomTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		return map[string]any{"error": "invalid arguments"}, nil
+	}
+	body, err := json.Marshal(argsMap)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("encoding arguments: %v", err)}, nil
+	}
+
+	runCtx, cancel := context.WithTimeout(toolContext(ctx), t.config.Timeout)
+	defer cancel()
+	if t.config.URL != "" {
+		return t.post(runCtx, body), nil
+	}
+	return t.exec(runCtx, argsMap, body), nil
+}
+
+// exec runs the command with the arguments on stdin and in the environment.
+func (t *CustomTool) exec(ctx context.Context, args map[string]any, body []byte) map[string]any {
+	cmd := exec.CommandContext(ctx, "sh", "-c", t.config.Command)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(), "KASA_ARGS="+string(body))
+	for name, value := range args {
+		env := "KASA_ARG_" + strings.ToUpper(name)
+		switch v := value.(type) {
+		case string:
+			cmd.Env = append(cmd.Env, env+"="+v)
+		default:
+			encoded, _ := json.Marshal(v)
+			cmd.Env = append(cmd.Env, env+"="+string(encoded))
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	// Don't wait for background processes holding the output open
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	result := map[string]any{"tool": t.Name()}
+	addOutput(result, stdout.Bytes())
+	if s := strings.TrimSpace(stderr.String()); s != "" {
+		result["stderr"] = s
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result["error"] = fmt.Sprintf("command timed out after %s", t.config.Timeout)
+	case errors.As(err, &exitErr):
+		result["error"] = fmt.Sprintf("command failed with exit code %d", exitErr.ExitCode())
+		result["exit_code"] = exitErr.ExitCode()
+	case err != nil:
+		result["error"] = fmt.Sprintf("running command: %v", err)
+	default:
+		result["success"] = true
+	}
+	return result
+}
+
+// post sends the arguments to the endpoint.
+func (t *CustomTool) post(ctx context.Context, body []byte) map[string]any {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("creating request: %v", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range t.config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("request failed: %v", err)}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("reading response: %v", err)}
+	}
+
+	result := map[string]any{"tool": t.Name(), "status_code": resp.StatusCode}
+	addOutput(result, data)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		result["error"] = fmt.Sprintf("%s returned %s", t.config.URL, resp.Status)
+	} else {
+		result["success"] = true
+	}
+	return result
+}
+
+// addOutput adds output to result: parsed if it is JSON, as text otherwise.
+func addOutput(result map[string]any, output []byte) {
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 {
+		return
+	}
+	var parsed any
+	if json.Unmarshal(output, &parsed) == nil {
+		result["result"] = parsed
+		return
+	}
+	result["output"] = string(output)
+}
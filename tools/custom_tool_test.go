@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/tool"
+)
+
+func TestCustomTool(t *testing.T) {
+	params := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"database": map[string]any{"type": "string", "description": "Database to rotate"},
+			"force":    map[string]any{"type": "boolean"},
+		},
+		"required": []any{"database"},
+	}
+
+	rotate := NewCustomTool(CustomToolConfig{
+		Name:        "rotate_db_creds",
+		Description: "Rotate database credentials",
+		Parameters:  params,
+		Command:     `echo "{\"rotated\": \"$KASA_ARG_DATABASE\", \"force\": $KASA_ARG_FORCE}"`,
+	})
+	if rotate.Category() != CategoryMutating {
+		t.Errorf("custom tools should be mutating unless read_only, got %s", rotate.Category())
+	}
+	if decl := rotate.Declaration(); decl.Parameters.Properties["database"].Type != "string" || decl.Parameters.Required[0] != "database" {
+		t.Errorf("unexpected declaration %+v", decl.Parameters)
+	}
+	result, _ := rotate.Run(nil, map[string]any{"database": "orders", "force": true})
+	if result["success"] != true {
+		t.Fatalf("expected success, got %v", result)
+	}
+	if got, _ := result["result"].(map[string]any); got["rotated"] != "orders" || got["force"] != true {
+		t.Errorf("expected the JSON output to be parsed, got %v", result)
+	}
+
+	failing := NewCustomTool(CustomToolConfig{Name: "fail", Description: "Fails", Command: "cat; echo oops >&2; exit 3"})
+	result, _ = failing.Run(nil, map[string]any{"a": "b"})
+	if result["exit_code"] != 3 || result["stderr"] != "oops" || result["result"] == nil {
+		t.Errorf("expected exit code 3 with stdin echoed and stderr, got %v", result)
+	}
+
+	var received map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		_, _ = w.Write([]byte("queued"))
+	}))
+	t.Cleanup(srv.Close)
+	hook := NewCustomTool(CustomToolConfig{
+		Name: "open_ticket", Description: "Open a ticket", ReadOnly: true,
+		URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer s3cret"},
+	})
+	result, _ = hook.Run(nil, map[string]any{"title": "disk full"})
+	if result["success"] != true || result["output"] != "queued" || received["title"] != "disk full" {
+		t.Errorf("expected the arguments to be posted, got %v (received %v)", result, received)
+	}
+
+	k := &KubeTools{manifest: newTestManifestManager(t)}
+	for _, tt := range []struct {
+		config CustomToolConfig
+		err    string
+	}{
+		{CustomToolConfig{Name: "Rotate", Description: "x", Command: "true"}, "lowercase"},
+		{CustomToolConfig{Name: "rotate", Command: "true"}, "description"},
+		{CustomToolConfig{Name: "rotate", Description: "x"}, "exactly one"},
+		{CustomToolConfig{Name: "rotate", Description: "x", Command: "true", Parameters: map[string]any{"type": "string"}}, "type must be object"},
+		{CustomToolConfig{Name: "list_pods", Description: "x", Command: "true"}, "already exists"},
+	} {
+		err := k.SetCustomTools([]CustomToolConfig{tt.config})
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%+v: expected an error containing %q, got %v", tt.config, tt.err, err)
+		}
+	}
+}
+
+func TestCustomToolPolicies(t *testing.T) {
+	touch := filepath.Join(t.TempDir(), "ran")
+	restart := NewCustomTool(CustomToolConfig{Name: "restart_queue", Description: "Restart the queue", Command: "touch " + touch})
+	ran := func() bool {
+		_, err := os.Stat(touch)
+		return err == nil
+	}
+
+	g := &dryRunGuard{policy: DryRunPolicy{Namespaces: []string{"prod"}}, confirmed: make(map[string]int)}
+	wrapped := withMiddleware([]tool.Tool{restart}, g.middleware(nil))[0]
+	if !IsCustom(wrapped) {
+		t.Error("expected the wrapped tool to be reported as custom")
+	}
+	args := map[string]any{"namespace": "prod", "queue": "orders"}
+	result, _ := wrapped.(runnableTool).Run(nil, args)
+	if result["confirmation_required"] != true || ran() {
+		t.Fatalf("expected the custom tool to wait for confirmation without running, got %v", result)
+	}
+	g.confirm("restart_queue", "prod", result["confirmation_key"].(string))
+	if result, _ = wrapped.(runnableTool).Run(nil, args); result["success"] != true || !ran() {
+		t.Fatalf("expected the confirmed call to run, got %v", result)
+	}
+
+	protect := &protectGuard{policy: ProtectionPolicy{Names: []string{"kube-*"}}}
+	wrapped = withMiddleware([]tool.Tool{NewCustomTool(restart.config)}, protect.middleware)[0]
+	if result, _ = wrapped.(runnableTool).Run(nil, args); result["protected"] != true {
+		t.Errorf("expected a mutating custom tool to be refused under a protection policy, got %v", result)
+	}
+	readOnly := restart.config
+	readOnly.ReadOnly = true
+	wrapped = withMiddleware([]tool.Tool{NewCustomTool(readOnly)}, protect.middleware)[0]
+	if result, _ = wrapped.(runnableTool).Run(nil, args); result["success"] != true {
+		t.Errorf("expected a read-only custom tool to run, got %v", result)
+	}
+}
@@ -1,10 +1,9 @@
 package tools
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
-	"time"
+	"maps"
 
 	"github.com/perbu/kasa/manifest"
 	"google.golang.org/adk/model"
@@ -21,15 +20,18 @@ import (
 
 // CreateDeploymentTool provides the create_deployment tool for the agent.
 type CreateDeploymentTool struct {
-	clientset *kubernetes.Clientset
-	manifest  *manifest.Manager
+	clientset  *kubernetes.Clientset
+	manifest   *manifest.Manager
+	pinDigests bool // default for pin_digest
 }
 
-// NewCreateDeploymentTool creates a new CreateDeploymentTool.
-func NewCreateDeploymentTool(clientset *kubernetes.Clientset, manifest *manifest.Manager) *CreateDeploymentTool {
+// NewCreateDeploymentTool creates a new CreateDeploymentTool. With
+// pinDigests, images are deployed by digest unless pin_digest is false.
+func NewCreateDeploymentTool(clientset *kubernetes.Clientset, manifest *manifest.Manager, pinDigests bool) *CreateDeploymentTool {
 	return &CreateDeploymentTool{
-		clientset: clientset,
-		manifest:  manifest,
+		clientset:  clientset,
+		manifest:   manifest,
+		pinDigests: pinDigests,
 	}
 }
 
@@ -40,7 +42,7 @@ func (t *CreateDeploymentTool) Name() string {
 
 // Description returns the tool description.
 func (t *CreateDeploymentTool) Description() string {
-	return "Create or update a Kubernetes deployment. Saves the manifest to git and applies it to the cluster."
+	return "Create or update a Kubernetes deployment. Optionally sets resource requests and limits, command and args, extra ports, volumes from ConfigMaps, Secrets or PVCs, node selector and tolerations, security context and rollout strategy. Saves the manifest to git and applies it to the cluster."
 }
 
 // IsLongRunning returns false as this is a quick operation.
@@ -60,42 +62,55 @@ func (t *CreateDeploymentTool) ProcessRequest(ctx tool.Context, req *model.LLMRe
 
 // Declaration returns the function declaration for the tool.
 func (t *CreateDeploymentTool) Declaration() *genai.FunctionDeclaration {
+	properties := map[string]*genai.Schema{
+		"name": {
+			Type:        "string",
+			Description: "The name of the deployment",
+		},
+		"namespace": {
+			Type:        "string",
+			Description: "The target Kubernetes namespace",
+		},
+		"image": {
+			Type:        "string",
+			Description: "The container image with tag (e.g., nginx:1.25)",
+		},
+		"replicas": {
+			Type:        "integer",
+			Description: "Number of replicas (default: 1)",
+		},
+		"port": {
+			Type:        "integer",
+			Description: "Container port to expose",
+		},
+		"health_path": {
+			Type:        "string",
+			Description: "HTTP path for health checks (e.g., /health)",
+		},
+		"env": {
+			Type:        "object",
+			Description: "Environment variables as key-value pairs",
+		},
+		"service_account": {
+			Type:        "string",
+			Description: "ServiceAccount the pods run as (default: the namespace's default account)",
+		},
+		"image_pull_secret": {
+			Type:        "string",
+			Description: "Secret with registry credentials for a private image (see create_image_pull_secret)",
+		},
+		"pin_digest":   pinDigestSchema,
+		"change_cause": changeCauseSchema,
+	}
+	maps.Copy(properties, deploymentOptionSchemas)
+
 	return &genai.FunctionDeclaration{
 		Name:        t.Name(),
 		Description: t.Description(),
 		Parameters: &genai.Schema{
-			Type: "object",
-			Properties: map[string]*genai.Schema{
-				"name": {
-					Type:        "string",
-					Description: "The name of the deployment",
-				},
-				"namespace": {
-					Type:        "string",
-					Description: "The target Kubernetes namespace",
-				},
-				"image": {
-					Type:        "string",
-					Description: "The container image with tag (e.g., nginx:1.25)",
-				},
-				"replicas": {
-					Type:        "integer",
-					Description: "Number of replicas (default: 1)",
-				},
-				"port": {
-					Type:        "integer",
-					Description: "Container port to expose",
-				},
-				"health_path": {
-					Type:        "string",
-					Description: "HTTP path for health checks (e.g., /health)",
-				},
-				"env": {
-					Type:        "object",
-					Description: "Environment variables as key-value pairs",
-				},
-			},
-			Required: []string{"name", "namespace", "image"},
+			Type:       "object",
+			Properties: properties,
+			Required:   []string{"name", "namespace", "image"},
 		},
 	}
 }
@@ -130,6 +145,18 @@ func (t *CreateDeploymentTool) Run(ctx tool.Context, args any) (map[string]any,
 		return map[string]any{"error": "image is required"}, nil
 	}
 
+	// Deploy by digest if asked; a tag that can't be resolved is deployed
+	// as is, with a warning
+	var pinWarning string
+	if pinDigestArg(argsMap, t.pinDigests) {
+		pinned, err := newImageChecker(t.clientset).pinImage(toolContext(ctx), t.Name(), argsMap)
+		if err != nil {
+			pinWarning = fmt.Sprintf("deployed %s by tag, not digest: %v", image, err)
+		} else {
+			image = pinned
+		}
+	}
+
 	// Extract optional parameters
 	replicas := int32(1)
 	if r, ok := argsMap["replicas"].(float64); ok {
@@ -206,6 +233,24 @@ func (t *CreateDeploymentTool) Run(ctx tool.Context, args any) (map[string]any,
 		}
 	}
 
+	if sa, ok := argsMap["service_account"].(string); ok && sa != "" {
+		deployment.Spec.Template.Spec.ServiceAccountName = sa
+	}
+	if ps, ok := argsMap["image_pull_secret"].(string); ok && ps != "" {
+		deployment.Spec.Template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: ps}}
+	}
+
+	if err := applyPodOptions(&deployment.Spec.Template.Spec, argsMap); err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+	strategy, err := deploymentStrategy(argsMap)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+	if strategy != nil {
+		deployment.Spec.Strategy = *strategy
+	}
+
 	// Add health check if path specified
 	if healthPath != "" && containerPort > 0 {
 		probe := &corev1.Probe{
@@ -222,6 +267,8 @@ func (t *CreateDeploymentTool) Run(ctx tool.Context, args any) (map[string]any,
 		deployment.Spec.Template.Spec.Containers[0].ReadinessProbe = probe
 	}
 
+	setImageDigests(&deployment.ObjectMeta, &deployment.Spec.Template.Spec)
+
 	// Marshal to YAML
 	yamlBytes, err := yaml.Marshal(deployment)
 	if err != nil {
@@ -234,8 +281,11 @@ func (t *CreateDeploymentTool) Run(ctx tool.Context, args any) (map[string]any,
 		return map[string]any{"error": fmt.Sprintf("failed to save manifest: %v", err)}, nil
 	}
 
+	// The change-cause goes on the live object only, not the stored manifest
+	setChangeCause(toolContext(ctx), deployment, changeCause(argsMap, fmt.Sprintf("create_deployment %s with %s", name, image)))
+
 	// Apply to cluster
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
 	defer cancel()
 
 	var action string
@@ -260,7 +310,7 @@ func (t *CreateDeploymentTool) Run(ctx tool.Context, args any) (map[string]any,
 		action = "updated"
 	}
 
-	return map[string]any{
+	result := map[string]any{
 		"success":       true,
 		"action":        action,
 		"name":          name,
@@ -269,5 +319,9 @@ func (t *CreateDeploymentTool) Run(ctx tool.Context, args any) (map[string]any,
 		"replicas":      replicas,
 		"manifest_path": manifestPath,
 		"message":       fmt.Sprintf("Deployment %s %s in namespace %s", name, action, namespace),
-	}, nil
+	}
+	if pinWarning != "" {
+		result["pin_warning"] = pinWarning
+	}
+	return result, nil
 }
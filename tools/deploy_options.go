@@ -0,0 +1,354 @@
+package tools
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/genai"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// deploymentOptionSchemas are the create_deployment parameters for the pod
+// and rollout settings beyond image, replicas and a single port.
+var deploymentOptionSchemas = map[string]*genai.Schema{
+	"cpu_request": {
+		Type:        "string",
+		Description: "CPU request, e.g. 250m or 1",
+	},
+	"cpu_limit": {
+		Type:        "string",
+		Description: "CPU limit, e.g. 500m or 2",
+	},
+	"memory_request": {
+		Type:        "string",
+		Description: "Memory request, e.g. 256Mi",
+	},
+	"memory_limit": {
+		Type:        "string",
+		Description: "Memory limit, e.g. 512Mi or 1Gi",
+	},
+	"command": {
+		Type:        "array",
+		Description: "Container entrypoint, replacing the image's ENTRYPOINT (e.g., [\"/app/server\"])",
+		Items:       &genai.Schema{Type: "string"},
+	},
+	"args": {
+		Type:        "array",
+		Description: "Arguments to the entrypoint, replacing the image's CMD (e.g., [\"--port\", \"8080\"])",
+		Items:       &genai.Schema{Type: "string"},
+	},
+	"ports": {
+		Type:        "array",
+		Description: "Container ports, for more than one port or named ports (in addition to port)",
+		Items: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"name":     {Type: "string", Description: "Port name, e.g. http or metrics"},
+				"port":     {Type: "integer", Description: "Container port number"},
+				"protocol": {Type: "string", Description: "TCP (default), UDP or SCTP"},
+			},
+			Required: []string{"port"},
+		},
+	},
+	"volumes": {
+		Type:        "array",
+		Description: "Volumes to mount, each from exactly one of config_map, secret, pvc or empty_dir",
+		Items: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"name":       {Type: "string", Description: "Volume name (default: derived from the source)"},
+				"mount_path": {Type: "string", Description: "Path in the container"},
+				"config_map": {Type: "string", Description: "ConfigMap to mount"},
+				"secret":     {Type: "string", Description: "Secret to mount"},
+				"pvc":        {Type: "string", Description: "PersistentVolumeClaim to mount"},
+				"empty_dir":  {Type: "boolean", Description: "Mount an empty scratch directory (needs a name), e.g. to share files with a sidecar"},
+				"sub_path":   {Type: "string", Description: "Mount a single key or subdirectory (optional)"},
+				"read_only":  {Type: "boolean", Description: "Mount read-only (ConfigMaps and Secrets always are)"},
+			},
+			Required: []string{"mount_path"},
+		},
+	},
+	"node_selector": {
+		Type:        "object",
+		Description: "Node labels the pods must run on, e.g. {\"kubernetes.io/arch\": \"arm64\"}",
+	},
+	"tolerations": {
+		Type:        "array",
+		Description: "Taints the pods tolerate",
+		Items: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"key":      {Type: "string", Description: "Taint key"},
+				"operator": {Type: "string", Description: "Equal (default) or Exists"},
+				"value":    {Type: "string", Description: "Taint value (for Equal)"},
+				"effect":   {Type: "string", Description: "NoSchedule, PreferNoSchedule or NoExecute (default: all effects)"},
+			},
+		},
+	},
+	"security_context": {
+		Type:        "object",
+		Description: "Security settings",
+		Properties: map[string]*genai.Schema{
+			"run_as_user":                {Type: "integer", Description: "UID the container runs as"},
+			"run_as_group":               {Type: "integer", Description: "GID the container runs as"},
+			"fs_group":                   {Type: "integer", Description: "GID that owns mounted volumes"},
+			"run_as_non_root":            {Type: "boolean", Description: "Refuse to start containers running as root"},
+			"read_only_root_filesystem":  {Type: "boolean", Description: "Mount the container's root filesystem read-only"},
+			"allow_privilege_escalation": {Type: "boolean", Description: "Allow setuid binaries to gain privileges"},
+			"drop_all_capabilities":      {Type: "boolean", Description: "Drop all Linux capabilities"},
+		},
+	},
+	"strategy": {
+		Type:        "string",
+		Description: "RollingUpdate (default) or Recreate (stop all old pods first, e.g. for ReadWriteOnce volumes)",
+	},
+	"max_surge": {
+		Type:        "string",
+		Description: "RollingUpdate: extra pods allowed during a rollout, e.g. 1 or 25%",
+	},
+	"max_unavailable": {
+		Type:        "string",
+		Description: "RollingUpdate: pods that may be unavailable during a rollout, e.g. 0 or 25%",
+	},
+}
+
+// applyPodOptions sets the optional create_deployment pod settings on spec,
+// whose first container is the application container.
+func applyPodOptions(spec *corev1.PodSpec, argsMap map[string]any) error {
+	container := &spec.Containers[0]
+	if err := applyContainerOptions(container, argsMap); err != nil {
+		return err
+	}
+
+	volumes, _ := argsMap["volumes"].([]any)
+	for _, v := range volumes {
+		volume, mount, err := deploymentVolume(v)
+		if err != nil {
+			return err
+		}
+		spec.Volumes = append(spec.Volumes, volume)
+		container.VolumeMounts = append(container.VolumeMounts, mount)
+	}
+
+	if selector, ok := argsMap["node_selector"].(map[string]any); ok {
+		for k, v := range selector {
+			if vs, ok := v.(string); ok {
+				if spec.NodeSelector == nil {
+					spec.NodeSelector = map[string]string{}
+				}
+				spec.NodeSelector[k] = vs
+			}
+		}
+	}
+
+	tolerations, _ := argsMap["tolerations"].([]any)
+	for _, raw := range tolerations {
+		t, _ := raw.(map[string]any)
+		toleration := corev1.Toleration{Operator: corev1.TolerationOpEqual}
+		toleration.Key, _ = t["key"].(string)
+		toleration.Value, _ = t["value"].(string)
+		if op, _ := t["operator"].(string); op != "" {
+			toleration.Operator = corev1.TolerationOperator(op)
+		}
+		if effect, _ := t["effect"].(string); effect != "" {
+			toleration.Effect = corev1.TaintEffect(effect)
+		}
+		if toleration.Operator != corev1.TolerationOpEqual && toleration.Operator != corev1.TolerationOpExists {
+			return fmt.Errorf("toleration operator must be Equal or Exists, got %q", toleration.Operator)
+		}
+		if toleration.Operator == corev1.TolerationOpExists {
+			toleration.Value = ""
+		}
+		spec.Tolerations = append(spec.Tolerations, toleration)
+	}
+
+	if sc, ok := argsMap["security_context"].(map[string]any); ok {
+		applySecurityContext(spec, sc)
+	}
+	return nil
+}
+
+// applyContainerOptions sets the resources, command, args and ports
+// arguments on a container.
+func applyContainerOptions(container *corev1.Container, argsMap map[string]any) error {
+	changes := make(map[string]*resource.Quantity)
+	for _, f := range resourceFields {
+		v, ok := argsMap[f.param].(string)
+		if !ok || strings.TrimSpace(v) == "" {
+			continue
+		}
+		q, err := resource.ParseQuantity(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %v", f.param, v, err)
+		}
+		changes[f.param] = &q
+	}
+	applyResourceChanges(&container.Resources, changes)
+
+	container.Command = stringArray(argsMap["command"])
+	container.Args = stringArray(argsMap["args"])
+
+	ports, _ := argsMap["ports"].([]any)
+	for _, p := range ports {
+		port, _ := p.(map[string]any)
+		number, _ := port["port"].(float64)
+		if number <= 0 {
+			return fmt.Errorf("each entry in ports needs a port number")
+		}
+		name, _ := port["name"].(string)
+		protocol := corev1.ProtocolTCP
+		if proto, _ := port["protocol"].(string); proto != "" {
+			protocol = corev1.Protocol(strings.ToUpper(proto))
+		}
+		// The same port may also have been given as port; name that entry instead
+		if i := slices.IndexFunc(container.Ports, func(p corev1.ContainerPort) bool {
+			return p.ContainerPort == int32(number) && p.Protocol == protocol
+		}); i >= 0 {
+			container.Ports[i].Name = name
+			continue
+		}
+		container.Ports = append(container.Ports, corev1.ContainerPort{Name: name, ContainerPort: int32(number), Protocol: protocol})
+	}
+	return nil
+}
+
+// deploymentVolume builds a volume and its mount from a volumes entry.
+func deploymentVolume(raw any) (corev1.Volume, corev1.VolumeMount, error) {
+	v, _ := raw.(map[string]any)
+	mountPath, _ := v["mount_path"].(string)
+	if mountPath == "" {
+		return corev1.Volume{}, corev1.VolumeMount{}, fmt.Errorf("each entry in volumes needs a mount_path")
+	}
+	configMap, _ := v["config_map"].(string)
+	secret, _ := v["secret"].(string)
+	pvc, _ := v["pvc"].(string)
+	readOnly, _ := v["read_only"].(bool)
+
+	volume := corev1.Volume{}
+	sources := 0
+	if configMap != "" {
+		sources++
+		volume.Name = "configmap-" + configMap
+		volume.ConfigMap = &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: configMap}}
+		readOnly = true
+	}
+	if secret != "" {
+		sources++
+		volume.Name = "secret-" + secret
+		volume.Secret = &corev1.SecretVolumeSource{SecretName: secret}
+		readOnly = true
+	}
+	if pvc != "" {
+		sources++
+		volume.Name = "pvc-" + pvc
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvc, ReadOnly: readOnly}
+	}
+	if emptyDir, _ := v["empty_dir"].(bool); emptyDir {
+		sources++
+		volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+	}
+	if sources != 1 {
+		return corev1.Volume{}, corev1.VolumeMount{}, fmt.Errorf("volume at %s needs exactly one of config_map, secret, pvc or empty_dir", mountPath)
+	}
+	if name, _ := v["name"].(string); name != "" {
+		volume.Name = name
+	}
+	if volume.Name == "" {
+		return corev1.Volume{}, corev1.VolumeMount{}, fmt.Errorf("the empty_dir volume at %s needs a name", mountPath)
+	}
+
+	mount := corev1.VolumeMount{Name: volume.Name, MountPath: mountPath, ReadOnly: readOnly}
+	mount.SubPath, _ = v["sub_path"].(string)
+	return volume, mount, nil
+}
+
+// applySecurityContext sets the pod and container security settings from a
+// security_context argument.
+func applySecurityContext(spec *corev1.PodSpec, sc map[string]any) {
+	pod := &corev1.PodSecurityContext{}
+	if v, ok := sc["run_as_user"].(float64); ok {
+		uid := int64(v)
+		pod.RunAsUser = &uid
+	}
+	if v, ok := sc["run_as_group"].(float64); ok {
+		gid := int64(v)
+		pod.RunAsGroup = &gid
+	}
+	if v, ok := sc["fs_group"].(float64); ok {
+		gid := int64(v)
+		pod.FSGroup = &gid
+	}
+	if v, ok := sc["run_as_non_root"].(bool); ok {
+		pod.RunAsNonRoot = &v
+	}
+	if pod.RunAsUser != nil || pod.RunAsGroup != nil || pod.FSGroup != nil || pod.RunAsNonRoot != nil {
+		spec.SecurityContext = pod
+	}
+
+	container := &corev1.SecurityContext{}
+	if v, ok := sc["read_only_root_filesystem"].(bool); ok {
+		container.ReadOnlyRootFilesystem = &v
+	}
+	if v, ok := sc["allow_privilege_escalation"].(bool); ok {
+		container.AllowPrivilegeEscalation = &v
+	}
+	if v, _ := sc["drop_all_capabilities"].(bool); v {
+		container.Capabilities = &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}}
+	}
+	if container.ReadOnlyRootFilesystem != nil || container.AllowPrivilegeEscalation != nil || container.Capabilities != nil {
+		spec.Containers[0].SecurityContext = container
+	}
+}
+
+// deploymentStrategy builds the rollout strategy from the strategy,
+// max_surge and max_unavailable arguments. Returns nil for the default.
+func deploymentStrategy(argsMap map[string]any) (*appsv1.DeploymentStrategy, error) {
+	strategy, _ := argsMap["strategy"].(string)
+	maxSurge, err := intOrPercentArg(argsMap, "max_surge")
+	if err != nil {
+		return nil, err
+	}
+	maxUnavailable, err := intOrPercentArg(argsMap, "max_unavailable")
+	if err != nil {
+		return nil, err
+	}
+
+	switch strategy {
+	case "", string(appsv1.RollingUpdateDeploymentStrategyType):
+		if maxSurge == nil && maxUnavailable == nil {
+			if strategy == "" {
+				return nil, nil
+			}
+			return &appsv1.DeploymentStrategy{Type: appsv1.RollingUpdateDeploymentStrategyType}, nil
+		}
+		if maxSurge != nil && maxUnavailable != nil && maxSurge.String() == "0" && maxUnavailable.String() == "0" {
+			return nil, fmt.Errorf("max_surge and max_unavailable cannot both be 0")
+		}
+		return &appsv1.DeploymentStrategy{
+			Type:          appsv1.RollingUpdateDeploymentStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateDeployment{MaxSurge: maxSurge, MaxUnavailable: maxUnavailable},
+		}, nil
+	case string(appsv1.RecreateDeploymentStrategyType):
+		if maxSurge != nil || maxUnavailable != nil {
+			return nil, fmt.Errorf("max_surge and max_unavailable only apply to the RollingUpdate strategy")
+		}
+		return &appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}, nil
+	default:
+		return nil, fmt.Errorf("strategy must be RollingUpdate or Recreate, got %q", strategy)
+	}
+}
+
+// stringArray reads an argument given as an array of strings.
+func stringArray(v any) []string {
+	items, _ := v.([]any)
+	var out []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestApplyPodOptions(t *testing.T) {
+	var argsMap map[string]any
+	if err := json.Unmarshal([]byte(`{
+		"cpu_request": "250m",
+		"memory_limit": "512Mi",
+		"command": ["/app/server"],
+		"args": ["--port", "8080"],
+		"ports": [{"name": "http", "port": 8080}, {"name": "metrics", "port": 9090}],
+		"volumes": [
+			{"mount_path": "/etc/app", "config_map": "app-config"},
+			{"name": "data", "mount_path": "/data", "pvc": "app-data"}
+		],
+		"node_selector": {"kubernetes.io/arch": "arm64"},
+		"tolerations": [{"key": "dedicated", "value": "batch", "effect": "NoSchedule"}],
+		"security_context": {"run_as_user": 1000, "run_as_non_root": true, "read_only_root_filesystem": true}
+	}`), &argsMap); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := corev1.PodSpec{Containers: []corev1.Container{{
+		Name:  "web",
+		Ports: []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+	}}}
+	if err := applyPodOptions(&spec, argsMap); err != nil {
+		t.Fatalf("applyPodOptions failed: %v", err)
+	}
+	c := spec.Containers[0]
+
+	if got := c.Resources.Requests.Cpu().String(); got != "250m" {
+		t.Errorf("cpu request = %s, want 250m", got)
+	}
+	if got := c.Resources.Limits.Memory().String(); got != "512Mi" {
+		t.Errorf("memory limit = %s, want 512Mi", got)
+	}
+	if _, ok := c.Resources.Limits[corev1.ResourceCPU]; ok {
+		t.Error("cpu limit should not be set")
+	}
+	if !reflect.DeepEqual(c.Command, []string{"/app/server"}) || !reflect.DeepEqual(c.Args, []string{"--port", "8080"}) {
+		t.Errorf("command/args = %v %v", c.Command, c.Args)
+	}
+
+	// port 8080 was given twice and is named rather than duplicated
+	wantPorts := []corev1.ContainerPort{
+		{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+		{Name: "metrics", ContainerPort: 9090, Protocol: corev1.ProtocolTCP},
+	}
+	if !reflect.DeepEqual(c.Ports, wantPorts) {
+		t.Errorf("ports = %+v, want %+v", c.Ports, wantPorts)
+	}
+
+	if len(spec.Volumes) != 2 || spec.Volumes[0].Name != "configmap-app-config" || spec.Volumes[1].PersistentVolumeClaim.ClaimName != "app-data" {
+		t.Errorf("volumes = %+v", spec.Volumes)
+	}
+	wantMounts := []corev1.VolumeMount{
+		{Name: "configmap-app-config", MountPath: "/etc/app", ReadOnly: true},
+		{Name: "data", MountPath: "/data"},
+	}
+	if !reflect.DeepEqual(c.VolumeMounts, wantMounts) {
+		t.Errorf("mounts = %+v, want %+v", c.VolumeMounts, wantMounts)
+	}
+
+	if spec.NodeSelector["kubernetes.io/arch"] != "arm64" {
+		t.Errorf("node selector = %v", spec.NodeSelector)
+	}
+	wantToleration := corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "batch", Effect: corev1.TaintEffectNoSchedule}
+	if len(spec.Tolerations) != 1 || spec.Tolerations[0] != wantToleration {
+		t.Errorf("tolerations = %+v", spec.Tolerations)
+	}
+
+	if sc := spec.SecurityContext; sc == nil || *sc.RunAsUser != 1000 || !*sc.RunAsNonRoot || sc.FSGroup != nil {
+		t.Errorf("pod security context = %+v", sc)
+	}
+	if sc := c.SecurityContext; sc == nil || !*sc.ReadOnlyRootFilesystem || sc.AllowPrivilegeEscalation != nil {
+		t.Errorf("container security context = %+v", sc)
+	}
+}
+
+func TestApplyPodOptionsErrors(t *testing.T) {
+	for _, args := range []string{
+		`{"cpu_limit": "lots"}`,
+		`{"ports": [{"name": "http"}]}`,
+		`{"volumes": [{"config_map": "app-config"}]}`,
+		`{"volumes": [{"mount_path": "/data"}]}`,
+		`{"volumes": [{"mount_path": "/data", "pvc": "a", "secret": "b"}]}`,
+		`{"tolerations": [{"key": "a", "operator": "Matches"}]}`,
+	} {
+		var argsMap map[string]any
+		if err := json.Unmarshal([]byte(args), &argsMap); err != nil {
+			t.Fatal(err)
+		}
+		spec := corev1.PodSpec{Containers: []corev1.Container{{Name: "web"}}}
+		if err := applyPodOptions(&spec, argsMap); err == nil {
+			t.Errorf("applyPodOptions(%s) should fail", args)
+		}
+	}
+}
+
+func TestDeploymentStrategy(t *testing.T) {
+	tests := []struct {
+		args    map[string]any
+		want    appsv1.DeploymentStrategyType
+		surge   string
+		wantNil bool
+		wantErr bool
+	}{
+		{args: map[string]any{}, wantNil: true},
+		{args: map[string]any{"strategy": "Recreate"}, want: appsv1.RecreateDeploymentStrategyType},
+		{args: map[string]any{"max_surge": "50%", "max_unavailable": float64(0)}, want: appsv1.RollingUpdateDeploymentStrategyType, surge: "50%"},
+		{args: map[string]any{"strategy": "Recreate", "max_surge": "1"}, wantErr: true},
+		{args: map[string]any{"max_surge": "0", "max_unavailable": "0"}, wantErr: true},
+		{args: map[string]any{"strategy": "BlueGreen"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := deploymentStrategy(tt.args)
+		switch {
+		case tt.wantErr:
+			if err == nil {
+				t.Errorf("deploymentStrategy(%v) should fail", tt.args)
+			}
+		case err != nil:
+			t.Errorf("deploymentStrategy(%v) failed: %v", tt.args, err)
+		case tt.wantNil:
+			if got != nil {
+				t.Errorf("deploymentStrategy(%v) = %+v, want nil", tt.args, got)
+			}
+		case got == nil || got.Type != tt.want:
+			t.Errorf("deploymentStrategy(%v) = %+v, want type %s", tt.args, got, tt.want)
+		case tt.surge != "" && got.RollingUpdate.MaxSurge.String() != tt.surge:
+			t.Errorf("deploymentStrategy(%v) max surge = %s, want %s", tt.args, got.RollingUpdate.MaxSurge, tt.surge)
+		}
+	}
+}
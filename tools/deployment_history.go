@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RevisionInfo describes one revision of a deployment: the ReplicaSet
+// that runs it and where the change came from.
+type RevisionInfo struct {
+	Revision       int               `json:"revision"`
+	ReplicaSet     string            `json:"replica_set"`
+	Created        time.Time         `json:"created"`
+	Current        bool              `json:"current,omitempty"`
+	Replicas       int32             `json:"replicas"`
+	Ready          int32             `json:"ready"`
+	Images         map[string]string `json:"images"`
+	ChangeCause    string            `json:"change_cause,omitempty"`
+	PlanID         string            `json:"plan_id,omitempty"`
+	GitCommit      string            `json:"git_commit,omitempty"`
+	Prompt         string            `json:"prompt,omitempty"`
+	RolledBackFrom string            `json:"rolled_back_from,omitempty"` // earlier numbers of a revision that was rolled back to
+}
+
+// DeploymentHistoryTool provides the deployment_history tool for the agent.
+type DeploymentHistoryTool struct {
+	clientset *kubernetes.Clientset
+}
+
+// NewDeploymentHistoryTool creates a new DeploymentHistoryTool.
+func NewDeploymentHistoryTool(clientset *kubernetes.Clientset) *DeploymentHistoryTool {
+	return &DeploymentHistoryTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *DeploymentHistoryTool) Name() string {
+	return "deployment_history"
+}
+
+// Description returns the tool description.
+func (t *DeploymentHistoryTool) Description() string {
+	return "List the revisions of a deployment, newest first, like 'kubectl rollout history': each ReplicaSet with its images, replicas, " +
+		"change-cause and, for changes made by kasa, the plan ID, manifest commit and user request behind it. " +
+		"Use it to pick the revision for rollback_deployment."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *DeploymentHistoryTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *DeploymentHistoryTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *DeploymentHistoryTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *DeploymentHistoryTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"name": {
+					Type:        "string",
+					Description: "The name of the deployment",
+				},
+			},
+			Required: []string{"namespace", "name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *DeploymentHistoryTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	_, namespace, name, errResult := parseRolloutArgs(args)
+	if errResult != nil {
+		return errResult, nil
+	}
+	return deploymentHistory(toolContext(ctx), t.clientset, namespace, name), nil
+}
+
+// deploymentHistory returns the tool response for deployment_history.
+func deploymentHistory(ctx context.Context, clientset kubernetes.Interface, namespace, name string) map[string]any {
+	apiCtx, cancel := apiContext(ctx)
+	defer cancel()
+	deployment, err := clientset.AppsV1().Deployments(namespace).Get(apiCtx, name, metav1.GetOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to get deployment %s/%s: %v", namespace, name, err)}
+	}
+	replicaSets, err := deploymentReplicaSets(apiCtx, clientset, deployment)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+
+	revisions := deploymentRevisions(deployment, replicaSets)
+	result := map[string]any{
+		"namespace": namespace,
+		"name":      name,
+		"revisions": revisions,
+		"count":     len(revisions),
+	}
+	if len(revisions) > 0 && revisions[0].Current {
+		result["current_revision"] = revisions[0].Revision
+	}
+	if limit := deployment.Spec.RevisionHistoryLimit; limit != nil {
+		result["revision_history_limit"] = *limit
+	}
+	return result
+}
+
+// deploymentReplicaSets returns the ReplicaSets a deployment controls.
+func deploymentReplicaSets(ctx context.Context, clientset kubernetes.Interface, deployment *appsv1.Deployment) ([]appsv1.ReplicaSet, error) {
+	selector := metav1.FormatLabelSelector(deployment.Spec.Selector)
+	list, err := clientset.AppsV1().ReplicaSets(deployment.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("listing replica sets: %v", err)
+	}
+	var replicaSets []appsv1.ReplicaSet
+	for _, rs := range list.Items {
+		if metav1.IsControlledBy(&rs, deployment) {
+			replicaSets = append(replicaSets, rs)
+		}
+	}
+	return replicaSets, nil
+}
+
+// deploymentRevisions describes the revisions of a deployment, newest
+// first.
+func deploymentRevisions(deployment *appsv1.Deployment, replicaSets []appsv1.ReplicaSet) []RevisionInfo {
+	current, _ := strconv.Atoi(deployment.Annotations[revisionAnnotation])
+	revisions := make([]RevisionInfo, 0, len(replicaSets))
+	for _, rs := range replicaSets {
+		revision := replicaSetRevision(rs)
+		info := RevisionInfo{
+			Revision:       revision,
+			ReplicaSet:     rs.Name,
+			Created:        rs.CreationTimestamp.Time,
+			Current:        revision == current,
+			Replicas:       rs.Status.Replicas,
+			Ready:          rs.Status.ReadyReplicas,
+			Images:         containerImages(rs.Spec.Template.Spec.Containers),
+			ChangeCause:    rs.Annotations[changeCauseAnnotation],
+			PlanID:         rs.Annotations[planIDAnnotation],
+			GitCommit:      rs.Annotations[gitCommitAnnotation],
+			Prompt:         rs.Annotations[promptAnnotation],
+			RolledBackFrom: rs.Annotations[revisionHistoryAnnotation],
+		}
+		revisions = append(revisions, info)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
+	})
+	return revisions
+}
@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+)
+
+// revisionReplicaSet returns a ReplicaSet of deployment running image as
+// the given revision.
+func revisionReplicaSet(deployment *appsv1.Deployment, revision, image string, annotations map[string]string) *appsv1.ReplicaSet {
+	controller := true
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            deployment.Name + "-" + revision,
+			Namespace:       deployment.Namespace,
+			Labels:          map[string]string{"app": deployment.Name},
+			Annotations:     map[string]string{revisionAnnotation: revision},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: deployment.Name, UID: deployment.UID, Controller: &controller}},
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": deployment.Name, appsv1.DefaultDeploymentUniqueLabelKey: revision}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: image}}},
+			},
+		},
+	}
+	for k, v := range annotations {
+		rs.Annotations[k] = v
+	}
+	return rs
+}
+
+func TestDeploymentHistory(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", UID: types.UID("web-uid"), Annotations: map[string]string{revisionAnnotation: "3"}},
+		Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+	}
+	other := deployment.DeepCopy()
+	other.UID = "other-uid"
+	clientset := fake.NewSimpleClientset(
+		deployment,
+		revisionReplicaSet(deployment, "1", "nginx:1.26", map[string]string{changeCauseAnnotation: "initial"}),
+		revisionReplicaSet(deployment, "3", "nginx:1.27", map[string]string{
+			changeCauseAnnotation:     "set_image web to nginx:1.27",
+			planIDAnnotation:          "plan-1a2b3c4d",
+			gitCommitAnnotation:       "abc1234",
+			promptAnnotation:          "upgrade nginx",
+			revisionHistoryAnnotation: "2",
+		}),
+		revisionReplicaSet(other, "9", "nginx:9", nil),
+	)
+
+	result := deploymentHistory(context.Background(), clientset, "shop", "web")
+	revisions, _ := result["revisions"].([]RevisionInfo)
+	if len(revisions) != 2 || result["current_revision"] != 3 {
+		t.Fatalf("expected the two revisions of web, got %v", result)
+	}
+	latest := revisions[0]
+	if !latest.Current || latest.PlanID != "plan-1a2b3c4d" || latest.GitCommit != "abc1234" || latest.Prompt != "upgrade nginx" || latest.RolledBackFrom != "2" {
+		t.Errorf("unexpected latest revision %+v", latest)
+	}
+	if revisions[1].Revision != 1 || revisions[1].Current || revisions[1].Images["web"] != "nginx:1.26" {
+		t.Errorf("unexpected first revision %+v", revisions[1])
+	}
+
+	if result := deploymentHistory(context.Background(), clientset, "shop", "api"); !strings.Contains(result["error"].(string), "not found") {
+		t.Errorf("expected an error for a missing deployment, got %v", result)
+	}
+}
+
+func TestRollbackTarget(t *testing.T) {
+	revisions := []RevisionInfo{{Revision: 5}, {Revision: 3}, {Revision: 1}}
+	tests := []struct {
+		current, revision int
+		want              int
+		err               string
+	}{
+		{5, 0, 3, ""},
+		{5, 1, 1, ""},
+		{5, 5, 0, "already runs"},
+		{5, 2, 0, "available: [5 3 1]"},
+		{1, 0, 0, "no revision before 1"},
+	}
+	for _, tt := range tests {
+		got, err := rollbackTarget(revisions, tt.current, tt.revision)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("current %d, revision %d: expected error %q, got %v", tt.current, tt.revision, tt.err, err)
+			}
+			continue
+		}
+		if err != nil || got.Revision != tt.want {
+			t.Errorf("current %d, revision %d: got %d, %v; want %d", tt.current, tt.revision, got.Revision, err, tt.want)
+		}
+	}
+}
+
+// deploymentManifest is a stored deployment manifest of web.
+func deploymentManifest(replicas, image string) string {
+	return `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+spec:
+  replicas: ` + replicas + `
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: ` + image + `
+`
+}
+
+func TestRollbackRestoreManifest(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	writeTestManifest(t, mgr, "shop", "web", "deployment", deploymentManifest("2", "nginx:1.26"))
+	if err := mgr.Commit("Deploy 1.26"); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := mgr.Head()
+	writeTestManifest(t, mgr, "shop", "web", "deployment", deploymentManifest("3", "nginx:1.27"))
+	if err := mgr.Commit("Deploy 1.27"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The ReplicaSet's template as the API server returns it, with defaults
+	template := func(image string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+			Spec: corev1.PodSpec{
+				Containers:    []corev1.Container{{Name: "web", Image: image, TerminationMessagePath: "/dev/termination-log", ImagePullPolicy: corev1.PullIfNotPresent}},
+				RestartPolicy: corev1.RestartPolicyAlways,
+			},
+		}
+	}
+	tool := NewRollbackDeploymentTool(nil, mgr)
+	readSaved := func() (appsv1.Deployment, string) {
+		content, err := mgr.ReadManifest("shop", "web", "deployment")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var saved appsv1.Deployment
+		if err := yaml.Unmarshal(content, &saved); err != nil {
+			t.Fatal(err)
+		}
+		return saved, string(content)
+	}
+
+	if _, version, err := tool.restoreManifest("shop", "web", template("nginx:1.26")); err != nil || version != first {
+		t.Fatalf("expected the template of %s, got %q, %v", first, version, err)
+	}
+	saved, content := readSaved()
+	if *saved.Spec.Replicas != 3 || saved.Spec.Template.Spec.Containers[0].Image != "nginx:1.26" || strings.Contains(content, "terminationMessagePath") {
+		t.Errorf("expected only the stored template of 1.26 to be restored, got %s", content)
+	}
+
+	// A template that was never stored is stored as the ReplicaSet has it
+	if _, version, err := tool.restoreManifest("shop", "web", template("nginx:1.25@sha256:aaa")); err != nil || version != "" {
+		t.Fatalf("expected no matching version, got %q, %v", version, err)
+	}
+	saved, content = readSaved()
+	if saved.Spec.Template.Spec.Containers[0].TerminationMessagePath != "/dev/termination-log" || strings.Contains(content, "creationTimestamp") {
+		t.Errorf("expected the ReplicaSet's template, got %s", content)
+	}
+	if saved.Annotations[ImageDigestsAnnotation] != `{"nginx:1.25":"sha256:aaa"}` {
+		t.Errorf("expected the digest annotation to follow the image, got %v", saved.Annotations)
+	}
+
+	if _, _, err := tool.restoreManifest("shop", "api", template("nginx:1.26")); err == nil || !strings.Contains(err.Error(), "import_resource") {
+		t.Errorf("expected an error for a missing manifest, got %v", err)
+	}
+}
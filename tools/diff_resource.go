@@ -1,7 +1,6 @@
 package tools
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 
@@ -10,19 +9,26 @@ import (
 	"google.golang.org/adk/tool"
 	"google.golang.org/genai"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 )
 
 // DiffResourceTool provides the diff_resource tool for the agent.
 type DiffResourceTool struct {
+	images        *imageChecker
 	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
 	manifest      *manifest.Manager
+	drift         *driftCache // updated with every comparison; may be nil
 }
 
 // NewDiffResourceTool creates a new DiffResourceTool.
-func NewDiffResourceTool(dynamicClient dynamic.Interface, manifest *manifest.Manager) *DiffResourceTool {
+func NewDiffResourceTool(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, resolver *GVRResolver, manifest *manifest.Manager, drift *driftCache) *DiffResourceTool {
 	return &DiffResourceTool{
+		images:        newImageChecker(clientset),
 		dynamicClient: dynamicClient,
+		resolver:      resolver,
 		manifest:      manifest,
+		drift:         drift,
 	}
 }
 
@@ -33,7 +39,7 @@ func (t *DiffResourceTool) Name() string {
 
 // Description returns the tool description.
 func (t *DiffResourceTool) Description() string {
-	return "Compare a stored manifest against the live cluster resource and return field-by-field differences."
+	return "Compare a stored manifest against the live cluster resource and return field-by-field differences. Fields listed in the .kasaignore file of the manifest repository are excluded. For workloads deployed by digest, also reports tags that were pushed again since (repushed_tags)."
 }
 
 // IsLongRunning returns false as this is a quick operation.
@@ -61,7 +67,7 @@ func (t *DiffResourceTool) Declaration() *genai.FunctionDeclaration {
 			Properties: map[string]*genai.Schema{
 				"namespace": {
 					Type:        "string",
-					Description: "The Kubernetes namespace",
+					Description: "The Kubernetes namespace (_cluster for cluster-scoped resources)",
 				},
 				"app": {
 					Type:        "string",
@@ -111,8 +117,14 @@ func (t *DiffResourceTool) Run(ctx tool.Context, args any) (map[string]any, erro
 		return map[string]any{"error": err.Error()}, nil
 	}
 
-	// Compare against live cluster
-	result := CompareManifest(context.Background(), t.dynamicClient, namespace, app, resourceType, content)
+	ignore, err := LoadDriftIgnore(t.manifest.BaseDir())
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	// Compare against live cluster, dropping fields listed in .kasaignore
+	result := ignore.Apply(CompareManifest(toolContext(ctx), t.dynamicClient, t.resolver, namespace, app, resourceType, content))
+	t.drift.record(result)
 
 	response := map[string]any{
 		"namespace":  result.Namespace,
@@ -126,6 +138,10 @@ func (t *DiffResourceTool) Run(ctx tool.Context, args any) (map[string]any, erro
 		response["error"] = result.Error
 	}
 
+	if result.Ignored > 0 {
+		response["ignored_count"] = result.Ignored
+	}
+
 	if len(result.Diffs) > 0 {
 		diffs := make([]map[string]any, len(result.Diffs))
 		for i, d := range result.Diffs {
@@ -156,5 +172,12 @@ func (t *DiffResourceTool) Run(ctx tool.Context, args any) (map[string]any, erro
 		response["summary"] = fmt.Sprintf("Error comparing %s/%s/%s: %s", namespace, app, resourceType, result.Error)
 	}
 
+	// A pinned tag pushed again since means the stored digest no longer
+	// matches what the tag deploys
+	if repushed := t.images.repushedTags(toolContext(ctx), namespace, content); len(repushed) > 0 {
+		response["repushed_tags"] = repushed
+		response["summary"] = fmt.Sprintf("%s; %d pinned image tag(s) were pushed again since the deploy and now point to a different digest", response["summary"], len(repushed))
+	}
+
 	return response, nil
 }
@@ -28,6 +28,7 @@ type DriftResult struct {
 	Kind      string      `json:"kind"`
 	Status    string      `json:"status"` // "in_sync", "drifted", "missing", "error"
 	Diffs     []DiffEntry `json:"diffs,omitempty"`
+	Ignored   int         `json:"ignored,omitempty"` // diffs dropped by .kasaignore rules
 	Error     string      `json:"error,omitempty"`
 }
 
@@ -39,6 +40,7 @@ type DriftScanResults struct {
 	Drifted int           `json:"drifted"`
 	Missing int           `json:"missing"`
 	Errors  int           `json:"errors"`
+	Skipped int           `json:"skipped,omitempty"` // not checked because the scan was cancelled
 }
 
 // DiffMaps recursively compares two maps and returns field-level differences.
@@ -198,13 +200,13 @@ func diffSlices(stored, live []any, path string) []DiffEntry {
 
 // FetchAndCleanLiveResource fetches a resource from the cluster via dynamic client,
 // applies cleanForImport, and returns the cleaned map.
-func FetchAndCleanLiveResource(ctx context.Context, dynClient dynamic.Interface, namespace, name, kind, apiVersion string) (map[string]any, error) {
-	gvr, found := BuildGVRFromKindAndAPIVersion(kind, apiVersion)
-	if !found {
-		return nil, fmt.Errorf("unknown resource kind '%s'", kind)
+func FetchAndCleanLiveResource(ctx context.Context, dynClient dynamic.Interface, resolver *GVRResolver, namespace, name, kind, apiVersion string) (map[string]any, error) {
+	gvr, err := resolver.Resolve(kind, apiVersion)
+	if err != nil {
+		return nil, err
 	}
 
-	namespaced := IsNamespaced(kind)
+	namespaced := resolver.IsNamespaced(gvr, kind)
 
 	var resourceClient dynamic.ResourceInterface
 	if namespaced {
@@ -228,7 +230,7 @@ func FetchAndCleanLiveResource(ctx context.Context, dynClient dynamic.Interface,
 // It applies cleanForImport to both sides and uses one-directional comparison:
 // only fields present in the stored manifest are checked. Server-added defaults
 // (fields only in live) are ignored.
-func CompareManifest(ctx context.Context, dynClient dynamic.Interface, namespace, name, kind string, storedYAML []byte) DriftResult {
+func CompareManifest(ctx context.Context, dynClient dynamic.Interface, resolver *GVRResolver, namespace, name, kind string, storedYAML []byte) DriftResult {
 	result := DriftResult{
 		Namespace: namespace,
 		Name:      name,
@@ -254,7 +256,7 @@ func CompareManifest(ctx context.Context, dynClient dynamic.Interface, namespace
 	timeoutCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	liveMap, err := FetchAndCleanLiveResource(timeoutCtx, dynClient, namespace, name, kind, apiVersion)
+	liveMap, err := FetchAndCleanLiveResource(timeoutCtx, dynClient, resolver, namespace, name, kind, apiVersion)
 	if err != nil {
 		errStr := err.Error()
 		if strings.Contains(errStr, "not found") {
@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"sync"
+	"time"
+)
+
+// driftCache remembers the latest drift result per stored manifest so
+// inventory views can report drift without re-comparing every resource.
+// A nil *driftCache records nothing and finds nothing.
+type driftCache struct {
+	mu      sync.Mutex
+	results map[string]cachedDrift
+}
+
+// cachedDrift is a drift result and when it was computed.
+type cachedDrift struct {
+	result  DriftResult
+	checked time.Time
+}
+
+func newDriftCache() *driftCache {
+	return &driftCache{results: make(map[string]cachedDrift)}
+}
+
+// record stores a drift result, replacing any earlier one for the manifest.
+func (c *driftCache) record(dr DriftResult) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results[driftCacheKey(dr.Namespace, dr.Name, dr.Kind)] = cachedDrift{result: dr, checked: time.Now()}
+}
+
+// lookup returns the cached drift result for a manifest, if any.
+func (c *driftCache) lookup(namespace, app, resourceType string) (cachedDrift, bool) {
+	if c == nil {
+		return cachedDrift{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cd, ok := c.results[driftCacheKey(namespace, app, resourceType)]
+	return cd, ok
+}
+
+func driftCacheKey(namespace, app, resourceType string) string {
+	return namespace + "/" + app + "/" + resourceType
+}
+
+// RecordDriftScan caches the results of a drift scan so list_manifests can
+// report each manifest's drift status.
+func (k *KubeTools) RecordDriftScan(results *DriftScanResults) {
+	if results == nil {
+		return
+	}
+	for _, dr := range results.Results {
+		k.drift.record(dr)
+	}
+}
@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DriftIgnoreFile is the name of the ignore rules file in the manifest
+// repository root.
+const DriftIgnoreFile = ".kasaignore"
+
+// DriftIgnore holds field paths that are excluded from drift comparisons.
+//
+// Each non-empty, non-comment line of .kasaignore is either a global rule or
+// a rule scoped to manifests matching a <namespace>/<app>/<type> glob:
+//
+//	# HPA owns the replica count everywhere
+//	spec.replicas
+//	metadata.annotations.deployment.kubernetes.io/revision
+//	prod/*/deployment: spec.template.metadata.annotations.kubectl.kubernetes.io/restartedAt
+//	default/web/deployment: spec.template.spec.containers[*].image
+//
+// A rule matches the path itself and everything below it. [*] matches any
+// list index. A nil *DriftIgnore ignores nothing.
+type DriftIgnore struct {
+	rules []driftIgnoreRule
+}
+
+type driftIgnoreRule struct {
+	scope string // glob over "<namespace>/<app>/<type>", empty for global rules
+	path  string
+}
+
+// LoadDriftIgnore reads the .kasaignore file in dir. A missing file yields
+// an empty rule set.
+func LoadDriftIgnore(dir string) (*DriftIgnore, error) {
+	content, err := os.ReadFile(filepath.Join(dir, DriftIgnoreFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &DriftIgnore{}, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", DriftIgnoreFile, err)
+	}
+	return ParseDriftIgnore(content)
+}
+
+// ParseDriftIgnore parses .kasaignore content.
+func ParseDriftIgnore(content []byte) (*DriftIgnore, error) {
+	ig := &DriftIgnore{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := driftIgnoreRule{path: line}
+		// "ns/app/type: path" — field paths never contain a colon
+		if scope, p, ok := strings.Cut(line, ":"); ok {
+			rule.scope = strings.TrimSpace(scope)
+			rule.path = strings.TrimSpace(p)
+			if strings.Count(rule.scope, "/") != 2 {
+				return nil, fmt.Errorf("%s line %d: scope %q must be <namespace>/<app>/<type>", DriftIgnoreFile, lineNo, rule.scope)
+			}
+			if _, err := path.Match(rule.scope, ""); err != nil {
+				return nil, fmt.Errorf("%s line %d: invalid scope %q: %v", DriftIgnoreFile, lineNo, rule.scope, err)
+			}
+		}
+		if rule.path == "" {
+			return nil, fmt.Errorf("%s line %d: missing field path", DriftIgnoreFile, lineNo)
+		}
+		ig.rules = append(ig.rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", DriftIgnoreFile, err)
+	}
+	return ig, nil
+}
+
+// Apply removes ignored diffs from a drift result. A drifted result whose
+// diffs are all ignored becomes in_sync; the number of ignored diffs is
+// recorded in Ignored.
+func (ig *DriftIgnore) Apply(result DriftResult) DriftResult {
+	if ig == nil || len(ig.rules) == 0 || len(result.Diffs) == 0 {
+		return result
+	}
+
+	target := result.Namespace + "/" + result.Name + "/" + result.Kind
+	var paths []string
+	for _, r := range ig.rules {
+		if r.scope == "" {
+			paths = append(paths, r.path)
+		} else if ok, _ := path.Match(r.scope, target); ok {
+			paths = append(paths, r.path)
+		}
+	}
+	if len(paths) == 0 {
+		return result
+	}
+
+	var kept []DiffEntry
+	for _, d := range result.Diffs {
+		if ignoredPath(paths, d.Path) {
+			result.Ignored++
+			continue
+		}
+		kept = append(kept, d)
+	}
+	result.Diffs = kept
+	if len(kept) == 0 && result.Status == "drifted" {
+		result.Status = "in_sync"
+	}
+	return result
+}
+
+// listIndexRe matches list indexes in diff paths, e.g. "[0]".
+var listIndexRe = regexp.MustCompile(`\[\d+\]`)
+
+// ignoredPath reports whether diffPath equals or is below one of the rules.
+func ignoredPath(rules []string, diffPath string) bool {
+	wildcard := listIndexRe.ReplaceAllString(diffPath, "[*]")
+	for _, rule := range rules {
+		for _, p := range []string{diffPath, wildcard} {
+			if p == rule || strings.HasPrefix(p, rule+".") || strings.HasPrefix(p, rule+"[") {
+				return true
+			}
+		}
+	}
+	return false
+}
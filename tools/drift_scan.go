@@ -3,19 +3,27 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/perbu/kasa/manifest"
 	"k8s.io/client-go/dynamic"
 )
 
-// ProgressFunc is called during drift scan to report progress.
-// It receives the current index (0-based) and total count plus the resource being checked.
-type ProgressFunc func(current, total int, namespace, name, kind string)
+// driftScanWorkers bounds how many manifests are compared concurrently.
+const driftScanWorkers = 8
 
-// RunDriftScan iterates over all stored manifests and compares each against
-// the live cluster state. Returns nil, nil if there are no manifests.
-// The optional progress callback is called before each resource is checked.
-func RunDriftScan(ctx context.Context, dynClient dynamic.Interface, mgr *manifest.Manager, progress ProgressFunc) (*DriftScanResults, error) {
+// ProgressFunc is called during drift scan to report progress.
+// It receives the number of manifests checked so far and the total count,
+// plus the resource that just finished. Calls are serialized.
+type ProgressFunc func(done, total int, namespace, name, kind string)
+
+// RunDriftScan compares all stored manifests against the live cluster state
+// using a bounded pool of workers. Returns nil, nil if there are no manifests.
+// The optional progress callback is called as each resource is checked.
+//
+// If ctx is cancelled, the scan stops dispatching work and returns the
+// results gathered so far with Skipped set to the number of unchecked manifests.
+func RunDriftScan(ctx context.Context, dynClient dynamic.Interface, resolver *GVRResolver, mgr *manifest.Manager, progress ProgressFunc) (*DriftScanResults, error) {
 	manifests, err := mgr.ListManifests("", "")
 	if err != nil {
 		return nil, err
@@ -25,30 +33,69 @@ func RunDriftScan(ctx context.Context, dynClient dynamic.Interface, mgr *manifes
 		return nil, nil
 	}
 
-	results := &DriftScanResults{
-		Total: len(manifests),
+	ignore, err := LoadDriftIgnore(mgr.BaseDir())
+	if err != nil {
+		return nil, err
 	}
 
-	for i, m := range manifests {
+	checked := make([]*DriftResult, len(manifests))
+	jobs := make(chan int)
+	done := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(driftScanWorkers, len(manifests)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					done <- i
+					continue
+				}
+				dr := ignore.Apply(scanManifest(ctx, dynClient, resolver, mgr, manifests[i]))
+				// A comparison cut short by cancellation counts as skipped
+				if ctx.Err() == nil || dr.Status != "error" {
+					checked[i] = &dr
+				}
+				done <- i
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for i := range manifests {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	completed := 0
+	for i := range done {
+		completed++
 		if progress != nil {
-			progress(i, len(manifests), m.Namespace, m.App, m.Type)
+			m := manifests[i]
+			progress(completed, len(manifests), m.Namespace, m.App, m.Type)
 		}
+	}
 
-		content, err := mgr.ReadManifest(m.Namespace, m.App, m.Type)
-		if err != nil {
-			results.Results = append(results.Results, DriftResult{
-				Namespace: m.Namespace,
-				Name:      m.App,
-				Kind:      m.Type,
-				Status:    "error",
-				Error:     err.Error(),
-			})
-			results.Errors++
+	results := &DriftScanResults{
+		Total: len(manifests),
+	}
+	for _, dr := range checked {
+		if dr == nil {
+			results.Skipped++
 			continue
 		}
-
-		dr := CompareManifest(ctx, dynClient, m.Namespace, m.App, m.Type, content)
-		results.Results = append(results.Results, dr)
+		results.Results = append(results.Results, *dr)
 
 		switch dr.Status {
 		case "in_sync":
@@ -65,6 +112,21 @@ func RunDriftScan(ctx context.Context, dynClient dynamic.Interface, mgr *manifes
 	return results, nil
 }
 
+// scanManifest reads one stored manifest and compares it with the cluster.
+func scanManifest(ctx context.Context, dynClient dynamic.Interface, resolver *GVRResolver, mgr *manifest.Manager, m manifest.ManifestInfo) DriftResult {
+	content, err := mgr.ReadManifest(m.Namespace, m.App, m.Type)
+	if err != nil {
+		return DriftResult{
+			Namespace: m.Namespace,
+			Name:      m.App,
+			Kind:      m.Type,
+			Status:    "error",
+			Error:     err.Error(),
+		}
+	}
+	return CompareManifest(ctx, dynClient, resolver, m.Namespace, m.App, m.Type, content)
+}
+
 // FormatDriftScanResults formats drift scan results as a markdown string.
 func FormatDriftScanResults(results *DriftScanResults) string {
 	if results.Total == 0 {
@@ -76,6 +138,9 @@ func FormatDriftScanResults(results *DriftScanResults) string {
 	}
 
 	s := fmt.Sprintf("**Drift scan:** %d manifests\n\n", results.Total)
+	if results.Skipped > 0 {
+		s = fmt.Sprintf("**Drift scan (interrupted):** %d of %d manifests checked\n\n", results.Total-results.Skipped, results.Total)
+	}
 	s += "| Resource | Status |\n"
 	s += "|----------|--------|\n"
 
@@ -109,6 +174,9 @@ func FormatDriftContext(results *DriftScanResults) string {
 
 	s := fmt.Sprintf("\n## Drift scan results\n%d stored manifests: %d in sync, %d drifted, %d not in cluster, %d errors.\n",
 		results.Total, results.InSync, results.Drifted, results.Missing, results.Errors)
+	if results.Skipped > 0 {
+		s += fmt.Sprintf("The scan was interrupted; %d manifests were not checked.\n", results.Skipped)
+	}
 
 	for _, r := range results.Results {
 		resource := fmt.Sprintf("%s/%s/%s", r.Namespace, r.Name, r.Kind)
@@ -124,6 +192,6 @@ func FormatDriftContext(results *DriftScanResults) string {
 		}
 	}
 
-	s += "\nUse the diff_resource tool to see detailed field-level differences for drifted resources.\n"
+	s += "\nUse the diff_resource tool to see detailed field-level differences for drifted resources. If a live change is intentional, use adopt_drift to record it in git instead of reverting it.\n"
 	return s
 }
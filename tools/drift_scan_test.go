@@ -0,0 +1,361 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/perbu/kasa/manifest"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func configMapYAML(name, value string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %s
+  namespace: default
+data:
+  key: %s
+`, name, value)
+}
+
+func newConfigMap(name, value string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]any{"name": name, "namespace": "default"},
+		"data":       map[string]any{"key": value},
+	}}
+}
+
+func TestRunDriftScan_Concurrent(t *testing.T) {
+	mgr := newTestManifestManager(t)
+
+	var live []runtime.Object
+	const n = 25
+	for i := range n {
+		name := fmt.Sprintf("cm-%02d", i)
+		if _, err := mgr.SaveManifest("default", name, "configmap", []byte(configMapYAML(name, "stored"))); err != nil {
+			t.Fatalf("SaveManifest: %v", err)
+		}
+		switch {
+		case i%5 == 0:
+			// missing from the cluster
+		case i%5 == 1:
+			live = append(live, newConfigMap(name, "changed"))
+		default:
+			live = append(live, newConfigMap(name, "stored"))
+		}
+	}
+	dynClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), live...)
+
+	var calls, last int
+	progress := func(done, total int, namespace, name, kind string) {
+		calls++
+		if done != last+1 || total != n {
+			t.Errorf("progress(%d, %d) after %d", done, total, last)
+		}
+		last = done
+	}
+
+	results, err := RunDriftScan(context.Background(), dynClient, nil, mgr, progress)
+	if err != nil {
+		t.Fatalf("RunDriftScan: %v", err)
+	}
+	if calls != n {
+		t.Errorf("progress called %d times, want %d", calls, n)
+	}
+	if results.Total != n || results.Skipped != 0 {
+		t.Errorf("Total=%d Skipped=%d, want %d, 0", results.Total, results.Skipped, n)
+	}
+	if results.Missing != 5 || results.Drifted != 5 || results.InSync != 15 || results.Errors != 0 {
+		t.Errorf("got in_sync=%d drifted=%d missing=%d errors=%d", results.InSync, results.Drifted, results.Missing, results.Errors)
+	}
+
+	// Results keep manifest order regardless of completion order
+	for i, r := range results.Results {
+		if want := fmt.Sprintf("cm-%02d", i); r.Name != want {
+			t.Fatalf("Results[%d].Name = %s, want %s", i, r.Name, want)
+		}
+	}
+}
+
+func TestRunDriftScan_CancelledReturnsPartial(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	for i := range 10 {
+		name := fmt.Sprintf("cm-%d", i)
+		if _, err := mgr.SaveManifest("default", name, "configmap", []byte(configMapYAML(name, "stored"))); err != nil {
+			t.Fatalf("SaveManifest: %v", err)
+		}
+	}
+	dynClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := RunDriftScan(ctx, dynClient, nil, mgr, nil)
+	if err != nil {
+		t.Fatalf("RunDriftScan: %v", err)
+	}
+	if results.Total != 10 {
+		t.Errorf("Total = %d, want 10", results.Total)
+	}
+	if results.Skipped != 10 || len(results.Results) != 0 {
+		t.Errorf("Skipped=%d, len(Results)=%d, want 10, 0", results.Skipped, len(results.Results))
+	}
+}
+
+func TestRemediationPolicy_Covers(t *testing.T) {
+	p := RemediationPolicy{Namespaces: []string{"staging"}, Apps: []string{"prod/web", "dev/api-*"}}
+	tests := []struct {
+		namespace, app string
+		want           bool
+	}{
+		{"staging", "anything", true},
+		{"prod", "web", true},
+		{"prod", "api", false},
+		{"dev", "api-v2", true},
+		{"default", "web", false},
+	}
+	for _, tt := range tests {
+		if got := p.Covers(tt.namespace, tt.app); got != tt.want {
+			t.Errorf("Covers(%q, %q) = %v, want %v", tt.namespace, tt.app, got, tt.want)
+		}
+	}
+}
+
+func TestRemediateDrift(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	for _, name := range []string{"covered", "uncovered", "gone"} {
+		if _, err := mgr.SaveManifest("default", name, "configmap", []byte(configMapYAML(name, "stored"))); err != nil {
+			t.Fatalf("SaveManifest: %v", err)
+		}
+	}
+	dynClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newConfigMap("covered", "changed"),
+		newConfigMap("uncovered", "changed"),
+	)
+
+	scan, err := RunDriftScan(context.Background(), dynClient, nil, mgr, nil)
+	if err != nil {
+		t.Fatalf("RunDriftScan: %v", err)
+	}
+	if scan.Drifted != 2 || scan.Missing != 1 {
+		t.Fatalf("drifted=%d missing=%d, want 2, 1", scan.Drifted, scan.Missing)
+	}
+
+	policy := RemediationPolicy{Apps: []string{"default/covered", "default/gone"}, RecreateMissing: true}
+	remediated := (&KubeTools{dynamicClient: dynClient, manifest: mgr}).RemediateDrift(context.Background(), policy, scan)
+	if len(remediated) != 2 {
+		t.Fatalf("remediated %d resources, want 2: %+v", len(remediated), remediated)
+	}
+	for _, r := range remediated {
+		if r.Error != "" {
+			t.Errorf("%s: %s", r.Name, r.Error)
+		}
+	}
+
+	scan, err = RunDriftScan(context.Background(), dynClient, nil, mgr, nil)
+	if err != nil {
+		t.Fatalf("RunDriftScan: %v", err)
+	}
+	if scan.InSync != 2 || scan.Drifted != 1 {
+		t.Errorf("after remediation: in_sync=%d drifted=%d, want 2, 1", scan.InSync, scan.Drifted)
+	}
+}
+
+func TestRemediateDrift_Guarded(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	if _, err := mgr.SaveManifest("default", "web", "configmap", []byte(configMapYAML("web", "stored"))); err != nil {
+		t.Fatalf("SaveManifest: %v", err)
+	}
+	dynClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), newConfigMap("web", "changed"))
+
+	k := &KubeTools{dynamicClient: dynClient, manifest: mgr}
+	err := k.SetMaintenanceWindows(MaintenancePolicy{
+		Timezone: "UTC",
+		Windows:  []MaintenanceWindow{{Namespaces: []string{"default"}, Start: "09:00", End: "17:00"}},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.windowGuard.now = func() time.Time { return time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC) }
+
+	scan, err := RunDriftScan(context.Background(), dynClient, nil, mgr, nil)
+	if err != nil {
+		t.Fatalf("RunDriftScan: %v", err)
+	}
+	policy := RemediationPolicy{Namespaces: []string{"default"}}
+
+	// Outside the maintenance window the drift is left alone
+	remediated := k.RemediateDrift(context.Background(), policy, scan)
+	if len(remediated) != 1 || !strings.Contains(remediated[0].Skipped, "maintenance windows") || remediated[0].Action != "" {
+		t.Fatalf("remediated %+v; want it skipped", remediated)
+	}
+	if again, _ := RunDriftScan(context.Background(), dynClient, nil, mgr, nil); again.Drifted != 1 {
+		t.Errorf("skipped remediation changed the cluster: %+v", again.Results)
+	}
+
+	if err := k.GrantWindowOverride("default", "INC-42"); err != nil {
+		t.Fatal(err)
+	}
+	remediated = k.RemediateDrift(context.Background(), policy, scan)
+	if len(remediated) != 1 || remediated[0].Skipped != "" || remediated[0].Action != "updated" {
+		t.Errorf("remediated %+v; want it applied under the override", remediated)
+	}
+}
+
+func TestClusterScopedManifests(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	clusterRole := `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+rules:
+- apiGroups: [""]
+  resources: ["pods"]
+  verbs: ["get", "list"]
+`
+	if _, err := mgr.SaveManifest(manifest.ClusterNamespace, "reader", "clusterrole", []byte(clusterRole)); err != nil {
+		t.Fatalf("SaveManifest: %v", err)
+	}
+	dynClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+
+	scan, err := RunDriftScan(context.Background(), dynClient, nil, mgr, nil)
+	if err != nil {
+		t.Fatalf("RunDriftScan: %v", err)
+	}
+	if scan.Missing != 1 || scan.Results[0].Namespace != manifest.ClusterNamespace {
+		t.Fatalf("expected the cluster role to be missing, got %+v", scan.Results)
+	}
+
+	policy := RemediationPolicy{Namespaces: []string{manifest.ClusterNamespace}, RecreateMissing: true}
+	remediated := (&KubeTools{dynamicClient: dynClient, manifest: mgr}).RemediateDrift(context.Background(), policy, scan)
+	if len(remediated) != 1 || remediated[0].Error != "" || remediated[0].Action != "created" {
+		t.Fatalf("unexpected remediation: %+v", remediated)
+	}
+
+	scan, err = RunDriftScan(context.Background(), dynClient, nil, mgr, nil)
+	if err != nil {
+		t.Fatalf("RunDriftScan: %v", err)
+	}
+	if scan.InSync != 1 {
+		t.Errorf("after recreate: %+v", scan.Results)
+	}
+
+	// Namespaced kinds can't be stored under the pseudo-namespace
+	_, err = applyStoredManifest(context.Background(), dynClient, nil, manifest.ClusterNamespace, []byte(configMapYAML("cm", "v")), false, "")
+	if err == nil {
+		t.Error("expected error applying a namespaced kind from _cluster")
+	}
+}
+
+func TestSyncDrift(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	for _, m := range []struct{ namespace, name string }{{"default", "drifted"}, {"default", "gone"}, {"other", "gone"}} {
+		content := strings.Replace(configMapYAML(m.name, "stored"), "namespace: default", "namespace: "+m.namespace, 1)
+		if _, err := mgr.SaveManifest(m.namespace, m.name, "configmap", []byte(content)); err != nil {
+			t.Fatalf("SaveManifest: %v", err)
+		}
+	}
+	dynClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), newConfigMap("drifted", "changed"))
+	// The fake client ignores dry-run; answer like the API server would
+	dryRunReactor := func(action k8stesting.Action) (bool, runtime.Object, error) {
+		switch a := action.(type) {
+		case k8stesting.CreateActionImpl:
+			return len(a.CreateOptions.DryRun) > 0, a.Object, nil
+		case k8stesting.UpdateActionImpl:
+			return len(a.UpdateOptions.DryRun) > 0, a.Object, nil
+		}
+		return false, nil, nil
+	}
+	dynClient.PrependReactor("create", "*", dryRunReactor)
+	dynClient.PrependReactor("update", "*", dryRunReactor)
+
+	scan, err := RunDriftScan(context.Background(), dynClient, nil, mgr, nil)
+	if err != nil {
+		t.Fatalf("RunDriftScan: %v", err)
+	}
+	k := &KubeTools{dynamicClient: dynClient, manifest: mgr}
+
+	// A dry run validates the changes but leaves the cluster alone
+	synced := k.SyncDrift(context.Background(), nil, true, scan)
+	if len(synced) != 3 {
+		t.Fatalf("dry run synced %d resources, want 3: %+v", len(synced), synced)
+	}
+	for _, r := range synced {
+		if !r.DryRun || r.Error != "" {
+			t.Errorf("dry run result %+v", r)
+		}
+	}
+	if again, _ := RunDriftScan(context.Background(), dynClient, nil, mgr, nil); again.InSync != 0 {
+		t.Errorf("dry run changed the cluster: %+v", again.Results)
+	}
+
+	// Missing resources are recreated and only the selected namespaces are touched
+	synced = k.SyncDrift(context.Background(), []string{"default"}, false, scan)
+	if len(synced) != 2 {
+		t.Fatalf("synced %d resources, want 2: %+v", len(synced), synced)
+	}
+	scan, err = RunDriftScan(context.Background(), dynClient, nil, mgr, nil)
+	if err != nil {
+		t.Fatalf("RunDriftScan: %v", err)
+	}
+	if scan.InSync != 2 || scan.Missing != 1 {
+		t.Errorf("after sync: in_sync=%d missing=%d, want 2, 1", scan.InSync, scan.Missing)
+	}
+}
+
+func TestSyncDrift_Guarded(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	for _, m := range []struct{ namespace, name string }{{"default", "app-config"}, {"default", "kube-config"}, {"secret", "app-config"}} {
+		content := strings.Replace(configMapYAML(m.name, "stored"), "namespace: default", "namespace: "+m.namespace, 1)
+		if _, err := mgr.SaveManifest(m.namespace, m.name, "configmap", []byte(content)); err != nil {
+			t.Fatalf("SaveManifest: %v", err)
+		}
+	}
+	dynClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), newConfigMap("app-config", "changed"), newConfigMap("kube-config", "changed"))
+
+	k := &KubeTools{dynamicClient: dynClient, manifest: mgr}
+	if err := k.SetNamespacePolicy(NamespacePolicy{Denied: []string{"secret"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.SetProtectedResources(ProtectionPolicy{Names: []string{"kube-*"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	scan, err := RunDriftScan(context.Background(), dynClient, nil, mgr, nil)
+	if err != nil {
+		t.Fatalf("RunDriftScan: %v", err)
+	}
+	synced := k.SyncDrift(context.Background(), nil, false, scan)
+	skipped := map[string]string{}
+	for _, r := range synced {
+		if r.Error != "" {
+			t.Errorf("%s/%s: %s", r.Namespace, r.Name, r.Error)
+		}
+		if r.Skipped != "" {
+			skipped[r.Namespace+"/"+r.Name] = r.Skipped
+		}
+	}
+	if len(synced) != 3 || len(skipped) != 2 {
+		t.Fatalf("synced %+v; want the protected resource and the denied namespace skipped", synced)
+	}
+	if !strings.Contains(skipped["default/kube-config"], "protected") || !strings.Contains(skipped["secret/app-config"], `"secret"`) {
+		t.Errorf("skipped = %v", skipped)
+	}
+
+	scan, err = RunDriftScan(context.Background(), dynClient, nil, mgr, nil)
+	if err != nil {
+		t.Fatalf("RunDriftScan: %v", err)
+	}
+	if scan.InSync != 1 || scan.Drifted != 1 || scan.Missing != 1 {
+		t.Errorf("after sync: in_sync=%d drifted=%d missing=%d, want only app-config in default synced", scan.InSync, scan.Drifted, scan.Missing)
+	}
+}
@@ -316,3 +316,88 @@ func TestDiffMaps_SliceShorterLive(t *testing.T) {
 		t.Errorf("expected items[2] removed, got %+v", diffs[1])
 	}
 }
+
+func TestDriftIgnore_Apply(t *testing.T) {
+	ig, err := ParseDriftIgnore([]byte(`
+# global
+spec.replicas
+metadata.annotations.deployment.kubernetes.io/revision
+
+default/web/deployment: spec.template.spec.containers[*].image
+prod/*/deployment: spec.paused
+`))
+	if err != nil {
+		t.Fatalf("ParseDriftIgnore: %v", err)
+	}
+
+	result := DriftResult{
+		Namespace: "default",
+		Name:      "web",
+		Kind:      "deployment",
+		Status:    "drifted",
+		Diffs: []DiffEntry{
+			{Path: "spec.replicas", ChangeType: "changed"},
+			{Path: "metadata.annotations.deployment.kubernetes.io/revision", ChangeType: "changed"},
+			{Path: "spec.template.spec.containers[1].image", ChangeType: "changed"},
+			{Path: "spec.paused", ChangeType: "changed"},
+		},
+	}
+
+	got := ig.Apply(result)
+	if got.Status != "drifted" {
+		t.Errorf("Status = %s, want drifted", got.Status)
+	}
+	if got.Ignored != 3 {
+		t.Errorf("Ignored = %d, want 3", got.Ignored)
+	}
+	if len(got.Diffs) != 1 || got.Diffs[0].Path != "spec.paused" {
+		t.Errorf("Diffs = %v, want only spec.paused", got.Diffs)
+	}
+
+	result.Namespace = "prod"
+	got = ig.Apply(result)
+	if got.Ignored != 3 || len(got.Diffs) != 1 || got.Diffs[0].Path != "spec.template.spec.containers[1].image" {
+		t.Errorf("prod: Ignored = %d, Diffs = %v", got.Ignored, got.Diffs)
+	}
+}
+
+func TestDriftIgnore_AllIgnoredIsInSync(t *testing.T) {
+	ig, err := ParseDriftIgnore([]byte("spec.template\n"))
+	if err != nil {
+		t.Fatalf("ParseDriftIgnore: %v", err)
+	}
+	got := ig.Apply(DriftResult{
+		Status: "drifted",
+		Diffs:  []DiffEntry{{Path: "spec.template.spec.containers[0].image", ChangeType: "changed"}},
+	})
+	if got.Status != "in_sync" || len(got.Diffs) != 0 || got.Ignored != 1 {
+		t.Errorf("got status=%s diffs=%d ignored=%d", got.Status, len(got.Diffs), got.Ignored)
+	}
+
+	// Prefix matching is per path segment
+	got = ig.Apply(DriftResult{
+		Status: "drifted",
+		Diffs:  []DiffEntry{{Path: "spec.templateHash", ChangeType: "changed"}},
+	})
+	if got.Status != "drifted" {
+		t.Errorf("spec.templateHash should not match spec.template")
+	}
+}
+
+func TestParseDriftIgnore_Invalid(t *testing.T) {
+	for _, content := range []string{
+		"web/deployment: spec.replicas",
+		"default/web/deployment: ",
+		"default/[/deployment: spec.replicas",
+	} {
+		if _, err := ParseDriftIgnore([]byte(content)); err == nil {
+			t.Errorf("expected error for %q", content)
+		}
+	}
+
+	var ig *DriftIgnore
+	r := DriftResult{Status: "drifted", Diffs: []DiffEntry{{Path: "spec.replicas"}}}
+	if got := ig.Apply(r); got.Status != "drifted" {
+		t.Error("nil DriftIgnore should ignore nothing")
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/perbu/kasa/manifest"
 	"google.golang.org/adk/model"
@@ -128,7 +127,7 @@ func (t *DryRunApplyTool) Run(ctx tool.Context, args any) (map[string]any, error
 	}
 
 	// Validate with dry-run
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
 	defer cancel()
 
 	err = t.dryRunApply(timeoutCtx, namespace, resourceType, content)
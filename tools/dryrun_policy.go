@@ -0,0 +1,247 @@
+package tools
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	"google.golang.org/adk/tool"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+)
+
+// DryRunPolicy lists where mutating tools must run as a server-side dry-run
+// until the user explicitly confirms the change a second time.
+type DryRunPolicy struct {
+	Namespaces []string `yaml:"namespaces"` // e.g. ["prod"]
+	Tools      []string `yaml:"tools"`      // enforced in every namespace, e.g. ["delete_namespace"]
+}
+
+// Enforced reports whether a call to toolName targeting namespace must be dry-run.
+func (p DryRunPolicy) Enforced(toolName, namespace string) bool {
+	if slices.Contains(p.Tools, toolName) {
+		return true
+	}
+	return namespace != "" && slices.Contains(p.Namespaces, namespace)
+}
+
+// IsEmpty reports whether the policy enforces nothing.
+func (p DryRunPolicy) IsEmpty() bool {
+	return len(p.Namespaces) == 0 && len(p.Tools) == 0
+}
+
+// dryRunGuard tracks one-shot confirmations that let an enforced call through.
+type dryRunGuard struct {
+	policy       DryRunPolicy
+	dryClientset *kubernetes.Clientset
+	dryDynamic   dynamic.Interface
+
+	mu        sync.Mutex
+	confirmed map[string]int
+}
+
+// confirmationKey identifies the dry run the user confirmed: the tool, the
+// namespace and argsKey of its arguments.
+func confirmationKey(toolName, namespace, args string) string {
+	return toolName + "/" + namespace + "/" + args
+}
+
+// confirm grants a single real execution of toolName in namespace with the
+// arguments args (an argsKey) of the dry run.
+func (g *dryRunGuard) confirm(toolName, namespace, args string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.confirmed[confirmationKey(toolName, namespace, args)]++
+}
+
+// consume uses up the confirmation of a call with these arguments,
+// returning false if none was granted. A call with other arguments is dry
+// run again.
+func (g *dryRunGuard) consume(toolName, namespace string, args map[string]any) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := confirmationKey(toolName, namespace, argsKey(args))
+	if g.confirmed[key] == 0 {
+		return false
+	}
+	g.confirmed[key]--
+	return true
+}
+
+// SetDryRunPolicy enforces server-side dry-run for the mutating tools and
+// namespaces in policy. dryClientset and dryDynamic must send every write
+// with dryRun=All (see NewDryRunClients).
+func (k *KubeTools) SetDryRunPolicy(policy DryRunPolicy, dryClientset *kubernetes.Clientset, dryDynamic dynamic.Interface) {
+	if policy.IsEmpty() {
+		k.dryRunGuard = nil
+		return
+	}
+	k.dryRunGuard = &dryRunGuard{
+		policy:       policy,
+		dryClientset: dryClientset,
+		dryDynamic:   dryDynamic,
+		confirmed:    make(map[string]int),
+	}
+}
+
+// ConfirmDryRun lets the next call to toolName in namespace with the
+// arguments of the dry run apply for real; key is the confirmation_key of
+// the dry-run result. It must only be called on an explicit user decision,
+// never by the agent.
+func (k *KubeTools) ConfirmDryRun(toolName, namespace, key string) {
+	if k.dryRunGuard != nil {
+		k.dryRunGuard.confirm(toolName, namespace, key)
+	}
+}
+
+// enforceDryRun returns middleware that runs the dry-run variant of a
+// mutating tool when the policy covers the call, unless the user has
+// confirmed it.
+func (k *KubeTools) enforceDryRun() Middleware {
+	// Same configuration, but backed by dry-run clients and manifest manager
+	dry := *k
+	dry.clientset = k.dryRunGuard.dryClientset
+	dry.dynamicClient = k.dryRunGuard.dryDynamic
+	dry.manifest = k.manifest.DryRun()
+	dry.dryRunGuard = nil
+
+	dryTools := make(map[string]runnableTool)
+	for _, t := range dry.baseTools() {
+		if rt, ok := t.(runnableTool); ok {
+			dryTools[t.Name()] = rt
+		}
+	}
+
+	return k.dryRunGuard.middleware(dryTools)
+}
+
+// middleware runs the dry-run variant from dryTools of a mutating call the
+// policy covers, unless the user has confirmed a dry run with the same
+// arguments. Custom tools have no dry-run variant: they don't run at all
+// until the user has confirmed the call.
+func (g *dryRunGuard) middleware(dryTools map[string]runnableTool) Middleware {
+	return func(t ToolInfo, next RunFunc) RunFunc {
+		dryTool, ok := dryTools[t.Name]
+		if (!ok && !t.Custom) || t.Category != CategoryMutating {
+			return next
+		}
+		return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			name := t.Name
+			namespace := targetNamespace(name, args)
+			if !g.policy.Enforced(name, namespace) || g.consume(name, namespace, args) {
+				return next(ctx, args)
+			}
+			scope := fmt.Sprintf("namespace %q", namespace)
+			if slices.Contains(g.policy.Tools, name) {
+				scope = fmt.Sprintf("tool %s", name)
+			}
+			if dryTool == nil {
+				return map[string]any{
+					"confirmation_required": true,
+					"tool":                  name,
+					"namespace":             namespace,
+					"confirmation_key":      argsKey(args),
+					"message": fmt.Sprintf("Dry-run is enforced for %s, and %s is a custom tool that cannot be dry-run, so it was NOT called. "+
+						"Stop here and tell the user what the call would do. Do not call %s again until the user confirms; "+
+						"the confirmation only applies to a call with exactly these arguments.", scope, name, name),
+				}, nil
+			}
+
+			result, err := dryTool.Run(ctx, args)
+			if err != nil {
+				return nil, err
+			}
+			if _, failed := result["error"]; failed {
+				result["dry_run"] = true
+				return result, nil
+			}
+
+			return map[string]any{
+				"dry_run":               true,
+				"confirmation_required": true,
+				"tool":                  name,
+				"namespace":             namespace,
+				"confirmation_key":      argsKey(args),
+				"dry_run_result":        result,
+				"message": fmt.Sprintf("Dry-run is enforced for %s: the change was validated by the API server but NOT applied. "+
+					"Stop here and tell the user what would change. Do not call %s again until the user confirms; "+
+					"the confirmation only applies to a call with exactly these arguments.", scope, name),
+			}, nil
+		}
+	}
+}
+
+// runnableTool is a function tool that can be executed.
+type runnableTool interface {
+	functionTool
+	Run(ctx tool.Context, args any) (map[string]any, error)
+}
+
+// targetNamespace returns the namespace a mutating tool call will change.
+func targetNamespace(toolName string, args map[string]any) string {
+	switch toolName {
+	case "create_namespace", "delete_namespace":
+		name, _ := args["name"].(string)
+		return name
+	}
+	if ns, _ := args["namespace"].(string); ns != "" {
+		return ns
+	}
+	// apply_resource takes the namespace from the YAML when not given
+	if content, _ := args["yaml"].(string); content != "" {
+		var obj struct {
+			Metadata struct {
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		}
+		if err := yaml.Unmarshal([]byte(content), &obj); err == nil && obj.Metadata.Namespace != "" {
+			return obj.Metadata.Namespace
+		}
+		return "default"
+	}
+	return ""
+}
+
+// NewDryRunClients returns clients whose write requests (POST, PUT, PATCH,
+// DELETE) all carry dryRun=All, so the API server validates and admits them
+// without persisting anything. creds, if not nil, refreshes their expired
+// credentials like those of the other clients.
+func NewDryRunClients(config *rest.Config, creds *CredentialRefresher) (*kubernetes.Clientset, dynamic.Interface, error) {
+	config = rest.CopyConfig(config)
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return dryRunTransport{next: rt}
+	})
+
+	httpClient, err := creds.HTTPClient(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating dry-run HTTP client: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfigAndClient(config, httpClient)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating dry-run kubernetes client: %w", err)
+	}
+	dynamicClient, err := dynamic.NewForConfigAndClient(config, httpClient)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating dry-run dynamic client: %w", err)
+	}
+	return clientset, dynamicClient, nil
+}
+
+// dryRunTransport adds dryRun=All to every write request.
+type dryRunTransport struct {
+	next http.RoundTripper
+}
+
+func (t dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		req = req.Clone(req.Context())
+		q := req.URL.Query()
+		q.Set("dryRun", "All")
+		req.URL.RawQuery = q.Encode()
+	}
+	return t.next.RoundTrip(req)
+}
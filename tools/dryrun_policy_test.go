@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/adk/tool"
+)
+
+func TestDryRunPolicy_Enforced(t *testing.T) {
+	p := DryRunPolicy{Namespaces: []string{"prod"}, Tools: []string{"delete_namespace"}}
+
+	tests := []struct {
+		tool, namespace string
+		want            bool
+	}{
+		{"create_deployment", "prod", true},
+		{"create_deployment", "staging", false},
+		{"create_deployment", "", false},
+		{"delete_namespace", "staging", true},
+		{"commit_manifests", "", false},
+	}
+	for _, tt := range tests {
+		if got := p.Enforced(tt.tool, tt.namespace); got != tt.want {
+			t.Errorf("Enforced(%q, %q) = %v, want %v", tt.tool, tt.namespace, got, tt.want)
+		}
+	}
+}
+
+func TestDryRunGuard_ConfirmIsOneShot(t *testing.T) {
+	g := &dryRunGuard{confirmed: make(map[string]int)}
+	args := map[string]any{"namespace": "prod", "name": "web"}
+
+	if g.consume("create_deployment", "prod", args) {
+		t.Fatal("expected no confirmation before confirm")
+	}
+	g.confirm("create_deployment", "prod", argsKey(args))
+	if g.consume("create_deployment", "staging", args) {
+		t.Error("confirmation must not apply to another namespace")
+	}
+	if !g.consume("create_deployment", "prod", args) {
+		t.Error("expected confirmation to be consumed")
+	}
+	if g.consume("create_deployment", "prod", args) {
+		t.Error("confirmation must only be usable once")
+	}
+}
+
+func TestDryRunGuard_ChangedArgs(t *testing.T) {
+	g := &dryRunGuard{policy: DryRunPolicy{Namespaces: []string{"prod"}}, confirmed: make(map[string]int)}
+	dry := &recordingTool{name: "scale_deployment"}
+	real := &recordingTool{name: "scale_deployment"}
+	wrapped := withMiddleware([]tool.Tool{real}, g.middleware(map[string]runnableTool{"scale_deployment": dry}))[0].(runnableTool)
+
+	result, _ := wrapped.Run(nil, map[string]any{"namespace": "prod", "name": "web", "replicas": 3, "reason": "scale up"})
+	key, _ := result["confirmation_key"].(string)
+	if key == "" || dry.calls != 1 || real.calls != 0 {
+		t.Fatalf("expected a dry run with a confirmation key, got %v", result)
+	}
+
+	// The model rewrites the reason; the call is still the one confirmed
+	g.confirm("scale_deployment", "prod", key)
+	wrapped.Run(nil, map[string]any{"namespace": "prod", "name": "web", "replicas": 3, "reason": "as confirmed", "timeout_seconds": 30})
+	if real.calls != 1 {
+		t.Fatalf("expected the confirmed call to run, got %d calls", real.calls)
+	}
+
+	g.confirm("scale_deployment", "prod", key)
+	result, _ = wrapped.Run(nil, map[string]any{"namespace": "prod", "name": "web", "replicas": 5, "reason": "scale up"})
+	if dryRun, _ := result["dry_run"].(bool); !dryRun || real.calls != 1 || dry.calls != 2 {
+		t.Errorf("expected a call with other arguments to be dry-run again, got %v", result)
+	}
+}
+
+func TestTargetNamespace(t *testing.T) {
+	tests := []struct {
+		name string
+		tool string
+		args map[string]any
+		want string
+	}{
+		{"namespace arg", "create_deployment", map[string]any{"namespace": "prod"}, "prod"},
+		{"namespace tools use name", "delete_namespace", map[string]any{"name": "prod"}, "prod"},
+		{"namespace from yaml", "apply_resource", map[string]any{"yaml": "kind: ConfigMap\nmetadata:\n  name: x\n  namespace: prod\n"}, "prod"},
+		{"yaml without namespace", "apply_resource", map[string]any{"yaml": "kind: ConfigMap\nmetadata:\n  name: x\n"}, "default"},
+		{"no namespace", "commit_manifests", map[string]any{"message": "x"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := targetNamespace(tt.tool, tt.args); got != tt.want {
+				t.Errorf("targetNamespace() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDryRunTransport(t *testing.T) {
+	gotQuery := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery[r.Method] = r.URL.Query().Get("dryRun")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: dryRunTransport{next: http.DefaultTransport}}
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		req, _ := http.NewRequest(method, srv.URL+"/api/v1/namespaces/prod/configmaps?fieldManager=kasa", nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		resp.Body.Close()
+	}
+
+	if gotQuery[http.MethodGet] != "" {
+		t.Errorf("GET must not be dry-run, got dryRun=%q", gotQuery[http.MethodGet])
+	}
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		if gotQuery[method] != "All" {
+			t.Errorf("%s: expected dryRun=All, got %q", method, gotQuery[method])
+		}
+	}
+}
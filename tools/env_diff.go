@@ -0,0 +1,427 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// EnvValue is a single resolved environment variable.
+type EnvValue struct {
+	Value     string `json:"value"`
+	Source    string `json:"source"`    // e.g. "env", "configmap:app-config", "secret:app-secret"
+	Sensitive bool   `json:"sensitive"` // true when the value comes from a Secret
+}
+
+// EnvDiffEntry describes a key whose value differs between the two sides.
+type EnvDiffEntry struct {
+	Key     string `json:"key"`
+	ValueA  string `json:"value_a"`
+	ValueB  string `json:"value_b"`
+	SourceA string `json:"source_a"`
+	SourceB string `json:"source_b"`
+}
+
+// EnvDiffResult holds the outcome of comparing two resolved environments.
+type EnvDiffResult struct {
+	Differing []EnvDiffEntry `json:"differing"`
+	OnlyInA   []string       `json:"only_in_a"`
+	OnlyInB   []string       `json:"only_in_b"`
+	Identical int            `json:"identical"`
+}
+
+// DiffEnvTool provides the diff_env tool for the agent.
+type DiffEnvTool struct {
+	clientset *kubernetes.Clientset
+}
+
+// NewDiffEnvTool creates a new DiffEnvTool.
+func NewDiffEnvTool(clientset *kubernetes.Clientset) *DiffEnvTool {
+	return &DiffEnvTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *DiffEnvTool) Name() string {
+	return "diff_env"
+}
+
+// Description returns the tool description.
+func (t *DiffEnvTool) Description() string {
+	return "Compare the effective environment variables (env and envFrom, with ConfigMap/Secret references resolved) of the same workload in two namespaces. Reports keys that differ or are missing on either side. Secret values are compared but never shown."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *DiffEnvTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *DiffEnvTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *DiffEnvTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *DiffEnvTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"name": {
+					Type:        "string",
+					Description: "The workload name in the first namespace",
+				},
+				"namespace_a": {
+					Type:        "string",
+					Description: "The first namespace (e.g., staging)",
+				},
+				"namespace_b": {
+					Type:        "string",
+					Description: "The second namespace (e.g., production)",
+				},
+				"name_b": {
+					Type:        "string",
+					Description: "Workload name in the second namespace, if it differs from name",
+				},
+				"kind": {
+					Type:        "string",
+					Description: "Workload kind: deployment, statefulset, or daemonset (default: deployment)",
+				},
+				"container": {
+					Type:        "string",
+					Description: "Container name to compare (default: first container)",
+				},
+			},
+			Required: []string{"name", "namespace_a", "namespace_b"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *DiffEnvTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+
+	namespaceA, ok := argsMap["namespace_a"].(string)
+	if !ok || namespaceA == "" {
+		return map[string]any{"error": "namespace_a is required"}, nil
+	}
+
+	namespaceB, ok := argsMap["namespace_b"].(string)
+	if !ok || namespaceB == "" {
+		return map[string]any{"error": "namespace_b is required"}, nil
+	}
+
+	nameB := name
+	if nb, ok := argsMap["name_b"].(string); ok && nb != "" {
+		nameB = nb
+	}
+
+	kind := "deployment"
+	if k, ok := argsMap["kind"].(string); ok && k != "" {
+		kind = NormalizeKindName(k)
+	}
+
+	containerName := ""
+	if c, ok := argsMap["container"].(string); ok {
+		containerName = c
+	}
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
+	defer cancel()
+
+	podSpecA, err := t.getPodSpec(timeoutCtx, kind, namespaceA, name)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+	podSpecB, err := t.getPodSpec(timeoutCtx, kind, namespaceB, nameB)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	containerA, err := findContainer(podSpecA, containerName)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("%s/%s: %v", namespaceA, name, err)}, nil
+	}
+	containerB, err := findContainer(podSpecB, containerName)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("%s/%s: %v", namespaceB, nameB, err)}, nil
+	}
+
+	envA, warningsA := t.resolveEnv(timeoutCtx, namespaceA, containerA)
+	envB, warningsB := t.resolveEnv(timeoutCtx, namespaceB, containerB)
+
+	diff := DiffEnv(envA, envB)
+
+	result := map[string]any{
+		"a":          fmt.Sprintf("%s/%s", namespaceA, name),
+		"b":          fmt.Sprintf("%s/%s", namespaceB, nameB),
+		"kind":       kind,
+		"container":  containerA.Name,
+		"differing":  diff.Differing,
+		"only_in_a":  diff.OnlyInA,
+		"only_in_b":  diff.OnlyInB,
+		"identical":  diff.Identical,
+		"total_keys": len(diff.Differing) + len(diff.OnlyInA) + len(diff.OnlyInB) + diff.Identical,
+	}
+
+	if warnings := append(warningsA, warningsB...); len(warnings) > 0 {
+		result["warnings"] = warnings
+	}
+
+	if len(diff.Differing) == 0 && len(diff.OnlyInA) == 0 && len(diff.OnlyInB) == 0 {
+		result["summary"] = fmt.Sprintf("Environments are identical (%d keys)", diff.Identical)
+	} else {
+		result["summary"] = fmt.Sprintf("%d differing, %d only in %s, %d only in %s, %d identical",
+			len(diff.Differing), len(diff.OnlyInA), namespaceA, len(diff.OnlyInB), namespaceB, diff.Identical)
+	}
+
+	return result, nil
+}
+
+// getPodSpec fetches the pod template spec for a workload.
+func (t *DiffEnvTool) getPodSpec(ctx context.Context, kind, namespace, name string) (*corev1.PodSpec, error) {
+	switch kind {
+	case "deployment":
+		d, err := t.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deployment %s/%s: %v", namespace, name, err)
+		}
+		return &d.Spec.Template.Spec, nil
+	case "statefulset":
+		s, err := t.clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get statefulset %s/%s: %v", namespace, name, err)
+		}
+		return &s.Spec.Template.Spec, nil
+	case "daemonset":
+		d, err := t.clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get daemonset %s/%s: %v", namespace, name, err)
+		}
+		return &d.Spec.Template.Spec, nil
+	default:
+		return nil, fmt.Errorf("unsupported kind: %s. Supported kinds: deployment, statefulset, daemonset", kind)
+	}
+}
+
+// findContainer returns the named container, or the first one if name is empty.
+func findContainer(spec *corev1.PodSpec, name string) (*corev1.Container, error) {
+	if len(spec.Containers) == 0 {
+		return nil, fmt.Errorf("no containers in pod spec")
+	}
+	if name == "" {
+		return &spec.Containers[0], nil
+	}
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == name {
+			return &spec.Containers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("container %q not found", name)
+}
+
+// resolveEnv computes the effective environment of a container. envFrom sources
+// are applied first in order, then explicit env entries override them, matching
+// how the kubelet builds the environment. Problems resolving references are
+// returned as warnings rather than failing the whole comparison.
+func (t *DiffEnvTool) resolveEnv(ctx context.Context, namespace string, c *corev1.Container) (map[string]EnvValue, []string) {
+	env := make(map[string]EnvValue)
+	var warnings []string
+
+	configMaps := make(map[string]*corev1.ConfigMap)
+	secrets := make(map[string]*corev1.Secret)
+
+	getConfigMap := func(name string) (*corev1.ConfigMap, error) {
+		if cm, ok := configMaps[name]; ok {
+			return cm, nil
+		}
+		cm, err := t.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		configMaps[name] = cm
+		return cm, nil
+	}
+
+	getSecret := func(name string) (*corev1.Secret, error) {
+		if s, ok := secrets[name]; ok {
+			return s, nil
+		}
+		s, err := t.clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		secrets[name] = s
+		return s, nil
+	}
+
+	for _, from := range c.EnvFrom {
+		switch {
+		case from.ConfigMapRef != nil:
+			cm, err := getConfigMap(from.ConfigMapRef.Name)
+			if err != nil {
+				if !isOptional(from.ConfigMapRef.Optional) {
+					warnings = append(warnings, fmt.Sprintf("%s: envFrom configmap %s: %v", namespace, from.ConfigMapRef.Name, err))
+				}
+				continue
+			}
+			for k, v := range cm.Data {
+				env[from.Prefix+k] = EnvValue{Value: v, Source: "configmap:" + cm.Name}
+			}
+		case from.SecretRef != nil:
+			s, err := getSecret(from.SecretRef.Name)
+			if err != nil {
+				if !isOptional(from.SecretRef.Optional) {
+					warnings = append(warnings, fmt.Sprintf("%s: envFrom secret %s: %v", namespace, from.SecretRef.Name, err))
+				}
+				continue
+			}
+			for k, v := range s.Data {
+				env[from.Prefix+k] = EnvValue{Value: string(v), Source: "secret:" + s.Name, Sensitive: true}
+			}
+		}
+	}
+
+	for _, e := range c.Env {
+		if e.ValueFrom == nil {
+			env[e.Name] = EnvValue{Value: e.Value, Source: "env"}
+			continue
+		}
+
+		switch {
+		case e.ValueFrom.ConfigMapKeyRef != nil:
+			ref := e.ValueFrom.ConfigMapKeyRef
+			cm, err := getConfigMap(ref.Name)
+			if err != nil {
+				if !isOptional(ref.Optional) {
+					warnings = append(warnings, fmt.Sprintf("%s: %s references configmap %s: %v", namespace, e.Name, ref.Name, err))
+				}
+				continue
+			}
+			v, ok := cm.Data[ref.Key]
+			if !ok {
+				if !isOptional(ref.Optional) {
+					warnings = append(warnings, fmt.Sprintf("%s: %s references missing key %s in configmap %s", namespace, e.Name, ref.Key, ref.Name))
+				}
+				continue
+			}
+			env[e.Name] = EnvValue{Value: v, Source: "configmap:" + ref.Name}
+		case e.ValueFrom.SecretKeyRef != nil:
+			ref := e.ValueFrom.SecretKeyRef
+			s, err := getSecret(ref.Name)
+			if err != nil {
+				if !isOptional(ref.Optional) {
+					warnings = append(warnings, fmt.Sprintf("%s: %s references secret %s: %v", namespace, e.Name, ref.Name, err))
+				}
+				continue
+			}
+			v, ok := s.Data[ref.Key]
+			if !ok {
+				if !isOptional(ref.Optional) {
+					warnings = append(warnings, fmt.Sprintf("%s: %s references missing key %s in secret %s", namespace, e.Name, ref.Key, ref.Name))
+				}
+				continue
+			}
+			env[e.Name] = EnvValue{Value: string(v), Source: "secret:" + ref.Name, Sensitive: true}
+		case e.ValueFrom.FieldRef != nil:
+			env[e.Name] = EnvValue{Value: "fieldRef:" + e.ValueFrom.FieldRef.FieldPath, Source: "fieldRef"}
+		case e.ValueFrom.ResourceFieldRef != nil:
+			env[e.Name] = EnvValue{Value: "resourceFieldRef:" + e.ValueFrom.ResourceFieldRef.Resource, Source: "resourceFieldRef"}
+		}
+	}
+
+	return env, warnings
+}
+
+// isOptional reports whether an optional reference flag is set.
+func isOptional(optional *bool) bool {
+	return optional != nil && *optional
+}
+
+// DiffEnv compares two resolved environments. Values sourced from Secrets are
+// compared but replaced with [REDACTED] in the returned entries.
+func DiffEnv(a, b map[string]EnvValue) EnvDiffResult {
+	result := EnvDiffResult{
+		Differing: []EnvDiffEntry{},
+		OnlyInA:   []string{},
+		OnlyInB:   []string{},
+	}
+
+	keys := make([]string, 0, len(a)+len(b))
+	seen := make(map[string]bool)
+	for k := range a {
+		keys = append(keys, k)
+		seen[k] = true
+	}
+	for k := range b {
+		if !seen[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		va, inA := a[k]
+		vb, inB := b[k]
+		switch {
+		case !inB:
+			result.OnlyInA = append(result.OnlyInA, k)
+		case !inA:
+			result.OnlyInB = append(result.OnlyInB, k)
+		case va.Value == vb.Value:
+			result.Identical++
+		default:
+			result.Differing = append(result.Differing, EnvDiffEntry{
+				Key:     k,
+				ValueA:  displayEnvValue(va),
+				ValueB:  displayEnvValue(vb),
+				SourceA: va.Source,
+				SourceB: vb.Source,
+			})
+		}
+	}
+
+	return result
+}
+
+// displayEnvValue returns the value to show to the user, hiding secret data.
+func displayEnvValue(v EnvValue) string {
+	if v.Sensitive {
+		return "[REDACTED]"
+	}
+	if strings.Contains(v.Value, "\n") {
+		return strings.SplitN(v.Value, "\n", 2)[0] + "... (multi-line)"
+	}
+	return v.Value
+}
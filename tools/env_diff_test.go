@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"testing"
+)
+
+func TestDiffEnv_Identical(t *testing.T) {
+	a := map[string]EnvValue{
+		"LOG_LEVEL": {Value: "info", Source: "env"},
+		"PORT":      {Value: "8080", Source: "configmap:app"},
+	}
+	b := map[string]EnvValue{
+		"LOG_LEVEL": {Value: "info", Source: "configmap:app"},
+		"PORT":      {Value: "8080", Source: "env"},
+	}
+
+	diff := DiffEnv(a, b)
+	if diff.Identical != 2 {
+		t.Errorf("expected 2 identical keys, got %d", diff.Identical)
+	}
+	if len(diff.Differing) != 0 || len(diff.OnlyInA) != 0 || len(diff.OnlyInB) != 0 {
+		t.Errorf("expected no differences, got %+v", diff)
+	}
+}
+
+func TestDiffEnv_MissingKeys(t *testing.T) {
+	a := map[string]EnvValue{
+		"SHARED":  {Value: "x", Source: "env"},
+		"ONLY_A":  {Value: "a", Source: "env"},
+		"ONLY_A2": {Value: "a", Source: "env"},
+	}
+	b := map[string]EnvValue{
+		"SHARED": {Value: "x", Source: "env"},
+		"ONLY_B": {Value: "b", Source: "env"},
+	}
+
+	diff := DiffEnv(a, b)
+	if len(diff.OnlyInA) != 2 || diff.OnlyInA[0] != "ONLY_A" || diff.OnlyInA[1] != "ONLY_A2" {
+		t.Errorf("expected only_in_a [ONLY_A ONLY_A2], got %v", diff.OnlyInA)
+	}
+	if len(diff.OnlyInB) != 1 || diff.OnlyInB[0] != "ONLY_B" {
+		t.Errorf("expected only_in_b [ONLY_B], got %v", diff.OnlyInB)
+	}
+	if diff.Identical != 1 {
+		t.Errorf("expected 1 identical key, got %d", diff.Identical)
+	}
+}
+
+func TestDiffEnv_SecretValuesRedacted(t *testing.T) {
+	a := map[string]EnvValue{
+		"DB_PASSWORD": {Value: "staging-pass", Source: "secret:db", Sensitive: true},
+		"DB_HOST":     {Value: "db.staging", Source: "env"},
+	}
+	b := map[string]EnvValue{
+		"DB_PASSWORD": {Value: "prod-pass", Source: "secret:db", Sensitive: true},
+		"DB_HOST":     {Value: "db.prod", Source: "env"},
+	}
+
+	diff := DiffEnv(a, b)
+	if len(diff.Differing) != 2 {
+		t.Fatalf("expected 2 differing keys, got %d: %+v", len(diff.Differing), diff.Differing)
+	}
+
+	// Keys are sorted: DB_HOST before DB_PASSWORD
+	host := diff.Differing[0]
+	if host.ValueA != "db.staging" || host.ValueB != "db.prod" {
+		t.Errorf("expected plain values for DB_HOST, got %q / %q", host.ValueA, host.ValueB)
+	}
+
+	pass := diff.Differing[1]
+	if pass.ValueA != "[REDACTED]" || pass.ValueB != "[REDACTED]" {
+		t.Errorf("expected secret values to be redacted, got %q / %q", pass.ValueA, pass.ValueB)
+	}
+}
@@ -0,0 +1,303 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// defaultWatchSeconds is how long watch_events watches by default.
+	defaultWatchSeconds = 60
+	// maxWatchSeconds caps how long one watch_events call watches.
+	maxWatchSeconds = 600
+	// defaultWatchEvents is how many events end a watch_events call early.
+	defaultWatchEvents = 50
+	// rewatchDelay is the pause before an event watch that failed is
+	// established again.
+	rewatchDelay = 2 * time.Second
+)
+
+// WarningEvent is a Warning event seen by an event watch.
+type WarningEvent struct {
+	Time      time.Time `json:"time"`
+	Namespace string    `json:"namespace"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Reason    string    `json:"reason"`
+	Message   string    `json:"message"`
+	Count     int32     `json:"count,omitempty"` // times it happened so far
+}
+
+// String returns the event on one line, e.g. "15:04:05 prod Pod/web-7d9f
+// BackOff (x12): Back-off restarting failed container".
+func (e WarningEvent) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s/%s %s", e.Time.Local().Format(time.TimeOnly), e.Namespace, e.Kind, e.Name, e.Reason)
+	if e.Count > 1 {
+		fmt.Fprintf(&b, " (x%d)", e.Count)
+	}
+	b.WriteString(": ")
+	b.WriteString(strings.TrimSpace(e.Message))
+	return b.String()
+}
+
+// watchWarnings calls fn with each Warning event that happens in namespace
+// ("" for all) to an object of kind ("" for any) until ctx is cancelled,
+// including repeats of an event, which update its count. Events from before
+// the watch started are skipped. Watches the API server closes are
+// established again.
+func watchWarnings(ctx context.Context, cs kubernetes.Interface, namespace, kind string, fn func(WarningEvent)) error {
+	selector := "type=" + corev1.EventTypeWarning
+	if kind != "" {
+		selector += ",involvedObject.kind=" + kind
+	}
+	started := time.Now().Add(-time.Second)
+
+	resourceVersion := ""
+	for {
+		if resourceVersion == "" {
+			// Listing gives the version to watch from, so only new events arrive
+			listCtx, cancel := apiContext(ctx)
+			list, err := cs.CoreV1().Events(namespace).List(listCtx, metav1.ListOptions{FieldSelector: selector, Limit: 1})
+			cancel()
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("listing events: %w", err)
+			}
+			resourceVersion = list.ResourceVersion
+		}
+
+		w, err := cs.CoreV1().Events(namespace).Watch(ctx, metav1.ListOptions{
+			FieldSelector:       selector,
+			ResourceVersion:     resourceVersion,
+			AllowWatchBookmarks: true,
+		})
+		if err != nil {
+			if apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err) || apierrors.IsBadRequest(err) {
+				return fmt.Errorf("watching events: %w", err)
+			}
+			if !pause(ctx, rewatchDelay) {
+				return nil
+			}
+			continue
+		}
+		resourceVersion = deliverWarnings(ctx, w, resourceVersion, kind, started, fn)
+		w.Stop()
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// deliverWarnings passes the Warning events from w to fn until the watch
+// ends or ctx is cancelled, and returns the resource version to watch from
+// next, or "" if it expired and must be listed again.
+func deliverWarnings(ctx context.Context, w watch.Interface, resourceVersion, kind string, started time.Time, fn func(WarningEvent)) string {
+	for {
+		var ev watch.Event
+		select {
+		case <-ctx.Done():
+			return resourceVersion
+		case e, ok := <-w.ResultChan():
+			if !ok {
+				return resourceVersion
+			}
+			ev = e
+		}
+		switch ev.Type {
+		case watch.Error:
+			if status, ok := ev.Object.(*metav1.Status); ok && (status.Code == http.StatusGone || status.Reason == metav1.StatusReasonExpired) {
+				return ""
+			}
+			return resourceVersion
+		case watch.Bookmark:
+			if event, ok := ev.Object.(*corev1.Event); ok {
+				resourceVersion = event.ResourceVersion
+			}
+			continue
+		case watch.Added, watch.Modified:
+		default:
+			continue
+		}
+		event, ok := ev.Object.(*corev1.Event)
+		if !ok {
+			continue
+		}
+		resourceVersion = event.ResourceVersion
+		// The field selector already filters, but not every server applies it
+		if event.Type != corev1.EventTypeWarning || (kind != "" && !strings.EqualFold(event.InvolvedObject.Kind, kind)) {
+			continue
+		}
+		at := eventTime(*event)
+		if at.Before(started) {
+			continue
+		}
+		fn(WarningEvent{
+			Time:      at,
+			Namespace: event.InvolvedObject.Namespace,
+			Kind:      event.InvolvedObject.Kind,
+			Name:      event.InvolvedObject.Name,
+			Reason:    event.Reason,
+			Message:   event.Message,
+			Count:     eventCount(event),
+		})
+	}
+}
+
+// eventCount returns how often an event happened.
+func eventCount(e *corev1.Event) int32 {
+	if e.Series != nil && e.Series.Count > e.Count {
+		return e.Series.Count
+	}
+	return e.Count
+}
+
+// WatchWarnings calls fn with each new Warning event in namespace ("" for
+// all) about objects of kind ("" for any), on one line, until ctx is
+// cancelled. It backs the REPL's /watch.
+func (k *KubeTools) WatchWarnings(ctx context.Context, namespace, kind string, fn func(string)) error {
+	return watchWarnings(ctx, k.clientset, namespace, kind, func(e WarningEvent) {
+		fn(e.String())
+	})
+}
+
+// WatchEventsTool collects Warning events as they happen.
+type WatchEventsTool struct {
+	clientset kubernetes.Interface
+}
+
+// NewWatchEventsTool creates a new WatchEventsTool.
+func NewWatchEventsTool(clientset kubernetes.Interface) *WatchEventsTool {
+	return &WatchEventsTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *WatchEventsTool) Name() string {
+	return "watch_events"
+}
+
+// Description returns the tool description.
+func (t *WatchEventsTool) Description() string {
+	return "Watch the cluster for new Warning events (crash loops, failed probes, failed scheduling or image pulls) for a while and return the ones that happened, " +
+		"e.g. to see what goes wrong while a rollout progresses. Unlike get_events it only reports events from now on. " +
+		"Stops early once max_events were seen."
+}
+
+// IsLongRunning returns false as the watch duration is capped.
+func (t *WatchEventsTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *WatchEventsTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *WatchEventsTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *WatchEventsTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The namespace to watch. Omit to watch all namespaces.",
+				},
+				"kind": {
+					Type:        "string",
+					Description: "Only report events about objects of this kind, e.g. 'Pod' or 'Deployment'",
+				},
+				"seconds": {
+					Type:        "integer",
+					Description: fmt.Sprintf("How long to watch (default %d, at most %d)", defaultWatchSeconds, maxWatchSeconds),
+				},
+				"max_events": {
+					Type:        "integer",
+					Description: fmt.Sprintf("Stop once this many events were seen (default %d)", defaultWatchEvents),
+				},
+			},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *WatchEventsTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, _ := argsMap["namespace"].(string)
+	kind, _ := argsMap["kind"].(string)
+	seconds := float64(defaultWatchSeconds)
+	if s, ok := argsMap["seconds"].(float64); ok && s > 0 {
+		seconds = min(s, maxWatchSeconds)
+	}
+	maxEvents := defaultWatchEvents
+	if n, ok := argsMap["max_events"].(float64); ok && n > 0 {
+		maxEvents = int(n)
+	}
+
+	goCtx := toolContext(ctx)
+	watchCtx, cancel := context.WithTimeout(goCtx, time.Duration(seconds*float64(time.Second)))
+	defer cancel()
+
+	start := time.Now()
+	events := []WarningEvent{}
+	err := watchWarnings(watchCtx, t.clientset, namespace, kind, func(e WarningEvent) {
+		if len(events) < maxEvents {
+			events = append(events, e)
+		}
+		if len(events) >= maxEvents {
+			cancel()
+		}
+	})
+	if err != nil {
+		return map[string]any{"error": err.Error(), "namespace": namespace}, nil
+	}
+
+	result := map[string]any{
+		"namespace":       namespace,
+		"kind":            kind,
+		"watched_seconds": int(time.Since(start).Seconds()),
+		"events":          events,
+		"count":           len(events),
+	}
+	if goCtx.Err() != nil {
+		result["cancelled"] = true
+	}
+	if len(events) >= maxEvents {
+		result["note"] = fmt.Sprintf("stopped after %d events; call again to keep watching", maxEvents)
+	}
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func warningEvent(kind, name, reason string, count int32, at time.Time) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: name + "." + reason, Namespace: "prod"},
+		InvolvedObject: corev1.ObjectReference{Kind: kind, Name: name, Namespace: "prod"},
+		Type:           corev1.EventTypeWarning,
+		Reason:         reason,
+		Message:        "Back-off restarting failed container web",
+		Count:          count,
+		LastTimestamp:  metav1.NewTime(at),
+	}
+}
+
+func TestWatchEventsTool(t *testing.T) {
+	now := time.Now()
+	clientset := fake.NewSimpleClientset()
+	watcher := watch.NewFake()
+	clientset.PrependWatchReactor("events", k8stesting.DefaultWatchReactor(watcher, nil))
+
+	go func() {
+		old := warningEvent("Pod", "web-1", "BackOff", 3, now.Add(-time.Hour))
+		normal := warningEvent("Pod", "web-1", "Pulled", 1, now)
+		normal.Type = corev1.EventTypeNormal
+		watcher.Add(old)
+		watcher.Add(normal)
+		watcher.Add(warningEvent("Node", "node-1", "NodeNotReady", 1, now))
+		watcher.Add(warningEvent("Pod", "web-1", "BackOff", 4, now))
+		watcher.Modify(warningEvent("Pod", "web-1", "BackOff", 5, now.Add(time.Second)))
+	}()
+
+	result, err := NewWatchEventsTool(clientset).Run(nil, map[string]any{"namespace": "prod", "kind": "Pod", "seconds": float64(5), "max_events": float64(2)})
+	if err != nil || result["error"] != nil {
+		t.Fatalf("Run: %v, %v", result, err)
+	}
+	events := result["events"].([]WarningEvent)
+	if len(events) != 2 || events[0].Count != 4 || events[1].Count != 5 {
+		t.Fatalf("events = %+v", events)
+	}
+	if result["note"] == nil {
+		t.Error("expected a note that the watch stopped at max_events")
+	}
+	if s := events[1].String(); !strings.Contains(s, "prod Pod/web-1 BackOff (x5): Back-off restarting failed container web") {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestDeliverWarningsExpired(t *testing.T) {
+	watcher := watch.NewFake()
+	go watcher.Error(&metav1.Status{Code: 410, Reason: metav1.StatusReasonExpired})
+
+	rv := deliverWarnings(context.Background(), watcher, "42", "", time.Now(), func(WarningEvent) {
+		t.Error("unexpected event")
+	})
+	if rv != "" {
+		t.Errorf("expected an expired watch to be listed again, got resource version %q", rv)
+	}
+}
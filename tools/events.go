@@ -1,7 +1,6 @@
 package tools
 
 import (
-	"context"
 	"encoding/json"
 	"sort"
 	"time"
@@ -13,6 +12,9 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
+// defaultEventLimit is the number of events returned per page.
+const defaultEventLimit = 50
+
 // EventInfo contains information about a Kubernetes event.
 type EventInfo struct {
 	Type           string `json:"type"`
@@ -32,6 +34,7 @@ type EventInfo struct {
 // GetEventsTool provides the get_events tool for the agent.
 type GetEventsTool struct {
 	clientset *kubernetes.Clientset
+	cache     *ResourceCache // nil reads from the API server
 }
 
 // NewGetEventsTool creates a new GetEventsTool.
@@ -41,6 +44,11 @@ func NewGetEventsTool(clientset *kubernetes.Clientset) *GetEventsTool {
 	}
 }
 
+// useCache makes the tool read through the resource cache.
+func (t *GetEventsTool) useCache(c *ResourceCache) {
+	t.cache = c
+}
+
 // Name returns the tool name.
 func (t *GetEventsTool) Name() string {
 	return "get_events"
@@ -73,7 +81,7 @@ func (t *GetEventsTool) Declaration() *genai.FunctionDeclaration {
 		Description: t.Description(),
 		Parameters: &genai.Schema{
 			Type: "object",
-			Properties: map[string]*genai.Schema{
+			Properties: pagingSchema(map[string]*genai.Schema{
 				"namespace": {
 					Type:        "string",
 					Description: "The namespace to list events from",
@@ -86,7 +94,7 @@ func (t *GetEventsTool) Declaration() *genai.FunctionDeclaration {
 					Type:        "string",
 					Description: "Optional: filter events by involved object kind (e.g., 'Pod', 'Deployment', 'ReplicaSet')",
 				},
-			},
+			}, defaultEventLimit),
 			Required: []string{"namespace"},
 		},
 	}
@@ -124,7 +132,12 @@ func (t *GetEventsTool) Run(ctx tool.Context, args any) (map[string]any, error)
 		resourceKind = rk
 	}
 
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	page, limit, err := pagingArgs(argsMap, defaultEventLimit)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
 	defer cancel()
 
 	// Build field selector for filtering
@@ -139,7 +152,7 @@ func (t *GetEventsTool) Run(ctx tool.Context, args any) (map[string]any, error)
 		fieldSelector += "involvedObject.kind=" + resourceKind
 	}
 
-	events, err := t.clientset.CoreV1().Events(namespace).List(timeoutCtx, metav1.ListOptions{
+	events, err := t.cache.listEvents(timeoutCtx, t.clientset, namespace, metav1.ListOptions{
 		FieldSelector: fieldSelector,
 	})
 	if err != nil {
@@ -160,14 +173,14 @@ func (t *GetEventsTool) Run(ctx tool.Context, args any) (map[string]any, error)
 		return ti.After(tj)
 	})
 
-	// Limit to 50 events
-	maxEvents := 50
-	if len(events.Items) > maxEvents {
-		events.Items = events.Items[:maxEvents]
+	// Page through the sorted events, most recent first
+	items, next, remaining, err := paginate(events.Items, page, limit)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
 	}
 
-	result := make([]EventInfo, 0, len(events.Items))
-	for _, event := range events.Items {
+	result := make([]EventInfo, 0, len(items))
+	for _, event := range items {
 		info := EventInfo{
 			Type:    event.Type,
 			Reason:  event.Reason,
@@ -202,8 +215,10 @@ func (t *GetEventsTool) Run(ctx tool.Context, args any) (map[string]any, error)
 		result = append(result, info)
 	}
 
-	return map[string]any{
+	response := map[string]any{
 		"events": result,
 		"count":  len(result),
-	}, nil
+	}
+	setNextPage(response, next, int64(remaining))
+	return response, nil
 }
@@ -0,0 +1,391 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// explainMaxDescription caps field descriptions in the child field listing.
+const explainMaxDescription = 200
+
+// ExplainResourceTool provides the explain_resource tool, similar to kubectl explain.
+type ExplainResourceTool struct {
+	discovery discovery.DiscoveryInterface
+}
+
+// NewExplainResourceTool creates a new ExplainResourceTool.
+func NewExplainResourceTool(discovery discovery.DiscoveryInterface) *ExplainResourceTool {
+	return &ExplainResourceTool{
+		discovery: discovery,
+	}
+}
+
+// Name returns the tool name.
+func (t *ExplainResourceTool) Name() string {
+	return "explain_resource"
+}
+
+// Description returns the tool description.
+func (t *ExplainResourceTool) Description() string {
+	return "Show the schema documentation for a resource kind or one of its fields, like 'kubectl explain'. Uses the OpenAPI schema served by the cluster, so it covers installed CRDs. Use this to check exact field names and types before writing YAML for less common resources."
+}
+
+// IsLongRunning returns false.
+func (t *ExplainResourceTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *ExplainResourceTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *ExplainResourceTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *ExplainResourceTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"kind": {
+					Type:        "string",
+					Description: "Resource kind, plural or short name (e.g., Deployment, httproutes, hpa)",
+				},
+				"api_version": {
+					Type:        "string",
+					Description: "Optional API version to disambiguate (e.g., apps/v1, gateway.networking.k8s.io/v1)",
+				},
+				"field_path": {
+					Type:        "string",
+					Description: "Optional dotted field path (e.g., spec.template.spec.containers.livenessProbe). Omit to explain the top-level object",
+				},
+			},
+			Required: []string{"kind"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *ExplainResourceTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	kind, _ := argsMap["kind"].(string)
+	if kind == "" {
+		return map[string]any{"error": "kind is required"}, nil
+	}
+	apiVersion, _ := argsMap["api_version"].(string)
+	fieldPath, _ := argsMap["field_path"].(string)
+
+	gvk, err := resolveKindFromDiscovery(t.discovery, kind, apiVersion)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	raw, err := openAPISchema(t.discovery, gvk.GroupVersion())
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	var doc openAPIDoc
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to parse OpenAPI schema: %v", err)}, nil
+	}
+
+	result, err := doc.explain(gvk, fieldPath)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+	return result, nil
+}
+
+// openAPISchema fetches the OpenAPI v3 document of a group version from the
+// cluster, which includes the schemas of CRDs.
+func openAPISchema(disc discovery.DiscoveryInterface, gv schema.GroupVersion) ([]byte, error) {
+	paths, err := disc.OpenAPIV3().Paths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OpenAPI paths: %v", err)
+	}
+	gvPath := "apis/" + gv.Group + "/" + gv.Version
+	if gv.Group == "" {
+		gvPath = "api/" + gv.Version
+	}
+	path, ok := paths[gvPath]
+	if !ok {
+		return nil, fmt.Errorf("cluster does not publish an OpenAPI v3 schema for %s", gvPath)
+	}
+	raw, err := path.Schema("application/json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OpenAPI schema for %s: %v", gvPath, err)
+	}
+	return raw, nil
+}
+
+// resolveKindFromDiscovery finds the GroupVersionKind for a kind, plural,
+// singular or short name using the cluster's discovery API.
+func resolveKindFromDiscovery(disc discovery.DiscoveryInterface, kind, apiVersion string) (schema.GroupVersionKind, error) {
+	var lists []*metav1.APIResourceList
+	if apiVersion != "" {
+		list, err := disc.ServerResourcesForGroupVersion(apiVersion)
+		if err != nil {
+			return schema.GroupVersionKind{}, fmt.Errorf("failed to discover resources for %s: %v", apiVersion, err)
+		}
+		lists = []*metav1.APIResourceList{list}
+	} else {
+		// Partial results are fine; some aggregated APIs are often unavailable.
+		lists, _ = disc.ServerPreferredResources()
+	}
+
+	want := strings.ToLower(kind)
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			if strings.Contains(r.Name, "/") {
+				continue // subresource
+			}
+			if strings.ToLower(r.Kind) == want || r.Name == want || r.SingularName == want || slices.Contains(r.ShortNames, want) {
+				return gv.WithKind(r.Kind), nil
+			}
+		}
+	}
+	return schema.GroupVersionKind{}, fmt.Errorf("resource kind %q not found in the cluster", kind)
+}
+
+// openAPIDoc is the subset of an OpenAPI v3 document needed to explain fields.
+type openAPIDoc struct {
+	Components struct {
+		Schemas map[string]map[string]any `json:"schemas"`
+	} `json:"components"`
+}
+
+// explain returns documentation for the field at the dotted path of gvk.
+func (d *openAPIDoc) explain(gvk schema.GroupVersionKind, fieldPath string) (map[string]any, error) {
+	node := d.findKind(gvk)
+	if node == nil {
+		return nil, fmt.Errorf("no schema found for %s", gvk)
+	}
+
+	walked := []string{gvk.Kind}
+	required := false
+	for _, field := range strings.Split(fieldPath, ".") {
+		if field == "" {
+			continue
+		}
+		obj := d.elementSchema(node)
+		props, _ := obj["properties"].(map[string]any)
+		child, ok := props[field].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("field %q does not exist in %s (available: %s)",
+				field, strings.Join(walked, "."), strings.Join(sortedKeys(props), ", "))
+		}
+		required = containsAny(obj["required"], field)
+		node = child
+		walked = append(walked, field)
+	}
+
+	result := map[string]any{
+		"kind":        gvk.Kind,
+		"api_version": gvk.GroupVersion().String(),
+		"field":       strings.Join(walked, "."),
+		"type":        d.typeName(node),
+	}
+	if desc := d.description(node); desc != "" {
+		result["description"] = desc
+	}
+	if len(walked) > 1 {
+		result["required"] = required
+	}
+
+	obj := d.elementSchema(node)
+	props, _ := obj["properties"].(map[string]any)
+	if len(props) > 0 {
+		fields := make([]map[string]any, 0, len(props))
+		for _, name := range sortedKeys(props) {
+			child, _ := props[name].(map[string]any)
+			entry := map[string]any{
+				"name": name,
+				"type": d.typeName(child),
+			}
+			if desc := d.description(child); desc != "" {
+				if len(desc) > explainMaxDescription {
+					desc = desc[:explainMaxDescription-3] + "..."
+				}
+				entry["description"] = desc
+			}
+			if containsAny(obj["required"], name) {
+				entry["required"] = true
+			}
+			fields = append(fields, entry)
+		}
+		result["fields"] = fields
+	}
+	if enum, ok := d.resolve(node)["enum"]; ok {
+		result["enum"] = enum
+	}
+
+	return result, nil
+}
+
+// findKind returns the top-level schema tagged with the given GroupVersionKind.
+func (d *openAPIDoc) findKind(gvk schema.GroupVersionKind) map[string]any {
+	for _, s := range d.Components.Schemas {
+		tags, _ := s["x-kubernetes-group-version-kind"].([]any)
+		for _, tag := range tags {
+			m, _ := tag.(map[string]any)
+			if m["group"] == gvk.Group && m["version"] == gvk.Version && m["kind"] == gvk.Kind {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// resolve follows $ref and single-element allOf wrappers to the actual schema.
+func (d *openAPIDoc) resolve(s map[string]any) map[string]any {
+	for i := 0; i < 10 && s != nil; i++ {
+		if ref, ok := s["$ref"].(string); ok {
+			s = d.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+			continue
+		}
+		if allOf, ok := s["allOf"].([]any); ok && len(allOf) == 1 {
+			if inner, ok := allOf[0].(map[string]any); ok {
+				s = inner
+				continue
+			}
+		}
+		break
+	}
+	return s
+}
+
+// elementSchema returns the object schema whose properties apply at this
+// node, looking through arrays and maps to their element type.
+func (d *openAPIDoc) elementSchema(s map[string]any) map[string]any {
+	s = d.resolve(s)
+	for i := 0; i < 5 && s != nil; i++ {
+		if items, ok := s["items"].(map[string]any); ok {
+			s = d.resolve(items)
+			continue
+		}
+		if _, hasProps := s["properties"]; !hasProps {
+			if ap, ok := s["additionalProperties"].(map[string]any); ok {
+				s = d.resolve(ap)
+				continue
+			}
+		}
+		break
+	}
+	return s
+}
+
+// description returns the description at the node, preferring the field's own
+// description over that of the referenced type.
+func (d *openAPIDoc) description(s map[string]any) string {
+	if desc, ok := s["description"].(string); ok && desc != "" {
+		return desc
+	}
+	desc, _ := d.resolve(s)["description"].(string)
+	return desc
+}
+
+// typeName describes the node's type in kubectl explain style,
+// e.g. "string", "[]Container", "map[string]string".
+func (d *openAPIDoc) typeName(s map[string]any) string {
+	name := refName(s)
+	s = d.resolve(s)
+	if s == nil {
+		return "unknown"
+	}
+
+	typ, _ := s["type"].(string)
+	switch typ {
+	case "array":
+		items, _ := s["items"].(map[string]any)
+		return "[]" + d.typeName(items)
+	case "object":
+		if ap, ok := s["additionalProperties"].(map[string]any); ok {
+			return "map[string]" + d.typeName(ap)
+		}
+		if name != "" {
+			return name
+		}
+		return "object"
+	case "":
+		if name != "" {
+			return name
+		}
+		if _, ok := s["properties"]; ok {
+			return "object"
+		}
+		if v, ok := s["x-kubernetes-int-or-string"].(bool); ok && v {
+			return "int-or-string"
+		}
+		return "any"
+	}
+	return typ
+}
+
+// refName returns the short type name of a $ref (e.g. "Container"), if any.
+func refName(s map[string]any) string {
+	ref, ok := s["$ref"].(string)
+	if !ok {
+		if allOf, ok := s["allOf"].([]any); ok && len(allOf) == 1 {
+			if inner, ok := allOf[0].(map[string]any); ok {
+				ref, _ = inner["$ref"].(string)
+			}
+		}
+	}
+	if ref == "" {
+		return ""
+	}
+	return ref[strings.LastIndex(ref, ".")+1:]
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsAny(list any, s string) bool {
+	items, _ := list.([]any)
+	for _, v := range items {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const testOpenAPIDoc = `{
+  "components": {
+    "schemas": {
+      "io.k8s.api.apps.v1.Deployment": {
+        "description": "Deployment enables declarative updates for Pods and ReplicaSets.",
+        "type": "object",
+        "properties": {
+          "spec": {"description": "Specification of the desired behavior of the Deployment.", "allOf": [{"$ref": "#/components/schemas/io.k8s.api.apps.v1.DeploymentSpec"}]}
+        },
+        "x-kubernetes-group-version-kind": [{"group": "apps", "kind": "Deployment", "version": "v1"}]
+      },
+      "io.k8s.api.apps.v1.DeploymentSpec": {
+        "type": "object",
+        "required": ["selector", "template"],
+        "properties": {
+          "replicas": {"description": "Number of desired pods.", "type": "integer"},
+          "selector": {"type": "object", "x-kubernetes-preserve-unknown-fields": true},
+          "template": {"allOf": [{"$ref": "#/components/schemas/io.k8s.api.core.v1.PodTemplateSpec"}]}
+        }
+      },
+      "io.k8s.api.core.v1.PodTemplateSpec": {
+        "description": "PodTemplateSpec describes the data a pod should have when created from a template",
+        "type": "object",
+        "properties": {
+          "spec": {"allOf": [{"$ref": "#/components/schemas/io.k8s.api.core.v1.PodSpec"}]}
+        }
+      },
+      "io.k8s.api.core.v1.PodSpec": {
+        "type": "object",
+        "properties": {
+          "containers": {"type": "array", "items": {"allOf": [{"$ref": "#/components/schemas/io.k8s.api.core.v1.Container"}]}},
+          "nodeSelector": {"type": "object", "additionalProperties": {"type": "string", "default": ""}}
+        }
+      },
+      "io.k8s.api.core.v1.Container": {
+        "description": "A single application container.",
+        "type": "object",
+        "required": ["name"],
+        "properties": {
+          "name": {"description": "Name of the container.", "type": "string"},
+          "image": {"description": "Container image name.", "type": "string"}
+        }
+      }
+    }
+  }
+}`
+
+func loadTestOpenAPIDoc(t *testing.T) *openAPIDoc {
+	t.Helper()
+	var doc openAPIDoc
+	if err := json.Unmarshal([]byte(testOpenAPIDoc), &doc); err != nil {
+		t.Fatalf("failed to parse test doc: %v", err)
+	}
+	return &doc
+}
+
+var deploymentGVK = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+
+func TestExplain_TopLevel(t *testing.T) {
+	doc := loadTestOpenAPIDoc(t)
+
+	result, err := doc.explain(deploymentGVK, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["api_version"] != "apps/v1" || result["field"] != "Deployment" {
+		t.Errorf("unexpected header: %+v", result)
+	}
+	fields := result["fields"].([]map[string]any)
+	if len(fields) != 1 || fields[0]["name"] != "spec" || fields[0]["type"] != "DeploymentSpec" {
+		t.Errorf("unexpected fields: %+v", fields)
+	}
+}
+
+func TestExplain_NestedArrayPath(t *testing.T) {
+	doc := loadTestOpenAPIDoc(t)
+
+	result, err := doc.explain(deploymentGVK, "spec.template.spec.containers")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["type"] != "[]Container" {
+		t.Errorf("expected type []Container, got %v", result["type"])
+	}
+	fields := result["fields"].([]map[string]any)
+	if len(fields) != 2 || fields[1]["name"] != "name" || fields[1]["required"] != true {
+		t.Errorf("unexpected container fields: %+v", fields)
+	}
+
+	result, err = doc.explain(deploymentGVK, "spec.template.spec.containers.image")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["description"] != "Container image name." || result["required"] != false {
+		t.Errorf("unexpected image field: %+v", result)
+	}
+}
+
+func TestExplain_MapAndRequired(t *testing.T) {
+	doc := loadTestOpenAPIDoc(t)
+
+	result, err := doc.explain(deploymentGVK, "spec.template.spec.nodeSelector")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["type"] != "map[string]string" {
+		t.Errorf("expected map[string]string, got %v", result["type"])
+	}
+
+	result, err = doc.explain(deploymentGVK, "spec.template")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["required"] != true {
+		t.Errorf("expected spec.template to be required")
+	}
+}
+
+func TestExplain_UnknownField(t *testing.T) {
+	doc := loadTestOpenAPIDoc(t)
+
+	_, err := doc.explain(deploymentGVK, "spec.replica")
+	if err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+	if !strings.Contains(err.Error(), "replicas") {
+		t.Errorf("expected error to list available fields, got: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	"k8s.io/client-go/util/homedir"
+)
+
+// exportInlineMaxBytes is the largest YAML export returned inline as well.
+const exportInlineMaxBytes = 16 * 1024
+
+// ExportAppTool provides the export_app tool for the agent.
+type ExportAppTool struct {
+	manifest *manifest.Manager
+}
+
+// NewExportAppTool creates a new ExportAppTool.
+func NewExportAppTool(manifest *manifest.Manager) *ExportAppTool {
+	return &ExportAppTool{
+		manifest: manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *ExportAppTool) Name() string {
+	return "export_app"
+}
+
+// Description returns the tool description.
+func (t *ExportAppTool) Description() string {
+	return "Export the stored manifests of an app, or of a whole namespace, as a self-contained bundle for people who don't use kasa: " +
+		"one multi-document YAML (ordered so it can be applied with kubectl apply -f), a tarball, or a Helm chart skeleton. " +
+		"The file is written to ~/.kasa/exports. Secrets are left out unless include_secrets is set."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *ExportAppTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *ExportAppTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *ExportAppTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *ExportAppTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The namespace of the app",
+				},
+				"app": {
+					Type:        "string",
+					Description: "The app to export. Omit to export every app in the namespace.",
+				},
+				"format": {
+					Type:        "string",
+					Description: "yaml (default): one multi-document file; tar: a .tar.gz with the manifest store's layout; helm: a .tgz Helm chart with the manifests as templates",
+					Enum:        manifest.ExportFormats,
+				},
+				"include_secrets": {
+					Type:        "boolean",
+					Description: "Also export Secrets, including their data. Default is false.",
+				},
+			},
+			Required: []string{"namespace"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *ExportAppTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	opts := manifest.ExportOptions{}
+	opts.Namespace, _ = argsMap["namespace"].(string)
+	if opts.Namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+	opts.App, _ = argsMap["app"].(string)
+	opts.Format, _ = argsMap["format"].(string)
+	if opts.Format == "" {
+		opts.Format = manifest.ExportYAML
+	}
+	opts.IncludeSecrets, _ = argsMap["include_secrets"].(bool)
+
+	var buf bytes.Buffer
+	export, err := t.manifest.Export(&buf, opts)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	home := homedir.HomeDir()
+	if home == "" {
+		return map[string]any{"error": "cannot determine home directory for exports"}, nil
+	}
+	destDir := filepath.Join(home, ".kasa", "exports")
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to create export directory: %v", err)}, nil
+	}
+	localPath := filepath.Join(destDir, fmt.Sprintf("%s-%s%s", export.Name, time.Now().Format("20060102-150405"), exportExtension(opts.Format)))
+	if err := os.WriteFile(localPath, buf.Bytes(), 0600); err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to write export: %v", err)}, nil
+	}
+
+	files := make([]string, len(export.Manifests))
+	for i, m := range export.Manifests {
+		files[i] = m.Path
+	}
+	result := map[string]any{
+		"success":    true,
+		"namespace":  opts.Namespace,
+		"format":     opts.Format,
+		"local_path": localPath,
+		"manifests":  files,
+		"bytes":      buf.Len(),
+		"message":    fmt.Sprintf("Exported %d manifest(s) to %s", len(files), localPath),
+	}
+	if opts.App != "" {
+		result["app"] = opts.App
+	}
+	if len(export.Skipped) > 0 {
+		skipped := make([]string, len(export.Skipped))
+		for i, m := range export.Skipped {
+			skipped[i] = m.Path
+		}
+		result["skipped_secrets"] = skipped
+		result["note"] = "Secrets were left out; the recipients must create them, or export again with include_secrets"
+	}
+	if opts.Format == manifest.ExportYAML && buf.Len() <= exportInlineMaxBytes {
+		result["content"] = buf.String()
+	}
+	return result, nil
+}
+
+// exportExtension returns the file extension of an export format.
+func exportExtension(format string) string {
+	switch format {
+	case manifest.ExportTar:
+		return ".tar.gz"
+	case manifest.ExportHelm:
+		return ".tgz"
+	default:
+		return ".yaml"
+	}
+}
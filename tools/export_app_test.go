@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExportAppTool(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	mgr := newTestManifestManager(t)
+	writeTestManifest(t, mgr, "shop", "web", "service", "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n")
+	writeTestManifest(t, mgr, "shop", "web", "deployment", "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n")
+	writeTestManifest(t, mgr, "shop", "web", "configmap", configMapYAML("web", "x"))
+	writeTestManifest(t, mgr, "shop", "web", "secret", "apiVersion: v1\nkind: Secret\nmetadata:\n  name: web\n")
+	writeTestManifest(t, mgr, "shop", "api", "deployment", "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api\n")
+
+	tool := NewExportAppTool(mgr)
+	result, err := tool.Run(nil, map[string]any{"namespace": "shop", "app": "web"})
+	if err != nil || result["error"] != nil {
+		t.Fatalf("Run: %v, %v", result, err)
+	}
+	content, _ := result["content"].(string)
+	// ConfigMaps and Services come before the Deployment that uses them
+	configMap, deployment, service := strings.Index(content, "kind: ConfigMap"), strings.Index(content, "kind: Deployment"), strings.Index(content, "kind: Service")
+	if configMap < 0 || !(configMap < service && service < deployment) || strings.Contains(content, "kind: Secret") {
+		t.Errorf("content:\n%s", content)
+	}
+	if !strings.Contains(content, "# Source: shop/web/configmap.yaml") || strings.Contains(content, "name: api") {
+		t.Errorf("content:\n%s", content)
+	}
+	if skipped, _ := result["skipped_secrets"].([]string); len(skipped) != 1 {
+		t.Errorf("skipped_secrets = %v", result["skipped_secrets"])
+	}
+	written, err := os.ReadFile(result["local_path"].(string))
+	if err != nil || string(written) != content {
+		t.Errorf("written file = %q, %v", written, err)
+	}
+
+	// A Helm chart of the whole namespace
+	result, err = tool.Run(nil, map[string]any{"namespace": "shop", "format": "helm", "include_secrets": true})
+	if err != nil || result["error"] != nil {
+		t.Fatalf("Run: %v, %v", result, err)
+	}
+	if !strings.HasSuffix(result["local_path"].(string), ".tgz") || result["content"] != nil {
+		t.Errorf("result = %v", result)
+	}
+	f, err := os.Open(result["local_path"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, h.Name)
+	}
+	want := "shop/Chart.yaml shop/values.yaml shop/templates/web-configmap.yaml shop/templates/web-secret.yaml shop/templates/web-service.yaml shop/templates/api-deployment.yaml shop/templates/web-deployment.yaml"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("chart files = %s\nwant %s", got, want)
+	}
+
+	if result, _ := tool.Run(nil, map[string]any{"namespace": "shop", "app": "nope"}); result["error"] == nil {
+		t.Error("expected an error for an app without manifests")
+	}
+}
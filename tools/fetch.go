@@ -85,7 +85,7 @@ func (t *FetchUrlTool) Run(ctx tool.Context, args any) (map[string]any, error) {
 
 	// Create request to Jina Reader API
 	jinaURL := "https://r.jina.ai/" + url
-	req, err := http.NewRequest("GET", jinaURL, nil)
+	req, err := http.NewRequestWithContext(toolContext(ctx), "GET", jinaURL, nil)
 	if err != nil {
 		return map[string]any{"error": fmt.Sprintf("failed to create request: %v", err)}, nil
 	}
@@ -0,0 +1,182 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+// fluxKinds are the Flux objects that apply resources to the cluster.
+var fluxKinds = []string{"kustomization", "helmrelease"}
+
+// ListFluxResourcesTool provides the list_flux_resources tool for the agent.
+type ListFluxResourcesTool struct {
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+}
+
+// NewListFluxResourcesTool creates a new ListFluxResourcesTool.
+func NewListFluxResourcesTool(dynamicClient dynamic.Interface, resolver *GVRResolver) *ListFluxResourcesTool {
+	return &ListFluxResourcesTool{
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+	}
+}
+
+// Name returns the tool name.
+func (t *ListFluxResourcesTool) Name() string {
+	return "list_flux_resources"
+}
+
+// Description returns the tool description.
+func (t *ListFluxResourcesTool) Description() string {
+	return "List Flux Kustomizations and HelmReleases with their source, path or chart, applied revision, Ready condition and whether they are suspended. Use it to see which workloads are deployed by GitOps before changing them."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *ListFluxResourcesTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *ListFluxResourcesTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *ListFluxResourcesTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *ListFluxResourcesTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace (default: all namespaces)",
+				},
+				"kind": {
+					Type:        "string",
+					Description: "Only list this kind: kustomization or helmrelease (default: both)",
+				},
+			},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *ListFluxResourcesTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else if args != nil {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, _ := argsMap["namespace"].(string)
+	kinds := fluxKinds
+	if kind, _ := argsMap["kind"].(string); kind != "" {
+		kind = NormalizeKindName(kind)
+		if kind != "kustomization" && kind != "helmrelease" {
+			return map[string]any{"error": fmt.Sprintf("kind must be kustomization or helmrelease, got %q", kind)}, nil
+		}
+		kinds = []string{kind}
+	}
+	return listFluxResources(toolContext(ctx), t.dynamicClient, t.resolver, namespace, kinds), nil
+}
+
+// listFluxResources lists the given Flux kinds. Kinds whose CRD is missing
+// (e.g. helm-controller not installed) are skipped.
+func listFluxResources(ctx context.Context, dyn dynamic.Interface, resolver *GVRResolver, namespace string, kinds []string) map[string]any {
+	if dyn == nil {
+		return map[string]any{"error": "dynamic client not available"}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	resources := []FluxResource{}
+	var installed, problems, errs []string
+	for _, kind := range kinds {
+		gvr, err := resolver.Resolve(kind, "")
+		if err != nil {
+			continue
+		}
+		var resourceClient dynamic.ResourceInterface = dyn.Resource(gvr)
+		if namespace != "" {
+			resourceClient = dyn.Resource(gvr).Namespace(namespace)
+		}
+		list, err := resourceClient.List(ctx, metav1.ListOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("failed to list %s: %v", kind, err))
+			continue
+		}
+		installed = append(installed, kind)
+
+		for _, item := range list.Items {
+			var obj fluxObject
+			if err := decodeUnstructured(&item, &obj); err != nil {
+				errs = append(errs, fmt.Sprintf("failed to decode %s %s: %v", kind, item.GetName(), err))
+				continue
+			}
+			s := obj.summarize()
+			resources = append(resources, s)
+			switch {
+			case s.Suspended:
+				problems = append(problems, fmt.Sprintf("%s %s/%s: suspended", s.Kind, s.Namespace, s.Name))
+			case s.Ready != "True":
+				problems = append(problems, fmt.Sprintf("%s %s/%s: not ready (%s)", s.Kind, s.Namespace, s.Name, s.Reason))
+			}
+		}
+	}
+
+	if len(installed) == 0 && len(errs) == 0 {
+		return map[string]any{"installed": false, "message": fmt.Sprintf("Flux does not seem to be installed (no %s CRD)", strings.Join(kinds, " or "))}
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		a, b := resources[i], resources[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+
+	result := map[string]any{
+		"installed": len(installed) > 0,
+		"resources": resources,
+		"count":     len(resources),
+	}
+	if len(problems) > 0 {
+		result["not_ready"] = problems
+	}
+	if len(errs) > 0 {
+		result["errors"] = errs
+	}
+	return result
+}
@@ -0,0 +1,310 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// CreateGatewayTool provides the create_gateway tool for the agent.
+type CreateGatewayTool struct {
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+	manifest      *manifest.Manager
+}
+
+// NewCreateGatewayTool creates a new CreateGatewayTool.
+func NewCreateGatewayTool(dynamicClient dynamic.Interface, resolver *GVRResolver, manifest *manifest.Manager) *CreateGatewayTool {
+	return &CreateGatewayTool{
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+		manifest:      manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *CreateGatewayTool) Name() string {
+	return "create_gateway"
+}
+
+// Description returns the tool description.
+func (t *CreateGatewayTool) Description() string {
+	return "Create or update a Gateway API Gateway. Give a hostname and optional TLS secret for an HTTP listener on port 80 plus an HTTPS listener on 443, or list the listeners explicitly. Saves the manifest to git and applies it to the cluster."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *CreateGatewayTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *CreateGatewayTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *CreateGatewayTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *CreateGatewayTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"name": {
+					Type:        "string",
+					Description: "The name of the Gateway",
+				},
+				"namespace": {
+					Type:        "string",
+					Description: "The target Kubernetes namespace",
+				},
+				"gateway_class": {
+					Type:        "string",
+					Description: "The GatewayClass name (e.g., envoy-gateway, istio, cilium)",
+				},
+				"hostname": {
+					Type:        "string",
+					Description: "Hostname the default listeners accept (e.g., *.example.com); ignored if listeners is given",
+				},
+				"tls_secret": {
+					Type:        "string",
+					Description: "TLS secret for the default HTTPS listener; without it only the HTTP listener is created",
+				},
+				"allowed_routes": {
+					Type:        "string",
+					Description: "Namespaces whose routes may attach: Same (default) or All",
+				},
+				"listeners": {
+					Type:        "array",
+					Description: "Explicit listeners, replacing the defaults",
+					Items: &genai.Schema{
+						Type: "object",
+						Properties: map[string]*genai.Schema{
+							"name": {
+								Type:        "string",
+								Description: "Listener name, referenced by routes as sectionName",
+							},
+							"port": {
+								Type:        "integer",
+								Description: "Listener port",
+							},
+							"protocol": {
+								Type:        "string",
+								Description: "HTTP or HTTPS (default: HTTPS if tls_secret is set, else HTTP)",
+							},
+							"hostname": {
+								Type:        "string",
+								Description: "Hostname the listener accepts (optional)",
+							},
+							"tls_secret": {
+								Type:        "string",
+								Description: "TLS secret for an HTTPS listener",
+							},
+						},
+						Required: []string{"name", "port"},
+					},
+				},
+			},
+			Required: []string{"name", "namespace", "gateway_class"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *CreateGatewayTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+	gatewayClass, ok := argsMap["gateway_class"].(string)
+	if !ok || gatewayClass == "" {
+		return map[string]any{"error": "gateway_class is required"}, nil
+	}
+
+	gw, err := buildGateway(name, namespace, gatewayClass, argsMap)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	yamlBytes, err := yaml.Marshal(gw.Object)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to marshal gateway: %v", err)}, nil
+	}
+	manifestPath, err := t.manifest.SaveManifest(namespace, name, "gateway", yamlBytes)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to save manifest: %v", err)}, nil
+	}
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
+	defer cancel()
+	action, err := createOrUpdate(timeoutCtx, t.dynamicClient, t.resolver, gw)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	var listeners []string
+	for _, l := range gw.Object["spec"].(map[string]any)["listeners"].([]any) {
+		l := l.(map[string]any)
+		listeners = append(listeners, fmt.Sprintf("%s (%s/%d)", l["name"], l["protocol"], l["port"]))
+	}
+	return map[string]any{
+		"success":       true,
+		"action":        action,
+		"name":          name,
+		"namespace":     namespace,
+		"gateway_class": gatewayClass,
+		"listeners":     listeners,
+		"manifest_path": manifestPath,
+		"message":       fmt.Sprintf("Gateway %s %s in namespace %s; check_ingress on an HTTPRoute attached to it shows whether the controller programmed it", name, action, namespace),
+	}, nil
+}
+
+// buildGateway builds a Gateway from the create_gateway arguments.
+func buildGateway(name, namespace, gatewayClass string, argsMap map[string]any) (*unstructured.Unstructured, error) {
+	allowed := "Same"
+	if a, ok := argsMap["allowed_routes"].(string); ok && a != "" {
+		if a != "Same" && a != "All" {
+			return nil, fmt.Errorf("allowed_routes must be Same or All, got %q", a)
+		}
+		allowed = a
+	}
+
+	var specs []map[string]any
+	if raw, ok := argsMap["listeners"].([]any); ok && len(raw) > 0 {
+		for _, r := range raw {
+			l, ok := r.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("listeners must be objects")
+			}
+			specs = append(specs, l)
+		}
+	} else {
+		hostname, _ := argsMap["hostname"].(string)
+		specs = []map[string]any{{"name": "http", "port": float64(80), "protocol": "HTTP", "hostname": hostname}}
+		if secret, _ := argsMap["tls_secret"].(string); secret != "" {
+			specs = append(specs, map[string]any{"name": "https", "port": float64(443), "protocol": "HTTPS", "hostname": hostname, "tls_secret": secret})
+		}
+	}
+
+	var listeners []any
+	for _, s := range specs {
+		listener, err := gatewayListener(s, allowed)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": gatewayAPIGroup + "/v1",
+		"kind":       "Gateway",
+		"metadata": map[string]any{
+			"name":      name,
+			"namespace": namespace,
+			"labels": map[string]any{
+				"app.kubernetes.io/name":       name,
+				"app.kubernetes.io/managed-by": "kasa",
+			},
+		},
+		"spec": map[string]any{
+			"gatewayClassName": gatewayClass,
+			"listeners":        listeners,
+		},
+	}}, nil
+}
+
+// gatewayListener builds one Gateway listener from its create_gateway arguments.
+func gatewayListener(spec map[string]any, allowedRoutes string) (map[string]any, error) {
+	name, _ := spec["name"].(string)
+	port, _ := spec["port"].(float64)
+	if name == "" || port <= 0 {
+		return nil, fmt.Errorf("each listener needs a name and a port")
+	}
+	secret, _ := spec["tls_secret"].(string)
+	protocol, _ := spec["protocol"].(string)
+	if protocol == "" {
+		protocol = "HTTP"
+		if secret != "" {
+			protocol = "HTTPS"
+		}
+	}
+	protocol = strings.ToUpper(protocol)
+	if protocol == "HTTPS" && secret == "" {
+		return nil, fmt.Errorf("HTTPS listener %s needs a tls_secret", name)
+	}
+
+	listener := map[string]any{
+		"name":          name,
+		"port":          int64(port),
+		"protocol":      protocol,
+		"allowedRoutes": map[string]any{"namespaces": map[string]any{"from": allowedRoutes}},
+	}
+	if hostname, _ := spec["hostname"].(string); hostname != "" {
+		listener["hostname"] = hostname
+	}
+	if secret != "" {
+		listener["tls"] = map[string]any{
+			"mode":            "Terminate",
+			"certificateRefs": []any{map[string]any{"kind": "Secret", "name": secret}},
+		}
+	}
+	return listener, nil
+}
+
+// createOrUpdate applies obj with the dynamic client, creating it or
+// replacing the existing object. Returns "created" or "updated".
+func createOrUpdate(ctx context.Context, dynamicClient dynamic.Interface, resolver *GVRResolver, obj *unstructured.Unstructured) (string, error) {
+	gvk := obj.GroupVersionKind()
+	gvr, err := resolver.ResolveGVK(gvk)
+	if err != nil {
+		return "", err
+	}
+	client := dynamicClient.Resource(gvr).Namespace(obj.GetNamespace())
+
+	existing, err := client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return "", fmt.Errorf("failed to check existing %s: %v", gvk.Kind, err)
+		}
+		if _, err := client.Create(ctx, obj, metav1.CreateOptions{}); err != nil {
+			return "", fmt.Errorf("failed to create %s: %v", gvk.Kind, err)
+		}
+		return "created", nil
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	if _, err := client.Update(ctx, obj, metav1.UpdateOptions{}); err != nil {
+		return "", fmt.Errorf("failed to update %s: %v", gvk.Kind, err)
+	}
+	return "updated", nil
+}
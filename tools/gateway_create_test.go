@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestBuildGateway(t *testing.T) {
+	gw, err := buildGateway("public", "infra", "envoy", map[string]any{
+		"hostname":       "*.example.com",
+		"tls_secret":     "wildcard-tls",
+		"allowed_routes": "All",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listeners, _, _ := unstructured.NestedSlice(gw.Object, "spec", "listeners")
+	if len(listeners) != 2 {
+		t.Fatalf("expected http and https listeners, got %v", listeners)
+	}
+	https := listeners[1].(map[string]any)
+	if https["protocol"] != "HTTPS" || https["port"] != int64(443) || https["hostname"] != "*.example.com" {
+		t.Errorf("unexpected https listener %v", https)
+	}
+	if mode, _, _ := unstructured.NestedString(https, "tls", "mode"); mode != "Terminate" {
+		t.Errorf("https listener should terminate TLS, got %v", https["tls"])
+	}
+	if from, _, _ := unstructured.NestedString(https, "allowedRoutes", "namespaces", "from"); from != "All" {
+		t.Errorf("allowedRoutes = %q, want All", from)
+	}
+
+	gw, err = buildGateway("internal", "infra", "envoy", map[string]any{
+		"listeners": []any{map[string]any{"name": "web", "port": float64(8080)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listeners, _, _ = unstructured.NestedSlice(gw.Object, "spec", "listeners")
+	if len(listeners) != 1 || listeners[0].(map[string]any)["protocol"] != "HTTP" {
+		t.Errorf("explicit listener should default to HTTP, got %v", listeners)
+	}
+
+	for _, args := range []map[string]any{
+		{"allowed_routes": "Selector"},
+		{"listeners": []any{map[string]any{"name": "secure", "port": float64(443), "protocol": "HTTPS"}}},
+		{"listeners": []any{map[string]any{"name": "noport"}}},
+	} {
+		if _, err := buildGateway("gw", "infra", "envoy", args); err == nil {
+			t.Errorf("buildGateway(%v) should fail", args)
+		}
+	}
+}
+
+func TestBuildHTTPRoute(t *testing.T) {
+	args := map[string]any{
+		"gateway":           "public",
+		"gateway_namespace": "infra",
+		"section_name":      "https",
+		"hostnames":         []any{"shop.example.com"},
+		"service_name":      "web",
+		"service_port":      float64(8080),
+		"paths":             "/api, /static",
+	}
+	route, err := buildHTTPRoute("web", "shop", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded httpRoute
+	if err := decodeUnstructured(route, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	parent := decoded.Spec.ParentRefs[0]
+	if parent.Name != "public" || parent.Namespace != "infra" || parent.SectionName != "https" {
+		t.Errorf("unexpected parentRef %+v", parent)
+	}
+	backend := decoded.Spec.Rules[0].BackendRefs[0]
+	if backend.Name != "web" || backend.Port == nil || *backend.Port != 8080 {
+		t.Errorf("unexpected backendRef %+v", backend)
+	}
+	matches, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+	if got := len(matches[0].(map[string]any)["matches"].([]any)); got != 2 {
+		t.Errorf("expected 2 path matches, got %d", got)
+	}
+
+	// Applying twice creates, then updates
+	dyn := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	for _, want := range []string{"created", "updated"} {
+		action, err := createOrUpdate(context.Background(), dyn, nil, route.DeepCopy())
+		if err != nil || action != want {
+			t.Fatalf("createOrUpdate = %q, %v; want %q", action, err, want)
+		}
+	}
+	gvr := schema.GroupVersionResource{Group: gatewayAPIGroup, Version: "v1", Resource: "httproutes"}
+	if _, err := dyn.Resource(gvr).Namespace("shop").Get(context.Background(), "web", metav1.GetOptions{}); err != nil {
+		t.Errorf("route not stored: %v", err)
+	}
+
+	args["path_match"] = "Regex"
+	if _, err := buildHTTPRoute("web", "shop", args); err == nil {
+		t.Error("invalid path_match should fail")
+	}
+}
@@ -0,0 +1,326 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Labels and annotations Argo CD and Flux put on the objects they apply.
+const (
+	argoTrackingAnnotation   = "argocd.argoproj.io/tracking-id" // "<app>:<group>/<kind>:<namespace>/<name>"
+	argoInstanceLabel        = "argocd.argoproj.io/instance"
+	fluxKustomizeNameLabel   = "kustomize.toolkit.fluxcd.io/name"
+	fluxKustomizeNSLabel     = "kustomize.toolkit.fluxcd.io/namespace"
+	fluxHelmReleaseNameLabel = "helm.toolkit.fluxcd.io/name"
+	fluxHelmReleaseNSLabel   = "helm.toolkit.fluxcd.io/namespace"
+)
+
+// GitOpsManager identifies the Argo CD Application or Flux object that
+// deploys a resource.
+type GitOpsManager struct {
+	Tool      string `json:"tool"` // "argocd" or "flux"
+	Kind      string `json:"kind"` // Application, Kustomization or HelmRelease
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// String returns e.g. "Argo CD Application argocd/web".
+func (m *GitOpsManager) String() string {
+	tool := "Flux"
+	if m.Tool == "argocd" {
+		tool = "Argo CD"
+	}
+	if m.Namespace == "" {
+		return fmt.Sprintf("%s %s %s", tool, m.Kind, m.Name)
+	}
+	return fmt.Sprintf("%s %s %s/%s", tool, m.Kind, m.Namespace, m.Name)
+}
+
+// Advice tells the agent to change the source instead of the cluster.
+func (m *GitOpsManager) Advice() string {
+	return fmt.Sprintf("Managed by %s. Change it in the GitOps source repository instead of patching the cluster: hot-patches are reverted or reported as drift on the next sync. Use get_application_status to find the repository and path.", m)
+}
+
+// gitOpsManagerOf returns the GitOps object that manages a resource with the
+// given labels and annotations, or nil. Argo CD's default label tracking
+// (app.kubernetes.io/instance) is not used since Helm sets the same label.
+func gitOpsManagerOf(labels, annotations map[string]string) *GitOpsManager {
+	if id := annotations[argoTrackingAnnotation]; id != "" {
+		app, _, _ := strings.Cut(id, ":")
+		m := &GitOpsManager{Tool: "argocd", Kind: "Application", Name: app}
+		// Applications outside the control plane namespace are "<namespace>_<name>"
+		if ns, name, ok := strings.Cut(app, "_"); ok {
+			m.Namespace, m.Name = ns, name
+		}
+		return m
+	}
+	if app := labels[argoInstanceLabel]; app != "" {
+		return &GitOpsManager{Tool: "argocd", Kind: "Application", Name: app}
+	}
+	if name := labels[fluxHelmReleaseNameLabel]; name != "" {
+		return &GitOpsManager{Tool: "flux", Kind: "HelmRelease", Namespace: labels[fluxHelmReleaseNSLabel], Name: name}
+	}
+	if name := labels[fluxKustomizeNameLabel]; name != "" {
+		return &GitOpsManager{Tool: "flux", Kind: "Kustomization", Namespace: labels[fluxKustomizeNSLabel], Name: name}
+	}
+	return nil
+}
+
+// gitOpsManagerOfObject is gitOpsManagerOf for an object as a map.
+func gitOpsManagerOfObject(obj map[string]any) *GitOpsManager {
+	metadata, _ := obj["metadata"].(map[string]any)
+	return gitOpsManagerOf(stringMap(metadata["labels"]), stringMap(metadata["annotations"]))
+}
+
+// stringMap converts a decoded JSON object with string values.
+func stringMap(v any) map[string]string {
+	m, _ := v.(map[string]any)
+	out := make(map[string]string, len(m))
+	for k, val := range m {
+		if s, ok := val.(string); ok {
+			out[k] = s
+		}
+	}
+	return out
+}
+
+// The parts of an Argo CD Application the GitOps tools report.
+type (
+	argoSource struct {
+		RepoURL        string `json:"repoURL"`
+		Path           string `json:"path"`
+		Chart          string `json:"chart"`
+		TargetRevision string `json:"targetRevision"`
+	}
+
+	argoApplication struct {
+		Metadata metav1.ObjectMeta `json:"metadata"`
+		Spec     struct {
+			Project     string       `json:"project"`
+			Source      *argoSource  `json:"source"`
+			Sources     []argoSource `json:"sources"`
+			Destination struct {
+				Server    string `json:"server"`
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"destination"`
+			SyncPolicy struct {
+				Automated *struct {
+					Prune    bool `json:"prune"`
+					SelfHeal bool `json:"selfHeal"`
+				} `json:"automated"`
+			} `json:"syncPolicy"`
+		} `json:"spec"`
+		Status struct {
+			Sync struct {
+				Status   string `json:"status"`
+				Revision string `json:"revision"`
+			} `json:"sync"`
+			Health struct {
+				Status  string `json:"status"`
+				Message string `json:"message"`
+			} `json:"health"`
+			OperationState *struct {
+				Phase      string `json:"phase"`
+				Message    string `json:"message"`
+				FinishedAt string `json:"finishedAt"`
+			} `json:"operationState"`
+			Conditions []struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"conditions"`
+			Resources []struct {
+				Group     string `json:"group"`
+				Kind      string `json:"kind"`
+				Namespace string `json:"namespace"`
+				Name      string `json:"name"`
+				Status    string `json:"status"`
+				Health    *struct {
+					Status  string `json:"status"`
+					Message string `json:"message"`
+				} `json:"health"`
+			} `json:"resources"`
+			History []struct {
+				Revision   string `json:"revision"`
+				DeployedAt string `json:"deployedAt"`
+			} `json:"history"`
+		} `json:"status"`
+	}
+
+	fluxSourceRef struct {
+		Kind      string `json:"kind"`
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	}
+
+	fluxObject struct {
+		Kind     string            `json:"kind"`
+		Metadata metav1.ObjectMeta `json:"metadata"`
+		Spec     struct {
+			Suspend   bool          `json:"suspend"`
+			Path      string        `json:"path"`      // Kustomization
+			SourceRef fluxSourceRef `json:"sourceRef"` // Kustomization
+			Chart     *struct {     // HelmRelease
+				Spec struct {
+					Chart     string        `json:"chart"`
+					Version   string        `json:"version"`
+					SourceRef fluxSourceRef `json:"sourceRef"`
+				} `json:"spec"`
+			} `json:"chart"`
+			ChartRef *fluxSourceRef `json:"chartRef"` // HelmRelease
+		} `json:"spec"`
+		Status struct {
+			Conditions            []metav1.Condition `json:"conditions"`
+			LastAppliedRevision   string             `json:"lastAppliedRevision"`
+			LastAttemptedRevision string             `json:"lastAttemptedRevision"`
+			Inventory             *struct {
+				Entries []struct {
+					ID string `json:"id"`
+				} `json:"entries"`
+			} `json:"inventory"`
+			History []struct { // HelmRelease
+				ChartVersion string `json:"chartVersion"`
+				Status       string `json:"status"`
+			} `json:"history"`
+		} `json:"status"`
+	}
+)
+
+// ArgoApplication summarizes an Argo CD Application.
+type ArgoApplication struct {
+	Name           string `json:"name"`
+	Namespace      string `json:"namespace"`
+	Project        string `json:"project,omitempty"`
+	Repo           string `json:"repo,omitempty"`
+	Path           string `json:"path,omitempty"`
+	Chart          string `json:"chart,omitempty"`
+	TargetRevision string `json:"target_revision,omitempty"`
+	Destination    string `json:"destination,omitempty"`
+	Sync           string `json:"sync"`
+	Health         string `json:"health"`
+	Revision       string `json:"revision,omitempty"`
+	AutoSync       bool   `json:"auto_sync"`
+	SelfHeal       bool   `json:"self_heal,omitempty"`
+	Operation      string `json:"operation,omitempty"` // last sync operation, unless it succeeded
+}
+
+// summarize returns the list view of an Application.
+func (a *argoApplication) summarize() ArgoApplication {
+	s := ArgoApplication{
+		Name:      a.Metadata.Name,
+		Namespace: a.Metadata.Namespace,
+		Project:   a.Spec.Project,
+		Sync:      orUnknown(a.Status.Sync.Status),
+		Health:    orUnknown(a.Status.Health.Status),
+		Revision:  shortRevision(a.Status.Sync.Revision),
+	}
+	source := a.Spec.Source
+	if source == nil && len(a.Spec.Sources) > 0 {
+		source = &a.Spec.Sources[0]
+	}
+	if source != nil {
+		s.Repo, s.Path, s.Chart, s.TargetRevision = source.RepoURL, source.Path, source.Chart, source.TargetRevision
+	}
+	dest := a.Spec.Destination
+	server := dest.Server
+	if server == "" {
+		server = dest.Name
+	}
+	s.Destination = dest.Namespace
+	if server != "" && server != "https://kubernetes.default.svc" && server != "in-cluster" {
+		s.Destination += " @ " + server
+	}
+	if auto := a.Spec.SyncPolicy.Automated; auto != nil {
+		s.AutoSync, s.SelfHeal = true, auto.SelfHeal
+	}
+	if op := a.Status.OperationState; op != nil && op.Phase != "Succeeded" {
+		s.Operation = op.Phase
+		if op.Message != "" {
+			s.Operation += ": " + op.Message
+		}
+	}
+	return s
+}
+
+// FluxResource summarizes a Flux Kustomization or HelmRelease.
+type FluxResource struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Ready     string `json:"ready"` // the Ready condition: True, False or Unknown
+	Reason    string `json:"reason,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Source    string `json:"source,omitempty"` // e.g. GitRepository/flux-system/flux-system
+	Path      string `json:"path,omitempty"`
+	Chart     string `json:"chart,omitempty"` // chart@version
+	Revision  string `json:"revision,omitempty"`
+	Suspended bool   `json:"suspended,omitempty"`
+}
+
+// summarize returns the list view of a Flux object.
+func (f *fluxObject) summarize() FluxResource {
+	s := FluxResource{
+		Kind:      f.Kind,
+		Name:      f.Metadata.Name,
+		Namespace: f.Metadata.Namespace,
+		Ready:     "Unknown",
+		Path:      f.Spec.Path,
+		Revision:  f.Status.LastAppliedRevision,
+		Suspended: f.Spec.Suspend,
+	}
+	if ready := meta.FindStatusCondition(f.Status.Conditions, "Ready"); ready != nil {
+		s.Ready, s.Reason, s.Message = string(ready.Status), ready.Reason, ready.Message
+	}
+	if ref := f.sourceRef(); ref != nil {
+		s.Source = fmt.Sprintf("%s/%s/%s", ref.Kind, ref.Namespace, ref.Name)
+	}
+	if c := f.Spec.Chart; c != nil {
+		s.Chart = c.Spec.Chart
+		if c.Spec.Version != "" {
+			s.Chart += "@" + c.Spec.Version
+		}
+	}
+	if s.Revision == "" && len(f.Status.History) > 0 {
+		s.Revision = f.Status.History[0].ChartVersion
+	}
+	return s
+}
+
+// sourceRef returns the source the object is built from, with its
+// namespace defaulted to the object's own.
+func (f *fluxObject) sourceRef() *fluxSourceRef {
+	var ref fluxSourceRef
+	switch {
+	case f.Spec.SourceRef.Name != "":
+		ref = f.Spec.SourceRef
+	case f.Spec.Chart != nil:
+		ref = f.Spec.Chart.Spec.SourceRef
+	case f.Spec.ChartRef != nil:
+		ref = *f.Spec.ChartRef
+	default:
+		return nil
+	}
+	if ref.Namespace == "" {
+		ref.Namespace = f.Metadata.Namespace
+	}
+	return &ref
+}
+
+// orUnknown returns s, or "Unknown" if the controller hasn't reported yet.
+func orUnknown(s string) string {
+	if s == "" {
+		return "Unknown"
+	}
+	return s
+}
+
+// shortRevision shortens a git commit SHA to 8 characters.
+func shortRevision(rev string) string {
+	if len(rev) == 40 && !strings.ContainsAny(rev, "/:@") {
+		return rev[:8]
+	}
+	return rev
+}
@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestGitOpsManagerOf(t *testing.T) {
+	for _, tt := range []struct {
+		labels, annotations map[string]string
+		want                string
+	}{
+		{nil, map[string]string{argoTrackingAnnotation: "web:apps/Deployment:shop/web"}, "Argo CD Application web"},
+		{nil, map[string]string{argoTrackingAnnotation: "team-a_web:apps/Deployment:shop/web"}, "Argo CD Application team-a/web"},
+		{map[string]string{argoInstanceLabel: "web"}, nil, "Argo CD Application web"},
+		{map[string]string{fluxKustomizeNameLabel: "apps", fluxKustomizeNSLabel: "flux-system"}, nil, "Flux Kustomization flux-system/apps"},
+		{map[string]string{fluxHelmReleaseNameLabel: "redis", fluxHelmReleaseNSLabel: "cache"}, nil, "Flux HelmRelease cache/redis"},
+		// Helm sets this too, so it doesn't mean Argo CD
+		{map[string]string{"app.kubernetes.io/instance": "web"}, nil, ""},
+	} {
+		got := ""
+		if m := gitOpsManagerOf(tt.labels, tt.annotations); m != nil {
+			got = m.String()
+		}
+		if got != tt.want {
+			t.Errorf("gitOpsManagerOf(%v, %v) = %q, want %q", tt.labels, tt.annotations, got, tt.want)
+		}
+	}
+}
+
+func newGitOpsFake(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	listKinds := map[schema.GroupVersionResource]string{
+		CommonGVRs["application"]:   "ApplicationList",
+		CommonGVRs["kustomization"]: "KustomizationList",
+		CommonGVRs["helmrelease"]:   "HelmReleaseList",
+		CommonGVRs["gitrepository"]: "GitRepositoryList",
+	}
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+}
+
+func argoTestApplication(name, sync, health string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Application",
+		"metadata":   map[string]any{"name": name, "namespace": "argocd"},
+		"spec": map[string]any{
+			"project":     "default",
+			"source":      map[string]any{"repoURL": "https://github.com/acme/deploy", "path": "apps/" + name, "targetRevision": "main"},
+			"destination": map[string]any{"server": "https://kubernetes.default.svc", "namespace": "shop"},
+			"syncPolicy":  map[string]any{"automated": map[string]any{"prune": true, "selfHeal": true}},
+		},
+		"status": map[string]any{
+			"sync":   map[string]any{"status": sync, "revision": "0123456789abcdef0123456789abcdef01234567"},
+			"health": map[string]any{"status": health},
+			"resources": []any{
+				map[string]any{"kind": "Service", "namespace": "shop", "name": name, "status": "Synced", "health": map[string]any{"status": "Healthy"}},
+				map[string]any{"group": "apps", "kind": "Deployment", "namespace": "shop", "name": name, "status": "OutOfSync",
+					"health": map[string]any{"status": "Degraded", "message": "Deployment exceeded its progress deadline"}},
+			},
+			"history": []any{
+				map[string]any{"revision": "1111111111111111111111111111111111111111", "deployedAt": "2026-10-01T10:00:00Z"},
+				map[string]any{"revision": "2222222222222222222222222222222222222222", "deployedAt": "2026-10-02T10:00:00Z"},
+			},
+		},
+	}}
+}
+
+func TestArgoApplications(t *testing.T) {
+	dyn := newGitOpsFake(argoTestApplication("web", "OutOfSync", "Degraded"), argoTestApplication("api", "Synced", "Healthy"))
+
+	result := listArgoApplications(context.Background(), dyn, nil, "", "shop")
+	apps, _ := result["applications"].([]ArgoApplication)
+	if len(apps) != 2 || apps[0].Name != "api" {
+		t.Fatalf("applications = %+v", apps)
+	}
+	web := apps[1]
+	if web.Repo != "https://github.com/acme/deploy" || web.Path != "apps/web" || web.Destination != "shop" || !web.SelfHeal || web.Revision != "01234567" {
+		t.Errorf("web = %+v", web)
+	}
+	if problems, _ := result["not_synced_or_healthy"].([]string); len(problems) != 1 || problems[0] != "argocd/web: OutOfSync, Degraded" {
+		t.Errorf("not_synced_or_healthy = %v", result["not_synced_or_healthy"])
+	}
+	if result := listArgoApplications(context.Background(), dyn, nil, "", "other"); result["count"] != 0 {
+		t.Errorf("destination filter: %v", result)
+	}
+
+	result = argoApplicationStatus(context.Background(), dyn, nil, "argocd", "web")
+	resources, _ := result["resources_needing_attention"].([]string)
+	if len(resources) != 1 || resources[0] != "Deployment shop/web: OutOfSync, Degraded: Deployment exceeded its progress deadline" {
+		t.Errorf("resources_needing_attention = %v", resources)
+	}
+	if syncs, _ := result["recent_syncs"].([]string); len(syncs) != 2 || !strings.HasPrefix(syncs[0], "22222222") {
+		t.Errorf("recent_syncs = %v", syncs)
+	}
+	if advice, _ := result["advice"].(string); !strings.Contains(advice, "self-heal") || !strings.Contains(advice, "https://github.com/acme/deploy path apps/web at main") {
+		t.Errorf("advice = %q", advice)
+	}
+}
+
+func TestFluxStatus(t *testing.T) {
+	ks := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "kustomize.toolkit.fluxcd.io/v1",
+		"kind":       "Kustomization",
+		"metadata":   map[string]any{"name": "apps", "namespace": "flux-system"},
+		"spec": map[string]any{
+			"path":      "./clusters/prod/apps",
+			"sourceRef": map[string]any{"kind": "GitRepository", "name": "flux-system"},
+		},
+		"status": map[string]any{
+			"conditions": []any{map[string]any{
+				"type": "Ready", "status": "False", "reason": "BuildFailed", "message": "kustomize build failed",
+				"lastTransitionTime": "2026-10-02T10:00:00Z",
+			}},
+			"lastAppliedRevision":   "main@sha1:aaaa",
+			"lastAttemptedRevision": "main@sha1:bbbb",
+			"inventory": map[string]any{"entries": []any{
+				map[string]any{"id": "shop_web_apps_Deployment", "v": "v1"},
+				map[string]any{"id": "_shop__Namespace", "v": "v1"},
+			}},
+		},
+	}}
+	repo := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "source.toolkit.fluxcd.io/v1",
+		"kind":       "GitRepository",
+		"metadata":   map[string]any{"name": "flux-system", "namespace": "flux-system"},
+		"spec":       map[string]any{"url": "ssh://git@github.com/acme/fleet"},
+		"status": map[string]any{
+			"artifact":   map[string]any{"revision": "main@sha1:bbbb"},
+			"conditions": []any{map[string]any{"type": "Ready", "status": "True", "reason": "Succeeded", "lastTransitionTime": "2026-10-02T10:00:00Z"}},
+		},
+	}}
+	dyn := newGitOpsFake(ks, repo)
+
+	result := listFluxResources(context.Background(), dyn, nil, "", fluxKinds)
+	resources, _ := result["resources"].([]FluxResource)
+	if len(resources) != 1 || resources[0].Source != "GitRepository/flux-system/flux-system" || resources[0].Ready != "False" {
+		t.Fatalf("resources = %+v", resources)
+	}
+
+	result = fluxStatus(context.Background(), dyn, nil, "kustomization", "flux-system", "apps")
+	inventory, _ := result["inventory"].([]string)
+	if len(inventory) != 2 || inventory[0] != "Deployment shop/web" || inventory[1] != "Namespace shop" {
+		t.Errorf("inventory = %v", inventory)
+	}
+	source, _ := result["source"].(map[string]any)
+	if source["url"] != "ssh://git@github.com/acme/fleet" || source["ready"] != "True" {
+		t.Errorf("source = %v", source)
+	}
+	issues, _ := result["issues"].([]string)
+	if len(issues) != 2 || !strings.Contains(issues[1], "main@sha1:bbbb failed to apply") {
+		t.Errorf("issues = %v", issues)
+	}
+	if advice, _ := result["advice"].(string); !strings.Contains(advice, "ssh://git@github.com/acme/fleet path ./clusters/prod/apps") {
+		t.Errorf("advice = %q", advice)
+	}
+}
@@ -55,9 +55,20 @@ var CommonGVRs = map[string]schema.GroupVersionResource{
 	"issuer":        {Group: "cert-manager.io", Version: "v1", Resource: "issuers"},
 	"clusterissuer": {Group: "cert-manager.io", Version: "v1", Resource: "clusterissuers"},
 	"certificaterequest": {Group: "cert-manager.io", Version: "v1", Resource: "certificaterequests"},
+	"order":         {Group: "acme.cert-manager.io", Version: "v1", Resource: "orders"},
+	"challenge":     {Group: "acme.cert-manager.io", Version: "v1", Resource: "challenges"},
+
+	// Policy
+	"poddisruptionbudget": {Group: "policy", Version: "v1", Resource: "poddisruptionbudgets"},
 
 	// Autoscaling
 	"horizontalpodautoscaler": {Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"},
+
+	// GitOps controllers
+	"application":   {Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"},
+	"kustomization": {Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Resource: "kustomizations"},
+	"helmrelease":   {Group: "helm.toolkit.fluxcd.io", Version: "v2", Resource: "helmreleases"},
+	"gitrepository": {Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "gitrepositories"},
 }
 
 // KindAliases maps common aliases to their canonical kind names.
@@ -109,8 +120,19 @@ var KindAliases = map[string]string{
 	"clusterissuers": "clusterissuer",
 	"certificaterequests": "certificaterequest",
 	"cr":          "certificaterequest",
+	"orders":      "order",
+	"challenges":  "challenge",
+	"pdb":         "poddisruptionbudget",
+	"poddisruptionbudgets": "poddisruptionbudget",
 	"hpa":         "horizontalpodautoscaler",
 	"horizontalpodautoscalers": "horizontalpodautoscaler",
+	"applications": "application",
+	"kustomizations": "kustomization",
+	"ks":          "kustomization",
+	"helmreleases": "helmrelease",
+	"hr":          "helmrelease",
+	"gitrepositories": "gitrepository",
+	"gitrepo":     "gitrepository",
 }
 
 // ClusterScopedKinds lists kinds that are cluster-scoped (not namespaced).
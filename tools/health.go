@@ -1,7 +1,6 @@
 package tools
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -32,6 +31,7 @@ type HealthEventInfo struct {
 // CheckDeploymentHealthTool provides the check_deployment_health tool for the agent.
 type CheckDeploymentHealthTool struct {
 	clientset *kubernetes.Clientset
+	cache     *ResourceCache // nil reads from the API server
 }
 
 // NewCheckDeploymentHealthTool creates a new CheckDeploymentHealthTool.
@@ -41,6 +41,11 @@ func NewCheckDeploymentHealthTool(clientset *kubernetes.Clientset) *CheckDeploym
 	}
 }
 
+// useCache makes the tool read through the resource cache.
+func (t *CheckDeploymentHealthTool) useCache(c *ResourceCache) {
+	t.cache = c
+}
+
 // Name returns the tool name.
 func (t *CheckDeploymentHealthTool) Name() string {
 	return "check_deployment_health"
@@ -113,18 +118,18 @@ func (t *CheckDeploymentHealthTool) Run(ctx tool.Context, args any) (map[string]
 		return map[string]any{"error": "namespace is required"}, nil
 	}
 
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
 	defer cancel()
 
 	// Get deployment
-	deployment, err := t.clientset.AppsV1().Deployments(namespace).Get(timeoutCtx, name, metav1.GetOptions{})
+	deployment, err := t.cache.getDeployment(timeoutCtx, t.clientset, namespace, name)
 	if err != nil {
 		return map[string]any{"error": fmt.Sprintf("failed to get deployment: %v", err)}, nil
 	}
 
 	// Get pods for this deployment
 	labelSelector := fmt.Sprintf("app.kubernetes.io/name=%s", name)
-	pods, err := t.clientset.CoreV1().Pods(namespace).List(timeoutCtx, metav1.ListOptions{
+	pods, err := t.cache.listPods(timeoutCtx, t.clientset, namespace, metav1.ListOptions{
 		LabelSelector: labelSelector,
 	})
 	if err != nil {
@@ -153,7 +158,7 @@ func (t *CheckDeploymentHealthTool) Run(ctx tool.Context, args any) (map[string]
 	}
 
 	// Get recent events
-	events, err := t.clientset.CoreV1().Events(namespace).List(timeoutCtx, metav1.ListOptions{
+	events, err := t.cache.listEvents(timeoutCtx, t.clientset, namespace, metav1.ListOptions{
 		FieldSelector: fmt.Sprintf("involvedObject.name=%s", name),
 	})
 	if err != nil {
@@ -195,12 +200,26 @@ func (t *CheckDeploymentHealthTool) Run(ctx tool.Context, args any) (map[string]
 		message = fmt.Sprintf("Deployment %s is not healthy: %d/%d replicas ready", name, readyReplicas, replicas)
 	}
 
-	return map[string]any{
+	result := map[string]any{
 		"healthy":        healthy,
 		"replicas":       replicas,
 		"ready_replicas": readyReplicas,
 		"pods":           podInfos,
 		"events":         eventInfos,
 		"message":        message,
-	}, nil
+	}
+
+	// Without a budget a node drain may evict every replica at once
+	if replicas > 1 {
+		pdbs, err := t.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(timeoutCtx, metav1.ListOptions{})
+		if err == nil {
+			if covering := pdbsCovering(pdbs.Items, namespace, deployment.Spec.Template.Labels); len(covering) > 0 {
+				result["pdbs"] = covering
+			} else {
+				result["warnings"] = []string{fmt.Sprintf("No PodDisruptionBudget covers this deployment's %d replicas; a node drain may evict them all at once (create one with create_pdb)", replicas)}
+			}
+		}
+	}
+
+	return result, nil
 }
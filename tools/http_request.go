@@ -125,7 +125,7 @@ func (t *HTTPRequestTool) Run(ctx tool.Context, args any) (map[string]any, error
 	}
 
 	// Create request
-	req, err := http.NewRequest(method, url, nil)
+	req, err := http.NewRequestWithContext(toolContext(ctx), method, url, nil)
 	if err != nil {
 		return map[string]any{"error": fmt.Sprintf("failed to create request: %v", err)}, nil
 	}
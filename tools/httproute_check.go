@@ -0,0 +1,330 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const gatewayAPIGroup = "gateway.networking.k8s.io"
+
+// The parts of the Gateway API objects check_ingress inspects.
+type (
+	gatewayObjectRef struct {
+		Group       string `json:"group"`
+		Kind        string `json:"kind"`
+		Namespace   string `json:"namespace"`
+		Name        string `json:"name"`
+		SectionName string `json:"sectionName"`
+		Port        *int32 `json:"port"`
+	}
+
+	httpRoute struct {
+		Spec struct {
+			ParentRefs []gatewayObjectRef `json:"parentRefs"`
+			Hostnames  []string           `json:"hostnames"`
+			Rules      []struct {
+				BackendRefs []gatewayObjectRef `json:"backendRefs"`
+			} `json:"rules"`
+		} `json:"spec"`
+		Status struct {
+			Parents []struct {
+				ParentRef  gatewayObjectRef   `json:"parentRef"`
+				Conditions []metav1.Condition `json:"conditions"`
+			} `json:"parents"`
+		} `json:"status"`
+	}
+
+	gateway struct {
+		Spec struct {
+			GatewayClassName string `json:"gatewayClassName"`
+			Listeners        []struct {
+				Name     string `json:"name"`
+				Hostname string `json:"hostname"`
+				Port     int32  `json:"port"`
+				Protocol string `json:"protocol"`
+				TLS      *struct {
+					CertificateRefs []gatewayObjectRef `json:"certificateRefs"`
+				} `json:"tls"`
+			} `json:"listeners"`
+		} `json:"spec"`
+		Status struct {
+			Addresses []struct {
+				Value string `json:"value"`
+			} `json:"addresses"`
+			Conditions []metav1.Condition `json:"conditions"`
+		} `json:"status"`
+	}
+
+	gatewayClass struct {
+		Spec struct {
+			ControllerName string `json:"controllerName"`
+		} `json:"spec"`
+		Status struct {
+			Conditions []metav1.Condition `json:"conditions"`
+		} `json:"status"`
+	}
+
+	referenceGrant struct {
+		Spec struct {
+			From []gatewayObjectRef `json:"from"`
+			To   []gatewayObjectRef `json:"to"`
+		} `json:"spec"`
+	}
+)
+
+// decodeUnstructured converts an unstructured object into one of the types above.
+func decodeUnstructured(obj *unstructured.Unstructured, into any) error {
+	data, err := json.Marshal(obj.Object)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, into)
+}
+
+// dynamicGetter fetches custom resources with the dynamic client.
+type dynamicGetter struct {
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+}
+
+func (g dynamicGetter) get(ctx context.Context, kind, namespace, name string, into any) error {
+	gvr, err := g.resolver.Resolve(kind, "")
+	if err != nil {
+		return err
+	}
+	var obj *unstructured.Unstructured
+	if namespace == "" {
+		obj, err = g.dynamicClient.Resource(gvr).Get(ctx, name, metav1.GetOptions{})
+	} else {
+		obj, err = g.dynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return err
+	}
+	return decodeUnstructured(obj, into)
+}
+
+// listOwnedBy lists the objects of kind in namespace that have owner as an
+// owner reference.
+func (g dynamicGetter) listOwnedBy(ctx context.Context, kind, namespace string, owner types.UID) ([]unstructured.Unstructured, error) {
+	gvr, err := g.resolver.Resolve(kind, "")
+	if err != nil {
+		return nil, err
+	}
+	list, err := g.dynamicClient.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var owned []unstructured.Unstructured
+	for _, obj := range list.Items {
+		for _, ref := range obj.GetOwnerReferences() {
+			if ref.UID == owner {
+				owned = append(owned, obj)
+				break
+			}
+		}
+	}
+	return owned, nil
+}
+
+// checkHTTPRoute builds the check_ingress response for an HTTPRoute.
+func checkHTTPRoute(ctx context.Context, clientset kubernetes.Interface, dynamicClient dynamic.Interface, resolver *GVRResolver, namespace, name string, now time.Time) map[string]any {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	getter := dynamicGetter{dynamicClient: dynamicClient, resolver: resolver}
+	var route httpRoute
+	if err := getter.get(ctx, "httproute", namespace, name, &route); err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to get HTTPRoute: %v", err)}
+	}
+
+	var issues []string
+	var conditions []StatusCondition
+	var addresses []string
+	var tlsChecks []TLSCheck
+	var gateways []string
+	for _, ref := range route.Spec.ParentRefs {
+		if ref.Kind != "" && ref.Kind != "Gateway" {
+			continue
+		}
+		gwNamespace := defaultString(ref.Namespace, namespace)
+		gwName := gwNamespace + "/" + ref.Name
+		gateways = append(gateways, gwName)
+
+		var gw gateway
+		if err := getter.get(ctx, "gateway", gwNamespace, ref.Name, &gw); err != nil {
+			if apierrors.IsNotFound(err) {
+				issues = append(issues, fmt.Sprintf("parent Gateway %s does not exist", gwName))
+			} else {
+				issues = append(issues, fmt.Sprintf("failed to get Gateway %s: %v", gwName, err))
+			}
+			continue
+		}
+
+		var class gatewayClass
+		if err := getter.get(ctx, "gatewayclass", "", gw.Spec.GatewayClassName, &class); apierrors.IsNotFound(err) {
+			issues = append(issues, fmt.Sprintf("GatewayClass %s of Gateway %s does not exist", gw.Spec.GatewayClassName, gwName))
+		} else if err == nil {
+			conditions, issues = collectConditions("GatewayClass/"+gw.Spec.GatewayClassName, class.Status.Conditions, conditions, issues)
+		}
+
+		conditions, issues = collectConditions("Gateway/"+gwName, gw.Status.Conditions, conditions, issues)
+		for _, a := range gw.Status.Addresses {
+			addresses = append(addresses, a.Value)
+		}
+		if len(gw.Status.Addresses) == 0 {
+			issues = append(issues, fmt.Sprintf("Gateway %s has no address in status; its controller hasn't programmed it", gwName))
+		}
+
+		for _, l := range gw.Spec.Listeners {
+			if (ref.SectionName != "" && l.Name != ref.SectionName) || (ref.Port != nil && l.Port != *ref.Port) || l.TLS == nil {
+				continue
+			}
+			hosts := route.Spec.Hostnames
+			if l.Hostname != "" {
+				hosts = []string{l.Hostname}
+			}
+			for _, cert := range l.TLS.CertificateRefs {
+				if cert.Kind != "" && cert.Kind != "Secret" {
+					continue
+				}
+				certNamespace := defaultString(cert.Namespace, gwNamespace)
+				check := checkTLSSecret(ctx, clientset, certNamespace, cert.Name, hosts, now)
+				check.Namespace = certNamespace
+				if check.Problem != "" {
+					issues = append(issues, fmt.Sprintf("listener %s TLS secret %s/%s: %s", l.Name, certNamespace, cert.Name, check.Problem))
+				}
+				tlsChecks = append(tlsChecks, check)
+			}
+		}
+	}
+	if len(gateways) == 0 {
+		issues = append(issues, "HTTPRoute has no parent Gateway, so no gateway serves it")
+	}
+
+	for _, parent := range route.Status.Parents {
+		conditions, issues = collectConditions("HTTPRoute parent Gateway/"+parent.ParentRef.Name, parent.Conditions, conditions, issues)
+	}
+	if len(gateways) > 0 && len(route.Status.Parents) == 0 {
+		issues = append(issues, "HTTPRoute has no status from any Gateway; the gateway controller hasn't processed it (check the Gateway's allowedRoutes)")
+	}
+
+	var backends []BackendCheck
+	for _, rule := range route.Spec.Rules {
+		for _, ref := range rule.BackendRefs {
+			if (ref.Group != "" && ref.Group != "core") || (ref.Kind != "" && ref.Kind != "Service") {
+				continue
+			}
+			backends = append(backends, routeBackend(ctx, clientset, getter, namespace, ref))
+		}
+	}
+	for _, b := range backends {
+		if b.Problem != "" {
+			issues = append(issues, fmt.Sprintf("backend %s:%s: %s", b.Service, b.Port, b.Problem))
+		}
+	}
+
+	result := map[string]any{
+		"kind":      "HTTPRoute",
+		"namespace": namespace,
+		"name":      name,
+		"hostnames": route.Spec.Hostnames,
+		"gateways":  gateways,
+		"addresses": addresses,
+		"backends":  backends,
+	}
+	if len(tlsChecks) > 0 {
+		result["tls"] = tlsChecks
+	}
+	if len(conditions) > 0 {
+		result["conditions"] = conditions
+	}
+	return finishIngressCheck(result, issues, fmt.Sprintf("HTTPRoute %s is accepted by %s and served at %s", name, strings.Join(gateways, ", "), strings.Join(addresses, ", ")))
+}
+
+// collectConditions appends conditions and reports the ones that are False.
+func collectConditions(object string, in []metav1.Condition, conditions []StatusCondition, issues []string) ([]StatusCondition, []string) {
+	for _, c := range in {
+		conditions = append(conditions, StatusCondition{
+			Object:  object,
+			Type:    c.Type,
+			Status:  string(c.Status),
+			Reason:  c.Reason,
+			Message: c.Message,
+		})
+		if c.Status == metav1.ConditionFalse {
+			issues = append(issues, fmt.Sprintf("%s is not %s: %s %s", object, c.Type, c.Reason, c.Message))
+		}
+	}
+	return conditions, issues
+}
+
+// routeBackend checks an HTTPRoute backendRef, including the ReferenceGrant
+// a cross-namespace reference needs.
+func routeBackend(ctx context.Context, clientset kubernetes.Interface, getter dynamicGetter, routeNamespace string, ref gatewayObjectRef) BackendCheck {
+	backendNamespace := defaultString(ref.Namespace, routeNamespace)
+	check := BackendCheck{Service: ref.Name}
+	if ref.Port != nil {
+		check.Port = fmt.Sprintf("%d", *ref.Port)
+	}
+	if backendNamespace != routeNamespace {
+		check.Namespace = backendNamespace
+		if !referenceGranted(ctx, getter, routeNamespace, backendNamespace, ref.Name) {
+			check.Problem = fmt.Sprintf("no ReferenceGrant in %s allows HTTPRoutes from %s to use this Service", backendNamespace, routeNamespace)
+			return check
+		}
+	}
+	if ref.Port == nil {
+		check.Problem = "backendRef has no port"
+		return check
+	}
+	checkBackendService(ctx, clientset, backendNamespace, ref.Name, "", *ref.Port, &check)
+	return check
+}
+
+// referenceGranted reports whether a ReferenceGrant in toNamespace lets
+// HTTPRoutes in fromNamespace reference the Service.
+func referenceGranted(ctx context.Context, getter dynamicGetter, fromNamespace, toNamespace, service string) bool {
+	gvr, err := getter.resolver.Resolve("referencegrant", "")
+	if err != nil {
+		return false
+	}
+	list, err := getter.dynamicClient.Resource(gvr).Namespace(toNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false
+	}
+	for i := range list.Items {
+		var grant referenceGrant
+		if err := decodeUnstructured(&list.Items[i], &grant); err != nil {
+			continue
+		}
+		from, to := false, false
+		for _, f := range grant.Spec.From {
+			from = from || (f.Group == gatewayAPIGroup && f.Kind == "HTTPRoute" && f.Namespace == fromNamespace)
+		}
+		for _, t := range grant.Spec.To {
+			to = to || (t.Group == "" && t.Kind == "Service" && (t.Name == "" || t.Name == service))
+		}
+		if from && to {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultString(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
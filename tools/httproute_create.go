@@ -0,0 +1,265 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// CreateHTTPRouteTool provides the create_httproute tool for the agent.
+type CreateHTTPRouteTool struct {
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+	manifest      *manifest.Manager
+}
+
+// NewCreateHTTPRouteTool creates a new CreateHTTPRouteTool.
+func NewCreateHTTPRouteTool(dynamicClient dynamic.Interface, resolver *GVRResolver, manifest *manifest.Manager) *CreateHTTPRouteTool {
+	return &CreateHTTPRouteTool{
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+		manifest:      manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *CreateHTTPRouteTool) Name() string {
+	return "create_httproute"
+}
+
+// Description returns the tool description.
+func (t *CreateHTTPRouteTool) Description() string {
+	return "Create or update a Gateway API HTTPRoute that attaches to a Gateway and sends matching hostnames and paths to a Service. Saves the manifest to git and applies it to the cluster."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *CreateHTTPRouteTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *CreateHTTPRouteTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *CreateHTTPRouteTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *CreateHTTPRouteTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"name": {
+					Type:        "string",
+					Description: "The name of the HTTPRoute",
+				},
+				"namespace": {
+					Type:        "string",
+					Description: "The target Kubernetes namespace",
+				},
+				"gateway": {
+					Type:        "string",
+					Description: "Name of the parent Gateway",
+				},
+				"gateway_namespace": {
+					Type:        "string",
+					Description: "Namespace of the parent Gateway (default: the route's namespace)",
+				},
+				"section_name": {
+					Type:        "string",
+					Description: "Gateway listener to attach to (default: all listeners that allow the route)",
+				},
+				"hostnames": {
+					Type:        "array",
+					Description: "Hostnames the route matches (e.g., [\"shop.example.com\"])",
+					Items:       &genai.Schema{Type: "string"},
+				},
+				"service_name": {
+					Type:        "string",
+					Description: "The backend service name",
+				},
+				"service_port": {
+					Type:        "integer",
+					Description: "The backend service port",
+				},
+				"paths": {
+					Type:        "array",
+					Description: "Path matches (default: [\"/\"])",
+					Items:       &genai.Schema{Type: "string"},
+				},
+				"path_match": {
+					Type:        "string",
+					Description: "How paths match: PathPrefix (default), Exact or RegularExpression",
+				},
+			},
+			Required: []string{"name", "namespace", "gateway", "service_name", "service_port"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *CreateHTTPRouteTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+
+	route, err := buildHTTPRoute(name, namespace, argsMap)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	yamlBytes, err := yaml.Marshal(route.Object)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to marshal httproute: %v", err)}, nil
+	}
+	manifestPath, err := t.manifest.SaveManifest(namespace, name, "httproute", yamlBytes)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to save manifest: %v", err)}, nil
+	}
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
+	defer cancel()
+	action, err := createOrUpdate(timeoutCtx, t.dynamicClient, t.resolver, route)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	result := map[string]any{
+		"success":       true,
+		"action":        action,
+		"name":          name,
+		"namespace":     namespace,
+		"gateway":       argsMap["gateway"],
+		"service":       argsMap["service_name"],
+		"port":          argsMap["service_port"],
+		"manifest_path": manifestPath,
+		"message":       fmt.Sprintf("HTTPRoute %s %s in namespace %s; run check_ingress with kind HTTPRoute to confirm the Gateway accepted it", name, action, namespace),
+	}
+	if gwNamespace, _ := argsMap["gateway_namespace"].(string); gwNamespace != "" && gwNamespace != namespace {
+		result["note"] = fmt.Sprintf("The Gateway is in %s; its listener must set allowedRoutes.namespaces.from to All (or select %s) for the route to attach", gwNamespace, namespace)
+	}
+	return result, nil
+}
+
+// buildHTTPRoute builds an HTTPRoute from the create_httproute arguments.
+func buildHTTPRoute(name, namespace string, argsMap map[string]any) (*unstructured.Unstructured, error) {
+	gateway, _ := argsMap["gateway"].(string)
+	if gateway == "" {
+		return nil, fmt.Errorf("gateway is required")
+	}
+	serviceName, _ := argsMap["service_name"].(string)
+	if serviceName == "" {
+		return nil, fmt.Errorf("service_name is required")
+	}
+	servicePort, _ := argsMap["service_port"].(float64)
+	if servicePort <= 0 {
+		return nil, fmt.Errorf("service_port is required")
+	}
+
+	matchType := "PathPrefix"
+	if m, ok := argsMap["path_match"].(string); ok && m != "" {
+		if m != "PathPrefix" && m != "Exact" && m != "RegularExpression" {
+			return nil, fmt.Errorf("path_match must be PathPrefix, Exact or RegularExpression, got %q", m)
+		}
+		matchType = m
+	}
+	paths := stringArg(argsMap, "paths")
+	if len(paths) == 0 {
+		paths = []string{"/"}
+	}
+	var matches []any
+	for _, p := range paths {
+		if matchType != "RegularExpression" && !strings.HasPrefix(p, "/") {
+			return nil, fmt.Errorf("path %q must start with /", p)
+		}
+		matches = append(matches, map[string]any{"path": map[string]any{"type": matchType, "value": p}})
+	}
+
+	parentRef := map[string]any{"name": gateway}
+	if gwNamespace, _ := argsMap["gateway_namespace"].(string); gwNamespace != "" {
+		parentRef["namespace"] = gwNamespace
+	}
+	if section, _ := argsMap["section_name"].(string); section != "" {
+		parentRef["sectionName"] = section
+	}
+
+	spec := map[string]any{
+		"parentRefs": []any{parentRef},
+		"rules": []any{map[string]any{
+			"matches":     matches,
+			"backendRefs": []any{map[string]any{"name": serviceName, "port": int64(servicePort)}},
+		}},
+	}
+	if hostnames := stringArg(argsMap, "hostnames"); len(hostnames) > 0 {
+		var hs []any
+		for _, h := range hostnames {
+			hs = append(hs, h)
+		}
+		spec["hostnames"] = hs
+	}
+
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": gatewayAPIGroup + "/v1",
+		"kind":       "HTTPRoute",
+		"metadata": map[string]any{
+			"name":      name,
+			"namespace": namespace,
+			"labels": map[string]any{
+				"app.kubernetes.io/name":       name,
+				"app.kubernetes.io/managed-by": "kasa",
+			},
+		},
+		"spec": spec,
+	}}, nil
+}
+
+// stringArg reads a list argument given either as an array of strings or a
+// comma-separated string.
+func stringArg(argsMap map[string]any, key string) []string {
+	var out []string
+	switch v := argsMap[key].(type) {
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// imageCheckTimeout bounds the registry lookup for one planned image.
+const imageCheckTimeout = 10 * time.Second
+
+// imageChecker verifies that the images of planned create_deployment and
+// set_image actions can be pulled, so a typo'd tag shows up in the plan
+// instead of minutes later as ImagePullBackOff.
+type imageChecker struct {
+	clientset kubernetes.Interface
+}
+
+// newImageChecker returns an imageChecker reading pull secrets through
+// clientset, or anonymous if it is nil.
+func newImageChecker(clientset *kubernetes.Clientset) *imageChecker {
+	if clientset == nil {
+		return &imageChecker{}
+	}
+	return &imageChecker{clientset: clientset}
+}
+
+// check returns a warning if the image of a planned action can't be pulled,
+// or "" if it can or the action doesn't deploy an image.
+func (c *imageChecker) check(ctx context.Context, toolName string, params map[string]any) string {
+	if toolName != "create_deployment" && toolName != "set_image" {
+		return ""
+	}
+	image, _ := params["image"].(string)
+	if image == "" {
+		return ""
+	}
+	ref, err := parseImageRef(image)
+	if err != nil {
+		return err.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, imageCheckTimeout)
+	defer cancel()
+	return checkImage(ctx, c.client(ctx, params, c.podSpec(ctx, toolName, params), ref), ref)
+}
+
+// checkImage looks the manifest of ref up with a HEAD request.
+func checkImage(ctx context.Context, client *registryClient, ref imageRef) string {
+	_, err := client.head(ctx, ref.repository, "manifests/"+ref.reference(), strings.Join(manifestMediaTypes, ", "))
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, errNotFound):
+		return fmt.Sprintf("image %s was not found in %s; check the tag for typos, the pods would fail with ImagePullBackOff", ref, ref.registry)
+	default:
+		return fmt.Sprintf("could not verify that image %s can be pulled: %v", ref, err)
+	}
+}
+
+// client returns a registry client for ref, logged in with the pull
+// secrets of spec in the namespace of params.
+func (c *imageChecker) client(ctx context.Context, params map[string]any, spec *corev1.PodSpec, ref imageRef) *registryClient {
+	namespace, _ := params["namespace"].(string)
+	username, password := c.credentials(ctx, namespace, spec, ref.registry)
+	return newRegistryClient(ref, username, password)
+}
+
+// podSpec returns the pod spec whose pull secrets the pods of a planned
+// action would use: the image_pull_secret and service_account of
+// create_deployment, or the workload set_image updates. Returns nil if
+// there is none.
+func (c *imageChecker) podSpec(ctx context.Context, toolName string, params map[string]any) *corev1.PodSpec {
+	switch toolName {
+	case "create_deployment":
+		spec := &corev1.PodSpec{}
+		if secret, _ := params["image_pull_secret"].(string); secret != "" {
+			spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: secret}}
+		}
+		spec.ServiceAccountName, _ = params["service_account"].(string)
+		return spec
+	case "set_image":
+		namespace, _ := params["namespace"].(string)
+		name, _ := params["name"].(string)
+		kind, _ := params["kind"].(string)
+		return c.workloadSpec(ctx, namespace, kind, name)
+	}
+	return nil
+}
+
+// credentials returns the registry credentials from the pull secrets of
+// spec, then those of its service account (default "default"). Secrets
+// that don't exist yet (e.g. created earlier in the same plan) are
+// skipped, and the lookup is anonymous if none match.
+func (c *imageChecker) credentials(ctx context.Context, namespace string, spec *corev1.PodSpec, registry string) (string, string) {
+	if c.clientset == nil || namespace == "" {
+		return "", ""
+	}
+	var secrets []string
+	serviceAccount := "default"
+	if spec != nil {
+		for _, ref := range spec.ImagePullSecrets {
+			secrets = append(secrets, ref.Name)
+		}
+		if spec.ServiceAccountName != "" {
+			serviceAccount = spec.ServiceAccountName
+		}
+	}
+	if sa, err := c.clientset.CoreV1().ServiceAccounts(namespace).Get(ctx, serviceAccount, metav1.GetOptions{}); err == nil {
+		for _, ref := range sa.ImagePullSecrets {
+			secrets = append(secrets, ref.Name)
+		}
+	}
+
+	for _, secret := range secrets {
+		if username, password, err := pullSecretCredentials(ctx, c.clientset, namespace, secret, registry); err == nil {
+			return username, password
+		}
+	}
+	return "", ""
+}
+
+// workloadSpec returns the pod template of a live deployment (the default
+// kind) or statefulset, or nil if it can't be read.
+func (c *imageChecker) workloadSpec(ctx context.Context, namespace, kind, name string) *corev1.PodSpec {
+	if c.clientset == nil || name == "" {
+		return nil
+	}
+	if NormalizeKindName(kind) == "statefulset" {
+		sts, err := c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil
+		}
+		return &sts.Spec.Template.Spec
+	}
+	deploy, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil
+	}
+	return &deploy.Spec.Template.Spec
+}
@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckImage(t *testing.T) {
+	server := newTestRegistry(t)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	ref, _ := parseImageRef(host + "/team/app:1.25")
+	if warning := checkImage(context.Background(), newRegistryClient(ref, "", ""), ref); warning != "" {
+		t.Errorf("expected the image to exist, got %q", warning)
+	}
+	typo, _ := parseImageRef(host + "/team/app:1.52")
+	if warning := checkImage(context.Background(), newRegistryClient(typo, "", ""), typo); !strings.Contains(warning, "was not found") {
+		t.Errorf("expected a missing tag warning, got %q", warning)
+	}
+}
+
+func TestImageChecker_PullSecrets(t *testing.T) {
+	// A private registry that only answers HEAD requests with credentials
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("expected a HEAD request, got %s", r.Method)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "robot" || pass != "s3cret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v2/shop/web/manifests/2.0" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	replicas := int32(1)
+	clientset := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "regcred", Namespace: "shop"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"` + host + `":{"username":"robot","password":"s3cret"}}}`)},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					ImagePullSecrets: []corev1.LocalObjectReference{{Name: "regcred"}},
+				}},
+			},
+		},
+		&corev1.ServiceAccount{
+			ObjectMeta:       metav1.ObjectMeta{Name: "builder", Namespace: "shop"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "missing"}, {Name: "regcred"}},
+		},
+	)
+	checker := &imageChecker{clientset: clientset}
+
+	tests := []struct {
+		tool   string
+		params map[string]any
+		want   string
+	}{
+		{"set_image", map[string]any{"namespace": "shop", "name": "web", "image": host + "/shop/web:2.0"}, ""},
+		{"set_image", map[string]any{"namespace": "shop", "name": "web", "image": host + "/shop/web:2.O"}, "was not found"},
+		{"create_deployment", map[string]any{"namespace": "shop", "name": "api", "image": host + "/shop/web:2.0", "image_pull_secret": "regcred"}, ""},
+		{"create_deployment", map[string]any{"namespace": "shop", "name": "api", "image": host + "/shop/web:2.0", "service_account": "builder"}, ""},
+		{"create_deployment", map[string]any{"namespace": "shop", "name": "api", "image": host + "/shop/web:2.0"}, "401"},
+		{"create_deployment", map[string]any{"namespace": "shop", "name": "api", "image": "Web:2.0"}, "invalid"},
+		{"apply_manifest", map[string]any{"namespace": "shop", "image": host + "/shop/web:2.O"}, ""},
+	}
+	for _, tt := range tests {
+		warning := checker.check(context.Background(), tt.tool, tt.params)
+		if (tt.want == "") != (warning == "") || !strings.Contains(warning, tt.want) {
+			t.Errorf("%s %v: got warning %q, want %q", tt.tool, tt.params, warning, tt.want)
+		}
+	}
+}
+
+func TestProposePlan_ImageWarnings(t *testing.T) {
+	server := newTestRegistry(t)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	result, err := NewProposePlanTool(nil).Run(nil, map[string]any{
+		"description": "Upgrade the app",
+		"actions": []any{
+			map[string]any{"tool": "set_image", "reason": "upgrade", "parameters": map[string]any{"namespace": "shop", "name": "app", "image": host + "/team/app:1.25"}},
+			map[string]any{"tool": "create_deployment", "reason": "new", "parameters": map[string]any{"namespace": "shop", "name": "worker", "image": host + "/team/app:1.255"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings, _ := result["image_warnings"].([]string)
+	if result["status"] != "awaiting_approval" || len(warnings) != 1 || !strings.HasPrefix(warnings[0], "action 2 (create_deployment): image ") {
+		t.Errorf("expected a warning for the second action, got %v", result)
+	}
+}
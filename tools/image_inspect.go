@@ -0,0 +1,701 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// maxImageCVEs is how many vulnerabilities inspect_image lists.
+	maxImageCVEs = 20
+	// maxManifestBytes bounds manifests and image configs read from a registry.
+	maxManifestBytes = 4 << 20
+)
+
+// Manifest media types inspect_image understands, most specific first.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// severityOrder ranks Trivy severities, most severe first.
+var severityOrder = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"}
+
+// imageRef is a parsed image reference.
+type imageRef struct {
+	registry   string // host as written, docker.io by default
+	repository string // library/nginx for Docker Hub's official images
+	tag        string // latest if neither tag nor digest is given
+	digest     string
+}
+
+// parseImageRef parses an image reference the way the container runtime
+// does: the first component is a registry if it has a dot or a port or is
+// localhost, and images without a tag or digest are latest.
+func parseImageRef(image string) (imageRef, error) {
+	var ref imageRef
+	image = strings.TrimSpace(image)
+	if image == "" || strings.ContainsAny(image, " \t") {
+		return ref, fmt.Errorf("invalid image reference %q", image)
+	}
+	name, digest, _ := strings.Cut(image, "@")
+	ref.digest = digest
+	ref.tag = imageTag(name)
+	name = strings.TrimSuffix(name, ":"+ref.tag)
+	if ref.tag == "" && ref.digest == "" {
+		ref.tag = "latest"
+	}
+
+	ref.registry = "docker.io"
+	if first, rest, ok := strings.Cut(name, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.registry, name = first, rest
+	}
+	if ref.registry == "docker.io" || ref.registry == "index.docker.io" {
+		ref.registry = "docker.io"
+		if !strings.Contains(name, "/") {
+			name = "library/" + name
+		}
+	}
+	if name == "" || strings.ToLower(name) != name {
+		return ref, fmt.Errorf("invalid repository name in %q", image)
+	}
+	ref.repository = name
+	return ref, nil
+}
+
+// reference is the tag or digest to fetch the manifest by.
+func (r imageRef) reference() string {
+	if r.digest != "" {
+		return r.digest
+	}
+	return r.tag
+}
+
+// String returns the reference in full, as the runtime pulls it.
+func (r imageRef) String() string {
+	s := r.registry + "/" + r.repository
+	if r.tag != "" {
+		s += ":" + r.tag
+	}
+	if r.digest != "" {
+		s += "@" + r.digest
+	}
+	return s
+}
+
+// registryClient talks to one registry over the distribution API.
+type registryClient struct {
+	http     *http.Client
+	scheme   string // https, except for registries on localhost
+	host     string // the API host, registry-1.docker.io for Docker Hub
+	username string // from a pull secret; anonymous if empty
+	password string
+	token    string // bearer token, once the registry asked for one
+}
+
+// newRegistryClient returns a client for the registry of ref.
+func newRegistryClient(ref imageRef, username, password string) *registryClient {
+	c := &registryClient{
+		http:     &http.Client{Timeout: time.Minute},
+		scheme:   "https",
+		host:     ref.registry,
+		username: username,
+		password: password,
+	}
+	if c.host == "docker.io" {
+		c.host = "registry-1.docker.io"
+	}
+	if h := strings.Split(c.host, ":")[0]; h == "localhost" || h == "127.0.0.1" {
+		c.scheme = "http"
+	}
+	return c
+}
+
+// errNotFound is returned by get and head for a 404.
+var errNotFound = fmt.Errorf("not found")
+
+// get fetches a path under /v2/<repository>/.
+func (c *registryClient) get(ctx context.Context, repository, path, accept string) (*http.Response, []byte, error) {
+	return c.do(ctx, http.MethodGet, repository, path, accept)
+}
+
+// head is get without the body, for checking that a manifest exists
+// without counting against Docker Hub's pull rate limit.
+func (c *registryClient) head(ctx context.Context, repository, path, accept string) (*http.Response, error) {
+	resp, _, err := c.do(ctx, http.MethodHead, repository, path, accept)
+	return resp, err
+}
+
+// do sends a request for a path under /v2/<repository>/, authenticating as
+// the registry asks (bearer token or basic auth) and retrying once.
+func (c *registryClient) do(ctx context.Context, method, repository, path, accept string) (*http.Response, []byte, error) {
+	endpoint := fmt.Sprintf("%s://%s/v2/%s/%s", c.scheme, c.host, repository, path)
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		} else if c.username != "" && attempt > 0 {
+			req.SetBasicAuth(c.username, c.password)
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes))
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return resp, body, nil
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, nil, errNotFound
+		case resp.StatusCode == http.StatusUnauthorized && attempt == 0:
+			challenge := resp.Header.Get("WWW-Authenticate")
+			if scheme, _, _ := strings.Cut(challenge, " "); strings.EqualFold(scheme, "bearer") {
+				if c.token, err = c.fetchToken(ctx, challenge, repository); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			if c.username != "" {
+				continue
+			}
+		}
+		return nil, nil, fmt.Errorf("registry returned %s: %s", resp.Status, registryError(body))
+	}
+}
+
+// fetchToken gets a pull token from the realm of a bearer challenge.
+func (c *registryClient) fetchToken(ctx context.Context, challenge, repository string) (string, error) {
+	params := map[string]string{}
+	_, attrs, _ := strings.Cut(challenge, " ")
+	for _, attr := range strings.Split(attrs, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(attr), "=")
+		if ok {
+			params[key] = strings.Trim(value, `"`)
+		}
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("registry sent an invalid auth challenge %q", challenge)
+	}
+	q := realm.Query()
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + repository + ":pull"
+	}
+	q.Set("scope", scope)
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get a registry token: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get a registry token: %s: %s", resp.Status, registryError(body))
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("failed to parse the registry token: %v", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return token.Token, nil
+}
+
+// registryError extracts the message of a distribution API error.
+func registryError(body []byte) string {
+	var e struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &e) == nil && len(e.Errors) > 0 {
+		return strings.ToLower(e.Errors[0].Code) + ": " + e.Errors[0].Message
+	}
+	if len(body) > 200 {
+		body = body[:200]
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// imageManifest is the part of an image index or manifest inspect_image
+// reads.
+type imageManifest struct {
+	MediaType string `json:"mediaType"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+			Variant      string `json:"variant"`
+		} `json:"platform"`
+	} `json:"manifests"`
+	Config struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Layers []struct {
+		Size int64 `json:"size"`
+	} `json:"layers"`
+}
+
+// imageConfig is the part of an image config inspect_image reads.
+type imageConfig struct {
+	Created      time.Time `json:"created"`
+	OS           string    `json:"os"`
+	Architecture string    `json:"architecture"`
+	Config       struct {
+		User         string              `json:"User"`
+		ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+		Labels       map[string]string   `json:"Labels"`
+	} `json:"config"`
+}
+
+// inspectImage looks up ref in its registry: the digest the tag points
+// at, the platforms of a multi-platform image, and the creation date,
+// size, user, ports and labels of the image for platform.
+func inspectImage(ctx context.Context, c *registryClient, ref imageRef, platform string, now time.Time) (map[string]any, error) {
+	accept := strings.Join(manifestMediaTypes, ", ")
+	resp, body, err := c.get(ctx, ref.repository, "manifests/"+ref.reference(), accept)
+	if err == errNotFound {
+		return map[string]any{
+			"image":   ref.String(),
+			"exists":  false,
+			"message": fmt.Sprintf("%s does not exist in %s", ref.reference(), ref.registry+"/"+ref.repository),
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		digest = fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	}
+
+	var m imageManifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse the manifest: %v", err)
+	}
+	if m.MediaType == "" {
+		m.MediaType = resp.Header.Get("Content-Type")
+	}
+	result := map[string]any{
+		"image":      ref.String(),
+		"exists":     true,
+		"digest":     digest,
+		"pinned":     ref.registry + "/" + ref.repository + "@" + digest,
+		"media_type": m.MediaType,
+	}
+
+	if len(m.Manifests) > 0 {
+		var platforms []string
+		chosen := ""
+		for _, entry := range m.Manifests {
+			p := entry.Platform.OS + "/" + entry.Platform.Architecture
+			if entry.Platform.Variant != "" {
+				p += "/" + entry.Platform.Variant
+			}
+			if entry.Platform.OS == "unknown" || entry.Platform.OS == "" {
+				continue // attestations and signatures
+			}
+			platforms = append(platforms, p)
+			if chosen == "" && (p == platform || strings.HasPrefix(p, platform+"/")) {
+				chosen = entry.Digest
+			}
+		}
+		result["platforms"] = platforms
+		if chosen == "" {
+			result["platform_missing"] = fmt.Sprintf("the image has no %s variant; pods on such nodes will fail with exec format errors or fail to pull", platform)
+			return result, nil
+		}
+		if _, body, err = c.get(ctx, ref.repository, "manifests/"+chosen, accept); err != nil {
+			return nil, fmt.Errorf("failed to get the %s manifest: %v", platform, err)
+		}
+		m = imageManifest{}
+		if err := json.Unmarshal(body, &m); err != nil {
+			return nil, fmt.Errorf("failed to parse the %s manifest: %v", platform, err)
+		}
+	}
+
+	var size int64
+	for _, layer := range m.Layers {
+		size += layer.Size
+	}
+	result["layers"] = len(m.Layers)
+	result["compressed_size"] = formatBytes(size)
+	if m.Config.Digest == "" {
+		return result, nil
+	}
+	_, body, err = c.get(ctx, ref.repository, "blobs/"+m.Config.Digest, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the image config: %v", err)
+	}
+	var cfg imageConfig
+	if err := json.Unmarshal(body, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse the image config: %v", err)
+	}
+	result["platform"] = cfg.OS + "/" + cfg.Architecture
+	if !cfg.Created.IsZero() && cfg.Created.Year() > 1970 {
+		result["created"] = cfg.Created.UTC().Format(time.RFC3339)
+		result["age_days"] = int(now.Sub(cfg.Created).Hours() / 24)
+	}
+	if cfg.Config.User == "" || cfg.Config.User == "0" || cfg.Config.User == "root" {
+		result["user"] = "root"
+	} else {
+		result["user"] = cfg.Config.User
+	}
+	if len(cfg.Config.ExposedPorts) > 0 {
+		result["exposed_ports"] = slices.Sorted(maps.Keys(cfg.Config.ExposedPorts))
+	}
+	if len(cfg.Config.Labels) > 0 {
+		result["labels"] = cfg.Config.Labels
+	}
+	return result, nil
+}
+
+// formatBytes formats a size for humans, e.g. 42.3 MiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// pullSecretCredentials returns the username and password a
+// kubernetes.io/dockerconfigjson Secret holds for registry.
+func pullSecretCredentials(ctx context.Context, clientset kubernetes.Interface, namespace, name, registry string) (string, string, error) {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", "", err
+	}
+	data, ok := secret.Data[corev1.DockerConfigJsonKey]
+	if !ok {
+		return "", "", fmt.Errorf("secret %s is not a docker config (type %s)", name, secret.Type)
+	}
+	var config struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", "", fmt.Errorf("secret %s: %v", name, err)
+	}
+	for server, entry := range config.Auths {
+		host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://"), "/")
+		host, _, _ = strings.Cut(host, "/")
+		if host == "index.docker.io" || host == "registry-1.docker.io" {
+			host = "docker.io"
+		}
+		if host != registry {
+			continue
+		}
+		if entry.Username == "" && entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return "", "", fmt.Errorf("secret %s: invalid auth for %s", name, server)
+			}
+			entry.Username, entry.Password, _ = strings.Cut(string(decoded), ":")
+		}
+		return entry.Username, entry.Password, nil
+	}
+	return "", "", fmt.Errorf("secret %s has no credentials for %s", name, registry)
+}
+
+// trivyReport is the part of Trivy's JSON report inspect_image reads.
+type trivyReport struct {
+	Results []struct {
+		Target          string `json:"Target"`
+		Vulnerabilities []struct {
+			VulnerabilityID  string `json:"VulnerabilityID"`
+			PkgName          string `json:"PkgName"`
+			InstalledVersion string `json:"InstalledVersion"`
+			FixedVersion     string `json:"FixedVersion"`
+			Severity         string `json:"Severity"`
+			Title            string `json:"Title"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// scanImage runs trivy on image, against server if set, and summarizes the
+// vulnerabilities: counts by severity and the most severe ones.
+func scanImage(ctx context.Context, binary, server, image, username, password string) (map[string]any, error) {
+	args := []string{"image", "--quiet", "--format", "json", "--scanners", "vuln"}
+	if server != "" {
+		args = append(args, "--server", server)
+	}
+	cmd := exec.CommandContext(ctx, binary, append(args, image)...)
+	cmd.Env = os.Environ()
+	if username != "" {
+		cmd.Env = append(cmd.Env, "TRIVY_USERNAME="+username, "TRIVY_PASSWORD="+password)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 500 {
+			msg = msg[len(msg)-500:]
+		}
+		return nil, fmt.Errorf("trivy failed: %v: %s", err, msg)
+	}
+	var report trivyReport
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse the trivy report: %v", err)
+	}
+	return summarizeTrivy(report), nil
+}
+
+// summarizeTrivy counts a report's vulnerabilities by severity and lists
+// the most severe, each once.
+func summarizeTrivy(report trivyReport) map[string]any {
+	counts := map[string]int{}
+	fixable := 0
+	seen := map[string]bool{}
+	var vulns []map[string]any
+	for _, r := range report.Results {
+		for _, v := range r.Vulnerabilities {
+			key := v.VulnerabilityID + " " + v.PkgName
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			counts[v.Severity]++
+			if v.FixedVersion != "" {
+				fixable++
+			}
+			vuln := map[string]any{
+				"id":        v.VulnerabilityID,
+				"severity":  v.Severity,
+				"package":   v.PkgName,
+				"installed": v.InstalledVersion,
+			}
+			if v.FixedVersion != "" {
+				vuln["fixed_in"] = v.FixedVersion
+			}
+			if v.Title != "" {
+				vuln["title"] = v.Title
+			}
+			vulns = append(vulns, vuln)
+		}
+	}
+	rank := func(severity any) int {
+		if i := slices.Index(severityOrder, severity.(string)); i >= 0 {
+			return i
+		}
+		return len(severityOrder)
+	}
+	slices.SortStableFunc(vulns, func(a, b map[string]any) int {
+		return rank(a["severity"]) - rank(b["severity"])
+	})
+
+	result := map[string]any{
+		"total":    len(vulns),
+		"fixable":  fixable,
+		"severity": counts,
+	}
+	if len(vulns) > maxImageCVEs {
+		result["truncated"] = true
+		vulns = vulns[:maxImageCVEs]
+	}
+	if len(vulns) > 0 {
+		result["vulnerabilities"] = vulns
+	}
+	return result
+}
+
+// InspectImageTool provides the inspect_image tool for the agent.
+type InspectImageTool struct {
+	clientset   *kubernetes.Clientset
+	trivyBinary string // "" disables scans
+	trivyServer string
+}
+
+// NewInspectImageTool creates a new InspectImageTool. Vulnerability scans
+// need a trivy binary, which uses the Trivy server if one is given.
+func NewInspectImageTool(clientset *kubernetes.Clientset, trivyBinary, trivyServer string) *InspectImageTool {
+	return &InspectImageTool{
+		clientset:   clientset,
+		trivyBinary: trivyBinary,
+		trivyServer: trivyServer,
+	}
+}
+
+// Name returns the tool name.
+func (t *InspectImageTool) Name() string {
+	return "inspect_image"
+}
+
+// Description returns the tool description.
+func (t *InspectImageTool) Description() string {
+	return "Look up a container image in its registry without pulling it: whether the tag exists, the digest it points at (to pin deployments), " +
+		"its platforms, creation date, size, user and labels. With scan=true, also lists known CVEs by severity using Trivy " +
+		"(requires integrations.trivy; scans can take a minute or more, so pass a larger timeout_seconds). " +
+		"Use it before deploying or changing an image, and tell the user about a missing tag, a missing platform, an old image or critical CVEs."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *InspectImageTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *InspectImageTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *InspectImageTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *InspectImageTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"image": {
+					Type:        "string",
+					Description: "The image reference, e.g. nginx:1.25, ghcr.io/org/app:v2 or registry.example.com/app@sha256:...",
+				},
+				"platform": {
+					Type:        "string",
+					Description: "The platform to inspect in a multi-platform image (default linux/amd64)",
+				},
+				"secret_namespace": {
+					Type:        "string",
+					Description: "The namespace of pull_secret",
+				},
+				"pull_secret": {
+					Type:        "string",
+					Description: "An image pull Secret (kubernetes.io/dockerconfigjson) with credentials for a private registry",
+				},
+				"scan": {
+					Type:        "boolean",
+					Description: "Also scan the image for known vulnerabilities with Trivy. Default is false.",
+				},
+			},
+			Required: []string{"image"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *InspectImageTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	image, _ := argsMap["image"].(string)
+	ref, err := parseImageRef(image)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+	platform, _ := argsMap["platform"].(string)
+	if platform == "" {
+		platform = "linux/amd64"
+	}
+	scan, _ := argsMap["scan"].(bool)
+	if scan && t.trivyBinary == "" {
+		return map[string]any{"error": "vulnerability scans are not configured (set integrations.trivy in config.yaml); call again without scan"}, nil
+	}
+
+	goCtx := toolContext(ctx)
+	var username, password string
+	if secret, _ := argsMap["pull_secret"].(string); secret != "" {
+		namespace, _ := argsMap["secret_namespace"].(string)
+		if namespace == "" {
+			return map[string]any{"error": "secret_namespace is required with pull_secret"}, nil
+		}
+		apiCtx, cancel := apiContext(goCtx)
+		username, password, err = pullSecretCredentials(apiCtx, t.clientset, namespace, secret, ref.registry)
+		cancel()
+		if err != nil {
+			return map[string]any{"error": fmt.Sprintf("failed to read pull secret: %v", err)}, nil
+		}
+	}
+
+	result, err := inspectImage(goCtx, newRegistryClient(ref, username, password), ref, platform, time.Now())
+	if err != nil {
+		hint := ""
+		if strings.Contains(err.Error(), "401") || strings.Contains(err.Error(), "unauthorized") || strings.Contains(err.Error(), "denied") {
+			hint = "; the image may not exist, or the registry is private and needs a pull_secret"
+		}
+		return map[string]any{"error": fmt.Sprintf("failed to inspect %s: %v%s", ref, err, hint)}, nil
+	}
+	if scan && result["exists"] == true {
+		pinned, _ := result["pinned"].(string)
+		vulns, err := scanImage(goCtx, t.trivyBinary, t.trivyServer, pinned, username, password)
+		if err != nil {
+			result["scan_error"] = err.Error()
+		} else {
+			result["vulnerabilities"] = vulns
+		}
+	}
+	return result, nil
+}
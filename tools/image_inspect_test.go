@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseImageRef(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+		repo  string
+	}{
+		{"nginx", "docker.io/library/nginx:latest", "library/nginx"},
+		{"nginx:1.25", "docker.io/library/nginx:1.25", "library/nginx"},
+		{"bitnami/redis:7.2", "docker.io/bitnami/redis:7.2", "bitnami/redis"},
+		{"ghcr.io/org/app:v2", "ghcr.io/org/app:v2", "org/app"},
+		{"localhost:5000/app", "localhost:5000/app:latest", "app"},
+		{"registry.example.com/team/app@sha256:abc", "registry.example.com/team/app@sha256:abc", "team/app"},
+		{"quay.io/app:1.0@sha256:abc", "quay.io/app:1.0@sha256:abc", "app"},
+	}
+	for _, tt := range tests {
+		ref, err := parseImageRef(tt.image)
+		if err != nil {
+			t.Errorf("parseImageRef(%q): %v", tt.image, err)
+			continue
+		}
+		if ref.String() != tt.want || ref.repository != tt.repo {
+			t.Errorf("parseImageRef(%q) = %s (repository %s), want %s (%s)", tt.image, ref, ref.repository, tt.want, tt.repo)
+		}
+	}
+	for _, image := range []string{"", "Nginx:1.25", "nginx 1.25"} {
+		if _, err := parseImageRef(image); err == nil {
+			t.Errorf("expected an error for %q", image)
+		}
+	}
+}
+
+// newTestRegistry serves an image index with an amd64 and an arm64
+// manifest, and an attestation, behind a bearer token.
+func newTestRegistry(t *testing.T) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:team/app:pull" {
+				http.Error(w, "bad scope", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"token":"secret-token"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errors":[{"code":"UNAUTHORIZED","message":"authentication required"}]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/team/app/manifests/1.25":
+			if !strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+				t.Errorf("expected index media types to be accepted, got %q", r.Header.Get("Accept"))
+			}
+			w.Header().Set("Docker-Content-Digest", "sha256:index")
+			w.Write([]byte(`{"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[
+				{"digest":"sha256:amd64","platform":{"os":"linux","architecture":"amd64"}},
+				{"digest":"sha256:arm64","platform":{"os":"linux","architecture":"arm64","variant":"v8"}},
+				{"digest":"sha256:att","platform":{"os":"unknown","architecture":"unknown"}}]}`))
+		case "/v2/team/app/manifests/sha256:arm64":
+			w.Write([]byte(`{"mediaType":"application/vnd.oci.image.manifest.v1+json","config":{"digest":"sha256:config"},
+				"layers":[{"size":3145728},{"size":1048576}]}`))
+		case "/v2/team/app/blobs/sha256:config":
+			w.Write([]byte(`{"created":"2025-06-01T00:00:00Z","os":"linux","architecture":"arm64",
+				"config":{"User":"101","ExposedPorts":{"80/tcp":{}},"Labels":{"org.opencontainers.image.version":"1.25.5"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestInspectImage(t *testing.T) {
+	server := newTestRegistry(t)
+	host := strings.TrimPrefix(server.URL, "http://")
+	now := time.Date(2025, 6, 11, 0, 0, 0, 0, time.UTC)
+
+	ref, err := parseImageRef(host + "/team/app:1.25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := inspectImage(context.Background(), newRegistryClient(ref, "", ""), ref, "linux/arm64", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	platforms, _ := result["platforms"].([]string)
+	if result["exists"] != true || result["digest"] != "sha256:index" || result["pinned"] != host+"/team/app@sha256:index" {
+		t.Errorf("unexpected result: %v", result)
+	}
+	if len(platforms) != 2 || platforms[1] != "linux/arm64/v8" {
+		t.Errorf("expected the attestation to be left out, got %v", platforms)
+	}
+	if result["platform"] != "linux/arm64" || result["age_days"] != 10 || result["compressed_size"] != "4.0 MiB" || result["user"] != "101" {
+		t.Errorf("unexpected image details: %v", result)
+	}
+
+	result, err = inspectImage(context.Background(), newRegistryClient(ref, "", ""), ref, "linux/s390x", now)
+	if err != nil || result["platform_missing"] == nil || result["created"] != nil {
+		t.Errorf("expected a missing platform, got %v, %v", result, err)
+	}
+
+	missing, _ := parseImageRef(host + "/team/app:9.9")
+	result, err = inspectImage(context.Background(), newRegistryClient(missing, "", ""), missing, "linux/amd64", now)
+	if err != nil || result["exists"] != false {
+		t.Errorf("expected a missing tag, got %v, %v", result, err)
+	}
+}
+
+func TestPullSecretCredentials(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("robot:s3cret"))
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "regcred", Namespace: "shop"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{
+			"https://index.docker.io/v1/":{"username":"hub","password":"hubpass"},
+			"registry.example.com":{"auth":"` + auth + `"}}}`)},
+	})
+
+	user, pass, err := pullSecretCredentials(context.Background(), clientset, "shop", "regcred", "registry.example.com")
+	if err != nil || user != "robot" || pass != "s3cret" {
+		t.Errorf("got %q %q %v, want robot s3cret", user, pass, err)
+	}
+	user, _, err = pullSecretCredentials(context.Background(), clientset, "shop", "regcred", "docker.io")
+	if err != nil || user != "hub" {
+		t.Errorf("expected the Docker Hub credentials, got %q %v", user, err)
+	}
+	if _, _, err := pullSecretCredentials(context.Background(), clientset, "shop", "regcred", "ghcr.io"); err == nil {
+		t.Error("expected an error for a registry without credentials")
+	}
+}
+
+func TestSummarizeTrivy(t *testing.T) {
+	var report trivyReport
+	if err := json.Unmarshal([]byte(`{"Results":[
+		{"Target":"alpine 3.19","Vulnerabilities":[
+			{"VulnerabilityID":"CVE-1","PkgName":"musl","Severity":"LOW"},
+			{"VulnerabilityID":"CVE-2","PkgName":"openssl","Severity":"CRITICAL","FixedVersion":"3.1.5"},
+			{"VulnerabilityID":"CVE-3","PkgName":"busybox","Severity":"MEDIUM"}]},
+		{"Target":"app","Vulnerabilities":[
+			{"VulnerabilityID":"CVE-4","PkgName":"stdlib","Severity":"CRITICAL"},
+			{"VulnerabilityID":"CVE-5","PkgName":"x/net","Severity":"HIGH"},
+			{"VulnerabilityID":"CVE-2","PkgName":"openssl","Severity":"CRITICAL","FixedVersion":"3.1.5"}]}]}`), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	summary := summarizeTrivy(report)
+	counts := summary["severity"].(map[string]int)
+	if summary["total"] != 5 || summary["fixable"] != 1 || counts["CRITICAL"] != 2 {
+		t.Errorf("unexpected summary: %v", summary)
+	}
+	list := summary["vulnerabilities"].([]map[string]any)
+	if list[0]["id"] != "CVE-2" || list[1]["id"] != "CVE-4" || list[2]["severity"] != "HIGH" || list[4]["severity"] != "LOW" {
+		t.Errorf("expected the most severe first, got %v", list)
+	}
+}
@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// ImageDigestsAnnotation records the tag each pinned container image was
+// deployed from and the digest it resolved to, as a JSON object such as
+// {"nginx:1.27":"sha256:..."}. It goes on the workload, in the stored
+// manifest and the live object alike, so diff_resource can tell when a
+// tag has been re-pushed since.
+const ImageDigestsAnnotation = "kasa.io/image-digests"
+
+// pinDigestSchema is the pin_digest parameter of create_deployment and
+// set_image.
+var pinDigestSchema = &genai.Schema{
+	Type:        "boolean",
+	Description: "Deploy the image by the digest its tag resolves to now (image:tag@sha256:...), so later pushes to the tag don't change what runs and rollbacks are exact (default: deployments.pin_digests in the config)",
+}
+
+// pinDigestArg returns the pin_digest argument, or def if it isn't set.
+func pinDigestArg(args map[string]any, def bool) bool {
+	if pin, ok := args["pin_digest"].(bool); ok {
+		return pin
+	}
+	return def
+}
+
+// pinImage returns the image of a create_deployment or set_image call
+// pinned to the digest its tag resolves to now, using the pull secrets the
+// pods would use. Images that already have a digest are returned as is.
+func (c *imageChecker) pinImage(ctx context.Context, toolName string, params map[string]any) (string, error) {
+	image, _ := params["image"].(string)
+	ref, err := parseImageRef(image)
+	if err != nil {
+		return "", err
+	}
+	if ref.digest != "" {
+		return image, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, imageCheckTimeout)
+	defer cancel()
+	digest, err := resolveDigest(ctx, c.client(ctx, params, c.podSpec(ctx, toolName, params), ref), ref)
+	if err != nil {
+		return "", err
+	}
+	// Keep the tag for readers; the runtime pulls by the digest
+	if !imageHasTag(image) {
+		image += ":" + ref.tag
+	}
+	return image + "@" + digest, nil
+}
+
+// resolveDigest returns the digest the registry serves for ref now.
+func resolveDigest(ctx context.Context, client *registryClient, ref imageRef) (string, error) {
+	resp, err := client.head(ctx, ref.repository, "manifests/"+ref.reference(), strings.Join(manifestMediaTypes, ", "))
+	if errors.Is(err, errNotFound) {
+		return "", fmt.Errorf("image %s was not found in %s", ref, ref.registry)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %v", ref, err)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("%s did not return a digest for %s", ref.registry, ref)
+	}
+	return digest, nil
+}
+
+// imageHasTag reports whether image names a tag, rather than relying on
+// the implicit latest.
+func imageHasTag(image string) bool {
+	image, _, _ = strings.Cut(image, "@")
+	return strings.Contains(image[strings.LastIndex(image, "/")+1:], ":")
+}
+
+// imageDigests maps the tag of each pinned image (image:tag@digest) to its
+// digest.
+func imageDigests(images []string) map[string]string {
+	digests := map[string]string{}
+	for _, image := range images {
+		tagged, digest, ok := strings.Cut(image, "@")
+		if ok && imageHasTag(tagged) {
+			digests[tagged] = digest
+		}
+	}
+	return digests
+}
+
+// setImageDigests sets the ImageDigestsAnnotation of a workload to the
+// pinned images of its pod spec, or removes it if none are pinned.
+func setImageDigests(meta *metav1.ObjectMeta, spec *corev1.PodSpec) {
+	var images []string
+	for _, c := range slices.Concat(spec.InitContainers, spec.Containers) {
+		images = append(images, c.Image)
+	}
+	digests := imageDigests(images)
+	if len(digests) == 0 {
+		delete(meta.Annotations, ImageDigestsAnnotation)
+		return
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	value, _ := json.Marshal(digests)
+	meta.Annotations[ImageDigestsAnnotation] = string(value)
+}
+
+// setManifestImageDigests is setImageDigests for a stored deployment or
+// statefulset manifest.
+func setManifestImageDigests(obj map[string]any) {
+	spec, _ := obj["spec"].(map[string]any)
+	template, _ := spec["template"].(map[string]any)
+	podSpec, _ := template["spec"].(map[string]any)
+	var images []string
+	for _, key := range []string{"initContainers", "containers"} {
+		containers, _ := podSpec[key].([]any)
+		for _, c := range containers {
+			cm, _ := c.(map[string]any)
+			if image, ok := cm["image"].(string); ok {
+				images = append(images, image)
+			}
+		}
+	}
+
+	metadata, _ := obj["metadata"].(map[string]any)
+	annotations, _ := metadata["annotations"].(map[string]any)
+	digests := imageDigests(images)
+	if len(digests) == 0 {
+		if annotations != nil {
+			delete(annotations, ImageDigestsAnnotation)
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+		return
+	}
+	if metadata == nil {
+		metadata = map[string]any{}
+		obj["metadata"] = metadata
+	}
+	if annotations == nil {
+		annotations = map[string]any{}
+		metadata["annotations"] = annotations
+	}
+	value, _ := json.Marshal(digests)
+	annotations[ImageDigestsAnnotation] = string(value)
+}
+
+// repushedTags returns the tags recorded in the ImageDigestsAnnotation of
+// a stored workload manifest that resolve to a different digest now, that
+// is, tags that were pushed again since the workload was pinned. Tags that
+// can't be resolved are left out.
+func (c *imageChecker) repushedTags(ctx context.Context, namespace string, storedYAML []byte) []map[string]any {
+	var workload struct {
+		Metadata metav1.ObjectMeta `json:"metadata"`
+		Spec     struct {
+			Template corev1.PodTemplateSpec `json:"template"`
+		} `json:"spec"`
+	}
+	if err := yaml.Unmarshal(storedYAML, &workload); err != nil {
+		return nil
+	}
+	var pinned map[string]string
+	if err := json.Unmarshal([]byte(workload.Metadata.Annotations[ImageDigestsAnnotation]), &pinned); err != nil {
+		return nil
+	}
+
+	var repushed []map[string]any
+	for _, tag := range slices.Sorted(maps.Keys(pinned)) {
+		ref, err := parseImageRef(tag)
+		if err != nil {
+			continue
+		}
+		lookupCtx, cancel := context.WithTimeout(ctx, imageCheckTimeout)
+		username, password := c.credentials(lookupCtx, namespace, &workload.Spec.Template.Spec, ref.registry)
+		digest, err := resolveDigest(lookupCtx, newRegistryClient(ref, username, password), ref)
+		cancel()
+		if err == nil && digest != pinned[tag] {
+			repushed = append(repushed, map[string]any{
+				"image":          tag,
+				"pinned_digest":  pinned[tag],
+				"current_digest": digest,
+			})
+		}
+	}
+	return repushed
+}
@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPinImage(t *testing.T) {
+	server := newTestRegistry(t)
+	host := strings.TrimPrefix(server.URL, "http://")
+	checker := newImageChecker(nil)
+
+	pinned, err := checker.pinImage(context.Background(), "set_image", map[string]any{"namespace": "shop", "image": host + "/team/app:1.25"})
+	if err != nil || pinned != host+"/team/app:1.25@sha256:index" {
+		t.Errorf("got %q, %v; want the tag pinned to sha256:index", pinned, err)
+	}
+	pinned, err = checker.pinImage(context.Background(), "set_image", map[string]any{"image": host + "/team/app@sha256:other"})
+	if err != nil || pinned != host+"/team/app@sha256:other" {
+		t.Errorf("expected a digest to be kept as is, got %q, %v", pinned, err)
+	}
+	if _, err := checker.pinImage(context.Background(), "set_image", map[string]any{"image": host + "/team/app:1.26"}); err == nil || !strings.Contains(err.Error(), "was not found") {
+		t.Errorf("expected a missing tag error, got %v", err)
+	}
+}
+
+func TestImageDigests(t *testing.T) {
+	digests := imageDigests([]string{
+		"nginx:1.27@sha256:aaa",
+		"localhost:5000/app@sha256:bbb",
+		"envoy:v1.31",
+	})
+	if len(digests) != 1 || digests["nginx:1.27"] != "sha256:aaa" {
+		t.Errorf("expected only the tagged, pinned image, got %v", digests)
+	}
+	if !imageHasTag("localhost:5000/team/app:2") || imageHasTag("localhost:5000/team/app") {
+		t.Error("expected the registry port not to count as a tag")
+	}
+}
+
+func TestSetImageDigests(t *testing.T) {
+	meta := metav1.ObjectMeta{Annotations: map[string]string{"owner": "shop"}}
+	spec := corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "migrate", Image: "migrate:3@sha256:ccc"}},
+		Containers:     []corev1.Container{{Name: "web", Image: "nginx:1.27@sha256:aaa"}},
+	}
+	setImageDigests(&meta, &spec)
+	if got := meta.Annotations[ImageDigestsAnnotation]; got != `{"migrate:3":"sha256:ccc","nginx:1.27":"sha256:aaa"}` {
+		t.Errorf("unexpected annotation %q", got)
+	}
+	spec.InitContainers = nil
+	spec.Containers[0].Image = "nginx:1.27"
+	setImageDigests(&meta, &spec)
+	if _, ok := meta.Annotations[ImageDigestsAnnotation]; ok || meta.Annotations["owner"] != "shop" {
+		t.Errorf("expected only the digest annotation to be removed, got %v", meta.Annotations)
+	}
+
+	obj := deploymentObject(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.27@sha256:aaa
+`)
+	setManifestImageDigests(obj)
+	annotations, _ := obj["metadata"].(map[string]any)["annotations"].(map[string]any)
+	if annotations[ImageDigestsAnnotation] != `{"nginx:1.27":"sha256:aaa"}` {
+		t.Errorf("unexpected manifest annotations %v", annotations)
+	}
+	if _, err := setManifestImage(obj, "web", "nginx:1.28"); err != nil {
+		t.Fatal(err)
+	}
+	setManifestImageDigests(obj)
+	if _, ok := obj["metadata"].(map[string]any)["annotations"]; ok {
+		t.Errorf("expected the annotations to be removed, got %v", obj["metadata"])
+	}
+}
+
+func TestRepushedTags(t *testing.T) {
+	server := newTestRegistry(t)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	stored := []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  annotations:
+    kasa.io/image-digests: '{"` + host + `/team/app:1.25":"sha256:old","` + host + `/team/app:1.26":"sha256:gone"}'
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: ` + host + `/team/app:1.25@sha256:old
+`)
+	repushed := newImageChecker(nil).repushedTags(context.Background(), "shop", stored)
+	if len(repushed) != 1 || repushed[0]["image"] != host+"/team/app:1.25" || repushed[0]["current_digest"] != "sha256:index" {
+		t.Errorf("expected the re-pushed 1.25 tag, got %v", repushed)
+	}
+	if repushed := newImageChecker(nil).repushedTags(context.Background(), "shop", []byte(testPolicyDeployment)); repushed != nil {
+		t.Errorf("expected nothing for an unpinned manifest, got %v", repushed)
+	}
+}
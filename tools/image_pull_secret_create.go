@@ -0,0 +1,247 @@
+package tools
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// dockerHubServer is the key Docker Hub credentials are stored under in a
+// docker config.
+const dockerHubServer = "https://index.docker.io/v1/"
+
+// CreateImagePullSecretTool provides the create_image_pull_secret tool for the agent.
+type CreateImagePullSecretTool struct {
+	clientset *kubernetes.Clientset
+	manifest  *manifest.Manager
+}
+
+// NewCreateImagePullSecretTool creates a new CreateImagePullSecretTool.
+func NewCreateImagePullSecretTool(clientset *kubernetes.Clientset, manifest *manifest.Manager) *CreateImagePullSecretTool {
+	return &CreateImagePullSecretTool{
+		clientset: clientset,
+		manifest:  manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *CreateImagePullSecretTool) Name() string {
+	return "create_image_pull_secret"
+}
+
+// Description returns the tool description.
+func (t *CreateImagePullSecretTool) Description() string {
+	return "Create or update a kubernetes.io/dockerconfigjson Secret for a private container registry from a registry, username and password or token, like 'kubectl create secret docker-registry'. Optionally adds it to a ServiceAccount's imagePullSecrets so every pod using that account can pull. Saves the manifest to git and applies it to the cluster."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *CreateImagePullSecretTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *CreateImagePullSecretTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *CreateImagePullSecretTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *CreateImagePullSecretTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"name": {
+					Type:        "string",
+					Description: "The name of the Secret",
+				},
+				"namespace": {
+					Type:        "string",
+					Description: "The target Kubernetes namespace",
+				},
+				"registry": {
+					Type:        "string",
+					Description: "Registry host (e.g., ghcr.io, registry.gitlab.com, docker.io)",
+				},
+				"username": {
+					Type:        "string",
+					Description: "Registry username",
+				},
+				"password": {
+					Type:        "string",
+					Description: "Registry password or access token",
+				},
+				"email": {
+					Type:        "string",
+					Description: "Email address (optional, some registries require it)",
+				},
+				"service_account": {
+					Type:        "string",
+					Description: "ServiceAccount to add the secret to as an imagePullSecret (optional, e.g. default)",
+				},
+			},
+			Required: []string{"name", "namespace", "registry", "username", "password"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *CreateImagePullSecretTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+	registry, ok := argsMap["registry"].(string)
+	if !ok || registry == "" {
+		return map[string]any{"error": "registry is required"}, nil
+	}
+	username, ok := argsMap["username"].(string)
+	if !ok || username == "" {
+		return map[string]any{"error": "username is required"}, nil
+	}
+	password, ok := argsMap["password"].(string)
+	if !ok || password == "" {
+		return map[string]any{"error": "password is required"}, nil
+	}
+	email, _ := argsMap["email"].(string)
+	serviceAccount, _ := argsMap["service_account"].(string)
+
+	config, err := dockerConfigJSON(registry, username, password, email)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to build docker config: %v", err)}, nil
+	}
+
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       name,
+				"app.kubernetes.io/managed-by": "kasa",
+			},
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{corev1.DockerConfigJsonKey: config},
+	}
+
+	yamlBytes, err := yaml.Marshal(secret)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to marshal secret: %v", err)}, nil
+	}
+	manifestPath, err := t.manifest.SaveManifest(namespace, name, "secret", yamlBytes)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to save manifest: %v", err)}, nil
+	}
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
+	defer cancel()
+
+	var action string
+	existing, err := t.clientset.CoreV1().Secrets(namespace).Get(timeoutCtx, name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return map[string]any{"error": fmt.Sprintf("failed to check existing secret: %v", err)}, nil
+		}
+		if _, err := t.clientset.CoreV1().Secrets(namespace).Create(timeoutCtx, secret, metav1.CreateOptions{}); err != nil {
+			return map[string]any{"error": fmt.Sprintf("failed to create secret: %v", err)}, nil
+		}
+		action = "created"
+	} else {
+		if existing.Type != corev1.SecretTypeDockerConfigJson {
+			return map[string]any{"error": fmt.Sprintf("secret %s already exists with type %s; the type of a Secret cannot be changed", name, existing.Type)}, nil
+		}
+		secret.ResourceVersion = existing.ResourceVersion
+		if _, err := t.clientset.CoreV1().Secrets(namespace).Update(timeoutCtx, secret, metav1.UpdateOptions{}); err != nil {
+			return map[string]any{"error": fmt.Sprintf("failed to update secret: %v", err)}, nil
+		}
+		action = "updated"
+	}
+
+	result := map[string]any{
+		"success":       true,
+		"action":        action,
+		"name":          name,
+		"namespace":     namespace,
+		"registry":      registry,
+		"manifest_path": manifestPath,
+		"message":       fmt.Sprintf("Image pull secret %s %s in namespace %s", name, action, namespace),
+		"warning":       "The registry credentials are stored in plaintext in the manifest file. Ensure the repository is properly secured.",
+	}
+
+	if serviceAccount != "" {
+		sa, err := t.clientset.CoreV1().ServiceAccounts(namespace).Get(timeoutCtx, serviceAccount, metav1.GetOptions{})
+		if err != nil {
+			result["service_account_error"] = fmt.Sprintf("secret %s but not added to serviceaccount %s: %v", action, serviceAccount, err)
+			return result, nil
+		}
+		if addImagePullSecret(sa, name) {
+			if _, err := t.clientset.CoreV1().ServiceAccounts(namespace).Update(timeoutCtx, sa, metav1.UpdateOptions{}); err != nil {
+				result["service_account_error"] = fmt.Sprintf("secret %s but not added to serviceaccount %s: %v", action, serviceAccount, err)
+				return result, nil
+			}
+		}
+		result["service_account"] = serviceAccount
+		result["message"] = fmt.Sprintf("%s and added to serviceaccount %s; new pods using it can pull from %s", result["message"], serviceAccount, registry)
+	}
+	return result, nil
+}
+
+// dockerConfigJSON builds the .dockerconfigjson content for one registry.
+func dockerConfigJSON(registry, username, password, email string) ([]byte, error) {
+	server := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://"), "/")
+	switch server {
+	case "docker.io", "index.docker.io", "registry-1.docker.io", "index.docker.io/v1":
+		server = dockerHubServer
+	}
+	if server == "" {
+		return nil, fmt.Errorf("registry is empty")
+	}
+
+	entry := map[string]string{
+		"username": username,
+		"password": password,
+		"auth":     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+	}
+	if email != "" {
+		entry["email"] = email
+	}
+	return json.Marshal(map[string]any{
+		"auths": map[string]any{server: entry},
+	})
+}
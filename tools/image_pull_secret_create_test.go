@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func TestDockerConfigJSON(t *testing.T) {
+	tests := []struct {
+		registry, server string
+	}{
+		{"ghcr.io", "ghcr.io"},
+		{"https://registry.gitlab.com/", "registry.gitlab.com"},
+		{"docker.io", dockerHubServer},
+	}
+	for _, tt := range tests {
+		data, err := dockerConfigJSON(tt.registry, "bot", "s3cret", "")
+		if err != nil {
+			t.Fatalf("dockerConfigJSON(%q) failed: %v", tt.registry, err)
+		}
+		var config struct {
+			Auths map[string]map[string]string `json:"auths"`
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			t.Fatal(err)
+		}
+		entry, ok := config.Auths[tt.server]
+		if !ok {
+			t.Errorf("dockerConfigJSON(%q) has no entry for %q: %s", tt.registry, tt.server, data)
+			continue
+		}
+		if want := base64.StdEncoding.EncodeToString([]byte("bot:s3cret")); entry["auth"] != want {
+			t.Errorf("auth = %q, want %q", entry["auth"], want)
+		}
+		if _, ok := entry["email"]; ok {
+			t.Error("email should be omitted when empty")
+		}
+	}
+}
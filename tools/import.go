@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/perbu/kasa/manifest"
 	"google.golang.org/adk/model"
@@ -21,14 +20,16 @@ import (
 type ImportResourceTool struct {
 	clientset     *kubernetes.Clientset
 	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
 	manifest      *manifest.Manager
 }
 
 // NewImportResourceTool creates a new ImportResourceTool.
-func NewImportResourceTool(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, manifest *manifest.Manager) *ImportResourceTool {
+func NewImportResourceTool(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, resolver *GVRResolver, manifest *manifest.Manager) *ImportResourceTool {
 	return &ImportResourceTool{
 		clientset:     clientset,
 		dynamicClient: dynamicClient,
+		resolver:      resolver,
 		manifest:      manifest,
 	}
 }
@@ -68,7 +69,7 @@ func (t *ImportResourceTool) Declaration() *genai.FunctionDeclaration {
 			Properties: map[string]*genai.Schema{
 				"namespace": {
 					Type:        "string",
-					Description: "The Kubernetes namespace of the resource",
+					Description: "The Kubernetes namespace of the resource. Omit for cluster-scoped resources (ClusterRole, GatewayClass, ClusterIssuer, ...), which are stored under the _cluster pseudo-namespace",
 				},
 				"name": {
 					Type:        "string",
@@ -87,7 +88,7 @@ func (t *ImportResourceTool) Declaration() *genai.FunctionDeclaration {
 					Description: "If true, overwrite an existing manifest. Default is false.",
 				},
 			},
-			Required: []string{"namespace", "name", "kind"},
+			Required: []string{"name", "kind"},
 		},
 	}
 }
@@ -106,10 +107,7 @@ func (t *ImportResourceTool) Run(ctx tool.Context, args any) (map[string]any, er
 		}
 	}
 
-	namespace, ok := argsMap["namespace"].(string)
-	if !ok || namespace == "" {
-		return map[string]any{"error": "namespace is required"}, nil
-	}
+	namespace, _ := argsMap["namespace"].(string)
 
 	name, ok := argsMap["name"].(string)
 	if !ok || name == "" {
@@ -135,17 +133,24 @@ func (t *ImportResourceTool) Run(ctx tool.Context, args any) (map[string]any, er
 	resourceType := normalizeKind(kind)
 	useDynamic := false
 
+	namespaced := true
+
 	if resourceType == "" {
-		// Check if it's a known CRD kind from our GVR table
-		normalized := NormalizeKindName(kind)
-		if _, found := LookupGVR(normalized); found || apiVersion != "" {
-			resourceType = normalized
-			useDynamic = true
-		} else {
-			return map[string]any{
-				"error": fmt.Sprintf("unsupported resource kind: %s. Provide api_version for custom resources.", kind),
-			}, nil
+		// Check that the cluster serves this kind
+		gvr, err := t.resolver.Resolve(kind, apiVersion)
+		if err != nil {
+			return map[string]any{"error": err.Error()}, nil
 		}
+		resourceType = NormalizeKindName(kind)
+		useDynamic = true
+		namespaced = t.resolver.IsNamespaced(gvr, kind)
+	}
+
+	// Cluster-scoped resources are stored under the _cluster pseudo-namespace
+	if !namespaced {
+		namespace = manifest.ClusterNamespace
+	} else if namespace == "" || namespace == manifest.ClusterNamespace {
+		return map[string]any{"error": fmt.Sprintf("namespace is required: %s is namespaced", kind)}, nil
 	}
 
 	// Check if manifest already exists
@@ -158,7 +163,7 @@ func (t *ImportResourceTool) Run(ctx tool.Context, args any) (map[string]any, er
 	}
 
 	// Fetch resource from cluster
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
 	defer cancel()
 
 	var resourceMap map[string]any
@@ -173,19 +178,9 @@ func (t *ImportResourceTool) Run(ctx tool.Context, args any) (map[string]any, er
 		return map[string]any{"error": err.Error()}, nil
 	}
 
-	// Clean runtime fields
-	cleanForImport(resourceMap)
-
-	// Marshal to YAML
-	yamlBytes, err := yaml.Marshal(resourceMap)
+	manifestPath, err := saveImported(t.manifest, namespace, name, resourceType, resourceMap)
 	if err != nil {
-		return map[string]any{"error": fmt.Sprintf("failed to marshal resource: %v", err)}, nil
-	}
-
-	// Save manifest
-	manifestPath, err := t.manifest.SaveManifest(namespace, name, resourceType, yamlBytes)
-	if err != nil {
-		return map[string]any{"error": fmt.Sprintf("failed to save manifest: %v", err)}, nil
+		return map[string]any{"error": err.Error()}, nil
 	}
 
 	result := map[string]any{
@@ -205,6 +200,23 @@ func (t *ImportResourceTool) Run(ctx tool.Context, args any) (map[string]any, er
 	return result, nil
 }
 
+// saveImported removes the runtime fields from a resource fetched from the
+// cluster and saves it as the manifest of app name.
+func saveImported(mgr *manifest.Manager, namespace, name, resourceType string, resource map[string]any) (string, error) {
+	cleanForImport(resource)
+
+	yamlBytes, err := yaml.Marshal(resource)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal resource: %w", err)
+	}
+
+	manifestPath, err := mgr.SaveManifest(namespace, name, resourceType, yamlBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to save manifest: %w", err)
+	}
+	return manifestPath, nil
+}
+
 // normalizeKind converts kind aliases to canonical names.
 func normalizeKind(kind string) string {
 	switch strings.ToLower(kind) {
@@ -257,14 +269,14 @@ func (t *ImportResourceTool) fetchDynamicResource(ctx context.Context, namespace
 		return nil, fmt.Errorf("dynamic client not available")
 	}
 
-	// Build GVR from kind and api_version
-	gvr, found := BuildGVRFromKindAndAPIVersion(kind, apiVersion)
-	if !found && apiVersion == "" {
-		return nil, fmt.Errorf("unknown resource kind '%s'. Provide api_version for custom resources", kind)
+	// Resolve GVR via discovery
+	gvr, err := t.resolver.Resolve(kind, apiVersion)
+	if err != nil {
+		return nil, err
 	}
 
 	// Check if resource is namespaced
-	namespaced := IsNamespaced(kind)
+	namespaced := t.resolver.IsNamespaced(gvr, kind)
 
 	// Get the resource interface
 	var resourceClient dynamic.ResourceInterface
@@ -0,0 +1,267 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// namespaceImportKinds are the kinds import_namespace captures. Kinds the
+// cluster doesn't serve, such as Gateway API kinds without their CRDs, are
+// left out.
+var namespaceImportKinds = []string{
+	"serviceaccount", "role", "rolebinding", "configmap", "secret", "persistentvolumeclaim",
+	"deployment", "statefulset", "daemonset", "cronjob", "job", "service", "ingress",
+	"networkpolicy", "poddisruptionbudget", "horizontalpodautoscaler",
+	"gateway", "httproute", "grpcroute", "referencegrant", "certificate", "issuer",
+}
+
+// ImportedResource is a resource import_namespace saved as a manifest.
+type ImportedResource struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// SkippedResource is a resource import_namespace left out, and why.
+type SkippedResource struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// ImportNamespaceTool provides the import_namespace tool for the agent.
+type ImportNamespaceTool struct {
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+	manifest      *manifest.Manager
+}
+
+// NewImportNamespaceTool creates a new ImportNamespaceTool.
+func NewImportNamespaceTool(dynamicClient dynamic.Interface, resolver *GVRResolver, manifest *manifest.Manager) *ImportNamespaceTool {
+	return &ImportNamespaceTool{
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+		manifest:      manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *ImportNamespaceTool) Name() string {
+	return "import_namespace"
+}
+
+// Description returns the tool description.
+func (t *ImportNamespaceTool) Description() string {
+	return "Import every supported resource in a namespace (workloads, services, config, RBAC, ingresses, routes, certificates, ...) into managed manifests at once, " +
+		"cleaned the same way as import_resource. Leaves out resources created by controllers or Kubernetes itself, Helm-managed ones, and, unless include_secrets is set, Secrets. " +
+		"Returns what was imported and what was skipped with the reason. Use it to start managing an existing namespace; commit the manifests afterwards."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *ImportNamespaceTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *ImportNamespaceTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *ImportNamespaceTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *ImportNamespaceTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The namespace to import",
+				},
+				"kinds": {
+					Type:        "array",
+					Description: "Only import these kinds, e.g. ['deployment', 'service']. Default: " + strings.Join(namespaceImportKinds, ", "),
+					Items:       &genai.Schema{Type: "string"},
+				},
+				"include_secrets": {
+					Type:        "boolean",
+					Description: "Also import Secrets, writing their data to the manifest directory. Default is false.",
+				},
+				"overwrite": {
+					Type:        "boolean",
+					Description: "Replace manifests that already exist. Default is false, which skips those resources.",
+				},
+			},
+			Required: []string{"namespace"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *ImportNamespaceTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, _ := argsMap["namespace"].(string)
+	if namespace == "" || namespace == manifest.ClusterNamespace {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+	opts := namespaceImport{namespace: namespace, kinds: namespaceImportKinds}
+	if raw, ok := argsMap["kinds"].([]any); ok && len(raw) > 0 {
+		opts.kinds = nil
+		for _, k := range raw {
+			if s, ok := k.(string); ok && s != "" {
+				opts.kinds = append(opts.kinds, NormalizeKindName(s))
+			}
+		}
+	}
+	opts.includeSecrets, _ = argsMap["include_secrets"].(bool)
+	opts.overwrite, _ = argsMap["overwrite"].(bool)
+
+	return importNamespace(toolContext(ctx), t.dynamicClient, t.resolver, t.manifest, opts), nil
+}
+
+// namespaceImport holds the options of import_namespace.
+type namespaceImport struct {
+	namespace      string
+	kinds          []string
+	includeSecrets bool
+	overwrite      bool
+}
+
+// importNamespace saves the resources of a namespace as manifests.
+func importNamespace(ctx context.Context, dyn dynamic.Interface, resolver *GVRResolver, mgr *manifest.Manager, opts namespaceImport) map[string]any {
+	if dyn == nil {
+		return map[string]any{"error": "dynamic client not available"}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	var imported []ImportedResource
+	var skipped []SkippedResource
+	var errs []string
+	byKind := map[string]int{}
+	for _, kind := range opts.kinds {
+		gvr, err := resolver.Resolve(kind, "")
+		if err != nil {
+			continue
+		}
+		if !resolver.IsNamespaced(gvr, kind) {
+			errs = append(errs, fmt.Sprintf("%s is cluster-scoped; use import_resource", kind))
+			continue
+		}
+
+		list, err := dyn.Resource(gvr).Namespace(opts.namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			// Kinds like gateway are only there when their CRDs are installed
+			if !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Sprintf("failed to list %s: %v", kind, err))
+			}
+			continue
+		}
+
+		for _, item := range list.Items {
+			name := item.GetName()
+			reason := importSkipReason(kind, &item, opts.includeSecrets)
+			if reason == "" && !opts.overwrite && mgr.ManifestExists(opts.namespace, name, kind) {
+				reason = "manifest already exists (set overwrite to replace it)"
+			}
+			if reason != "" {
+				skipped = append(skipped, SkippedResource{Kind: kind, Name: name, Reason: reason})
+				continue
+			}
+
+			path, err := saveImported(mgr, opts.namespace, name, kind, item.Object)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s/%s: %v", kind, name, err))
+				continue
+			}
+			imported = append(imported, ImportedResource{Kind: kind, Name: name, Path: path})
+			byKind[kind]++
+		}
+	}
+
+	sort.Slice(skipped, func(i, j int) bool {
+		return skipped[i].Reason < skipped[j].Reason
+	})
+	result := map[string]any{
+		"namespace":      opts.namespace,
+		"imported":       imported,
+		"imported_count": len(imported),
+		"by_kind":        byKind,
+		"skipped":        skipped,
+	}
+	if len(errs) > 0 {
+		result["errors"] = errs
+	}
+	if len(imported) > 0 {
+		result["message"] = fmt.Sprintf("Imported %d resources from %s to the manifest store; review them and commit with commit_manifests", len(imported), opts.namespace)
+		if byKind["secret"] > 0 {
+			result["warning"] = "Secret data imported. Ensure manifest directory is secured."
+		}
+	} else {
+		result["message"] = fmt.Sprintf("Nothing imported from %s", opts.namespace)
+	}
+	return result
+}
+
+// importSkipReason returns why a resource isn't imported, or "" if it is.
+func importSkipReason(kind string, obj *unstructured.Unstructured, includeSecrets bool) string {
+	if owner := metav1.GetControllerOf(obj); owner != nil {
+		return fmt.Sprintf("created by %s/%s", owner.Kind, owner.Name)
+	}
+	if obj.GetLabels()["app.kubernetes.io/managed-by"] == "Helm" {
+		return "managed by Helm (use import_resource to take it over)"
+	}
+	switch kind {
+	case "serviceaccount":
+		if obj.GetName() == "default" {
+			return "created by Kubernetes"
+		}
+	case "configmap":
+		if obj.GetName() == "kube-root-ca.crt" {
+			return "created by Kubernetes"
+		}
+	case "secret":
+		secretType, _, _ := unstructured.NestedString(obj.Object, "type")
+		switch {
+		case secretType == "kubernetes.io/service-account-token":
+			return "service account token, created by Kubernetes"
+		case strings.HasPrefix(secretType, "helm.sh/release"):
+			return "Helm release state"
+		case obj.GetAnnotations()["cert-manager.io/certificate-name"] != "":
+			return fmt.Sprintf("issued by cert-manager for Certificate %s", obj.GetAnnotations()["cert-manager.io/certificate-name"])
+		case !includeSecrets:
+			return "Secrets are only imported with include_secrets"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestImportNamespace(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	writeTestManifest(t, mgr, "shop", "settings", "configmap", configMapYAML("settings", "old"))
+
+	deploy := findTestObject("apps/v1", "Deployment", "shop", "web", nil)
+	deploy.SetUID("uid-web")
+	deploy.SetResourceVersion("42")
+	rs := ownedObject("apps/v1", "ReplicaSet", "web-7d4b9", "uid-rs", deploy)
+	rs.SetNamespace("shop")
+	token := findTestObject("v1", "Secret", "shop", "builder-token", nil)
+	token.Object["type"] = "kubernetes.io/service-account-token"
+	objects := []runtime.Object{
+		deploy,
+		rs,
+		findTestObject("v1", "Service", "shop", "web", nil),
+		findTestObject("v1", "ConfigMap", "shop", "settings", nil),
+		findTestObject("v1", "ConfigMap", "shop", "kube-root-ca.crt", nil),
+		findTestObject("v1", "ServiceAccount", "shop", "default", nil),
+		findTestObject("v1", "Secret", "shop", "db-password", nil),
+		token,
+		findTestObject("apps/v1", "Deployment", "shop", "redis", map[string]string{"app.kubernetes.io/managed-by": "Helm"}),
+		findTestObject("v1", "Service", "other", "api", nil),
+	}
+
+	listKinds := map[schema.GroupVersionResource]string{}
+	var resolver *GVRResolver
+	for _, kind := range append([]string{"replicaset"}, namespaceImportKinds...) {
+		gvr, err := resolver.Resolve(kind, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		listKinds[gvr] = kind + "List"
+	}
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+
+	result := importNamespace(context.Background(), dyn, nil, mgr, namespaceImport{namespace: "shop", kinds: namespaceImportKinds})
+	if result["errors"] != nil {
+		t.Fatalf("errors: %v", result["errors"])
+	}
+	imported := result["imported"].([]ImportedResource)
+	var names []string
+	for _, r := range imported {
+		names = append(names, r.Kind+"/"+r.Name)
+	}
+	if got := strings.Join(names, " "); got != "deployment/web service/web" {
+		t.Errorf("imported = %s", got)
+	}
+	skipped := map[string]string{}
+	for _, s := range result["skipped"].([]SkippedResource) {
+		skipped[s.Kind+"/"+s.Name] = s.Reason
+	}
+	for name, reason := range map[string]string{
+		"configmap/settings":         "manifest already exists",
+		"configmap/kube-root-ca.crt": "created by Kubernetes",
+		"serviceaccount/default":     "created by Kubernetes",
+		"secret/db-password":         "include_secrets",
+		"secret/builder-token":       "service account token",
+		"deployment/redis":           "managed by Helm",
+	} {
+		if !strings.Contains(skipped[name], reason) {
+			t.Errorf("skip reason of %s = %q, want %q", name, skipped[name], reason)
+		}
+	}
+
+	content, err := mgr.ReadManifest("shop", "web", "deployment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(content); !strings.Contains(s, "kind: Deployment") || strings.Contains(s, "resourceVersion") || strings.Contains(s, "uid") {
+		t.Errorf("manifest not cleaned:\n%s", s)
+	}
+
+	// Secrets on request, and existing manifests replaced with overwrite
+	result = importNamespace(context.Background(), dyn, nil, mgr, namespaceImport{namespace: "shop", kinds: []string{"configmap", "secret"}, includeSecrets: true, overwrite: true})
+	if got := result["by_kind"].(map[string]int); got["configmap"] != 1 || got["secret"] != 1 || result["warning"] == nil {
+		t.Errorf("by_kind = %v, warning = %v", got, result["warning"])
+	}
+}
@@ -0,0 +1,421 @@
+package tools
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// certExpiryWarning is how close to expiry a TLS certificate is flagged.
+const certExpiryWarning = 14 * 24 * time.Hour
+
+// BackendCheck describes a Service an Ingress or route sends traffic to.
+type BackendCheck struct {
+	Host           string `json:"host,omitempty"`
+	Path           string `json:"path,omitempty"`
+	Namespace      string `json:"namespace,omitempty"` // set for cross-namespace route backends
+	Service        string `json:"service"`
+	Port           string `json:"port"`
+	ReadyEndpoints int    `json:"ready_endpoints"`
+	Problem        string `json:"problem,omitempty"`
+}
+
+// TLSCheck describes a TLS certificate Secret.
+type TLSCheck struct {
+	Secret    string   `json:"secret"`
+	Namespace string   `json:"namespace,omitempty"`
+	Hosts     []string `json:"hosts,omitempty"` // hosts the certificate must cover
+	DNSNames  []string `json:"dns_names,omitempty"`
+	NotAfter  string   `json:"not_after,omitempty"`
+	ExpiresIn string   `json:"expires_in,omitempty"`
+	Problem   string   `json:"problem,omitempty"`
+}
+
+// StatusCondition is a condition reported by an ingress or gateway controller.
+type StatusCondition struct {
+	Object  string `json:"object"` // e.g. "Gateway/prod-gw" or "HTTPRoute parent Gateway/prod-gw"
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// CheckIngressTool provides the check_ingress tool for the agent.
+type CheckIngressTool struct {
+	clientset     *kubernetes.Clientset
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+}
+
+// NewCheckIngressTool creates a new CheckIngressTool.
+func NewCheckIngressTool(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, resolver *GVRResolver) *CheckIngressTool {
+	return &CheckIngressTool{
+		clientset:     clientset,
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+	}
+}
+
+// Name returns the tool name.
+func (t *CheckIngressTool) Name() string {
+	return "check_ingress"
+}
+
+// Description returns the tool description.
+func (t *CheckIngressTool) Description() string {
+	return "Validate an Ingress or Gateway API HTTPRoute end to end: the IngressClass or parent Gateway and GatewayClass exist, every backend Service and port exists and has ready endpoints, TLS Secrets exist, cover the hosts and aren't expired, plus the controller's status conditions and load balancer address."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *CheckIngressTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *CheckIngressTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *CheckIngressTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *CheckIngressTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"name": {
+					Type:        "string",
+					Description: "Name of the Ingress or HTTPRoute",
+				},
+				"kind": {
+					Type:        "string",
+					Description: "Ingress (default) or HTTPRoute",
+				},
+			},
+			Required: []string{"namespace", "name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *CheckIngressTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+	kind, _ := argsMap["kind"].(string)
+
+	switch strings.ToLower(kind) {
+	case "", "ingress", "ing":
+		return checkIngress(toolContext(ctx), t.clientset, namespace, name, time.Now()), nil
+	case "httproute", "httproutes":
+		return checkHTTPRoute(toolContext(ctx), t.clientset, t.dynamicClient, t.resolver, namespace, name, time.Now()), nil
+	default:
+		return map[string]any{"error": fmt.Sprintf("unsupported kind %q; use Ingress or HTTPRoute", kind)}, nil
+	}
+}
+
+// checkIngress builds the check_ingress response for an Ingress.
+func checkIngress(ctx context.Context, clientset kubernetes.Interface, namespace, name string, now time.Time) map[string]any {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	ing, err := clientset.NetworkingV1().Ingresses(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to get ingress: %v", err)}
+	}
+
+	var issues []string
+	result := map[string]any{
+		"kind":      "Ingress",
+		"namespace": namespace,
+		"name":      name,
+	}
+
+	class, problem := ingressClass(ctx, clientset, ing)
+	if class != "" {
+		result["ingress_class"] = class
+	}
+	if problem != "" {
+		issues = append(issues, problem)
+	}
+
+	var backends []BackendCheck
+	if b := ing.Spec.DefaultBackend; b != nil && b.Service != nil {
+		backends = append(backends, ingressBackend(ctx, clientset, namespace, "", "(default)", b.Service))
+	}
+	var hosts []string
+	for _, rule := range ing.Spec.Rules {
+		if rule.Host != "" {
+			hosts = append(hosts, rule.Host)
+		}
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, p := range rule.HTTP.Paths {
+			if p.Backend.Service == nil {
+				continue // resource backends are controller specific
+			}
+			backends = append(backends, ingressBackend(ctx, clientset, namespace, rule.Host, p.Path, p.Backend.Service))
+		}
+	}
+	for _, b := range backends {
+		if b.Problem != "" {
+			issues = append(issues, fmt.Sprintf("backend %s:%s: %s", b.Service, b.Port, b.Problem))
+		}
+	}
+	result["hosts"] = hosts
+	result["backends"] = backends
+
+	var tlsChecks []TLSCheck
+	for _, tls := range ing.Spec.TLS {
+		if tls.SecretName == "" {
+			continue
+		}
+		check := checkTLSSecret(ctx, clientset, namespace, tls.SecretName, tls.Hosts, now)
+		if check.Problem != "" {
+			problem := fmt.Sprintf("TLS secret %s: %s", tls.SecretName, check.Problem)
+			if ing.Annotations["cert-manager.io/cluster-issuer"] != "" || ing.Annotations["cert-manager.io/issuer"] != "" {
+				problem += " (cert-manager manages it; check the Certificate with get_resource)"
+			}
+			issues = append(issues, problem)
+		}
+		tlsChecks = append(tlsChecks, check)
+	}
+	if len(tlsChecks) > 0 {
+		result["tls"] = tlsChecks
+	}
+
+	var addresses []string
+	for _, lb := range ing.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			addresses = append(addresses, lb.IP)
+		}
+		if lb.Hostname != "" {
+			addresses = append(addresses, lb.Hostname)
+		}
+	}
+	result["addresses"] = addresses
+	if len(addresses) == 0 {
+		issues = append(issues, "No load balancer address in status; the ingress controller hasn't admitted this Ingress (is it running, and does it serve this class?)")
+	}
+
+	return finishIngressCheck(result, issues, fmt.Sprintf("Ingress %s is served at %s with %d healthy backend(s)", name, strings.Join(addresses, ", "), len(backends)))
+}
+
+// finishIngressCheck sets ok, issues and summary on a check result.
+func finishIngressCheck(result map[string]any, issues []string, okSummary string) map[string]any {
+	result["ok"] = len(issues) == 0
+	if len(issues) > 0 {
+		result["issues"] = issues
+		result["summary"] = issues[0]
+	} else {
+		result["summary"] = okSummary
+	}
+	return result
+}
+
+// ingressClass resolves the IngressClass of an Ingress and checks it exists,
+// falling back to the cluster's default class.
+func ingressClass(ctx context.Context, clientset kubernetes.Interface, ing *networkingv1.Ingress) (string, string) {
+	name := ""
+	if ing.Spec.IngressClassName != nil {
+		name = *ing.Spec.IngressClassName
+	} else if legacy := ing.Annotations["kubernetes.io/ingress.class"]; legacy != "" {
+		// Controllers match the deprecated annotation themselves; there may be no IngressClass object
+		return legacy, ""
+	}
+
+	classes, err := clientset.NetworkingV1().IngressClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return name, ""
+	}
+	var defaults []string
+	for _, c := range classes.Items {
+		if name != "" && c.Name == name {
+			return name, ""
+		}
+		if c.Annotations[networkingv1.AnnotationIsDefaultIngressClass] == "true" {
+			defaults = append(defaults, c.Name)
+		}
+	}
+
+	var available []string
+	for _, c := range classes.Items {
+		available = append(available, c.Name)
+	}
+	switch {
+	case name != "":
+		return name, fmt.Sprintf("IngressClass %s does not exist (available: %s)", name, orNone(available))
+	case len(defaults) == 1:
+		return defaults[0] + " (default)", ""
+	case len(defaults) > 1:
+		return "", fmt.Sprintf("No ingressClassName set and several IngressClasses are marked default (%s)", strings.Join(defaults, ", "))
+	default:
+		return "", fmt.Sprintf("No ingressClassName set and no default IngressClass exists, so no controller will serve it (available: %s)", orNone(available))
+	}
+}
+
+func orNone(list []string) string {
+	if len(list) == 0 {
+		return "none"
+	}
+	return strings.Join(list, ", ")
+}
+
+// ingressBackend checks that an Ingress backend's Service and port exist.
+func ingressBackend(ctx context.Context, clientset kubernetes.Interface, namespace, host, path string, backend *networkingv1.IngressServiceBackend) BackendCheck {
+	port := backend.Port.Name
+	if port == "" {
+		port = fmt.Sprintf("%d", backend.Port.Number)
+	}
+	check := BackendCheck{Host: host, Path: path, Service: backend.Name, Port: port}
+	checkBackendService(ctx, clientset, namespace, backend.Name, backend.Port.Name, backend.Port.Number, &check)
+	return check
+}
+
+// checkBackendService fills in check for a Service port, given by name or number.
+func checkBackendService(ctx context.Context, clientset kubernetes.Interface, namespace, service, portName string, portNumber int32, check *BackendCheck) {
+	svc, err := clientset.CoreV1().Services(namespace).Get(ctx, service, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		check.Problem = "Service does not exist"
+		return
+	}
+	if err != nil {
+		check.Problem = fmt.Sprintf("failed to get Service: %v", err)
+		return
+	}
+
+	found := false
+	var ports []string
+	for _, p := range svc.Spec.Ports {
+		if (portName != "" && p.Name == portName) || (portName == "" && p.Port == portNumber) {
+			found = true
+		}
+		ports = append(ports, fmt.Sprintf("%d", p.Port))
+		if p.Name != "" {
+			ports[len(ports)-1] = p.Name + "/" + ports[len(ports)-1]
+		}
+	}
+	if !found {
+		check.Problem = fmt.Sprintf("Service has no port %s (ports: %s)", check.Port, orNone(ports))
+		return
+	}
+	if svc.Spec.Type == corev1.ServiceTypeExternalName {
+		return
+	}
+
+	check.ReadyEndpoints = readyEndpoints(ctx, clientset, namespace, service)
+	if check.ReadyEndpoints == 0 {
+		check.Problem = "Service has no ready endpoints (run check_service)"
+	}
+}
+
+// readyEndpoints counts the ready addresses in a Service's EndpointSlices.
+func readyEndpoints(ctx context.Context, clientset kubernetes.Interface, namespace, service string) int {
+	endpointSlices, err := clientset.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + service,
+	})
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for _, s := range endpointSlices.Items {
+		n += len(summarizeEndpointSlice(s).Ready)
+	}
+	return n
+}
+
+// checkTLSSecret checks that a TLS Secret exists, holds a certificate
+// covering hosts, and isn't expired or about to expire.
+func checkTLSSecret(ctx context.Context, clientset kubernetes.Interface, namespace, name string, hosts []string, now time.Time) TLSCheck {
+	check := TLSCheck{Secret: name, Hosts: hosts}
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		check.Problem = "Secret does not exist"
+		return check
+	}
+	if err != nil {
+		check.Problem = fmt.Sprintf("failed to get Secret: %v", err)
+		return check
+	}
+
+	block, _ := pem.Decode(secret.Data[corev1.TLSCertKey])
+	if block == nil {
+		check.Problem = fmt.Sprintf("Secret has no PEM certificate in %s", corev1.TLSCertKey)
+		return check
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		check.Problem = fmt.Sprintf("invalid certificate: %v", err)
+		return check
+	}
+
+	check.DNSNames = cert.DNSNames
+	check.NotAfter = cert.NotAfter.UTC().Format(time.RFC3339)
+	remaining := cert.NotAfter.Sub(now)
+	check.ExpiresIn = formatDuration(remaining.Abs())
+
+	var problems []string
+	switch {
+	case remaining <= 0:
+		check.ExpiresIn = "expired " + check.ExpiresIn + " ago"
+		problems = append(problems, fmt.Sprintf("certificate expired at %s", check.NotAfter))
+	case remaining < certExpiryWarning:
+		problems = append(problems, fmt.Sprintf("certificate expires in %s", check.ExpiresIn))
+	}
+	if len(secret.Data[corev1.TLSPrivateKeyKey]) == 0 {
+		problems = append(problems, fmt.Sprintf("Secret has no %s", corev1.TLSPrivateKeyKey))
+	}
+	var uncovered []string
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			uncovered = append(uncovered, host)
+		}
+	}
+	if len(uncovered) > 0 {
+		problems = append(problems, fmt.Sprintf("certificate does not cover %s", strings.Join(uncovered, ", ")))
+	}
+	check.Problem = strings.Join(problems, "; ")
+	return check
+}
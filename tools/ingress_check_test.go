@@ -0,0 +1,235 @@
+package tools
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testCertSecret returns a TLS Secret holding a self-signed certificate for
+// dnsNames that expires at notAfter.
+func testCertSecret(t *testing.T, name string, notAfter time.Time, dnsNames ...string) *corev1.Secret {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			corev1.TLSPrivateKeyKey: []byte("key"),
+		},
+	}
+}
+
+func backendTestObjects() []runtime.Object {
+	ready := true
+	return []runtime.Object{
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80}}},
+		},
+		&discoveryv1.EndpointSlice{
+			ObjectMeta:  metav1.ObjectMeta{Name: "web-abc", Namespace: "default", Labels: map[string]string{discoveryv1.LabelServiceName: "web"}},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"10.1.0.5"}, Conditions: discoveryv1.EndpointConditions{Ready: &ready}}},
+		},
+	}
+}
+
+func TestCheckIngress(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	nginx := "nginx"
+	missing := "traefik"
+	class := &networkingv1.IngressClass{ObjectMeta: metav1.ObjectMeta{Name: "nginx"}}
+	ingress := func(className *string, backend string, lb bool) *networkingv1.Ingress {
+		ing := &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: networkingv1.IngressSpec{
+				IngressClassName: className,
+				TLS:              []networkingv1.IngressTLS{{Hosts: []string{"shop.example.com"}, SecretName: "web-tls"}},
+				Rules: []networkingv1.IngressRule{{
+					Host: "shop.example.com",
+					IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Path:    "/",
+							Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: backend, Port: networkingv1.ServiceBackendPort{Name: "http"}}},
+						}},
+					}},
+				}},
+			},
+		}
+		if lb {
+			ing.Status.LoadBalancer.Ingress = []networkingv1.IngressLoadBalancerIngress{{IP: "203.0.113.10"}}
+		}
+		return ing
+	}
+
+	tests := []struct {
+		name   string
+		ing    *networkingv1.Ingress
+		secret *corev1.Secret
+		ok     bool
+		issues []string
+	}{
+		{
+			name:   "healthy",
+			ing:    ingress(&nginx, "web", true),
+			secret: testCertSecret(t, "web-tls", now.Add(60*24*time.Hour), "*.example.com"),
+			ok:     true,
+		},
+		{
+			name:   "missing class and backend",
+			ing:    ingress(&missing, "api", true),
+			secret: testCertSecret(t, "web-tls", now.Add(60*24*time.Hour), "shop.example.com"),
+			issues: []string{"IngressClass traefik does not exist (available: nginx)", "backend api:http: Service does not exist"},
+		},
+		{
+			name:   "expired certificate for another host",
+			ing:    ingress(&nginx, "web", true),
+			secret: testCertSecret(t, "web-tls", now.Add(-time.Hour), "other.example.org"),
+			issues: []string{"certificate expired", "certificate does not cover shop.example.com"},
+		},
+		{
+			name:   "certificate expiring soon",
+			ing:    ingress(&nginx, "web", true),
+			secret: testCertSecret(t, "web-tls", now.Add(3*24*time.Hour), "shop.example.com"),
+			issues: []string{"certificate expires in"},
+		},
+		{
+			name:   "not admitted by a controller",
+			ing:    ingress(nil, "web", false),
+			secret: testCertSecret(t, "web-tls", now.Add(60*24*time.Hour), "shop.example.com"),
+			issues: []string{"no default IngressClass exists", "No load balancer address"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append(backendTestObjects(), class, tt.ing, tt.secret)
+			result := checkIngress(context.Background(), fake.NewSimpleClientset(objects...), "default", "web", now)
+			if _, failed := result["error"]; failed {
+				t.Fatalf("checkIngress failed: %v", result["error"])
+			}
+			if result["ok"] != tt.ok {
+				t.Errorf("ok = %v, want %v (issues: %v)", result["ok"], tt.ok, result["issues"])
+			}
+			issues, _ := result["issues"].([]string)
+			joined := strings.Join(issues, "\n")
+			for _, want := range tt.issues {
+				if !strings.Contains(joined, want) {
+					t.Errorf("issues %q should contain %q", joined, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckHTTPRoute(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	gatewayObject := func(kind, namespace, name string, spec, status map[string]any) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": gatewayAPIGroup + "/v1",
+			"kind":       kind,
+			"metadata":   map[string]any{"name": name},
+			"spec":       spec,
+			"status":     status,
+		}}
+		if namespace != "" {
+			obj.SetNamespace(namespace)
+		}
+		return obj
+	}
+	accepted := []any{map[string]any{"type": "Accepted", "status": "True", "reason": "Accepted"}}
+
+	class := gatewayObject("GatewayClass", "", "envoy", map[string]any{"controllerName": "example.com/envoy"}, map[string]any{"conditions": accepted})
+	gw := gatewayObject("Gateway", "infra", "public", map[string]any{
+		"gatewayClassName": "envoy",
+		"listeners": []any{map[string]any{
+			"name": "https", "port": int64(443), "protocol": "HTTPS",
+			"tls": map[string]any{"certificateRefs": []any{map[string]any{"name": "web-tls", "namespace": "default"}}},
+		}},
+	}, map[string]any{"addresses": []any{map[string]any{"value": "203.0.113.20"}}, "conditions": accepted})
+	route := func(backendNamespace string) *unstructured.Unstructured {
+		return gatewayObject("HTTPRoute", "default", "web", map[string]any{
+			"parentRefs": []any{map[string]any{"name": "public", "namespace": "infra"}},
+			"hostnames":  []any{"shop.example.com"},
+			"rules": []any{map[string]any{"backendRefs": []any{
+				map[string]any{"name": "web", "namespace": backendNamespace, "port": int64(80)},
+			}}},
+		}, map[string]any{"parents": []any{map[string]any{
+			"parentRef":  map[string]any{"name": "public", "namespace": "infra"},
+			"conditions": []any{map[string]any{"type": "ResolvedRefs", "status": "False", "reason": "RefNotPermitted", "message": "backend not permitted"}},
+		}}})
+	}
+	newDynamicClient := func(route *unstructured.Unstructured) *dynamicfake.FakeDynamicClient {
+		dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+			{Group: gatewayAPIGroup, Version: "v1beta1", Resource: "referencegrants"}: "ReferenceGrantList",
+		}, class, route)
+		// The fake guesses "gatewaies" as the resource of kind Gateway, so create it explicitly
+		gvr := schema.GroupVersionResource{Group: gatewayAPIGroup, Version: "v1", Resource: "gateways"}
+		if _, err := dyn.Resource(gvr).Namespace("infra").Create(context.Background(), gw, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		return dyn
+	}
+
+	clientset := fake.NewSimpleClientset(append(backendTestObjects(), testCertSecret(t, "web-tls", now.Add(60*24*time.Hour), "shop.example.com"))...)
+	dyn := newDynamicClient(route("backends"))
+	result := checkHTTPRoute(context.Background(), clientset, dyn, nil, "default", "web", now)
+	if _, failed := result["error"]; failed {
+		t.Fatalf("checkHTTPRoute failed: %v", result["error"])
+	}
+	if result["ok"] != false {
+		t.Errorf("route with an ungranted cross-namespace backend should not be ok")
+	}
+	joined := strings.Join(result["issues"].([]string), "\n")
+	for _, want := range []string{
+		"no ReferenceGrant in backends allows HTTPRoutes from default",
+		"HTTPRoute parent Gateway/public is not ResolvedRefs: RefNotPermitted",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("issues %q should contain %q", joined, want)
+		}
+	}
+	if tls, _ := result["tls"].([]TLSCheck); len(tls) != 1 || tls[0].Problem != "" {
+		t.Errorf("listener certificate should be checked and valid, got %+v", result["tls"])
+	}
+
+	dyn = newDynamicClient(route(""))
+	result = checkHTTPRoute(context.Background(), clientset, dyn, nil, "default", "web", now)
+	backends, _ := result["backends"].([]BackendCheck)
+	if len(backends) != 1 || backends[0].Problem != "" || backends[0].ReadyEndpoints != 1 {
+		t.Errorf("same-namespace backend should be healthy, got %+v", result["backends"])
+	}
+}
@@ -1,10 +1,8 @@
 package tools
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/perbu/kasa/manifest"
 	"google.golang.org/adk/model"
@@ -243,7 +241,7 @@ func (t *CreateIngressTool) Run(ctx tool.Context, args any) (map[string]any, err
 	}
 
 	// Apply to cluster
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
 	defer cancel()
 
 	var action string
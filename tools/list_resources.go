@@ -1,7 +1,6 @@
 package tools
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -9,6 +8,7 @@ import (
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/tool"
 	"google.golang.org/genai"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/dynamic"
 )
@@ -16,12 +16,14 @@ import (
 // ListResourcesTool provides the list_resources tool for listing any Kubernetes resources.
 type ListResourcesTool struct {
 	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
 }
 
 // NewListResourcesTool creates a new ListResourcesTool.
-func NewListResourcesTool(dynamicClient dynamic.Interface) *ListResourcesTool {
+func NewListResourcesTool(dynamicClient dynamic.Interface, resolver *GVRResolver) *ListResourcesTool {
 	return &ListResourcesTool{
 		dynamicClient: dynamicClient,
+		resolver:      resolver,
 	}
 }
 
@@ -57,7 +59,7 @@ func (t *ListResourcesTool) Declaration() *genai.FunctionDeclaration {
 		Description: t.Description(),
 		Parameters: &genai.Schema{
 			Type: "object",
-			Properties: map[string]*genai.Schema{
+			Properties: pagingSchema(map[string]*genai.Schema{
 				"kind": {
 					Type:        "string",
 					Description: "The resource kind to list (e.g., httproute, gateway, certificate, deployment, pod, service). Aliases like 'gw', 'deploy', 'svc' are supported.",
@@ -74,7 +76,7 @@ func (t *ListResourcesTool) Declaration() *genai.FunctionDeclaration {
 					Type:        "string",
 					Description: "Filter by label selector (e.g., 'app=nginx,env=prod')",
 				},
-			},
+			}, defaultPageLimit),
 			Required: []string{"kind"},
 		},
 	}
@@ -113,21 +115,24 @@ func (t *ListResourcesTool) Run(ctx tool.Context, args any) (map[string]any, err
 		labelSelector = ls
 	}
 
-	// Build GVR from kind and api_version
-	gvr, found := BuildGVRFromKindAndAPIVersion(kind, apiVersion)
-	if !found && apiVersion == "" {
-		return map[string]any{
-			"error": fmt.Sprintf("Unknown resource kind '%s'. Provide api_version for custom resources. Known kinds include: deployment, service, pod, configmap, secret, ingress, httproute, gateway, certificate.", kind),
-		}, nil
+	page, limit, err := pagingArgs(argsMap, defaultPageLimit)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	// Resolve GVR via discovery
+	gvr, err := t.resolver.Resolve(kind, apiVersion)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
 	}
 
 	// Check if resource is namespaced
-	namespaced := IsNamespaced(kind)
+	namespaced := t.resolver.IsNamespaced(gvr, kind)
 
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
 	defer cancel()
 
-	listOptions := metav1.ListOptions{}
+	listOptions := metav1.ListOptions{Limit: int64(limit), Continue: page}
 	if labelSelector != "" {
 		listOptions.LabelSelector = labelSelector
 	}
@@ -145,6 +150,9 @@ func (t *ListResourcesTool) Run(ctx tool.Context, args any) (map[string]any, err
 	}
 
 	list, err := resourceClient.List(timeoutCtx, listOptions)
+	if apierrors.IsResourceExpired(err) {
+		return map[string]any{"error": "the page token has expired; list again without page"}, nil
+	}
 	if err != nil {
 		return map[string]any{"error": fmt.Sprintf("failed to list %s: %v", kind, err)}, nil
 	}
@@ -184,6 +192,12 @@ func (t *ListResourcesTool) Run(ctx tool.Context, args any) (map[string]any, err
 		"items":      items,
 	}
 
+	remaining := int64(-1)
+	if n := list.GetRemainingItemCount(); n != nil {
+		remaining = *n
+	}
+	setNextPage(result, list.GetContinue(), remaining)
+
 	if namespace != "" {
 		result["namespace"] = namespace
 	} else if namespaced {
@@ -232,10 +246,64 @@ func extractStatusSummary(status any, kind string) map[string]any {
 			summary["available"] = available
 		}
 
+	case "replicaset", "statefulset":
+		if replicas, ok := statusMap["replicas"]; ok {
+			summary["replicas"] = replicas
+		}
+		if ready, ok := statusMap["readyReplicas"]; ok {
+			summary["ready"] = ready
+		}
+
+	case "daemonset":
+		if desired, ok := statusMap["desiredNumberScheduled"]; ok {
+			summary["desired"] = desired
+		}
+		if ready, ok := statusMap["numberReady"]; ok {
+			summary["ready"] = ready
+		}
+
+	case "job":
+		for _, key := range []string{"active", "succeeded", "failed"} {
+			if v, ok := statusMap[key]; ok {
+				summary[key] = v
+			}
+		}
+
 	case "pod":
 		if phase, ok := statusMap["phase"]; ok {
 			summary["phase"] = phase
 		}
+		if reason, ok := statusMap["reason"].(string); ok {
+			summary["reason"] = reason
+		}
+		if statuses, ok := statusMap["containerStatuses"].([]any); ok && len(statuses) > 0 {
+			var ready, restarts int64
+			for _, s := range statuses {
+				cs, _ := s.(map[string]any)
+				if r, _ := cs["ready"].(bool); r {
+					ready++
+				}
+				if n, ok := cs["restartCount"].(int64); ok {
+					restarts += n
+				}
+				// The first unready container's waiting or terminated reason,
+				// e.g. CrashLoopBackOff or ImagePullBackOff
+				if _, ok := summary["reason"]; !ok && cs["ready"] != true {
+					state, _ := cs["state"].(map[string]any)
+					for _, key := range []string{"waiting", "terminated"} {
+						if st, ok := state[key].(map[string]any); ok {
+							if reason, ok := st["reason"].(string); ok && reason != "" {
+								summary["reason"] = reason
+							}
+						}
+					}
+				}
+			}
+			summary["ready"] = fmt.Sprintf("%d/%d", ready, len(statuses))
+			if restarts > 0 {
+				summary["restarts"] = restarts
+			}
+		}
 
 	case "service":
 		if loadBalancer, ok := statusMap["loadBalancer"].(map[string]any); ok {
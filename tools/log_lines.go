@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Modes of get_logs.
+const (
+	logModeAuto        = "auto"        // raw if short, else condensed
+	logModeRaw         = "raw"         // the lines as fetched
+	logModeInteresting = "interesting" // error and warning lines only
+)
+
+const (
+	// defaultLogTailLines is how many lines mode raw fetches by default.
+	defaultLogTailLines = 100
+	// defaultLogScanLines is how many lines modes auto and interesting
+	// fetch by default, to find errors further back.
+	defaultLogScanLines = 2000
+	// autoLogLines is the longest log mode auto returns as it is.
+	autoLogLines = 200
+	// autoLogContext is how many of the last lines a condensed log keeps.
+	autoLogContext = 50
+	// maxTraceLines bounds the lines kept after a panic or a traceback.
+	maxTraceLines = 30
+	// maxLogBytes bounds the logs fetched, whatever tail_lines asks for.
+	maxLogBytes = 8 << 20
+)
+
+// interestingLine matches log lines worth the model's attention: errors,
+// warnings, panics, and failures such as timeouts and refused connections,
+// including exception names such as IllegalStateException and klog's
+// E/W/F severity prefixes.
+var interestingLine = regexp.MustCompile(`(?i)\b(err|errors|warn|warning|panic|fatal|traceback|fail|failed|failure|crash|crashed|killed|oomkilled|out of memory|refused|timeout|timed out|deadline exceeded|unavailable|denied|forbidden|unauthorized)\b|\b\w*(error|exception)\b|level=(error|warn|warning|fatal)|"level":\s*"(error|warn|warning|fatal)"|^[EWF]\d{4} `)
+
+// traceStart matches lines followed by a stack trace, which is kept even
+// though its lines don't look like errors.
+var traceStart = regexp.MustCompile(`^(panic:|fatal error:|Traceback |Exception in thread |goroutine \d+ \[)`)
+
+// variablePart matches what differs between repeats of one message:
+// timestamps, UUIDs, hex IDs and numbers.
+var variablePart = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|0x[0-9a-fA-F]+|\b[0-9a-f]{12,}\b|\d+`)
+
+// interestingLines returns the error and warning lines of lines, each
+// message once with the number of times it was repeated, and the lines of
+// stack traces after them. Indented lines after an error, such as the
+// frames of a Java exception, are kept too.
+func interestingLines(lines []string) (kept []string, folded int) {
+	var keys []string
+	counts := make(map[string]int)
+	first := make(map[string]string)
+	trace, indented := 0, false
+	for _, line := range lines {
+		switch {
+		case traceStart.MatchString(line):
+			trace, indented = maxTraceLines, false
+		case trace > 0:
+			trace--
+		case interestingLine.MatchString(line):
+			indented = true
+		case indented && strings.TrimSpace(line) != "" && (line[0] == ' ' || line[0] == '\t'):
+		default:
+			indented = false
+			continue
+		}
+		key := variablePart.ReplaceAllString(line, "#")
+		if counts[key] == 0 {
+			keys = append(keys, key)
+			first[key] = line
+		}
+		counts[key]++
+	}
+	for _, key := range keys {
+		line := first[key]
+		if n := counts[key]; n > 1 {
+			line += fmt.Sprintf(" [repeated %d times]", n)
+			folded += n - 1
+		}
+		kept = append(kept, line)
+	}
+	return kept, folded
+}
+
+// filterLogs applies a get_logs mode to fetched logs. It returns the logs
+// to hand the model and, if they were shortened, a note saying how.
+func filterLogs(logs, mode string) (string, string) {
+	if mode == logModeRaw || logs == "" {
+		return logs, ""
+	}
+	lines := strings.Split(strings.TrimSuffix(logs, "\n"), "\n")
+	if mode == logModeAuto && len(lines) <= autoLogLines {
+		return logs, ""
+	}
+	kept, folded := interestingLines(lines)
+
+	if mode == logModeInteresting {
+		if len(kept) == 0 {
+			return "", fmt.Sprintf("none of the %d lines are errors or warnings; call again with mode=raw to see them", len(lines))
+		}
+		return strings.Join(kept, "\n"), fmt.Sprintf("%d error and warning lines of %d, with %d repeats folded; call again with mode=raw for the full logs",
+			len(kept), len(lines), folded)
+	}
+
+	tail := strings.Join(lines[len(lines)-autoLogContext:], "\n")
+	if len(kept) == 0 {
+		return tail, fmt.Sprintf("none of the %d lines are errors or warnings, so only the last %d are shown; call again with mode=raw for the full logs",
+			len(lines), autoLogContext)
+	}
+	var b strings.Builder
+	b.WriteString("Error and warning lines:\n")
+	b.WriteString(strings.Join(kept, "\n"))
+	fmt.Fprintf(&b, "\n\nLast %d lines:\n", autoLogContext)
+	b.WriteString(tail)
+	return b.String(), fmt.Sprintf("%d lines condensed to %d error and warning lines, with %d repeats folded, and the last %d lines; call again with mode=raw for the full logs",
+		len(lines), len(kept), folded, autoLogContext)
+}
@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestInterestingLines(t *testing.T) {
+	lines := []string{
+		"2026-01-01T10:00:00Z level=info msg=\"serving on :8080\"",
+		"2026-01-01T10:00:01Z level=error msg=\"dial tcp 10.0.0.7:5432: connect: connection refused\" attempt=1",
+		"2026-01-01T10:00:02Z level=info msg=\"request handled\" status=200",
+		"2026-01-01T10:00:03Z level=error msg=\"dial tcp 10.0.0.7:5432: connect: connection refused\" attempt=2",
+		"java.lang.IllegalStateException: pool exhausted",
+		"\tat com.example.Pool.get(Pool.java:42)",
+		"\tat com.example.Handler.handle(Handler.java:17)",
+		"request done",
+		"panic: runtime error: invalid memory address or nil pointer dereference",
+		"",
+		"goroutine 1 [running]:",
+		"main.main()",
+		"\t/src/main.go:12 +0x1d",
+	}
+	kept, folded := interestingLines(lines)
+	want := []string{
+		"2026-01-01T10:00:01Z level=error msg=\"dial tcp 10.0.0.7:5432: connect: connection refused\" attempt=1 [repeated 2 times]",
+		"java.lang.IllegalStateException: pool exhausted",
+		"\tat com.example.Pool.get(Pool.java:42)",
+		"\tat com.example.Handler.handle(Handler.java:17)",
+		"panic: runtime error: invalid memory address or nil pointer dereference",
+		"",
+		"goroutine 1 [running]:",
+		"main.main()",
+		"\t/src/main.go:12 +0x1d",
+	}
+	if strings.Join(kept, "\n") != strings.Join(want, "\n") {
+		t.Errorf("kept:\n%s\nwant:\n%s", strings.Join(kept, "\n"), strings.Join(want, "\n"))
+	}
+	if folded != 1 {
+		t.Errorf("folded = %d, want 1", folded)
+	}
+}
+
+func TestFilterLogs(t *testing.T) {
+	var b strings.Builder
+	for i := range 1000 {
+		if i%100 == 50 {
+			fmt.Fprintf(&b, "W0101 10:%02d:00 cache.go:88] watch of *v1.Pod ended with: too old resource version: %d\n", i/100, 1000+i)
+		} else {
+			fmt.Fprintf(&b, "I0101 request %d handled in %dms\n", i, i%7)
+		}
+	}
+	logs := b.String()
+
+	if out, note := filterLogs(logs, logModeRaw); out != logs || note != "" {
+		t.Error("mode raw changed the logs")
+	}
+	short := "line one\nline two\n"
+	if out, note := filterLogs(short, logModeAuto); out != short || note != "" {
+		t.Error("mode auto changed short logs")
+	}
+
+	out, note := filterLogs(logs, logModeAuto)
+	if !strings.HasPrefix(out, "Error and warning lines:\nW0101 10:00:00 cache.go:88] watch of *v1.Pod ended with: too old resource version: 1050 [repeated 10 times]\n\nLast 50 lines:\n") ||
+		!strings.HasSuffix(out, "I0101 request 999 handled in 5ms") {
+		t.Errorf("auto:\n%s", out)
+	}
+	if !strings.Contains(note, "1000 lines condensed to 1 error and warning lines, with 9 repeats folded") {
+		t.Errorf("note = %q", note)
+	}
+
+	if out, _ := filterLogs(logs, logModeInteresting); strings.Count(out, "\n") != 0 || !strings.Contains(out, "[repeated 10 times]") {
+		t.Errorf("interesting:\n%s", out)
+	}
+	if out, note := filterLogs(short, logModeInteresting); out != "" || !strings.Contains(note, "none of the 2 lines") {
+		t.Errorf("interesting without errors: %q, %q", out, note)
+	}
+}
@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// patternWildcard replaces the tokens that vary between the lines of a
+	// pattern.
+	patternWildcard = "<*>"
+	// patternSimilarity is the share of tokens a line must have in common
+	// with a pattern to join it.
+	patternSimilarity = 0.5
+	// maxLogPatterns bounds the patterns of one analysis; lines that match
+	// none once it is reached are only counted.
+	maxLogPatterns = 1000
+	// rarePatternCount is the most lines a rare pattern has.
+	rarePatternCount = 2
+)
+
+// logPattern is a template of log lines, such as "connection to <*>
+// refused", and the lines that matched it.
+type logPattern struct {
+	tokens      []string
+	count       int
+	first, last time.Time
+	example     string
+}
+
+// template returns the pattern as text.
+func (p *logPattern) template() string {
+	return strings.Join(p.tokens, " ")
+}
+
+// patternMiner groups log lines by template, the way Drain (He et al.,
+// 2017) does: each line is split into tokens, tokens with digits become
+// wildcards, and the line joins the most similar pattern with the same
+// number of tokens and the same first token, if enough tokens match. The
+// tokens where the line differs from the pattern become wildcards too.
+// Otherwise the line starts a pattern of its own.
+type patternMiner struct {
+	buckets   map[string][]*logPattern
+	patterns  []*logPattern // in order of appearance
+	unmatched int           // lines left out once maxLogPatterns was reached
+}
+
+func newPatternMiner() *patternMiner {
+	return &patternMiner{buckets: make(map[string][]*logPattern)}
+}
+
+// add adds a line logged at the given time, which may be zero.
+func (m *patternMiner) add(line string, at time.Time) {
+	tokens := patternTokens(line)
+	if len(tokens) == 0 {
+		return
+	}
+	bucket := strconv.Itoa(len(tokens)) + " " + tokens[0]
+
+	var best *logPattern
+	bestScore := 0.0
+	for _, p := range m.buckets[bucket] {
+		if score := similarity(p.tokens, tokens); score >= patternSimilarity && score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+	if best == nil {
+		if len(m.patterns) >= maxLogPatterns {
+			m.unmatched++
+			return
+		}
+		best = &logPattern{tokens: tokens, first: at, example: line}
+		m.buckets[bucket] = append(m.buckets[bucket], best)
+		m.patterns = append(m.patterns, best)
+	} else {
+		for i, token := range tokens {
+			if best.tokens[i] != token {
+				best.tokens[i] = patternWildcard
+			}
+		}
+	}
+	best.count++
+	if best.first.IsZero() || (!at.IsZero() && at.Before(best.first)) {
+		best.first = at
+	}
+	if at.After(best.last) {
+		best.last = at
+	}
+}
+
+// patternTokens splits a line into tokens and replaces those with digits,
+// such as IDs, addresses and durations, by wildcards.
+func patternTokens(line string) []string {
+	tokens := strings.Fields(line)
+	for i, token := range tokens {
+		if strings.ContainsFunc(token, unicode.IsDigit) {
+			tokens[i] = patternWildcard
+		}
+	}
+	return tokens
+}
+
+// similarity returns the share of positions where the tokens equal the
+// pattern's. Wildcards in the pattern count as half a match, so a line
+// prefers the pattern that matches it literally.
+func similarity(pattern, tokens []string) float64 {
+	var score float64
+	for i, token := range tokens {
+		switch pattern[i] {
+		case token:
+			score++
+		case patternWildcard:
+			score += 0.5
+		}
+	}
+	return score / float64(len(tokens))
+}
+
+// byCount returns the patterns, most lines first.
+func (m *patternMiner) byCount() []*logPattern {
+	sorted := slices.Clone(m.patterns)
+	slices.SortStableFunc(sorted, func(a, b *logPattern) int {
+		return b.count - a.count
+	})
+	return sorted
+}
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPatternMiner(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	m := newPatternMiner()
+	for i := range 30 {
+		m.add(fmt.Sprintf("GET /api/orders/%d 200 %dms", 1000+i, i%9), start.Add(time.Duration(i)*time.Second))
+	}
+	for i := range 5 {
+		m.add(fmt.Sprintf("cache miss for user alice%d", i), start.Add(time.Duration(i)*time.Second))
+	}
+	m.add("connection to db-primary refused", start.Add(time.Minute))
+	m.add("connection to db-replica refused", start.Add(2*time.Minute))
+	m.add("shutting down", start.Add(3*time.Minute))
+	m.add("", start)
+
+	want := []struct {
+		template string
+		count    int
+	}{
+		{"GET <*> <*> <*>", 30},
+		{"cache miss for user <*>", 5},
+		{"connection to <*> refused", 2},
+		{"shutting down", 1},
+	}
+	patterns := m.byCount()
+	if len(patterns) != len(want) {
+		for _, p := range patterns {
+			t.Logf("%q: %d", p.template(), p.count)
+		}
+		t.Fatalf("got %d patterns, want %d", len(patterns), len(want))
+	}
+	for i, w := range want {
+		if patterns[i].template() != w.template || patterns[i].count != w.count {
+			t.Errorf("pattern %d = %q (%d), want %q (%d)", i, patterns[i].template(), patterns[i].count, w.template, w.count)
+		}
+	}
+	refused := patterns[2]
+	if !refused.first.Equal(start.Add(time.Minute)) || !refused.last.Equal(start.Add(2*time.Minute)) || refused.example != "connection to db-primary refused" {
+		t.Errorf("refused pattern = %+v", refused)
+	}
+
+	out := logPatterns(errorsFirst([]*logPattern{patterns[3], refused}))
+	if out[0].Template != "connection to <*> refused" || !out[0].Errors || out[1].Errors || out[0].FirstSeen != "2026-01-01T10:01:00Z" {
+		t.Errorf("errorsFirst = %+v", out)
+	}
+}
+
+func TestSplitLogTimestamp(t *testing.T) {
+	at, line := splitLogTimestamp("2026-01-01T10:00:00.123456789Z level=info msg=started")
+	if at.IsZero() || line != "level=info msg=started" {
+		t.Errorf("got %v, %q", at, line)
+	}
+	if at, line := splitLogTimestamp("no timestamp here"); !at.IsZero() || line != "no timestamp here" {
+		t.Errorf("got %v, %q", at, line)
+	}
+}
@@ -1,8 +1,8 @@
 package tools
 
 import (
-	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"time"
 
@@ -10,6 +10,7 @@ import (
 	"google.golang.org/adk/tool"
 	"google.golang.org/genai"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -32,7 +33,8 @@ func (t *GetLogsTool) Name() string {
 
 // Description returns the tool description.
 func (t *GetLogsTool) Description() string {
-	return "Get logs from a container in a pod. Can retrieve current or previous container logs."
+	return "Get logs from a container in a pod. Can retrieve current or previous container logs. " +
+		"Long logs are condensed to their error and warning lines, with repeated messages folded, and the last lines."
 }
 
 // IsLongRunning returns false as this is a quick operation.
@@ -76,7 +78,17 @@ func (t *GetLogsTool) Declaration() *genai.FunctionDeclaration {
 				},
 				"tail_lines": {
 					Type:        "integer",
-					Description: "Number of lines from the end of the logs to retrieve. Defaults to 100.",
+					Description: "Number of lines from the end of the logs to retrieve. Defaults to 100 in mode raw and 2000 otherwise.",
+				},
+				"since": {
+					Type:        "string",
+					Description: "Only return logs newer than this: a duration such as '15m' or an RFC3339 time.",
+				},
+				"mode": {
+					Type: "string",
+					Enum: []string{logModeAuto, logModeRaw, logModeInteresting},
+					Description: "auto (default) returns up to 200 lines as they are and condenses longer logs to their error and warning lines plus the last 50; " +
+						"interesting returns only the error and warning lines, each message once with its repeat count; raw returns every line.",
 				},
 			},
 			Required: []string{"namespace", "pod"},
@@ -118,19 +130,39 @@ func (t *GetLogsTool) Run(ctx tool.Context, args any) (map[string]any, error) {
 		previous = p
 	}
 
-	tailLines := int64(100)
+	mode := logModeAuto
+	if m, ok := argsMap["mode"].(string); ok && m != "" {
+		mode = m
+	}
+	if mode != logModeAuto && mode != logModeRaw && mode != logModeInteresting {
+		return map[string]any{"error": fmt.Sprintf("unknown mode %q (use auto, raw or interesting)", mode)}, nil
+	}
+
+	tailLines := int64(defaultLogScanLines)
+	if mode == logModeRaw {
+		tailLines = defaultLogTailLines
+	}
 	if tl, ok := argsMap["tail_lines"].(float64); ok {
 		tailLines = int64(tl)
 	}
 
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
 	defer cancel()
 
 	// Build log options
+	limitBytes := int64(maxLogBytes)
 	opts := &corev1.PodLogOptions{
-		Container: container,
-		Previous:  previous,
-		TailLines: &tailLines,
+		Container:  container,
+		Previous:   previous,
+		TailLines:  &tailLines,
+		LimitBytes: &limitBytes,
+	}
+	if since, _ := argsMap["since"].(string); since != "" {
+		sinceTime, err := parsePromTime(since, time.Now())
+		if err != nil {
+			return map[string]any{"error": fmt.Sprintf("invalid since: %v", err)}, nil
+		}
+		opts.SinceTime = &metav1.Time{Time: sinceTime}
 	}
 
 	// Get logs
@@ -154,12 +186,18 @@ func (t *GetLogsTool) Run(ctx tool.Context, args any) (map[string]any, error) {
 		}, nil
 	}
 
-	return map[string]any{
+	text, note := filterLogs(string(logs), mode)
+	result := map[string]any{
 		"namespace":  namespace,
 		"pod":        pod,
 		"container":  container,
 		"previous":   previous,
 		"tail_lines": tailLines,
-		"logs":       string(logs),
-	}, nil
+		"mode":       mode,
+		"logs":       text,
+	}
+	if note != "" {
+		result["note"] = note
+	}
+	return result, nil
 }
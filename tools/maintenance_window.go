@@ -0,0 +1,427 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// windowOverrideTTL is how long an approved override lifts the maintenance
+// windows of a namespace.
+const windowOverrideTTL = time.Hour
+
+// MaintenanceWindow is a recurring period in which mutating tools may change
+// the namespaces it covers.
+type MaintenanceWindow struct {
+	Namespaces []string `yaml:"namespaces"` // glob patterns, e.g. ["prod", "prod-*"]
+	Days       []string `yaml:"days"`       // e.g. ["mon", "tue"]; every day if empty
+	Start      string   `yaml:"start"`      // "HH:MM"
+	End        string   `yaml:"end"`        // "HH:MM"; before Start for windows spanning midnight
+}
+
+// MaintenancePolicy restricts mutations of covered namespaces to their
+// maintenance windows. Namespaces no window covers are unrestricted.
+type MaintenancePolicy struct {
+	Timezone string              `yaml:"timezone"` // IANA name; local time if empty
+	Windows  []MaintenanceWindow `yaml:"windows"`
+}
+
+// IsEmpty reports whether the policy restricts nothing.
+func (p MaintenancePolicy) IsEmpty() bool {
+	return len(p.Windows) == 0
+}
+
+// Validate checks the timezone, days, times and namespace patterns.
+func (p MaintenancePolicy) Validate() error {
+	if _, err := p.location(); err != nil {
+		return fmt.Errorf("timezone: %w", err)
+	}
+	for i, w := range p.Windows {
+		if len(w.Namespaces) == 0 {
+			return fmt.Errorf("windows[%d]: namespaces must be set", i)
+		}
+		for _, pattern := range w.Namespaces {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("windows[%d]: namespace pattern %q: %w", i, pattern, err)
+			}
+		}
+		for _, d := range w.Days {
+			if _, ok := weekdays[strings.ToLower(d)]; !ok {
+				return fmt.Errorf("windows[%d]: unknown day %q (use mon, tue, ...)", i, d)
+			}
+		}
+		if _, err := parseClock(w.Start); err != nil {
+			return fmt.Errorf("windows[%d]: start: %w", i, err)
+		}
+		if _, err := parseClock(w.End); err != nil {
+			return fmt.Errorf("windows[%d]: end: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// location returns the policy's timezone.
+func (p MaintenancePolicy) location() (*time.Location, error) {
+	if p.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(p.Timezone)
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// parseClock returns the minutes since midnight of an "HH:MM" time.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// covers reports whether the window applies to namespace.
+func (w MaintenanceWindow) covers(namespace string) bool {
+	for _, pattern := range w.Namespaces {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// onDay reports whether the window opens on day.
+func (w MaintenanceWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether t (in the policy's timezone) falls inside the window.
+func (w MaintenanceWindow) contains(t time.Time) bool {
+	start, _ := parseClock(w.Start)
+	end, _ := parseClock(w.End)
+	minute := t.Hour()*60 + t.Minute()
+	switch {
+	case start == end:
+		return w.onDay(t.Weekday())
+	case start < end:
+		return w.onDay(t.Weekday()) && minute >= start && minute < end
+	default:
+		// Spans midnight; the window belongs to the day it opens on
+		yesterday := (t.Weekday() + 6) % 7
+		return (w.onDay(t.Weekday()) && minute >= start) || (w.onDay(yesterday) && minute < end)
+	}
+}
+
+// nextOpening returns the first time after t the window opens.
+func (w MaintenanceWindow) nextOpening(t time.Time) time.Time {
+	start, _ := parseClock(w.Start)
+	for d := 0; d <= 7; d++ {
+		day := t.AddDate(0, 0, d)
+		opening := time.Date(day.Year(), day.Month(), day.Day(), start/60, start%60, 0, 0, t.Location())
+		if opening.After(t) && w.onDay(opening.Weekday()) {
+			return opening
+		}
+	}
+	return time.Time{}
+}
+
+func (w MaintenanceWindow) String() string {
+	days := "daily"
+	if len(w.Days) > 0 {
+		days = strings.Join(w.Days, ",")
+	}
+	return fmt.Sprintf("%s %s-%s", days, w.Start, w.End)
+}
+
+// windowCheck is the outcome of checking a namespace against the policy.
+type windowCheck struct {
+	open    bool
+	windows []string  // windows covering the namespace
+	next    time.Time // next opening when closed
+}
+
+// check reports whether namespace may be changed at now.
+func (p MaintenancePolicy) check(namespace string, now time.Time, loc *time.Location) windowCheck {
+	now = now.In(loc)
+	result := windowCheck{open: true}
+	for _, w := range p.Windows {
+		if !w.covers(namespace) {
+			continue
+		}
+		result.windows = append(result.windows, w.String())
+		if w.contains(now) {
+			return windowCheck{open: true, windows: result.windows}
+		}
+		result.open = false
+		if next := w.nextOpening(now); !next.IsZero() && (result.next.IsZero() || next.Before(result.next)) {
+			result.next = next
+		}
+	}
+	return result
+}
+
+// windowOverride is an approved exception to the maintenance windows of a
+// namespace, as recorded in the override log.
+type windowOverride struct {
+	Time          time.Time `json:"time"`
+	Namespace     string    `json:"namespace"`
+	Justification string    `json:"justification"`
+	User          string    `json:"user,omitempty"`
+	Expires       time.Time `json:"expires"`
+}
+
+// windowGuard enforces the maintenance policy and tracks approved overrides.
+type windowGuard struct {
+	policy  MaintenancePolicy
+	loc     *time.Location
+	logFile string // override log; overrides are not recorded if empty
+	now     func() time.Time
+
+	mu        sync.Mutex
+	overrides map[string]windowOverride // by namespace
+}
+
+// overridden reports whether an unexpired override covers namespace.
+func (g *windowGuard) overridden(namespace string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	o, ok := g.overrides[namespace]
+	return ok && g.now().Before(o.Expires)
+}
+
+// grant records an override and lifts the windows of namespace until it expires.
+func (g *windowGuard) grant(namespace, justification string) error {
+	now := g.now()
+	o := windowOverride{
+		Time:          now,
+		Namespace:     namespace,
+		Justification: justification,
+		User:          os.Getenv("USER"),
+		Expires:       now.Add(windowOverrideTTL),
+	}
+
+	// An override that can't be recorded isn't granted
+	if g.logFile != "" {
+		if err := appendOverrideLog(g.logFile, o); err != nil {
+			return err
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.overrides[namespace] = o
+	return nil
+}
+
+func appendOverrideLog(file string, o windowOverride) error {
+	line, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("creating override log directory: %w", err)
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening override log: %w", err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// WindowOverrideLogPath returns the location of the override log
+// (~/.kasa/window_overrides.jsonl).
+func WindowOverrideLogPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kasa", "window_overrides.jsonl")
+}
+
+// SetMaintenanceWindows restricts mutating tools to the maintenance windows
+// in policy. Approved overrides are appended to logFile.
+func (k *KubeTools) SetMaintenanceWindows(policy MaintenancePolicy, logFile string) error {
+	if policy.IsEmpty() {
+		k.windowGuard = nil
+		return nil
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	loc, _ := policy.location()
+	k.windowGuard = &windowGuard{
+		policy:    policy,
+		loc:       loc,
+		logFile:   logFile,
+		now:       time.Now,
+		overrides: make(map[string]windowOverride),
+	}
+	return nil
+}
+
+// GrantWindowOverride lets mutating tools change namespace outside its
+// maintenance windows for the next hour, recording the justification.
+// It must only be called on an explicit user decision, never by the agent.
+func (k *KubeTools) GrantWindowOverride(namespace, justification string) error {
+	if k.windowGuard == nil {
+		return nil
+	}
+	return k.windowGuard.grant(namespace, justification)
+}
+
+// middleware refuses calls to mutating tools outside the maintenance
+// windows of the target namespace, unless an override was approved.
+func (g *windowGuard) middleware(t ToolInfo, next RunFunc) RunFunc {
+	if t.Category != CategoryMutating {
+		return next
+	}
+	return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		name := t.Name
+		namespace := targetNamespace(name, args)
+		check := g.policy.check(namespace, g.now(), g.loc)
+		if check.open || g.overridden(namespace) {
+			return next(ctx, args)
+		}
+
+		opens := "never (no window opens within a week)"
+		if !check.next.IsZero() {
+			opens = check.next.Format("Mon 2006-01-02 15:04 MST")
+		}
+		return map[string]any{
+			"error":         fmt.Sprintf("policy: %s may only be changed during its maintenance windows (%s)", describeNamespace(namespace), strings.Join(check.windows, "; ")),
+			"window_closed": true,
+			"tool":          name,
+			"namespace":     namespace,
+			"next_window":   opens,
+			"message": fmt.Sprintf("The change was NOT applied. Stop executing the plan and tell the user the next window opens %s; "+
+				"they can run /resume then. If the change cannot wait, ask the user for a justification and call request_window_override.", opens),
+		}, nil
+	}
+}
+
+func describeNamespace(namespace string) string {
+	if namespace == "" {
+		return "the cluster"
+	}
+	return fmt.Sprintf("namespace %q", namespace)
+}
+
+// RequestWindowOverrideTool provides the request_window_override tool for the agent.
+type RequestWindowOverrideTool struct {
+	guard *windowGuard
+}
+
+// NewRequestWindowOverrideTool creates a new RequestWindowOverrideTool.
+func NewRequestWindowOverrideTool(guard *windowGuard) *RequestWindowOverrideTool {
+	return &RequestWindowOverrideTool{
+		guard: guard,
+	}
+}
+
+// Name returns the tool name.
+func (t *RequestWindowOverrideTool) Name() string {
+	return "request_window_override"
+}
+
+// Description returns the tool description.
+func (t *RequestWindowOverrideTool) Description() string {
+	return "Ask the user to approve changing a namespace outside its maintenance window. Only use this after a mutating tool returned window_closed and the user has said the change cannot wait. The justification is recorded; nothing is applied until the user approves."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *RequestWindowOverrideTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *RequestWindowOverrideTool) Category() ToolCategory {
+	return CategoryPlanning
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *RequestWindowOverrideTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *RequestWindowOverrideTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The namespace whose maintenance window should be overridden (empty for cluster-scoped changes)",
+				},
+				"justification": {
+					Type:        "string",
+					Description: "Why the change cannot wait for the next window, in the user's words (e.g. 'incident INC-123: checkout is down')",
+				},
+			},
+			Required: []string{"justification"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *RequestWindowOverrideTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, _ := argsMap["namespace"].(string)
+	justification, _ := argsMap["justification"].(string)
+	justification = strings.TrimSpace(justification)
+	if justification == "" {
+		return map[string]any{"error": "justification is required"}, nil
+	}
+
+	check := t.guard.policy.check(namespace, t.guard.now(), t.guard.loc)
+	if check.open || t.guard.overridden(namespace) {
+		return map[string]any{"error": fmt.Sprintf("%s is not outside a maintenance window; no override is needed", describeNamespace(namespace))}, nil
+	}
+
+	return map[string]any{
+		"confirmation_required": true,
+		"confirmation":          "window_override",
+		"tool":                  t.Name(),
+		"namespace":             namespace,
+		"justification":         justification,
+		"message":               "Stop here. The override takes effect only if the user approves it.",
+	}, nil
+}
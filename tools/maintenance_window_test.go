@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// recordingTool is a mutating tool that counts how often it runs.
+type recordingTool struct {
+	name  string
+	calls int
+}
+
+func (t *recordingTool) Name() string           { return t.name }
+func (t *recordingTool) Description() string    { return "" }
+func (t *recordingTool) IsLongRunning() bool    { return false }
+func (t *recordingTool) Category() ToolCategory { return CategoryMutating }
+func (t *recordingTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{Name: t.name}
+}
+func (t *recordingTool) Run(tool.Context, any) (map[string]any, error) {
+	t.calls++
+	return map[string]any{"success": true}, nil
+}
+
+func TestMaintenancePolicyCheck(t *testing.T) {
+	policy := MaintenancePolicy{
+		Timezone: "Europe/Oslo",
+		Windows: []MaintenanceWindow{
+			{Namespaces: []string{"prod-*"}, Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00"},
+			{Namespaces: []string{"batch"}, Days: []string{"sat"}, Start: "22:00", End: "02:00"},
+		},
+	}
+	if err := policy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	loc, _ := policy.location()
+	at := func(s string) time.Time {
+		ts, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+
+	tests := []struct {
+		namespace string
+		now       string // 2026-10-16 is a Friday
+		open      bool
+		next      string
+	}{
+		{"prod-web", "2026-10-16 10:30", true, ""},
+		{"prod-web", "2026-10-16 17:00", false, "2026-10-19 09:00"},
+		{"prod-web", "2026-10-17 12:00", false, "2026-10-19 09:00"},
+		{"prod-web", "2026-10-19 08:59", false, "2026-10-19 09:00"},
+		{"staging", "2026-10-17 12:00", true, ""},
+		{"batch", "2026-10-17 23:00", true, ""},
+		{"batch", "2026-10-18 01:30", true, ""},
+		{"batch", "2026-10-18 02:00", false, "2026-10-24 22:00"},
+	}
+	for _, tt := range tests {
+		got := policy.check(tt.namespace, at(tt.now), loc)
+		if got.open != tt.open {
+			t.Errorf("%s at %s: open = %v, want %v", tt.namespace, tt.now, got.open, tt.open)
+			continue
+		}
+		if tt.next != "" && !got.next.Equal(at(tt.next)) {
+			t.Errorf("%s at %s: next = %v, want %s", tt.namespace, tt.now, got.next, tt.next)
+		}
+	}
+}
+
+func TestMaintenancePolicyValidate(t *testing.T) {
+	tests := []struct {
+		policy MaintenancePolicy
+		want   string
+	}{
+		{MaintenancePolicy{Timezone: "Mars/Olympus"}, "timezone"},
+		{MaintenancePolicy{Windows: []MaintenanceWindow{{Days: []string{"mon"}, Start: "09:00", End: "17:00"}}}, "namespaces must be set"},
+		{MaintenancePolicy{Windows: []MaintenanceWindow{{Namespaces: []string{"prod"}, Days: []string{"funday"}, Start: "09:00", End: "17:00"}}}, "unknown day"},
+		{MaintenancePolicy{Windows: []MaintenanceWindow{{Namespaces: []string{"prod"}, Start: "9am", End: "17:00"}}}, "start"},
+	}
+	for _, tt := range tests {
+		err := tt.policy.Validate()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Validate(%+v) = %v, want error containing %q", tt.policy, err, tt.want)
+		}
+	}
+}
+
+func TestWindowedTool(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "window_overrides.jsonl")
+	k := &KubeTools{}
+	err := k.SetMaintenanceWindows(MaintenancePolicy{
+		Timezone: "UTC",
+		Windows:  []MaintenanceWindow{{Namespaces: []string{"prod"}, Start: "09:00", End: "17:00"}},
+	}, logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+	k.windowGuard.now = func() time.Time { return now }
+
+	inner := &recordingTool{name: "scale_deployment"}
+	wrapped := withMiddleware([]tool.Tool{inner}, k.windowGuard.middleware)[0].(runnableTool)
+
+	result, _ := wrapped.Run(nil, map[string]any{"namespace": "prod", "name": "web"})
+	if closed, _ := result["window_closed"].(bool); !closed || inner.calls != 0 {
+		t.Fatalf("call outside the window should be refused, got %v", result)
+	}
+	if result["next_window"] != "Sat 2026-10-17 09:00 UTC" {
+		t.Errorf("next_window = %v", result["next_window"])
+	}
+	if _, err := wrapped.Run(nil, map[string]any{"namespace": "dev", "name": "web"}); err != nil || inner.calls != 1 {
+		t.Errorf("uncovered namespace should pass through")
+	}
+
+	request := NewRequestWindowOverrideTool(k.windowGuard)
+	result, _ = request.Run(nil, map[string]any{"namespace": "prod", "justification": "INC-42 checkout down"})
+	if result["confirmation_required"] != true || result["confirmation"] != "window_override" {
+		t.Fatalf("override request = %v", result)
+	}
+	if inner.calls != 1 {
+		t.Fatal("requesting an override must not grant it")
+	}
+
+	if err := k.GrantWindowOverride("prod", "INC-42 checkout down"); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = wrapped.Run(nil, map[string]any{"namespace": "prod", "name": "web"})
+	if inner.calls != 2 {
+		t.Error("approved override should let the call through")
+	}
+	logged, _ := os.ReadFile(logFile)
+	if !strings.Contains(string(logged), `"justification":"INC-42 checkout down"`) {
+		t.Errorf("override log = %s", logged)
+	}
+
+	now = now.Add(windowOverrideTTL)
+	if result, _ := wrapped.Run(nil, map[string]any{"namespace": "prod", "name": "web"}); result["window_closed"] != true {
+		t.Error("expired override should no longer apply")
+	}
+}
@@ -1,7 +1,6 @@
 package tools
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 
@@ -166,8 +165,9 @@ func (t *DeleteManifestTool) Run(ctx tool.Context, args any) (map[string]any, er
 }
 
 // deleteFromCluster deletes a resource from the Kubernetes cluster.
-func (t *DeleteManifestTool) deleteFromCluster(_ tool.Context, namespace, app, resourceType string) error {
-	goCtx := context.Background()
+func (t *DeleteManifestTool) deleteFromCluster(ctx tool.Context, namespace, app, resourceType string) error {
+	goCtx, cancel := apiContext(toolContext(ctx))
+	defer cancel()
 	deletePolicy := metav1.DeletePropagationForeground
 	deleteOptions := metav1.DeleteOptions{
 		PropagationPolicy: &deletePolicy,
@@ -0,0 +1,220 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/perbu/kasa/manifest"
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// EditManifestFieldTool provides the edit_manifest_field tool for the agent.
+type EditManifestFieldTool struct {
+	manifest *manifest.Manager
+}
+
+// NewEditManifestFieldTool creates a new EditManifestFieldTool.
+func NewEditManifestFieldTool(manifest *manifest.Manager) *EditManifestFieldTool {
+	return &EditManifestFieldTool{
+		manifest: manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *EditManifestFieldTool) Name() string {
+	return "edit_manifest_field"
+}
+
+// Description returns the tool description.
+func (t *EditManifestFieldTool) Description() string {
+	return "Set or remove a single field in a stored manifest by YAML path (e.g. spec.replicas or spec.template.spec.containers[name=web].image) without rewriting the rest of the file; comments and formatting are kept. The change is staged but not applied to the cluster; use apply_manifest to apply it and commit_manifests to commit it."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *EditManifestFieldTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *EditManifestFieldTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *EditManifestFieldTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *EditManifestFieldTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"app": {
+					Type:        "string",
+					Description: "The application name",
+				},
+				"type": {
+					Type:        "string",
+					Description: "The resource type (e.g., deployment, service)",
+				},
+				"path": {
+					Type:        "string",
+					Description: "YAML path of the field, e.g. spec.replicas, metadata.labels.tier or spec.template.spec.containers[0].image. Missing mapping keys are created; an index one past the end of a list appends.",
+				},
+				"value": {
+					Type:        "string",
+					Description: "The new value as YAML, e.g. 3, nginx:1.27 or {cpu: 100m, memory: 128Mi}. Required unless remove is set.",
+				},
+				"remove": {
+					Type:        "boolean",
+					Description: "Remove the field instead of setting it (default: false)",
+				},
+			},
+			Required: []string{"namespace", "app", "type", "path"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *EditManifestFieldTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+
+	app, ok := argsMap["app"].(string)
+	if !ok || app == "" {
+		return map[string]any{"error": "app is required"}, nil
+	}
+
+	resourceType, ok := argsMap["type"].(string)
+	if !ok || resourceType == "" {
+		return map[string]any{"error": "type is required"}, nil
+	}
+
+	yamlPath, ok := argsMap["path"].(string)
+	if !ok || yamlPath == "" {
+		return map[string]any{"error": "path is required"}, nil
+	}
+
+	remove, _ := argsMap["remove"].(bool)
+	value, hasValue := argsMap["value"].(string)
+	if !hasValue {
+		// Models sometimes send numbers and booleans unquoted
+		if v, ok := argsMap["value"]; ok && v != nil {
+			value, hasValue = fmt.Sprint(v), true
+		}
+	}
+	if !remove && !hasValue {
+		return map[string]any{"error": "value is required unless remove is set"}, nil
+	}
+
+	content, err := t.manifest.ReadManifest(namespace, app, resourceType)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	updated, previous, err := editManifestField(content, yamlPath, value, remove)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	if _, err := t.manifest.SaveManifest(namespace, app, resourceType, updated); err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to save manifest: %v", err)}, nil
+	}
+
+	result := map[string]any{
+		"success":   true,
+		"path":      filepath.Join(namespace, app, resourceType+".yaml"),
+		"yaml_path": yamlPath,
+	}
+	if previous != "" {
+		result["previous"] = previous
+	}
+
+	action := "Set"
+	if remove {
+		action = "Removed"
+	} else {
+		result["value"] = value
+	}
+	if t.manifest.IsDryRun() {
+		result["dry_run"] = true
+		result["message"] = fmt.Sprintf("Dry run: %s %s in %s/%s/%s.yaml (not saved)", strings.ToLower(action), yamlPath, namespace, app, resourceType)
+		return result, nil
+	}
+	result["message"] = fmt.Sprintf("%s %s in %s/%s/%s.yaml and staged the change; use apply_manifest to apply it to the cluster", action, yamlPath, namespace, app, resourceType)
+	return result, nil
+}
+
+// editManifestField sets or removes the value at yamlPath in a manifest,
+// keeping comments and the layout of the rest of the document. Returns the
+// updated content and the previous value as YAML (empty if the field was added).
+func editManifestField(content []byte, yamlPath, value string, remove bool) ([]byte, string, error) {
+	segs, err := parseYAMLPath(yamlPath)
+	if err != nil {
+		return nil, "", err
+	}
+	doc, root, err := parseYAMLDocument(content)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var old *yaml.Node
+	if remove {
+		old, err = removeYAMLPath(root, segs)
+	} else {
+		var node *yaml.Node
+		node, err = parseYAMLValue(value)
+		if err != nil {
+			return nil, "", err
+		}
+		old, err = setYAMLPath(root, segs, node)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	updated, err := encodeYAMLDocument(doc, usesCompactSequences(content))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	obj, err := ParseYAMLToUnstructured(updated)
+	if err != nil || obj.GetKind() == "" || obj.GetName() == "" {
+		return nil, "", fmt.Errorf("edit would leave the manifest without a kind or metadata.name")
+	}
+
+	previous := ""
+	if old != nil {
+		out, err := encodeYAMLNode(old)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode previous value: %w", err)
+		}
+		previous = strings.TrimSuffix(string(out), "\n")
+	}
+	return updated, previous, nil
+}
@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+const editManifestTestManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  labels:
+    app: web
+  name: web
+  namespace: default
+spec:
+  replicas: 2 # scaled for launch
+  template:
+    spec:
+      # main containers
+      containers:
+      - image: nginx:1.25
+        name: web
+        ports:
+        - containerPort: 80
+      - image: envoy:1.30
+        name: proxy
+        env:
+        - name: PORT
+          value: "8080"
+`
+
+func TestEditManifestFieldSet(t *testing.T) {
+	updated, previous, err := editManifestField([]byte(editManifestTestManifest), "spec.template.spec.containers[name=web].image", "nginx:1.27", false)
+	if err != nil {
+		t.Fatalf("editManifestField: %v", err)
+	}
+	if previous != "nginx:1.25" {
+		t.Errorf("previous = %q, want nginx:1.25", previous)
+	}
+	want := strings.Replace(editManifestTestManifest, "nginx:1.25", "nginx:1.27", 1)
+	if string(updated) != want {
+		t.Errorf("only the image should change, got:\n%s", updated)
+	}
+
+	updated, _, err = editManifestField([]byte(editManifestTestManifest), "spec.replicas", "5", false)
+	if err != nil {
+		t.Fatalf("editManifestField: %v", err)
+	}
+	if !strings.Contains(string(updated), "replicas: 5 # scaled for launch\n") {
+		t.Errorf("line comment should be kept, got:\n%s", updated)
+	}
+
+	// Strings stay strings
+	updated, _, err = editManifestField([]byte(editManifestTestManifest), "spec.template.spec.containers[1].env[name=PORT].value", "9090", false)
+	if err != nil {
+		t.Fatalf("editManifestField: %v", err)
+	}
+	if !strings.Contains(string(updated), `value: "9090"`) {
+		t.Errorf("quoted string value should stay quoted, got:\n%s", updated)
+	}
+}
+
+func TestEditManifestFieldAdd(t *testing.T) {
+	updated, previous, err := editManifestField([]byte(editManifestTestManifest), "spec.template.spec.containers[0].resources.limits", "{cpu: 500m, memory: 256Mi}", false)
+	if err != nil {
+		t.Fatalf("editManifestField: %v", err)
+	}
+	if previous != "" {
+		t.Errorf("previous = %q, want empty for a new field", previous)
+	}
+	if !strings.Contains(string(updated), "        resources:\n          limits:\n            cpu: 500m\n            memory: 256Mi\n") {
+		t.Errorf("new mapping should be written in block style, got:\n%s", updated)
+	}
+
+	updated, _, err = editManifestField([]byte(editManifestTestManifest), "spec.template.spec.containers[0].ports[1]", "{containerPort: 443}", false)
+	if err != nil {
+		t.Fatalf("editManifestField: %v", err)
+	}
+	if !strings.Contains(string(updated), "        - containerPort: 80\n        - containerPort: 443\n") {
+		t.Errorf("index past the end should append, got:\n%s", updated)
+	}
+
+	if _, _, err := editManifestField([]byte(editManifestTestManifest), "spec.template.spec.containers[5].image", "x", false); err == nil {
+		t.Error("setting under a missing list item should fail")
+	}
+}
+
+func TestEditManifestFieldRemove(t *testing.T) {
+	updated, previous, err := editManifestField([]byte(editManifestTestManifest), "spec.template.spec.containers[name=proxy]", "", true)
+	if err != nil {
+		t.Fatalf("editManifestField: %v", err)
+	}
+	if !strings.Contains(previous, "image: envoy:1.30") {
+		t.Errorf("previous should hold the removed container, got %q", previous)
+	}
+	if strings.Contains(string(updated), "proxy") || !strings.Contains(string(updated), "# main containers") {
+		t.Errorf("proxy container should be removed and comments kept, got:\n%s", updated)
+	}
+
+	if _, _, err := editManifestField([]byte(editManifestTestManifest), "spec.paused", "", true); err == nil {
+		t.Error("removing a missing field should fail")
+	}
+	if _, _, err := editManifestField([]byte(editManifestTestManifest), "metadata.name", "", true); err == nil {
+		t.Error("removing metadata.name should fail")
+	}
+}
+
+func TestUsesCompactSequences(t *testing.T) {
+	if !usesCompactSequences([]byte(editManifestTestManifest)) {
+		t.Error("manifest with '- ' at key indentation should be compact")
+	}
+	if usesCompactSequences([]byte("spec:\n  containers:\n    - name: web\n")) {
+		t.Error("manifest with indented list items should not be compact")
+	}
+	if !usesCompactSequences([]byte("kind: ConfigMap\n")) {
+		t.Error("manifest without lists should default to compact")
+	}
+}
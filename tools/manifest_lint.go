@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Lint severities.
+const (
+	lintError   = "error"
+	lintWarning = "warning"
+)
+
+// LintFinding is a likely mistake in a manifest that its schema allows.
+type LintFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+// podSpecPaths are where workload kinds keep their pod spec.
+var podSpecPaths = map[string][]string{
+	"Pod":         {"spec"},
+	"Deployment":  {"spec", "template", "spec"},
+	"StatefulSet": {"spec", "template", "spec"},
+	"DaemonSet":   {"spec", "template", "spec"},
+	"ReplicaSet":  {"spec", "template", "spec"},
+	"Job":         {"spec", "template", "spec"},
+	"CronJob":     {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// lintManifest checks a workload for common mistakes: images without a
+// pinned tag, containers without resource requests or memory limits, or
+// without probes, privileged containers, and a selector that doesn't match
+// the pod template.
+func lintManifest(obj map[string]any) []LintFinding {
+	kind, _ := obj["kind"].(string)
+	specPath, ok := podSpecPaths[kind]
+	if !ok {
+		return nil
+	}
+	var findings []LintFinding
+	add := func(rule, severity, path, format string, args ...any) {
+		findings = append(findings, LintFinding{Rule: rule, Severity: severity, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Probes make no sense for containers that are meant to finish
+	batch := kind == "Job" || kind == "CronJob"
+	specField := strings.Join(specPath, ".")
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _, _ := unstructured.NestedSlice(obj, append(append([]string{}, specPath...), field)...)
+		for i, c := range containers {
+			container, ok := c.(map[string]any)
+			if !ok {
+				continue
+			}
+			path := fmt.Sprintf("%s.%s[%d]", specField, field, i)
+			name, _ := container["name"].(string)
+
+			image, _ := container["image"].(string)
+			switch tag := imageTag(image); {
+			case image == "":
+			case tag == "latest":
+				add("latest-tag", lintWarning, path+".image", "container %s uses the latest tag; pin a version so rollouts are repeatable", name)
+			case tag == "" && !strings.Contains(image, "@"):
+				add("latest-tag", lintWarning, path+".image", "container %s has no image tag, which means latest; pin a version", name)
+			}
+
+			requests, _, _ := unstructured.NestedMap(container, "resources", "requests")
+			limits, _, _ := unstructured.NestedMap(container, "resources", "limits")
+			if len(requests) == 0 && len(limits) == 0 {
+				add("no-resources", lintWarning, path+".resources", "container %s sets no resource requests or limits, so the scheduler can't place it reliably and it can starve its neighbours", name)
+			} else if _, ok := limits["memory"]; !ok {
+				add("no-memory-limit", lintWarning, path+".resources.limits", "container %s has no memory limit", name)
+			}
+
+			if privileged, _, _ := unstructured.NestedBool(container, "securityContext", "privileged"); privileged {
+				add("privileged", lintWarning, path+".securityContext.privileged", "container %s runs privileged, with full access to the node", name)
+			}
+
+			if field == "containers" && !batch {
+				if _, ok := container["readinessProbe"]; !ok {
+					add("no-readiness-probe", lintWarning, path, "container %s has no readinessProbe, so it gets traffic before it is ready", name)
+				}
+				if _, ok := container["livenessProbe"]; !ok {
+					add("no-liveness-probe", lintWarning, path, "container %s has no livenessProbe, so a hung process is never restarted", name)
+				}
+			}
+		}
+	}
+
+	// The API server rejects this too, but only when the object is applied
+	if kind == "Deployment" || kind == "StatefulSet" || kind == "ReplicaSet" || kind == "DaemonSet" {
+		selector, _, _ := unstructured.NestedStringMap(obj, "spec", "selector", "matchLabels")
+		labels, _, _ := unstructured.NestedStringMap(obj, "spec", "template", "metadata", "labels")
+		for key, value := range selector {
+			if labels[key] != value {
+				add("selector-mismatch", lintError, "spec.selector.matchLabels", "the selector %s=%s doesn't match the pod template labels", key, value)
+			}
+		}
+	}
+	return findings
+}
+
+// imageTag returns the tag of an image reference, or "" if it has none.
+func imageTag(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	// A colon before the last slash belongs to a registry port
+	name := image[strings.LastIndex(image, "/")+1:]
+	if _, tag, ok := strings.Cut(name, ":"); ok {
+		return tag
+	}
+	return ""
+}
@@ -1,23 +1,49 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/perbu/kasa/manifest"
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/tool"
 	"google.golang.org/genai"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
 )
 
+// ManifestStatus is a stored manifest enriched with its git history, whether
+// the resource exists in the cluster and its last known drift status.
+type ManifestStatus struct {
+	manifest.ManifestInfo
+	LastCommit   *manifest.CommitInfo `json:"last_commit,omitempty"`
+	Uncommitted  string               `json:"uncommitted,omitempty"` // git status code, e.g. "A " or " M"
+	Exists       *bool                `json:"exists,omitempty"`
+	ClusterError string               `json:"cluster_error,omitempty"`
+	Drift        string               `json:"drift,omitempty"` // "in_sync", "drifted", "missing", "error"
+	DriftAge     string               `json:"drift_checked_ago,omitempty"`
+}
+
 // ListManifestsTool provides the list_manifests tool for the agent.
 type ListManifestsTool struct {
-	manifest *manifest.Manager
+	manifest      *manifest.Manager
+	dynamicClient dynamic.Interface // nil skips the cluster existence check
+	resolver      *GVRResolver
+	drift         *driftCache
 }
 
 // NewListManifestsTool creates a new ListManifestsTool.
-func NewListManifestsTool(manifest *manifest.Manager) *ListManifestsTool {
+func NewListManifestsTool(manifest *manifest.Manager, dynamicClient dynamic.Interface, resolver *GVRResolver, drift *driftCache) *ListManifestsTool {
 	return &ListManifestsTool{
-		manifest: manifest,
+		manifest:      manifest,
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+		drift:         drift,
 	}
 }
 
@@ -28,7 +54,7 @@ func (t *ListManifestsTool) Name() string {
 
 // Description returns the tool description.
 func (t *ListManifestsTool) Description() string {
-	return "List manifest files stored in the deployments directory. Can filter by namespace and/or app name."
+	return "List manifest files stored in the deployments directory, with each manifest's last commit, uncommitted changes, whether the resource exists in the cluster, and its drift status from the last drift check. Can filter by namespace and/or app name."
 }
 
 // IsLongRunning returns false as this is a quick operation.
@@ -62,6 +88,10 @@ func (t *ListManifestsTool) Declaration() *genai.FunctionDeclaration {
 					Type:        "string",
 					Description: "Filter by application name (optional)",
 				},
+				"include_status": {
+					Type:        "boolean",
+					Description: "Include git history, cluster existence and drift status (default: true). Set to false for a quick file listing",
+				},
 			},
 		},
 	}
@@ -84,6 +114,10 @@ func (t *ListManifestsTool) Run(ctx tool.Context, args any) (map[string]any, err
 	// Extract optional filters
 	namespace, _ := argsMap["namespace"].(string)
 	app, _ := argsMap["app"].(string)
+	includeStatus := true
+	if v, ok := argsMap["include_status"].(bool); ok {
+		includeStatus = v
+	}
 
 	// List manifests
 	manifests, err := t.manifest.ListManifests(namespace, app)
@@ -93,8 +127,115 @@ func (t *ListManifestsTool) Run(ctx tool.Context, args any) (map[string]any, err
 		}, nil
 	}
 
-	return map[string]any{
-		"manifests": manifests,
-		"count":     len(manifests),
-	}, nil
+	if !includeStatus {
+		return map[string]any{
+			"manifests": manifests,
+			"count":     len(manifests),
+		}, nil
+	}
+
+	statuses, warnings := t.manifestStatuses(toolContext(ctx), manifests)
+	result := map[string]any{
+		"manifests": statuses,
+		"count":     len(statuses),
+	}
+	if len(warnings) > 0 {
+		result["warnings"] = warnings
+	}
+	return result, nil
+}
+
+// manifestStatuses enriches manifests with git and cluster status. Git
+// failures are reported as warnings rather than failing the listing.
+func (t *ListManifestsTool) manifestStatuses(ctx context.Context, manifests []manifest.ManifestInfo) ([]ManifestStatus, []string) {
+	var warnings []string
+
+	commits, err := t.manifest.LastCommits()
+	if err != nil {
+		warnings = append(warnings, err.Error())
+	}
+	uncommitted, err := t.manifest.UncommittedPaths()
+	if err != nil {
+		warnings = append(warnings, err.Error())
+	}
+
+	statuses := make([]ManifestStatus, len(manifests))
+	for i, m := range manifests {
+		s := ManifestStatus{ManifestInfo: m}
+		gitPath := filepath.ToSlash(m.Path)
+		if c, ok := commits[gitPath]; ok {
+			s.LastCommit = &c
+		}
+		s.Uncommitted = uncommitted[gitPath]
+		if cd, ok := t.drift.lookup(m.Namespace, m.App, m.Type); ok {
+			s.Drift = cd.result.Status
+			s.DriftAge = time.Since(cd.checked).Round(time.Second).String()
+		}
+		statuses[i] = s
+	}
+
+	if t.dynamicClient == nil {
+		return statuses, warnings
+	}
+
+	// Check existence concurrently; each lookup is one GET
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(driftScanWorkers, len(statuses)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				exists, err := t.resourceExists(ctx, statuses[i].ManifestInfo)
+				if err != nil {
+					statuses[i].ClusterError = err.Error()
+					continue
+				}
+				statuses[i].Exists = &exists
+			}
+		}()
+	}
+	for i := range statuses {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return statuses, warnings
+}
+
+// resourceExists reports whether the resource described by a stored manifest
+// exists in the cluster. Like drift detection, the resource is identified by
+// the manifest's app name and type, using the stored apiVersion if present.
+func (t *ListManifestsTool) resourceExists(ctx context.Context, m manifest.ManifestInfo) (bool, error) {
+	var apiVersion string
+	if content, err := t.manifest.ReadManifest(m.Namespace, m.App, m.Type); err == nil {
+		var header struct {
+			APIVersion string `json:"apiVersion"`
+		}
+		if yaml.Unmarshal(content, &header) == nil {
+			apiVersion = header.APIVersion
+		}
+	}
+
+	gvr, err := t.resolver.Resolve(m.Type, apiVersion)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var resourceClient dynamic.ResourceInterface = t.dynamicClient.Resource(gvr)
+	if t.resolver.IsNamespaced(gvr, m.Type) {
+		resourceClient = t.dynamicClient.Resource(gvr).Namespace(m.Namespace)
+	}
+	_, err = resourceClient.Get(ctx, m.App, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
 }
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestListManifestsStatus(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	for _, name := range []string{"live", "gone"} {
+		if _, err := mgr.SaveManifest("default", name, "configmap", []byte(configMapYAML(name, "stored"))); err != nil {
+			t.Fatalf("SaveManifest: %v", err)
+		}
+	}
+	if err := mgr.Commit("Add config maps"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if _, err := mgr.SaveManifest("default", "live", "configmap", []byte(configMapYAML("live", "edited"))); err != nil {
+		t.Fatalf("SaveManifest: %v", err)
+	}
+
+	dynClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), newConfigMap("live", "changed"))
+	drift := newDriftCache()
+	drift.record(DriftResult{Namespace: "default", Name: "live", Kind: "configmap", Status: "drifted"})
+
+	tool := NewListManifestsTool(mgr, dynClient, nil, drift)
+	result, err := tool.Run(nil, map[string]any{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w, ok := result["warnings"]; ok {
+		t.Errorf("unexpected warnings: %v", w)
+	}
+
+	statuses, ok := result["manifests"].([]ManifestStatus)
+	if !ok || len(statuses) != 2 {
+		t.Fatalf("expected 2 manifest statuses, got %#v", result["manifests"])
+	}
+	byApp := map[string]ManifestStatus{}
+	for _, s := range statuses {
+		byApp[s.App] = s
+	}
+
+	live := byApp["live"]
+	if live.LastCommit == nil || live.LastCommit.Message != "Add config maps" || live.LastCommit.Hash == "" || live.LastCommit.Date.IsZero() {
+		t.Errorf("live: last_commit = %+v", live.LastCommit)
+	}
+	if live.Uncommitted == "" {
+		t.Error("live: staged edit should be reported as uncommitted")
+	}
+	if live.Exists == nil || !*live.Exists {
+		t.Errorf("live: exists = %v, want true", live.Exists)
+	}
+	if live.Drift != "drifted" || live.DriftAge == "" {
+		t.Errorf("live: drift = %q (%q), want drifted", live.Drift, live.DriftAge)
+	}
+
+	gone := byApp["gone"]
+	if gone.Exists == nil || *gone.Exists {
+		t.Errorf("gone: exists = %v, want false", gone.Exists)
+	}
+	if gone.Uncommitted != "" || gone.Drift != "" {
+		t.Errorf("gone: uncommitted = %q, drift = %q, want both empty", gone.Uncommitted, gone.Drift)
+	}
+
+	// A plain listing skips the enrichment
+	result, _ = tool.Run(nil, map[string]any{"include_status": false})
+	if _, ok := result["manifests"].([]ManifestStatus); ok {
+		t.Error("include_status=false should return the plain listing")
+	}
+}
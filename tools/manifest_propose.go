@@ -0,0 +1,214 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	"sigs.k8s.io/yaml"
+)
+
+// EditManifestTool provides the edit_manifest tool for the agent.
+type EditManifestTool struct {
+	manifest *manifest.Manager
+}
+
+// NewEditManifestTool creates a new EditManifestTool.
+func NewEditManifestTool(manifest *manifest.Manager) *EditManifestTool {
+	return &EditManifestTool{
+		manifest: manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *EditManifestTool) Name() string {
+	return "edit_manifest"
+}
+
+// Description returns the tool description.
+func (t *EditManifestTool) Description() string {
+	return "Propose new content for a stored manifest. The user reviews and can change it in their editor; it is saved and staged only if they accept it, and the outcome arrives as the response to this call. Use it for changes spanning several fields; use edit_manifest_field for a single field. The change is not applied to the cluster."
+}
+
+// IsLongRunning returns true: the user's decision arrives later, as a new
+// response to the same call.
+func (t *EditManifestTool) IsLongRunning() bool {
+	return true
+}
+
+// Category returns the tool category.
+func (t *EditManifestTool) Category() ToolCategory {
+	return CategoryPlanning
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *EditManifestTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *EditManifestTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"app": {
+					Type:        "string",
+					Description: "The application name",
+				},
+				"type": {
+					Type:        "string",
+					Description: "The resource type (e.g., deployment, service)",
+				},
+				"content": {
+					Type:        "string",
+					Description: "The complete new manifest as YAML",
+				},
+				"reason": {
+					Type:        "string",
+					Description: "What the change does, shown to the user",
+				},
+			},
+			Required: []string{"namespace", "app", "type", "content"},
+		},
+	}
+}
+
+// Run executes the tool. Like ask_clarification it does not block: it checks
+// the proposal and returns a status saying the user is reviewing it. The REPL
+// sends the outcome as the final response to the call, with status "saved"
+// (and the content if the user changed it) or "declined".
+func (t *EditManifestTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+
+	app, ok := argsMap["app"].(string)
+	if !ok || app == "" {
+		return map[string]any{"error": "app is required"}, nil
+	}
+
+	resourceType, ok := argsMap["type"].(string)
+	if !ok || resourceType == "" {
+		return map[string]any{"error": "type is required"}, nil
+	}
+
+	content, ok := argsMap["content"].(string)
+	if !ok || strings.TrimSpace(content) == "" {
+		return map[string]any{"error": "content is required"}, nil
+	}
+
+	stored, err := t.manifest.ReadManifest(namespace, app, resourceType)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+	if err := validateManifest([]byte(content), stored, namespace); err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	return map[string]any{
+		"status":  "awaiting_review",
+		"path":    filepath.Join(namespace, app, resourceType+".yaml"),
+		"message": "The proposal is shown to the user. Their decision will follow as the response to this call; wait for it before applying anything.",
+	}, nil
+}
+
+// validateManifest checks that content is a single Kubernetes object that
+// replaces stored: same kind and name, and in the manifest's namespace.
+func validateManifest(content, stored []byte, namespace string) error {
+	var obj map[string]any
+	if err := yaml.Unmarshal(content, &obj); err != nil {
+		return fmt.Errorf("invalid YAML: %w", err)
+	}
+	if len(obj) == 0 {
+		return fmt.Errorf("the manifest is empty")
+	}
+	kind, _ := obj["kind"].(string)
+	meta, _ := obj["metadata"].(map[string]any)
+	name, _ := meta["name"].(string)
+	if kind == "" || name == "" {
+		return fmt.Errorf("the manifest needs kind and metadata.name")
+	}
+	if ns, _ := meta["namespace"].(string); ns != "" && ns != namespace {
+		return fmt.Errorf("metadata.namespace is %q but the manifest is stored under %q", ns, namespace)
+	}
+
+	var old map[string]any
+	if yaml.Unmarshal(stored, &old) != nil {
+		return nil
+	}
+	oldKind, _ := old["kind"].(string)
+	oldMeta, _ := old["metadata"].(map[string]any)
+	oldName, _ := oldMeta["name"].(string)
+	if oldKind != "" && oldKind != kind {
+		return fmt.Errorf("kind changed from %s to %s; create a new manifest instead", oldKind, kind)
+	}
+	if oldName != "" && oldName != name {
+		return fmt.Errorf("name changed from %s to %s; create a new manifest instead", oldName, name)
+	}
+	return nil
+}
+
+// ReadManifest returns a stored manifest for the REPL's /edit.
+func (k *KubeTools) ReadManifest(namespace, app, resourceType string) ([]byte, error) {
+	if k.namespaceGuard != nil && !k.namespaceGuard.policy.Allows(namespace) {
+		return nil, fmt.Errorf("namespace %q is outside the namespace policy", namespace)
+	}
+	return k.manifest.ReadManifest(namespace, app, resourceType)
+}
+
+// SaveManifest validates an edited manifest against the stored one, writes
+// it and stages it in git.
+func (k *KubeTools) SaveManifest(namespace, app, resourceType string, content []byte) (string, error) {
+	stored, err := k.ReadManifest(namespace, app, resourceType)
+	if err != nil {
+		return "", err
+	}
+	if err := validateManifest(content, stored, namespace); err != nil {
+		return "", err
+	}
+	if _, err := k.manifest.SaveManifest(namespace, app, resourceType, content); err != nil {
+		return "", err
+	}
+	return filepath.Join(namespace, app, resourceType+".yaml"), nil
+}
+
+// Manifests returns the stored manifests as namespace/app/type, for
+// completing /edit.
+func (k *KubeTools) Manifests() []string {
+	if k.manifest == nil {
+		return nil
+	}
+	manifests, err := k.manifest.ListManifests("", "")
+	if err != nil {
+		return nil
+	}
+	names := make([]string, len(manifests))
+	for i, m := range manifests {
+		names[i] = m.Namespace + "/" + m.App + "/" + m.Type
+	}
+	return names
+}
@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateManifest(t *testing.T) {
+	stored := []byte(editManifestTestManifest)
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"unchanged", editManifestTestManifest, ""},
+		{"edited", strings.Replace(editManifestTestManifest, "replicas: 2", "replicas: 4", 1), ""},
+		{"invalid", "kind: [Deployment\n", "invalid YAML"},
+		{"empty", "# nothing\n", "empty"},
+		{"no name", "kind: Deployment\n", "metadata.name"},
+		{"other namespace", strings.Replace(editManifestTestManifest, "namespace: default", "namespace: prod", 1), "stored under"},
+		{"other kind", strings.Replace(editManifestTestManifest, "kind: Deployment", "kind: StatefulSet", 1), "kind changed"},
+		{"renamed", strings.Replace(editManifestTestManifest, "  name: web\n  namespace", "  name: api\n  namespace", 1), "name changed"},
+	}
+	for _, tt := range tests {
+		err := validateManifest([]byte(tt.content), stored, "default")
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestEditManifestTool(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	if _, err := mgr.SaveManifest("default", "web", "deployment", []byte(editManifestTestManifest)); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewEditManifestTool(mgr)
+
+	proposed := strings.Replace(editManifestTestManifest, "replicas: 2", "replicas: 4", 1)
+	result, _ := tool.Run(nil, map[string]any{"namespace": "default", "app": "web", "type": "deployment", "content": proposed})
+	if result["status"] != "awaiting_review" {
+		t.Fatalf("expected the proposal to await review, got %v", result)
+	}
+	content, _ := mgr.ReadManifest("default", "web", "deployment")
+	if string(content) != editManifestTestManifest {
+		t.Error("the proposal must not be saved before the user accepts it")
+	}
+
+	result, _ = tool.Run(nil, map[string]any{"namespace": "default", "app": "api", "type": "deployment", "content": proposed})
+	if result["error"] == nil {
+		t.Errorf("expected an error for a manifest that isn't stored, got %v", result)
+	}
+}
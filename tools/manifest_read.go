@@ -2,7 +2,9 @@ package tools
 
 import (
 	"encoding/json"
+	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/perbu/kasa/manifest"
 	"google.golang.org/adk/model"
@@ -10,6 +12,10 @@ import (
 	"google.golang.org/genai"
 )
 
+// readManifestMaxLines is returned when no line window is requested, so a
+// huge manifest doesn't flood the context.
+const readManifestMaxLines = 400
+
 // ReadManifestTool provides the read_manifest tool for the agent.
 type ReadManifestTool struct {
 	manifest *manifest.Manager
@@ -29,7 +35,7 @@ func (t *ReadManifestTool) Name() string {
 
 // Description returns the tool description.
 func (t *ReadManifestTool) Description() string {
-	return "Read the content of a specific manifest file from the deployments directory."
+	return "Read the content of a specific manifest file from the deployments directory. For large manifests, use path to extract one field (e.g. spec.template or data) and offset/limit to read a range of lines; total_lines tells how long the content is."
 }
 
 // IsLongRunning returns false as this is a quick operation.
@@ -67,6 +73,18 @@ func (t *ReadManifestTool) Declaration() *genai.FunctionDeclaration {
 					Type:        "string",
 					Description: "The resource type (e.g., deployment, service)",
 				},
+				"path": {
+					Type:        "string",
+					Description: "Only return the value at this YAML path, e.g. spec.template, spec.template.spec.containers[0] or spec.template.spec.containers[name=web] (optional)",
+				},
+				"offset": {
+					Type:        "integer",
+					Description: "Line number to start reading from, 1-based (optional)",
+				},
+				"limit": {
+					Type:        "integer",
+					Description: fmt.Sprintf("Number of lines to return (optional, default: %d)", readManifestMaxLines),
+				},
 			},
 			Required: []string{"namespace", "app", "type"},
 		},
@@ -112,9 +130,70 @@ func (t *ReadManifestTool) Run(ctx tool.Context, args any) (map[string]any, erro
 	}
 
 	relPath := filepath.Join(namespace, app, resourceType+".yaml")
+	result := map[string]any{
+		"path": relPath,
+	}
+
+	if yamlPath, _ := argsMap["path"].(string); yamlPath != "" {
+		content, err = extractYAMLPath(content, yamlPath)
+		if err != nil {
+			return map[string]any{"error": err.Error()}, nil
+		}
+		result["yaml_path"] = yamlPath
+	}
 
-	return map[string]any{
-		"content": string(content),
-		"path":    relPath,
-	}, nil
+	offset := 1
+	if o, ok := argsMap["offset"].(float64); ok && o > 1 {
+		offset = int(o)
+	}
+	limit := readManifestMaxLines
+	if l, ok := argsMap["limit"].(float64); ok && l > 0 {
+		limit = int(l)
+	}
+
+	chunk, total, end := lineWindow(string(content), offset, limit)
+	result["content"] = chunk
+	result["total_lines"] = total
+	if offset > 1 || end < total {
+		result["start_line"] = offset
+		result["end_line"] = end
+	}
+	if end < total {
+		result["truncated"] = true
+		result["hint"] = fmt.Sprintf("Showing lines %d-%d of %d; use offset=%d to continue, or path to extract a field", offset, end, total, end+1)
+	}
+
+	return result, nil
+}
+
+// extractYAMLPath returns the YAML for the value at yamlPath in content.
+func extractYAMLPath(content []byte, yamlPath string) ([]byte, error) {
+	segs, err := parseYAMLPath(yamlPath)
+	if err != nil {
+		return nil, err
+	}
+	_, root, err := parseYAMLDocument(content)
+	if err != nil {
+		return nil, err
+	}
+	node, err := lookupYAMLPath(root, segs)
+	if err != nil {
+		return nil, err
+	}
+	return encodeYAMLNode(node)
+}
+
+// lineWindow returns up to limit lines of content starting at the 1-based
+// line offset, the total number of lines and the last line returned.
+func lineWindow(content string, offset, limit int) (string, int, int) {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	total := len(lines)
+	if offset > total {
+		return "", total, total
+	}
+	end := min(offset-1+limit, total)
+	return strings.Join(lines[offset-1:end], ""), total, end
 }
@@ -0,0 +1,461 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/util/homedir"
+	"sigs.k8s.io/yaml"
+)
+
+// maxSchemaErrors caps the schema errors reported per document.
+const maxSchemaErrors = 50
+
+// documentSeparator splits multi-document YAML.
+var documentSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// ManifestValidation is the outcome of validating one YAML document.
+type ManifestValidation struct {
+	Source       string        `json:"source,omitempty"` // stored manifest path
+	Kind         string        `json:"kind,omitempty"`
+	Name         string        `json:"name,omitempty"`
+	Valid        bool          `json:"valid"`
+	SchemaErrors []string      `json:"schema_errors,omitempty"`
+	SchemaNote   string        `json:"schema_note,omitempty"` // why the schema wasn't or couldn't fully be checked
+	Lint         []LintFinding `json:"lint,omitempty"`
+}
+
+// openAPISchemas fetches OpenAPI documents from the cluster once per group
+// version, and keeps a copy on disk for when the cluster is unreachable.
+type openAPISchemas struct {
+	discovery discovery.DiscoveryInterface
+	dir       string // "" keeps no copies
+
+	mu   sync.Mutex
+	docs map[schema.GroupVersion]*openAPIDoc
+}
+
+// get returns the document of gv and whether it is a copy from disk.
+func (s *openAPISchemas) get(gv schema.GroupVersion) (*openAPIDoc, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if doc, ok := s.docs[gv]; ok {
+		return doc, false, nil
+	}
+
+	file := ""
+	if s.dir != "" {
+		name := gv.Group + "_" + gv.Version + ".json"
+		if gv.Group == "" {
+			name = "core_" + gv.Version + ".json"
+		}
+		file = filepath.Join(s.dir, name)
+	}
+
+	var raw []byte
+	var fetchErr error
+	if s.discovery != nil {
+		raw, fetchErr = openAPISchema(s.discovery, gv)
+	} else {
+		fetchErr = fmt.Errorf("no cluster connection")
+	}
+	fromDisk := false
+	if fetchErr != nil {
+		if file == "" {
+			return nil, false, fetchErr
+		}
+		var err error
+		if raw, err = os.ReadFile(file); err != nil {
+			return nil, false, fetchErr
+		}
+		fromDisk = true
+	}
+
+	var doc openAPIDoc
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, false, fmt.Errorf("failed to parse OpenAPI schema: %v", err)
+	}
+	if !fromDisk {
+		if s.docs == nil {
+			s.docs = make(map[schema.GroupVersion]*openAPIDoc)
+		}
+		s.docs[gv] = &doc
+		// Best effort: the copy only matters once the cluster is unreachable
+		if file != "" && os.MkdirAll(s.dir, 0755) == nil {
+			_ = os.WriteFile(file, raw, 0644)
+		}
+	}
+	return &doc, fromDisk, nil
+}
+
+// ValidateManifestTool provides the validate_manifest tool for the agent.
+type ValidateManifestTool struct {
+	manifest *manifest.Manager
+	schemas  *openAPISchemas
+}
+
+// NewValidateManifestTool creates a new ValidateManifestTool.
+func NewValidateManifestTool(disc discovery.DiscoveryInterface, manifest *manifest.Manager) *ValidateManifestTool {
+	dir := ""
+	if home := homedir.HomeDir(); home != "" {
+		dir = filepath.Join(home, ".kasa", "openapi")
+	}
+	return &ValidateManifestTool{
+		manifest: manifest,
+		schemas:  &openAPISchemas{discovery: disc, dir: dir},
+	}
+}
+
+// Name returns the tool name.
+func (t *ValidateManifestTool) Name() string {
+	return "validate_manifest"
+}
+
+// Description returns the tool description.
+func (t *ValidateManifestTool) Description() string {
+	return "Validate YAML, or stored manifests, without sending them to the API server: checks every document against the cluster's OpenAPI schema " +
+		"(including CRDs; unknown fields, wrong types, missing required fields, invalid enum values) and lints workloads for common mistakes " +
+		"(latest or missing image tag, no resource requests or limits, no probes, privileged containers, selector not matching the template). " +
+		"Works where dry_run_apply can't, e.g. for resources that exist already or when the dry-run is unavailable; schemas are kept on disk for when the cluster is unreachable."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *ValidateManifestTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *ValidateManifestTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *ValidateManifestTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *ValidateManifestTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"yaml": {
+					Type:        "string",
+					Description: "YAML to validate; may hold several documents separated by ---. Either yaml or namespace is required.",
+				},
+				"namespace": {
+					Type:        "string",
+					Description: "Validate the stored manifests of this namespace",
+				},
+				"app": {
+					Type:        "string",
+					Description: "Only validate the stored manifests of this app",
+				},
+				"type": {
+					Type:        "string",
+					Description: "Only validate the stored manifest of this resource type, e.g. 'deployment' (requires app)",
+				},
+			},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *ValidateManifestTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	content, _ := argsMap["yaml"].(string)
+	namespace, _ := argsMap["namespace"].(string)
+	app, _ := argsMap["app"].(string)
+	resourceType, _ := argsMap["type"].(string)
+	if (content == "") == (namespace == "") {
+		return map[string]any{"error": "exactly one of yaml and namespace is required"}, nil
+	}
+	if resourceType != "" && app == "" {
+		return map[string]any{"error": "type requires app"}, nil
+	}
+
+	var results []ManifestValidation
+	if content != "" {
+		results = t.validate(content, "")
+	} else {
+		infos, err := t.manifest.ListManifests(namespace, app)
+		if err != nil {
+			return map[string]any{"error": err.Error()}, nil
+		}
+		for _, info := range infos {
+			if resourceType != "" && info.Type != resourceType {
+				continue
+			}
+			data, err := t.manifest.ReadManifest(info.Namespace, info.App, info.Type)
+			if err != nil {
+				results = append(results, ManifestValidation{Source: info.Path, SchemaErrors: []string{err.Error()}})
+				continue
+			}
+			results = append(results, t.validate(string(data), info.Path)...)
+		}
+		if len(results) == 0 {
+			return map[string]any{"error": fmt.Sprintf("no stored manifests match %s", filepath.Join(namespace, app, resourceType))}, nil
+		}
+	}
+
+	invalid, warnings := 0, 0
+	for _, r := range results {
+		if !r.Valid {
+			invalid++
+		}
+		for _, f := range r.Lint {
+			if f.Severity == lintWarning {
+				warnings++
+			}
+		}
+	}
+	result := map[string]any{
+		"valid":     invalid == 0,
+		"documents": results,
+		"invalid":   invalid,
+		"warnings":  warnings,
+	}
+	if invalid == 0 && warnings == 0 {
+		result["message"] = fmt.Sprintf("%d document(s) valid, no lint findings", len(results))
+	}
+	return result, nil
+}
+
+// validate checks each document of content against its schema and lints it.
+func (t *ValidateManifestTool) validate(content, source string) []ManifestValidation {
+	var results []ManifestValidation
+	for _, document := range documentSeparator.Split(content, -1) {
+		if strings.TrimSpace(document) == "" {
+			continue
+		}
+		r := ManifestValidation{Source: source}
+		var obj map[string]any
+		if err := yaml.Unmarshal([]byte(document), &obj); err != nil {
+			r.SchemaErrors = []string{fmt.Sprintf("invalid YAML: %v", err)}
+			results = append(results, r)
+			continue
+		}
+		if obj == nil {
+			continue // only comments
+		}
+		r.Kind, _ = obj["kind"].(string)
+		r.Name, _, _ = unstructured.NestedString(obj, "metadata", "name")
+		apiVersion, _ := obj["apiVersion"].(string)
+
+		var errs []string
+		switch {
+		case apiVersion == "" || r.Kind == "":
+			errs = append(errs, "apiVersion and kind are required")
+		default:
+			gv, err := schema.ParseGroupVersion(apiVersion)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("invalid apiVersion %q", apiVersion))
+				break
+			}
+			doc, fromDisk, err := t.schemas.get(gv)
+			if err != nil {
+				r.SchemaNote = fmt.Sprintf("schema not checked: %v", err)
+				break
+			}
+			if fromDisk {
+				r.SchemaNote = "checked against a saved copy of the schema, as the cluster couldn't be reached"
+			}
+			node := doc.findKind(gv.WithKind(r.Kind))
+			if node == nil {
+				errs = append(errs, fmt.Sprintf("the cluster doesn't serve %s in %s (is the CRD installed?)", r.Kind, apiVersion))
+				break
+			}
+			doc.validate(node, obj, "", &errs)
+			if len(errs) >= maxSchemaErrors {
+				errs = append(errs[:maxSchemaErrors], "more errors left out")
+			}
+		}
+		if r.Name == "" && r.Kind != "" {
+			errs = append(errs, "metadata.name is required")
+		}
+		r.SchemaErrors = errs
+		r.Lint = lintManifest(obj)
+		r.Valid = len(errs) == 0
+		for _, f := range r.Lint {
+			if f.Severity == lintError {
+				r.Valid = false
+			}
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// validate checks value against the schema node, kubeconform-style in
+// strict mode: fields the schema doesn't know are errors unless it
+// preserves unknown fields. Null values are accepted, as the API server
+// drops them.
+func (d *openAPIDoc) validate(node map[string]any, value any, path string, errs *[]string) {
+	if len(*errs) >= maxSchemaErrors || value == nil {
+		return
+	}
+	s := d.resolve(node)
+	if s == nil {
+		return
+	}
+	at := path
+	if at == "" {
+		at = "(root)"
+	}
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, at+": "+fmt.Sprintf(format, args...))
+	}
+
+	if s["x-kubernetes-int-or-string"] == true {
+		switch value.(type) {
+		case string, float64, int, int64:
+		default:
+			fail("must be an integer or a string, got %s", jsonType(value))
+		}
+		return
+	}
+	preserve := s["x-kubernetes-preserve-unknown-fields"] == true
+
+	switch typ, _ := s["type"].(string); typ {
+	case "object":
+		m, ok := value.(map[string]any)
+		if !ok {
+			fail("must be an object, got %s", jsonType(value))
+			return
+		}
+		props, _ := s["properties"].(map[string]any)
+		for _, key := range sortedKeys(m) {
+			child := joinPath(path, key)
+			if p, ok := props[key].(map[string]any); ok {
+				d.validate(p, m[key], child, errs)
+				continue
+			}
+			switch ap := s["additionalProperties"].(type) {
+			case map[string]any:
+				d.validate(ap, m[key], child, errs)
+			case bool:
+				if !ap && !preserve {
+					*errs = append(*errs, child+": unknown field")
+				}
+			default:
+				// An object schema without properties takes anything
+				if len(props) > 0 && !preserve {
+					*errs = append(*errs, child+": unknown field"+suggestField(key, props))
+				}
+			}
+		}
+		required, _ := s["required"].([]any)
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, present := m[name]; !present {
+					*errs = append(*errs, joinPath(path, name)+": required field is missing")
+				}
+			}
+		}
+	case "array":
+		list, ok := value.([]any)
+		if !ok {
+			fail("must be an array, got %s", jsonType(value))
+			return
+		}
+		if items, ok := s["items"].(map[string]any); ok {
+			for i, item := range list {
+				d.validate(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			fail("must be a string, got %s", jsonType(value))
+			return
+		}
+		if enum, ok := s["enum"].([]any); ok && len(enum) > 0 && !containsAny(enum, str) {
+			fail("%q is not one of %s", str, joinValues(enum))
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			fail("must be an integer, got %s", jsonType(value))
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			fail("must be a number, got %s", jsonType(value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be a boolean, got %s", jsonType(value))
+		}
+	}
+}
+
+// joinPath appends a field to a dotted path.
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// suggestField returns a hint at the known field a misspelled one was
+// probably meant to be, or "".
+func suggestField(field string, props map[string]any) string {
+	lower := strings.ToLower(field)
+	for _, name := range sortedKeys(props) {
+		candidate := strings.ToLower(name)
+		if candidate == lower || strings.TrimSuffix(candidate, "s") == lower || candidate == strings.TrimSuffix(lower, "s") {
+			return fmt.Sprintf(" (did you mean %q?)", name)
+		}
+	}
+	return ""
+}
+
+// jsonType names the JSON type of a value decoded from YAML.
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("string %q", v)
+	case float64, int, int64:
+		return fmt.Sprintf("number %v", v)
+	case bool:
+		return fmt.Sprintf("boolean %v", v)
+	case []any:
+		return "an array"
+	case map[string]any:
+		return "an object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// joinValues lists enum values for a message.
+func joinValues(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,206 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+func schemaErrors(t *testing.T, manifest string) []string {
+	t.Helper()
+	doc := loadTestOpenAPIDoc(t)
+	var obj map[string]any
+	if err := yaml.Unmarshal([]byte(manifest), &obj); err != nil {
+		t.Fatalf("failed to parse manifest: %v", err)
+	}
+	var errs []string
+	doc.validate(doc.findKind(deploymentGVK), obj, "", &errs)
+	return errs
+}
+
+func TestValidate_Valid(t *testing.T) {
+	errs := schemaErrors(t, `
+spec:
+  replicas: 2
+  selector: {}
+  template:
+    spec:
+      nodeSelector:
+        disk: ssd
+      containers:
+      - name: web
+        image: nginx:1.27
+`)
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
+
+func TestValidate_Errors(t *testing.T) {
+	errs := schemaErrors(t, `
+spec:
+  replica: 2
+  template:
+    spec:
+      containers:
+      - image: 1.27
+`)
+	want := []string{
+		`spec.replica: unknown field (did you mean "replicas"?)`,
+		"spec.template.spec.containers[0].image: must be a string, got number 1.27",
+		"spec.template.spec.containers[0].name: required field is missing",
+		"spec.selector: required field is missing",
+	}
+	if strings.Join(errs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected errors:\n%s\nwant:\n%s", strings.Join(errs, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestValidate_WrongTypes(t *testing.T) {
+	errs := schemaErrors(t, `
+spec:
+  replicas: "2"
+  selector: {}
+  template:
+    spec:
+      containers: web
+`)
+	if len(errs) != 2 ||
+		errs[0] != `spec.replicas: must be an integer, got string "2"` ||
+		errs[1] != "spec.template.spec.containers: must be an array, got string \"web\"" {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestLintManifest(t *testing.T) {
+	var obj map[string]any
+	if err := yaml.Unmarshal([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: frontend
+    spec:
+      containers:
+      - name: web
+        image: registry.example.com:5000/web
+      - name: sidecar
+        image: envoy:v1.31
+        resources:
+          requests:
+            cpu: 10m
+        readinessProbe: {}
+        livenessProbe: {}
+`), &obj); err != nil {
+		t.Fatal(err)
+	}
+
+	rules := map[string]string{}
+	for _, f := range lintManifest(obj) {
+		rules[f.Rule+" "+f.Path] = f.Severity
+	}
+	want := map[string]string{
+		"latest-tag spec.template.spec.containers[0].image":                 lintWarning,
+		"no-resources spec.template.spec.containers[0].resources":           lintWarning,
+		"no-readiness-probe spec.template.spec.containers[0]":               lintWarning,
+		"no-liveness-probe spec.template.spec.containers[0]":                lintWarning,
+		"no-memory-limit spec.template.spec.containers[1].resources.limits": lintWarning,
+		"selector-mismatch spec.selector.matchLabels":                       lintError,
+	}
+	if len(rules) != len(want) {
+		t.Errorf("unexpected findings: %v", rules)
+	}
+	for rule, severity := range want {
+		if rules[rule] != severity {
+			t.Errorf("expected %s finding %q, got %v", severity, rule, rules)
+		}
+	}
+}
+
+func TestLintManifest_Job(t *testing.T) {
+	obj := map[string]any{
+		"kind": "Job",
+		"spec": map[string]any{"template": map[string]any{"spec": map[string]any{
+			"containers": []any{map[string]any{
+				"name":      "migrate",
+				"image":     "migrate:2.0",
+				"resources": map[string]any{"limits": map[string]any{"memory": "128Mi"}},
+			}},
+		}}},
+	}
+	if findings := lintManifest(obj); len(findings) != 0 {
+		t.Errorf("expected no findings for a Job, got %+v", findings)
+	}
+	if findings := lintManifest(map[string]any{"kind": "ConfigMap"}); findings != nil {
+		t.Errorf("expected no findings for a ConfigMap, got %+v", findings)
+	}
+}
+
+func TestImageTag(t *testing.T) {
+	for image, want := range map[string]string{
+		"nginx":                           "",
+		"nginx:1.27":                      "1.27",
+		"localhost:5000/web":              "",
+		"localhost:5000/web:v2":           "v2",
+		"nginx@sha256:abc":                "",
+		"ghcr.io/org/app:1.0@sha256:abcd": "1.0",
+	} {
+		if got := imageTag(image); got != want {
+			t.Errorf("imageTag(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestOpenAPISchemas_DiskCopy(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "apps_v1.json"), []byte(testOpenAPIDoc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	schemas := &openAPISchemas{dir: dir}
+
+	doc, fromDisk, err := schemas.get(schema.GroupVersion{Group: "apps", Version: "v1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !fromDisk || doc.findKind(deploymentGVK) == nil {
+		t.Errorf("expected the saved Deployment schema, got fromDisk=%v", fromDisk)
+	}
+
+	if _, _, err := schemas.get(schema.GroupVersion{Version: "v1"}); err == nil {
+		t.Error("expected an error for a group version without a saved copy")
+	}
+}
+
+func TestValidateManifest_NoSchema(t *testing.T) {
+	tool := &ValidateManifestTool{schemas: &openAPISchemas{}}
+	results := tool.validate(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+# only a comment
+---
+kind: Service
+`, "")
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	if !results[0].Valid || results[0].Name != "settings" || !strings.Contains(results[0].SchemaNote, "schema not checked") {
+		t.Errorf("unexpected first result: %+v", results[0])
+	}
+	if results[1].Valid || len(results[1].SchemaErrors) == 0 {
+		t.Errorf("expected the Service without apiVersion to be invalid: %+v", results[1])
+	}
+}
@@ -0,0 +1,240 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// RunFunc runs one tool call with its parsed arguments.
+type RunFunc func(ctx tool.Context, args map[string]any) (map[string]any, error)
+
+// Middleware wraps the Run of a tool with a cross-cutting concern such as
+// a policy check or logging. It is called once per tool when the tools are
+// built and returns the RunFunc used for every call; returning next leaves
+// the tool alone.
+type Middleware func(t ToolInfo, next RunFunc) RunFunc
+
+// ToolInfo describes the tool a middleware wraps.
+type ToolInfo struct {
+	Name        string
+	Category    ToolCategory
+	Declaration *genai.FunctionDeclaration
+	Custom      bool // declared in the config; see SetCustomTools
+}
+
+// Use adds middleware to every tool returned by All. It runs after the
+// arguments are validated and before the audit log and policy checks.
+func (k *KubeTools) Use(mw ...Middleware) {
+	k.middleware = append(k.middleware, mw...)
+}
+
+// chain returns the middleware every tool call goes through, outermost
+// first.
+func (k *KubeTools) chain() []Middleware {
+	var chain []Middleware
+	if limit := k.maxResultBytes; limit >= 0 {
+		if limit == 0 {
+			limit = DefaultMaxResultBytes
+		}
+		chain = append(chain, limitResults(limit))
+	}
+	// Prefetched calls start below the scheduler, so everything after it
+	// applies to them too
+	chain = append(chain, k.scheduler.middleware, recoverPanics, validateArgs)
+	if k.credentials != nil {
+		chain = append(chain, k.credentials.middleware)
+	}
+	chain = append(chain, k.enforceTimeouts())
+	chain = append(chain, k.middleware...)
+	if k.results != nil {
+		chain = append(chain, k.results.middleware)
+	}
+	if k.auditLog != "" {
+		chain = append(chain, auditMutations(k.auditLog))
+	}
+	chain = append(chain, k.guards()...)
+	chain = append(chain, k.recordChanges)
+	return chain
+}
+
+// guards returns the safety checks a mutating call must pass: the
+// namespace policy, protected resources, admission policies, maintenance
+// windows and enforced dry-run, outermost first.
+func (k *KubeTools) guards() []Middleware {
+	var chain []Middleware
+	if k.namespaceGuard != nil {
+		chain = append(chain, k.namespaceGuard.middleware)
+	}
+	if k.protectGuard != nil {
+		chain = append(chain, k.protectGuard.middleware)
+	}
+	if k.policyGuard != nil {
+		chain = append(chain, k.policyGuard.middleware)
+	}
+	if k.windowGuard != nil {
+		chain = append(chain, k.windowGuard.middleware)
+	}
+	if k.dryRunGuard != nil {
+		chain = append(chain, k.enforceDryRun())
+	}
+	return chain
+}
+
+// withMiddleware wraps every tool in all with chain, the first middleware
+// outermost.
+func withMiddleware(all []tool.Tool, chain ...Middleware) []tool.Tool {
+	for i, t := range all {
+		rt, ok := t.(runnableTool)
+		if !ok {
+			continue
+		}
+		_, custom := rt.(*CustomTool)
+		info := ToolInfo{Name: rt.Name(), Category: rt.Category(), Declaration: rt.Declaration(), Custom: custom}
+		run := func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			return rt.Run(ctx, args)
+		}
+		for _, mw := range slices.Backward(chain) {
+			run = mw(info, run)
+		}
+		all[i] = &chainedTool{runnableTool: rt, run: run}
+	}
+	return all
+}
+
+// chainedTool runs a tool through its middleware chain.
+type chainedTool struct {
+	runnableTool
+	run RunFunc
+}
+
+// ProcessRequest adds this tool (not the wrapped one) to the LLM request.
+func (t *chainedTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Run parses the arguments once and executes the chain.
+func (t *chainedTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		switch a := args.(type) {
+		case nil:
+			argsMap = map[string]any{}
+		case string:
+			if err := json.Unmarshal([]byte(a), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		default:
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+	return t.run(ctx, argsMap)
+}
+
+// WithContext returns ctx with the deadline, cancellation and values of
+// goCtx, which must derive from it. Middleware uses it to hand a tool a
+// context with something added, such as a deadline or a trace span.
+func WithContext(ctx tool.Context, goCtx context.Context) tool.Context {
+	return derivedContext{Context: ctx, ctx: goCtx}
+}
+
+// derivedContext is a tool.Context whose context.Context part is replaced.
+type derivedContext struct {
+	tool.Context
+	ctx context.Context
+}
+
+func (c derivedContext) Deadline() (time.Time, bool) { return c.ctx.Deadline() }
+func (c derivedContext) Done() <-chan struct{}       { return c.ctx.Done() }
+func (c derivedContext) Err() error                  { return c.ctx.Err() }
+func (c derivedContext) Value(key any) any           { return c.ctx.Value(key) }
+
+// recoverPanics turns a panicking tool into an error result, so one bug
+// doesn't take down the session.
+func recoverPanics(t ToolInfo, next RunFunc) RunFunc {
+	return func(ctx tool.Context, args map[string]any) (result map[string]any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				result, err = map[string]any{
+					"error": fmt.Sprintf("%s crashed: %v. This is a bug in kasa, not a problem with the cluster; try another tool.", t.Name, r),
+				}, nil
+			}
+		}()
+		return next(ctx, args)
+	}
+}
+
+// validateArgs checks the arguments against the tool's declaration before
+// it runs: required arguments must be present, strings must be strings,
+// and lists and objects must be lists and objects or JSON strings. Numbers
+// and booleans are left to the tools, which also accept them as strings.
+func validateArgs(t ToolInfo, next RunFunc) RunFunc {
+	if t.Declaration == nil || t.Declaration.Parameters == nil {
+		return next
+	}
+	params := t.Declaration.Parameters
+	return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		var problems []string
+		for _, name := range params.Required {
+			if _, ok := args[name]; !ok {
+				problems = append(problems, fmt.Sprintf("missing required argument %q", name))
+			}
+		}
+		for name, value := range args {
+			schema := params.Properties[name]
+			if schema == nil || value == nil {
+				continue
+			}
+			if want := schemaType(schema.Type); want != "" && !hasType(value, want) {
+				problems = append(problems, fmt.Sprintf("argument %q must be %s", name, want))
+			}
+		}
+		if len(problems) > 0 {
+			slices.Sort(problems)
+			return map[string]any{"error": fmt.Sprintf("invalid arguments for %s: %s", t.Name, strings.Join(problems, "; "))}, nil
+		}
+		return next(ctx, args)
+	}
+}
+
+// schemaType returns the JSON type validateArgs checks for a schema type,
+// or "" if it doesn't check it.
+func schemaType(t genai.Type) string {
+	switch strings.ToLower(string(t)) {
+	case "string":
+		return "a string"
+	case "array":
+		return "a list"
+	case "object":
+		return "an object"
+	}
+	return ""
+}
+
+func hasType(v any, want string) bool {
+	switch want {
+	case "a string":
+		_, ok := v.(string)
+		return ok
+	case "a list":
+		switch v.(type) {
+		case []any, string:
+			return true
+		}
+		return false
+	case "an object":
+		switch v.(type) {
+		case map[string]any, string:
+			return true
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// declaredTool is a mutating tool with a declaration, which can be made to panic.
+type declaredTool struct {
+	recordingTool
+	panics bool
+}
+
+func (t *declaredTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name: t.name,
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace":   {Type: "string"},
+				"name":        {Type: "string"},
+				"string_data": {Type: "object"},
+			},
+			Required: []string{"namespace", "name"},
+		},
+	}
+}
+
+func (t *declaredTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	if t.panics {
+		var m map[string]int
+		m["boom"] = 1
+	}
+	return t.recordingTool.Run(ctx, args)
+}
+
+func TestMiddlewareChain(t *testing.T) {
+	auditLog := filepath.Join(t.TempDir(), "audit.jsonl")
+	k := &KubeTools{scheduler: newToolScheduler()}
+	k.SetAuditLog(auditLog)
+
+	var order []string
+	k.Use(func(info ToolInfo, next RunFunc) RunFunc {
+		return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			order = append(order, info.Name)
+			return next(ctx, args)
+		}
+	})
+
+	inner := &declaredTool{recordingTool: recordingTool{name: "create_secret"}}
+	crashing := &declaredTool{recordingTool: recordingTool{name: "scale_deployment"}, panics: true}
+	wrapped := withMiddleware([]tool.Tool{inner, crashing}, k.chain()...)
+	secret, crash := wrapped[0].(runnableTool), wrapped[1].(runnableTool)
+
+	// Arguments arrive as a JSON string and are parsed once
+	result, err := secret.Run(nil, `{"namespace":"prod","name":"db","string_data":{"password":"hunter2"}}`)
+	if err != nil || result["success"] != true || inner.calls != 1 {
+		t.Fatalf("valid call: %v, %v", result, err)
+	}
+
+	result, _ = secret.Run(nil, map[string]any{"name": 3})
+	msg, _ := result["error"].(string)
+	if !strings.Contains(msg, `missing required argument "namespace"`) || !strings.Contains(msg, `argument "name" must be a string`) {
+		t.Errorf("invalid arguments should be refused, got %v", result)
+	}
+	if inner.calls != 1 {
+		t.Error("tool ran with invalid arguments")
+	}
+
+	result, err = crash.Run(nil, map[string]any{"namespace": "prod", "name": "web"})
+	if err != nil || !strings.Contains(result["error"].(string), "scale_deployment crashed") {
+		t.Errorf("panic should become an error result, got %v, %v", result, err)
+	}
+
+	if strings.Join(order, ",") != "create_secret,scale_deployment" {
+		t.Errorf("middleware from Use ran for %v, want only calls with valid arguments", order)
+	}
+
+	data, err := os.ReadFile(auditLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 audit records, got %d:\n%s", len(lines), data)
+	}
+	var rec auditRecord
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Tool != "create_secret" || rec.Namespace != "prod" || rec.Outcome != "applied" || rec.Args["string_data"] != "[redacted]" {
+		t.Errorf("audit record = %+v", rec)
+	}
+	if strings.Contains(string(data), "hunter2") {
+		t.Error("secret value written to the audit log")
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &rec); err != nil || rec.Outcome != "failed" {
+		t.Errorf("crash should be audited as failed, got %+v", rec)
+	}
+}
@@ -1,10 +1,8 @@
 package tools
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/tool"
@@ -117,7 +115,7 @@ func (t *CreateNamespaceTool) Run(ctx tool.Context, args any) (map[string]any, e
 	}
 
 	// Create in cluster
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
 	defer cancel()
 
 	// Check if namespace already exists
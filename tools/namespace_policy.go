@@ -0,0 +1,198 @@
+package tools
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"sigs.k8s.io/yaml"
+)
+
+// NamespacePolicy confines the tools to a set of namespaces (the
+// allowed_namespaces and denied_namespaces of the kubernetes section of
+// config.yaml).
+type NamespacePolicy struct {
+	Allowed []string `yaml:"allowed_namespaces"` // glob patterns, e.g. ["team-a-*"]; every namespace if empty
+	Denied  []string `yaml:"denied_namespaces"`  // glob patterns; win over Allowed
+}
+
+// IsEmpty reports whether the policy restricts nothing.
+func (p NamespacePolicy) IsEmpty() bool {
+	return len(p.Allowed) == 0 && len(p.Denied) == 0
+}
+
+// Validate checks the namespace patterns.
+func (p NamespacePolicy) Validate() error {
+	for _, list := range []struct {
+		name     string
+		patterns []string
+	}{{"allowed_namespaces", p.Allowed}, {"denied_namespaces", p.Denied}} {
+		for _, pattern := range list.patterns {
+			if pattern == "" {
+				return fmt.Errorf("%s: empty namespace pattern", list.name)
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%s: namespace pattern %q: %w", list.name, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Allows reports whether the tools may use namespace.
+func (p NamespacePolicy) Allows(namespace string) bool {
+	if matchesAny(p.Denied, namespace) {
+		return false
+	}
+	return len(p.Allowed) == 0 || matchesAny(p.Allowed, namespace)
+}
+
+// String describes the policy for error messages, e.g. "namespaces
+// matching team-a-*, except team-a-secrets".
+func (p NamespacePolicy) String() string {
+	switch {
+	case len(p.Allowed) == 0:
+		return "namespaces not matching " + strings.Join(p.Denied, ", ")
+	case len(p.Denied) == 0:
+		return "namespaces matching " + strings.Join(p.Allowed, ", ")
+	}
+	return fmt.Sprintf("namespaces matching %s, except %s", strings.Join(p.Allowed, ", "), strings.Join(p.Denied, ", "))
+}
+
+func matchesAny(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeTools change nodes, and with them the pods of every namespace
+// scheduled there.
+var nodeTools = []string{"cordon_node", "drain_node"}
+
+// namespaceGuard refuses tool calls outside the namespace policy.
+type namespaceGuard struct {
+	policy   NamespacePolicy
+	resolver *GVRResolver
+}
+
+// SetNamespacePolicy confines every tool to the namespaces policy allows.
+// An empty policy removes the restriction.
+func (k *KubeTools) SetNamespacePolicy(policy NamespacePolicy) error {
+	if policy.IsEmpty() {
+		k.namespaceGuard = nil
+		return nil
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	k.namespaceGuard = &namespaceGuard{policy: policy, resolver: k.resolver}
+	return nil
+}
+
+// middleware checks every namespace a call names before the tool runs.
+// Tools that don't take a namespace (nodes, the web, the manifest git
+// repository) are left alone, except the node tools, which are refused.
+// A missing namespace usually means all namespaces, so it is refused too,
+// unless the call reads a cluster-scoped kind; changing cluster-scoped
+// resources is never allowed.
+func (g *namespaceGuard) middleware(t ToolInfo, next RunFunc) RunFunc {
+	if slices.Contains(nodeTools, t.Name) {
+		return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			return g.refuse(t.Name, "", fmt.Sprintf("%s affects pods in every namespace on the node", t.Name)), nil
+		}
+	}
+	keys := namespaceArgs(t)
+	if len(keys) == 0 {
+		return next
+	}
+	return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		if g.clusterScoped(args) {
+			if t.Category == CategoryMutating {
+				return g.refuse(t.Name, "", "cluster-scoped resources are shared by every namespace"), nil
+			}
+			return next(ctx, args)
+		}
+		for _, key := range keys {
+			namespace, _ := args[key].(string)
+			if key == "namespace" {
+				namespace = targetNamespace(t.Name, args)
+				if namespace == "" {
+					return g.refuse(t.Name, "", "the call doesn't name a namespace, which means all namespaces or the cluster"), nil
+				}
+			}
+			if namespace != "" && !g.policy.Allows(namespace) {
+				return g.refuse(t.Name, namespace, ""), nil
+			}
+		}
+		return next(ctx, args)
+	}
+}
+
+// namespaceArgs returns the arguments of a tool that name a namespace.
+func namespaceArgs(t ToolInfo) []string {
+	switch t.Name {
+	case "create_namespace", "delete_namespace":
+		return []string{"namespace"} // see targetNamespace
+	}
+	if t.Declaration == nil || t.Declaration.Parameters == nil {
+		return nil
+	}
+	var keys []string
+	for name := range t.Declaration.Parameters.Properties {
+		if name == "namespace" || strings.HasPrefix(name, "namespace_") || strings.HasSuffix(name, "_namespace") {
+			keys = append(keys, name)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// clusterScoped reports whether the call names a kind of resource that
+// doesn't live in a namespace, in its arguments or YAML. Kinds that can't
+// be resolved are left to the tool to reject.
+func (g *namespaceGuard) clusterScoped(args map[string]any) bool {
+	kind, _ := args["type"].(string)
+	if kind == "" {
+		kind, _ = args["kind"].(string)
+	}
+	apiVersion, _ := args["api_version"].(string)
+	if content, _ := args["yaml"].(string); kind == "" && content != "" {
+		var obj struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+		}
+		if err := yaml.Unmarshal([]byte(content), &obj); err == nil {
+			kind, apiVersion = obj.Kind, obj.APIVersion
+		}
+	}
+	if kind == "" {
+		return false
+	}
+	gvr, err := g.resolver.Resolve(kind, apiVersion)
+	if err != nil {
+		return false
+	}
+	return !g.resolver.IsNamespaced(gvr, kind)
+}
+
+// refuse returns the result of a call the policy doesn't allow; reason
+// explains refusals that aren't about one namespace.
+func (g *namespaceGuard) refuse(toolName, namespace, reason string) map[string]any {
+	msg := fmt.Sprintf("policy: kasa is confined to %s, and %s is outside them", g.policy, describeNamespace(namespace))
+	if reason != "" {
+		msg = fmt.Sprintf("policy: kasa is confined to %s, so %s is not allowed: %s", g.policy, toolName, reason)
+	}
+	return map[string]any{
+		"error":            msg,
+		"namespace_denied": true,
+		"tool":             toolName,
+		"namespace":        namespace,
+		"message": "The call was NOT made. Do not try to reach the same resources another way; " +
+			"work within the allowed namespaces, or tell the user this is outside what kasa is permitted to do here.",
+	}
+}
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+func TestNamespacePolicyAllows(t *testing.T) {
+	policy := NamespacePolicy{Allowed: []string{"team-a-*"}, Denied: []string{"team-a-secrets"}}
+	if err := policy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for ns, want := range map[string]bool{
+		"team-a-web":     true,
+		"team-a-secrets": false,
+		"team-b-web":     false,
+		"default":        false,
+	} {
+		if got := policy.Allows(ns); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", ns, got, want)
+		}
+	}
+	if !(NamespacePolicy{Denied: []string{"kube-*"}}).Allows("default") {
+		t.Error("a deny list alone should allow other namespaces")
+	}
+	if err := (NamespacePolicy{Allowed: []string{"team-[a"}}).Validate(); err == nil {
+		t.Error("expected an error for a malformed pattern")
+	}
+}
+
+func TestNamespaceGuard(t *testing.T) {
+	k := &KubeTools{}
+	if err := k.SetNamespacePolicy(NamespacePolicy{Allowed: []string{"team-a-*"}}); err != nil {
+		t.Fatal(err)
+	}
+	schema := func(props ...string) *genai.FunctionDeclaration {
+		params := &genai.Schema{Type: "object", Properties: map[string]*genai.Schema{}}
+		for _, p := range props {
+			params.Properties[p] = &genai.Schema{Type: "string"}
+		}
+		return &genai.FunctionDeclaration{Parameters: params}
+	}
+	calls := 0
+	next := func(tool.Context, map[string]any) (map[string]any, error) {
+		calls++
+		return map[string]any{"success": true}, nil
+	}
+
+	tests := []struct {
+		name    string
+		info    ToolInfo
+		args    map[string]any
+		allowed bool
+	}{
+		{"allowed namespace", ToolInfo{Name: "set_image", Category: CategoryMutating, Declaration: schema("namespace", "name")},
+			map[string]any{"namespace": "team-a-web", "name": "api"}, true},
+		{"other namespace", ToolInfo{Name: "set_image", Category: CategoryMutating, Declaration: schema("namespace", "name")},
+			map[string]any{"namespace": "prod", "name": "api"}, false},
+		{"all namespaces", ToolInfo{Name: "list_pods", Category: CategoryReadOnly, Declaration: schema("namespace")},
+			map[string]any{}, false},
+		{"second namespace", ToolInfo{Name: "diff_env", Category: CategoryReadOnly, Declaration: schema("namespace_a", "namespace_b")},
+			map[string]any{"namespace_a": "team-a-staging", "namespace_b": "prod"}, false},
+		{"namespace in YAML", ToolInfo{Name: "apply_resource", Category: CategoryMutating, Declaration: schema("namespace", "yaml")},
+			map[string]any{"yaml": "kind: ConfigMap\nmetadata:\n  name: x\n  namespace: team-b\n"}, false},
+		{"namespace override", ToolInfo{Name: "apply_resource", Category: CategoryMutating, Declaration: schema("namespace", "yaml")},
+			map[string]any{"yaml": "kind: ConfigMap\nmetadata:\n  name: x\n", "namespace": "team-a-web"}, true},
+		{"cluster-scoped change", ToolInfo{Name: "apply_resource", Category: CategoryMutating, Declaration: schema("namespace", "yaml")},
+			map[string]any{"yaml": "apiVersion: rbac.authorization.k8s.io/v1\nkind: ClusterRoleBinding\nmetadata:\n  name: x\n", "namespace": "team-a-web"}, false},
+		{"cluster-scoped read", ToolInfo{Name: "get_resource", Category: CategoryReadOnly, Declaration: schema("namespace", "type", "name")},
+			map[string]any{"type": "clusterrole", "name": "view"}, true},
+		{"create namespace", ToolInfo{Name: "create_namespace", Category: CategoryMutating, Declaration: schema("name")},
+			map[string]any{"name": "kube-system"}, false},
+		{"node tool", ToolInfo{Name: "drain_node", Category: CategoryMutating, Declaration: schema("name")},
+			map[string]any{"name": "node-1"}, false},
+		{"no namespace", ToolInfo{Name: "list_nodes", Category: CategoryReadOnly, Declaration: schema()},
+			map[string]any{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := calls
+			result, err := k.namespaceGuard.middleware(tt.info, next)(nil, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ran := calls > before; ran != tt.allowed {
+				t.Fatalf("ran = %v, want %v (result %v)", ran, tt.allowed, result)
+			}
+			if !tt.allowed {
+				msg, _ := result["error"].(string)
+				if result["namespace_denied"] != true || !strings.Contains(msg, "team-a-*") {
+					t.Errorf("refusal should name the allowed namespaces, got %v", result)
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package tools
 
 import (
-	"context"
 	"encoding/json"
 	"time"
 
@@ -83,7 +82,7 @@ func (t *ListNamespacesTool) Run(ctx tool.Context, args any) (map[string]any, er
 		}
 	}
 
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
 	defer cancel()
 
 	namespaces, err := t.clientset.CoreV1().Namespaces().List(timeoutCtx, metav1.ListOptions{})
@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CordonNodeTool provides the cordon_node tool for the agent.
+type CordonNodeTool struct {
+	clientset *kubernetes.Clientset
+}
+
+// NewCordonNodeTool creates a new CordonNodeTool.
+func NewCordonNodeTool(clientset *kubernetes.Clientset) *CordonNodeTool {
+	return &CordonNodeTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *CordonNodeTool) Name() string {
+	return "cordon_node"
+}
+
+// Description returns the tool description.
+func (t *CordonNodeTool) Description() string {
+	return "Mark a node unschedulable so no new pods are placed on it (like 'kubectl cordon'), or make it schedulable again with uncordon=true. Pods already running on the node are not affected; use drain_node to move them."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *CordonNodeTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *CordonNodeTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *CordonNodeTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *CordonNodeTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"name": {
+					Type:        "string",
+					Description: "The name of the node",
+				},
+				"uncordon": {
+					Type:        "boolean",
+					Description: "Make the node schedulable again instead of cordoning it (default: false)",
+				},
+			},
+			Required: []string{"name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *CordonNodeTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+
+	uncordon, _ := argsMap["uncordon"].(bool)
+
+	changed, err := setNodeUnschedulable(toolContext(ctx), t.clientset, name, !uncordon)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	action := "cordoned"
+	if uncordon {
+		action = "uncordoned"
+	}
+	message := fmt.Sprintf("Node %s %s", name, action)
+	if !changed {
+		message = fmt.Sprintf("Node %s was already %s", name, action)
+	}
+
+	return map[string]any{
+		"success":       true,
+		"name":          name,
+		"unschedulable": !uncordon,
+		"changed":       changed,
+		"message":       message,
+	}, nil
+}
+
+// setNodeUnschedulable sets spec.unschedulable on a node. Returns false if
+// the node already had the requested value.
+func setNodeUnschedulable(ctx context.Context, clientset kubernetes.Interface, name string, unschedulable bool) (bool, error) {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	node, err := clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get node %s: %w", name, err)
+	}
+	if node.Spec.Unschedulable == unschedulable {
+		return false, nil
+	}
+
+	patch := fmt.Appendf(nil, `{"spec":{"unschedulable":%t}}`, unschedulable)
+	if _, err := clientset.CoreV1().Nodes().Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return false, fmt.Errorf("failed to patch node %s: %w", name, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,246 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DrainNodeTool provides the drain_node tool for the agent.
+type DrainNodeTool struct {
+	clientset *kubernetes.Clientset
+}
+
+// NewDrainNodeTool creates a new DrainNodeTool.
+func NewDrainNodeTool(clientset *kubernetes.Clientset) *DrainNodeTool {
+	return &DrainNodeTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *DrainNodeTool) Name() string {
+	return "drain_node"
+}
+
+// Description returns the tool description.
+func (t *DrainNodeTool) Description() string {
+	return "Cordon a node and evict its pods through the eviction API, respecting PodDisruptionBudgets (like 'kubectl drain --ignore-daemonsets'). DaemonSet and static pods are left alone. Pods without a controller or with emptyDir data block the drain unless force or delete_emptydir_data is set. Evictions refused by a PDB are retried until the timeout."
+}
+
+// IsLongRunning returns true as evictions are retried until pods are gone.
+func (t *DrainNodeTool) IsLongRunning() bool {
+	return true
+}
+
+// Category returns the tool category.
+func (t *DrainNodeTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *DrainNodeTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *DrainNodeTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"name": {
+					Type:        "string",
+					Description: "The name of the node",
+				},
+				"force": {
+					Type:        "boolean",
+					Description: "Also evict pods that have no controller; they will not be recreated (default: false)",
+				},
+				"delete_emptydir_data": {
+					Type:        "boolean",
+					Description: "Also evict pods using emptyDir volumes; their local data is lost (default: false)",
+				},
+				"timeout": {
+					Type:        "integer",
+					Description: "Seconds to keep retrying evictions and waiting for pods to terminate (default: 300, max: 900)",
+				},
+			},
+			Required: []string{"name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *DrainNodeTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+
+	force, _ := argsMap["force"].(bool)
+	deleteEmptyDir, _ := argsMap["delete_emptydir_data"].(bool)
+
+	timeout := 300
+	if to, ok := argsMap["timeout"].(float64); ok {
+		timeout = int(to)
+	}
+	timeout = min(max(timeout, 10), 900)
+
+	if _, err := setNodeUnschedulable(toolContext(ctx), t.clientset, name, true); err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	listCtx, cancel := apiContext(toolContext(ctx))
+	pods, err := t.clientset.CoreV1().Pods("").List(listCtx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("spec.nodeName=%s", name),
+	})
+	cancel()
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("node %s cordoned, but listing its pods failed: %v", name, err)}, nil
+	}
+
+	plan := planDrain(pods.Items, force, deleteEmptyDir)
+	result := map[string]any{
+		"name":     name,
+		"cordoned": true,
+		"skipped":  plan.skipped,
+	}
+	if len(plan.blocked) > 0 {
+		result["success"] = false
+		result["blocked"] = plan.blocked
+		result["error"] = fmt.Sprintf("node %s cordoned, but %d pod(s) block the drain; no pods were evicted", name, len(plan.blocked))
+		return result, nil
+	}
+
+	evicted, pending := t.evictAll(toolContext(ctx), plan.evict, time.Now().Add(time.Duration(timeout)*time.Second))
+	result["evicted"] = evicted
+	if len(pending) > 0 {
+		result["success"] = false
+		result["pending"] = pending
+		result["message"] = fmt.Sprintf("Node %s cordoned; %d pod(s) evicted, %d still pending after %ds (likely held by PodDisruptionBudgets)", name, len(evicted), len(pending), timeout)
+		return result, nil
+	}
+
+	result["success"] = true
+	result["message"] = fmt.Sprintf("Node %s drained: %d pod(s) evicted, %d skipped", name, len(evicted), len(plan.skipped))
+	return result, nil
+}
+
+// evictAll evicts pods, retrying evictions refused by a disruption budget,
+// and waits for the evicted pods to terminate. Returns the evicted pods and
+// those still present at the deadline, as namespace/name.
+func (t *DrainNodeTool) evictAll(ctx context.Context, pods []corev1.Pod, deadline time.Time) ([]string, []string) {
+	remaining := make(map[string]corev1.Pod, len(pods))
+	for _, pod := range pods {
+		remaining[pod.Namespace+"/"+pod.Name] = pod
+	}
+	requested := map[string]bool{}
+	evicted := []string{}
+
+	for {
+		reqCtx, cancel := apiContext(ctx)
+		for key, pod := range remaining {
+			if !requested[key] {
+				err := t.clientset.PolicyV1().Evictions(pod.Namespace).Evict(reqCtx, &policyv1.Eviction{
+					ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+				})
+				if err != nil && !errors.IsNotFound(err) {
+					// Refused (usually 429 from a PDB); retry on the next pass
+					continue
+				}
+				requested[key] = true
+				evicted = append(evicted, key)
+			}
+
+			// Done once the pod is gone or replaced by a new pod with the same name
+			current, err := t.clientset.CoreV1().Pods(pod.Namespace).Get(reqCtx, pod.Name, metav1.GetOptions{})
+			if errors.IsNotFound(err) || (err == nil && current.UID != pod.UID) {
+				delete(remaining, key)
+			}
+		}
+		cancel()
+
+		if len(remaining) == 0 || time.Now().After(deadline) || !pause(ctx, 5*time.Second) {
+			break
+		}
+	}
+
+	pending := make([]string, 0, len(remaining))
+	for key := range remaining {
+		pending = append(pending, key)
+	}
+	return evicted, pending
+}
+
+// drainPlan sorts a node's pods into those to evict, those drain leaves
+// alone and those that prevent the drain.
+type drainPlan struct {
+	evict   []corev1.Pod
+	skipped []string // namespace/name: reason
+	blocked []string // namespace/name: reason
+}
+
+// planDrain classifies pods the way 'kubectl drain --ignore-daemonsets' does.
+func planDrain(pods []corev1.Pod, force, deleteEmptyDir bool) drainPlan {
+	plan := drainPlan{skipped: []string{}}
+	for _, pod := range pods {
+		key := pod.Namespace + "/" + pod.Name
+		owner := metav1.GetControllerOf(&pod)
+
+		switch {
+		case podFinished(&pod):
+			plan.skipped = append(plan.skipped, key+": already terminated")
+			continue
+		case pod.Annotations[corev1.MirrorPodAnnotationKey] != "":
+			plan.skipped = append(plan.skipped, key+": static pod")
+			continue
+		case owner != nil && owner.Kind == "DaemonSet":
+			plan.skipped = append(plan.skipped, key+": managed by DaemonSet")
+			continue
+		case owner == nil && !force:
+			plan.blocked = append(plan.blocked, key+": no controller (set force=true to evict it anyway)")
+			continue
+		}
+
+		if !deleteEmptyDir && usesEmptyDir(&pod) {
+			plan.blocked = append(plan.blocked, key+": uses emptyDir data (set delete_emptydir_data=true to evict it anyway)")
+			continue
+		}
+		plan.evict = append(plan.evict, pod)
+	}
+	return plan
+}
+
+// usesEmptyDir reports whether the pod mounts an emptyDir volume.
+func usesEmptyDir(pod *corev1.Pod) bool {
+	for _, v := range pod.Spec.Volumes {
+		if v.EmptyDir != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NodeConditionInfo describes one node condition.
+type NodeConditionInfo struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	Since   string `json:"since,omitempty"`
+}
+
+// NodePodInfo describes a pod scheduled on a node.
+type NodePodInfo struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Phase     string `json:"phase"`
+	Owner     string `json:"owner,omitempty"`
+}
+
+// GetNodeTool provides the get_node tool for the agent.
+type GetNodeTool struct {
+	clientset *kubernetes.Clientset
+}
+
+// NewGetNodeTool creates a new GetNodeTool.
+func NewGetNodeTool(clientset *kubernetes.Clientset) *GetNodeTool {
+	return &GetNodeTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *GetNodeTool) Name() string {
+	return "get_node"
+}
+
+// Description returns the tool description.
+func (t *GetNodeTool) Description() string {
+	return "Get details of a single node: conditions with reasons, taints, addresses, OS/runtime/kubelet versions, capacity and allocatable resources, the CPU and memory requested by its pods, and the pods running on it."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *GetNodeTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *GetNodeTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *GetNodeTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *GetNodeTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"name": {
+					Type:        "string",
+					Description: "The name of the node",
+				},
+			},
+			Required: []string{"name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *GetNodeTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
+	defer cancel()
+
+	node, err := t.clientset.CoreV1().Nodes().Get(timeoutCtx, name, metav1.GetOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to get node: %v", err)}, nil
+	}
+
+	pods, err := t.clientset.CoreV1().Pods("").List(timeoutCtx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("spec.nodeName=%s", name),
+	})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to list pods on node: %v", err)}, nil
+	}
+
+	now := time.Now()
+	var active []corev1.Pod
+	podInfos := make([]NodePodInfo, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		if podFinished(&pod) {
+			continue
+		}
+		active = append(active, pod)
+		info := NodePodInfo{Namespace: pod.Namespace, Name: pod.Name, Phase: string(pod.Status.Phase)}
+		if owner := metav1.GetControllerOf(&pod); owner != nil {
+			info.Owner = fmt.Sprintf("%s/%s", owner.Kind, owner.Name)
+		}
+		podInfos = append(podInfos, info)
+	}
+
+	conditions := make([]NodeConditionInfo, 0, len(node.Status.Conditions))
+	for _, cond := range node.Status.Conditions {
+		c := NodeConditionInfo{
+			Type:    string(cond.Type),
+			Status:  string(cond.Status),
+			Reason:  cond.Reason,
+			Message: cond.Message,
+		}
+		if !cond.LastTransitionTime.IsZero() {
+			c.Since = formatDuration(now.Sub(cond.LastTransitionTime.Time))
+		}
+		conditions = append(conditions, c)
+	}
+
+	addresses := map[string]string{}
+	for _, addr := range node.Status.Addresses {
+		addresses[string(addr.Type)] = addr.Address
+	}
+
+	summary := summarizeNode(node, len(active), now)
+	requests, limits := podResourceTotals(active)
+
+	return map[string]any{
+		"name":          node.Name,
+		"ready":         summary.Ready,
+		"roles":         summary.Roles,
+		"unschedulable": node.Spec.Unschedulable,
+		"taints":        summary.Taints,
+		"labels":        node.Labels,
+		"addresses":     addresses,
+		"conditions":    conditions,
+		"node_info": map[string]string{
+			"kubelet_version":           node.Status.NodeInfo.KubeletVersion,
+			"container_runtime_version": node.Status.NodeInfo.ContainerRuntimeVersion,
+			"os_image":                  node.Status.NodeInfo.OSImage,
+			"kernel_version":            node.Status.NodeInfo.KernelVersion,
+			"architecture":              node.Status.NodeInfo.Architecture,
+		},
+		"capacity":    summary.Capacity,
+		"allocatable": summary.Allocatable,
+		"requested": map[string]string{
+			"cpu_requests":    requests.Cpu().String(),
+			"cpu_limits":      limits.Cpu().String(),
+			"memory_requests": requests.Memory().String(),
+			"memory_limits":   limits.Memory().String(),
+		},
+		"pod_count": len(podInfos),
+		"pods":      podInfos,
+		"age":       summary.Age,
+	}, nil
+}
+
+// podResourceTotals sums the container CPU and memory requests and limits of pods.
+func podResourceTotals(pods []corev1.Pod) (corev1.ResourceList, corev1.ResourceList) {
+	requests := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.Quantity{Format: resource.DecimalSI},
+		corev1.ResourceMemory: resource.Quantity{Format: resource.BinarySI},
+	}
+	limits := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.Quantity{Format: resource.DecimalSI},
+		corev1.ResourceMemory: resource.Quantity{Format: resource.BinarySI},
+	}
+	for _, pod := range pods {
+		for _, c := range pod.Spec.Containers {
+			for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+				if q, ok := c.Resources.Requests[name]; ok {
+					total := requests[name]
+					total.Add(q)
+					requests[name] = total
+				}
+				if q, ok := c.Resources.Limits[name]; ok {
+					total := limits[name]
+					total.Add(q)
+					limits[name] = total
+				}
+			}
+		}
+	}
+	return requests, limits
+}
@@ -0,0 +1,195 @@
+package tools
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NodeInfo contains summary information about a Kubernetes node.
+type NodeInfo struct {
+	Name           string            `json:"name"`
+	Ready          bool              `json:"ready"`
+	Roles          []string          `json:"roles,omitempty"`
+	Unschedulable  bool              `json:"unschedulable,omitempty"`
+	KubeletVersion string            `json:"kubelet_version"`
+	Capacity       map[string]string `json:"capacity"`
+	Allocatable    map[string]string `json:"allocatable"`
+	Pods           int               `json:"pods"`
+	Taints         []string          `json:"taints,omitempty"`
+	Problems       []string          `json:"problems,omitempty"` // conditions other than Ready that are True
+	Age            string            `json:"age"`
+}
+
+// ListNodesTool provides the list_nodes tool for the agent.
+type ListNodesTool struct {
+	clientset *kubernetes.Clientset
+}
+
+// NewListNodesTool creates a new ListNodesTool.
+func NewListNodesTool(clientset *kubernetes.Clientset) *ListNodesTool {
+	return &ListNodesTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *ListNodesTool) Name() string {
+	return "list_nodes"
+}
+
+// Description returns the tool description.
+func (t *ListNodesTool) Description() string {
+	return "List the cluster's nodes with readiness, roles, kubelet version, capacity and allocatable resources, taints, pressure conditions and the number of pods on each node"
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *ListNodesTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *ListNodesTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *ListNodesTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *ListNodesTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type:       "object",
+			Properties: map[string]*genai.Schema{},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *ListNodesTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	// Parse arguments (none required for this tool)
+	if args != nil {
+		if _, ok := args.(map[string]any); !ok {
+			if argsStr, ok := args.(string); ok {
+				var argsMap map[string]any
+				if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+					return map[string]any{"error": "invalid arguments format"}, nil
+				}
+			}
+		}
+	}
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
+	defer cancel()
+
+	nodes, err := t.clientset.CoreV1().Nodes().List(timeoutCtx, metav1.ListOptions{})
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	// Count pods per node; a failed pod list only loses the counts
+	podCounts := map[string]int{}
+	if pods, err := t.clientset.CoreV1().Pods("").List(timeoutCtx, metav1.ListOptions{}); err == nil {
+		for _, pod := range pods.Items {
+			if pod.Spec.NodeName != "" && !podFinished(&pod) {
+				podCounts[pod.Spec.NodeName]++
+			}
+		}
+	}
+
+	result := make([]NodeInfo, 0, len(nodes.Items))
+	notReady := 0
+	for _, node := range nodes.Items {
+		info := summarizeNode(&node, podCounts[node.Name], time.Now())
+		if !info.Ready {
+			notReady++
+		}
+		result = append(result, info)
+	}
+
+	return map[string]any{
+		"nodes":     result,
+		"count":     len(result),
+		"not_ready": notReady,
+	}, nil
+}
+
+// summarizeNode builds the list_nodes entry for a node.
+func summarizeNode(node *corev1.Node, pods int, now time.Time) NodeInfo {
+	info := NodeInfo{
+		Name:           node.Name,
+		Roles:          nodeRoles(node.Labels),
+		Unschedulable:  node.Spec.Unschedulable,
+		KubeletVersion: node.Status.NodeInfo.KubeletVersion,
+		Capacity:       resourceSummary(node.Status.Capacity),
+		Allocatable:    resourceSummary(node.Status.Allocatable),
+		Pods:           pods,
+		Taints:         formatTaints(node.Spec.Taints),
+		Age:            formatDuration(now.Sub(node.CreationTimestamp.Time)),
+	}
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			info.Ready = cond.Status == corev1.ConditionTrue
+		} else if cond.Status == corev1.ConditionTrue {
+			info.Problems = append(info.Problems, string(cond.Type))
+		}
+	}
+	return info
+}
+
+// nodeRoles extracts roles from node-role.kubernetes.io/<role> labels.
+func nodeRoles(labels map[string]string) []string {
+	var roles []string
+	for k, v := range labels {
+		if role, ok := strings.CutPrefix(k, "node-role.kubernetes.io/"); ok && role != "" {
+			roles = append(roles, role)
+		} else if k == "kubernetes.io/role" && v != "" {
+			roles = append(roles, v)
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// resourceSummary returns the cpu, memory, ephemeral-storage and pods
+// quantities of a resource list as strings.
+func resourceSummary(list corev1.ResourceList) map[string]string {
+	summary := map[string]string{}
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage, corev1.ResourcePods} {
+		if q, ok := list[name]; ok {
+			summary[string(name)] = q.String()
+		}
+	}
+	return summary
+}
+
+// formatTaints renders taints as key=value:effect.
+func formatTaints(taints []corev1.Taint) []string {
+	var out []string
+	for _, taint := range taints {
+		s := taint.Key
+		if taint.Value != "" {
+			s += "=" + taint.Value
+		}
+		out = append(out, s+":"+string(taint.Effect))
+	}
+	return out
+}
+
+// podFinished reports whether a pod has terminated and no longer uses node resources.
+func podFinished(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSummarizeNode(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "worker-1",
+			Labels: map[string]string{
+				"node-role.kubernetes.io/worker":        "",
+				"node-role.kubernetes.io/control-plane": "",
+				"kubernetes.io/hostname":                "worker-1",
+			},
+		},
+		Spec: corev1.NodeSpec{
+			Unschedulable: true,
+			Taints: []corev1.Taint{
+				{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "node.kubernetes.io/unschedulable", Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+		Status: corev1.NodeStatus{
+			NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.31.2"},
+			Capacity: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("16Gi"),
+				corev1.ResourcePods:   resource.MustParse("110"),
+			},
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+				{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionTrue},
+				{Type: corev1.NodeDiskPressure, Status: corev1.ConditionFalse},
+			},
+		},
+	}
+
+	info := summarizeNode(node, 7, time.Now())
+	if !info.Ready || !info.Unschedulable || info.Pods != 7 || info.KubeletVersion != "v1.31.2" {
+		t.Errorf("unexpected summary: %+v", info)
+	}
+	if !slices.Equal(info.Roles, []string{"control-plane", "worker"}) {
+		t.Errorf("roles = %v", info.Roles)
+	}
+	if !slices.Equal(info.Taints, []string{"dedicated=gpu:NoSchedule", "node.kubernetes.io/unschedulable:NoSchedule"}) {
+		t.Errorf("taints = %v", info.Taints)
+	}
+	if !slices.Equal(info.Problems, []string{"MemoryPressure"}) {
+		t.Errorf("problems = %v", info.Problems)
+	}
+	if info.Capacity["cpu"] != "4" || info.Capacity["memory"] != "16Gi" || info.Capacity["pods"] != "110" {
+		t.Errorf("capacity = %v", info.Capacity)
+	}
+}
+
+func TestPodResourceTotals(t *testing.T) {
+	container := func(cpu, mem string) corev1.Container {
+		return corev1.Container{Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu), corev1.ResourceMemory: resource.MustParse(mem)},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(mem)},
+		}}
+	}
+	pods := []corev1.Pod{
+		{Spec: corev1.PodSpec{Containers: []corev1.Container{container("250m", "128Mi"), container("250m", "128Mi")}}},
+		{Spec: corev1.PodSpec{Containers: []corev1.Container{container("1", "256Mi")}}},
+	}
+
+	requests, limits := podResourceTotals(pods)
+	if got := requests.Cpu().String(); got != "1500m" {
+		t.Errorf("cpu requests = %s, want 1500m", got)
+	}
+	if got := requests.Memory().String(); got != "512Mi" {
+		t.Errorf("memory requests = %s, want 512Mi", got)
+	}
+	if got := limits.Cpu().String(); got != "0" {
+		t.Errorf("cpu limits = %s, want 0", got)
+	}
+}
+
+func TestPlanDrain(t *testing.T) {
+	controller := true
+	ownedBy := func(kind string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: "owner", Controller: &controller}}
+	}
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "web", OwnerReferences: ownedBy("ReplicaSet")}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "proxy", OwnerReferences: ownedBy("DaemonSet")}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "etcd", Annotations: map[string]string{corev1.MirrorPodAnnotationKey: "x"}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "job", OwnerReferences: ownedBy("Job")}, Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "bare"}},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "cache", OwnerReferences: ownedBy("ReplicaSet")},
+			Spec:       corev1.PodSpec{Volumes: []corev1.Volume{{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}},
+		},
+	}
+
+	plan := planDrain(pods, false, false)
+	if len(plan.evict) != 1 || plan.evict[0].Name != "web" {
+		t.Errorf("evict = %v", plan.evict)
+	}
+	if len(plan.skipped) != 3 {
+		t.Errorf("skipped = %v", plan.skipped)
+	}
+	if len(plan.blocked) != 2 || !strings.HasPrefix(plan.blocked[0], "app/bare") || !strings.HasPrefix(plan.blocked[1], "app/cache") {
+		t.Errorf("blocked = %v", plan.blocked)
+	}
+
+	plan = planDrain(pods, true, true)
+	if len(plan.evict) != 3 || len(plan.blocked) != 0 {
+		t.Errorf("with force: evict %d, blocked %v", len(plan.evict), plan.blocked)
+	}
+}
+
+func TestSetNodeUnschedulable(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}})
+
+	changed, err := setNodeUnschedulable(context.Background(), clientset, "worker-1", true)
+	if err != nil || !changed {
+		t.Fatalf("cordon: changed=%v err=%v", changed, err)
+	}
+	node, _ := clientset.CoreV1().Nodes().Get(context.Background(), "worker-1", metav1.GetOptions{})
+	if !node.Spec.Unschedulable {
+		t.Error("node not cordoned")
+	}
+
+	if changed, err := setNodeUnschedulable(context.Background(), clientset, "worker-1", true); err != nil || changed {
+		t.Errorf("second cordon: changed=%v err=%v", changed, err)
+	}
+	if changed, err := setNodeUnschedulable(context.Background(), clientset, "worker-1", false); err != nil || !changed {
+		t.Errorf("uncordon: changed=%v err=%v", changed, err)
+	}
+	if _, err := setNodeUnschedulable(context.Background(), clientset, "missing", true); err == nil {
+		t.Error("expected error for missing node")
+	}
+}
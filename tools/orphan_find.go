@@ -0,0 +1,301 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// managedByKasa is the label selector matching resources kasa created.
+const managedByKasa = "app.kubernetes.io/managed-by=kasa"
+
+// orphanKinds are the kinds kasa labels when it creates them. Kinds of
+// stored manifests are searched as well.
+var orphanKinds = []string{
+	"namespace", "deployment", "service", "configmap", "secret", "ingress",
+	"gateway", "httproute", "poddisruptionbudget", "serviceaccount",
+}
+
+// FindOrphansTool provides the find_orphans tool for the agent.
+type FindOrphansTool struct {
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+	manifest      *manifest.Manager
+}
+
+// NewFindOrphansTool creates a new FindOrphansTool.
+func NewFindOrphansTool(dynamicClient dynamic.Interface, resolver *GVRResolver, manifest *manifest.Manager) *FindOrphansTool {
+	return &FindOrphansTool{
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+		manifest:      manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *FindOrphansTool) Name() string {
+	return "find_orphans"
+}
+
+// Description returns the tool description.
+func (t *FindOrphansTool) Description() string {
+	return "Find resources labeled app.kubernetes.io/managed-by=kasa that have no stored manifest, and stored manifests whose resource no longer exists in the cluster. Complements drift detection, which only compares resources that are on both sides."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *FindOrphansTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *FindOrphansTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *FindOrphansTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *FindOrphansTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "Only check this namespace (default: all namespaces and cluster-scoped resources)",
+				},
+			},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *FindOrphansTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else if args != nil {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, _ := argsMap["namespace"].(string)
+	return findOrphans(toolContext(ctx), t.dynamicClient, t.resolver, t.manifest, namespace), nil
+}
+
+// OrphanedResource is a kasa-labeled resource without a stored manifest.
+type OrphanedResource struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Age       string `json:"age,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+// MissingResource is a stored manifest whose resource is not in the cluster.
+type MissingResource struct {
+	Namespace string `json:"namespace"`
+	App       string `json:"app"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+}
+
+// storedResource is a stored manifest together with the object it describes.
+type storedResource struct {
+	info       manifest.ManifestInfo
+	kind       string
+	name       string
+	apiVersion string
+}
+
+// orphanKey identifies a resource by manifest namespace, kind and name.
+func orphanKey(namespace, kind, name string) string {
+	return namespace + "/" + kind + "/" + name
+}
+
+// findOrphans lists kasa-labeled resources and stored manifests and reports
+// what is only on one side. An empty namespace checks everything.
+func findOrphans(ctx context.Context, dyn dynamic.Interface, resolver *GVRResolver, mgr *manifest.Manager, namespace string) map[string]any {
+	if dyn == nil {
+		return map[string]any{"error": "dynamic client not available"}
+	}
+
+	infos, err := mgr.ListManifests(namespace, "")
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to list manifests: %v", err)}
+	}
+
+	// Manifests are usually stored under the resource name, but not always
+	// (a PodDisruptionBudget lives under its deployment), so read the name
+	stored := make(map[string]storedResource, len(infos))
+	namespacesWithManifests := map[string]bool{}
+	kinds := append([]string{}, orphanKinds...)
+	for _, m := range infos {
+		s := storedResource{info: m, kind: NormalizeKindName(m.Type), name: m.App}
+		if content, err := mgr.ReadManifest(m.Namespace, m.App, m.Type); err == nil {
+			var meta struct {
+				APIVersion string `json:"apiVersion"`
+				Metadata   struct {
+					Name string `json:"name"`
+				} `json:"metadata"`
+			}
+			if yaml.Unmarshal(content, &meta) == nil {
+				s.apiVersion = meta.APIVersion
+				if meta.Metadata.Name != "" {
+					s.name = meta.Metadata.Name
+				}
+			}
+		}
+		stored[orphanKey(m.Namespace, s.kind, s.name)] = s
+		namespacesWithManifests[m.Namespace] = true
+		kinds = append(kinds, s.kind)
+	}
+	sort.Strings(kinds)
+	kinds = slices.Compact(kinds)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var errs []string
+	var orphaned []OrphanedResource
+	for _, kind := range kinds {
+		gvr, err := resolver.Resolve(kind, "")
+		if err != nil {
+			continue
+		}
+		namespaced := resolver.IsNamespaced(gvr, kind)
+		var resourceClient dynamic.ResourceInterface = dyn.Resource(gvr)
+		if namespaced && namespace != "" {
+			resourceClient = dyn.Resource(gvr).Namespace(namespace)
+		}
+
+		list, err := resourceClient.List(ctx, metav1.ListOptions{LabelSelector: managedByKasa})
+		if err != nil {
+			// Kinds like gateway are only there when their CRDs are installed
+			if !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Sprintf("failed to list %s: %v", kind, err))
+			}
+			continue
+		}
+
+		for _, item := range list.Items {
+			// Pods and ReplicaSets inherit the label from their template
+			if metav1.GetControllerOf(&item) != nil {
+				continue
+			}
+			manifestNamespace := item.GetNamespace()
+			if !namespaced {
+				if namespace != "" && !(kind == "namespace" && item.GetName() == namespace) {
+					continue
+				}
+				manifestNamespace = manifest.ClusterNamespace
+			}
+			if _, ok := stored[orphanKey(manifestNamespace, kind, item.GetName())]; ok {
+				continue
+			}
+			o := OrphanedResource{Kind: kind, Namespace: item.GetNamespace(), Name: item.GetName()}
+			if ts := item.GetCreationTimestamp(); !ts.IsZero() {
+				o.Age = formatDuration(time.Since(ts.Time))
+			}
+			if kind == "namespace" {
+				// create_namespace stores no manifest; a namespace only counts
+				// as orphaned when nothing is stored in it either
+				if namespacesWithManifests[item.GetName()] {
+					continue
+				}
+				o.Note = "no manifests are stored in this namespace"
+			}
+			orphaned = append(orphaned, o)
+		}
+	}
+
+	// A manifest is missing when its resource can't be found
+	var missing []MissingResource
+	for _, key := range sortedStoredKeys(stored) {
+		s := stored[key]
+		gvr, err := resolver.Resolve(s.kind, s.apiVersion)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", s.info.Path, err))
+			continue
+		}
+		var resourceClient dynamic.ResourceInterface = dyn.Resource(gvr)
+		if resolver.IsNamespaced(gvr, s.kind) {
+			resourceClient = dyn.Resource(gvr).Namespace(s.info.Namespace)
+		}
+		if _, err := resourceClient.Get(ctx, s.name, metav1.GetOptions{}); err != nil {
+			if apierrors.IsNotFound(err) {
+				missing = append(missing, MissingResource{Namespace: s.info.Namespace, App: s.info.App, Type: s.info.Type, Name: s.name})
+			} else {
+				errs = append(errs, fmt.Sprintf("failed to get %s %s: %v", s.kind, s.name, err))
+			}
+		}
+	}
+
+	sort.Slice(orphaned, func(i, j int) bool {
+		a, b := orphaned[i], orphaned[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+
+	result := map[string]any{
+		"manifests_checked":    len(stored),
+		"orphaned_count":       len(orphaned),
+		"missing_count":        len(missing),
+		"orphaned_in_cluster":  orphaned,
+		"missing_from_cluster": missing,
+	}
+	if len(errs) > 0 {
+		result["errors"] = errs
+	}
+
+	switch {
+	case len(orphaned) == 0 && len(missing) == 0:
+		result["message"] = "Every kasa-managed resource has a stored manifest, and every stored manifest exists in the cluster"
+	default:
+		var hints []string
+		if len(orphaned) > 0 {
+			hints = append(hints, fmt.Sprintf("%d resource(s) in the cluster without a manifest: use import_resource to manage them again, or delete_resource if they are leftovers", len(orphaned)))
+		}
+		if len(missing) > 0 {
+			hints = append(hints, fmt.Sprintf("%d manifest(s) without a resource: use apply_manifest to recreate them, or delete_manifest if they were removed on purpose", len(missing)))
+		}
+		result["suggestions"] = hints
+		result["message"] = fmt.Sprintf("Found %d orphaned resource(s) and %d missing resource(s)", len(orphaned), len(missing))
+	}
+	return result
+}
+
+// sortedStoredKeys returns the keys of m in sorted order.
+func sortedStoredKeys(m map[string]storedResource) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestFindOrphans(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	for _, m := range []struct{ namespace, app, kind, content string }{
+		{"shop", "web", "deployment", "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: shop\n"},
+		// Stored under the deployment name, not its own
+		{"shop", "web", "poddisruptionbudget", "apiVersion: policy/v1\nkind: PodDisruptionBudget\nmetadata:\n  name: web-pdb\n  namespace: shop\n"},
+		{"shop", "settings", "configmap", configMapYAML("settings", "x")},
+	} {
+		if _, err := mgr.SaveManifest(m.namespace, m.app, m.kind, []byte(m.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	kasa := map[string]string{"app.kubernetes.io/managed-by": "kasa"}
+	deploy := findTestObject("apps/v1", "Deployment", "shop", "web", kasa)
+	rs := ownedObject("apps/v1", "ReplicaSet", "web-7d4b9", "uid-rs", deploy)
+	rs.SetNamespace("shop")
+	rs.SetLabels(kasa)
+	objects := []runtime.Object{
+		findTestObject("v1", "Namespace", "", "shop", kasa),
+		findTestObject("v1", "Namespace", "", "scratch", kasa),
+		deploy,
+		rs,
+		findTestObject("policy/v1", "PodDisruptionBudget", "shop", "web-pdb", kasa),
+		findTestObject("v1", "Service", "shop", "old-api", kasa),
+		findTestObject("v1", "Service", "shop", "unlabeled", nil),
+	}
+
+	listKinds := map[schema.GroupVersionResource]string{}
+	var resolver *GVRResolver
+	for _, kind := range append([]string{"replicaset"}, orphanKinds...) {
+		gvr, err := resolver.Resolve(kind, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		listKinds[gvr] = kind + "List"
+	}
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+
+	result := findOrphans(context.Background(), dyn, nil, mgr, "")
+	if _, ok := result["errors"]; ok {
+		t.Fatalf("unexpected errors: %v", result["errors"])
+	}
+
+	orphaned, _ := result["orphaned_in_cluster"].([]OrphanedResource)
+	want := []OrphanedResource{
+		{Kind: "namespace", Name: "scratch", Note: "no manifests are stored in this namespace"},
+		{Kind: "service", Namespace: "shop", Name: "old-api"},
+	}
+	if len(orphaned) != len(want) {
+		t.Fatalf("orphaned = %+v, want %+v", orphaned, want)
+	}
+	for i := range want {
+		if orphaned[i] != want[i] {
+			t.Errorf("orphaned[%d] = %+v, want %+v", i, orphaned[i], want[i])
+		}
+	}
+
+	missing, _ := result["missing_from_cluster"].([]MissingResource)
+	if len(missing) != 1 || missing[0] != (MissingResource{Namespace: "shop", App: "settings", Type: "configmap", Name: "settings"}) {
+		t.Errorf("missing = %+v", missing)
+	}
+
+	// A namespace filter leaves out cluster-scoped resources of other namespaces
+	result = findOrphans(context.Background(), dyn, nil, mgr, "shop")
+	if orphaned, _ := result["orphaned_in_cluster"].([]OrphanedResource); len(orphaned) != 1 || orphaned[0].Name != "old-api" {
+		t.Errorf("orphaned in shop = %+v", orphaned)
+	}
+}
@@ -0,0 +1,393 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// maxOwnershipDepth bounds how far the ownership graph is walked in either
+// direction, guarding against ownerReference cycles.
+const maxOwnershipDepth = 6
+
+// dependentKinds lists, per owning kind, the kinds of its dependents that the
+// ownership graph follows downwards.
+var dependentKinds = map[string][]schema.GroupVersionKind{
+	"Deployment":  {{Group: "apps", Version: "v1", Kind: "ReplicaSet"}},
+	"ReplicaSet":  {{Version: "v1", Kind: "Pod"}},
+	"StatefulSet": {{Version: "v1", Kind: "Pod"}},
+	"DaemonSet":   {{Version: "v1", Kind: "Pod"}},
+	"CronJob":     {{Group: "batch", Version: "v1", Kind: "Job"}},
+	"Job":         {{Version: "v1", Kind: "Pod"}},
+}
+
+// OwnershipNode is a resource in the ownership graph. Owners point up the
+// ownerReference chain, Dependents down to the objects the resource owns.
+type OwnershipNode struct {
+	Kind       string           `json:"kind"`
+	APIVersion string           `json:"apiVersion"`
+	Name       string           `json:"name"`
+	Namespace  string           `json:"namespace,omitempty"`
+	Controller bool             `json:"controller,omitempty"` // the reference from the child is the controller reference
+	Missing    bool             `json:"missing,omitempty"`    // referenced owner no longer exists
+	Status     map[string]any   `json:"status,omitempty"`
+	Owners     []*OwnershipNode `json:"owners,omitempty"`
+	Dependents []*OwnershipNode `json:"dependents,omitempty"`
+	Omitted    int              `json:"omitted_inactive,omitempty"` // ReplicaSets scaled to zero that were left out
+
+	uid string
+}
+
+// OwnershipTool provides the get_ownership tool for the agent.
+type OwnershipTool struct {
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+}
+
+// NewOwnershipTool creates a new OwnershipTool.
+func NewOwnershipTool(dynamicClient dynamic.Interface, resolver *GVRResolver) *OwnershipTool {
+	return &OwnershipTool{
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+	}
+}
+
+// Name returns the tool name.
+func (t *OwnershipTool) Name() string {
+	return "get_ownership"
+}
+
+// Description returns the tool description.
+func (t *OwnershipTool) Description() string {
+	return "Show the ownership graph of a resource by following ownerReferences: up from a pod to its ReplicaSet and Deployment (or Job and CronJob, StatefulSet, DaemonSet, operator CRs), and down from a workload to its ReplicaSets, Jobs and pods. Use it to answer precisely which workload owns a pod, or which pods belong to a workload."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *OwnershipTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *OwnershipTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *OwnershipTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *OwnershipTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"kind": {
+					Type:        "string",
+					Description: "The resource kind, e.g. pod, deployment, replicaset, job, cronjob, statefulset",
+				},
+				"name": {
+					Type:        "string",
+					Description: "The resource name",
+				},
+				"api_version": {
+					Type:        "string",
+					Description: "API version for custom resources (e.g. 'postgresql.cnpg.io/v1')",
+				},
+				"direction": {
+					Type:        "string",
+					Description: "Which way to walk: 'up' (owners), 'down' (dependents) or 'both' (default)",
+					Enum:        []string{"up", "down", "both"},
+				},
+				"include_inactive": {
+					Type:        "boolean",
+					Description: "Include old ReplicaSets scaled to zero (default: false)",
+				},
+			},
+			Required: []string{"namespace", "kind", "name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *OwnershipTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+
+	kind, ok := argsMap["kind"].(string)
+	if !ok || kind == "" {
+		return map[string]any{"error": "kind is required"}, nil
+	}
+
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+
+	apiVersion, _ := argsMap["api_version"].(string)
+
+	direction, _ := argsMap["direction"].(string)
+	switch direction {
+	case "":
+		direction = "both"
+	case "up", "down", "both":
+	default:
+		return map[string]any{"error": "direction must be 'up', 'down' or 'both'"}, nil
+	}
+	includeInactive, _ := argsMap["include_inactive"].(bool)
+
+	gvr, err := t.resolver.Resolve(kind, apiVersion)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
+	defer cancel()
+
+	obj, err := t.resource(gvr, kind, namespace).Get(timeoutCtx, name, metav1.GetOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to get %s %s/%s: %v", kind, namespace, name, err)}, nil
+	}
+
+	w := &ownershipWalker{tool: t, namespace: namespace, includeInactive: includeInactive, lists: make(map[schema.GroupVersionResource][]unstructured.Unstructured)}
+	root := newOwnershipNode(obj)
+	if direction != "down" {
+		if err := w.owners(timeoutCtx, root, obj, 0); err != nil {
+			return map[string]any{"error": err.Error()}, nil
+		}
+	}
+	if direction != "up" {
+		if err := w.dependents(timeoutCtx, root, 0); err != nil {
+			return map[string]any{"error": err.Error()}, nil
+		}
+	}
+
+	result := map[string]any{
+		"graph":   root,
+		"summary": ownershipSummary(root),
+	}
+	if top := topOwner(root); top != root {
+		result["top_owner"] = fmt.Sprintf("%s/%s", top.Kind, top.Name)
+	}
+	if direction != "up" {
+		result["pod_count"] = countPods(root)
+	}
+	return result, nil
+}
+
+// resource returns the client for gvr, namespaced if the kind is.
+func (t *OwnershipTool) resource(gvr schema.GroupVersionResource, kind, namespace string) dynamic.ResourceInterface {
+	if t.resolver.IsNamespaced(gvr, kind) {
+		return t.dynamicClient.Resource(gvr).Namespace(namespace)
+	}
+	return t.dynamicClient.Resource(gvr)
+}
+
+// ownershipWalker walks the graph for one request, listing each dependent
+// kind at most once.
+type ownershipWalker struct {
+	tool            *OwnershipTool
+	namespace       string
+	includeInactive bool
+	lists           map[schema.GroupVersionResource][]unstructured.Unstructured
+}
+
+// owners follows obj's ownerReferences upwards. Owners always live in the
+// same namespace as their dependents, or are cluster-scoped.
+func (w *ownershipWalker) owners(ctx context.Context, node *OwnershipNode, obj *unstructured.Unstructured, depth int) error {
+	if depth >= maxOwnershipDepth {
+		return nil
+	}
+	for _, ref := range obj.GetOwnerReferences() {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			continue
+		}
+		owner := &OwnershipNode{
+			Kind:       ref.Kind,
+			APIVersion: ref.APIVersion,
+			Name:       ref.Name,
+			Controller: ref.Controller != nil && *ref.Controller,
+		}
+		node.Owners = append(node.Owners, owner)
+
+		gvr, err := w.tool.resolver.ResolveGVK(gv.WithKind(ref.Kind))
+		if err != nil {
+			owner.Missing = true
+			continue
+		}
+		ownerObj, err := w.tool.resource(gvr, ref.Kind, w.namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) || (err == nil && string(ownerObj.GetUID()) != string(ref.UID)) {
+			owner.Missing = true
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get owner %s/%s: %w", ref.Kind, ref.Name, err)
+		}
+		owner.Namespace = ownerObj.GetNamespace()
+		owner.Status = extractStatusSummary(ownerObj.Object["status"], strings.ToLower(ref.Kind))
+		if err := w.owners(ctx, owner, ownerObj, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependents finds the objects owned by node, for the kinds in dependentKinds.
+func (w *ownershipWalker) dependents(ctx context.Context, node *OwnershipNode, depth int) error {
+	if depth >= maxOwnershipDepth {
+		return nil
+	}
+	for _, gvk := range dependentKinds[node.Kind] {
+		items, err := w.list(ctx, gvk)
+		if err != nil {
+			return err
+		}
+		for i := range items {
+			item := &items[i]
+			if !ownedBy(item, node.uid) {
+				continue
+			}
+			child := newOwnershipNode(item)
+			if err := w.dependents(ctx, child, depth+1); err != nil {
+				return err
+			}
+			if !w.includeInactive && isInactiveReplicaSet(item) && len(child.Dependents) == 0 {
+				node.Omitted++
+				continue
+			}
+			node.Dependents = append(node.Dependents, child)
+		}
+	}
+	return nil
+}
+
+// list returns every object of a dependent kind in the namespace.
+func (w *ownershipWalker) list(ctx context.Context, gvk schema.GroupVersionKind) ([]unstructured.Unstructured, error) {
+	gvr, err := w.tool.resolver.ResolveGVK(gvk)
+	if err != nil {
+		return nil, err
+	}
+	if items, ok := w.lists[gvr]; ok {
+		return items, nil
+	}
+	list, err := w.tool.dynamicClient.Resource(gvr).Namespace(w.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", gvr.Resource, err)
+	}
+	w.lists[gvr] = list.Items
+	return list.Items, nil
+}
+
+func newOwnershipNode(obj *unstructured.Unstructured) *OwnershipNode {
+	return &OwnershipNode{
+		Kind:       obj.GetKind(),
+		APIVersion: obj.GetAPIVersion(),
+		Name:       obj.GetName(),
+		Namespace:  obj.GetNamespace(),
+		Status:     extractStatusSummary(obj.Object["status"], strings.ToLower(obj.GetKind())),
+		uid:        string(obj.GetUID()),
+	}
+}
+
+// ownedBy reports whether obj has an ownerReference to uid.
+func ownedBy(obj *unstructured.Unstructured, uid string) bool {
+	if uid == "" {
+		return false
+	}
+	for _, ref := range obj.GetOwnerReferences() {
+		if string(ref.UID) == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// isInactiveReplicaSet reports whether obj is a ReplicaSet scaled to zero,
+// such as an old revision kept for rollback.
+func isInactiveReplicaSet(obj *unstructured.Unstructured) bool {
+	if obj.GetKind() != "ReplicaSet" {
+		return false
+	}
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	return found && replicas == 0
+}
+
+// topOwner follows the controller owner to the top of the chain, stopping
+// at owners that no longer exist.
+func topOwner(node *OwnershipNode) *OwnershipNode {
+	for next := controllerOwner(node); next != nil && !next.Missing; next = controllerOwner(node) {
+		node = next
+	}
+	return node
+}
+
+// controllerOwner returns node's controller owner, falling back to its first
+// owner, or nil if it has none.
+func controllerOwner(node *OwnershipNode) *OwnershipNode {
+	if len(node.Owners) == 0 {
+		return nil
+	}
+	for _, o := range node.Owners {
+		if o.Controller {
+			return o
+		}
+	}
+	return node.Owners[0]
+}
+
+// countPods counts the pods among node's dependents.
+func countPods(node *OwnershipNode) int {
+	n := 0
+	for _, d := range node.Dependents {
+		if d.Kind == "Pod" {
+			n++
+		}
+		n += countPods(d)
+	}
+	return n
+}
+
+// ownershipSummary renders the controller owner chain, e.g.
+// "Pod/web-7d4b9-abcde <- ReplicaSet/web-7d4b9 <- Deployment/web".
+func ownershipSummary(root *OwnershipNode) string {
+	parts := []string{fmt.Sprintf("%s/%s", root.Kind, root.Name)}
+	for next := controllerOwner(root); next != nil; next = controllerOwner(next) {
+		if next.Missing {
+			parts = append(parts, fmt.Sprintf("%s/%s (missing)", next.Kind, next.Name))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s/%s", next.Kind, next.Name))
+	}
+	return strings.Join(parts, " <- ")
+}
@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func ownedObject(apiVersion, kind, name, uid string, owner *unstructured.Unstructured) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   map[string]any{"name": name, "namespace": "default", "uid": uid},
+	}}
+	if owner != nil {
+		obj.Object["metadata"].(map[string]any)["ownerReferences"] = []any{map[string]any{
+			"apiVersion": owner.GetAPIVersion(),
+			"kind":       owner.GetKind(),
+			"name":       owner.GetName(),
+			"uid":        string(owner.GetUID()),
+			"controller": true,
+		}}
+	}
+	return obj
+}
+
+func newOwnershipFake(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Group: "apps", Version: "v1", Resource: "replicasets"}: "ReplicaSetList",
+		{Version: "v1", Resource: "pods"}:                       "PodList",
+		{Group: "batch", Version: "v1", Resource: "jobs"}:       "JobList",
+	}, objects...)
+}
+
+func TestOwnershipTool(t *testing.T) {
+	deploy := ownedObject("apps/v1", "Deployment", "web", "uid-deploy", nil)
+	rs := ownedObject("apps/v1", "ReplicaSet", "web-7d4b9", "uid-rs", deploy)
+	_ = unstructured.SetNestedField(rs.Object, int64(2), "spec", "replicas")
+	oldRS := ownedObject("apps/v1", "ReplicaSet", "web-5c6f8", "uid-rs-old", deploy)
+	_ = unstructured.SetNestedField(oldRS.Object, int64(0), "spec", "replicas")
+	pod1 := ownedObject("v1", "Pod", "web-7d4b9-aaaaa", "uid-pod1", rs)
+	pod2 := ownedObject("v1", "Pod", "web-7d4b9-bbbbb", "uid-pod2", rs)
+	stray := ownedObject("v1", "Pod", "other", "uid-other", nil)
+
+	tool := NewOwnershipTool(newOwnershipFake(deploy, rs, oldRS, pod1, pod2, stray), nil)
+
+	t.Run("up from pod", func(t *testing.T) {
+		result, err := tool.Run(nil, map[string]any{"namespace": "default", "kind": "pod", "name": "web-7d4b9-aaaaa", "direction": "up"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result["top_owner"] != "Deployment/web" {
+			t.Errorf("top_owner = %v, want Deployment/web (result: %v)", result["top_owner"], result)
+		}
+		if want := "Pod/web-7d4b9-aaaaa <- ReplicaSet/web-7d4b9 <- Deployment/web"; result["summary"] != want {
+			t.Errorf("summary = %q, want %q", result["summary"], want)
+		}
+	})
+
+	t.Run("down from deployment", func(t *testing.T) {
+		result, err := tool.Run(nil, map[string]any{"namespace": "default", "kind": "deployment", "name": "web"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result["pod_count"] != 2 {
+			t.Errorf("pod_count = %v, want 2", result["pod_count"])
+		}
+		root := result["graph"].(*OwnershipNode)
+		if len(root.Dependents) != 1 || root.Dependents[0].Name != "web-7d4b9" || root.Omitted != 1 {
+			t.Errorf("expected only the active ReplicaSet with one omitted, got %+v (omitted %d)", root.Dependents, root.Omitted)
+		}
+
+		result, _ = tool.Run(nil, map[string]any{"namespace": "default", "kind": "deployment", "name": "web", "include_inactive": true})
+		if root := result["graph"].(*OwnershipNode); len(root.Dependents) != 2 {
+			t.Errorf("include_inactive should list both ReplicaSets, got %d", len(root.Dependents))
+		}
+	})
+
+	t.Run("missing owner", func(t *testing.T) {
+		orphan := ownedObject("v1", "Pod", "orphan", "uid-orphan", ownedObject("batch/v1", "Job", "gone", "uid-gone", nil))
+		tool := NewOwnershipTool(newOwnershipFake(orphan), nil)
+		result, _ := tool.Run(nil, map[string]any{"namespace": "default", "kind": "pod", "name": "orphan"})
+		if want := "Pod/orphan <- Job/gone (missing)"; result["summary"] != want {
+			t.Errorf("summary = %q, want %q", result["summary"], want)
+		}
+		if _, ok := result["top_owner"]; ok {
+			t.Errorf("top_owner should be absent when the owner is gone, got %v", result["top_owner"])
+		}
+	})
+}
+
+func TestResourceTreeTool(t *testing.T) {
+	deploy := ownedObject("apps/v1", "Deployment", "web", "uid-deploy", nil)
+	_ = unstructured.SetNestedMap(deploy.Object, map[string]any{"replicas": int64(2), "readyReplicas": int64(1)}, "status")
+	rs := ownedObject("apps/v1", "ReplicaSet", "web-7d4b9", "uid-rs", deploy)
+	_ = unstructured.SetNestedField(rs.Object, int64(2), "spec", "replicas")
+	oldRS := ownedObject("apps/v1", "ReplicaSet", "web-5c6f8", "uid-rs-old", deploy)
+	_ = unstructured.SetNestedField(oldRS.Object, int64(0), "spec", "replicas")
+	healthy := ownedObject("v1", "Pod", "web-7d4b9-aaaaa", "uid-pod1", rs)
+	_ = unstructured.SetNestedMap(healthy.Object, map[string]any{
+		"phase":             "Running",
+		"containerStatuses": []any{map[string]any{"ready": true, "restartCount": int64(0)}},
+	}, "status")
+	broken := ownedObject("v1", "Pod", "web-7d4b9-bbbbb", "uid-pod2", rs)
+	_ = unstructured.SetNestedMap(broken.Object, map[string]any{
+		"phase": "Running",
+		"containerStatuses": []any{map[string]any{
+			"ready":        false,
+			"restartCount": int64(4),
+			"state":        map[string]any{"waiting": map[string]any{"reason": "CrashLoopBackOff"}},
+		}},
+	}, "status")
+
+	tool := NewResourceTreeTool(newOwnershipFake(deploy, rs, oldRS, healthy, broken), nil)
+	result, err := tool.Run(nil, map[string]any{"namespace": "default", "kind": "pod", "name": "web-7d4b9-bbbbb"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `Deployment/web  ready 1/2  (+1 inactive)
+└── ReplicaSet/web-7d4b9
+    ├── Pod/web-7d4b9-aaaaa  Running, ready 1/1
+    └── Pod/web-7d4b9-bbbbb  Running, ready 0/1, CrashLoopBackOff, 4 restarts  <- requested
+`
+	if result["tree"] != want {
+		t.Errorf("tree:\n%s\nwant:\n%s", result["tree"], want)
+	}
+	if result["root"] != "Deployment/web" || result["pod_count"] != 2 {
+		t.Errorf("root = %v, pod_count = %v", result["root"], result["pod_count"])
+	}
+	unhealthy, _ := result["unhealthy"].([]string)
+	if len(unhealthy) != 1 || unhealthy[0] != "Pod/web-7d4b9-bbbbb: Running, ready 0/1, CrashLoopBackOff, 4 restarts (in ReplicaSet/web-7d4b9)" {
+		t.Errorf("unhealthy = %v", unhealthy)
+	}
+
+	// Asking for the old ReplicaSet shows it even though it is inactive
+	result, _ = tool.Run(nil, map[string]any{"namespace": "default", "kind": "replicaset", "name": "web-5c6f8"})
+	if tree, _ := result["tree"].(string); !strings.Contains(tree, "├── ReplicaSet/web-5c6f8  <- requested") {
+		t.Errorf("tree should include the requested inactive ReplicaSet:\n%s", tree)
+	}
+}
@@ -0,0 +1,256 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/memory"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// DefaultParallelTools is how many read-only tool calls run at once.
+const DefaultParallelTools = 4
+
+// prefetchTTL is how long an unclaimed prefetched result is kept when its
+// session gets no further model response, e.g. when the run was cancelled
+// before the agent got to the call.
+const prefetchTTL = 10 * time.Minute
+
+// ToolTiming describes one tool call, for debug output.
+type ToolTiming struct {
+	Name     string
+	Elapsed  time.Duration // time the tool itself ran
+	Waited   time.Duration // time the agent waited for a prefetched result
+	Parallel bool          // started early, alongside other read-only calls
+}
+
+// String returns e.g. "list_pods 412ms (parallel, waited 20ms)".
+func (t ToolTiming) String() string {
+	s := fmt.Sprintf("%s %s", t.Name, t.Elapsed.Round(time.Millisecond))
+	if t.Parallel {
+		s += fmt.Sprintf(" (parallel, waited %s)", t.Waited.Round(time.Millisecond))
+	}
+	return s
+}
+
+// SetParallelism sets how many read-only tool calls from one model response
+// run concurrently. 0 uses DefaultParallelTools and 1 runs them one by one.
+func (k *KubeTools) SetParallelism(n int) {
+	k.scheduler.workers = n
+}
+
+// SetToolTimer registers a function called with the timing of every tool
+// call, e.g. to print it in debug mode.
+func (k *KubeTools) SetToolTimer(fn func(ToolTiming)) {
+	k.scheduler.timer = fn
+}
+
+// PrefetchReadOnlyCalls is an llmagent.AfterModelCallback. When a model
+// response asks for several read-only tools, it starts them concurrently so
+// their results are ready by the time the agent runs the calls, which ADK
+// does one after the other. A new response ends the session's previous
+// turn, so the results it left unclaimed are dropped.
+func (k *KubeTools) PrefetchReadOnlyCalls(ctx agent.CallbackContext, resp *model.LLMResponse, respErr error) (*model.LLMResponse, error) {
+	if respErr != nil || resp == nil || resp.Partial {
+		return nil, nil
+	}
+	var calls []*genai.FunctionCall
+	if resp.Content != nil {
+		for _, p := range resp.Content.Parts {
+			if p.FunctionCall != nil {
+				calls = append(calls, p.FunctionCall)
+			}
+		}
+	}
+	k.scheduler.prefetch(ctx, calls)
+	return nil, nil
+}
+
+// toolScheduler runs read-only tool calls ahead of time on a bounded pool
+// of workers and hands the results to the agent's calls with the same
+// session, function call ID, tool and arguments. Call IDs aren't unique
+// across responses or sessions (the OpenAI backend numbers them call_0,
+// call_1, ...), so a result is only kept until the session's next model
+// response, and only handed to a call of the same session.
+type toolScheduler struct {
+	workers int
+	timer   func(ToolTiming)
+
+	mu       sync.Mutex
+	readOnly map[string]RunFunc // the chain below the scheduler, by tool name
+	pending  map[callKey]*prefetchedCall
+	sem      chan struct{}
+}
+
+// callKey identifies a function call across the sessions of `kasa serve`.
+type callKey struct {
+	session string
+	id      string
+}
+
+// prefetchedCall is a tool call started ahead of the agent.
+type prefetchedCall struct {
+	name string
+	args string // argsKey of the call's arguments
+
+	done    chan struct{}
+	result  map[string]any
+	err     error
+	elapsed time.Duration
+	started time.Time
+}
+
+func newToolScheduler() *toolScheduler {
+	return &toolScheduler{
+		readOnly: make(map[string]RunFunc),
+		pending:  make(map[callKey]*prefetchedCall),
+	}
+}
+
+// middleware returns the prefetched result of a call if there is one, and
+// runs the rest of the chain otherwise. Read-only tools are registered for
+// prefetching.
+func (s *toolScheduler) middleware(t ToolInfo, next RunFunc) RunFunc {
+	if t.Category == CategoryReadOnly {
+		s.mu.Lock()
+		s.readOnly[t.Name] = next
+		s.mu.Unlock()
+	}
+	return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		var p *prefetchedCall
+		if !sessionless(ctx) {
+			p = s.claim(ctx.SessionID(), ctx.FunctionCallID(), t.Name, args)
+		}
+		if p != nil {
+			begin := time.Now()
+			<-p.done
+			s.report(ToolTiming{Name: t.Name, Elapsed: p.elapsed, Waited: time.Since(begin), Parallel: true})
+			return p.result, p.err
+		}
+
+		begin := time.Now()
+		result, err := next(ctx, args)
+		s.report(ToolTiming{Name: t.Name, Elapsed: time.Since(begin)})
+		return result, err
+	}
+}
+
+// prefetch drops the unclaimed results of the session's previous turn and
+// starts the read-only calls among calls, if there are at least two. Calls
+// without an ID are given one, which ADK keeps.
+func (s *toolScheduler) prefetch(ctx agent.CallbackContext, calls []*genai.FunctionCall) {
+	var sessionID string
+	if ctx != nil {
+		sessionID = ctx.SessionID()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, p := range s.pending {
+		if key.session == sessionID || time.Since(p.started) > prefetchTTL {
+			delete(s.pending, key)
+		}
+	}
+
+	workers := s.workers
+	if workers == 0 {
+		workers = DefaultParallelTools
+	}
+	if workers < 2 {
+		return
+	}
+	var start []*genai.FunctionCall
+	for _, call := range calls {
+		if _, ok := s.readOnly[call.Name]; ok {
+			start = append(start, call)
+		}
+	}
+	if len(start) < 2 {
+		return
+	}
+	if s.sem == nil || cap(s.sem) != workers {
+		s.sem = make(chan struct{}, workers)
+	}
+
+	sem := s.sem
+	for _, call := range start {
+		if call.ID == "" {
+			call.ID = newCallID()
+		}
+		run, args := s.readOnly[call.Name], call.Args
+		if args == nil {
+			args = map[string]any{}
+		}
+		p := &prefetchedCall{
+			name:    call.Name,
+			args:    argsKey(args),
+			done:    make(chan struct{}),
+			started: time.Now(),
+		}
+		s.pending[callKey{sessionID, call.ID}] = p
+		tctx := &prefetchContext{CallbackContext: ctx, id: call.ID, actions: &session.EventActions{StateDelta: map[string]any{}}}
+		go func() {
+			defer close(p.done)
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			begin := time.Now()
+			p.result, p.err = run(tctx, args)
+			p.elapsed = time.Since(begin)
+		}()
+	}
+}
+
+// claim returns the session's prefetched call with the given ID, if any,
+// and if it was for the same tool and arguments.
+func (s *toolScheduler) claim(session, id, name string, args map[string]any) *prefetchedCall {
+	if id == "" {
+		return nil
+	}
+	key := callKey{session, id}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.pending[key]
+	delete(s.pending, key)
+	if p == nil || p.name != name || p.args != argsKey(args) {
+		return nil
+	}
+	return p
+}
+
+func (s *toolScheduler) report(t ToolTiming) {
+	if s.timer != nil {
+		s.timer(t)
+	}
+}
+
+// prefetchContext is the tool.Context of a prefetched call. Read-only tools
+// don't change state, so its actions are discarded.
+type prefetchContext struct {
+	agent.CallbackContext
+	id      string
+	actions *session.EventActions
+}
+
+func (c *prefetchContext) FunctionCallID() string { return c.id }
+
+func (c *prefetchContext) Actions() *session.EventActions { return c.actions }
+
+func (c *prefetchContext) SearchMemory(context.Context, string) (*memory.SearchResponse, error) {
+	return nil, errors.New("memory search is not available to prefetched tool calls")
+}
+
+// newCallID returns a function call ID like the ones ADK generates.
+func newCallID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "kasa-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// slowTool is a read-only tool that sleeps and tracks how many of its
+// calls overlap.
+type slowTool struct {
+	name             string
+	running, maxSeen *atomic.Int32
+	calls            atomic.Int32
+}
+
+func (t *slowTool) Name() string           { return t.name }
+func (t *slowTool) Description() string    { return "" }
+func (t *slowTool) IsLongRunning() bool    { return false }
+func (t *slowTool) Category() ToolCategory { return CategoryReadOnly }
+func (t *slowTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{Name: t.name}
+}
+func (t *slowTool) Run(tool.Context, any) (map[string]any, error) {
+	t.calls.Add(1)
+	n := t.running.Add(1)
+	for {
+		seen := t.maxSeen.Load()
+		if n <= seen || t.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	t.running.Add(-1)
+	return map[string]any{"tool": t.name}, nil
+}
+
+// callContext is a tool.Context that only knows its session and function
+// call IDs.
+type callContext struct {
+	tool.Context
+	session string
+	id      string
+}
+
+func (c callContext) SessionID() string      { return c.session }
+func (c callContext) FunctionCallID() string { return c.id }
+
+func TestPrefetchReadOnlyCalls(t *testing.T) {
+	var running, maxSeen atomic.Int32
+	slow := []*slowTool{
+		{name: "list_pods", running: &running, maxSeen: &maxSeen},
+		{name: "get_events", running: &running, maxSeen: &maxSeen},
+		{name: "check_deployment_health", running: &running, maxSeen: &maxSeen},
+	}
+	mutating := &recordingTool{name: "set_image"}
+
+	var timings []ToolTiming
+	k := &KubeTools{scheduler: newToolScheduler()}
+	k.SetToolTimer(func(tt ToolTiming) { timings = append(timings, tt) })
+	wrapped := withMiddleware([]tool.Tool{slow[0], slow[1], slow[2], mutating}, k.scheduler.middleware)
+
+	calls := []*genai.FunctionCall{{Name: "list_pods"}, {Name: "get_events"}, {Name: "check_deployment_health"}, {ID: "m1", Name: "set_image"}}
+	k.scheduler.prefetch(nil, calls)
+	if mutating.calls != 0 {
+		t.Fatal("mutating tool was prefetched")
+	}
+
+	// The agent then runs the calls one after the other
+	begin := time.Now()
+	for i, call := range calls {
+		if call.ID == "" {
+			t.Fatalf("call %d has no ID", i)
+		}
+		result, err := wrapped[i].(runnableTool).Run(callContext{id: call.ID}, nil)
+		if err != nil || (i < 3 && result["tool"] != call.Name) {
+			t.Errorf("call %d: %v, %v", i, result, err)
+		}
+	}
+	if elapsed := time.Since(begin); elapsed > 120*time.Millisecond {
+		t.Errorf("calls took %s, want them to overlap", elapsed)
+	}
+	if maxSeen.Load() < 2 {
+		t.Errorf("at most %d calls ran at once", maxSeen.Load())
+	}
+	for _, s := range slow {
+		if n := s.calls.Load(); n != 1 {
+			t.Errorf("%s ran %d times", s.name, n)
+		}
+	}
+	if len(timings) != 4 || !timings[0].Parallel || timings[3].Parallel {
+		t.Errorf("timings = %v", timings)
+	}
+
+	// A single read-only call runs normally
+	k.scheduler.prefetch(nil, []*genai.FunctionCall{{ID: "one", Name: "list_pods"}})
+	if _, ok := k.scheduler.pending[callKey{"", "one"}]; ok {
+		t.Error("single call was prefetched")
+	}
+
+	// A result is only handed to the same tool and arguments; a reused call
+	// ID runs the new call
+	k.scheduler.prefetch(nil, []*genai.FunctionCall{
+		{ID: "call_0", Name: "list_pods", Args: map[string]any{"namespace": "shop"}},
+		{ID: "call_1", Name: "get_events"},
+	})
+	if p := k.scheduler.claim("", "call_0", "list_pods", map[string]any{"namespace": "staging"}); p != nil {
+		t.Error("prefetched result claimed by a call with other arguments")
+	}
+	if p := k.scheduler.claim("", "call_1", "list_pods", nil); p != nil {
+		t.Error("prefetched result claimed by another tool")
+	}
+
+	// The next model response drops what the previous turn left unclaimed
+	k.scheduler.prefetch(nil, []*genai.FunctionCall{{ID: "call_0", Name: "list_pods"}, {ID: "call_1", Name: "get_events"}})
+	k.scheduler.prefetch(nil, nil)
+	if len(k.scheduler.pending) != 0 {
+		t.Errorf("%d prefetched results kept after the turn ended", len(k.scheduler.pending))
+	}
+
+	// Parallelism 1 disables prefetching
+	k.SetParallelism(1)
+	k.scheduler.prefetch(nil, []*genai.FunctionCall{{ID: "a", Name: "list_pods"}, {ID: "b", Name: "get_events"}})
+	if len(k.scheduler.pending) != 0 {
+		t.Error("calls prefetched with parallelism 1")
+	}
+}
+
+func TestPrefetchReadOnlyCalls_Sessions(t *testing.T) {
+	var running, maxSeen atomic.Int32
+	pods := &slowTool{name: "list_pods", running: &running, maxSeen: &maxSeen}
+	events := &slowTool{name: "get_events", running: &running, maxSeen: &maxSeen}
+	k := &KubeTools{scheduler: newToolScheduler()}
+	wrapped := withMiddleware([]tool.Tool{pods, events}, k.scheduler.middleware)
+
+	// Two sessions of `kasa serve` get the same call IDs from the OpenAI backend
+	calls := func() []*genai.FunctionCall {
+		return []*genai.FunctionCall{{ID: "call_0", Name: "list_pods"}, {ID: "call_1", Name: "get_events"}}
+	}
+	k.scheduler.prefetch(sessionContext{session: "a"}, calls())
+	k.scheduler.prefetch(sessionContext{session: "b"}, calls())
+	if len(k.scheduler.pending) != 4 {
+		t.Fatalf("%d calls pending, want both sessions' calls", len(k.scheduler.pending))
+	}
+
+	// Each session's calls get its own results, and none are run again
+	for _, session := range []string{"b", "a"} {
+		for i, call := range calls() {
+			if _, err := wrapped[i].(runnableTool).Run(callContext{session: session, id: call.ID}, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if pods.calls.Load() != 2 || events.calls.Load() != 2 {
+		t.Errorf("list_pods ran %d times and get_events %d, want twice each", pods.calls.Load(), events.calls.Load())
+	}
+	if len(k.scheduler.pending) != 0 {
+		t.Errorf("%d prefetched results unclaimed", len(k.scheduler.pending))
+	}
+
+	// Another session's call with the same ID doesn't claim the result
+	k.scheduler.prefetch(sessionContext{session: "a"}, calls())
+	if p := k.scheduler.claim("b", "call_0", "list_pods", nil); p != nil {
+		t.Error("prefetched result claimed by another session")
+	}
+	if p := k.scheduler.claim("a", "call_0", "list_pods", nil); p == nil {
+		t.Error("prefetched result not claimed by its session")
+	}
+}
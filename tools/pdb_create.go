@@ -0,0 +1,300 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// CreatePDBTool provides the create_pdb tool for the agent.
+type CreatePDBTool struct {
+	clientset *kubernetes.Clientset
+	manifest  *manifest.Manager
+}
+
+// NewCreatePDBTool creates a new CreatePDBTool.
+func NewCreatePDBTool(clientset *kubernetes.Clientset, manifest *manifest.Manager) *CreatePDBTool {
+	return &CreatePDBTool{
+		clientset: clientset,
+		manifest:  manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *CreatePDBTool) Name() string {
+	return "create_pdb"
+}
+
+// Description returns the tool description.
+func (t *CreatePDBTool) Description() string {
+	return "Create or update a PodDisruptionBudget for a deployment, using the deployment's own pod selector. Set exactly one of min_available or max_unavailable (a number or a percentage). Saves the manifest to git and applies it to the cluster."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *CreatePDBTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *CreatePDBTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *CreatePDBTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *CreatePDBTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"deployment": {
+					Type:        "string",
+					Description: "The deployment whose pods the budget protects",
+				},
+				"namespace": {
+					Type:        "string",
+					Description: "The target Kubernetes namespace",
+				},
+				"name": {
+					Type:        "string",
+					Description: "Name of the PodDisruptionBudget (default: <deployment>-pdb)",
+				},
+				"min_available": {
+					Type:        "string",
+					Description: "Pods that must stay available during voluntary disruptions, e.g. 2 or 50%",
+				},
+				"max_unavailable": {
+					Type:        "string",
+					Description: "Pods that may be unavailable during voluntary disruptions, e.g. 1 or 25%",
+				},
+			},
+			Required: []string{"deployment", "namespace"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *CreatePDBTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	deploymentName, ok := argsMap["deployment"].(string)
+	if !ok || deploymentName == "" {
+		return map[string]any{"error": "deployment is required"}, nil
+	}
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+	name, _ := argsMap["name"].(string)
+	if name == "" {
+		name = deploymentName + "-pdb"
+	}
+
+	minAvailable, err := intOrPercentArg(argsMap, "min_available")
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+	maxUnavailable, err := intOrPercentArg(argsMap, "max_unavailable")
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+	if (minAvailable == nil) == (maxUnavailable == nil) {
+		return map[string]any{"error": "set exactly one of min_available or max_unavailable"}, nil
+	}
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
+	defer cancel()
+
+	deployment, err := t.clientset.AppsV1().Deployments(namespace).Get(timeoutCtx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to get deployment: %v", err)}, nil
+	}
+
+	pdb := &policyv1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "policy/v1",
+			Kind:       "PodDisruptionBudget",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       deploymentName,
+				"app.kubernetes.io/managed-by": "kasa",
+			},
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable:   minAvailable,
+			MaxUnavailable: maxUnavailable,
+			Selector:       deployment.Spec.Selector.DeepCopy(),
+		},
+	}
+
+	yamlBytes, err := yaml.Marshal(pdb)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to marshal pdb: %v", err)}, nil
+	}
+	// Stored with the deployment's manifests, since it only makes sense alongside them
+	manifestPath, err := t.manifest.SaveManifest(namespace, deploymentName, "poddisruptionbudget", yamlBytes)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to save manifest: %v", err)}, nil
+	}
+
+	var action string
+	existing, err := t.clientset.PolicyV1().PodDisruptionBudgets(namespace).Get(timeoutCtx, name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return map[string]any{"error": fmt.Sprintf("failed to check existing pdb: %v", err)}, nil
+		}
+		if _, err := t.clientset.PolicyV1().PodDisruptionBudgets(namespace).Create(timeoutCtx, pdb, metav1.CreateOptions{}); err != nil {
+			return map[string]any{"error": fmt.Sprintf("failed to create pdb: %v", err)}, nil
+		}
+		action = "created"
+	} else {
+		pdb.ResourceVersion = existing.ResourceVersion
+		if _, err := t.clientset.PolicyV1().PodDisruptionBudgets(namespace).Update(timeoutCtx, pdb, metav1.UpdateOptions{}); err != nil {
+			return map[string]any{"error": fmt.Sprintf("failed to update pdb: %v", err)}, nil
+		}
+		action = "updated"
+	}
+
+	result := map[string]any{
+		"success":       true,
+		"action":        action,
+		"name":          name,
+		"namespace":     namespace,
+		"deployment":    deploymentName,
+		"selector":      metav1.FormatLabelSelector(pdb.Spec.Selector),
+		"manifest_path": manifestPath,
+		"message":       fmt.Sprintf("PodDisruptionBudget %s %s in namespace %s", name, action, namespace),
+	}
+	if minAvailable != nil {
+		result["min_available"] = minAvailable.String()
+	} else {
+		result["max_unavailable"] = maxUnavailable.String()
+	}
+	if warning := pdbBlocksDisruptions(pdb, deployment); warning != "" {
+		result["warning"] = warning
+	}
+	return result, nil
+}
+
+// intOrPercentArg parses an argument given as a number or a percentage,
+// such as min_available or max_surge. Returns nil if the argument is absent.
+func intOrPercentArg(argsMap map[string]any, key string) (*intstr.IntOrString, error) {
+	var v intstr.IntOrString
+	switch raw := argsMap[key].(type) {
+	case nil:
+		return nil, nil
+	case float64:
+		v = intstr.FromInt32(int32(raw))
+	case string:
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			return nil, nil
+		}
+		if pct, ok := strings.CutSuffix(raw, "%"); ok {
+			n, err := strconv.Atoi(pct)
+			if err != nil || n < 0 || n > 100 {
+				return nil, fmt.Errorf("%s must be a number or a percentage between 0%% and 100%%, got %q", key, raw)
+			}
+			v = intstr.FromString(raw)
+		} else {
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a number or a percentage, got %q", key, raw)
+			}
+			v = intstr.FromInt32(int32(n))
+		}
+	default:
+		return nil, fmt.Errorf("%s must be a number or a percentage", key)
+	}
+	if v.Type == intstr.Int && v.IntVal < 0 {
+		return nil, fmt.Errorf("%s must not be negative", key)
+	}
+	return &v, nil
+}
+
+// pdbBlocksDisruptions warns when a budget allows no voluntary disruption
+// of the deployment at all, which makes node drains hang.
+func pdbBlocksDisruptions(pdb *policyv1.PodDisruptionBudget, deployment *appsv1.Deployment) string {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	if m := pdb.Spec.MaxUnavailable; m != nil {
+		if allowed, err := intstr.GetScaledValueFromIntOrPercent(m, int(replicas), true); err == nil && allowed == 0 {
+			return "max_unavailable allows no pod to be evicted; node drains will hang until the budget is changed"
+		}
+	}
+	if m := pdb.Spec.MinAvailable; m != nil {
+		if required, err := intstr.GetScaledValueFromIntOrPercent(m, int(replicas), true); err == nil && required >= int(replicas) {
+			return fmt.Sprintf("min_available requires all %d replica(s) to stay up, so no pod can be evicted; node drains will hang until the deployment is scaled up or the budget lowered", replicas)
+		}
+	}
+	return ""
+}
+
+// pdbsCovering returns the names of the budgets in pdbs that select pods
+// with the given labels in namespace.
+func pdbsCovering(pdbs []policyv1.PodDisruptionBudget, namespace string, podLabels map[string]string) []string {
+	var names []string
+	for _, pdb := range pdbs {
+		if pdb.Namespace != namespace || pdb.Spec.Selector == nil {
+			continue
+		}
+		// In policy/v1 an empty selector matches every pod in the namespace
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(podLabels)) {
+			names = append(names, pdb.Name)
+		}
+	}
+	return names
+}
+
+// deploymentsWithoutPDB returns "namespace/name" for each multi-replica
+// deployment no PodDisruptionBudget covers.
+func deploymentsWithoutPDB(deps []appsv1.Deployment, pdbs []policyv1.PodDisruptionBudget) []string {
+	var uncovered []string
+	for _, dep := range deps {
+		if dep.Spec.Replicas == nil || *dep.Spec.Replicas < 2 {
+			continue
+		}
+		if len(pdbsCovering(pdbs, dep.Namespace, dep.Spec.Template.Labels)) == 0 {
+			uncovered = append(uncovered, dep.Namespace+"/"+dep.Name)
+		}
+	}
+	return uncovered
+}
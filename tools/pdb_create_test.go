@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestIntOrPercentArg(t *testing.T) {
+	tests := []struct {
+		arg     any
+		want    string
+		wantNil bool
+		wantErr bool
+	}{
+		{arg: nil, wantNil: true},
+		{arg: "", wantNil: true},
+		{arg: float64(2), want: "2"},
+		{arg: "1", want: "1"},
+		{arg: "50%", want: "50%"},
+		{arg: "150%", wantErr: true},
+		{arg: "two", wantErr: true},
+		{arg: "-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := intOrPercentArg(map[string]any{"min_available": tt.arg}, "min_available")
+		switch {
+		case tt.wantErr:
+			if err == nil {
+				t.Errorf("intOrPercentArg(%v) should fail", tt.arg)
+			}
+		case err != nil:
+			t.Errorf("intOrPercentArg(%v) failed: %v", tt.arg, err)
+		case tt.wantNil:
+			if got != nil {
+				t.Errorf("intOrPercentArg(%v) = %v, want nil", tt.arg, got)
+			}
+		case got == nil || got.String() != tt.want:
+			t.Errorf("intOrPercentArg(%v) = %v, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestPDBBlocksDisruptions(t *testing.T) {
+	three := int32(3)
+	dep := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: &three}}
+	pdb := func(minAvailable, maxUnavailable *intstr.IntOrString) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{Spec: policyv1.PodDisruptionBudgetSpec{MinAvailable: minAvailable, MaxUnavailable: maxUnavailable}}
+	}
+	value := intstr.Parse
+
+	for _, tt := range []struct {
+		pdb     *policyv1.PodDisruptionBudget
+		blocked bool
+	}{
+		{pdb(ptrTo(value("2")), nil), false},
+		{pdb(ptrTo(value("3")), nil), true},
+		{pdb(ptrTo(value("100%")), nil), true},
+		{pdb(nil, ptrTo(value("1"))), false},
+		{pdb(nil, ptrTo(value("0"))), true},
+	} {
+		if got := pdbBlocksDisruptions(tt.pdb, dep) != ""; got != tt.blocked {
+			t.Errorf("pdbBlocksDisruptions(min=%v, max=%v) blocked = %v, want %v", tt.pdb.Spec.MinAvailable, tt.pdb.Spec.MaxUnavailable, got, tt.blocked)
+		}
+	}
+}
+
+func ptrTo(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
+
+func TestDeploymentsWithoutPDB(t *testing.T) {
+	one, three := int32(1), int32(3)
+	deployment := func(namespace, name string, replicas *int32) appsv1.Deployment {
+		return appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: replicas,
+				Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}}},
+			},
+		}
+	}
+	deps := []appsv1.Deployment{
+		deployment("shop", "web", &three),
+		deployment("shop", "api", &three),
+		deployment("shop", "worker", &one),
+		deployment("billing", "web", &three),
+	}
+	pdbs := []policyv1.PodDisruptionBudget{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "web-pdb"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "batch"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "batch"}}},
+		},
+	}
+
+	got := deploymentsWithoutPDB(deps, pdbs)
+	want := []string{"shop/api", "billing/web"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("deploymentsWithoutPDB = %v, want %v", got, want)
+	}
+	if covering := pdbsCovering(pdbs, "shop", map[string]string{"app": "web", "tier": "frontend"}); strings.Join(covering, ",") != "web-pdb" {
+		t.Errorf("pdbsCovering = %v, want [web-pdb]", covering)
+	}
+
+	// An empty selector covers the whole namespace
+	pdbs = append(pdbs, policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "billing", Name: "all"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{}},
+	})
+	if got := deploymentsWithoutPDB(deps, pdbs); !reflect.DeepEqual(got, []string{"shop/api"}) {
+		t.Errorf("deploymentsWithoutPDB = %v, want [shop/api]", got)
+	}
+}
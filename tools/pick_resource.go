@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"encoding/json"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// PickResourceTool lets the user choose among resources from a list instead
+// of typing their names.
+type PickResourceTool struct{}
+
+// NewPickResourceTool creates a new PickResourceTool.
+func NewPickResourceTool() *PickResourceTool {
+	return &PickResourceTool{}
+}
+
+// Name returns the tool name.
+func (t *PickResourceTool) Name() string {
+	return "pick_resource"
+}
+
+// Description returns the tool description.
+func (t *PickResourceTool) Description() string {
+	return "Let the user pick one or more resources from a list, e.g. which pod of a deployment to get logs from or which of several matching deployments they mean. " +
+		"Use it instead of asking the user to type exact names when you have listed the candidates. The selection follows as the response to this call."
+}
+
+// IsLongRunning returns true: the selection arrives later, as a new
+// response to the same call.
+func (t *PickResourceTool) IsLongRunning() bool {
+	return true
+}
+
+// Category returns the tool category. Picking changes nothing, so every
+// agent can ask the user to.
+func (t *PickResourceTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *PickResourceTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *PickResourceTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"prompt": {
+					Type:        "string",
+					Description: "What the user is picking for, e.g. 'Which pod should I get logs from?'",
+				},
+				"items": {
+					Type:        "array",
+					Description: "The resources to pick from (2-50)",
+					Items: &genai.Schema{
+						Type: "object",
+						Properties: map[string]*genai.Schema{
+							"name": {
+								Type:        "string",
+								Description: "The resource name",
+							},
+							"namespace": {
+								Type:        "string",
+								Description: "The resource namespace",
+							},
+							"kind": {
+								Type:        "string",
+								Description: "The resource kind, e.g. pod",
+							},
+							"detail": {
+								Type:        "string",
+								Description: "A short description to tell the items apart, e.g. 'Running, 3 restarts, node-2'",
+							},
+						},
+						Required: []string{"name"},
+					},
+				},
+				"multiple": {
+					Type:        "boolean",
+					Description: "Allow picking several items (default false)",
+				},
+			},
+			Required: []string{"prompt", "items"},
+		},
+	}
+}
+
+// maxPickItems keeps the list usable in a terminal.
+const maxPickItems = 50
+
+// Run executes the tool. Like ask_clarification it does not block: it
+// checks the list and returns a status saying a selection is needed. The
+// REPL sends the selection as the final response to the call, with status
+// "selected" and the chosen items, or "cancelled".
+func (t *PickResourceTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	prompt, _ := argsMap["prompt"].(string)
+	if prompt == "" {
+		return map[string]any{"error": "prompt is required"}, nil
+	}
+
+	items, ok := argsMap["items"].([]any)
+	if !ok || len(items) == 0 {
+		return map[string]any{"error": "at least one item is required"}, nil
+	}
+	if len(items) > maxPickItems {
+		return map[string]any{"error": "too many items to pick from; narrow the list down to at most 50"}, nil
+	}
+	for i, item := range items {
+		itemMap, ok := item.(map[string]any)
+		if !ok {
+			return map[string]any{"error": "invalid item format", "index": i}, nil
+		}
+		if name, _ := itemMap["name"].(string); name == "" {
+			return map[string]any{"error": "item missing name", "index": i}, nil
+		}
+	}
+
+	return map[string]any{
+		"status":  "awaiting_selection",
+		"message": "List displayed to the user. Their selection will follow as the response to this call; wait for it.",
+		"prompt":  prompt,
+		"items":   items,
+	}, nil
+}
@@ -0,0 +1,33 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPickResource(t *testing.T) {
+	pods := []any{
+		map[string]any{"name": "web-7d4b9-abcde", "namespace": "shop", "kind": "pod", "detail": "Running"},
+		map[string]any{"name": "web-7d4b9-fghij", "namespace": "shop", "kind": "pod", "detail": "CrashLoopBackOff"},
+	}
+	result, err := NewPickResourceTool().Run(nil, map[string]any{"prompt": "Which pod?", "items": pods})
+	if err != nil || result["status"] != "awaiting_selection" {
+		t.Errorf("expected the list to await a selection, got %v, %v", result, err)
+	}
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"items": pods}, "prompt is required"},
+		{map[string]any{"prompt": "Which pod?"}, "at least one item"},
+		{map[string]any{"prompt": "Which pod?", "items": []any{map[string]any{"namespace": "shop"}}}, "missing name"},
+		{map[string]any{"prompt": "Which pod?", "items": make([]any, maxPickItems+1)}, "too many items"},
+	}
+	for _, tt := range tests {
+		result, _ := NewPickResourceTool().Run(nil, tt.args)
+		if msg, _ := result["error"].(string); !strings.Contains(msg, tt.want) {
+			t.Errorf("%v: got %v, want error %q", tt.args, result, tt.want)
+		}
+	}
+}
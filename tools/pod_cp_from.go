@@ -0,0 +1,300 @@
+package tools
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/homedir"
+)
+
+const (
+	// cpDefaultMaxBytes and cpMaxBytes bound how much cp_from_pod and
+	// cp_to_pod transfer.
+	cpDefaultMaxBytes = 10 << 20
+	cpMaxBytes        = 200 << 20
+
+	// cpInlineMaxBytes is the largest text file returned inline by cp_from_pod.
+	cpInlineMaxBytes = 16 << 10
+)
+
+// CopiedFile describes a file extracted by cp_from_pod.
+type CopiedFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// CopyFromPodTool provides the cp_from_pod tool for the agent.
+type CopyFromPodTool struct {
+	clientset  *kubernetes.Clientset
+	restConfig *rest.Config
+}
+
+// NewCopyFromPodTool creates a new CopyFromPodTool.
+func NewCopyFromPodTool(clientset *kubernetes.Clientset, restConfig *rest.Config) *CopyFromPodTool {
+	return &CopyFromPodTool{
+		clientset:  clientset,
+		restConfig: restConfig,
+	}
+}
+
+// Name returns the tool name.
+func (t *CopyFromPodTool) Name() string {
+	return "cp_from_pod"
+}
+
+// Description returns the tool description.
+func (t *CopyFromPodTool) Description() string {
+	return "Copy a file or directory out of a running container (like 'kubectl cp', requires tar in the container) into ~/.kasa/downloads/<namespace>/<pod>/. Small text files are also returned inline. Use it to pull config files, heap dumps or logs written to disk while debugging."
+}
+
+// IsLongRunning returns true as large copies can take a while.
+func (t *CopyFromPodTool) IsLongRunning() bool {
+	return true
+}
+
+// Category returns the tool category.
+func (t *CopyFromPodTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *CopyFromPodTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *CopyFromPodTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"pod": {
+					Type:        "string",
+					Description: "The name of the pod",
+				},
+				"container": {
+					Type:        "string",
+					Description: "Container to copy from (default: the pod's default container)",
+				},
+				"path": {
+					Type:        "string",
+					Description: "File or directory in the container, e.g. /etc/nginx/nginx.conf or /tmp/heapdump.hprof",
+				},
+				"max_bytes": {
+					Type:        "integer",
+					Description: fmt.Sprintf("Abort if the copied files exceed this many bytes (default: %d, max: %d)", cpDefaultMaxBytes, cpMaxBytes),
+				},
+			},
+			Required: []string{"namespace", "pod", "path"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *CopyFromPodTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+
+	podName, ok := argsMap["pod"].(string)
+	if !ok || podName == "" {
+		return map[string]any{"error": "pod is required"}, nil
+	}
+
+	srcPath, ok := argsMap["path"].(string)
+	if !ok || srcPath == "" {
+		return map[string]any{"error": "path is required"}, nil
+	}
+	srcPath = path.Clean(srcPath)
+	if srcPath == "/" || srcPath == "." {
+		return map[string]any{"error": "path must name a file or directory, not the container root"}, nil
+	}
+
+	maxBytes := int64(cpDefaultMaxBytes)
+	if mb, ok := argsMap["max_bytes"].(float64); ok && mb > 0 {
+		maxBytes = min(int64(mb), cpMaxBytes)
+	}
+
+	container, _ := argsMap["container"].(string)
+	pod, container, err := runningPodContainer(toolContext(ctx), t.clientset, namespace, podName, container)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	home := homedir.HomeDir()
+	if home == "" {
+		return map[string]any{"error": "cannot determine home directory for downloads"}, nil
+	}
+	destDir := filepath.Join(home, ".kasa", "downloads", namespace, pod.Name)
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to create download directory: %v", err)}, nil
+	}
+
+	execCtx, cancel := context.WithTimeout(toolContext(ctx), 5*time.Minute)
+	defer cancel()
+
+	// Stream tar output straight into the extractor
+	reader, writer := io.Pipe()
+	execErr := make(chan error, 1)
+	go func() {
+		command := []string{"tar", "cf", "-", "-C", path.Dir(srcPath), path.Base(srcPath)}
+		err := execInPod(execCtx, t.clientset, t.restConfig, namespace, pod.Name, container, command, nil, writer)
+		writer.CloseWithError(err)
+		execErr <- err
+	}()
+
+	files, err := extractTar(reader, destDir, maxBytes)
+	if err != nil {
+		// Stop the remote tar and let the exec goroutine finish
+		cancel()
+		reader.CloseWithError(err)
+		<-execErr
+		return map[string]any{"error": fmt.Sprintf("failed to copy %s from %s/%s: %v", srcPath, namespace, pod.Name, err)}, nil
+	}
+	if err := <-execErr; err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to copy %s from %s/%s: %v", srcPath, namespace, pod.Name, err)}, nil
+	}
+	if len(files) == 0 {
+		return map[string]any{"error": fmt.Sprintf("%s contains no regular files", srcPath)}, nil
+	}
+
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+
+	localPath := filepath.Join(destDir, path.Base(srcPath))
+	result := map[string]any{
+		"success":     true,
+		"namespace":   namespace,
+		"pod":         pod.Name,
+		"container":   container,
+		"path":        srcPath,
+		"local_path":  localPath,
+		"files":       files,
+		"total_bytes": total,
+		"message":     fmt.Sprintf("Copied %d file(s), %d bytes, from %s:%s to %s", len(files), total, pod.Name, srcPath, localPath),
+	}
+
+	// Return a small text file inline so the agent can read it right away
+	if len(files) == 1 && files[0].Size <= cpInlineMaxBytes {
+		if data, err := os.ReadFile(filepath.Join(destDir, files[0].Path)); err == nil && utf8.Valid(data) {
+			result["content"] = string(data)
+		}
+	}
+
+	return result, nil
+}
+
+// runningPodContainer fetches a pod and resolves the container to exec into.
+// The pod must be running.
+func runningPodContainer(ctx context.Context, clientset kubernetes.Interface, namespace, name, container string) (*corev1.Pod, string, error) {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get pod %s/%s: %w", namespace, name, err)
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		return nil, "", fmt.Errorf("pod %s/%s is %s; files can only be copied from running pods", namespace, name, pod.Status.Phase)
+	}
+	container, err = execContainer(pod, container)
+	if err != nil {
+		return nil, "", err
+	}
+	return pod, container, nil
+}
+
+// extractTar writes the regular files and directories in a tar stream below
+// destDir and returns the files written, relative to destDir. Entries that
+// would escape destDir are rejected; links and special files are skipped.
+// Fails once the files exceed maxBytes.
+func extractTar(r io.Reader, destDir string, maxBytes int64) ([]CopiedFile, error) {
+	files := []CopiedFile{}
+	var total int64
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading tar stream: %w", err)
+		}
+
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("refusing tar entry outside the target directory: %s", hdr.Name)
+		}
+		target := filepath.Join(destDir, filepath.FromSlash(name))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			total += hdr.Size
+			if total > maxBytes {
+				return nil, fmt.Errorf("copy exceeds the limit of %d bytes; raise max_bytes or copy a smaller path", maxBytes)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return nil, err
+			}
+			if err := writeTarFile(tr, target, hdr.Size); err != nil {
+				return nil, err
+			}
+			files = append(files, CopiedFile{Path: name, Size: hdr.Size})
+		}
+	}
+}
+
+// writeTarFile writes the current tar entry to target.
+func writeTarFile(r io.Reader, target string, size int64) error {
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(f, r, size); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %w", target, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExtractTar(t *testing.T) {
+	archive, err := singleFileTar("nginx.conf", []byte("worker_processes 1;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	files, err := extractTar(bytes.NewReader(archive), dir, 1024)
+	if err != nil {
+		t.Fatalf("extractTar: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "nginx.conf" || files[0].Size != 20 {
+		t.Errorf("files = %+v", files)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "nginx.conf"))
+	if err != nil || string(data) != "worker_processes 1;\n" {
+		t.Errorf("extracted content = %q (%v)", data, err)
+	}
+
+	if _, err := extractTar(bytes.NewReader(archive), t.TempDir(), 10); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Errorf("expected size limit error, got %v", err)
+	}
+}
+
+func TestExtractTarRejectsEscapes(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	_ = tw.WriteHeader(&tar.Header{Name: "../../etc/passwd", Mode: 0644, Size: 1, Typeflag: tar.TypeReg})
+	_, _ = tw.Write([]byte("x"))
+	_ = tw.Close()
+
+	if _, err := extractTar(&buf, t.TempDir(), 1024); err == nil {
+		t.Error("entry outside the target directory should be refused")
+	}
+}
+
+func TestExtractTarSkipsLinks(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	_ = tw.WriteHeader(&tar.Header{Name: "logs", Mode: 0755, Typeflag: tar.TypeDir})
+	_ = tw.WriteHeader(&tar.Header{Name: "logs/current", Linkname: "/etc/shadow", Typeflag: tar.TypeSymlink})
+	_ = tw.WriteHeader(&tar.Header{Name: "logs/app.log", Mode: 0644, Size: 3, Typeflag: tar.TypeReg})
+	_, _ = tw.Write([]byte("ok\n"))
+	_ = tw.Close()
+
+	dir := t.TempDir()
+	files, err := extractTar(&buf, dir, 1024)
+	if err != nil {
+		t.Fatalf("extractTar: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "logs/app.log" {
+		t.Errorf("files = %+v, want only logs/app.log", files)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "logs", "current")); !os.IsNotExist(err) {
+		t.Error("symlink should not be created")
+	}
+}
+
+func TestExecContainer(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "proxy"}}},
+	}
+	if c, err := execContainer(pod, ""); err != nil || c != "app" {
+		t.Errorf("default container = %q (%v), want app", c, err)
+	}
+	if c, err := execContainer(pod, "proxy"); err != nil || c != "proxy" {
+		t.Errorf("container = %q (%v), want proxy", c, err)
+	}
+	pod.Annotations = map[string]string{"kubectl.kubernetes.io/default-container": "proxy"}
+	if c, _ := execContainer(pod, ""); c != "proxy" {
+		t.Errorf("annotated default container = %q, want proxy", c)
+	}
+	if _, err := execContainer(pod, "db"); err == nil {
+		t.Error("expected error for unknown container")
+	}
+}
@@ -0,0 +1,229 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// CopyToPodTool provides the cp_to_pod tool for the agent.
+type CopyToPodTool struct {
+	clientset  *kubernetes.Clientset
+	restConfig *rest.Config
+	dryRun     bool // validate only; exec has no server-side dry-run
+}
+
+// NewCopyToPodTool creates a new CopyToPodTool. With dryRun set the copy is
+// validated but not performed.
+func NewCopyToPodTool(clientset *kubernetes.Clientset, restConfig *rest.Config, dryRun bool) *CopyToPodTool {
+	return &CopyToPodTool{
+		clientset:  clientset,
+		restConfig: restConfig,
+		dryRun:     dryRun,
+	}
+}
+
+// Name returns the tool name.
+func (t *CopyToPodTool) Name() string {
+	return "cp_to_pod"
+}
+
+// Description returns the tool description.
+func (t *CopyToPodTool) Description() string {
+	return "Write a file into a running container (like 'kubectl cp', requires tar in the container), from inline content or a local file. The change lives only in that container and is lost when the pod restarts; use it for debugging, not configuration."
+}
+
+// IsLongRunning returns true as large copies can take a while.
+func (t *CopyToPodTool) IsLongRunning() bool {
+	return true
+}
+
+// Category returns the tool category.
+func (t *CopyToPodTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *CopyToPodTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *CopyToPodTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"pod": {
+					Type:        "string",
+					Description: "The name of the pod",
+				},
+				"container": {
+					Type:        "string",
+					Description: "Container to copy into (default: the pod's default container)",
+				},
+				"path": {
+					Type:        "string",
+					Description: "Destination file path in the container, e.g. /tmp/debug.conf. An existing file is overwritten",
+				},
+				"content": {
+					Type:        "string",
+					Description: "File content to write. Either content or local_path is required",
+				},
+				"local_path": {
+					Type:        "string",
+					Description: "Local file to upload instead of content",
+				},
+				"max_bytes": {
+					Type:        "integer",
+					Description: fmt.Sprintf("Refuse files larger than this many bytes (default: %d, max: %d)", cpDefaultMaxBytes, cpMaxBytes),
+				},
+			},
+			Required: []string{"namespace", "pod", "path"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *CopyToPodTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+
+	podName, ok := argsMap["pod"].(string)
+	if !ok || podName == "" {
+		return map[string]any{"error": "pod is required"}, nil
+	}
+
+	destPath, ok := argsMap["path"].(string)
+	if !ok || destPath == "" {
+		return map[string]any{"error": "path is required"}, nil
+	}
+	destPath = path.Clean(destPath)
+	if destPath == "/" || destPath == "." {
+		return map[string]any{"error": "path must name a file"}, nil
+	}
+
+	maxBytes := int64(cpDefaultMaxBytes)
+	if mb, ok := argsMap["max_bytes"].(float64); ok && mb > 0 {
+		maxBytes = min(int64(mb), cpMaxBytes)
+	}
+
+	content, hasContent := argsMap["content"].(string)
+	localPath, _ := argsMap["local_path"].(string)
+	var data []byte
+	switch {
+	case hasContent && localPath != "":
+		return map[string]any{"error": "specify either content or local_path, not both"}, nil
+	case hasContent:
+		data = []byte(content)
+	case localPath != "":
+		info, err := os.Stat(localPath)
+		if err != nil {
+			return map[string]any{"error": fmt.Sprintf("failed to read local file: %v", err)}, nil
+		}
+		if !info.Mode().IsRegular() {
+			return map[string]any{"error": fmt.Sprintf("%s is not a regular file", localPath)}, nil
+		}
+		if info.Size() > maxBytes {
+			return map[string]any{"error": fmt.Sprintf("%s is %d bytes, over the limit of %d; raise max_bytes", localPath, info.Size(), maxBytes)}, nil
+		}
+		if data, err = os.ReadFile(localPath); err != nil {
+			return map[string]any{"error": fmt.Sprintf("failed to read local file: %v", err)}, nil
+		}
+	default:
+		return map[string]any{"error": "content or local_path is required"}, nil
+	}
+	if int64(len(data)) > maxBytes {
+		return map[string]any{"error": fmt.Sprintf("content is %d bytes, over the limit of %d; raise max_bytes", len(data), maxBytes)}, nil
+	}
+
+	container, _ := argsMap["container"].(string)
+	pod, container, err := runningPodContainer(toolContext(ctx), t.clientset, namespace, podName, container)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	result := map[string]any{
+		"namespace": namespace,
+		"pod":       pod.Name,
+		"container": container,
+		"path":      destPath,
+		"bytes":     len(data),
+	}
+
+	if t.dryRun {
+		result["success"] = true
+		result["dry_run"] = true
+		result["message"] = fmt.Sprintf("Dry run: would write %d bytes to %s:%s (container %s)", len(data), pod.Name, destPath, container)
+		return result, nil
+	}
+
+	archive, err := singleFileTar(path.Base(destPath), data)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to build archive: %v", err)}, nil
+	}
+
+	execCtx, cancel := context.WithTimeout(toolContext(ctx), 5*time.Minute)
+	defer cancel()
+
+	command := []string{"tar", "xmf", "-", "-C", path.Dir(destPath)}
+	if err := execInPod(execCtx, t.clientset, t.restConfig, namespace, pod.Name, container, command, bytes.NewReader(archive), nil); err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to copy to %s:%s: %v", pod.Name, destPath, err)}, nil
+	}
+
+	result["success"] = true
+	result["message"] = fmt.Sprintf("Wrote %d bytes to %s:%s (container %s); the file is lost when the pod restarts", len(data), pod.Name, destPath, container)
+	return result, nil
+}
+
+// singleFileTar returns a tar archive holding one file.
+func singleFileTar(name string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,158 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// EvictPodTool provides the evict_pod tool for the agent.
+type EvictPodTool struct {
+	clientset *kubernetes.Clientset
+}
+
+// NewEvictPodTool creates a new EvictPodTool.
+func NewEvictPodTool(clientset *kubernetes.Clientset) *EvictPodTool {
+	return &EvictPodTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *EvictPodTool) Name() string {
+	return "evict_pod"
+}
+
+// Description returns the tool description.
+func (t *EvictPodTool) Description() string {
+	return "Evict a single pod through the eviction API so its controller replaces it. Unlike deleting the pod, eviction respects PodDisruptionBudgets and is refused if it would take the workload below its budget. Use to restart one wedged pod without restarting the whole deployment."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *EvictPodTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *EvictPodTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *EvictPodTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *EvictPodTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"name": {
+					Type:        "string",
+					Description: "The name of the pod to evict",
+				},
+				"grace_period_seconds": {
+					Type:        "integer",
+					Description: "Override the pod's termination grace period (optional)",
+				},
+				"allow_unmanaged": {
+					Type:        "boolean",
+					Description: "Evict a pod that has no controller and will not be recreated (default: false)",
+				},
+			},
+			Required: []string{"namespace", "name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *EvictPodTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+
+	allowUnmanaged, _ := argsMap["allow_unmanaged"].(bool)
+
+	deleteOptions := &metav1.DeleteOptions{}
+	if gp, ok := argsMap["grace_period_seconds"].(float64); ok {
+		grace := int64(gp)
+		deleteOptions.GracePeriodSeconds = &grace
+	}
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
+	defer cancel()
+
+	pod, err := t.clientset.CoreV1().Pods(namespace).Get(timeoutCtx, name, metav1.GetOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to get pod %s/%s: %v", namespace, name, err)}, nil
+	}
+
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil && !allowUnmanaged {
+		return map[string]any{
+			"error": fmt.Sprintf("pod %s/%s has no controller and will not be recreated after eviction; set allow_unmanaged=true to evict it anyway", namespace, name),
+		}, nil
+	}
+
+	eviction := &policyv1.Eviction{
+		ObjectMeta:    metav1.ObjectMeta{Name: name, Namespace: namespace},
+		DeleteOptions: deleteOptions,
+	}
+	if err := t.clientset.PolicyV1().Evictions(namespace).Evict(timeoutCtx, eviction); err != nil {
+		if errors.IsTooManyRequests(err) {
+			return map[string]any{
+				"success": false,
+				"blocked": true,
+				"error":   fmt.Sprintf("eviction of %s/%s refused, most likely by a PodDisruptionBudget: %v", namespace, name, err),
+				"hint":    "Wait for other replicas to become ready and retry, or check the PodDisruptionBudget with list_resources",
+			}, nil
+		}
+		return map[string]any{"error": fmt.Sprintf("failed to evict pod %s/%s: %v", namespace, name, err)}, nil
+	}
+
+	result := map[string]any{
+		"success":   true,
+		"namespace": namespace,
+		"name":      name,
+		"node":      pod.Spec.NodeName,
+	}
+	if owner != nil {
+		result["owner"] = fmt.Sprintf("%s/%s", owner.Kind, owner.Name)
+		result["message"] = fmt.Sprintf("Evicted pod %s/%s; %s %s will create a replacement", namespace, name, owner.Kind, owner.Name)
+	} else {
+		result["message"] = fmt.Sprintf("Evicted unmanaged pod %s/%s; it will not be recreated", namespace, name)
+	}
+	return result, nil
+}
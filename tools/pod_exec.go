@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// execInPod runs command in a container, streaming stdin and stdout. Output
+// on stderr is included in the returned error when the command fails.
+func execInPod(ctx context.Context, clientset kubernetes.Interface, config *rest.Config, namespace, pod, container string, command []string, stdin io.Reader, stdout io.Writer) error {
+	if config == nil {
+		return fmt.Errorf("pod exec is not available: no REST config")
+	}
+
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     stdin != nil,
+			Stdout:    stdout != nil,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	// Prefer websockets and fall back to SPDY for older API servers, like kubectl
+	spdyExec, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("failed to create executor: %w", err)
+	}
+	wsExec, err := remotecommand.NewWebSocketExecutor(config, "GET", req.URL().String())
+	if err != nil {
+		return fmt.Errorf("failed to create executor: %w", err)
+	}
+	exec, err := remotecommand.NewFallbackExecutor(wsExec, spdyExec, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create executor: %w", err)
+	}
+
+	var stderr bytes.Buffer
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// execContainer returns the container to exec into: the requested one, or
+// the pod's default container (kubectl.kubernetes.io/default-container, else
+// the first).
+func execContainer(pod *corev1.Pod, container string) (string, error) {
+	var names []string
+	for _, c := range pod.Spec.Containers {
+		names = append(names, c.Name)
+	}
+	if container == "" {
+		if def := pod.Annotations["kubectl.kubernetes.io/default-container"]; def != "" {
+			container = def
+		} else if len(names) > 0 {
+			container = names[0]
+		}
+	}
+	for _, name := range names {
+		if name == container {
+			return container, nil
+		}
+	}
+	return "", fmt.Errorf("container %q not found in pod %s (containers: %s)", container, pod.Name, strings.Join(names, ", "))
+}
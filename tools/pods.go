@@ -1,7 +1,6 @@
 package tools
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -9,6 +8,7 @@ import (
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/tool"
 	"google.golang.org/genai"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -35,6 +35,7 @@ type PodInfo struct {
 // ListPodsTool provides the list_pods tool for the agent.
 type ListPodsTool struct {
 	clientset *kubernetes.Clientset
+	cache     *ResourceCache // nil reads from the API server
 }
 
 // NewListPodsTool creates a new ListPodsTool.
@@ -44,6 +45,11 @@ func NewListPodsTool(clientset *kubernetes.Clientset) *ListPodsTool {
 	}
 }
 
+// useCache makes the tool read through the resource cache.
+func (t *ListPodsTool) useCache(c *ResourceCache) {
+	t.cache = c
+}
+
 // Name returns the tool name.
 func (t *ListPodsTool) Name() string {
 	return "list_pods"
@@ -76,7 +82,7 @@ func (t *ListPodsTool) Declaration() *genai.FunctionDeclaration {
 		Description: t.Description(),
 		Parameters: &genai.Schema{
 			Type: "object",
-			Properties: map[string]*genai.Schema{
+			Properties: pagingSchema(map[string]*genai.Schema{
 				"namespace": {
 					Type:        "string",
 					Description: "The namespace to list pods from. Use empty string for all namespaces.",
@@ -85,7 +91,7 @@ func (t *ListPodsTool) Declaration() *genai.FunctionDeclaration {
 					Type:        "string",
 					Description: "Optional label selector to filter pods (e.g., 'app=nginx')",
 				},
-			},
+			}, defaultPageLimit),
 			Required: []string{"namespace"},
 		},
 	}
@@ -115,12 +121,22 @@ func (t *ListPodsTool) Run(ctx tool.Context, args any) (map[string]any, error) {
 		labelSelector = ls
 	}
 
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	page, limit, err := pagingArgs(argsMap, defaultPageLimit)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
 	defer cancel()
 
-	pods, err := t.clientset.CoreV1().Pods(namespace).List(timeoutCtx, metav1.ListOptions{
+	pods, err := t.cache.listPods(timeoutCtx, t.clientset, namespace, metav1.ListOptions{
 		LabelSelector: labelSelector,
+		Limit:         int64(limit),
+		Continue:      page,
 	})
+	if apierrors.IsResourceExpired(err) {
+		return map[string]any{"error": "the page token has expired; list again without page"}, nil
+	}
 	if err != nil {
 		return map[string]any{"error": err.Error()}, nil
 	}
@@ -179,10 +195,16 @@ func (t *ListPodsTool) Run(ctx tool.Context, args any) (map[string]any, error) {
 		})
 	}
 
-	return map[string]any{
+	response := map[string]any{
 		"pods":  result,
 		"count": len(result),
-	}, nil
+	}
+	remaining := int64(-1)
+	if pods.RemainingItemCount != nil {
+		remaining = *pods.RemainingItemCount
+	}
+	setNextPage(response, pods.Continue, remaining)
+	return response, nil
 }
 
 // formatReady formats the ready count as "ready/total".
@@ -0,0 +1,390 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/tool"
+	"k8s.io/client-go/util/homedir"
+	"sigs.k8s.io/yaml"
+)
+
+// PolicyConfig points kasa at the admission policies the cluster enforces
+// (the policies section of config.yaml), so manifests are checked against
+// them before they are applied.
+type PolicyConfig struct {
+	// Path is a policy file or a directory of them: Kyverno ClusterPolicy
+	// and Policy YAML, and rego.
+	Path string `yaml:"path"`
+	// OPA is the opa binary that evaluates rego policies (default "opa"
+	// on PATH).
+	OPA string `yaml:"opa"`
+}
+
+// PolicyViolation is a manifest breaking a policy rule. Enforced policies
+// give errors, which keep the manifest from being applied; audit ones give
+// warnings.
+type PolicyViolation struct {
+	Policy   string `json:"policy"`
+	Rule     string `json:"rule,omitempty"`
+	Severity string `json:"severity"`
+	Resource string `json:"resource"` // Kind/name
+	Path     string `json:"path,omitempty"`
+	Message  string `json:"message"`
+}
+
+// regoPackage finds the package a rego file declares.
+var regoPackage = regexp.MustCompile(`(?m)^\s*package\s+([\w.]+)`)
+
+// PolicySet is the policies loaded from a PolicyConfig.
+type PolicySet struct {
+	kyverno []kyvernoPolicy
+	rego    []regoPolicy
+	opa     string   // "" if the binary wasn't found
+	skipped []string // what can't be evaluated, and why
+}
+
+// regoPolicy is a rego file and the package its rules are in.
+type regoPolicy struct {
+	file, pkg string
+}
+
+// LoadPolicies reads the policies cfg points at. Kyverno rules kasa can't
+// evaluate, and rego policies without an opa binary, are listed by
+// Skipped rather than refused.
+func LoadPolicies(cfg PolicyConfig) (*PolicySet, error) {
+	root := cfg.Path
+	if strings.HasPrefix(root, "~") {
+		root = filepath.Join(homedir.HomeDir(), root[1:])
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{root}
+	if info.IsDir() {
+		files = nil
+		err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && strings.HasPrefix(d.Name(), ".") && path != root {
+				return filepath.SkipDir
+			}
+			switch filepath.Ext(path) {
+			case ".yaml", ".yml", ".rego":
+				if !d.IsDir() && !strings.HasSuffix(path, "_test.rego") {
+					files = append(files, path)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := &PolicySet{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Ext(file) == ".rego" {
+			m := regoPackage.FindSubmatch(content)
+			if m == nil {
+				return nil, fmt.Errorf("%s: no package declaration", file)
+			}
+			s.rego = append(s.rego, regoPolicy{file: file, pkg: string(m[1])})
+			continue
+		}
+		for _, document := range documentSeparator.Split(string(content), -1) {
+			var p kyvernoPolicy
+			if err := yaml.Unmarshal([]byte(document), &p); err != nil {
+				return nil, fmt.Errorf("%s: %v", file, err)
+			}
+			if p.Kind != "ClusterPolicy" && p.Kind != "Policy" {
+				continue // not a Kyverno policy, such as a kustomization
+			}
+			for _, rule := range p.Spec.Rules {
+				if reason := rule.unsupported(); reason != "" {
+					s.skipped = append(s.skipped, fmt.Sprintf("%s rule %s: %s can't be evaluated offline", p.Metadata.Name, rule.Name, reason))
+				}
+			}
+			s.kyverno = append(s.kyverno, p)
+		}
+	}
+
+	if len(s.rego) > 0 {
+		opa := cfg.OPA
+		if opa == "" {
+			opa = "opa"
+		}
+		if s.opa, err = exec.LookPath(opa); err != nil {
+			s.opa = ""
+			s.skipped = append(s.skipped, fmt.Sprintf("%d rego policies: %s not found, install opa or set policies.opa", len(s.rego), opa))
+		}
+	}
+	if len(s.kyverno) == 0 && len(s.rego) == 0 {
+		return nil, fmt.Errorf("no Kyverno or rego policies in %s", cfg.Path)
+	}
+	return s, nil
+}
+
+// Skipped lists the policies and rules that aren't evaluated, and why.
+func (s *PolicySet) Skipped() []string {
+	return s.skipped
+}
+
+// Count returns how many policies were loaded.
+func (s *PolicySet) Count() int {
+	return len(s.kyverno) + len(s.rego)
+}
+
+// Evaluate checks a resource against every policy. namespace is used when
+// the manifest doesn't say.
+func (s *PolicySet) Evaluate(ctx context.Context, obj map[string]any, namespace string) ([]PolicyViolation, error) {
+	if meta, ok := obj["metadata"].(map[string]any); ok && namespace != "" && namespace != manifest.ClusterNamespace {
+		if ns, _ := meta["namespace"].(string); ns == "" {
+			meta = maps.Clone(meta)
+			meta["namespace"] = namespace
+			obj = maps.Clone(obj)
+			obj["metadata"] = meta
+		}
+	}
+
+	var violations []PolicyViolation
+	for _, p := range s.kyverno {
+		violations = append(violations, p.evaluate(obj)...)
+	}
+	if s.opa != "" {
+		for _, p := range s.rego {
+			v, err := s.evalRego(ctx, p, obj)
+			if err != nil {
+				return nil, err
+			}
+			violations = append(violations, v...)
+		}
+	}
+	slices.SortStableFunc(violations, func(a, b PolicyViolation) int {
+		return strings.Compare(a.Severity, b.Severity) // errors first
+	})
+	return violations, nil
+}
+
+// regoRules are the rules of a rego policy kasa reads, conftest-style, and
+// the severity of what they produce. The manifest is the input.
+var regoRules = map[string]string{
+	"deny":      lintError,
+	"violation": lintError,
+	"warn":      lintWarning,
+}
+
+// evalRego runs opa eval on one rego policy with obj as input.
+func (s *PolicySet) evalRego(ctx context.Context, p regoPolicy, obj map[string]any) ([]PolicyViolation, error) {
+	input, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, s.opa, "eval", "--format", "json", "--stdin-input", "--data", p.file, "data."+p.pkg)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("opa eval %s: %v: %s", p.file, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var result struct {
+		Result []struct {
+			Expressions []struct {
+				Value map[string]any `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("opa eval %s: %v", p.file, err)
+	}
+	kind, _ := obj["kind"].(string)
+	name := ""
+	if meta, ok := obj["metadata"].(map[string]any); ok {
+		name, _ = meta["name"].(string)
+	}
+
+	var violations []PolicyViolation
+	for _, r := range result.Result {
+		for _, e := range r.Expressions {
+			for _, rule := range sortedKeys(e.Value) {
+				severity, ok := regoRules[rule]
+				if !ok {
+					continue
+				}
+				messages, _ := e.Value[rule].([]any)
+				for _, m := range messages {
+					message := fmt.Sprint(m)
+					if details, ok := m.(map[string]any); ok {
+						if msg, ok := details["msg"].(string); ok {
+							message = msg
+						}
+					}
+					violations = append(violations, PolicyViolation{
+						Policy:   p.pkg,
+						Rule:     rule,
+						Severity: severity,
+						Resource: kind + "/" + name,
+						Message:  message,
+					})
+				}
+			}
+		}
+	}
+	return violations, nil
+}
+
+// policyGuard checks manifests against the policies before they are
+// applied, and refuses those that break an enforced one.
+type policyGuard struct {
+	policies *PolicySet
+	manifest *manifest.Manager
+}
+
+// SetPolicies loads the policies cfg points at. Manifests applied with
+// apply_manifest and apply_resource, and the objects other mutating tools
+// write, must pass the enforced ones, and check_policies evaluates them on
+// request. An empty path turns this off.
+func (k *KubeTools) SetPolicies(cfg PolicyConfig) error {
+	if cfg.Path == "" {
+		k.policyGuard = nil
+		return nil
+	}
+	policies, err := LoadPolicies(cfg)
+	if err != nil {
+		return err
+	}
+	k.policyGuard = &policyGuard{policies: policies, manifest: k.manifest}
+	return nil
+}
+
+// policies returns the loaded policies, or nil.
+func (k *KubeTools) policies() *PolicySet {
+	if k.policyGuard == nil {
+		return nil
+	}
+	return k.policyGuard.policies
+}
+
+// appliedManifest returns the manifest a call to apply_manifest or
+// apply_resource applies and its namespace, or nil for other tools.
+func (g *policyGuard) appliedManifest(toolName string, args map[string]any) (map[string]any, string) {
+	str := func(key string) string {
+		s, _ := args[key].(string)
+		return s
+	}
+	var content []byte
+	switch toolName {
+	case "apply_resource":
+		content = []byte(str("yaml"))
+	case "apply_manifest":
+		resourceType := normalizeKind(str("type"))
+		if resourceType == "" {
+			resourceType = NormalizeKindName(str("type"))
+		}
+		var err error
+		if content, err = g.manifest.ReadManifest(str("namespace"), str("app"), resourceType); err != nil {
+			return nil, "" // the tool reports the missing manifest
+		}
+	default:
+		return nil, ""
+	}
+	var obj map[string]any
+	if err := yaml.Unmarshal(content, &obj); err != nil {
+		return nil, "" // the tool reports the YAML error
+	}
+	return obj, str("namespace")
+}
+
+// middleware evaluates the policies before a manifest is applied. Errors
+// refuse the call; warnings are added to its result. The other mutating
+// tools build the objects they write themselves, so their writes are
+// checked as they are sent (see policyTransport).
+func (g *policyGuard) middleware(t ToolInfo, next RunFunc) RunFunc {
+	if t.Name != "apply_manifest" && t.Name != "apply_resource" {
+		if t.Category == CategoryMutating {
+			return g.checkWrites(t, next)
+		}
+		return next
+	}
+	return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		obj, namespace := g.appliedManifest(t.Name, args)
+		if obj == nil {
+			return next(ctx, args)
+		}
+		violations, err := g.policies.Evaluate(toolContext(ctx), obj, namespace)
+		if err != nil {
+			return map[string]any{
+				"error":   fmt.Sprintf("could not evaluate policies: %v", err),
+				"message": "The change was NOT made. Retry later, or tell the user if the error persists.",
+			}, nil
+		}
+		var errs, warnings []PolicyViolation
+		for _, v := range violations {
+			if v.Severity == lintError {
+				errs = append(errs, v)
+			} else {
+				warnings = append(warnings, v)
+			}
+		}
+		if len(errs) > 0 {
+			return map[string]any{
+				"error":             fmt.Sprintf("policy: the manifest breaks %d enforced admission policy rule(s); the cluster would reject it", len(errs)),
+				"policy_violations": violations,
+				"tool":              t.Name,
+				"message": "The change was NOT made. Fix the manifest so it satisfies the policies (check_policies shows the result without applying), " +
+					"then apply it again.",
+			}, nil
+		}
+		result, err := next(ctx, args)
+		if err == nil && len(warnings) > 0 && result != nil && result["error"] == nil {
+			result["policy_warnings"] = warnings
+		}
+		return result, err
+	}
+}
+
+// checkWrites evaluates every object the call writes against the policies.
+// Writes that break an enforced policy are refused; the tool reports them
+// like any failed request, and the violations are added to its result.
+func (g *policyGuard) checkWrites(t ToolInfo, next RunFunc) RunFunc {
+	return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		// Replays run tools without a tool.Context; their writes are checked all the same
+		check := &policyCheck{policies: g.policies}
+		result, err := next(WithContext(ctx, withPolicyCheck(toolContext(ctx), check)), args)
+		if err != nil || result == nil {
+			return result, err
+		}
+		errs, warnings := check.violations()
+		if len(errs) > 0 {
+			result["policy_violations"] = errs
+			if !errorResult(result) {
+				result["error"] = fmt.Sprintf("policy: %d write(s) broke enforced admission policy rules and were refused", len(errs))
+			}
+			result["policy_message"] = "The refused changes were NOT made; the cluster would reject them. Tell the user which policies they break " +
+				"(check_policies evaluates a manifest without applying it) before trying another way."
+			return result, nil
+		}
+		if len(warnings) > 0 && !errorResult(result) {
+			result["policy_warnings"] = warnings
+		}
+		return result, nil
+	}
+}
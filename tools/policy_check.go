@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	"sigs.k8s.io/yaml"
+)
+
+// CheckPoliciesTool provides the check_policies tool for the agent.
+type CheckPoliciesTool struct {
+	policies *PolicySet // nil unless policies.path is set
+	manifest *manifest.Manager
+}
+
+// NewCheckPoliciesTool creates a new CheckPoliciesTool.
+func NewCheckPoliciesTool(policies *PolicySet, manifest *manifest.Manager) *CheckPoliciesTool {
+	return &CheckPoliciesTool{
+		policies: policies,
+		manifest: manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *CheckPoliciesTool) Name() string {
+	return "check_policies"
+}
+
+// Description returns the tool description.
+func (t *CheckPoliciesTool) Description() string {
+	return "Check manifests against the cluster's admission policies (Kyverno and rego, from policies.path in the config) without applying them. " +
+		"Returns each violation with its policy, rule, path and severity: errors are enforced policies the cluster would reject the manifest for, " +
+		"warnings are audit-only. Pass yaml, or a namespace (and optionally app and type) to check stored manifests. " +
+		"apply_manifest and apply_resource refuse manifests with errors, so check and fix them first."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *CheckPoliciesTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *CheckPoliciesTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *CheckPoliciesTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *CheckPoliciesTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"yaml": {
+					Type:        "string",
+					Description: "YAML to check; may hold several documents separated by ---. Either yaml or namespace is required.",
+				},
+				"namespace": {
+					Type:        "string",
+					Description: "Check the stored manifests of this namespace, or the namespace the yaml will be applied to if it doesn't say",
+				},
+				"app": {
+					Type:        "string",
+					Description: "Only check the stored manifests of this app",
+				},
+				"type": {
+					Type:        "string",
+					Description: "Only check this resource type of the app (e.g. deployment)",
+				},
+			},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *CheckPoliciesTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+	if t.policies == nil {
+		return map[string]any{"error": "no policies are configured; set policies.path in config.yaml to a directory of Kyverno or rego policies"}, nil
+	}
+
+	content, _ := argsMap["yaml"].(string)
+	namespace, _ := argsMap["namespace"].(string)
+	app, _ := argsMap["app"].(string)
+	resourceType, _ := argsMap["type"].(string)
+	if content == "" && namespace == "" {
+		return map[string]any{"error": "yaml or namespace is required"}, nil
+	}
+	if resourceType != "" && app == "" {
+		return map[string]any{"error": "type requires app"}, nil
+	}
+
+	var violations []PolicyViolation
+	checked := 0
+	check := func(data []byte, namespace string) error {
+		for _, document := range documentSeparator.Split(string(data), -1) {
+			var obj map[string]any
+			if err := yaml.Unmarshal([]byte(document), &obj); err != nil {
+				return fmt.Errorf("invalid YAML: %v", err)
+			}
+			if obj == nil {
+				continue
+			}
+			v, err := t.policies.Evaluate(toolContext(ctx), obj, namespace)
+			if err != nil {
+				return err
+			}
+			violations = append(violations, v...)
+			checked++
+		}
+		return nil
+	}
+
+	if content != "" {
+		if err := check([]byte(content), namespace); err != nil {
+			return map[string]any{"error": err.Error()}, nil
+		}
+	} else {
+		infos, err := t.manifest.ListManifests(namespace, app)
+		if err != nil {
+			return map[string]any{"error": err.Error()}, nil
+		}
+		for _, info := range infos {
+			if resourceType != "" && info.Type != resourceType {
+				continue
+			}
+			data, err := t.manifest.ReadManifest(info.Namespace, info.App, info.Type)
+			if err == nil {
+				err = check(data, info.Namespace)
+			}
+			if err != nil {
+				return map[string]any{"error": fmt.Sprintf("%s: %v", info.Path, err)}, nil
+			}
+		}
+	}
+	if checked == 0 {
+		if content != "" {
+			return map[string]any{"error": "the yaml holds no resources"}, nil
+		}
+		return map[string]any{"error": fmt.Sprintf("no stored manifests match %s", filepath.Join(namespace, app, resourceType))}, nil
+	}
+
+	errs := 0
+	for _, v := range violations {
+		if v.Severity == lintError {
+			errs++
+		}
+	}
+	result := map[string]any{
+		"passed":     errs == 0,
+		"checked":    checked,
+		"errors":     errs,
+		"warnings":   len(violations) - errs,
+		"violations": violations,
+		"policies":   t.policies.Count(),
+	}
+	if skipped := t.policies.Skipped(); len(skipped) > 0 {
+		result["not_evaluated"] = skipped
+	}
+	switch {
+	case len(violations) == 0:
+		result["message"] = fmt.Sprintf("%d resource(s) pass all %d policies", checked, t.policies.Count())
+	case errs > 0:
+		result["message"] = fmt.Sprintf("%d enforced rule(s) broken; the cluster would reject the manifest. Fix: %s", errs, strings.Join(violationMessages(violations), "; "))
+	}
+	return result, nil
+}
+
+// violationMessages returns the messages of the enforced violations.
+func violationMessages(violations []PolicyViolation) []string {
+	var messages []string
+	for _, v := range violations {
+		if v.Severity == lintError {
+			messages = append(messages, fmt.Sprintf("%s (%s)", v.Message, v.Resource))
+		}
+	}
+	return messages
+}
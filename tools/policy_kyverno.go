@@ -0,0 +1,458 @@
+package tools
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// kyvernoPolicy is the part of a Kyverno ClusterPolicy or Policy kasa
+// evaluates: the match and exclude blocks and validate patterns.
+type kyvernoPolicy struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		ValidationFailureAction string        `json:"validationFailureAction"`
+		Rules                   []kyvernoRule `json:"rules"`
+	} `json:"spec"`
+}
+
+type kyvernoRule struct {
+	Name     string       `json:"name"`
+	Match    kyvernoMatch `json:"match"`
+	Exclude  kyvernoMatch `json:"exclude"`
+	Validate *struct {
+		Message       string `json:"message"`
+		FailureAction string `json:"failureAction"`
+		Pattern       any    `json:"pattern"`
+		AnyPattern    []any  `json:"anyPattern"`
+		Deny          any    `json:"deny"`
+		Foreach       any    `json:"foreach"`
+		CEL           any    `json:"cel"`
+		PodSecurity   any    `json:"podSecurity"`
+	} `json:"validate"`
+}
+
+// kyvernoMatch selects resources: any of Any, all of All, or Resources.
+type kyvernoMatch struct {
+	Any       []kyvernoFilter   `json:"any"`
+	All       []kyvernoFilter   `json:"all"`
+	Resources *kyvernoResources `json:"resources"`
+}
+
+type kyvernoFilter struct {
+	Resources kyvernoResources `json:"resources"`
+}
+
+type kyvernoResources struct {
+	Kinds      []string              `json:"kinds"`
+	Names      []string              `json:"names"`
+	Namespaces []string              `json:"namespaces"`
+	Selector   *metav1.LabelSelector `json:"selector"`
+}
+
+// isEmpty reports whether m selects nothing, as an exclude block that isn't
+// there.
+func (m kyvernoMatch) isEmpty() bool {
+	return len(m.Any) == 0 && len(m.All) == 0 && m.Resources == nil
+}
+
+// matches reports whether m selects the resource of the given kind.
+func (m kyvernoMatch) matches(kind string, obj map[string]any) bool {
+	if m.Resources != nil && !m.Resources.matches(kind, obj) {
+		return false
+	}
+	for _, f := range m.All {
+		if !f.Resources.matches(kind, obj) {
+			return false
+		}
+	}
+	if len(m.Any) > 0 {
+		return slices.ContainsFunc(m.Any, func(f kyvernoFilter) bool { return f.Resources.matches(kind, obj) })
+	}
+	return !m.isEmpty()
+}
+
+func (r kyvernoResources) matches(kind string, obj map[string]any) bool {
+	meta, _ := obj["metadata"].(map[string]any)
+	name, _ := meta["name"].(string)
+	namespace, _ := meta["namespace"].(string)
+
+	// Kinds may be Kind, version/Kind or group/version/Kind
+	if len(r.Kinds) > 0 && !slices.ContainsFunc(r.Kinds, func(k string) bool {
+		return wildcardMatch(k[strings.LastIndex(k, "/")+1:], kind)
+	}) {
+		return false
+	}
+	if len(r.Names) > 0 && !slices.ContainsFunc(r.Names, func(p string) bool { return wildcardMatch(p, name) }) {
+		return false
+	}
+	if len(r.Namespaces) > 0 && !slices.ContainsFunc(r.Namespaces, func(p string) bool { return wildcardMatch(p, namespace) }) {
+		return false
+	}
+	if r.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(r.Selector)
+		if err != nil {
+			return false
+		}
+		set := labels.Set{}
+		if l, ok := meta["labels"].(map[string]any); ok {
+			for k, v := range l {
+				set[k], _ = v.(string)
+			}
+		}
+		if !selector.Matches(set) {
+			return false
+		}
+	}
+	return true
+}
+
+// unsupported returns why kasa can't evaluate the rule, or "" if it can.
+// Rules without a validate block (mutate, generate) don't apply to it.
+func (r kyvernoRule) unsupported() string {
+	switch v := r.Validate; {
+	case v == nil:
+		return ""
+	case v.Deny != nil:
+		return "deny conditions"
+	case v.Foreach != nil:
+		return "foreach"
+	case v.CEL != nil:
+		return "CEL expressions"
+	case v.PodSecurity != nil:
+		return "podSecurity"
+	}
+	return ""
+}
+
+// podTemplate returns the pod a workload creates, for rules that match
+// Pods (Kyverno applies those to the pod templates of workloads too), and
+// the path of its template. It returns nil for other kinds.
+func podTemplate(kind string, obj map[string]any) (map[string]any, string) {
+	specPath, ok := podSpecPaths[kind]
+	if !ok || kind == "Pod" {
+		return nil, ""
+	}
+	templatePath := specPath[:len(specPath)-1]
+	var template any = obj
+	for _, field := range templatePath {
+		m, _ := template.(map[string]any)
+		template = m[field]
+	}
+	t, ok := template.(map[string]any)
+	if !ok {
+		return nil, ""
+	}
+	meta, _ := t["metadata"].(map[string]any)
+	podMeta := maps.Clone(meta)
+	if podMeta == nil {
+		podMeta = map[string]any{}
+	}
+	if objMeta, ok := obj["metadata"].(map[string]any); ok {
+		podMeta["namespace"] = objMeta["namespace"]
+	}
+	return map[string]any{"apiVersion": "v1", "kind": "Pod", "metadata": podMeta, "spec": t["spec"]},
+		strings.Join(templatePath, ".")
+}
+
+// evaluate checks obj against the validate rules of p that match it.
+func (p kyvernoPolicy) evaluate(obj map[string]any) []PolicyViolation {
+	kind, _ := obj["kind"].(string)
+	meta, _ := obj["metadata"].(map[string]any)
+	name, _ := meta["name"].(string)
+	namespace, _ := meta["namespace"].(string)
+	if p.Kind == "Policy" && p.Metadata.Namespace != "" && p.Metadata.Namespace != namespace {
+		return nil
+	}
+
+	var violations []PolicyViolation
+	for _, rule := range p.Spec.Rules {
+		if rule.Validate == nil || rule.unsupported() != "" {
+			continue
+		}
+		target, prefix := obj, ""
+		if !rule.Match.matches(kind, obj) {
+			pod, templatePath := podTemplate(kind, obj)
+			if pod == nil || !rule.Match.matches("Pod", pod) {
+				continue
+			}
+			target, prefix = pod, templatePath
+			if !rule.Exclude.isEmpty() && rule.Exclude.matches("Pod", pod) {
+				continue
+			}
+		}
+		if !rule.Exclude.isEmpty() && rule.Exclude.matches(kind, obj) {
+			continue
+		}
+
+		patterns := rule.Validate.AnyPattern
+		if rule.Validate.Pattern != nil {
+			patterns = []any{rule.Validate.Pattern}
+		}
+		if len(patterns) == 0 {
+			continue
+		}
+		var mismatches []string
+		for _, pattern := range patterns {
+			mismatch, skip := matchPattern(pattern, target, prefix)
+			if mismatch == "" || skip {
+				mismatches = nil
+				break
+			}
+			mismatches = append(mismatches, mismatch)
+		}
+		if len(mismatches) == 0 {
+			continue
+		}
+
+		action := rule.Validate.FailureAction
+		if action == "" {
+			action = p.Spec.ValidationFailureAction
+		}
+		severity := lintWarning
+		if strings.EqualFold(action, "enforce") {
+			severity = lintError
+		}
+		message := rule.Validate.Message
+		if message == "" {
+			message = "validation failed"
+		}
+		violations = append(violations, PolicyViolation{
+			Policy:   p.Metadata.Name,
+			Rule:     rule.Name,
+			Severity: severity,
+			Resource: kind + "/" + name,
+			Path:     strings.Join(mismatches, "; "),
+			Message:  message,
+		})
+	}
+	return violations
+}
+
+// matchPattern checks value against a Kyverno validate pattern. It returns
+// "" if value matches, or where and why it doesn't; skip is set when a
+// conditional anchor doesn't hold, so the pattern doesn't apply.
+func matchPattern(pattern, value any, path string) (mismatch string, skip bool) {
+	at := path
+	if at == "" {
+		at = "(root)"
+	}
+	switch p := pattern.(type) {
+	case nil:
+		return "", false
+	case map[string]any:
+		m, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Sprintf("%s: must be an object", at), false
+		}
+		// Conditions first: if one doesn't hold, nothing else is checked
+		keys := sortedKeys(p)
+		for _, key := range keys {
+			anchor, field := patternAnchor(key)
+			if anchor != "(" && anchor != "<(" {
+				continue
+			}
+			v, present := m[field]
+			if !present {
+				return "", true
+			}
+			if mismatch, skip := matchPattern(p[key], v, joinPath(path, field)); mismatch != "" || skip {
+				return "", true
+			}
+		}
+		for _, key := range keys {
+			anchor, field := patternAnchor(key)
+			child := joinPath(path, field)
+			v, present := m[field]
+			switch anchor {
+			case "(", "<(", "+(":
+				continue
+			case "X(":
+				if present {
+					return fmt.Sprintf("%s: must not be set", child), false
+				}
+				continue
+			case "=(":
+				if !present {
+					continue
+				}
+			case "^(":
+				list, _ := v.([]any)
+				items, _ := p[key].([]any)
+				if len(items) == 0 {
+					continue
+				}
+				if !slices.ContainsFunc(list, func(item any) bool {
+					mismatch, skip := matchPattern(items[0], item, child)
+					return mismatch == "" && !skip
+				}) {
+					return fmt.Sprintf("%s: no item matches", child), false
+				}
+				continue
+			}
+			if !present {
+				return fmt.Sprintf("%s: is required", child), false
+			}
+			if mismatch, skip := matchPattern(p[key], v, child); mismatch != "" || skip {
+				return mismatch, skip
+			}
+		}
+		return "", false
+	case []any:
+		list, ok := value.([]any)
+		if !ok {
+			return fmt.Sprintf("%s: must be a list", at), false
+		}
+		if len(p) == 0 {
+			return "", false
+		}
+		// A single pattern applies to every item; items it doesn't apply
+		// to are skipped
+		for i, item := range list {
+			itemPattern := p[0]
+			if len(p) > 1 {
+				if i >= len(p) {
+					break
+				}
+				itemPattern = p[i]
+			}
+			if mismatch, _ := matchPattern(itemPattern, item, fmt.Sprintf("%s[%d]", path, i)); mismatch != "" {
+				return mismatch, false
+			}
+		}
+		return "", false
+	default:
+		if !matchScalar(fmt.Sprint(p), value) {
+			return fmt.Sprintf("%s: %s doesn't match %q", at, describeValue(value), fmt.Sprint(p)), false
+		}
+		return "", false
+	}
+}
+
+// patternAnchor splits a pattern key into its anchor, such as "=(" for an
+// equality anchor, and the field name.
+func patternAnchor(key string) (anchor, field string) {
+	if !strings.HasSuffix(key, ")") {
+		return "", key
+	}
+	for _, a := range []string{"=(", "X(", "^(", "<(", "+(", "("} {
+		if strings.HasPrefix(key, a) {
+			return a, key[len(a) : len(key)-1]
+		}
+	}
+	return "", key
+}
+
+// matchScalar checks a value against a scalar pattern: alternatives
+// separated by |, each a wildcard pattern, a comparison such as ">=2" or
+// "<1Gi", or either negated with !.
+func matchScalar(pattern string, value any) bool {
+	for _, alt := range strings.Split(pattern, "|") {
+		alt = strings.TrimSpace(alt)
+		negate := strings.HasPrefix(alt, "!") && !strings.HasPrefix(alt, "!=")
+		if negate {
+			alt = alt[1:]
+		}
+		if matchOne(alt, value) != negate {
+			return true
+		}
+	}
+	return false
+}
+
+func matchOne(pattern string, value any) bool {
+	if value == nil {
+		return pattern == "null" || pattern == ""
+	}
+	switch value.(type) {
+	case map[string]any, []any:
+		return pattern == "*"
+	}
+	s := scalarString(value)
+	for _, op := range []string{">=", "<=", "!=", ">", "<"} {
+		if !strings.HasPrefix(pattern, op) {
+			continue
+		}
+		want := strings.TrimSpace(pattern[len(op):])
+		cmp, ok := compareQuantities(s, want)
+		if !ok {
+			return op == "!=" && s != want
+		}
+		switch op {
+		case ">=":
+			return cmp >= 0
+		case "<=":
+			return cmp <= 0
+		case "!=":
+			return cmp != 0
+		case ">":
+			return cmp > 0
+		default:
+			return cmp < 0
+		}
+	}
+	if pattern == "?*" {
+		return s != ""
+	}
+	if cmp, ok := compareQuantities(s, pattern); ok && !strings.ContainsAny(pattern, "*?") {
+		return cmp == 0
+	}
+	return wildcardMatch(pattern, s)
+}
+
+// compareQuantities compares two numbers or resource quantities, such as
+// "500m" and "1".
+func compareQuantities(a, b string) (int, bool) {
+	qa, err := resource.ParseQuantity(a)
+	if err != nil {
+		return 0, false
+	}
+	qb, err := resource.ParseQuantity(b)
+	if err != nil {
+		return 0, false
+	}
+	return qa.Cmp(qb), true
+}
+
+// scalarString formats a scalar decoded from YAML as Kyverno compares it.
+func scalarString(value any) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// describeValue describes a value for a mismatch.
+func describeValue(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "a list"
+	}
+	return strconv.Quote(scalarString(value))
+}
+
+// wildcardMatch matches s against a pattern where * matches any run of
+// characters, including /, and ? any one character.
+func wildcardMatch(pattern, s string) bool {
+	if !strings.ContainsAny(pattern, "*?") {
+		return pattern == s
+	}
+	expr := strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(pattern))
+	ok, _ := regexp.MatchString("^"+expr+"$", s)
+	return ok
+}
@@ -0,0 +1,485 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/tool"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+)
+
+const testKyvernoPolicies = `apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: require-limits
+spec:
+  validationFailureAction: Enforce
+  rules:
+  - name: memory-limit
+    match:
+      any:
+      - resources:
+          kinds: [Pod]
+    exclude:
+      any:
+      - resources:
+          namespaces: [kube-system]
+    validate:
+      message: "Containers must set a memory limit."
+      pattern:
+        spec:
+          containers:
+          - resources:
+              limits:
+                memory: "<=2Gi"
+---
+apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: disallow-latest
+spec:
+  validationFailureAction: Audit
+  rules:
+  - name: pinned-image
+    match:
+      any:
+      - resources:
+          kinds: [Pod]
+    validate:
+      message: "Pin image versions."
+      pattern:
+        spec:
+          containers:
+          - image: "!*:latest"
+  - name: owner
+    match:
+      any:
+      - resources:
+          kinds: [apps/v1/Deployment]
+          selector:
+            matchLabels:
+              tier: frontend
+    validate:
+      failureAction: Enforce
+      message: "Frontend deployments need an owner label."
+      pattern:
+        metadata:
+          labels:
+            owner: "?*"
+  - name: no-host-network
+    match:
+      any:
+      - resources:
+          kinds: [Pod]
+    validate:
+      message: "hostNetwork is not allowed."
+      pattern:
+        spec:
+          =(hostNetwork): false
+  - name: namespaced
+    match:
+      any:
+      - resources:
+          kinds: [Deployment]
+    validate:
+      deny:
+        conditions: []
+`
+
+func deploymentObject(t *testing.T, manifest string) map[string]any {
+	t.Helper()
+	var obj map[string]any
+	if err := yaml.Unmarshal([]byte(manifest), &obj); err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func loadTestPolicies(t *testing.T) *PolicySet {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "policies.yaml"), []byte(testKyvernoPolicies), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policies, err := LoadPolicies(PolicyConfig{Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policies
+}
+
+const testPolicyDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    tier: frontend
+spec:
+  template:
+    spec:
+      hostNetwork: true
+      containers:
+      - name: web
+        image: nginx:latest
+      - name: sidecar
+        image: envoy:v1.31
+        resources:
+          limits:
+            memory: 4Gi
+`
+
+func TestPolicies_Kyverno(t *testing.T) {
+	policies := loadTestPolicies(t)
+	if policies.Count() != 2 {
+		t.Errorf("expected 2 policies, got %d", policies.Count())
+	}
+	if skipped := policies.Skipped(); len(skipped) != 1 || !strings.Contains(skipped[0], "namespaced: deny conditions") {
+		t.Errorf("expected the deny rule to be skipped, got %v", skipped)
+	}
+
+	violations, err := policies.Evaluate(context.Background(), deploymentObject(t, testPolicyDeployment), "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]PolicyViolation{}
+	for _, v := range violations {
+		got[v.Rule] = v
+	}
+	want := map[string]string{
+		"memory-limit":    lintError,
+		"owner":           lintError,
+		"pinned-image":    lintWarning,
+		"no-host-network": lintWarning,
+	}
+	if len(got) != len(want) {
+		t.Errorf("unexpected violations: %+v", violations)
+	}
+	for rule, severity := range want {
+		if got[rule].Severity != severity {
+			t.Errorf("expected %s violation of %s, got %+v", severity, rule, got[rule])
+		}
+	}
+	if v := got["memory-limit"]; v.Resource != "Deployment/web" || v.Path != "spec.template.spec.containers[0].resources: is required" {
+		t.Errorf("unexpected memory-limit violation: %+v", v)
+	}
+	if v := got["owner"]; v.Path != "metadata.labels.owner: is required" {
+		t.Errorf("unexpected owner violation: %+v", v)
+	}
+	if violations[0].Severity != lintError {
+		t.Errorf("expected errors first, got %+v", violations)
+	}
+
+	// Excluded namespace, and a compliant manifest
+	obj := deploymentObject(t, testPolicyDeployment)
+	obj["metadata"].(map[string]any)["namespace"] = "kube-system"
+	violations, _ = policies.Evaluate(context.Background(), obj, "")
+	for _, v := range violations {
+		if v.Rule == "memory-limit" {
+			t.Errorf("expected kube-system to be excluded, got %+v", v)
+		}
+	}
+	violations, _ = policies.Evaluate(context.Background(), deploymentObject(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels: {tier: frontend, owner: shop}
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.27
+        resources: {limits: {memory: 512Mi}}
+`), "shop")
+	if len(violations) != 0 {
+		t.Errorf("expected no violations, got %+v", violations)
+	}
+}
+
+func TestMatchScalar(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   any
+		want    bool
+	}{
+		{"nginx:*", "nginx:1.27", true},
+		{"!*:latest", "nginx:latest", false},
+		{"!*:latest", "registry.example.com/nginx:1.27", true},
+		{"?*", "", false},
+		{"?*", "x", true},
+		{">=2", float64(3), true},
+		{">=2", float64(1), false},
+		{"<=1Gi", "512Mi", true},
+		{"<=1Gi", "2Gi", false},
+		{"500m", "0.5", true},
+		{"Always|IfNotPresent", "IfNotPresent", true},
+		{"Always|IfNotPresent", "Never", false},
+		{"false", false, true},
+		{"false", true, false},
+		{"*", map[string]any{}, true},
+	}
+	for _, tt := range tests {
+		if got := matchScalar(tt.pattern, tt.value); got != tt.want {
+			t.Errorf("matchScalar(%q, %v) = %v, want %v", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMatchPattern_Anchors(t *testing.T) {
+	pattern := map[string]any{
+		"spec": map[string]any{
+			"(runtimeClassName)": "gvisor",
+			"X(hostPID)":         nil,
+		},
+	}
+	tests := []struct {
+		spec     map[string]any
+		mismatch string
+		skip     bool
+	}{
+		{map[string]any{"runtimeClassName": "runc", "hostPID": true}, "", true},
+		{map[string]any{"runtimeClassName": "gvisor", "hostPID": true}, "spec.hostPID: must not be set", false},
+		{map[string]any{"runtimeClassName": "gvisor"}, "", false},
+	}
+	for _, tt := range tests {
+		mismatch, skip := matchPattern(pattern, map[string]any{"spec": tt.spec}, "")
+		if mismatch != tt.mismatch || skip != tt.skip {
+			t.Errorf("spec %v: got (%q, %v), want (%q, %v)", tt.spec, mismatch, skip, tt.mismatch, tt.skip)
+		}
+	}
+
+	existence := map[string]any{"^(containers)": []any{map[string]any{"name": "app"}}}
+	if mismatch, _ := matchPattern(existence, map[string]any{"containers": []any{map[string]any{"name": "sidecar"}, map[string]any{"name": "app"}}}, ""); mismatch != "" {
+		t.Errorf("expected one matching container to be enough, got %q", mismatch)
+	}
+	if mismatch, _ := matchPattern(existence, map[string]any{"containers": []any{map[string]any{"name": "sidecar"}}}, ""); mismatch == "" {
+		t.Error("expected a mismatch without a matching container")
+	}
+}
+
+func TestLoadPolicies_Rego(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "labels.rego"), []byte("package kasa.labels\n\ndeny[msg] {\n  not input.metadata.labels.app\n  msg := \"app label is required\"\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "labels_test.rego"), []byte("package kasa.labels\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	policies, err := LoadPolicies(PolicyConfig{Path: dir, OPA: "no-such-opa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policies.rego) != 1 || policies.rego[0].pkg != "kasa.labels" {
+		t.Errorf("unexpected rego policies: %+v", policies.rego)
+	}
+	if skipped := policies.Skipped(); len(skipped) != 1 || !strings.Contains(skipped[0], "no-such-opa not found") {
+		t.Errorf("expected the rego policy to be skipped, got %v", skipped)
+	}
+	violations, err := policies.Evaluate(context.Background(), map[string]any{"kind": "ConfigMap"}, "")
+	if err != nil || len(violations) != 0 {
+		t.Errorf("expected nothing evaluated without opa, got %v, %v", violations, err)
+	}
+
+	if _, err := LoadPolicies(PolicyConfig{Path: t.TempDir()}); err == nil {
+		t.Error("expected an error for a directory without policies")
+	}
+}
+
+func TestPolicyGuard(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	writeTestManifest(t, mgr, "shop", "web", "deployment", testPolicyDeployment)
+	k := &KubeTools{manifest: mgr}
+	k.policyGuard = &policyGuard{policies: loadTestPolicies(t), manifest: mgr}
+
+	calls := 0
+	next := func(tool.Context, map[string]any) (map[string]any, error) {
+		calls++
+		return map[string]any{"success": true}, nil
+	}
+	apply := k.policyGuard.middleware(ToolInfo{Name: "apply_manifest", Category: CategoryMutating}, next)
+	result, err := apply(nil, map[string]any{"namespace": "shop", "app": "web", "type": "deploy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 || !strings.Contains(result["error"].(string), "2 enforced") {
+		t.Errorf("expected the apply to be refused, got %v", result)
+	}
+
+	// Audit-only violations let the apply through, with warnings
+	apply = k.policyGuard.middleware(ToolInfo{Name: "apply_resource", Category: CategoryMutating}, next)
+	result, _ = apply(nil, map[string]any{"yaml": `apiVersion: v1
+kind: Pod
+metadata:
+  name: debug
+spec:
+  containers:
+  - name: debug
+    image: busybox:latest
+    resources: {limits: {memory: 64Mi}}
+`})
+	warnings, _ := result["policy_warnings"].([]PolicyViolation)
+	if calls != 1 || len(warnings) != 1 || warnings[0].Rule != "pinned-image" {
+		t.Errorf("expected the apply to run with a warning, got %v", result)
+	}
+
+	other := k.policyGuard.middleware(ToolInfo{Name: "create_secret", Category: CategoryMutating}, next)
+	if _, _ = other(nil, map[string]any{"namespace": "shop", "name": "db"}); calls != 2 {
+		t.Error("expected other tools to be left alone")
+	}
+
+	check := NewCheckPoliciesTool(k.policies(), mgr)
+	result, _ = check.Run(nil, map[string]any{"namespace": "shop"})
+	if result["passed"] != false || result["errors"] != 2 || result["checked"] != 1 || result["not_evaluated"] == nil {
+		t.Errorf("unexpected check_policies result: %v", result)
+	}
+	result, _ = NewCheckPoliciesTool(nil, mgr).Run(nil, map[string]any{"namespace": "shop"})
+	if !strings.Contains(result["error"].(string), "policies.path") {
+		t.Errorf("expected an error without policies, got %v", result)
+	}
+}
+
+const testRegistryPolicy = `apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: trusted-registry
+spec:
+  validationFailureAction: Enforce
+  rules:
+  - name: registry
+    match:
+      any:
+      - resources:
+          kinds: [Pod]
+    validate:
+      message: "Images must come from registry.example.com."
+      pattern:
+        spec:
+          containers:
+          - image: "registry.example.com/*"
+`
+
+func TestPolicyGuard_SetImage(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "registry.yaml"), []byte(testRegistryPolicy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policies, err := LoadPolicies(PolicyConfig{Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "web", Image: "registry.example.com/web:1.0"}},
+		}}},
+		// Rolled out, so an update that is sent completes at once
+		Status: appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+	}
+	var writes int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writes++
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(live)
+	}))
+	t.Cleanup(srv.Close)
+	config := &rest.Config{Host: srv.URL}
+	ConfigureAPIClient(config, APIOptions{MaxRetries: -1})
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := newTestManifestManager(t)
+	writeTestManifest(t, mgr, "shop", "web", "deployment", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: registry.example.com/web:1.0
+`)
+	g := &policyGuard{policies: policies, manifest: mgr}
+	setImage := withMiddleware([]tool.Tool{NewSetImageTool(clientset, mgr, false)}, g.middleware)[0].(runnableTool)
+	ctx := WithContext(sessionContext{}, context.Background())
+
+	result, _ := setImage.Run(ctx, map[string]any{"namespace": "shop", "name": "web", "image": "docker.io/web:2.0"})
+	violations, _ := result["policy_violations"].([]PolicyViolation)
+	if writes != 0 || len(violations) != 1 || violations[0].Policy != "trusted-registry" {
+		t.Fatalf("expected the update to be refused before it was sent, got %d writes and %v", writes, result)
+	}
+	if msg, _ := result["error"].(string); !strings.Contains(msg, "registry.example.com") {
+		t.Errorf("expected the error to name the broken rule, got %q", msg)
+	}
+
+	// An image the policy allows is written
+	result, _ = setImage.Run(ctx, map[string]any{"namespace": "shop", "name": "web", "image": "registry.example.com/web:2.0"})
+	if writes != 1 || result["policy_violations"] != nil {
+		t.Errorf("expected the allowed update to be sent, got %d writes and %v", writes, result)
+	}
+}
+
+func TestPolicyTransport_Patch(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "registry.yaml"), []byte(testRegistryPolicy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policies, err := LoadPolicies(PolicyConfig{Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dryRuns, writes int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("dryRun") == "All" {
+			dryRuns++
+		} else {
+			writes++
+		}
+		// The patched object, as the API server returns it
+		_, _ = w.Write([]byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web","namespace":"shop"},
+			"spec":{"template":{"spec":{"containers":[{"name":"web","image":"docker.io/web:2.0"}]}}}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := &http.Client{Transport: policyTransport{next: http.DefaultTransport}}
+	patch := func(ctx context.Context) *http.Response {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPatch, srv.URL+"/apis/apps/v1/namespaces/shop/deployments/web",
+			strings.NewReader(`{"spec":{"template":{"spec":{"containers":[{"name":"web","image":"docker.io/web:2.0"}]}}}}`))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := patch(context.Background()); resp.StatusCode != http.StatusOK || dryRuns != 0 || writes != 1 {
+		t.Fatalf("expected a request outside a checked call to pass through, got %d (%d dry runs)", resp.StatusCode, dryRuns)
+	}
+	check := &policyCheck{policies: policies}
+	if resp := patch(withPolicyCheck(context.Background(), check)); resp.StatusCode != http.StatusForbidden || dryRuns != 1 || writes != 1 {
+		t.Errorf("expected the patch to be refused after a dry run, got %d (%d dry runs, %d writes)", resp.StatusCode, dryRuns, writes)
+	}
+	if errs, _ := check.violations(); len(errs) != 1 || errs[0].Resource != "Deployment/web" {
+		t.Errorf("expected the violation to be recorded, got %v", errs)
+	}
+}
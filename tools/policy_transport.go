@@ -0,0 +1,196 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// policyCheckKey holds the policyCheck of a tool call in its context.
+type policyCheckKey struct{}
+
+// policyCheck evaluates the objects one tool call writes against the
+// policies, and collects what they broke.
+type policyCheck struct {
+	policies *PolicySet
+
+	mu       sync.Mutex
+	errors   []PolicyViolation
+	warnings []PolicyViolation
+}
+
+// record adds the violations of one write.
+func (c *policyCheck) record(violations []PolicyViolation) (errs []PolicyViolation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, v := range violations {
+		if v.Severity == lintError {
+			errs = append(errs, v)
+		} else {
+			c.warnings = append(c.warnings, v)
+		}
+	}
+	c.errors = append(c.errors, errs...)
+	return errs
+}
+
+// violations returns what the writes so far broke.
+func (c *policyCheck) violations() (errs, warnings []PolicyViolation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.errors, c.warnings
+}
+
+// policyTransport checks the objects written by a tool call against the
+// policies before they are sent, and answers writes that break an
+// enforced one with 403 Forbidden, like the cluster's admission controller
+// would. A patch is sent as a dry run first to get the patched object.
+// Requests outside a checked tool call (see policyGuard) pass through.
+type policyTransport struct {
+	next http.RoundTripper
+}
+
+func (t policyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	check, _ := req.Context().Value(policyCheckKey{}).(*policyCheck)
+	if check == nil || req.Body == nil || req.Body == http.NoBody {
+		return t.next.RoundTrip(req)
+	}
+
+	var obj map[string]any
+	switch req.Method {
+	case http.MethodPost, http.MethodPut:
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+		obj = writtenObject(body)
+	case http.MethodPatch:
+		patched, err := t.dryRun(req)
+		if err != nil {
+			return nil, err
+		}
+		obj = patched
+	}
+	// Not an object, such as an exec stream, or a failed dry run that the
+	// real request reports
+	if obj == nil || obj["kind"] == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	violations, err := check.policies.Evaluate(req.Context(), obj, requestNamespace(req.URL.Path))
+	if err != nil {
+		return nil, fmt.Errorf("could not evaluate policies: %w", err)
+	}
+	if errs := check.record(violations); len(errs) > 0 {
+		return policyRejection(req, errs), nil
+	}
+	return t.next.RoundTrip(req)
+}
+
+// writtenObject decodes the body of a write: JSON, or protobuf, which the
+// clientset sends for the built-in types. It returns nil for anything else.
+func writtenObject(body []byte) map[string]any {
+	var obj map[string]any
+	if json.Unmarshal(body, &obj) == nil {
+		return obj
+	}
+	decoded, gvk, err := scheme.Codecs.UniversalDeserializer().Decode(body, nil, nil)
+	if err != nil {
+		return nil
+	}
+	if obj, err = runtime.DefaultUnstructuredConverter.ToUnstructured(decoded); err != nil {
+		return nil
+	}
+	obj["apiVersion"], obj["kind"] = gvk.GroupVersion().String(), gvk.Kind
+	return obj
+}
+
+// dryRun sends a copy of the patch req with dryRun=All and returns the
+// patched object, or nil if the dry run failed.
+func (t policyTransport) dryRun(req *http.Request) (map[string]any, error) {
+	if !replayable(req) {
+		return nil, nil
+	}
+	r, err := replay(req)
+	if err != nil {
+		return nil, err
+	}
+	q := r.URL.Query()
+	q.Set("dryRun", "All")
+	r.URL.RawQuery = q.Encode()
+	r.Header.Set("Accept", "application/json")
+
+	resp, err := t.next.RoundTrip(r)
+	if err != nil {
+		return nil, nil
+	}
+	defer discard(resp)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil
+	}
+	var obj map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&obj); err != nil {
+		return nil, nil
+	}
+	return obj, nil
+}
+
+// requestNamespace returns the namespace in an API path, e.g. "shop" in
+// /apis/apps/v1/namespaces/shop/deployments/web, or "" for cluster-scoped
+// resources and namespaces themselves.
+func requestNamespace(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, part := range parts {
+		if part == "namespaces" && i+2 < len(parts) {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// policyRejection is the 403 a write that breaks errs is answered with.
+func policyRejection(req *http.Request, errs []PolicyViolation) *http.Response {
+	messages := make([]string, len(errs))
+	for i, v := range errs {
+		messages[i] = fmt.Sprintf("%s/%s: %s", v.Policy, v.Rule, v.Message)
+	}
+	status := metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusFailure,
+		Message: fmt.Sprintf("policy: %s breaks %d enforced admission policy rule(s): %s",
+			errs[0].Resource, len(errs), strings.Join(messages, "; ")),
+		Reason: metav1.StatusReasonForbidden,
+		Code:   http.StatusForbidden,
+	}
+	body, _ := json.Marshal(status)
+	return &http.Response{
+		Status:        "403 Forbidden",
+		StatusCode:    http.StatusForbidden,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// withPolicyCheck returns ctx with check, which the policyTransport of the
+// clients evaluates the writes made with ctx against.
+func withPolicyCheck(ctx context.Context, check *policyCheck) context.Context {
+	return context.WithValue(ctx, policyCheckKey{}, check)
+}
@@ -0,0 +1,330 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+const (
+	prometheusTimeout   = 30 * time.Second
+	prometheusMaxSeries = 20
+	prometheusMaxPoints = 60
+)
+
+// QueryPrometheusTool provides the query_prometheus tool for PromQL instant and range queries.
+type QueryPrometheusTool struct {
+	baseURL     string
+	bearerToken string
+}
+
+// NewQueryPrometheusTool creates a new QueryPrometheusTool.
+func NewQueryPrometheusTool(baseURL, bearerToken string) *QueryPrometheusTool {
+	return &QueryPrometheusTool{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		bearerToken: bearerToken,
+	}
+}
+
+// Name returns the tool name.
+func (t *QueryPrometheusTool) Name() string {
+	return "query_prometheus"
+}
+
+// Description returns the tool description.
+func (t *QueryPrometheusTool) Description() string {
+	return "Run a PromQL query against the configured Prometheus server. Use for latency, error rates, CPU/memory usage and other metrics when diagnosing performance problems. Set start (and optionally end/step) for a range query; otherwise an instant query is run."
+}
+
+// IsLongRunning returns false.
+func (t *QueryPrometheusTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *QueryPrometheusTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *QueryPrometheusTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *QueryPrometheusTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"query": {
+					Type:        "string",
+					Description: "PromQL expression, e.g. histogram_quantile(0.99, sum(rate(http_request_duration_seconds_bucket{namespace=\"shop\"}[5m])) by (le))",
+				},
+				"time": {
+					Type:        "string",
+					Description: "Evaluation time for an instant query: RFC3339, unix seconds, 'now', or relative like '-10m' (default: now)",
+				},
+				"start": {
+					Type:        "string",
+					Description: "Start of a range query: RFC3339, unix seconds, or relative like '-1h'. Setting this makes the query a range query",
+				},
+				"end": {
+					Type:        "string",
+					Description: "End of a range query (default: now)",
+				},
+				"step": {
+					Type:        "string",
+					Description: "Resolution step for a range query, e.g. '30s', '5m' (default: range divided into 60 points)",
+				},
+			},
+			Required: []string{"query"},
+		},
+	}
+}
+
+// promResponse is the envelope returned by the Prometheus HTTP API.
+type promResponse struct {
+	Status    string          `json:"status"`
+	ErrorType string          `json:"errorType"`
+	Error     string          `json:"error"`
+	Warnings  []string        `json:"warnings"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// promData is the data section of a query response.
+type promData struct {
+	ResultType string          `json:"resultType"`
+	Result     json.RawMessage `json:"result"`
+}
+
+// promSeries is a single vector sample or matrix series.
+type promSeries struct {
+	Metric map[string]string `json:"metric"`
+	Value  []any             `json:"value,omitempty"`
+	Values [][]any           `json:"values,omitempty"`
+}
+
+// Run executes the tool.
+func (t *QueryPrometheusTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		return map[string]any{"error": "invalid arguments"}, nil
+	}
+
+	query, _ := argsMap["query"].(string)
+	if query == "" {
+		return map[string]any{"error": "query parameter is required"}, nil
+	}
+
+	if t.baseURL == "" {
+		return map[string]any{"error": "Prometheus is not configured (set integrations.prometheus.url in config.yaml)"}, nil
+	}
+
+	now := time.Now()
+	params := url.Values{}
+	params.Set("query", query)
+
+	endpoint := "/api/v1/query"
+	startStr, _ := argsMap["start"].(string)
+	if startStr != "" {
+		start, err := parsePromTime(startStr, now)
+		if err != nil {
+			return map[string]any{"error": fmt.Sprintf("invalid start: %v", err)}, nil
+		}
+		end := now
+		if endStr, _ := argsMap["end"].(string); endStr != "" {
+			end, err = parsePromTime(endStr, now)
+			if err != nil {
+				return map[string]any{"error": fmt.Sprintf("invalid end: %v", err)}, nil
+			}
+		}
+		if !end.After(start) {
+			return map[string]any{"error": "end must be after start"}, nil
+		}
+
+		step := end.Sub(start) / prometheusMaxPoints
+		if stepStr, _ := argsMap["step"].(string); stepStr != "" {
+			step, err = time.ParseDuration(stepStr)
+			if err != nil || step <= 0 {
+				return map[string]any{"error": fmt.Sprintf("invalid step %q", stepStr)}, nil
+			}
+		}
+		if step < time.Second {
+			step = time.Second
+		}
+
+		endpoint = "/api/v1/query_range"
+		params.Set("start", formatPromTime(start))
+		params.Set("end", formatPromTime(end))
+		params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	} else if timeStr, _ := argsMap["time"].(string); timeStr != "" {
+		ts, err := parsePromTime(timeStr, now)
+		if err != nil {
+			return map[string]any{"error": fmt.Sprintf("invalid time: %v", err)}, nil
+		}
+		params.Set("time", formatPromTime(ts))
+	}
+
+	req, err := http.NewRequestWithContext(toolContext(ctx), "GET", t.baseURL+endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to create request: %v", err)}, nil
+	}
+	if t.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+t.bearerToken)
+	}
+
+	client := &http.Client{Timeout: prometheusTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to query Prometheus: %v", err)}, nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to read response: %v", err)}, nil
+	}
+
+	var promResp promResponse
+	if err := json.Unmarshal(body, &promResp); err != nil {
+		if len(body) > 512 {
+			body = body[:512]
+		}
+		return map[string]any{"error": fmt.Sprintf("Prometheus returned status %d: %s", resp.StatusCode, string(body))}, nil
+	}
+	if promResp.Status != "success" {
+		return map[string]any{"error": fmt.Sprintf("query failed (%s): %s", promResp.ErrorType, promResp.Error)}, nil
+	}
+
+	result, err := summarizePromData(promResp.Data)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to parse response: %v", err)}, nil
+	}
+	result["query"] = query
+	if len(promResp.Warnings) > 0 {
+		result["warnings"] = promResp.Warnings
+	}
+	return result, nil
+}
+
+// summarizePromData converts a query result into a compact form for the LLM,
+// capping the number of series and points so large matrices don't flood the context.
+func summarizePromData(raw json.RawMessage) (map[string]any, error) {
+	var data promData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+
+	result := map[string]any{"result_type": data.ResultType}
+
+	switch data.ResultType {
+	case "scalar", "string":
+		var sample []any
+		if err := json.Unmarshal(data.Result, &sample); err != nil {
+			return nil, err
+		}
+		if len(sample) == 2 {
+			result["value"] = sample[1]
+		}
+		return result, nil
+	case "vector", "matrix":
+	default:
+		return nil, fmt.Errorf("unknown result type %q", data.ResultType)
+	}
+
+	var series []promSeries
+	if err := json.Unmarshal(data.Result, &series); err != nil {
+		return nil, err
+	}
+
+	result["series_count"] = len(series)
+	if len(series) > prometheusMaxSeries {
+		result["truncated"] = fmt.Sprintf("showing first %d of %d series; narrow the query with label matchers or aggregation", prometheusMaxSeries, len(series))
+		series = series[:prometheusMaxSeries]
+	}
+
+	out := make([]map[string]any, 0, len(series))
+	for _, s := range series {
+		entry := map[string]any{"metric": s.Metric}
+		if data.ResultType == "vector" {
+			if len(s.Value) == 2 {
+				entry["value"] = s.Value[1]
+			}
+		} else {
+			values := downsample(s.Values, prometheusMaxPoints)
+			points := make([][]any, 0, len(values))
+			for _, v := range values {
+				if len(v) != 2 {
+					continue
+				}
+				points = append(points, []any{promTimestamp(v[0]), v[1]})
+			}
+			entry["values"] = points
+			if len(values) < len(s.Values) {
+				entry["points"] = len(s.Values)
+			}
+		}
+		out = append(out, entry)
+	}
+	result["series"] = out
+
+	return result, nil
+}
+
+// downsample returns at most limit evenly spaced points, always keeping the last one.
+func downsample(values [][]any, limit int) [][]any {
+	if len(values) <= limit {
+		return values
+	}
+	out := make([][]any, 0, limit)
+	stride := float64(len(values)-1) / float64(limit-1)
+	for i := 0; i < limit; i++ {
+		out = append(out, values[int(float64(i)*stride+0.5)])
+	}
+	return out
+}
+
+// promTimestamp renders a Prometheus sample timestamp (unix seconds) as RFC3339.
+func promTimestamp(v any) any {
+	f, ok := v.(float64)
+	if !ok {
+		return v
+	}
+	return time.Unix(int64(f), 0).UTC().Format(time.RFC3339)
+}
+
+// parsePromTime parses RFC3339, unix seconds, "now", or a relative duration
+// such as "-1h" or "30m" (both meaning that long before now).
+func parsePromTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "now" {
+		return now, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, int64(secs*float64(time.Second))), nil
+	}
+	if d, err := time.ParseDuration(strings.TrimPrefix(s, "-")); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q (use RFC3339, unix seconds, 'now', or a duration like '-1h')", s)
+}
+
+// formatPromTime formats a time as unix seconds for the Prometheus API.
+func formatPromTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/float64(time.Second), 'f', 3, 64)
+}
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParsePromTime(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"now", now},
+		{"", now},
+		{"-1h", now.Add(-time.Hour)},
+		{"30m", now.Add(-30 * time.Minute)},
+		{"2025-06-01T10:00:00Z", time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)},
+		{"1748772000", time.Unix(1748772000, 0)},
+	}
+	for _, tt := range tests {
+		got, err := parsePromTime(tt.in, now)
+		if err != nil {
+			t.Errorf("parsePromTime(%q) error: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parsePromTime(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := parsePromTime("yesterday", now); err == nil {
+		t.Error("expected error for unparseable time")
+	}
+}
+
+func TestSummarizePromData_Vector(t *testing.T) {
+	raw := json.RawMessage(`{"resultType":"vector","result":[{"metric":{"pod":"web-1"},"value":[1748772000,"0.25"]}]}`)
+
+	result, err := summarizePromData(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["result_type"] != "vector" || result["series_count"] != 1 {
+		t.Fatalf("unexpected summary: %+v", result)
+	}
+	series := result["series"].([]map[string]any)
+	if series[0]["value"] != "0.25" {
+		t.Errorf("expected value 0.25, got %v", series[0]["value"])
+	}
+}
+
+func TestSummarizePromData_MatrixDownsampled(t *testing.T) {
+	values := make([][]any, 0, 200)
+	for i := 0; i < 200; i++ {
+		values = append(values, []any{float64(1748772000 + i*15), "1"})
+	}
+	data, _ := json.Marshal(map[string]any{
+		"resultType": "matrix",
+		"result":     []map[string]any{{"metric": map[string]string{"job": "api"}, "values": values}},
+	})
+
+	result, err := summarizePromData(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	series := result["series"].([]map[string]any)
+	points := series[0]["values"].([][]any)
+	if len(points) != prometheusMaxPoints {
+		t.Errorf("expected %d points, got %d", prometheusMaxPoints, len(points))
+	}
+	if points[len(points)-1][0] != time.Unix(1748772000+199*15, 0).UTC().Format(time.RFC3339) {
+		t.Errorf("expected last point to be kept, got %v", points[len(points)-1][0])
+	}
+	if series[0]["points"] != 200 {
+		t.Errorf("expected original point count 200, got %v", series[0]["points"])
+	}
+}
+
+func TestSummarizePromData_TruncatesSeries(t *testing.T) {
+	series := make([]map[string]any, 0, 30)
+	for i := 0; i < 30; i++ {
+		series = append(series, map[string]any{"metric": map[string]string{}, "value": []any{1748772000, "1"}})
+	}
+	data, _ := json.Marshal(map[string]any{"resultType": "vector", "result": series})
+
+	result, err := summarizePromData(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := len(result["series"].([]map[string]any)); got != prometheusMaxSeries {
+		t.Errorf("expected %d series, got %d", prometheusMaxSeries, got)
+	}
+	if _, ok := result["truncated"]; !ok {
+		t.Error("expected truncated note")
+	}
+}
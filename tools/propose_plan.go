@@ -1,19 +1,28 @@
 package tools
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"sync"
 
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/tool"
 	"google.golang.org/genai"
+	"k8s.io/client-go/kubernetes"
 )
 
 // ProposePlanTool captures planned mutating actions for user approval.
-type ProposePlanTool struct{}
+type ProposePlanTool struct {
+	images *imageChecker
+}
 
-// NewProposePlanTool creates a new ProposePlanTool.
-func NewProposePlanTool() *ProposePlanTool {
-	return &ProposePlanTool{}
+// NewProposePlanTool creates a new ProposePlanTool. The images of planned
+// create_deployment and set_image actions are checked against their
+// registry, with the pull secrets read through clientset.
+func NewProposePlanTool(clientset *kubernetes.Clientset) *ProposePlanTool {
+	return &ProposePlanTool{images: newImageChecker(clientset)}
 }
 
 // Name returns the tool name.
@@ -119,11 +128,63 @@ func (t *ProposePlanTool) Run(ctx tool.Context, args any) (map[string]any, error
 		}
 	}
 
+	// The mutating calls that carry the plan out record its ID and the
+	// user's request on the objects they change; see recordChanges
+	planID := newPlanID()
+	if !sessionless(ctx) {
+		_ = ctx.State().Set(planIDStateKey, planID)
+		_ = ctx.State().Set(planPromptStateKey, annotationLine(userText(ctx.UserContent())))
+	}
+
 	// Return the plan details for the REPL to capture and display
-	return map[string]any{
+	result := map[string]any{
 		"status":      "awaiting_approval",
 		"message":     "Plan proposed. Waiting for user approval. Type 'yes' to approve or 'no' to reject.",
+		"plan_id":     planID,
 		"description": description,
 		"actions":     actions,
-	}, nil
+	}
+	if warnings := t.checkImages(ctx, actions); len(warnings) > 0 {
+		result["image_warnings"] = warnings
+		result["message"] = "Plan proposed with image warnings the user will see alongside it. Waiting for user approval. Type 'yes' to approve or 'no' to reject."
+	}
+	return result, nil
+}
+
+// newPlanID returns a random plan ID.
+func newPlanID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return "plan-" + hex.EncodeToString(b)
+}
+
+// checkImages returns a warning for each planned action whose image can't
+// be pulled. The lookups run in parallel to stay within the read timeout.
+func (t *ProposePlanTool) checkImages(ctx tool.Context, actions []any) []string {
+	if t.images == nil {
+		return nil
+	}
+	found := make([]string, len(actions))
+	var wg sync.WaitGroup
+	for i, action := range actions {
+		actionMap := action.(map[string]any)
+		toolName := actionMap["tool"].(string)
+		params, _ := actionMap["parameters"].(map[string]any)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if warning := t.images.check(toolContext(ctx), toolName, params); warning != "" {
+				found[i] = fmt.Sprintf("action %d (%s): %s", i+1, toolName, warning)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var warnings []string
+	for _, warning := range found {
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+	return warnings
 }
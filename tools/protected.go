@@ -0,0 +1,436 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/tool"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// ProtectedAnnotation marks a resource no tool may change or delete.
+const ProtectedAnnotation = "kasa.io/protected"
+
+// ProtectionPolicy lists resources mutating tools must leave alone, even
+// when the user approves the plan. Resources annotated
+// kasa.io/protected=true are always protected.
+type ProtectionPolicy struct {
+	Names    []string `yaml:"names"`    // glob patterns on the resource name, e.g. ["kube-*"]
+	Selector string   `yaml:"selector"` // label selector, e.g. "tier=critical"
+}
+
+// IsEmpty reports whether the policy protects nothing beyond the annotated
+// resources.
+func (p ProtectionPolicy) IsEmpty() bool {
+	return len(p.Names) == 0 && p.Selector == ""
+}
+
+// Validate checks the name patterns and the label selector.
+func (p ProtectionPolicy) Validate() error {
+	for _, pattern := range p.Names {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("names: pattern %q: %w", pattern, err)
+		}
+	}
+	if _, err := labels.Parse(p.Selector); err != nil {
+		return fmt.Errorf("selector: %w", err)
+	}
+	return nil
+}
+
+// protectGuard refuses mutating tool calls that would change a protected
+// resource.
+type protectGuard struct {
+	policy        ProtectionPolicy
+	selector      labels.Selector // nil if the policy has none
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+	manifest      *manifest.Manager
+}
+
+// SetProtectedResources refuses changes to the resources policy protects
+// and to those annotated kasa.io/protected=true.
+func (k *KubeTools) SetProtectedResources(policy ProtectionPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	g := &protectGuard{policy: policy, dynamicClient: k.dynamicClient, resolver: k.resolver, manifest: k.manifest}
+	if policy.Selector != "" {
+		g.selector, _ = labels.Parse(policy.Selector)
+	}
+	k.protectGuard = g
+	return nil
+}
+
+// resourceRef is a resource a tool call changes.
+type resourceRef struct {
+	kind, apiVersion, namespace, name string
+}
+
+func (r resourceRef) String() string {
+	if r.namespace == "" {
+		return fmt.Sprintf("%s %q", r.kind, r.name)
+	}
+	return fmt.Sprintf("%s %q in namespace %q", r.kind, r.name, r.namespace)
+}
+
+// changedKinds are the tools that change the resource named by their name
+// argument, and its kind. New mutating tools must be added here, to
+// clusterUnchanged or to changedResources, or they are refused while the
+// protection policy protects names or labels.
+var changedKinds = map[string]string{
+	"add_container":            "deployment",
+	"canary_deploy":            "deployment",
+	"pause_rollout":            "deployment",
+	"resume_rollout":           "deployment",
+	"rollback_deployment":      "deployment",
+	"create_deployment":        "deployment",
+	"create_service":           "service",
+	"create_ingress":           "ingress",
+	"create_gateway":           "gateway",
+	"create_httproute":         "httproute",
+	"create_serviceaccount":    "serviceaccount",
+	"create_configmap":         "configmap",
+	"update_configmap_key":     "configmap",
+	"create_secret":            "secret",
+	"create_image_pull_secret": "secret",
+	"update_secret_key":        "secret",
+	"evict_pod":                "pod",
+	"create_namespace":         "namespace",
+	"delete_namespace":         "namespace",
+	"cordon_node":              "node",
+	"drain_node":               "node",
+}
+
+// clusterUnchanged are the mutating tools that change no existing cluster
+// resource: they write stored manifests or git, send HTTP requests, or
+// create and remove a pod of their own.
+var clusterUnchanged = map[string]bool{
+	"commit_manifests":    true,
+	"push_manifests":      true,
+	"edit_manifest_field": true,
+	"import_resource":     true,
+	"import_namespace":    true,
+	"adopt_drift":         true,
+	"http_request":        true,
+	"probe_service":       true,
+}
+
+// changedResources returns the resources a call to toolName changes, and
+// false for tools it doesn't know, such as registered extension tools.
+// Tools that only change stored manifests or nothing in the cluster return
+// none.
+func (g *protectGuard) changedResources(toolName string, args map[string]any) ([]resourceRef, bool) {
+	str := func(key string) string {
+		s, _ := args[key].(string)
+		return s
+	}
+	namespace := str("namespace")
+	if kind, ok := changedKinds[toolName]; ok {
+		refs := []resourceRef{{kind: kind, namespace: namespace, name: str("name")}}
+		if kind == "namespace" || kind == "node" {
+			refs[0].namespace = ""
+		}
+		if sa := str("service_account"); toolName == "create_image_pull_secret" && sa != "" {
+			refs = append(refs, resourceRef{kind: "serviceaccount", namespace: namespace, name: sa})
+		}
+		return refs, true
+	}
+	if clusterUnchanged[toolName] {
+		return nil, true
+	}
+
+	switch toolName {
+	case "set_image", "set_resources":
+		kind := str("kind")
+		if kind == "" {
+			kind = "deployment"
+		}
+		return []resourceRef{{kind: kind, namespace: namespace, name: str("name")}}, true
+	case "delete_resource", "label_resource", "annotate_resource":
+		return []resourceRef{{kind: str("type"), apiVersion: str("api_version"), namespace: namespace, name: str("name")}}, true
+	case "create_pdb":
+		name := str("name")
+		if name == "" {
+			name = str("deployment") + "-pdb"
+		}
+		return []resourceRef{{kind: "poddisruptionbudget", namespace: namespace, name: name}}, true
+	case "cp_to_pod":
+		return []resourceRef{{kind: "pod", namespace: namespace, name: str("pod")}}, true
+	case "apply_resource":
+		ref := manifestRef([]byte(str("yaml")))
+		if namespace != "" {
+			ref.namespace = namespace
+		}
+		return []resourceRef{ref}, true
+	case "apply_manifest":
+		resourceType := normalizeKind(str("type"))
+		if resourceType == "" {
+			resourceType = NormalizeKindName(str("type"))
+		}
+		if g.manifest == nil {
+			return nil, true
+		}
+		content, err := g.manifest.ReadManifest(namespace, str("app"), resourceType)
+		if err != nil {
+			return nil, true // the tool reports the missing manifest
+		}
+		return []resourceRef{storedManifestRef(namespace, str("app"), resourceType, content)}, true
+	case "delete_manifest":
+		if fromCluster, ok := args["delete_from_cluster"].(bool); (ok && !fromCluster) || g.manifest == nil {
+			return nil, true
+		}
+		// The tool deletes the objects of the stored manifests, which may be
+		// named other than the app
+		stored, _ := g.manifest.ListManifests(namespace, str("app"))
+		var refs []resourceRef
+		for _, m := range stored {
+			if t := str("type"); t != "" && t != m.Type {
+				continue
+			}
+			content, err := g.manifest.ReadManifest(m.Namespace, m.App, m.Type)
+			if err != nil {
+				continue
+			}
+			refs = append(refs, storedManifestRef(m.Namespace, m.App, m.Type, content))
+		}
+		return refs, true
+	}
+	return nil, false
+}
+
+// manifestRef returns the resource a YAML manifest describes.
+func manifestRef(content []byte) resourceRef {
+	var obj struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+		Metadata   struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+	}
+	_ = yaml.Unmarshal(content, &obj)
+	return resourceRef{kind: obj.Kind, apiVersion: obj.APIVersion, namespace: obj.Metadata.Namespace, name: obj.Metadata.Name}
+}
+
+// storedManifestRef returns the resource a stored manifest describes.
+func storedManifestRef(namespace, app, resourceType string, content []byte) resourceRef {
+	ref := manifestRef(content)
+	if ref.kind == "" {
+		ref.kind = resourceType
+	}
+	if ref.name == "" {
+		ref.name = app
+	}
+	ref.namespace = namespace
+	if namespace == manifest.ClusterNamespace {
+		ref.namespace = ""
+	}
+	return ref
+}
+
+// middleware checks the resources a mutating call changes before it runs.
+// Calls that don't say which resource, or name a kind that can't be
+// resolved, are left to the tool to reject.
+// Mutating custom tools, and other tools changedResources doesn't know,
+// are refused when the policy protects names or labels.
+func (g *protectGuard) middleware(t ToolInfo, next RunFunc) RunFunc {
+	if t.Category != CategoryMutating {
+		return next
+	}
+	if t.Custom && !g.policy.IsEmpty() {
+		// What a custom tool changes is unknown, so it can't be checked
+		return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			return map[string]any{
+				"error":     fmt.Sprintf("policy: %s is a custom tool kasa cannot check against safety.protected_resources, so it is refused while protected resources are configured", t.Name),
+				"protected": true,
+				"tool":      t.Name,
+				"message":   "The change was NOT made and approving the plan again will not help. Stop executing the plan and tell the user to make the change outside kasa, or to mark the custom tool read_only if it changes nothing.",
+			}, nil
+		}
+	}
+	return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		refs, known := g.changedResources(t.Name, args)
+		if !known && !g.policy.IsEmpty() {
+			return map[string]any{
+				"error":     fmt.Sprintf("policy: kasa doesn't know which resources %s changes, so it cannot check them against safety.protected_resources and refuses it while protected resources are configured", t.Name),
+				"protected": true,
+				"tool":      t.Name,
+				"message":   "The change was NOT made and approving the plan again will not help. Stop executing the plan and tell the user to make the change outside kasa.",
+			}, nil
+		}
+		for _, ref := range refs {
+			if ref.kind == "" || ref.name == "" {
+				continue
+			}
+			reason, err := g.protected(toolContext(ctx), ref)
+			if err != nil {
+				return map[string]any{
+					"error":   fmt.Sprintf("could not check whether %s is protected: %v", ref, err),
+					"message": "The change was NOT made. Retry later, or tell the user if the error persists.",
+				}, nil
+			}
+			if reason != "" {
+				return map[string]any{
+					"error":     fmt.Sprintf("policy: %s is protected (%s); kasa may not change or delete it, even with approval", ref, reason),
+					"protected": true,
+					"tool":      t.Name,
+					"namespace": ref.namespace,
+					"resource":  ref.String(),
+					"message": "The change was NOT made and approving the plan again will not help. Stop executing the plan and tell the user " +
+						"the resource must be changed outside kasa, or removed from safety.protected_resources first.",
+				}, nil
+			}
+		}
+		ref, reason, err := g.removed(toolContext(ctx), t.Name, args)
+		if err != nil {
+			return map[string]any{
+				"error":   fmt.Sprintf("could not check whether %s removes protected resources: %v", t.Name, err),
+				"message": "The change was NOT made. Retry later, or tell the user if the error persists.",
+			}, nil
+		}
+		if reason != "" {
+			return map[string]any{
+				"error":     fmt.Sprintf("policy: %s would remove %s, which is protected (%s); kasa may not do that, even with approval", t.Name, ref, reason),
+				"protected": true,
+				"tool":      t.Name,
+				"namespace": ref.namespace,
+				"resource":  ref.String(),
+				"message": "The change was NOT made and approving the plan again will not help. Stop executing the plan and tell the user " +
+					"the change must be made outside kasa, or the resource removed from safety.protected_resources first.",
+			}, nil
+		}
+		return next(ctx, args)
+	}
+}
+
+// protected returns why ref is protected, or "" if it isn't. Resources that
+// don't exist yet are only protected by name.
+func (g *protectGuard) protected(ctx context.Context, ref resourceRef) (string, error) {
+	if reason := g.nameReason(ref.name); reason != "" {
+		return reason, nil
+	}
+	if g.dynamicClient == nil {
+		return "", nil
+	}
+
+	gvr, err := g.resolver.Resolve(ref.kind, ref.apiVersion)
+	if err != nil {
+		return "", nil
+	}
+	ri := g.dynamicClient.Resource(gvr)
+	var live dynamic.ResourceInterface = ri
+	if g.resolver.IsNamespaced(gvr, ref.kind) {
+		namespace := ref.namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		live = ri.Namespace(namespace)
+	}
+
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+	obj, err := live.Get(ctx, ref.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return g.objectReason(obj), nil
+}
+
+// nameReason returns why a resource called name is protected by name, or "".
+func (g *protectGuard) nameReason(name string) string {
+	for _, pattern := range g.policy.Names {
+		if ok, _ := path.Match(pattern, name); ok {
+			return fmt.Sprintf("name matches %s", pattern)
+		}
+	}
+	return ""
+}
+
+// objectReason returns why a live object is protected, or "" if it isn't.
+func (g *protectGuard) objectReason(obj metav1.Object) string {
+	if reason := g.nameReason(obj.GetName()); reason != "" {
+		return reason
+	}
+	if strings.EqualFold(obj.GetAnnotations()[ProtectedAnnotation], "true") {
+		return fmt.Sprintf("annotated %s=true", ProtectedAnnotation)
+	}
+	if g.selector != nil && g.selector.Matches(labels.Set(obj.GetLabels())) {
+		return fmt.Sprintf("labels match %s", g.policy.Selector)
+	}
+	return ""
+}
+
+// removed returns a protected resource a call to toolName removes along
+// with the one it names, and why it is protected: a resource in the
+// namespace delete_namespace deletes, or a pod drain_node evicts. The ref
+// is empty if there is none.
+func (g *protectGuard) removed(ctx context.Context, toolName string, args map[string]any) (resourceRef, string, error) {
+	name, _ := args["name"].(string)
+	if g.dynamicClient == nil || name == "" {
+		return resourceRef{}, "", nil
+	}
+
+	switch toolName {
+	case "delete_namespace":
+		for _, kind := range g.resolver.Kinds() {
+			gvr, err := g.resolver.Resolve(kind, "")
+			if err != nil || !g.resolver.IsNamespaced(gvr, kind) {
+				continue
+			}
+			listCtx, cancel := apiContext(ctx)
+			list, err := g.dynamicClient.Resource(gvr).Namespace(name).List(listCtx, metav1.ListOptions{})
+			cancel()
+			if apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err) {
+				continue // not installed, or can't be listed
+			}
+			if err != nil {
+				return resourceRef{}, "", fmt.Errorf("listing %s in namespace %q: %w", kind, name, err)
+			}
+			for _, item := range list.Items {
+				if reason := g.objectReason(&item); reason != "" {
+					return resourceRef{kind: kind, namespace: name, name: item.GetName()}, reason, nil
+				}
+			}
+		}
+	case "drain_node":
+		listCtx, cancel := apiContext(ctx)
+		list, err := g.dynamicClient.Resource(corev1.SchemeGroupVersion.WithResource("pods")).List(listCtx, metav1.ListOptions{
+			FieldSelector: fmt.Sprintf("spec.nodeName=%s", name),
+		})
+		cancel()
+		if err != nil {
+			return resourceRef{}, "", fmt.Errorf("listing the pods on node %q: %w", name, err)
+		}
+		var pods []corev1.Pod
+		for _, item := range list.Items {
+			var pod corev1.Pod
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &pod); err != nil || pod.Spec.NodeName != name {
+				continue
+			}
+			pods = append(pods, pod)
+		}
+		// Only the pods the drain evicts matter; it leaves the others alone
+		force, _ := args["force"].(bool)
+		deleteEmptyDir, _ := args["delete_emptydir_data"].(bool)
+		for _, pod := range planDrain(pods, force, deleteEmptyDir).evict {
+			if reason := g.objectReason(&pod); reason != "" {
+				return resourceRef{kind: "pod", namespace: pod.Namespace, name: pod.Name}, reason, nil
+			}
+		}
+	}
+	return resourceRef{}, "", nil
+}
@@ -0,0 +1,174 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/tool"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestProtectGuard(t *testing.T) {
+	object := func(kind, name string, labels, annotations map[string]any) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "v1",
+			"kind":       kind,
+			"metadata":   map[string]any{"name": name, "namespace": "default", "labels": labels, "annotations": annotations},
+		}}
+	}
+	mgr := newTestManifestManager(t)
+	if _, err := mgr.SaveManifest("default", "settings", "configmap", []byte(configMapYAML("settings", "v1"))); err != nil {
+		t.Fatal(err)
+	}
+	// An app whose stored configmap is named other than the app
+	if _, err := mgr.SaveManifest("default", "shop", "configmap", []byte(configMapYAML("shop-config", "v1"))); err != nil {
+		t.Fatal(err)
+	}
+
+	k := &KubeTools{
+		dynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+			object("ConfigMap", "settings", nil, map[string]any{ProtectedAnnotation: "true"}),
+			object("ConfigMap", "shop-config", map[string]any{"tier": "critical"}, nil),
+			object("Secret", "db", map[string]any{"tier": "critical"}, nil),
+			object("Secret", "web", map[string]any{"tier": "frontend"}, nil),
+		),
+		manifest: mgr,
+	}
+	if err := k.SetProtectedResources(ProtectionPolicy{Names: []string{"kube-*"}, Selector: "tier=critical"}); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	next := func(tool.Context, map[string]any) (map[string]any, error) {
+		calls++
+		return map[string]any{"success": true}, nil
+	}
+	tests := []struct {
+		tool      string
+		args      map[string]any
+		protected string // reason, or "" if the call runs
+	}{
+		{"update_configmap_key", map[string]any{"namespace": "default", "name": "settings", "key": "a", "value": "b"}, "annotated kasa.io/protected=true"},
+		{"apply_manifest", map[string]any{"namespace": "default", "app": "settings", "type": "cm"}, "annotated kasa.io/protected=true"},
+		{"delete_manifest", map[string]any{"namespace": "default", "app": "settings"}, "annotated kasa.io/protected=true"},
+		{"delete_manifest", map[string]any{"namespace": "default", "app": "settings", "delete_from_cluster": false}, ""},
+		{"delete_manifest", map[string]any{"namespace": "default", "app": "shop"}, "labels match tier=critical"},
+		{"delete_resource", map[string]any{"namespace": "default", "type": "secret", "name": "db"}, "labels match tier=critical"},
+		{"delete_resource", map[string]any{"namespace": "default", "type": "secret", "name": "web"}, ""},
+		{"create_namespace", map[string]any{"name": "kube-tools"}, "name matches kube-*"},
+		{"apply_resource", map[string]any{"yaml": configMapYAML("settings", "v2")}, "annotated kasa.io/protected=true"},
+		{"apply_resource", map[string]any{"yaml": configMapYAML("new", "v1")}, ""},
+		{"commit_manifests", map[string]any{"message": "kube-system"}, ""},
+		{"create_keda_scaler", map[string]any{"deployment": "web"}, "doesn't know which resources create_keda_scaler changes"},
+	}
+	for _, tt := range tests {
+		before := calls
+		result, err := k.protectGuard.middleware(ToolInfo{Name: tt.tool, Category: CategoryMutating}, next)(nil, tt.args)
+		if err != nil {
+			t.Fatalf("%s %v: %v", tt.tool, tt.args, err)
+		}
+		if tt.protected == "" {
+			if calls == before {
+				t.Errorf("%s %v should run, got %v", tt.tool, tt.args, result)
+			}
+			continue
+		}
+		msg, _ := result["error"].(string)
+		if calls != before || result["protected"] != true || !strings.Contains(msg, tt.protected) {
+			t.Errorf("%s %v should be refused (%s), got %v", tt.tool, tt.args, tt.protected, result)
+		}
+	}
+
+	// Without names or a selector, tools it doesn't know still run
+	if err := k.SetProtectedResources(ProtectionPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	before := calls
+	if result, _ := k.protectGuard.middleware(ToolInfo{Name: "create_keda_scaler", Category: CategoryMutating}, next)(nil, map[string]any{}); calls == before {
+		t.Errorf("create_keda_scaler should run without a protection policy, got %v", result)
+	}
+
+	if err := (ProtectionPolicy{Selector: "tier in (a"}).Validate(); err == nil {
+		t.Error("expected an error for a malformed selector")
+	}
+}
+
+// TestProtectGuard_KnowsBuiltinTools keeps changedResources up to date: a
+// new mutating tool it doesn't know would be refused under every protection
+// policy with names or a selector.
+func TestProtectGuard_Removed(t *testing.T) {
+	controller := true
+	pod := func(name, node, owner string, labels map[string]string) *unstructured.Unstructured {
+		obj := findTestObject("v1", "Pod", "shop", name, labels)
+		obj.Object["spec"] = map[string]any{"nodeName": node}
+		obj.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: owner, Name: owner, UID: "uid", Controller: &controller}})
+		return obj
+	}
+	settings := findTestObject("v1", "ConfigMap", "shop", "settings", nil)
+	settings.SetAnnotations(map[string]string{ProtectedAnnotation: "true"})
+	critical := map[string]string{"tier": "critical"}
+
+	listKinds := map[schema.GroupVersionResource]string{}
+	for kind, gvr := range CommonGVRs {
+		listKinds[gvr] = kind + "List"
+	}
+	k := &KubeTools{
+		dynamicClient: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+			settings,
+			findTestObject("v1", "Secret", "staging", "db", nil),
+			pod("cache", "node-1", "ReplicaSet", critical),
+			pod("web", "node-2", "ReplicaSet", nil),
+			pod("agent", "node-2", "DaemonSet", critical),
+		),
+		manifest: newTestManifestManager(t),
+	}
+	if err := k.SetProtectedResources(ProtectionPolicy{Selector: "tier=critical"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tool      string
+		args      map[string]any
+		protected string // the protected resource, or "" if the call runs
+	}{
+		{"delete_namespace", map[string]any{"name": "shop"}, `configmap "settings" in namespace "shop"`},
+		{"delete_namespace", map[string]any{"name": "staging"}, ""},
+		{"drain_node", map[string]any{"name": "node-1"}, `pod "cache" in namespace "shop"`},
+		// The drain leaves DaemonSet pods alone
+		{"drain_node", map[string]any{"name": "node-2"}, ""},
+	}
+	for _, tt := range tests {
+		calls := 0
+		next := func(tool.Context, map[string]any) (map[string]any, error) {
+			calls++
+			return map[string]any{"success": true}, nil
+		}
+		result, _ := k.protectGuard.middleware(ToolInfo{Name: tt.tool, Category: CategoryMutating}, next)(nil, tt.args)
+		if tt.protected == "" {
+			if calls != 1 {
+				t.Errorf("%s %v should run, got %v", tt.tool, tt.args, result)
+			}
+			continue
+		}
+		if calls != 0 || result["protected"] != true || result["resource"] != tt.protected {
+			t.Errorf("%s %v should be refused for %s, got %v", tt.tool, tt.args, tt.protected, result)
+		}
+	}
+}
+
+func TestProtectGuard_KnowsBuiltinTools(t *testing.T) {
+	k := &KubeTools{manifest: newTestManifestManager(t), scheduler: newToolScheduler()}
+	g := &protectGuard{}
+	for _, tl := range k.baseTools() {
+		if ft, ok := tl.(functionTool); !ok || ft.Category() != CategoryMutating {
+			continue
+		}
+		if _, known := g.changedResources(tl.Name(), map[string]any{}); !known {
+			t.Errorf("changedResources doesn't know the mutating tool %s; add it to changedKinds, clusterUnchanged or the switch", tl.Name())
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// quotaNearLimitPercent is the usage at which a quota resource is flagged.
+const quotaNearLimitPercent = 90
+
+// QuotaResource is the usage of one resource tracked by a ResourceQuota.
+type QuotaResource struct {
+	Resource  string  `json:"resource"`
+	Hard      string  `json:"hard"`
+	Used      string  `json:"used"`
+	Remaining string  `json:"remaining"`
+	Percent   float64 `json:"percent_used"`
+	NearLimit bool    `json:"near_limit,omitempty"`
+	Exhausted bool    `json:"exhausted,omitempty"`
+}
+
+// QuotaInfo summarizes a ResourceQuota.
+type QuotaInfo struct {
+	Name          string          `json:"name"`
+	Namespace     string          `json:"namespace"`
+	Scopes        []string        `json:"scopes,omitempty"`
+	ScopeSelector []string        `json:"scope_selector,omitempty"`
+	Resources     []QuotaResource `json:"resources"`
+}
+
+// LimitRangeItemInfo describes the constraints of one LimitRange item.
+type LimitRangeItemInfo struct {
+	Type                 string            `json:"type"`
+	Default              map[string]string `json:"default_limit,omitempty"`
+	DefaultRequest       map[string]string `json:"default_request,omitempty"`
+	Min                  map[string]string `json:"min,omitempty"`
+	Max                  map[string]string `json:"max,omitempty"`
+	MaxLimitRequestRatio map[string]string `json:"max_limit_request_ratio,omitempty"`
+}
+
+// LimitRangeInfo summarizes a LimitRange.
+type LimitRangeInfo struct {
+	Name      string               `json:"name"`
+	Namespace string               `json:"namespace"`
+	Limits    []LimitRangeItemInfo `json:"limits"`
+}
+
+// newQuotaInfo reports hard vs used for every resource a quota tracks.
+func newQuotaInfo(q corev1.ResourceQuota) QuotaInfo {
+	info := QuotaInfo{
+		Name:      q.Name,
+		Namespace: q.Namespace,
+		Resources: []QuotaResource{},
+	}
+	for _, s := range q.Spec.Scopes {
+		info.Scopes = append(info.Scopes, string(s))
+	}
+	if q.Spec.ScopeSelector != nil {
+		for _, expr := range q.Spec.ScopeSelector.MatchExpressions {
+			info.ScopeSelector = append(info.ScopeSelector, fmt.Sprintf("%s %s %s", expr.ScopeName, expr.Operator, strings.Join(expr.Values, ",")))
+		}
+	}
+
+	// Status.Hard is what the quota controller enforces; Spec.Hard is only
+	// newer if the controller hasn't caught up yet
+	hardList := q.Status.Hard
+	if len(hardList) == 0 {
+		hardList = q.Spec.Hard
+	}
+	for _, name := range sortedResourceNames(hardList) {
+		hard := hardList[name]
+		used := q.Status.Used[name]
+		remaining := hard.DeepCopy()
+		remaining.Sub(used)
+		if remaining.Sign() < 0 {
+			remaining = resource.Quantity{}
+		}
+
+		r := QuotaResource{
+			Resource:  string(name),
+			Hard:      hard.String(),
+			Used:      used.String(),
+			Remaining: remaining.String(),
+		}
+		if hard.IsZero() {
+			if !used.IsZero() {
+				r.Percent = 100
+			}
+		} else {
+			r.Percent = float64(int(float64(used.MilliValue())/float64(hard.MilliValue())*1000)) / 10
+		}
+		r.Exhausted = used.Cmp(hard) >= 0
+		r.NearLimit = !r.Exhausted && r.Percent >= quotaNearLimitPercent
+		info.Resources = append(info.Resources, r)
+	}
+	return info
+}
+
+// newLimitRangeInfo summarizes the defaults and bounds of a LimitRange.
+func newLimitRangeInfo(lr corev1.LimitRange) LimitRangeInfo {
+	info := LimitRangeInfo{
+		Name:      lr.Name,
+		Namespace: lr.Namespace,
+		Limits:    []LimitRangeItemInfo{},
+	}
+	for _, item := range lr.Spec.Limits {
+		info.Limits = append(info.Limits, LimitRangeItemInfo{
+			Type:                 string(item.Type),
+			Default:              resourceListStrings(item.Default),
+			DefaultRequest:       resourceListStrings(item.DefaultRequest),
+			Min:                  resourceListStrings(item.Min),
+			Max:                  resourceListStrings(item.Max),
+			MaxLimitRequestRatio: resourceListStrings(item.MaxLimitRequestRatio),
+		})
+	}
+	return info
+}
+
+// namespaceQuotas lists the ResourceQuotas and LimitRanges of a namespace,
+// or of all namespaces if namespace is empty.
+func namespaceQuotas(ctx context.Context, clientset kubernetes.Interface, namespace string) ([]QuotaInfo, []LimitRangeInfo, error) {
+	quotas, err := clientset.CoreV1().ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list ResourceQuotas: %w", err)
+	}
+	limitRanges, err := clientset.CoreV1().LimitRanges(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list LimitRanges: %w", err)
+	}
+
+	sort.Slice(quotas.Items, func(i, j int) bool {
+		a, b := quotas.Items[i], quotas.Items[j]
+		return a.Namespace < b.Namespace || (a.Namespace == b.Namespace && a.Name < b.Name)
+	})
+	sort.Slice(limitRanges.Items, func(i, j int) bool {
+		a, b := limitRanges.Items[i], limitRanges.Items[j]
+		return a.Namespace < b.Namespace || (a.Namespace == b.Namespace && a.Name < b.Name)
+	})
+
+	quotaInfos := make([]QuotaInfo, 0, len(quotas.Items))
+	for _, q := range quotas.Items {
+		quotaInfos = append(quotaInfos, newQuotaInfo(q))
+	}
+	limitRangeInfos := make([]LimitRangeInfo, 0, len(limitRanges.Items))
+	for _, lr := range limitRanges.Items {
+		limitRangeInfos = append(limitRangeInfos, newLimitRangeInfo(lr))
+	}
+	return quotaInfos, limitRangeInfos, nil
+}
+
+// podRequests returns the resources a pod counts against quota: the sum of
+// its containers, or its largest init container if that is bigger, plus
+// overhead.
+func podRequests(spec *corev1.PodSpec, limits bool) corev1.ResourceList {
+	list := func(c corev1.Container) corev1.ResourceList {
+		if limits {
+			return c.Resources.Limits
+		}
+		return c.Resources.Requests
+	}
+
+	total := corev1.ResourceList{}
+	for _, c := range spec.Containers {
+		addResourceList(total, list(c))
+	}
+	for _, c := range spec.InitContainers {
+		for name, q := range list(c) {
+			if cur, ok := total[name]; !ok || q.Cmp(cur) > 0 {
+				total[name] = q.DeepCopy()
+			}
+		}
+	}
+	addResourceList(total, spec.Overhead)
+	return total
+}
+
+func addResourceList(total, add corev1.ResourceList) {
+	for name, q := range add {
+		cur := total[name]
+		cur.Add(q)
+		total[name] = cur
+	}
+}
+
+func resourceListStrings(list corev1.ResourceList) map[string]string {
+	if len(list) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(list))
+	for name, q := range list {
+		out[string(name)] = q.String()
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ListQuotasTool provides the list_quotas tool for the agent.
+type ListQuotasTool struct {
+	clientset *kubernetes.Clientset
+}
+
+// NewListQuotasTool creates a new ListQuotasTool.
+func NewListQuotasTool(clientset *kubernetes.Clientset) *ListQuotasTool {
+	return &ListQuotasTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *ListQuotasTool) Name() string {
+	return "list_quotas"
+}
+
+// Description returns the tool description.
+func (t *ListQuotasTool) Description() string {
+	return "List ResourceQuotas (hard limit vs used for each resource, flagging exhausted and nearly full ones) and LimitRanges (default requests/limits, min/max) in a namespace or across all namespaces."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *ListQuotasTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *ListQuotasTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *ListQuotasTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *ListQuotasTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace (optional; all namespaces if empty)",
+				},
+			},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *ListQuotasTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				argsMap = make(map[string]any)
+			}
+		} else {
+			argsMap = make(map[string]any)
+		}
+	}
+
+	namespace, _ := argsMap["namespace"].(string)
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
+	defer cancel()
+
+	quotas, limitRanges, err := namespaceQuotas(timeoutCtx, t.clientset, namespace)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	var exhausted, nearLimit []string
+	for _, q := range quotas {
+		for _, r := range q.Resources {
+			switch {
+			case r.Exhausted:
+				exhausted = append(exhausted, fmt.Sprintf("%s/%s %s (%s of %s)", q.Namespace, q.Name, r.Resource, r.Used, r.Hard))
+			case r.NearLimit:
+				nearLimit = append(nearLimit, fmt.Sprintf("%s/%s %s (%.0f%%)", q.Namespace, q.Name, r.Resource, r.Percent))
+			}
+		}
+	}
+
+	result := map[string]any{
+		"quotas":       quotas,
+		"limit_ranges": limitRanges,
+		"quota_count":  len(quotas),
+	}
+	if namespace != "" {
+		result["namespace"] = namespace
+	}
+	if len(exhausted) > 0 {
+		result["exhausted"] = exhausted
+	}
+	if len(nearLimit) > 0 {
+		result["near_limit"] = nearLimit
+	}
+	return result, nil
+}
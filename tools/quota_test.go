@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNewQuotaInfo(t *testing.T) {
+	info := newQuotaInfo(corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "team"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    resource.MustParse("2"),
+				corev1.ResourceRequestsMemory: resource.MustParse("4Gi"),
+				corev1.ResourcePods:           resource.MustParse("10"),
+			},
+			Used: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    resource.MustParse("2"),
+				corev1.ResourceRequestsMemory: resource.MustParse("3800Mi"),
+				corev1.ResourcePods:           resource.MustParse("3"),
+			},
+		},
+	})
+
+	byName := map[string]QuotaResource{}
+	for _, r := range info.Resources {
+		byName[r.Resource] = r
+	}
+	if r := byName["requests.cpu"]; !r.Exhausted || r.Remaining != "0" || r.Percent != 100 {
+		t.Errorf("requests.cpu = %+v, want exhausted", r)
+	}
+	if r := byName["requests.memory"]; !r.NearLimit || r.Exhausted || r.Remaining != "296Mi" {
+		t.Errorf("requests.memory = %+v, want near limit with 296Mi left", r)
+	}
+	if r := byName["pods"]; r.NearLimit || r.Exhausted || r.Percent != 30 {
+		t.Errorf("pods = %+v, want 30%% used", r)
+	}
+}
+
+func TestPodRequests(t *testing.T) {
+	spec := &corev1.PodSpec{
+		InitContainers: []corev1.Container{{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("1"),
+		}}}},
+		Containers: []corev1.Container{
+			{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("250m"),
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+			}}},
+			{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("250m"),
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+			}}},
+		},
+	}
+	got := podRequests(spec, false)
+	if got.Cpu().String() != "1" || got.Memory().String() != "128Mi" {
+		t.Errorf("podRequests = cpu %s memory %s, want the init container's 1 CPU and 128Mi", got.Cpu(), got.Memory())
+	}
+}
+
+func TestQuotaUsage(t *testing.T) {
+	controller := true
+	pod := func(name, owner, cpu string, phase corev1.PodPhase) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team"},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+			}}}},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+		if owner != "" {
+			p.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: owner, Controller: &controller}}
+		}
+		return p
+	}
+
+	clientset := fake.NewSimpleClientset(
+		&corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "team"},
+			Status: corev1.ResourceQuotaStatus{
+				Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("1"), corev1.ResourceLimitsMemory: resource.MustParse("1Gi")},
+				Used: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("1"), corev1.ResourceLimitsMemory: resource.MustParse("0")},
+			},
+		},
+		&corev1.LimitRange{
+			ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "team"},
+			Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+				Type:           corev1.LimitTypeContainer,
+				DefaultRequest: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+			}}},
+		},
+		pod("web-1", "web-abc", "400m", corev1.PodRunning),
+		pod("web-2", "web-abc", "400m", corev1.PodRunning),
+		pod("debug", "", "200m", corev1.PodRunning),
+		pod("done", "", "4", corev1.PodSucceeded),
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "web-abc.1", Namespace: "team"},
+			InvolvedObject: corev1.ObjectReference{Kind: "ReplicaSet", Name: "web-abc"},
+			Reason:         "FailedCreate",
+			Message:        `pods "web-abc-x" is forbidden: exceeded quota: compute, requested: requests.cpu=400m, used: requests.cpu=1, limited: requests.cpu=1`,
+		},
+	)
+
+	result := quotaUsage(context.Background(), clientset, "team")
+	if _, ok := result["error"]; ok {
+		t.Fatalf("quotaUsage failed: %v", result["error"])
+	}
+
+	consumers := result["top_consumers"].([]QuotaConsumer)
+	if len(consumers) != 2 || consumers[0].Workload != "ReplicaSet/web-abc" || consumers[0].Pods != 2 || consumers[0].Requests["cpu"] != "800m" {
+		t.Errorf("top_consumers = %+v", consumers)
+	}
+
+	if rejections, _ := result["recent_rejections"].([]string); len(rejections) != 1 {
+		t.Errorf("recent_rejections = %v, want the exceeded quota event", result["recent_rejections"])
+	}
+
+	summary, _ := result["summary"].(string)
+	if !strings.Contains(summary, "exhausted for requests.cpu") {
+		t.Errorf("summary should report exhausted requests.cpu: %s", summary)
+	}
+	if !strings.Contains(summary, "tracks limits.memory and no LimitRange sets a default") {
+		t.Errorf("summary should report the missing limits.memory default: %s", summary)
+	}
+	if strings.Contains(summary, "tracks requests.cpu") {
+		t.Errorf("requests.cpu has a LimitRange default: %s", summary)
+	}
+}
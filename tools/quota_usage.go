@@ -0,0 +1,267 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// quotaTopConsumers is how many workloads get_quota_usage reports.
+const quotaTopConsumers = 10
+
+// QuotaConsumer is a workload's share of the namespace's compute quota.
+type QuotaConsumer struct {
+	Workload string            `json:"workload"` // controller kind/name, or pod/name for bare pods
+	Pods     int               `json:"pods"`
+	Requests map[string]string `json:"requests,omitempty"`
+	Limits   map[string]string `json:"limits,omitempty"`
+
+	cpu int64 // milli-CPU requested, for sorting
+}
+
+// GetQuotaUsageTool provides the get_quota_usage tool for the agent.
+type GetQuotaUsageTool struct {
+	clientset *kubernetes.Clientset
+}
+
+// NewGetQuotaUsageTool creates a new GetQuotaUsageTool.
+func NewGetQuotaUsageTool(clientset *kubernetes.Clientset) *GetQuotaUsageTool {
+	return &GetQuotaUsageTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *GetQuotaUsageTool) Name() string {
+	return "get_quota_usage"
+}
+
+// Description returns the tool description.
+func (t *GetQuotaUsageTool) Description() string {
+	return "Explain quota usage in a namespace: each ResourceQuota's hard vs used and remaining, the LimitRange defaults applied to pods, which workloads consume the most CPU and memory, and recent 'exceeded quota' or 'must specify' rejections. Use it to answer why pods fail to be created with 'forbidden: exceeded quota'."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *GetQuotaUsageTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *GetQuotaUsageTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *GetQuotaUsageTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *GetQuotaUsageTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+			},
+			Required: []string{"namespace"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *GetQuotaUsageTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+
+	return quotaUsage(toolContext(ctx), t.clientset, namespace), nil
+}
+
+// quotaUsage builds the get_quota_usage response for a namespace.
+func quotaUsage(ctx context.Context, clientset kubernetes.Interface, namespace string) map[string]any {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	quotas, limitRanges, err := namespaceQuotas(ctx, clientset, namespace)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+
+	result := map[string]any{
+		"namespace":    namespace,
+		"quotas":       quotas,
+		"limit_ranges": limitRanges,
+	}
+	if len(quotas) == 0 && len(limitRanges) == 0 {
+		result["summary"] = fmt.Sprintf("Namespace %s has no ResourceQuotas or LimitRanges; quota cannot be the reason pods are rejected", namespace)
+		return result
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to list pods: %v", err)}
+	}
+	result["top_consumers"] = quotaConsumers(pods.Items)
+
+	if rejections := quotaRejections(ctx, clientset, namespace); len(rejections) > 0 {
+		result["recent_rejections"] = rejections
+	}
+
+	findings := quotaFindings(quotas, limitRanges)
+	if len(findings) > 0 {
+		result["findings"] = findings
+		result["summary"] = strings.Join(findings, " ")
+	} else {
+		result["summary"] = fmt.Sprintf("All %d quota(s) in %s have headroom", len(quotas), namespace)
+	}
+	return result
+}
+
+// quotaConsumers sums the requests and limits of the non-terminated pods in
+// a namespace by owning workload, largest CPU request first.
+func quotaConsumers(pods []corev1.Pod) []QuotaConsumer {
+	type usage struct {
+		pods             int
+		requests, limits corev1.ResourceList
+	}
+	byWorkload := map[string]*usage{}
+	for i := range pods {
+		pod := &pods[i]
+		// Terminated pods no longer count against compute quota
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		workload := "Pod/" + pod.Name
+		if ref := metav1.GetControllerOf(pod); ref != nil {
+			workload = ref.Kind + "/" + ref.Name
+		}
+		u, ok := byWorkload[workload]
+		if !ok {
+			u = &usage{requests: corev1.ResourceList{}, limits: corev1.ResourceList{}}
+			byWorkload[workload] = u
+		}
+		u.pods++
+		addResourceList(u.requests, podRequests(&pod.Spec, false))
+		addResourceList(u.limits, podRequests(&pod.Spec, true))
+	}
+
+	consumers := make([]QuotaConsumer, 0, len(byWorkload))
+	for workload, u := range byWorkload {
+		consumers = append(consumers, QuotaConsumer{
+			Workload: workload,
+			Pods:     u.pods,
+			Requests: resourceListStrings(u.requests),
+			Limits:   resourceListStrings(u.limits),
+			cpu:      u.requests.Cpu().MilliValue(),
+		})
+	}
+	sort.Slice(consumers, func(i, j int) bool {
+		if consumers[i].cpu != consumers[j].cpu {
+			return consumers[i].cpu > consumers[j].cpu
+		}
+		return consumers[i].Workload < consumers[j].Workload
+	})
+	if len(consumers) > quotaTopConsumers {
+		consumers = consumers[:quotaTopConsumers]
+	}
+	return consumers
+}
+
+// quotaRejections returns recent events in which quota or LimitRange
+// admission rejected a pod or workload.
+func quotaRejections(ctx context.Context, clientset kubernetes.Interface, namespace string) []string {
+	events, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil
+	}
+	sort.Slice(events.Items, func(i, j int) bool {
+		return events.Items[i].LastTimestamp.After(events.Items[j].LastTimestamp.Time)
+	})
+
+	var rejections []string
+	for _, e := range events.Items {
+		if !strings.Contains(e.Message, "exceeded quota") && !strings.Contains(e.Message, "must specify") && !strings.Contains(e.Message, "LimitRange") {
+			continue
+		}
+		rejections = append(rejections, fmt.Sprintf("%s/%s: %s", e.InvolvedObject.Kind, e.InvolvedObject.Name, e.Message))
+		if len(rejections) == 10 {
+			break
+		}
+	}
+	return rejections
+}
+
+// quotaFindings explains which quota resources block new pods, and which
+// quota-tracked resources pods must declare because no LimitRange supplies
+// a default.
+func quotaFindings(quotas []QuotaInfo, limitRanges []LimitRangeInfo) []string {
+	defaults := map[string]bool{}
+	for _, lr := range limitRanges {
+		for _, item := range lr.Limits {
+			if item.Type != string(corev1.LimitTypeContainer) {
+				continue
+			}
+			for name := range item.Default {
+				defaults["limits."+name] = true
+				defaults["requests."+name] = true
+			}
+			for name := range item.DefaultRequest {
+				defaults["requests."+name] = true
+			}
+		}
+	}
+
+	var findings []string
+	for _, q := range quotas {
+		for _, r := range q.Resources {
+			switch {
+			case r.Exhausted:
+				findings = append(findings, fmt.Sprintf("Quota %s is exhausted for %s (%s of %s): new pods needing any %s are rejected with 'exceeded quota'.", q.Name, r.Resource, r.Used, r.Hard, r.Resource))
+			case r.NearLimit:
+				findings = append(findings, fmt.Sprintf("Quota %s has only %s of %s left (%.0f%% used); rollouts that surge extra pods may be rejected.", q.Name, r.Remaining, r.Resource, r.Percent))
+			}
+
+			tracked := r.Resource
+			switch tracked {
+			case "cpu", "memory":
+				tracked = "requests." + tracked
+			}
+			switch tracked {
+			case "requests.cpu", "requests.memory", "limits.cpu", "limits.memory":
+			default:
+				continue
+			}
+			if !defaults[tracked] {
+				findings = append(findings, fmt.Sprintf("Quota %s tracks %s and no LimitRange sets a default, so every container must declare it or its pod is rejected.", q.Name, tracked))
+			}
+		}
+	}
+	return findings
+}
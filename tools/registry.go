@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Tool is a tool contributed by a package outside this one. It runs through
+// the same middleware chain as the built-in tools, so it gets argument
+// validation, the audit log, the namespace policy, maintenance windows and
+// plan approval (for CategoryMutating) without doing anything.
+type Tool interface {
+	Name() string
+	Description() string
+	Category() ToolCategory
+	Declaration() *genai.FunctionDeclaration
+	Run(ctx tool.Context, args map[string]any) (map[string]any, error)
+}
+
+// Deps are the clients kasa was started with, for building tools. With an
+// enforced dry-run policy, tools are also built against dry-run clients.
+type Deps struct {
+	Clientset     *kubernetes.Clientset
+	DynamicClient dynamic.Interface
+	Resolver      *GVRResolver
+	Manifest      *manifest.Manager
+	RESTConfig    *rest.Config // nil unless pod exec is available
+}
+
+// Factory builds a tool against deps.
+type Factory func(deps Deps) Tool
+
+// Registry holds the factories of extension tools.
+type Registry struct {
+	mu        sync.Mutex
+	names     map[string]bool
+	factories []Factory
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// DefaultRegistry is the registry Register adds to and KubeTools uses
+// unless SetRegistry says otherwise.
+var DefaultRegistry = NewRegistry()
+
+// Register adds a tool to DefaultRegistry. Extension packages call it from
+// an init function, and are linked in with a blank import in package main:
+//
+//	func init() {
+//		tools.Register("create_keda_scaler", func(deps tools.Deps) tools.Tool {
+//			return NewCreateKEDAScalerTool(deps.DynamicClient, deps.Resolver)
+//		})
+//	}
+func Register(name string, factory Factory) {
+	DefaultRegistry.Register(name, factory)
+}
+
+// Register adds a tool. name must be the name of the tools factory
+// builds; registering a name twice panics.
+func (r *Registry) Register(name string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if factory == nil {
+		panic("tools: Register factory is nil for " + name)
+	}
+	if r.names[name] {
+		panic("tools: Register called twice for " + name)
+	}
+	r.names[name] = true
+	r.factories = append(r.factories, factory)
+}
+
+// build constructs the registered tools.
+func (r *Registry) build(deps Deps) []tool.Tool {
+	r.mu.Lock()
+	factories := append([]Factory(nil), r.factories...)
+	r.mu.Unlock()
+
+	built := make([]tool.Tool, 0, len(factories))
+	for _, factory := range factories {
+		built = append(built, registeredTool{factory(deps)})
+	}
+	return built
+}
+
+// SetRegistry replaces DefaultRegistry as the source of extension tools.
+func (k *KubeTools) SetRegistry(r *Registry) {
+	k.registry = r
+}
+
+// registeredTools builds the extension tools, which may not shadow a
+// built-in tool.
+func (k *KubeTools) registeredTools(builtin []tool.Tool) []tool.Tool {
+	registry := k.registry
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	extensions := registry.build(Deps{
+		Clientset:     k.clientset,
+		DynamicClient: k.dynamicClient,
+		Resolver:      k.resolver,
+		Manifest:      k.manifest,
+		RESTConfig:    k.restConfig,
+	})
+	for _, ext := range extensions {
+		for _, t := range builtin {
+			if t.Name() == ext.Name() {
+				panic(fmt.Sprintf("tools: registered tool %s shadows a built-in tool", ext.Name()))
+			}
+		}
+	}
+	return extensions
+}
+
+// IsRegistered reports whether t, as returned by All, was registered by an
+// extension package rather than built in.
+func IsRegistered(t tool.Tool) bool {
+	if chained, ok := t.(*chainedTool); ok {
+		t = chained.runnableTool
+	}
+	_, ok := t.(registeredTool)
+	return ok
+}
+
+// registeredTool adapts a Tool to the interfaces ADK and the middleware
+// chain expect.
+type registeredTool struct {
+	Tool
+}
+
+// IsLongRunning returns false.
+func (t registeredTool) IsLongRunning() bool {
+	return false
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t registeredTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Run executes the tool.
+func (t registeredTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		return map[string]any{"error": "invalid arguments"}, nil
+	}
+	return t.Tool.Run(ctx, argsMap)
+}
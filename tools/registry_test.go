@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// scalerTool is an extension tool as a downstream package would write it.
+type scalerTool struct {
+	deps Deps
+}
+
+func (t scalerTool) Name() string           { return "create_keda_scaler" }
+func (t scalerTool) Description() string    { return "Create a KEDA ScaledObject" }
+func (t scalerTool) Category() ToolCategory { return CategoryMutating }
+func (t scalerTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name: t.Name(),
+		Parameters: &genai.Schema{
+			Type:       "object",
+			Properties: map[string]*genai.Schema{"deployment": {Type: "string"}},
+			Required:   []string{"deployment"},
+		},
+	}
+}
+func (t scalerTool) Run(ctx tool.Context, args map[string]any) (map[string]any, error) {
+	return map[string]any{"success": true, "has_manifest": t.deps.Manifest != nil}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("create_keda_scaler", func(deps Deps) Tool { return scalerTool{deps} })
+	k := &KubeTools{manifest: newTestManifestManager(t), scheduler: newToolScheduler(), registry: registry}
+
+	var scaler runnableTool
+	for _, tl := range k.All() {
+		if tl.Name() == "create_keda_scaler" {
+			scaler = tl.(runnableTool)
+		}
+	}
+	if scaler == nil {
+		t.Fatal("registered tool missing from All()")
+	}
+	if !IsRegistered(scaler) || IsCustom(scaler) {
+		t.Error("registered tool should be reported as registered")
+	}
+	for _, tl := range k.All() {
+		if tl.Name() != "create_keda_scaler" && IsRegistered(tl) {
+			t.Errorf("built-in tool %s reported as registered", tl.Name())
+		}
+	}
+	if !k.IsMutatingName("create_keda_scaler") || !strings.Contains(k.GenerateToolDocs(), "create_keda_scaler") {
+		t.Error("registered tool should be documented and classified like built-in tools")
+	}
+	if result, _ := scaler.Run(nil, map[string]any{}); !strings.Contains(result["error"].(string), "missing required argument") {
+		t.Errorf("registered tools should run through the middleware chain, got %v", result)
+	}
+	if result, _ := scaler.Run(nil, map[string]any{"deployment": "web"}); result["success"] != true || result["has_manifest"] != true {
+		t.Errorf("expected the tool to run with its dependencies, got %v", result)
+	}
+
+	expectPanic := func(what string, f func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected a panic for %s", what)
+			}
+		}()
+		f()
+	}
+	expectPanic("a duplicate registration", func() {
+		registry.Register("create_keda_scaler", func(deps Deps) Tool { return scalerTool{deps} })
+	})
+	shadowing := NewRegistry()
+	shadowing.Register("list_pods", func(Deps) Tool { return namedTool{"list_pods"} })
+	k.SetRegistry(shadowing)
+	expectPanic("a tool shadowing a built-in one", func() { k.All() })
+}
+
+type namedTool struct{ name string }
+
+func (t namedTool) Name() string           { return t.name }
+func (t namedTool) Description() string    { return "" }
+func (t namedTool) Category() ToolCategory { return CategoryReadOnly }
+func (t namedTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{Name: t.name}
+}
+func (t namedTool) Run(tool.Context, map[string]any) (map[string]any, error) {
+	return nil, nil
+}
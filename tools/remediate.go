@@ -0,0 +1,218 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/tool"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+// RemediationPolicy selects the stored manifests that watch mode re-applies
+// automatically when drift is detected.
+type RemediationPolicy struct {
+	Namespaces      []string `yaml:"namespaces"`       // every app in these namespaces
+	Apps            []string `yaml:"apps"`             // "<namespace>/<app>" globs, e.g. "staging/*"
+	RecreateMissing bool     `yaml:"recreate_missing"` // also create resources deleted from the cluster
+}
+
+// Covers reports whether drift in namespace/app should be remediated.
+func (p RemediationPolicy) Covers(namespace, app string) bool {
+	if slices.Contains(p.Namespaces, namespace) {
+		return true
+	}
+	for _, pattern := range p.Apps {
+		if ok, _ := path.Match(pattern, namespace+"/"+app); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEmpty reports whether the policy remediates nothing.
+func (p RemediationPolicy) IsEmpty() bool {
+	return len(p.Namespaces) == 0 && len(p.Apps) == 0
+}
+
+// RemediationResult records one automatic re-apply of a stored manifest.
+type RemediationResult struct {
+	Time      time.Time `json:"time"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Drift     string    `json:"drift"`            // "drifted" or "missing"
+	Diffs     []string  `json:"diffs,omitempty"`  // drifted field paths that were reverted
+	Action    string    `json:"action,omitempty"` // "updated" or "created"
+	DryRun    bool      `json:"dry_run,omitempty"`
+	Skipped   string    `json:"skipped,omitempty"` // why the safety checks refused the re-apply
+	Error     string    `json:"error,omitempty"`
+}
+
+// RemediateDrift re-applies the stored manifest for every drifted (and,
+// if enabled, missing) resource in results that the policy covers.
+// Each re-apply goes through the same safety checks as a call to
+// apply_manifest; the resources they refuse are skipped.
+func (k *KubeTools) RemediateDrift(ctx context.Context, policy RemediationPolicy, results *DriftScanResults) []RemediationResult {
+	return k.remediate(ctx, policy, results, false)
+}
+
+// SyncDrift re-applies the stored manifest for every drifted or missing
+// resource in results, making the cluster match the manifest store. An empty
+// namespaces list syncs everything. With dryRun the changes are only
+// validated by the API server. Like RemediateDrift, it skips the resources
+// the safety checks refuse.
+func (k *KubeTools) SyncDrift(ctx context.Context, namespaces []string, dryRun bool, results *DriftScanResults) []RemediationResult {
+	policy := RemediationPolicy{Namespaces: namespaces, RecreateMissing: true}
+	if len(namespaces) == 0 {
+		policy.Apps = []string{"*/*"}
+	}
+	return k.remediate(ctx, policy, results, dryRun)
+}
+
+func (k *KubeTools) remediate(ctx context.Context, policy RemediationPolicy, results *DriftScanResults, dryRun bool) []RemediationResult {
+	if results == nil || policy.IsEmpty() {
+		return nil
+	}
+
+	// Re-applies are checked like calls to apply_manifest
+	reapply := k.reapplyManifest
+	info := ToolInfo{Name: "apply_manifest", Category: CategoryMutating, Declaration: (&ApplyManifestTool{}).Declaration()}
+	guards := k.guards()
+	for i := len(guards) - 1; i >= 0; i-- {
+		reapply = guards[i](info, reapply)
+	}
+
+	var remediated []RemediationResult
+	for _, dr := range results.Results {
+		if dr.Status != "drifted" && !(dr.Status == "missing" && policy.RecreateMissing) {
+			continue
+		}
+		if !policy.Covers(dr.Namespace, dr.Name) {
+			continue
+		}
+
+		rr := RemediationResult{
+			Time:      time.Now(),
+			Namespace: dr.Namespace,
+			Name:      dr.Name,
+			Kind:      dr.Kind,
+			Drift:     dr.Status,
+			DryRun:    dryRun,
+		}
+		for _, d := range dr.Diffs {
+			rr.Diffs = append(rr.Diffs, d.Path)
+		}
+
+		args := map[string]any{"namespace": dr.Namespace, "app": dr.Name, "type": dr.Kind, "dry_run": dryRun}
+		result, err := reapply(WithContext(nil, ctx), args)
+		if err != nil {
+			rr.Error = err.Error()
+		} else if reason := refused(result); reason != "" {
+			rr.Skipped = reason
+		} else if result["error"] != nil {
+			rr.Error = fmt.Sprint(result["error"])
+		} else {
+			rr.Action, _ = result["action"].(string)
+		}
+		remediated = append(remediated, rr)
+	}
+	return remediated
+}
+
+// refused returns why a safety check refused a re-apply, or "" if none did.
+func refused(result map[string]any) string {
+	if result["confirmation_required"] == true {
+		return fmt.Sprintf("dry-run is enforced for %s; the change was validated but not applied", describeNamespace(fmt.Sprint(result["namespace"])))
+	}
+	for _, flag := range []string{"namespace_denied", "protected", "window_closed", "policy_violations"} {
+		if _, ok := result[flag]; ok {
+			return fmt.Sprint(result["error"])
+		}
+	}
+	return ""
+}
+
+// reapplyManifest writes the stored manifest named by args back to the
+// cluster, creating the resource if it no longer exists. It is the RunFunc
+// remediate puts behind the safety checks.
+func (k *KubeTools) reapplyManifest(ctx tool.Context, args map[string]any) (map[string]any, error) {
+	namespace, _ := args["namespace"].(string)
+	app, _ := args["app"].(string)
+	resourceType, _ := args["type"].(string)
+	dryRun, _ := args["dry_run"].(bool)
+
+	content, err := k.manifest.ReadManifest(namespace, app, resourceType)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+	action, err := applyStoredManifest(toolContext(ctx), k.dynamicClient, k.resolver, namespace, content, dryRun, "kasa drift remediation: reapplied stored manifest")
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+	return map[string]any{"action": action}, nil
+}
+
+// applyStoredManifest creates or updates the resource described by a stored
+// manifest. namespace is only used for namespaced kinds that don't set one.
+func applyStoredManifest(ctx context.Context, dynClient dynamic.Interface, resolver *GVRResolver, namespace string, content []byte, dryRun bool, cause string) (string, error) {
+	obj, err := ParseYAMLToUnstructured(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse stored manifest: %w", err)
+	}
+	if isRolloutKind(obj.GetKind()) {
+		setChangeCause(ctx, obj, cause)
+	}
+
+	gvk := obj.GroupVersionKind()
+	gvr, err := resolver.ResolveGVK(gvk)
+	if err != nil {
+		return "", err
+	}
+
+	var resourceClient dynamic.ResourceInterface
+	if resolver.IsNamespaced(gvr, gvk.Kind) {
+		if namespace == manifest.ClusterNamespace {
+			return "", fmt.Errorf("%s is namespaced and can't be stored under %s", gvk.Kind, manifest.ClusterNamespace)
+		}
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+		resourceClient = dynClient.Resource(gvr).Namespace(obj.GetNamespace())
+	} else {
+		obj.SetNamespace("")
+		resourceClient = dynClient.Resource(gvr)
+	}
+
+	createOptions := metav1.CreateOptions{}
+	updateOptions := metav1.UpdateOptions{}
+	if dryRun {
+		createOptions.DryRun = []string{metav1.DryRunAll}
+		updateOptions.DryRun = []string{metav1.DryRunAll}
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	existing, err := resourceClient.Get(timeoutCtx, obj.GetName(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		if _, err := resourceClient.Create(timeoutCtx, obj, createOptions); err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", gvk.Kind, err)
+		}
+		return "created", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s: %w", gvk.Kind, err)
+	}
+
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	if _, err := resourceClient.Update(timeoutCtx, obj, updateOptions); err != nil {
+		return "", fmt.Errorf("failed to update %s: %w", gvk.Kind, err)
+	}
+	return "updated", nil
+}
@@ -0,0 +1,174 @@
+package tools
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/restmapper"
+)
+
+// resolverRefreshInterval limits how often a lookup miss re-reads discovery,
+// so repeated lookups of a kind that really doesn't exist stay cheap.
+const resolverRefreshInterval = 10 * time.Second
+
+// GVRResolver maps kinds, plural names and short names to the resources the
+// cluster actually serves, using a RESTMapper built from the discovery API.
+// Discovery results are cached and refreshed when a lookup misses, so CRDs
+// installed after startup are found without a restart.
+//
+// A nil *GVRResolver falls back to the static CommonGVRs table.
+type GVRResolver struct {
+	discovery discovery.CachedDiscoveryInterface
+	mapper    *restmapper.DeferredDiscoveryRESTMapper
+	expander  meta.RESTMapper
+
+	mu          sync.Mutex
+	lastRefresh time.Time
+}
+
+// NewGVRResolver creates a GVRResolver backed by the given discovery client.
+// Returns nil if disc is nil.
+func NewGVRResolver(disc discovery.DiscoveryInterface) *GVRResolver {
+	if disc == nil {
+		return nil
+	}
+	cached := memory.NewMemCacheClient(disc)
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(cached)
+	return &GVRResolver{
+		discovery: cached,
+		mapper:    mapper,
+		expander:  restmapper.NewShortcutExpander(mapper, cached, nil),
+	}
+}
+
+// Resolve returns the GroupVersionResource for a kind, plural or short name
+// (e.g. "HTTPRoute", "httproutes", "deploy"). apiVersion is optional and
+// narrows the lookup to one group/version.
+func (r *GVRResolver) Resolve(kind, apiVersion string) (schema.GroupVersionResource, error) {
+	if r == nil {
+		return staticGVR(kind, apiVersion)
+	}
+
+	input := schema.GroupVersionResource{Resource: strings.ToLower(kind)}
+	if apiVersion != "" {
+		input.Group, input.Version = ParseAPIVersion(apiVersion)
+	}
+
+	gvr, err := r.resourceFor(input)
+	if err != nil && meta.IsNoMatchError(err) {
+		// Try the canonical name for client-side aliases the server doesn't define
+		if alias := NormalizeKindName(kind); alias != input.Resource {
+			input.Resource = alias
+			gvr, err = r.resourceFor(input)
+		}
+	}
+	if err == nil {
+		return gvr, nil
+	}
+	if meta.IsNoMatchError(err) {
+		if apiVersion != "" {
+			return schema.GroupVersionResource{}, fmt.Errorf("resource kind '%s' is not served by %s in this cluster", kind, apiVersion)
+		}
+		return schema.GroupVersionResource{}, fmt.Errorf("resource kind '%s' not found in the cluster", kind)
+	}
+
+	// Discovery itself failed (e.g. API server unreachable); use the static table
+	return staticGVR(kind, apiVersion)
+}
+
+// ResolveGVK returns the GroupVersionResource for a fully qualified kind, as
+// found in a manifest's apiVersion and kind fields.
+func (r *GVRResolver) ResolveGVK(gvk schema.GroupVersionKind) (schema.GroupVersionResource, error) {
+	if r == nil {
+		return GVKToGVR(gvk), nil
+	}
+
+	mapping, err := r.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil && meta.IsNoMatchError(err) && r.refresh() {
+		mapping, err = r.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err == nil {
+		return mapping.Resource, nil
+	}
+	if meta.IsNoMatchError(err) {
+		return schema.GroupVersionResource{}, fmt.Errorf("kind %s is not served by %s in this cluster (is the CRD installed?)", gvk.Kind, gvk.GroupVersion())
+	}
+	return GVKToGVR(gvk), nil
+}
+
+// IsNamespaced reports whether gvr is a namespaced resource, using the
+// scope advertised by discovery. kind is used with the static
+// ClusterScopedKinds table if discovery has no answer.
+func (r *GVRResolver) IsNamespaced(gvr schema.GroupVersionResource, kind string) bool {
+	if r == nil {
+		return IsNamespaced(kind)
+	}
+	if gvk, err := r.mapper.KindFor(gvr); err == nil {
+		if mapping, err := r.mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err == nil {
+			return mapping.Scope.Name() == meta.RESTScopeNameNamespace
+		}
+	}
+	return IsNamespaced(kind)
+}
+
+// Kinds returns the kinds the cluster serves, sorted, or the (lowercase)
+// kinds of the static CommonGVRs table when there is no resolver. Discovery errors for
+// some groups are ignored.
+func (r *GVRResolver) Kinds() []string {
+	seen := make(map[string]bool)
+	if r == nil {
+		for kind := range CommonGVRs {
+			seen[kind] = true
+		}
+	} else {
+		lists, _ := r.discovery.ServerPreferredResources()
+		for _, list := range lists {
+			for _, res := range list.APIResources {
+				if !strings.Contains(res.Name, "/") {
+					seen[res.Kind] = true
+				}
+			}
+		}
+	}
+	delete(seen, "")
+	return slices.Sorted(maps.Keys(seen))
+}
+
+// resourceFor looks up a partial resource, refreshing discovery once on a miss.
+func (r *GVRResolver) resourceFor(input schema.GroupVersionResource) (schema.GroupVersionResource, error) {
+	gvr, err := r.expander.ResourceFor(input)
+	if err != nil && meta.IsNoMatchError(err) && r.refresh() {
+		gvr, err = r.expander.ResourceFor(input)
+	}
+	return gvr, err
+}
+
+// refresh invalidates the cached discovery data, at most once per
+// resolverRefreshInterval. Returns true if the cache was reset.
+func (r *GVRResolver) refresh() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.lastRefresh) < resolverRefreshInterval {
+		return false
+	}
+	r.lastRefresh = time.Now()
+	r.mapper.Reset()
+	return true
+}
+
+// staticGVR resolves a kind from the static CommonGVRs table.
+func staticGVR(kind, apiVersion string) (schema.GroupVersionResource, error) {
+	gvr, found := BuildGVRFromKindAndAPIVersion(kind, apiVersion)
+	if !found {
+		return schema.GroupVersionResource{}, fmt.Errorf("unknown resource kind '%s'. Provide api_version for custom resources", kind)
+	}
+	return gvr, nil
+}
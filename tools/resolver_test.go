@@ -0,0 +1,141 @@
+package tools
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newFakeResolver() (*GVRResolver, *fakediscovery.FakeDiscovery) {
+	disc := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	disc.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", SingularName: "deployment", Kind: "Deployment", Namespaced: true, ShortNames: []string{"deploy"}},
+			},
+		},
+		{
+			GroupVersion: "example.com/v1alpha1",
+			APIResources: []metav1.APIResource{
+				{Name: "widgets", SingularName: "widget", Kind: "Widget", Namespaced: true, ShortNames: []string{"wdg"}},
+			},
+		},
+		{
+			GroupVersion: "external-secrets.io/v1beta1",
+			APIResources: []metav1.APIResource{
+				{Name: "clustersecretstores", SingularName: "clustersecretstore", Kind: "ClusterSecretStore", Namespaced: false},
+			},
+		},
+	}
+	return NewGVRResolver(disc), disc
+}
+
+func TestGVRResolverResolve(t *testing.T) {
+	r, _ := newFakeResolver()
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1alpha1", Resource: "widgets"}
+
+	tests := []struct {
+		kind, apiVersion string
+		want             schema.GroupVersionResource
+	}{
+		{"Deployment", "", schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}},
+		{"deploy", "", schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}},
+		{"Widget", "", widgets},
+		{"widgets", "", widgets},
+		{"wdg", "", widgets},
+		{"Widget", "example.com/v1alpha1", widgets},
+	}
+	for _, tt := range tests {
+		got, err := r.Resolve(tt.kind, tt.apiVersion)
+		if err != nil {
+			t.Errorf("Resolve(%q, %q) error: %v", tt.kind, tt.apiVersion, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%q, %q) = %v, want %v", tt.kind, tt.apiVersion, got, tt.want)
+		}
+	}
+
+	if _, err := r.Resolve("Gadget", ""); err == nil {
+		t.Error("expected error for kind not served by the cluster")
+	}
+}
+
+func TestGVRResolverRefreshOnMiss(t *testing.T) {
+	r, disc := newFakeResolver()
+	if _, err := r.Resolve("Widget", ""); err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+
+	// Install a new CRD after the cache is populated
+	disc.Resources = append(disc.Resources, &metav1.APIResourceList{
+		GroupVersion: "example.com/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "gizmos", SingularName: "gizmo", Kind: "Gizmo", Namespaced: true},
+		},
+	})
+
+	got, err := r.Resolve("Gizmo", "")
+	if err != nil {
+		t.Fatalf("Resolve after CRD install: %v", err)
+	}
+	if got.Resource != "gizmos" || got.Group != "example.com" {
+		t.Errorf("got %v, want example.com/v1 gizmos", got)
+	}
+}
+
+func TestGVRResolverResolveGVK(t *testing.T) {
+	r, _ := newFakeResolver()
+	got, err := r.ResolveGVK(schema.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Widget"})
+	if err != nil {
+		t.Fatalf("ResolveGVK: %v", err)
+	}
+	if got.Resource != "widgets" {
+		t.Errorf("got resource %q, want widgets", got.Resource)
+	}
+
+	if _, err := r.ResolveGVK(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Missing"}); err == nil {
+		t.Error("expected error for kind not served by the cluster")
+	}
+}
+
+func TestGVRResolverNilFallsBackToStaticTable(t *testing.T) {
+	var r *GVRResolver
+	got, err := r.Resolve("deployment", "")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if got.Resource != "deployments" || got.Group != "apps" {
+		t.Errorf("got %v, want apps/v1 deployments", got)
+	}
+}
+
+func TestGVRResolverIsNamespaced(t *testing.T) {
+	r, _ := newFakeResolver()
+
+	store, err := r.Resolve("ClusterSecretStore", "")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if r.IsNamespaced(store, "ClusterSecretStore") {
+		t.Error("ClusterSecretStore should be cluster-scoped")
+	}
+
+	widgets, err := r.Resolve("Widget", "")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if !r.IsNamespaced(widgets, "Widget") {
+		t.Error("Widget should be namespaced")
+	}
+
+	// Unknown to discovery: falls back to the static table
+	ns := schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	if r.IsNamespaced(ns, "Namespace") {
+		t.Error("Namespace should be cluster-scoped")
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
-	"time"
 
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/tool"
@@ -19,16 +18,24 @@ import (
 type GetResourceTool struct {
 	clientset     *kubernetes.Clientset
 	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+	cache         *ResourceCache // nil reads from the API server
 }
 
 // NewGetResourceTool creates a new GetResourceTool.
-func NewGetResourceTool(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface) *GetResourceTool {
+func NewGetResourceTool(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, resolver *GVRResolver) *GetResourceTool {
 	return &GetResourceTool{
 		clientset:     clientset,
 		dynamicClient: dynamicClient,
+		resolver:      resolver,
 	}
 }
 
+// useCache makes the tool read through the resource cache.
+func (t *GetResourceTool) useCache(c *ResourceCache) {
+	t.cache = c
+}
+
 // Name returns the tool name.
 func (t *GetResourceTool) Name() string {
 	return "get_resource"
@@ -124,7 +131,7 @@ func (t *GetResourceTool) Run(ctx tool.Context, args any) (map[string]any, error
 		apiVersion = av
 	}
 
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
 	defer cancel()
 
 	var resource any
@@ -158,7 +165,14 @@ func (t *GetResourceTool) Run(ctx tool.Context, args any) (map[string]any, error
 		return map[string]any{"error": err.Error()}, nil
 	}
 
-	return map[string]any{"resource": resource}, nil
+	result := map[string]any{"resource": resource}
+	if obj, ok := resource.(map[string]any); ok {
+		if m := gitOpsManagerOfObject(obj); m != nil {
+			result["managed_by_gitops"] = m
+			result["gitops_warning"] = m.Advice()
+		}
+	}
+	return result, nil
 }
 
 // toMap converts a struct to a map via JSON marshal/unmarshal.
@@ -175,7 +189,7 @@ func toMap(obj any) (map[string]any, error) {
 }
 
 func (t *GetResourceTool) getDeployment(ctx context.Context, namespace, name string) (map[string]any, error) {
-	deployment, err := t.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	deployment, err := t.cache.getDeployment(ctx, t.clientset, namespace, name)
 	if err != nil {
 		return nil, err
 	}
@@ -192,7 +206,7 @@ func (t *GetResourceTool) getDeployment(ctx context.Context, namespace, name str
 }
 
 func (t *GetResourceTool) getService(ctx context.Context, namespace, name string) (map[string]any, error) {
-	service, err := t.clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	service, err := t.cache.getService(ctx, t.clientset, namespace, name)
 	if err != nil {
 		return nil, err
 	}
@@ -208,7 +222,7 @@ func (t *GetResourceTool) getService(ctx context.Context, namespace, name string
 }
 
 func (t *GetResourceTool) getPod(ctx context.Context, namespace, name string) (map[string]any, error) {
-	pod, err := t.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	pod, err := t.cache.getPod(ctx, t.clientset, namespace, name)
 	if err != nil {
 		return nil, err
 	}
@@ -292,14 +306,14 @@ func cleanMetadata(result map[string]any) {
 
 // getDynamicResource fetches any resource type using the dynamic client.
 func (t *GetResourceTool) getDynamicResource(ctx context.Context, namespace, name, kind, apiVersion string) (map[string]any, error) {
-	// Build GVR from kind and api_version
-	gvr, found := BuildGVRFromKindAndAPIVersion(kind, apiVersion)
-	if !found && apiVersion == "" {
-		return nil, fmt.Errorf("unknown resource kind '%s'. Provide api_version for custom resources", kind)
+	// Resolve GVR via discovery
+	gvr, err := t.resolver.Resolve(kind, apiVersion)
+	if err != nil {
+		return nil, err
 	}
 
 	// Check if resource is namespaced
-	namespaced := IsNamespaced(kind)
+	namespaced := t.resolver.IsNamespaced(gvr, kind)
 
 	// Get the resource interface
 	var resourceClient dynamic.ResourceInterface
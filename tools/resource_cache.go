@@ -0,0 +1,427 @@
+package tools
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/adk/tool"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	appsinformers "k8s.io/client-go/informers/apps/v1"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+// DefaultCacheTTL is how long an informer is trusted before it is restarted
+// with a fresh list.
+const DefaultCacheTTL = 5 * time.Minute
+
+// cacheSyncTimeout bounds the initial list of an informer. Reads fall back
+// to the API server while an informer is not synced.
+const cacheSyncTimeout = 20 * time.Second
+
+// cachedKind describes a resource kind the ResourceCache can serve.
+type cachedKind struct {
+	resource    schema.GroupResource
+	newInformer func(kubernetes.Interface) toolscache.SharedIndexInformer
+	// fields returns the field selector values of an object; selectors on
+	// other fields are sent to the API server.
+	fields func(obj any) fields.Set
+}
+
+var namespaceIndexers = toolscache.Indexers{toolscache.NamespaceIndex: toolscache.MetaNamespaceIndexFunc}
+
+var cachedKinds = map[string]cachedKind{
+	"pods": {
+		resource: schema.GroupResource{Resource: "pods"},
+		newInformer: func(cs kubernetes.Interface) toolscache.SharedIndexInformer {
+			return coreinformers.NewPodInformer(cs, metav1.NamespaceAll, 0, namespaceIndexers)
+		},
+		fields: metadataFields,
+	},
+	"deployments": {
+		resource: schema.GroupResource{Group: "apps", Resource: "deployments"},
+		newInformer: func(cs kubernetes.Interface) toolscache.SharedIndexInformer {
+			return appsinformers.NewDeploymentInformer(cs, metav1.NamespaceAll, 0, namespaceIndexers)
+		},
+		fields: metadataFields,
+	},
+	"services": {
+		resource: schema.GroupResource{Resource: "services"},
+		newInformer: func(cs kubernetes.Interface) toolscache.SharedIndexInformer {
+			return coreinformers.NewServiceInformer(cs, metav1.NamespaceAll, 0, namespaceIndexers)
+		},
+		fields: metadataFields,
+	},
+	"events": {
+		resource: schema.GroupResource{Resource: "events"},
+		newInformer: func(cs kubernetes.Interface) toolscache.SharedIndexInformer {
+			return coreinformers.NewEventInformer(cs, metav1.NamespaceAll, 0, namespaceIndexers)
+		},
+		fields: eventFields,
+	},
+}
+
+// ResourceCache serves reads of pods, deployments, services and events
+// from shared informers, so repeated list and get calls in a session don't
+// each go to the API server. An informer is started the first time its
+// kind is read and restarted with a fresh list once it is older than the
+// TTL. Reads fall back to the API server while an informer is syncing or
+// when it can't be started, e.g. because RBAC forbids cluster-wide watches.
+// A nil *ResourceCache sends every read to the API server.
+type ResourceCache struct {
+	clientset kubernetes.Interface
+	ttl       time.Duration
+
+	mu        sync.Mutex
+	informers map[string]*kindInformer
+}
+
+// kindInformer is a running informer for one kind.
+type kindInformer struct {
+	informer toolscache.SharedIndexInformer
+	stop     chan struct{}
+	stopOnce sync.Once
+	started  time.Time
+	ready    chan struct{} // closed once synced is set
+	synced   bool          // false if the initial list failed or timed out
+}
+
+func (e *kindInformer) shutdown() {
+	e.stopOnce.Do(func() { close(e.stop) })
+}
+
+// NewResourceCache returns a cache for the cluster behind clientset. A ttl
+// of 0 uses DefaultCacheTTL. Call Stop to shut down its informers.
+func NewResourceCache(clientset kubernetes.Interface, ttl time.Duration) *ResourceCache {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &ResourceCache{
+		clientset: clientset,
+		ttl:       ttl,
+		informers: make(map[string]*kindInformer),
+	}
+}
+
+// SetResourceCache makes the read-only diagnostic tools read through c.
+// Mutating tools always talk to the API server.
+func (k *KubeTools) SetResourceCache(c *ResourceCache) {
+	k.cache = c
+}
+
+// cachedReader is implemented by tools that can read through the cache.
+type cachedReader interface {
+	useCache(c *ResourceCache)
+}
+
+// useCache hands the resource cache to the tools that can use it.
+func (k *KubeTools) useCache(all []tool.Tool) []tool.Tool {
+	if k.cache == nil {
+		return all
+	}
+	for _, t := range all {
+		if r, ok := t.(cachedReader); ok {
+			r.useCache(k.cache)
+		}
+	}
+	return all
+}
+
+// Stop shuts down all informers.
+func (c *ResourceCache) Stop() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for kind, e := range c.informers {
+		e.shutdown()
+		delete(c.informers, kind)
+	}
+}
+
+// store returns the synced store for kind, starting or restarting its
+// informer as needed, and false if reads should go to the API server.
+func (c *ResourceCache) store(ctx context.Context, kind string) (toolscache.Indexer, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	e := c.informers[kind]
+	if e != nil && time.Since(e.started) > c.ttl {
+		e.shutdown()
+		e = nil
+	}
+	if e == nil {
+		e = c.start(kind)
+		c.informers[kind] = e
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-e.ready:
+	case <-ctx.Done():
+		return nil, false
+	}
+	if !e.synced {
+		// Keep the failed informer until the TTL passes so every read
+		// doesn't retry the initial list
+		return nil, false
+	}
+	return e.informer.GetIndexer(), true
+}
+
+// start starts an informer for kind and waits for its initial list in the
+// background. The caller holds c.mu.
+func (c *ResourceCache) start(kind string) *kindInformer {
+	e := &kindInformer{
+		informer: cachedKinds[kind].newInformer(c.clientset),
+		stop:     make(chan struct{}),
+		started:  time.Now(),
+		ready:    make(chan struct{}),
+	}
+
+	abort := make(chan struct{})
+	var abortOnce sync.Once
+	giveUp := func() { abortOnce.Do(func() { close(abort) }) }
+
+	// Managed fields are large and never shown to the agent
+	_ = e.informer.SetTransform(func(obj any) (any, error) {
+		if m, ok := obj.(metav1.Object); ok {
+			m.SetManagedFields(nil)
+		}
+		return obj, nil
+	})
+	// Replace the default handler, which logs every failed list and watch to
+	// stderr. Failures before the first sync mean the cache can't be used;
+	// later ones are retried by the informer.
+	_ = e.informer.SetWatchErrorHandler(func(_ *toolscache.Reflector, err error) {
+		if !e.informer.HasSynced() {
+			giveUp()
+		}
+	})
+
+	go e.informer.Run(e.stop)
+	go func() {
+		timer := time.AfterFunc(cacheSyncTimeout, giveUp)
+		defer timer.Stop()
+		e.synced = toolscache.WaitForCacheSync(abort, e.informer.HasSynced)
+		if !e.synced {
+			e.shutdown()
+		}
+		close(e.ready)
+	}()
+	return e
+}
+
+// list returns the cached objects of kind in namespace ("" for all) that
+// match opts, sorted by namespace and name, and one page of them if
+// opts.Limit is set. It returns false if the API server should be asked
+// instead: the cache isn't available, the field selector uses fields the
+// cache doesn't know, or the continue token came from the API server.
+func (c *ResourceCache) list(ctx context.Context, kind, namespace string, opts metav1.ListOptions) ([]any, metav1.ListMeta, bool, error) {
+	var meta metav1.ListMeta
+	if c == nil {
+		return nil, meta, false, nil
+	}
+	if opts.Continue != "" {
+		if _, err := strconv.Atoi(opts.Continue); err != nil {
+			return nil, meta, false, nil
+		}
+	}
+	labelSel, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, meta, false, nil
+	}
+	fieldSel, err := fields.ParseSelector(opts.FieldSelector)
+	if err != nil {
+		return nil, meta, false, nil
+	}
+	known := cachedKinds[kind].fields
+	for _, r := range fieldSel.Requirements() {
+		if _, ok := known(nil)[r.Field]; !ok {
+			return nil, meta, false, nil
+		}
+	}
+
+	store, ok := c.store(ctx, kind)
+	if !ok {
+		return nil, meta, false, nil
+	}
+	var objs []any
+	if namespace == "" {
+		objs = store.List()
+	} else if objs, err = store.ByIndex(toolscache.NamespaceIndex, namespace); err != nil {
+		return nil, meta, false, nil
+	}
+
+	matched := make([]any, 0, len(objs))
+	for _, obj := range objs {
+		m, ok := obj.(metav1.Object)
+		if !ok {
+			continue
+		}
+		if labelSel.Matches(labels.Set(m.GetLabels())) && fieldSel.Matches(known(obj)) {
+			matched = append(matched, obj)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i].(metav1.Object), matched[j].(metav1.Object)
+		if a.GetNamespace() != b.GetNamespace() {
+			return a.GetNamespace() < b.GetNamespace()
+		}
+		return a.GetName() < b.GetName()
+	})
+
+	if opts.Limit > 0 {
+		page, next, remaining, err := paginate(matched, opts.Continue, int(opts.Limit))
+		if err != nil {
+			return nil, meta, true, apierrors.NewBadRequest(err.Error())
+		}
+		matched, meta.Continue = page, next
+		if next != "" {
+			count := int64(remaining)
+			meta.RemainingItemCount = &count
+		}
+	}
+	return matched, meta, true, nil
+}
+
+// get returns the cached object of kind with the given namespace and name,
+// and false if the API server should be asked instead.
+func (c *ResourceCache) get(ctx context.Context, kind, namespace, name string) (any, bool, error) {
+	store, ok := c.store(ctx, kind)
+	if !ok {
+		return nil, false, nil
+	}
+	obj, exists, err := store.GetByKey(namespace + "/" + name)
+	if err != nil {
+		return nil, false, nil
+	}
+	if !exists {
+		return nil, true, apierrors.NewNotFound(cachedKinds[kind].resource, name)
+	}
+	return obj, true, nil
+}
+
+// listPods lists pods from the cache, or from the API server through cs.
+func (c *ResourceCache) listPods(ctx context.Context, cs kubernetes.Interface, namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	objs, meta, ok, err := c.list(ctx, "pods", namespace, opts)
+	if !ok {
+		return cs.CoreV1().Pods(namespace).List(ctx, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := &corev1.PodList{ListMeta: meta, Items: make([]corev1.Pod, 0, len(objs))}
+	for _, obj := range objs {
+		list.Items = append(list.Items, *obj.(*corev1.Pod))
+	}
+	return list, nil
+}
+
+// getPod gets a pod from the cache, or from the API server through cs.
+func (c *ResourceCache) getPod(ctx context.Context, cs kubernetes.Interface, namespace, name string) (*corev1.Pod, error) {
+	obj, ok, err := c.get(ctx, "pods", namespace, name)
+	if !ok {
+		return cs.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*corev1.Pod).DeepCopy(), nil
+}
+
+// listDeployments lists deployments from the cache, or from the API server through cs.
+func (c *ResourceCache) listDeployments(ctx context.Context, cs kubernetes.Interface, namespace string, opts metav1.ListOptions) (*appsv1.DeploymentList, error) {
+	objs, meta, ok, err := c.list(ctx, "deployments", namespace, opts)
+	if !ok {
+		return cs.AppsV1().Deployments(namespace).List(ctx, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := &appsv1.DeploymentList{ListMeta: meta, Items: make([]appsv1.Deployment, 0, len(objs))}
+	for _, obj := range objs {
+		list.Items = append(list.Items, *obj.(*appsv1.Deployment))
+	}
+	return list, nil
+}
+
+// getDeployment gets a deployment from the cache, or from the API server through cs.
+func (c *ResourceCache) getDeployment(ctx context.Context, cs kubernetes.Interface, namespace, name string) (*appsv1.Deployment, error) {
+	obj, ok, err := c.get(ctx, "deployments", namespace, name)
+	if !ok {
+		return cs.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*appsv1.Deployment).DeepCopy(), nil
+}
+
+// getService gets a service from the cache, or from the API server through cs.
+func (c *ResourceCache) getService(ctx context.Context, cs kubernetes.Interface, namespace, name string) (*corev1.Service, error) {
+	obj, ok, err := c.get(ctx, "services", namespace, name)
+	if !ok {
+		return cs.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*corev1.Service).DeepCopy(), nil
+}
+
+// listEvents lists events from the cache, or from the API server through cs.
+func (c *ResourceCache) listEvents(ctx context.Context, cs kubernetes.Interface, namespace string, opts metav1.ListOptions) (*corev1.EventList, error) {
+	objs, meta, ok, err := c.list(ctx, "events", namespace, opts)
+	if !ok {
+		return cs.CoreV1().Events(namespace).List(ctx, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := &corev1.EventList{ListMeta: meta, Items: make([]corev1.Event, 0, len(objs))}
+	for _, obj := range objs {
+		list.Items = append(list.Items, *obj.(*corev1.Event))
+	}
+	return list, nil
+}
+
+// metadataFields returns the field selector values every kind supports.
+// obj may be nil to get the supported field names.
+func metadataFields(obj any) fields.Set {
+	m, _ := obj.(metav1.Object)
+	if m == nil {
+		return fields.Set{"metadata.name": "", "metadata.namespace": ""}
+	}
+	return fields.Set{"metadata.name": m.GetName(), "metadata.namespace": m.GetNamespace()}
+}
+
+// eventFields returns the field selector values of an event, as supported
+// by the API server for the fields kasa filters on.
+func eventFields(obj any) fields.Set {
+	e, _ := obj.(*corev1.Event)
+	if e == nil {
+		e = &corev1.Event{}
+	}
+	return fields.Set{
+		"metadata.name":            e.Name,
+		"metadata.namespace":       e.Namespace,
+		"involvedObject.kind":      e.InvolvedObject.Kind,
+		"involvedObject.name":      e.InvolvedObject.Name,
+		"involvedObject.namespace": e.InvolvedObject.Namespace,
+		"reason":                   e.Reason,
+		"type":                     e.Type,
+	}
+}
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func cacheTestPod(namespace, name, app string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:          name,
+		Namespace:     namespace,
+		Labels:        map[string]string{"app": app},
+		ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+	}}
+}
+
+// countLists returns how many list calls for resource reached the fake API server.
+func countLists(cs *fake.Clientset, resource string) int {
+	n := 0
+	for _, a := range cs.Actions() {
+		if a.GetVerb() == "list" && a.GetResource().Resource == resource {
+			n++
+		}
+	}
+	return n
+}
+
+func TestResourceCachePods(t *testing.T) {
+	cs := fake.NewSimpleClientset(
+		cacheTestPod("web", "web-b", "web"),
+		cacheTestPod("web", "web-a", "web"),
+		cacheTestPod("web", "worker", "worker"),
+		cacheTestPod("db", "db-0", "db"),
+	)
+	c := NewResourceCache(cs, time.Minute)
+	defer c.Stop()
+	ctx := context.Background()
+
+	pods, err := c.listPods(ctx, cs, "web", metav1.ListOptions{LabelSelector: "app=web"})
+	if err != nil {
+		t.Fatalf("listPods: %v", err)
+	}
+	if len(pods.Items) != 2 || pods.Items[0].Name != "web-a" || pods.Items[1].Name != "web-b" {
+		t.Fatalf("want web-a, web-b sorted by name, got %v", pods.Items)
+	}
+	if len(pods.Items[0].ManagedFields) != 0 {
+		t.Error("managed fields should be dropped from cached objects")
+	}
+
+	// Served from the informer: no further list calls reach the API server
+	before := countLists(cs, "pods")
+	page, err := c.listPods(ctx, cs, "", metav1.ListOptions{Limit: 3})
+	if err != nil {
+		t.Fatalf("listPods page 1: %v", err)
+	}
+	if len(page.Items) != 3 || page.Continue != "3" || page.RemainingItemCount == nil || *page.RemainingItemCount != 1 {
+		t.Fatalf("want 3 pods, continue 3, 1 remaining, got %d, %q, %v", len(page.Items), page.Continue, page.RemainingItemCount)
+	}
+	page, err = c.listPods(ctx, cs, "", metav1.ListOptions{Limit: 3, Continue: page.Continue})
+	if err != nil {
+		t.Fatalf("listPods page 2: %v", err)
+	}
+	if len(page.Items) != 1 || page.Continue != "" {
+		t.Fatalf("want the last pod and no continue token, got %d, %q", len(page.Items), page.Continue)
+	}
+	if _, err := c.getPod(ctx, cs, "db", "db-0"); err != nil {
+		t.Fatalf("getPod: %v", err)
+	}
+	if n := countLists(cs, "pods"); n != before {
+		t.Errorf("cached reads listed pods %d more times", n-before)
+	}
+
+	if _, err := c.getPod(ctx, cs, "db", "missing"); !apierrors.IsNotFound(err) {
+		t.Errorf("want NotFound for a missing pod, got %v", err)
+	}
+
+	// Field selectors the cache doesn't know go to the API server
+	if _, err := c.listPods(ctx, cs, "web", metav1.ListOptions{FieldSelector: "spec.nodeName=node-1"}); err != nil {
+		t.Fatalf("listPods with field selector: %v", err)
+	}
+	if n := countLists(cs, "pods"); n != before+1 {
+		t.Errorf("want one API list for an unsupported field selector, got %d", n-before)
+	}
+}
+
+func TestResourceCacheEvents(t *testing.T) {
+	event := func(name, object, eventType string) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "web"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: object, Namespace: "web"},
+			Type:           eventType,
+		}
+	}
+	cs := fake.NewSimpleClientset(
+		event("e1", "web-a", corev1.EventTypeWarning),
+		event("e2", "web-a", corev1.EventTypeNormal),
+		event("e3", "web-b", corev1.EventTypeWarning),
+	)
+	c := NewResourceCache(cs, time.Minute)
+	defer c.Stop()
+
+	events, err := c.listEvents(context.Background(), cs, "web", metav1.ListOptions{
+		FieldSelector: "involvedObject.name=web-a,type=Warning",
+	})
+	if err != nil {
+		t.Fatalf("listEvents: %v", err)
+	}
+	if len(events.Items) != 1 || events.Items[0].Name != "e1" {
+		t.Errorf("want only e1, got %v", events.Items)
+	}
+}
+
+func TestNilResourceCacheUsesAPI(t *testing.T) {
+	cs := fake.NewSimpleClientset(cacheTestPod("web", "web-a", "web"))
+	var c *ResourceCache
+
+	pods, err := c.listPods(context.Background(), cs, "web", metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("listPods: %v", err)
+	}
+	if len(pods.Items) != 1 || countLists(cs, "pods") != 1 {
+		t.Errorf("want one pod listed through the API, got %d pods and %d lists", len(pods.Items), countLists(cs, "pods"))
+	}
+}
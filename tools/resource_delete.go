@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/perbu/kasa/manifest"
 	"google.golang.org/adk/model"
@@ -19,14 +18,16 @@ import (
 type DeleteResourceTool struct {
 	clientset     *kubernetes.Clientset
 	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
 	manifest      *manifest.Manager
 }
 
 // NewDeleteResourceTool creates a new DeleteResourceTool.
-func NewDeleteResourceTool(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, manifest *manifest.Manager) *DeleteResourceTool {
+func NewDeleteResourceTool(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, resolver *GVRResolver, manifest *manifest.Manager) *DeleteResourceTool {
 	return &DeleteResourceTool{
 		clientset:     clientset,
 		dynamicClient: dynamicClient,
+		resolver:      resolver,
 		manifest:      manifest,
 	}
 }
@@ -74,7 +75,7 @@ func (t *DeleteResourceTool) Declaration() *genai.FunctionDeclaration {
 				},
 				"namespace": {
 					Type:        "string",
-					Description: "The Kubernetes namespace",
+					Description: "The Kubernetes namespace. Omit for cluster-scoped resources",
 				},
 				"api_version": {
 					Type:        "string",
@@ -85,7 +86,7 @@ func (t *DeleteResourceTool) Declaration() *genai.FunctionDeclaration {
 					Description: "Also delete the stored manifest if one exists (default: true)",
 				},
 			},
-			Required: []string{"type", "name", "namespace"},
+			Required: []string{"type", "name"},
 		},
 	}
 }
@@ -115,10 +116,7 @@ func (t *DeleteResourceTool) Run(ctx tool.Context, args any) (map[string]any, er
 		return map[string]any{"error": "name is required"}, nil
 	}
 
-	namespace, ok := argsMap["namespace"].(string)
-	if !ok || namespace == "" {
-		return map[string]any{"error": "namespace is required"}, nil
-	}
+	namespace, _ := argsMap["namespace"].(string)
 
 	apiVersion := ""
 	if av, ok := argsMap["api_version"].(string); ok {
@@ -133,22 +131,28 @@ func (t *DeleteResourceTool) Run(ctx tool.Context, args any) (map[string]any, er
 	// Normalize resource type - first check if it's a known core type
 	normalizedType := normalizeResourceType(resourceType)
 	useDynamic := false
+	namespaced := true
 
 	if normalizedType == "" {
-		// Check if it's a known CRD kind from our GVR table
-		normalized := NormalizeKindName(resourceType)
-		if _, found := LookupGVR(normalized); found || apiVersion != "" {
-			normalizedType = normalized
-			useDynamic = true
-		} else {
-			return map[string]any{
-				"error": fmt.Sprintf("unsupported resource type: %s. Provide api_version for custom resources.", resourceType),
-			}, nil
+		// Check that the cluster serves this kind
+		gvr, err := t.resolver.Resolve(resourceType, apiVersion)
+		if err != nil {
+			return map[string]any{"error": err.Error()}, nil
 		}
+		normalizedType = NormalizeKindName(resourceType)
+		useDynamic = true
+		namespaced = t.resolver.IsNamespaced(gvr, resourceType)
+	}
+
+	// Manifests for cluster-scoped resources live under the _cluster pseudo-namespace
+	if !namespaced {
+		namespace = manifest.ClusterNamespace
+	} else if namespace == "" || namespace == manifest.ClusterNamespace {
+		return map[string]any{"error": fmt.Sprintf("namespace is required: %s is namespaced", resourceType)}, nil
 	}
 
 	// Delete from cluster
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
 	defer cancel()
 
 	var err error
@@ -171,6 +175,9 @@ func (t *DeleteResourceTool) Run(ctx tool.Context, args any) (map[string]any, er
 		"namespace": namespace,
 		"message":   fmt.Sprintf("Deleted %s/%s from namespace %s", normalizedType, name, namespace),
 	}
+	if !namespaced {
+		result["message"] = fmt.Sprintf("Deleted cluster-scoped %s/%s", normalizedType, name)
+	}
 
 	// Delete manifest if requested and it exists
 	if deleteManifest && normalizedType != "pod" {
@@ -238,14 +245,14 @@ func (t *DeleteResourceTool) deleteDynamicResource(ctx context.Context, namespac
 		return fmt.Errorf("dynamic client not available")
 	}
 
-	// Build GVR from kind and api_version
-	gvr, found := BuildGVRFromKindAndAPIVersion(kind, apiVersion)
-	if !found && apiVersion == "" {
-		return fmt.Errorf("unknown resource kind '%s'. Provide api_version for custom resources", kind)
+	// Resolve GVR via discovery
+	gvr, err := t.resolver.Resolve(kind, apiVersion)
+	if err != nil {
+		return err
 	}
 
 	// Check if resource is namespaced
-	namespaced := IsNamespaced(kind)
+	namespaced := t.resolver.IsNamespaced(gvr, kind)
 
 	deletePolicy := metav1.DeletePropagationForeground
 	deleteOptions := metav1.DeleteOptions{
@@ -0,0 +1,265 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+// findResourceKinds are the kinds find_resource searches by default: the
+// things users name, not the pods and replicasets generated from them.
+var findResourceKinds = []string{
+	"namespace", "deployment", "statefulset", "daemonset", "cronjob", "job",
+	"service", "ingress", "httproute", "configmap", "secret",
+	"persistentvolumeclaim", "serviceaccount",
+}
+
+// findResourceDefaultLimit caps the number of matches returned.
+const findResourceDefaultLimit = 50
+
+// FindResourceTool provides the find_resource tool for the agent.
+type FindResourceTool struct {
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+}
+
+// NewFindResourceTool creates a new FindResourceTool.
+func NewFindResourceTool(dynamicClient dynamic.Interface, resolver *GVRResolver) *FindResourceTool {
+	return &FindResourceTool{
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+	}
+}
+
+// Name returns the tool name.
+func (t *FindResourceTool) Name() string {
+	return "find_resource"
+}
+
+// Description returns the tool description.
+func (t *FindResourceTool) Description() string {
+	return fmt.Sprintf("Find resources whose name, or optionally a label, contains a text fragment, across all namespaces and several kinds at once. Use it to answer 'where is the thing called payments?' without listing every namespace. Searches %s by default.", strings.Join(findResourceKinds, ", "))
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *FindResourceTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *FindResourceTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *FindResourceTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *FindResourceTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"query": {
+					Type:        "string",
+					Description: "Text to look for in resource names (case-insensitive)",
+				},
+				"kinds": {
+					Type:        "array",
+					Description: "Kinds to search instead of the defaults (e.g., [\"pod\", \"certificate\"])",
+					Items:       &genai.Schema{Type: "string"},
+				},
+				"namespace": {
+					Type:        "string",
+					Description: "Only search this namespace (default: all namespaces)",
+				},
+				"match_labels": {
+					Type:        "boolean",
+					Description: "Also match label keys and values (default: true)",
+				},
+				"limit": {
+					Type:        "integer",
+					Description: fmt.Sprintf("Maximum number of matches to return (default: %d)", findResourceDefaultLimit),
+				},
+			},
+			Required: []string{"query"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *FindResourceTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	q := findQuery{matchLabels: true, limit: findResourceDefaultLimit}
+	q.text, _ = argsMap["query"].(string)
+	q.text = strings.TrimSpace(q.text)
+	if q.text == "" {
+		return map[string]any{"error": "query is required"}, nil
+	}
+	q.kinds = stringArg(argsMap, "kinds")
+	q.namespace, _ = argsMap["namespace"].(string)
+	if ml, ok := argsMap["match_labels"].(bool); ok {
+		q.matchLabels = ml
+	}
+	if l, ok := argsMap["limit"].(float64); ok && l > 0 {
+		q.limit = int(l)
+	}
+
+	return findResources(toolContext(ctx), t.dynamicClient, t.resolver, q), nil
+}
+
+// findQuery describes a find_resource call.
+type findQuery struct {
+	text        string
+	kinds       []string // empty means findResourceKinds
+	namespace   string
+	matchLabels bool
+	limit       int
+}
+
+// ResourceMatch is one resource found by find_resource.
+type ResourceMatch struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	MatchedOn string `json:"matched_on"` // "name" or "label key=value"
+	rank      int    // 0 exact name, 1 name contains, 2 label
+}
+
+// findResources lists each kind and returns the resources matching q.
+// Kinds from the default list that the cluster does not serve are skipped
+// quietly; problems with kinds the caller asked for are reported.
+func findResources(ctx context.Context, dyn dynamic.Interface, resolver *GVRResolver, q findQuery) map[string]any {
+	if dyn == nil {
+		return map[string]any{"error": "dynamic client not available"}
+	}
+	kinds, explicit := q.kinds, true
+	if len(kinds) == 0 {
+		kinds, explicit = findResourceKinds, false
+	}
+	needle := strings.ToLower(q.text)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var matches []ResourceMatch
+	var errs []string
+	for _, kind := range kinds {
+		gvr, err := resolver.Resolve(kind, "")
+		if err != nil {
+			if explicit {
+				errs = append(errs, err.Error())
+			}
+			continue
+		}
+		var resourceClient dynamic.ResourceInterface = dyn.Resource(gvr)
+		namespaced := resolver.IsNamespaced(gvr, kind)
+		if namespaced && q.namespace != "" {
+			resourceClient = dyn.Resource(gvr).Namespace(q.namespace)
+		}
+
+		list, err := resourceClient.List(ctx, metav1.ListOptions{})
+		if err != nil {
+			if explicit || !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Sprintf("failed to list %s: %v", kind, err))
+			}
+			continue
+		}
+
+		normalized := NormalizeKindName(kind)
+		for _, item := range list.Items {
+			// With a namespace filter, only that namespace itself is interesting
+			if !namespaced && q.namespace != "" && !(normalized == "namespace" && item.GetName() == q.namespace) {
+				continue
+			}
+			m := ResourceMatch{Kind: normalized, Namespace: item.GetNamespace(), Name: item.GetName()}
+			name := strings.ToLower(item.GetName())
+			switch {
+			case name == needle:
+				m.MatchedOn, m.rank = "name", 0
+			case strings.Contains(name, needle):
+				m.MatchedOn, m.rank = "name", 1
+			case q.matchLabels:
+				label := matchingLabel(item.GetLabels(), needle)
+				if label == "" {
+					continue
+				}
+				m.MatchedOn, m.rank = "label "+label, 2
+			default:
+				continue
+			}
+			matches = append(matches, m)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	result := map[string]any{
+		"query": q.text,
+		"count": len(matches),
+	}
+	if len(matches) > q.limit {
+		result["truncated"] = true
+		result["message"] = fmt.Sprintf("Showing %d of %d matches; use a longer query, kinds or namespace to narrow the search", q.limit, len(matches))
+		matches = matches[:q.limit]
+	}
+	result["matches"] = matches
+	if len(errs) > 0 {
+		result["errors"] = errs
+	}
+	if len(matches) == 0 {
+		searched := "default kinds"
+		if explicit {
+			searched = strings.Join(kinds, ", ")
+		}
+		result["message"] = fmt.Sprintf("No resources matching %q among %s", q.text, searched)
+	}
+	return result
+}
+
+// matchingLabel returns the first label, as "key=value", whose key or value
+// contains needle.
+func matchingLabel(labels map[string]string, needle string) string {
+	for _, k := range sortedStringKeys(labels) {
+		if strings.Contains(strings.ToLower(k), needle) || strings.Contains(strings.ToLower(labels[k]), needle) {
+			return k + "=" + labels[k]
+		}
+	}
+	return ""
+}
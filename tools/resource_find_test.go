@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func findTestObject(apiVersion, kind, namespace, name string, labels map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	return obj
+}
+
+func newFindFake(t *testing.T, objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	t.Helper()
+	listKinds := map[schema.GroupVersionResource]string{}
+	var resolver *GVRResolver
+	for _, kind := range append([]string{"pod"}, findResourceKinds...) {
+		gvr, err := resolver.Resolve(kind, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		listKinds[gvr] = kind + "List"
+	}
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+}
+
+func TestFindResources(t *testing.T) {
+	dyn := newFindFake(t,
+		findTestObject("v1", "Namespace", "", "payments", nil),
+		findTestObject("apps/v1", "Deployment", "payments", "payments-api", nil),
+		findTestObject("apps/v1", "Deployment", "shop", "checkout", map[string]string{"team": "payments"}),
+		findTestObject("v1", "Service", "shop", "Payments", nil),
+		findTestObject("v1", "ConfigMap", "shop", "unrelated", nil),
+		findTestObject("v1", "Pod", "payments", "payments-api-7d4b9-aaaaa", nil),
+	)
+
+	result := findResources(context.Background(), dyn, nil, findQuery{text: "payments", matchLabels: true, limit: 50})
+	matches, _ := result["matches"].([]ResourceMatch)
+	want := []ResourceMatch{
+		{Kind: "namespace", Name: "payments", MatchedOn: "name"},
+		{Kind: "service", Namespace: "shop", Name: "Payments", MatchedOn: "name"},
+		{Kind: "deployment", Namespace: "payments", Name: "payments-api", MatchedOn: "name"},
+		{Kind: "deployment", Namespace: "shop", Name: "checkout", MatchedOn: "label team=payments"},
+	}
+	if len(matches) != len(want) {
+		t.Fatalf("matches = %+v, want %+v", matches, want)
+	}
+	for i := range want {
+		got := matches[i]
+		got.rank = 0
+		if got != want[i] {
+			t.Errorf("match %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+	if _, ok := result["errors"]; ok {
+		t.Errorf("unexpected errors: %v", result["errors"])
+	}
+
+	// Pods are only searched when asked for, and a namespace narrows the search
+	result = findResources(context.Background(), dyn, nil, findQuery{text: "api", kinds: []string{"pod", "deploy"}, namespace: "payments", limit: 1})
+	matches, _ = result["matches"].([]ResourceMatch)
+	if result["count"] != 2 || result["truncated"] != true || len(matches) != 1 || matches[0].Kind != "deployment" {
+		t.Errorf("narrowed search = %v", result)
+	}
+
+	// Label matching can be turned off
+	result = findResources(context.Background(), dyn, nil, findQuery{text: "payments", kinds: []string{"deployment"}, limit: 50})
+	if result["count"] != 1 {
+		t.Errorf("search without labels = %v", result)
+	}
+
+	// Unknown kinds the caller asked for are reported
+	result = findResources(context.Background(), dyn, nil, findQuery{text: "x", kinds: []string{"nosuchkind"}, limit: 50})
+	if errs, _ := result["errors"].([]string); len(errs) != 1 {
+		t.Errorf("errors = %v, want one", result["errors"])
+	}
+}
@@ -0,0 +1,346 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/perbu/kasa/manifest"
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+)
+
+// ResourceMetadataTool provides the label_resource and annotate_resource
+// tools for the agent. field is "labels" or "annotations".
+type ResourceMetadataTool struct {
+	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+	manifest      *manifest.Manager
+	field         string
+}
+
+// NewLabelResourceTool creates the label_resource tool.
+func NewLabelResourceTool(dynamicClient dynamic.Interface, resolver *GVRResolver, manifest *manifest.Manager) *ResourceMetadataTool {
+	return &ResourceMetadataTool{
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+		manifest:      manifest,
+		field:         "labels",
+	}
+}
+
+// NewAnnotateResourceTool creates the annotate_resource tool.
+func NewAnnotateResourceTool(dynamicClient dynamic.Interface, resolver *GVRResolver, manifest *manifest.Manager) *ResourceMetadataTool {
+	return &ResourceMetadataTool{
+		dynamicClient: dynamicClient,
+		resolver:      resolver,
+		manifest:      manifest,
+		field:         "annotations",
+	}
+}
+
+// Name returns the tool name.
+func (t *ResourceMetadataTool) Name() string {
+	if t.field == "labels" {
+		return "label_resource"
+	}
+	return "annotate_resource"
+}
+
+// Description returns the tool description.
+func (t *ResourceMetadataTool) Description() string {
+	if t.field == "labels" {
+		return "Add, change or remove labels on any resource (like 'kubectl label'), leaving the rest of the object alone. The change is also written to the stored manifest if the resource has one. Note that labels on a pod decide which Services and ReplicaSets select it."
+	}
+	return "Add, change or remove annotations on any resource (like 'kubectl annotate'), leaving the rest of the object alone. The change is also written to the stored manifest if the resource has one."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *ResourceMetadataTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *ResourceMetadataTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *ResourceMetadataTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *ResourceMetadataTool) Declaration() *genai.FunctionDeclaration {
+	singular := strings.TrimSuffix(t.field, "s")
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"type": {
+					Type:        "string",
+					Description: "The resource type (e.g., deployment, service, node, namespace, or a CRD kind)",
+				},
+				"name": {
+					Type:        "string",
+					Description: "The name of the resource",
+				},
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace. Omit for cluster-scoped resources",
+				},
+				"api_version": {
+					Type:        "string",
+					Description: "API version for CRDs (e.g., 'gateway.networking.k8s.io/v1'). Only needed for unknown resource types.",
+				},
+				"app": {
+					Type:        "string",
+					Description: "Application name of the stored manifest (default: name)",
+				},
+				"set": {
+					Type:        "object",
+					Description: fmt.Sprintf("The %ss to add or change, as key-value pairs", singular),
+				},
+				"remove": {
+					Type:        "array",
+					Description: fmt.Sprintf("Keys of %ss to remove", singular),
+					Items:       &genai.Schema{Type: "string"},
+				},
+			},
+			Required: []string{"type", "name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *ResourceMetadataTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	u := metadataUpdate{field: t.field}
+	u.kind, _ = argsMap["type"].(string)
+	if u.kind == "" {
+		return map[string]any{"error": "type is required"}, nil
+	}
+	u.name, _ = argsMap["name"].(string)
+	if u.name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+	u.namespace, _ = argsMap["namespace"].(string)
+	u.apiVersion, _ = argsMap["api_version"].(string)
+	u.app = u.name
+	if app, ok := argsMap["app"].(string); ok && app != "" {
+		u.app = app
+	}
+
+	u.set = map[string]string{}
+	if set, ok := argsMap["set"].(map[string]any); ok {
+		for k, v := range set {
+			switch vv := v.(type) {
+			case string:
+				u.set[k] = vv
+			case bool, float64:
+				// Values are always strings; accept what the model meant
+				u.set[k] = fmt.Sprint(vv)
+			default:
+				return map[string]any{"error": fmt.Sprintf("value of %s must be a string", k)}, nil
+			}
+		}
+	}
+	u.remove = stringArg(argsMap, "remove")
+	if len(u.set) == 0 && len(u.remove) == 0 {
+		return map[string]any{"error": "set or remove is required"}, nil
+	}
+
+	return patchMetadata(toolContext(ctx), t.dynamicClient, t.resolver, t.manifest, u), nil
+}
+
+// metadataUpdate describes a label_resource or annotate_resource call.
+type metadataUpdate struct {
+	field                 string // labels or annotations
+	kind, name, namespace string
+	apiVersion            string
+	app                   string // stored manifest app name
+	set                   map[string]string
+	remove                []string
+}
+
+// validate checks the keys and values against the API server's rules, so a
+// bad key is reported before anything is changed.
+func (u metadataUpdate) validate() error {
+	var problems []string
+	for _, k := range sortedStringKeys(u.set) {
+		for _, msg := range validation.IsQualifiedName(k) {
+			problems = append(problems, fmt.Sprintf("key %q: %s", k, msg))
+		}
+		if u.field == "labels" {
+			for _, msg := range validation.IsValidLabelValue(u.set[k]) {
+				problems = append(problems, fmt.Sprintf("value of %s: %s", k, msg))
+			}
+		}
+	}
+	for _, k := range u.remove {
+		if _, ok := u.set[k]; ok {
+			problems = append(problems, fmt.Sprintf("%s is both set and removed", k))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid %s: %s", u.field, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// patchMetadata applies the update to the live resource with a merge patch
+// and then to its stored manifest, if there is one. Returns the tool response.
+func patchMetadata(ctx context.Context, dyn dynamic.Interface, resolver *GVRResolver, mgr *manifest.Manager, u metadataUpdate) map[string]any {
+	if err := u.validate(); err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	if dyn == nil {
+		return map[string]any{"error": "dynamic client not available"}
+	}
+
+	gvr, err := resolver.Resolve(u.kind, u.apiVersion)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	kind := NormalizeKindName(u.kind)
+	namespaced := resolver.IsNamespaced(gvr, u.kind)
+
+	manifestNamespace := u.namespace
+	var resourceClient dynamic.ResourceInterface = dyn.Resource(gvr)
+	if namespaced {
+		if u.namespace == "" || u.namespace == manifest.ClusterNamespace {
+			return map[string]any{"error": fmt.Sprintf("namespace is required: %s is namespaced", u.kind)}
+		}
+		resourceClient = dyn.Resource(gvr).Namespace(u.namespace)
+	} else {
+		manifestNamespace = manifest.ClusterNamespace
+	}
+
+	// In a merge patch null deletes a key; removing a missing key is a no-op
+	values := map[string]any{}
+	for k, v := range u.set {
+		values[k] = v
+	}
+	for _, k := range u.remove {
+		values[k] = nil
+	}
+	patch, err := json.Marshal(map[string]any{"metadata": map[string]any{u.field: values}})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to build patch: %v", err)}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	live, err := resourceClient.Patch(ctx, u.name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to patch %s %s: %v", kind, u.name, err)}
+	}
+
+	current := live.GetLabels()
+	if u.field == "annotations" {
+		current = live.GetAnnotations()
+	}
+	result := map[string]any{
+		"type":    kind,
+		"name":    u.name,
+		u.field:   current,
+		"success": true,
+	}
+	if namespaced {
+		result["namespace"] = u.namespace
+	}
+
+	if mgr.ManifestExists(manifestNamespace, u.app, kind) {
+		content, err := mgr.ReadManifest(manifestNamespace, u.app, kind)
+		if err == nil {
+			content, err = setManifestMetadata(content, u.field, u.set, u.remove)
+		}
+		if err == nil {
+			var manifestPath string
+			if manifestPath, err = mgr.SaveManifest(manifestNamespace, u.app, kind, content); err == nil {
+				result["manifest_path"] = manifestPath
+			}
+		}
+		if err != nil {
+			result["manifest_warning"] = fmt.Sprintf("Updated in the cluster but failed to update the stored manifest: %v", err)
+		}
+	}
+
+	var changes []string
+	for _, k := range sortedStringKeys(u.set) {
+		changes = append(changes, k+"="+u.set[k])
+	}
+	for _, k := range u.remove {
+		changes = append(changes, k+"-")
+	}
+	message := fmt.Sprintf("Updated %s on %s %s: %s", u.field, kind, u.name, strings.Join(changes, ", "))
+	if mgr.IsDryRun() {
+		result["dry_run"] = true
+		message = fmt.Sprintf("Dry run: would update %s on %s %s: %s", u.field, kind, u.name, strings.Join(changes, ", "))
+	}
+	result["message"] = message
+
+	if u.field == "labels" && kind == "pod" {
+		result["warning"] = "Pod labels decide which Services and ReplicaSets select the pod; a changed label can take it out of load balancing or make its ReplicaSet start a replacement."
+	}
+	return result
+}
+
+// setManifestMetadata sets and removes labels or annotations in a stored
+// manifest, leaving the rest of the document as it is.
+func setManifestMetadata(content []byte, field string, set map[string]string, remove []string) ([]byte, error) {
+	doc, root, err := parseYAMLDocument(content)
+	if err != nil {
+		return nil, err
+	}
+	base := []yamlPathSegment{{key: "metadata"}, {key: field}}
+
+	for _, k := range sortedStringKeys(set) {
+		// Values are always strings, even ones that look like numbers or booleans
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: set[k]}
+		if _, err := setYAMLPath(root, append(append([]yamlPathSegment{}, base...), yamlPathSegment{key: k}), value); err != nil {
+			return nil, err
+		}
+	}
+	for _, k := range remove {
+		// Removing a key that isn't there is fine
+		_, _ = removeYAMLPath(root, append(append([]yamlPathSegment{}, base...), yamlPathSegment{key: k}))
+	}
+	if node, err := lookupYAMLPath(root, base); err == nil && node.Kind == yaml.MappingNode && len(node.Content) == 0 {
+		_, _ = removeYAMLPath(root, base)
+	}
+
+	return encodeYAMLDocument(doc, usesCompactSequences(content))
+}
+
+// sortedStringKeys returns the keys of m in sorted order.
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestPatchMetadata(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	stored := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: default
+  labels:
+    app: web # owner
+    tier: legacy
+data:
+  key: stored
+`
+	if _, err := mgr.SaveManifest("default", "settings", "configmap", []byte(stored)); err != nil {
+		t.Fatal(err)
+	}
+	live := newConfigMap("settings", "stored")
+	live.SetLabels(map[string]string{"app": "web", "tier": "legacy"})
+	dyn := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), live)
+
+	u := metadataUpdate{
+		field: "labels", kind: "configmap", name: "settings", namespace: "default", app: "settings",
+		set:    map[string]string{"team": "payments", "pinned": "true"},
+		remove: []string{"tier"},
+	}
+	result := patchMetadata(context.Background(), dyn, nil, mgr, u)
+	if result["success"] != true {
+		t.Fatalf("patchMetadata failed: %v", result)
+	}
+
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	obj, _ := dyn.Resource(gvr).Namespace("default").Get(context.Background(), "settings", metav1.GetOptions{})
+	got := obj.GetLabels()
+	if got["team"] != "payments" || got["pinned"] != "true" || got["app"] != "web" {
+		t.Errorf("live labels = %v", got)
+	}
+	if _, ok := got["tier"]; ok {
+		t.Errorf("tier should be removed: %v", got)
+	}
+
+	content, _ := mgr.ReadManifest("default", "settings", "configmap")
+	want := strings.Replace(stored, "    tier: legacy\n", "    pinned: \"true\"\n    team: payments\n", 1)
+	if string(content) != want {
+		t.Errorf("stored manifest:\n%s\nwant:\n%s", content, want)
+	}
+
+	// Annotations are added to a manifest that had none, and removing the
+	// last one drops the empty mapping again
+	u = metadataUpdate{field: "annotations", kind: "cm", name: "settings", namespace: "default", app: "settings",
+		set: map[string]string{"owner": "team-a"}}
+	if result := patchMetadata(context.Background(), dyn, nil, mgr, u); result["success"] != true {
+		t.Fatalf("patchMetadata annotations failed: %v", result)
+	}
+	content, _ = mgr.ReadManifest("default", "settings", "configmap")
+	if !strings.Contains(string(content), "  annotations:\n    owner: team-a\n") {
+		t.Errorf("stored manifest lacks the annotation:\n%s", content)
+	}
+	u.set, u.remove = nil, []string{"owner"}
+	if result := patchMetadata(context.Background(), dyn, nil, mgr, u); result["success"] != true {
+		t.Fatalf("patchMetadata remove failed: %v", result)
+	}
+	content, _ = mgr.ReadManifest("default", "settings", "configmap")
+	if string(content) != want {
+		t.Errorf("stored manifest after removal:\n%s\nwant:\n%s", content, want)
+	}
+}
+
+func TestPatchMetadataWithoutManifest(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	dyn := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), newConfigMap("unmanaged", "x"))
+
+	u := metadataUpdate{field: "labels", kind: "configmap", name: "unmanaged", namespace: "default", app: "unmanaged",
+		set: map[string]string{"team": "payments"}}
+	result := patchMetadata(context.Background(), dyn, nil, mgr, u)
+	if result["success"] != true {
+		t.Fatalf("patchMetadata failed: %v", result)
+	}
+	if _, ok := result["manifest_path"]; ok {
+		t.Errorf("no manifest should be written: %v", result)
+	}
+}
+
+func TestMetadataUpdateValidate(t *testing.T) {
+	for _, tt := range []struct {
+		u     metadataUpdate
+		valid bool
+	}{
+		{metadataUpdate{field: "labels", set: map[string]string{"app.kubernetes.io/name": "web"}}, true},
+		{metadataUpdate{field: "labels", set: map[string]string{"bad key": "web"}}, false},
+		{metadataUpdate{field: "labels", set: map[string]string{"note": "not a label value"}}, false},
+		{metadataUpdate{field: "annotations", set: map[string]string{"note": "free text is fine here"}}, true},
+		{metadataUpdate{field: "labels", set: map[string]string{"team": "a"}, remove: []string{"team"}}, false},
+	} {
+		if err := tt.u.validate(); (err == nil) != tt.valid {
+			t.Errorf("validate(%v) = %v, want valid=%v", tt.u.set, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,454 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// defaultTimelineHours is how far back resource_timeline looks by default.
+	defaultTimelineHours = 24
+	// maxTimelineHours caps how far back resource_timeline looks.
+	maxTimelineHours = 24 * 7
+	// maxTimelineEntries caps the entries returned; the oldest are dropped.
+	maxTimelineEntries = 150
+
+	revisionAnnotation        = "deployment.kubernetes.io/revision"
+	revisionHistoryAnnotation = "deployment.kubernetes.io/revision-history"
+	restartedAtAnnotation     = "kubectl.kubernetes.io/restartedAt"
+)
+
+// Sources of timeline entries.
+const (
+	timelineRollout   = "rollout"
+	timelineCondition = "condition"
+	timelineEvent     = "event"
+	timelineManifest  = "manifest"
+)
+
+// TimelineEntry is one thing that happened to an app.
+type TimelineEntry struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"` // rollout, condition, event or manifest
+	Object  string    `json:"object,omitempty"`
+	Summary string    `json:"summary"`
+	Warning bool      `json:"warning,omitempty"`
+}
+
+// ResourceTimelineTool reconstructs what happened to a deployment.
+type ResourceTimelineTool struct {
+	clientset *kubernetes.Clientset
+	manifest  *manifest.Manager
+}
+
+// NewResourceTimelineTool creates a new ResourceTimelineTool.
+func NewResourceTimelineTool(clientset *kubernetes.Clientset, manifest *manifest.Manager) *ResourceTimelineTool {
+	return &ResourceTimelineTool{
+		clientset: clientset,
+		manifest:  manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *ResourceTimelineTool) Name() string {
+	return "resource_timeline"
+}
+
+// Description returns the tool description.
+func (t *ResourceTimelineTool) Description() string {
+	return "Reconstruct what happened to a deployment over the last hours, in chronological order: " +
+		"rollouts (ReplicaSet revisions with their images, change-cause and restarts), rollout conditions, " +
+		"events of the deployment, its ReplicaSets and pods, and commits to its stored manifests. " +
+		"Use it for incident reviews and 'what changed?' questions instead of piecing this together from other tools. " +
+		"The API server keeps events for about an hour by default, so older entries come from revisions and git."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *ResourceTimelineTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *ResourceTimelineTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *ResourceTimelineTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *ResourceTimelineTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The namespace of the deployment",
+				},
+				"name": {
+					Type:        "string",
+					Description: "The name of the deployment, which is also the app name in the manifest store",
+				},
+				"hours": {
+					Type:        "integer",
+					Description: fmt.Sprintf("How many hours back to look (default %d, at most %d)", defaultTimelineHours, maxTimelineHours),
+				},
+			},
+			Required: []string{"namespace", "name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *ResourceTimelineTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, _ := argsMap["namespace"].(string)
+	name, _ := argsMap["name"].(string)
+	if namespace == "" || name == "" {
+		return map[string]any{"error": "namespace and name are required"}, nil
+	}
+	hours := float64(defaultTimelineHours)
+	if h, ok := argsMap["hours"].(float64); ok && h > 0 {
+		hours = min(h, maxTimelineHours)
+	}
+	now := time.Now()
+	since := now.Add(-time.Duration(hours * float64(time.Hour)))
+
+	var notes []string
+	var commits []manifest.Commit
+	if t.manifest != nil {
+		var err error
+		if commits, err = t.manifest.History(namespace, name, since); err != nil {
+			notes = append(notes, fmt.Sprintf("manifest history: %v", err))
+		}
+	}
+
+	result := resourceTimeline(toolContext(ctx), t.clientset, namespace, name, since, commits)
+	if result["error"] != nil {
+		return result, nil
+	}
+	if n, ok := result["notes"].([]string); ok {
+		notes = append(notes, n...)
+	}
+	if len(notes) > 0 {
+		result["notes"] = notes
+	}
+	return result, nil
+}
+
+// resourceTimeline builds the timeline of a deployment since the given time
+// from the cluster and the manifest commits.
+func resourceTimeline(ctx context.Context, clientset kubernetes.Interface, namespace, name string, since time.Time, commits []manifest.Commit) map[string]any {
+	var entries []TimelineEntry
+	var notes []string
+	result := map[string]any{
+		"namespace": namespace,
+		"name":      name,
+		"since":     since.UTC().Format(time.RFC3339),
+	}
+
+	apiCtx, cancel := apiContext(ctx)
+	deployment, err := clientset.AppsV1().Deployments(namespace).Get(apiCtx, name, metav1.GetOptions{})
+	cancel()
+	switch {
+	case apierrors.IsNotFound(err):
+		deployment = nil
+		notes = append(notes, "the deployment doesn't exist (any more); the timeline has only events and manifest commits")
+	case err != nil:
+		return map[string]any{"error": fmt.Sprintf("getting deployment: %v", err)}
+	}
+
+	// Events name ReplicaSets and pods, so they are matched by those names
+	var replicaSets []appsv1.ReplicaSet
+	if deployment != nil {
+		apiCtx, cancel := apiContext(ctx)
+		replicaSets, err = deploymentReplicaSets(apiCtx, clientset, deployment)
+		cancel()
+		if err != nil {
+			return map[string]any{"error": err.Error()}
+		}
+		result["revision"] = deployment.Annotations[revisionAnnotation]
+		if deployment.Spec.Paused {
+			notes = append(notes, "the rollout is paused")
+		}
+
+		rollouts, n := revisionEntries(replicaSets, since)
+		entries = append(entries, rollouts...)
+		notes = append(notes, n...)
+		entries = append(entries, conditionEntries(deployment, since)...)
+	}
+
+	apiCtx, cancel = apiContext(ctx)
+	events, err := clientset.CoreV1().Events(namespace).List(apiCtx, metav1.ListOptions{})
+	cancel()
+	if err != nil {
+		notes = append(notes, fmt.Sprintf("listing events: %v", err))
+	} else {
+		entries = append(entries, eventEntries(events.Items, name, replicaSets, since)...)
+	}
+
+	for _, c := range commits {
+		files := make([]string, len(c.Files))
+		for i, f := range c.Files {
+			files[i] = filepath.Base(f)
+		}
+		entries = append(entries, TimelineEntry{
+			Time:    c.Date,
+			Source:  timelineManifest,
+			Object:  c.Hash,
+			Summary: fmt.Sprintf("committed %q (%s)", c.Message, strings.Join(files, ", ")),
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	if len(entries) > maxTimelineEntries {
+		notes = append(notes, fmt.Sprintf("%d entries; only the latest %d are shown, ask for fewer hours for the rest", len(entries), maxTimelineEntries))
+		entries = entries[len(entries)-maxTimelineEntries:]
+	}
+	if len(entries) == 0 {
+		notes = append(notes, "nothing happened in this period as far as revisions, events and manifest commits tell")
+	}
+
+	result["entries"] = entries
+	result["count"] = len(entries)
+	if len(notes) > 0 {
+		result["notes"] = notes
+	}
+	return result
+}
+
+// revisionEntries describes the rollout of each revision created since the
+// given time, with what changed from the revision before it.
+func revisionEntries(replicaSets []appsv1.ReplicaSet, since time.Time) ([]TimelineEntry, []string) {
+	sorted := make([]appsv1.ReplicaSet, len(replicaSets))
+	copy(sorted, replicaSets)
+	sort.Slice(sorted, func(i, j int) bool {
+		return replicaSetRevision(sorted[i]) < replicaSetRevision(sorted[j])
+	})
+
+	var entries []TimelineEntry
+	var notes []string
+	for i, rs := range sorted {
+		revision := replicaSetRevision(rs)
+		// A rollback reuses the ReplicaSet of the old revision and only
+		// renumbers it, so its creation doesn't tell when it happened
+		if history := rs.Annotations[revisionHistoryAnnotation]; history != "" {
+			notes = append(notes, fmt.Sprintf("revision %d (%s) was rolled back to from revision(s) %s; the ScalingReplicaSet events tell when", revision, rs.Name, history))
+			continue
+		}
+		if rs.CreationTimestamp.Time.Before(since) {
+			continue
+		}
+
+		var changes []string
+		images := containerImages(rs.Spec.Template.Spec.Containers)
+		if i > 0 {
+			previous := containerImages(sorted[i-1].Spec.Template.Spec.Containers)
+			for _, c := range rs.Spec.Template.Spec.Containers {
+				if old, ok := previous[c.Name]; !ok {
+					changes = append(changes, fmt.Sprintf("added container %s (%s)", c.Name, c.Image))
+				} else if old != c.Image {
+					changes = append(changes, fmt.Sprintf("image of %s %s -> %s", c.Name, old, c.Image))
+				}
+			}
+			if restarted := rs.Spec.Template.Annotations[restartedAtAnnotation]; restarted != "" && restarted != sorted[i-1].Spec.Template.Annotations[restartedAtAnnotation] {
+				changes = append(changes, "restart")
+			}
+			if len(changes) == 0 {
+				changes = append(changes, "pod template changed (not the images)")
+			}
+		} else {
+			for _, c := range rs.Spec.Template.Spec.Containers {
+				changes = append(changes, fmt.Sprintf("%s %s", c.Name, images[c.Name]))
+			}
+		}
+
+		summary := fmt.Sprintf("revision %d rolled out: %s", revision, strings.Join(changes, ", "))
+		if cause := rs.Annotations[changeCauseAnnotation]; cause != "" {
+			summary += fmt.Sprintf("; change-cause %q", cause)
+		}
+		entries = append(entries, TimelineEntry{
+			Time:    rs.CreationTimestamp.Time,
+			Source:  timelineRollout,
+			Object:  "ReplicaSet/" + rs.Name,
+			Summary: summary,
+		})
+	}
+	return entries, notes
+}
+
+// replicaSetRevision returns the deployment revision of a ReplicaSet.
+func replicaSetRevision(rs appsv1.ReplicaSet) int {
+	revision, _ := strconv.Atoi(rs.Annotations[revisionAnnotation])
+	return revision
+}
+
+// containerImages maps container names to images.
+func containerImages(containers []corev1.Container) map[string]string {
+	images := make(map[string]string, len(containers))
+	for _, c := range containers {
+		images[c.Name] = c.Image
+	}
+	return images
+}
+
+// conditionEntries reports the deployment's conditions that changed since
+// the given time.
+func conditionEntries(deployment *appsv1.Deployment, since time.Time) []TimelineEntry {
+	var entries []TimelineEntry
+	for _, c := range deployment.Status.Conditions {
+		if c.LastTransitionTime.Time.Before(since) {
+			continue
+		}
+		entries = append(entries, TimelineEntry{
+			Time:    c.LastTransitionTime.Time,
+			Source:  timelineCondition,
+			Object:  "Deployment/" + deployment.Name,
+			Summary: fmt.Sprintf("%s=%s (%s): %s", c.Type, c.Status, c.Reason, c.Message),
+			Warning: c.Status != corev1.ConditionTrue || c.Reason == "ProgressDeadlineExceeded",
+		})
+	}
+	return entries
+}
+
+// timelineEvents is the events of one reason about one object, or about the
+// pods of one ReplicaSet.
+type timelineEvents struct {
+	object      string
+	reason      string
+	warning     bool
+	pods        map[string]bool
+	count       int32
+	first, last time.Time
+	message     string // the latest
+}
+
+// eventEntries groups the events since the given time about the deployment,
+// its ReplicaSets and their pods, or other objects of the same name such as
+// its Service or HorizontalPodAutoscaler. Pod events are grouped by
+// ReplicaSet, so a crash loop in ten pods is one entry.
+func eventEntries(events []corev1.Event, name string, replicaSets []appsv1.ReplicaSet, since time.Time) []TimelineEntry {
+	groups := make(map[string]*timelineEvents)
+	var order []*timelineEvents
+	for _, e := range events {
+		at := eventTime(e)
+		if at.Before(since) {
+			continue
+		}
+		object := e.InvolvedObject.Kind + "/" + e.InvolvedObject.Name
+		pod := ""
+		switch {
+		case e.InvolvedObject.Name == name:
+		case e.InvolvedObject.Kind == "ReplicaSet" && ownsReplicaSet(replicaSets, e.InvolvedObject.Name):
+		case e.InvolvedObject.Kind == "Pod":
+			rs := podReplicaSet(replicaSets, e.InvolvedObject.Name)
+			if rs == "" {
+				continue
+			}
+			object = "pods of ReplicaSet/" + rs
+			pod = e.InvolvedObject.Name
+		default:
+			continue
+		}
+
+		key := object + "\x00" + e.Reason
+		g := groups[key]
+		if g == nil {
+			g = &timelineEvents{object: object, reason: e.Reason, pods: make(map[string]bool), first: at}
+			groups[key] = g
+			order = append(order, g)
+		}
+		if e.Type == corev1.EventTypeWarning {
+			g.warning = true
+		}
+		if pod != "" {
+			g.pods[pod] = true
+		}
+		g.count += max(eventCount(&e), 1)
+		if first := e.FirstTimestamp.Time; !first.IsZero() && !first.Before(since) && first.Before(g.first) {
+			g.first = first
+		}
+		if at.Before(g.first) {
+			g.first = at
+		}
+		if !at.Before(g.last) {
+			g.last = at
+			g.message = strings.TrimSpace(e.Message)
+		}
+	}
+
+	entries := make([]TimelineEntry, 0, len(order))
+	for _, g := range order {
+		var b strings.Builder
+		b.WriteString(g.reason)
+		if len(g.pods) > 1 {
+			fmt.Fprintf(&b, " in %d pods", len(g.pods))
+		}
+		if g.count > 1 {
+			fmt.Fprintf(&b, " (x%d until %s)", g.count, g.last.UTC().Format(time.TimeOnly))
+		}
+		b.WriteString(": ")
+		b.WriteString(g.message)
+		entries = append(entries, TimelineEntry{
+			Time:    g.first,
+			Source:  timelineEvent,
+			Object:  g.object,
+			Summary: b.String(),
+			Warning: g.warning,
+		})
+	}
+	return entries
+}
+
+// ownsReplicaSet reports whether name is one of the replica sets.
+func ownsReplicaSet(replicaSets []appsv1.ReplicaSet, name string) bool {
+	for _, rs := range replicaSets {
+		if rs.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// podReplicaSet returns the replica set a pod name belongs to, or "".
+func podReplicaSet(replicaSets []appsv1.ReplicaSet, pod string) string {
+	for _, rs := range replicaSets {
+		if strings.HasPrefix(pod, rs.Name+"-") {
+			return rs.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/perbu/kasa/manifest"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestResourceTimeline(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	since := now.Add(-2 * time.Hour)
+	labels := map[string]string{"app": "web"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod", UID: "dep-uid", Annotations: map[string]string{revisionAnnotation: "3"}},
+		Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+		Status: appsv1.DeploymentStatus{Conditions: []appsv1.DeploymentCondition{{
+			Type:               appsv1.DeploymentProgressing,
+			Status:             corev1.ConditionFalse,
+			Reason:             "ProgressDeadlineExceeded",
+			Message:            `ReplicaSet "web-c" has timed out progressing.`,
+			LastTransitionTime: metav1.NewTime(now.Add(-10 * time.Minute)),
+		}}},
+	}
+	replicaSet := func(name, revision, image, cause string, created time.Time) *appsv1.ReplicaSet {
+		controller := true
+		return &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "prod", Labels: labels,
+				CreationTimestamp: metav1.NewTime(created),
+				Annotations:       map[string]string{revisionAnnotation: revision, changeCauseAnnotation: cause},
+				OwnerReferences:   []metav1.OwnerReference{{Kind: "Deployment", Name: "web", UID: "dep-uid", Controller: &controller}},
+			},
+			Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "web", Image: image}},
+			}}},
+		}
+	}
+	event := func(kind, name, reason, eventType string, count int32, at time.Time) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name + "." + reason, Namespace: "prod"},
+			InvolvedObject: corev1.ObjectReference{Kind: kind, Name: name, Namespace: "prod"},
+			Type:           eventType,
+			Reason:         reason,
+			Message:        reason + " message",
+			Count:          count,
+			FirstTimestamp: metav1.NewTime(at),
+			LastTimestamp:  metav1.NewTime(at.Add(time.Minute)),
+		}
+	}
+
+	clientset := fake.NewSimpleClientset(
+		deployment,
+		replicaSet("web-a", "1", "web:1.0", "", now.Add(-48*time.Hour)),
+		replicaSet("web-b", "2", "web:1.1", "", now.Add(-30*time.Hour)),
+		replicaSet("web-c", "3", "web:1.2", "bump to 1.2", now.Add(-30*time.Minute)),
+		event("Deployment", "web", "ScalingReplicaSet", corev1.EventTypeNormal, 1, now.Add(-30*time.Minute)),
+		event("Pod", "web-c-x1", "BackOff", corev1.EventTypeWarning, 5, now.Add(-25*time.Minute)),
+		event("Pod", "web-c-x2", "BackOff", corev1.EventTypeWarning, 7, now.Add(-20*time.Minute)),
+		event("Pod", "api-7-x1", "BackOff", corev1.EventTypeWarning, 3, now.Add(-20*time.Minute)),
+		event("Pod", "web-c-x1", "Pulled", corev1.EventTypeNormal, 1, now.Add(-5*time.Hour)),
+	)
+	commits := []manifest.Commit{{
+		CommitInfo: manifest.CommitInfo{Hash: "abc1234", Date: now.Add(-35 * time.Minute), Message: "Update web to 1.2"},
+		Files:      []string{"prod/web/deployment.yaml"},
+	}}
+
+	result := resourceTimeline(context.Background(), clientset, "prod", "web", since, commits)
+	if result["error"] != nil {
+		t.Fatalf("resourceTimeline: %v", result["error"])
+	}
+	entries := result["entries"].([]TimelineEntry)
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Source+" "+e.Object+" "+e.Summary)
+	}
+	want := []string{
+		`manifest abc1234 committed "Update web to 1.2" (deployment.yaml)`,
+		`rollout ReplicaSet/web-c revision 3 rolled out: image of web web:1.1 -> web:1.2; change-cause "bump to 1.2"`,
+		`event Deployment/web ScalingReplicaSet: ScalingReplicaSet message`,
+		`event pods of ReplicaSet/web-c BackOff in 2 pods (x12 until `,
+		`condition Deployment/web Progressing=False (ProgressDeadlineExceeded): ReplicaSet "web-c" has timed out progressing.`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d:\n%s", len(got), len(want), strings.Join(got, "\n"))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("entry %d = %q, want prefix %q", i, got[i], want[i])
+		}
+	}
+	if !entries[3].Warning || !entries[4].Warning || entries[2].Warning {
+		t.Errorf("warnings = %v %v %v", entries[2].Warning, entries[3].Warning, entries[4].Warning)
+	}
+	if result["revision"] != "3" {
+		t.Errorf("revision = %v", result["revision"])
+	}
+}
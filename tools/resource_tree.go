@@ -0,0 +1,277 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// ResourceTreeTool provides the resource_tree tool for the agent. It walks
+// the same ownership graph as get_ownership, but from the top owner down, and
+// renders it like 'kubectl tree'.
+type ResourceTreeTool struct {
+	ownership *OwnershipTool
+}
+
+// NewResourceTreeTool creates a new ResourceTreeTool.
+func NewResourceTreeTool(dynamicClient dynamic.Interface, resolver *GVRResolver) *ResourceTreeTool {
+	return &ResourceTreeTool{
+		ownership: NewOwnershipTool(dynamicClient, resolver),
+	}
+}
+
+// Name returns the tool name.
+func (t *ResourceTreeTool) Name() string {
+	return "resource_tree"
+}
+
+// Description returns the tool description.
+func (t *ResourceTreeTool) Description() string {
+	return "Show the whole ownership tree a resource belongs to, like 'kubectl tree': from its top owner (e.g. Deployment) down through ReplicaSets or Jobs to Pods, with the status of every node and the requested resource marked. Use it to explain which ReplicaSet and rollout a broken pod belongs to."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *ResourceTreeTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *ResourceTreeTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *ResourceTreeTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *ResourceTreeTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"kind": {
+					Type:        "string",
+					Description: "The resource kind, e.g. pod, deployment, replicaset, job, cronjob, statefulset",
+				},
+				"name": {
+					Type:        "string",
+					Description: "The resource name",
+				},
+				"api_version": {
+					Type:        "string",
+					Description: "API version for custom resources (e.g. 'postgresql.cnpg.io/v1')",
+				},
+				"include_inactive": {
+					Type:        "boolean",
+					Description: "Include old ReplicaSets scaled to zero (default: false)",
+				},
+			},
+			Required: []string{"namespace", "kind", "name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *ResourceTreeTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+	kind, ok := argsMap["kind"].(string)
+	if !ok || kind == "" {
+		return map[string]any{"error": "kind is required"}, nil
+	}
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+	apiVersion, _ := argsMap["api_version"].(string)
+	includeInactive, _ := argsMap["include_inactive"].(bool)
+
+	o := t.ownership
+	gvr, err := o.resolver.Resolve(kind, apiVersion)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
+	defer cancel()
+
+	obj, err := o.resource(gvr, kind, namespace).Get(timeoutCtx, name, metav1.GetOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to get %s %s/%s: %v", kind, namespace, name, err)}, nil
+	}
+
+	// An old ReplicaSet asked for by name is shown even though it is inactive
+	if isInactiveReplicaSet(obj) {
+		includeInactive = true
+	}
+	w := &ownershipWalker{tool: o, namespace: namespace, includeInactive: includeInactive, lists: make(map[schema.GroupVersionResource][]unstructured.Unstructured)}
+
+	// Walk up to find the top owner, then build the tree down from it
+	target := newOwnershipNode(obj)
+	if err := w.owners(timeoutCtx, target, obj, 0); err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+	top := topOwner(target)
+	topObj := obj
+	if top != target {
+		topGVR, err := o.resolver.ResolveGVK(schema.FromAPIVersionAndKind(top.APIVersion, top.Kind))
+		if err != nil {
+			return map[string]any{"error": err.Error()}, nil
+		}
+		if topObj, err = o.resource(topGVR, top.Kind, namespace).Get(timeoutCtx, top.Name, metav1.GetOptions{}); err != nil {
+			return map[string]any{"error": fmt.Sprintf("failed to get %s %s/%s: %v", top.Kind, namespace, top.Name, err)}, nil
+		}
+	}
+	root := newOwnershipNode(topObj)
+	if err := w.dependents(timeoutCtx, root, 0); err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	var sb strings.Builder
+	renderResourceTree(&sb, root, target, "", "", true)
+
+	result := map[string]any{
+		"tree":      sb.String(),
+		"root":      fmt.Sprintf("%s/%s", root.Kind, root.Name),
+		"graph":     root,
+		"pod_count": countPods(root),
+	}
+	if top != target {
+		result["owner_chain"] = ownershipSummary(target)
+	}
+	if owner := controllerOwner(top); owner != nil && owner.Missing {
+		result["missing_owner"] = fmt.Sprintf("%s/%s", owner.Kind, owner.Name)
+	}
+	if unhealthy := unhealthyTreeNodes(root, nil); len(unhealthy) > 0 {
+		result["unhealthy"] = unhealthy
+	}
+	return result, nil
+}
+
+// renderResourceTree writes node and its dependents as an indented tree,
+// marking the node the tree was requested for.
+func renderResourceTree(sb *strings.Builder, node, target *OwnershipNode, prefix, branch string, last bool) {
+	sb.WriteString(prefix + branch + node.Kind + "/" + node.Name)
+	if status := treeNodeStatus(node); status != "" {
+		sb.WriteString("  " + status)
+	}
+	if node.Kind == target.Kind && node.Name == target.Name {
+		sb.WriteString("  <- requested")
+	}
+	if node.Omitted > 0 {
+		fmt.Fprintf(sb, "  (+%d inactive)", node.Omitted)
+	}
+	sb.WriteString("\n")
+
+	childPrefix := prefix
+	if branch != "" {
+		if last {
+			childPrefix += "    "
+		} else {
+			childPrefix += "│   "
+		}
+	}
+	for i, child := range node.Dependents {
+		childLast := i == len(node.Dependents)-1
+		childBranch := "├── "
+		if childLast {
+			childBranch = "└── "
+		}
+		renderResourceTree(sb, child, target, childPrefix, childBranch, childLast)
+	}
+}
+
+// treeNodeStatus renders a node's status summary on one line, e.g.
+// "Running, ready 1/1, 3 restarts" or "ready 2/3".
+func treeNodeStatus(node *OwnershipNode) string {
+	s := node.Status
+	if s == nil {
+		return ""
+	}
+	var parts []string
+	switch node.Kind {
+	case "Pod":
+		if phase, ok := s["phase"].(string); ok {
+			parts = append(parts, phase)
+		}
+		if ready, ok := s["ready"].(string); ok {
+			parts = append(parts, "ready "+ready)
+		}
+		if reason, ok := s["reason"].(string); ok {
+			parts = append(parts, reason)
+		}
+		if restarts, ok := s["restarts"]; ok {
+			parts = append(parts, fmt.Sprintf("%v restarts", restarts))
+		}
+	case "Deployment", "ReplicaSet", "StatefulSet":
+		parts = append(parts, fmt.Sprintf("ready %v/%v", numberOr0(s["ready"]), numberOr0(s["replicas"])))
+	case "DaemonSet":
+		parts = append(parts, fmt.Sprintf("ready %v/%v", numberOr0(s["ready"]), numberOr0(s["desired"])))
+	default:
+		for _, k := range sortedKeys(s) {
+			parts = append(parts, fmt.Sprintf("%s=%v", k, s[k]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// unhealthyTreeNodes lists the pods in the tree that are not running and
+// ready, with the owner they belong to.
+func unhealthyTreeNodes(node, parent *OwnershipNode) []string {
+	var out []string
+	if node.Kind == "Pod" && node.Status != nil {
+		phase, _ := node.Status["phase"].(string)
+		ready, _ := node.Status["ready"].(string)
+		reason, _ := node.Status["reason"].(string)
+		readyCount, total, _ := strings.Cut(ready, "/")
+		notReady := readyCount != total
+		if phase != "Succeeded" && (phase != "Running" || notReady || reason != "") {
+			line := fmt.Sprintf("Pod/%s: %s", node.Name, treeNodeStatus(node))
+			if parent != nil {
+				line += fmt.Sprintf(" (in %s/%s)", parent.Kind, parent.Name)
+			}
+			out = append(out, line)
+		}
+	}
+	for _, child := range node.Dependents {
+		out = append(out, unhealthyTreeNodes(child, node)...)
+	}
+	return out
+}
+
+// numberOr0 returns v, or 0 if the status field was absent.
+func numberOr0(v any) any {
+	if v == nil {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"google.golang.org/adk/tool"
+)
+
+// DefaultResultTTL is how long the result of a read-only tool call is
+// reused for an identical call in the same session.
+const DefaultResultTTL = 30 * time.Second
+
+// SetResultCacheTTL makes identical read-only tool calls within a session
+// reuse the first call's result for ttl, since the model tends to list the
+// same pods several times in one diagnosis. 0 uses DefaultResultTTL and a
+// negative ttl turns it off. Results are never reused after a mutating
+// call, nor for tools that wait for the cluster.
+func (k *KubeTools) SetResultCacheTTL(ttl time.Duration) {
+	if ttl < 0 {
+		k.results = nil
+		return
+	}
+	if ttl == 0 {
+		ttl = DefaultResultTTL
+	}
+	k.results = &resultCache{ttl: ttl, now: time.Now, entries: make(map[string]cachedResult)}
+}
+
+// resultCache holds the results of read-only tool calls by session, tool
+// and arguments.
+type resultCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedResult
+}
+
+type cachedResult struct {
+	result map[string]any
+	at     time.Time
+}
+
+// middleware reuses the results of read-only calls, and forgets them all
+// once a mutating call ran, in any session, since it may have changed what
+// they report.
+func (c *resultCache) middleware(t ToolInfo, next RunFunc) RunFunc {
+	switch {
+	case t.Category == CategoryMutating:
+		return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			defer c.clear()
+			return next(ctx, args)
+		}
+	case t.Category != CategoryReadOnly || Waits(t.Name):
+		return next
+	}
+	return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		// Replays and tests run tools without a tool.Context, so without a session
+		if sessionless(ctx) {
+			return next(ctx, args)
+		}
+		// The reason and timeout the model adds don't change the result
+		key := ctx.SessionID() + "\x00" + t.Name + "\x00" + argsKey(args)
+		if result, age, ok := c.get(key); ok {
+			result["cached"] = fmt.Sprintf("same result as an identical %s call %s ago; to see a change, wait with wait_for_condition or sleep first",
+				t.Name, age.Round(time.Second))
+			return result, nil
+		}
+
+		result, err := next(ctx, args)
+		if err == nil && result != nil && !errorResult(result) {
+			c.put(key, result)
+		}
+		return result, err
+	}
+}
+
+// get returns a copy of the result stored under key and its age, if it
+// hasn't expired.
+func (c *resultCache) get(key string) (map[string]any, time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, 0, false
+	}
+	age := c.now().Sub(e.at)
+	if age >= c.ttl {
+		delete(c.entries, key)
+		return nil, 0, false
+	}
+	return maps.Clone(e.result), age, true
+}
+
+// put stores a result and drops the expired ones.
+func (c *resultCache) put(key string, result map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for k, e := range c.entries {
+		if now.Sub(e.at) >= c.ttl {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cachedResult{result: maps.Clone(result), at: now}
+}
+
+func (c *resultCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/tool"
+)
+
+// sessionContext is a tool.Context that only knows its session.
+type sessionContext struct {
+	tool.Context
+	session string
+}
+
+func (c sessionContext) SessionID() string { return c.session }
+
+func TestResultCache(t *testing.T) {
+	var running, maxSeen atomic.Int32
+	pods := &slowTool{name: "list_pods", running: &running, maxSeen: &maxSeen}
+	wait := &slowTool{name: "wait_for_condition", running: &running, maxSeen: &maxSeen}
+	restart := &recordingTool{name: "restart_deployment"}
+
+	k := &KubeTools{}
+	k.SetResultCacheTTL(0)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	k.results.now = func() time.Time { return now }
+	wrapped := withMiddleware([]tool.Tool{pods, wait, restart}, k.results.middleware)
+	list, waitFor, mutate := wrapped[0].(runnableTool), wrapped[1].(runnableTool), wrapped[2].(runnableTool)
+
+	s1, s2 := sessionContext{session: "s1"}, sessionContext{session: "s2"}
+	args := map[string]any{"namespace": "prod", "label_selector": "app=web"}
+
+	if result, _ := list.Run(s1, args); result["cached"] != nil {
+		t.Fatal("first call marked as cached")
+	}
+	now = now.Add(10 * time.Second)
+	result, _ := list.Run(s1, map[string]any{"label_selector": "app=web", "namespace": "prod"})
+	if n := pods.calls.Load(); n != 1 {
+		t.Fatalf("identical call ran the tool again (%d calls)", n)
+	}
+	if note, _ := result["cached"].(string); !strings.Contains(note, "list_pods call 10s ago") {
+		t.Errorf("cached note = %q", note)
+	}
+
+	// A new reason or timeout doesn't make a call different
+	list.Run(s1, map[string]any{"namespace": "prod", "label_selector": "app=web", "reason": "Check the pods again", "timeout_seconds": 60})
+	if n := pods.calls.Load(); n != 1 {
+		t.Fatalf("call that only differs in reason ran the tool again (%d calls)", n)
+	}
+
+	// Other sessions, other arguments and tools that wait always run
+	list.Run(s2, args)
+	list.Run(s1, map[string]any{"namespace": "staging"})
+	waitFor.Run(s1, args)
+	waitFor.Run(s1, args)
+	if pods.calls.Load() != 3 || wait.calls.Load() != 2 {
+		t.Errorf("list_pods ran %d times, wait_for_condition %d times", pods.calls.Load(), wait.calls.Load())
+	}
+
+	// A mutating call in any session empties the cache
+	mutate.Run(s2, map[string]any{})
+	list.Run(s1, args)
+	if n := pods.calls.Load(); n != 4 {
+		t.Errorf("call after a mutation served from the cache (%d calls)", n)
+	}
+
+	// Results expire after the TTL
+	now = now.Add(DefaultResultTTL)
+	list.Run(s1, args)
+	if n := pods.calls.Load(); n != 5 {
+		t.Errorf("expired result served from the cache (%d calls)", n)
+	}
+
+	// Tests and replays without a tool.Context are never cached
+	list.Run(nil, args)
+	list.Run(nil, args)
+	if n := pods.calls.Load(); n != 7 {
+		t.Errorf("calls without a session were cached (%d calls)", n)
+	}
+}
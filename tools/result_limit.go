@@ -0,0 +1,275 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// DefaultMaxResultBytes is the size of a tool result, as JSON, above which
+// it is shaped to fit.
+const DefaultMaxResultBytes = 32 << 10
+
+// maxStringBytes is the length long strings (logs, embedded YAML) are cut to
+// when pruning fields isn't enough.
+const maxStringBytes = 2 << 10
+
+// Paging defaults for list tools.
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 500
+)
+
+// noisyFields are metadata fields that are dropped from oversized results
+// first; they are rarely useful to the agent and often most of the size.
+var noisyFields = []string{"managedFields", "resourceVersion", "uid", "selfLink", "generation"}
+
+// noisyAnnotations are annotations dropped along with noisyFields.
+var noisyAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"deployment.kubernetes.io/revision",
+}
+
+// SetMaxResultBytes sets the size limit for tool results. 0 uses
+// DefaultMaxResultBytes and a negative value disables the limit.
+func (k *KubeTools) SetMaxResultBytes(n int) {
+	k.maxResultBytes = n
+}
+
+// limitResults shapes the results of every tool to at most maxBytes of
+// JSON.
+func limitResults(maxBytes int) Middleware {
+	return func(_ ToolInfo, next RunFunc) RunFunc {
+		return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			result, err := next(ctx, args)
+			if err != nil || result == nil {
+				return result, err
+			}
+			return shapeResult(result, maxBytes), nil
+		}
+	}
+}
+
+// shapeResult returns result unchanged if it fits in maxBytes of JSON.
+// Otherwise it is reduced step by step until it fits: noisy metadata is
+// pruned, long strings are shortened, the largest lists are cut, and as a
+// last resort the JSON itself is truncated. The result says what was done
+// so the agent can narrow its query or page through the rest.
+func shapeResult(result map[string]any, maxBytes int) map[string]any {
+	data, err := json.Marshal(result)
+	if err != nil || len(data) <= maxBytes {
+		return result
+	}
+	originalSize := len(data)
+
+	// Work on a generic copy; tools may return structs or shared maps
+	var shaped map[string]any
+	if err := json.Unmarshal(data, &shaped); err != nil {
+		return result
+	}
+
+	var steps []string
+	fits := func() bool {
+		data, _ = json.Marshal(shaped)
+		return len(data) <= maxBytes
+	}
+
+	if pruneNoise(shaped) {
+		steps = append(steps, "dropped managedFields, resourceVersion, uid and last-applied annotations")
+	}
+	if !fits() && shortenStrings(shaped, maxStringBytes) {
+		steps = append(steps, fmt.Sprintf("shortened strings longer than %d bytes", maxStringBytes))
+	}
+	if !fits() {
+		for _, key := range listsBySize(shaped) {
+			list := shaped[key].([]any)
+			kept := len(list)
+			for kept > 1 && !fits() {
+				kept /= 2
+				shaped[key] = list[:kept]
+			}
+			steps = append(steps, fmt.Sprintf("showing the first %d of %d %s", kept, len(list), key))
+			if fits() {
+				break
+			}
+		}
+	}
+
+	if !fits() {
+		shaped = map[string]any{"truncated_result": string(data[:maxBytes])}
+		if errMsg, ok := result["error"]; ok {
+			shaped["error"] = errMsg
+		}
+		steps = append(steps, fmt.Sprintf("cut the JSON to %d bytes", maxBytes))
+	}
+
+	shaped["result_truncated"] = true
+	shaped["truncation"] = fmt.Sprintf("The result was %d bytes, over the %d byte limit, so kasa %s. "+
+		"Narrow the query (namespace, label_selector, a specific name) or use page/limit on list tools to see the rest.",
+		originalSize, maxBytes, strings.Join(steps, "; "))
+	return shaped
+}
+
+// pruneNoise removes noisyFields and noisyAnnotations from every object in
+// v. It reports whether anything was removed.
+func pruneNoise(v any) bool {
+	pruned := false
+	switch v := v.(type) {
+	case map[string]any:
+		for _, f := range noisyFields {
+			if _, ok := v[f]; ok {
+				delete(v, f)
+				pruned = true
+			}
+		}
+		if annotations, ok := v["annotations"].(map[string]any); ok {
+			for _, a := range noisyAnnotations {
+				if _, ok := annotations[a]; ok {
+					delete(annotations, a)
+					pruned = true
+				}
+			}
+		}
+		for _, child := range v {
+			pruned = pruneNoise(child) || pruned
+		}
+	case []any:
+		for _, child := range v {
+			pruned = pruneNoise(child) || pruned
+		}
+	}
+	return pruned
+}
+
+// shortenStrings cuts strings in v to limit bytes. It reports whether any
+// string was shortened.
+func shortenStrings(v any, limit int) bool {
+	shortened := false
+	shorten := func(s string) (string, bool) {
+		if len(s) <= limit {
+			return s, false
+		}
+		return fmt.Sprintf("%s... [%d more bytes]", s[:limit], len(s)-limit), true
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if s, ok := child.(string); ok {
+				if short, ok := shorten(s); ok {
+					v[k], shortened = short, true
+				}
+				continue
+			}
+			shortened = shortenStrings(child, limit) || shortened
+		}
+	case []any:
+		for i, child := range v {
+			if s, ok := child.(string); ok {
+				if short, ok := shorten(s); ok {
+					v[i], shortened = short, true
+				}
+				continue
+			}
+			shortened = shortenStrings(child, limit) || shortened
+		}
+	}
+	return shortened
+}
+
+// listsBySize returns the keys of the top-level lists in result, largest first.
+func listsBySize(result map[string]any) []string {
+	sizes := make(map[string]int)
+	for k, v := range result {
+		if list, ok := v.([]any); ok && len(list) > 1 {
+			data, _ := json.Marshal(list)
+			sizes[k] = len(data)
+		}
+	}
+	keys := make([]string, 0, len(sizes))
+	for k := range sizes {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if sizes[keys[i]] != sizes[keys[j]] {
+			return sizes[keys[i]] > sizes[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// pagingSchema adds the page and limit parameters of list tools to props.
+func pagingSchema(props map[string]*genai.Schema, defaultLimit int) map[string]*genai.Schema {
+	props["limit"] = &genai.Schema{
+		Type:        "integer",
+		Description: fmt.Sprintf("Maximum number of items to return (default %d, max %d)", defaultLimit, maxPageLimit),
+	}
+	props["page"] = &genai.Schema{
+		Type:        "string",
+		Description: "Continuation token from the next_page field of a previous call, to get the following items",
+	}
+	return props
+}
+
+// pagingArgs reads the page and limit arguments, defaulting limit to
+// defaultLimit.
+func pagingArgs(argsMap map[string]any, defaultLimit int) (page string, limit int, err error) {
+	page, _ = argsMap["page"].(string)
+	limit = defaultLimit
+	switch v := argsMap["limit"].(type) {
+	case nil:
+	case float64:
+		limit = int(v)
+	case int:
+		limit = v
+	case string:
+		if limit, err = strconv.Atoi(v); err != nil {
+			return "", 0, fmt.Errorf("limit must be a number, got %q", v)
+		}
+	default:
+		return "", 0, fmt.Errorf("limit must be a number")
+	}
+	if limit < 1 {
+		return "", 0, fmt.Errorf("limit must be at least 1")
+	}
+	return page, min(limit, maxPageLimit), nil
+}
+
+// paginate returns one page of items that were listed in memory, the token
+// for the next page ("" on the last page) and the number of items after
+// this page. Page tokens are offsets into items.
+func paginate[T any](items []T, page string, limit int) (pageItems []T, next string, remaining int, err error) {
+	offset := 0
+	if page != "" {
+		offset, err = strconv.Atoi(page)
+		if err != nil || offset < 0 {
+			return nil, "", 0, fmt.Errorf("invalid page token %q; use the next_page value of a previous call", page)
+		}
+	}
+	if offset >= len(items) {
+		return []T{}, "", 0, nil
+	}
+	end := min(offset+limit, len(items))
+	if end < len(items) {
+		next = strconv.Itoa(end)
+	}
+	return items[offset:end], next, len(items) - end, nil
+}
+
+// setNextPage records the continuation token in a list result. remaining
+// is the number of items after this page, or -1 if unknown.
+func setNextPage(result map[string]any, next string, remaining int64) {
+	if next == "" {
+		return
+	}
+	result["next_page"] = next
+	if remaining >= 0 {
+		result["remaining"] = remaining
+	}
+	result["more"] = "More items exist. Call again with the same arguments and page set to next_page to get them."
+}
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestShapeResult(t *testing.T) {
+	small := map[string]any{"name": "web"}
+	if got := shapeResult(small, 1024); got["result_truncated"] != nil {
+		t.Errorf("small result was shaped: %v", got)
+	}
+
+	// Pruning managedFields is enough
+	resource := map[string]any{
+		"resource": map[string]any{
+			"metadata": map[string]any{
+				"name":          "web",
+				"managedFields": []any{strings.Repeat("x", 4000)},
+				"annotations": map[string]any{
+					"kubectl.kubernetes.io/last-applied-configuration": strings.Repeat("y", 2000),
+					"team": "shop",
+				},
+			},
+		},
+	}
+	got := shapeResult(resource, 1024)
+	metadata := got["resource"].(map[string]any)["metadata"].(map[string]any)
+	if _, ok := metadata["managedFields"]; ok || metadata["name"] != "web" || metadata["annotations"].(map[string]any)["team"] != "shop" {
+		t.Errorf("metadata = %v", metadata)
+	}
+	if got["result_truncated"] != true || !strings.Contains(got["truncation"].(string), "dropped managedFields") {
+		t.Errorf("truncation = %v", got["truncation"])
+	}
+	if _, ok := resource["resource"].(map[string]any)["metadata"].(map[string]any)["managedFields"]; !ok {
+		t.Error("the tool's result was modified")
+	}
+
+	// Lists are cut to fit
+	items := make([]map[string]any, 200)
+	for i := range items {
+		items[i] = map[string]any{"name": strings.Repeat("p", 40)}
+	}
+	got = shapeResult(map[string]any{"pods": items, "count": 200}, 2048)
+	if pods := got["pods"].([]any); len(pods) == 0 || len(pods) >= 200 {
+		t.Errorf("got %d pods", len(pods))
+	}
+	if !strings.Contains(got["truncation"].(string), "of 200 pods") {
+		t.Errorf("truncation = %v", got["truncation"])
+	}
+	if data, _ := json.Marshal(got); len(data) > 2048+512 {
+		t.Errorf("shaped result is %d bytes", len(data))
+	}
+
+	// Anything else is cut as JSON, keeping the error
+	got = shapeResult(map[string]any{"error": "boom", "output": map[string]any{"a": strings.Repeat("z", 100), "b": strings.Repeat("z", 100)}}, 64)
+	if got["error"] != "boom" || len(got["truncated_result"].(string)) != 64 {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	page, next, remaining, err := paginate(items, "", 2)
+	if err != nil || len(page) != 2 || next != "2" || remaining != 3 {
+		t.Fatalf("first page = %v, %q, %d, %v", page, next, remaining, err)
+	}
+	page, next, remaining, _ = paginate(items, next, 2)
+	if page[0] != 3 || next != "4" || remaining != 1 {
+		t.Errorf("second page = %v, %q, %d", page, next, remaining)
+	}
+	page, next, remaining, _ = paginate(items, next, 2)
+	if len(page) != 1 || page[0] != 5 || next != "" || remaining != 0 {
+		t.Errorf("last page = %v, %q, %d", page, next, remaining)
+	}
+	if _, _, _, err := paginate(items, "abc", 2); err == nil {
+		t.Error("invalid token accepted")
+	}
+
+	if _, limit, _ := pagingArgs(map[string]any{"limit": float64(10000)}, 50); limit != maxPageLimit {
+		t.Errorf("limit = %d, want %d", limit, maxPageLimit)
+	}
+	if _, _, err := pagingArgs(map[string]any{"limit": float64(0)}, 50); err == nil {
+		t.Error("limit 0 accepted")
+	}
+}
@@ -0,0 +1,295 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/yaml"
+)
+
+// RollbackDeploymentTool provides the rollback_deployment tool for the agent.
+type RollbackDeploymentTool struct {
+	clientset *kubernetes.Clientset
+	manifest  *manifest.Manager
+}
+
+// NewRollbackDeploymentTool creates a new RollbackDeploymentTool.
+func NewRollbackDeploymentTool(clientset *kubernetes.Clientset, manifest *manifest.Manager) *RollbackDeploymentTool {
+	return &RollbackDeploymentTool{
+		clientset: clientset,
+		manifest:  manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *RollbackDeploymentTool) Name() string {
+	return "rollback_deployment"
+}
+
+// Description returns the tool description.
+func (t *RollbackDeploymentTool) Description() string {
+	return "Roll a deployment back to an earlier revision (like 'kubectl rollout undo --to-revision'): restores the pod template of that revision's ReplicaSet " +
+		"and puts the matching version of the stored manifest's pod template back, so git and the cluster don't diverge. Use deployment_history to find the revision. Without a revision, rolls back to the previous one. " +
+		"Check the rollout afterwards with check_deployment_health."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *RollbackDeploymentTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *RollbackDeploymentTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *RollbackDeploymentTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *RollbackDeploymentTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"name": {
+					Type:        "string",
+					Description: "The name of the deployment",
+				},
+				"revision": {
+					Type:        "integer",
+					Description: "The revision to roll back to, from deployment_history (default: the previous revision)",
+				},
+				"app": {
+					Type:        "string",
+					Description: "The app the stored manifest belongs to (default: the deployment name)",
+				},
+				"change_cause": changeCauseSchema,
+			},
+			Required: []string{"namespace", "name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *RollbackDeploymentTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, namespace, name, errResult := parseRolloutArgs(args)
+	if errResult != nil {
+		return errResult, nil
+	}
+	revision := 0
+	if r, ok := argsMap["revision"].(float64); ok {
+		revision = int(r)
+	}
+	app := name
+	if a, ok := argsMap["app"].(string); ok && a != "" {
+		app = a
+	}
+	return t.rollback(toolContext(ctx), namespace, name, app, revision, argsMap), nil
+}
+
+// rollback restores the pod template of revision (0 for the previous one)
+// on the live deployment and in its stored manifest. Returns the tool
+// response.
+func (t *RollbackDeploymentTool) rollback(ctx context.Context, namespace, name, app string, revision int, args map[string]any) map[string]any {
+	apiCtx, cancel := apiContext(ctx)
+	defer cancel()
+
+	deployment, err := t.clientset.AppsV1().Deployments(namespace).Get(apiCtx, name, metav1.GetOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to get deployment %s/%s: %v", namespace, name, err)}
+	}
+	replicaSets, err := deploymentReplicaSets(apiCtx, t.clientset, deployment)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	current, _ := strconv.Atoi(deployment.Annotations[revisionAnnotation])
+	target, err := rollbackTarget(deploymentRevisions(deployment, replicaSets), current, revision)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	var template corev1.PodTemplateSpec
+	for _, rs := range replicaSets {
+		if rs.Name == target.ReplicaSet {
+			template = *rs.Spec.Template.DeepCopy()
+		}
+	}
+	// The controller adds the hash label to the ReplicaSet template; the
+	// deployment's own template doesn't carry it
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+
+	cause := changeCause(args, fmt.Sprintf("rollback_deployment %s to revision %d", name, target.Revision))
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		dep, err := t.clientset.AppsV1().Deployments(namespace).Get(apiCtx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		dep.Spec.Template = template
+		setImageDigests(&dep.ObjectMeta, &dep.Spec.Template.Spec)
+		setChangeCause(ctx, dep, cause)
+		_, err = t.clientset.AppsV1().Deployments(namespace).Update(apiCtx, dep, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to update deployment %s/%s: %v", namespace, name, err)}
+	}
+
+	result := map[string]any{
+		"success":           true,
+		"namespace":         namespace,
+		"name":              name,
+		"revision":          target.Revision,
+		"previous_revision": current,
+		"images":            target.Images,
+	}
+	if t.manifest.IsDryRun() {
+		result["dry_run"] = true
+		result["message"] = fmt.Sprintf("Dry run: would roll deployment %s/%s back from revision %d to revision %d", namespace, name, current, target.Revision)
+		return result
+	}
+
+	manifestPath, version, err := t.restoreManifest(namespace, app, template)
+	if err != nil {
+		result["manifest_warning"] = fmt.Sprintf("Rolled back but failed to update the stored manifest: %v", err)
+	} else {
+		result["manifest_path"] = manifestPath
+		if version != "" {
+			result["manifest_version"] = version
+		}
+	}
+	result["message"] = fmt.Sprintf("Rolled deployment %s/%s back to revision %d (from %d); the rollout is in progress, check it with check_deployment_health", namespace, name, target.Revision, current)
+	return result
+}
+
+// rollbackTarget returns the revision to roll back to: the given one, or
+// the newest one before current if revision is 0.
+func rollbackTarget(revisions []RevisionInfo, current, revision int) (RevisionInfo, error) {
+	if revision == current && revision != 0 {
+		return RevisionInfo{}, fmt.Errorf("the deployment already runs revision %d", revision)
+	}
+	var available []int
+	for _, r := range revisions {
+		if r.Revision == revision || (revision == 0 && r.Revision < current) {
+			return r, nil
+		}
+		available = append(available, r.Revision)
+	}
+	if revision == 0 {
+		return RevisionInfo{}, fmt.Errorf("there is no revision before %d to roll back to", current)
+	}
+	return RevisionInfo{}, fmt.Errorf("revision %d not found (available: %v); older revisions are removed beyond the revision history limit", revision, available)
+}
+
+// restoreManifest puts the pod template of the revision rolled back to in
+// the stored deployment manifest of app. The template is taken from the
+// newest version of the manifest, on disk or in the git history, that
+// matches the ReplicaSet's, so it reads as it was written rather than with
+// the fields the API server defaulted; only when none matches is the
+// ReplicaSet's template stored. The rest of the manifest is left as is,
+// like the rest of the deployment. Returns the path of the saved manifest
+// and the commit the template was restored from, or "" if it wasn't.
+func (t *RollbackDeploymentTool) restoreManifest(namespace, app string, template corev1.PodTemplateSpec) (string, string, error) {
+	content, err := t.manifest.ReadManifest(namespace, app, "deployment")
+	if err != nil {
+		return "", "", fmt.Errorf("%v (use import_resource to start managing it)", err)
+	}
+	var stored map[string]any
+	if err := yaml.Unmarshal(content, &stored); err != nil {
+		return "", "", fmt.Errorf("failed to parse stored manifest: %v", err)
+	}
+	spec, ok := stored["spec"].(map[string]any)
+	if !ok {
+		return "", "", fmt.Errorf("stored manifest has no spec")
+	}
+
+	live, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&template)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to convert pod template: %v", err)
+	}
+	if metadata, ok := live["metadata"].(map[string]any); ok {
+		delete(metadata, "creationTimestamp")
+	}
+	restored, version := t.matchingTemplate(namespace, app, stored, live)
+	if restored == nil {
+		restored = live
+	}
+
+	spec["template"] = restored
+	setManifestImageDigests(stored)
+	updated, err := yaml.Marshal(stored)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal manifest: %v", err)
+	}
+	manifestPath, err := t.manifest.SaveManifest(namespace, app, "deployment", updated)
+	return manifestPath, version, err
+}
+
+// matchingTemplate returns the pod template of the newest version of the
+// stored manifest that matches live, starting with the current one, and
+// the commit of that version ("" for the current one). Returns nil if no
+// version matches.
+func (t *RollbackDeploymentTool) matchingTemplate(namespace, app string, current, live map[string]any) (map[string]any, string) {
+	if template := manifestTemplate(current); templateMatches(template, live) {
+		return template, ""
+	}
+	commits, err := t.manifest.History(namespace, app, time.Time{})
+	if err != nil {
+		return nil, ""
+	}
+	for _, c := range commits {
+		content, err := t.manifest.ReadManifestAt(c.Hash, namespace, app, "deployment")
+		if err != nil {
+			continue
+		}
+		var version map[string]any
+		if err := yaml.Unmarshal(content, &version); err != nil {
+			continue
+		}
+		if template := manifestTemplate(version); templateMatches(template, live) {
+			return template, c.Hash
+		}
+	}
+	return nil, ""
+}
+
+// manifestTemplate returns spec.template of a stored workload manifest.
+func manifestTemplate(obj map[string]any) map[string]any {
+	spec, _ := obj["spec"].(map[string]any)
+	template, _ := spec["template"].(map[string]any)
+	return template
+}
+
+// templateMatches reports whether a stored pod template describes the
+// live one: every field it sets has the live value, and the live template
+// only adds fields, which the API server defaulted.
+func templateMatches(stored, live map[string]any) bool {
+	if stored == nil {
+		return false
+	}
+	for _, d := range DiffMaps(stored, live, "") {
+		if d.ChangeType != "added" {
+			return false
+		}
+	}
+	return true
+}
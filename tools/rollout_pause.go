@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PauseRolloutTool provides the pause_rollout tool for the agent.
+type PauseRolloutTool struct {
+	clientset *kubernetes.Clientset
+}
+
+// NewPauseRolloutTool creates a new PauseRolloutTool.
+func NewPauseRolloutTool(clientset *kubernetes.Clientset) *PauseRolloutTool {
+	return &PauseRolloutTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *PauseRolloutTool) Name() string {
+	return "pause_rollout"
+}
+
+// Description returns the tool description.
+func (t *PauseRolloutTool) Description() string {
+	return "Pause a deployment's rollout (like 'kubectl rollout pause'). While paused, changes to the pod template are recorded but not rolled out, so several edits can be batched and released together with resume_rollout. The stored manifest is not changed."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *PauseRolloutTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *PauseRolloutTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *PauseRolloutTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *PauseRolloutTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"name": {
+					Type:        "string",
+					Description: "The name of the deployment",
+				},
+			},
+			Required: []string{"namespace", "name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *PauseRolloutTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	_, namespace, name, errResult := parseRolloutArgs(args)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	if err := setDeploymentPaused(toolContext(ctx), t.clientset, namespace, name, true, ""); err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	return map[string]any{
+		"success":   true,
+		"namespace": namespace,
+		"name":      name,
+		"paused":    true,
+		"message":   fmt.Sprintf("Paused rollout of deployment %s/%s; call resume_rollout to roll out pending changes", namespace, name),
+	}, nil
+}
+
+// parseRolloutArgs extracts the arguments, namespace and deployment name shared
+// by the rollout and revision tools. On failure it returns a tool
+// error result.
+func parseRolloutArgs(args any) (map[string]any, string, string, map[string]any) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return nil, "", "", map[string]any{"error": "invalid arguments format"}
+			}
+		} else {
+			return nil, "", "", map[string]any{"error": "invalid arguments type"}
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return nil, "", "", map[string]any{"error": "namespace is required"}
+	}
+
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return nil, "", "", map[string]any{"error": "name is required"}
+	}
+
+	return argsMap, namespace, name, nil
+}
+
+// setDeploymentPaused sets spec.paused on a deployment, recording cause as
+// its change-cause if set. Like kubectl, it refuses to pause a paused
+// deployment or resume one that isn't paused.
+func setDeploymentPaused(ctx context.Context, clientset kubernetes.Interface, namespace, name string, paused bool, cause string) error {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	dep, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get deployment %s/%s: %w", namespace, name, err)
+	}
+	if dep.Spec.Paused == paused {
+		if paused {
+			return fmt.Errorf("deployment %s/%s is already paused", namespace, name)
+		}
+		return fmt.Errorf("deployment %s/%s is not paused", namespace, name)
+	}
+
+	patchObj := map[string]any{"spec": map[string]any{"paused": paused}}
+	if cause != "" {
+		patchObj["metadata"] = map[string]any{"annotations": changeAnnotations(ctx, cause)}
+	}
+	patch, err := json.Marshal(patchObj)
+	if err != nil {
+		return fmt.Errorf("failed to build patch: %w", err)
+	}
+	if _, err := clientset.AppsV1().Deployments(namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to patch deployment %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
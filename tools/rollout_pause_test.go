@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSetDeploymentPaused(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+	})
+
+	paused := func() bool {
+		dep, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dep.Spec.Paused
+	}
+
+	if err := setDeploymentPaused(context.Background(), clientset, "default", "web", true, ""); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if !paused() {
+		t.Error("deployment not paused")
+	}
+	if err := setDeploymentPaused(context.Background(), clientset, "default", "web", true, ""); err == nil || !strings.Contains(err.Error(), "already paused") {
+		t.Errorf("expected already paused error, got %v", err)
+	}
+
+	if err := setDeploymentPaused(context.Background(), clientset, "default", "web", false, "bump memory limit"); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if paused() {
+		t.Error("deployment still paused")
+	}
+	dep, _ := clientset.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+	if got := dep.Annotations[changeCauseAnnotation]; got != "bump memory limit" {
+		t.Errorf("change-cause = %q, want it set on resume", got)
+	}
+	if err := setDeploymentPaused(context.Background(), clientset, "default", "web", false, ""); err == nil || !strings.Contains(err.Error(), "not paused") {
+		t.Errorf("expected not paused error, got %v", err)
+	}
+
+	if err := setDeploymentPaused(context.Background(), clientset, "default", "missing", true, ""); err == nil {
+		t.Error("expected error for missing deployment")
+	}
+}
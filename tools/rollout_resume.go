@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"fmt"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ResumeRolloutTool provides the resume_rollout tool for the agent.
+type ResumeRolloutTool struct {
+	clientset *kubernetes.Clientset
+}
+
+// NewResumeRolloutTool creates a new ResumeRolloutTool.
+func NewResumeRolloutTool(clientset *kubernetes.Clientset) *ResumeRolloutTool {
+	return &ResumeRolloutTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *ResumeRolloutTool) Name() string {
+	return "resume_rollout"
+}
+
+// Description returns the tool description.
+func (t *ResumeRolloutTool) Description() string {
+	return "Resume a paused deployment rollout (like 'kubectl rollout resume'). Changes made while the deployment was paused are rolled out together. Use check_deployment_health or wait_for_condition afterwards to follow the rollout."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *ResumeRolloutTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *ResumeRolloutTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *ResumeRolloutTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *ResumeRolloutTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"name": {
+					Type:        "string",
+					Description: "The name of the deployment",
+				},
+				"change_cause": changeCauseSchema,
+			},
+			Required: []string{"namespace", "name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *ResumeRolloutTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, namespace, name, errResult := parseRolloutArgs(args)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	cause := changeCause(argsMap, "resume_rollout "+name)
+	if err := setDeploymentPaused(toolContext(ctx), t.clientset, namespace, name, false, cause); err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	return map[string]any{
+		"success":   true,
+		"namespace": namespace,
+		"name":      name,
+		"paused":    false,
+		"message":   fmt.Sprintf("Resumed rollout of deployment %s/%s", namespace, name),
+	}, nil
+}
@@ -119,7 +119,7 @@ func (t *SearchWebTool) Run(ctx tool.Context, args any) (map[string]any, error)
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequest("POST", "https://api.tavily.com/search", bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(toolContext(ctx), "POST", "https://api.tavily.com/search", bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return map[string]any{"error": fmt.Sprintf("failed to create request: %v", err)}, nil
 	}
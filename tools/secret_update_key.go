@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	"k8s.io/client-go/kubernetes"
+)
+
+// UpdateSecretKeyTool provides the update_secret_key tool for the agent.
+type UpdateSecretKeyTool struct {
+	clientset *kubernetes.Clientset
+	manifest  *manifest.Manager
+}
+
+// NewUpdateSecretKeyTool creates a new UpdateSecretKeyTool.
+func NewUpdateSecretKeyTool(clientset *kubernetes.Clientset, manifest *manifest.Manager) *UpdateSecretKeyTool {
+	return &UpdateSecretKeyTool{
+		clientset: clientset,
+		manifest:  manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *UpdateSecretKeyTool) Name() string {
+	return "update_secret_key"
+}
+
+// Description returns the tool description.
+func (t *UpdateSecretKeyTool) Description() string {
+	return "Set or remove a single key in an existing Secret with a strategic merge patch, leaving its other keys alone, and update the stored manifest to match. The value is given in plaintext and never returned. Lists the deployments using the Secret and can rollout restart them so they pick up the change."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *UpdateSecretKeyTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *UpdateSecretKeyTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *UpdateSecretKeyTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *UpdateSecretKeyTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type:       "object",
+			Properties: configKeyProperties("Secret"),
+			Required:   []string{"namespace", "name", "key"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *UpdateSecretKeyTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	u, errResult := parseConfigKeyArgs(args, "secret")
+	if errResult != nil {
+		return errResult, nil
+	}
+	return updateConfigKey(toolContext(ctx), t.clientset, t.manifest, u), nil
+}
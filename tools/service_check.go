@@ -0,0 +1,389 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ServicePortCheck describes how one Service port maps onto the selected pods.
+type ServicePortCheck struct {
+	Name       string   `json:"name,omitempty"`
+	Port       int32    `json:"port"`
+	TargetPort string   `json:"target_port"`
+	Protocol   string   `json:"protocol"`
+	Resolved   []int32  `json:"resolved_container_ports,omitempty"` // distinct ports targetPort resolves to
+	Problems   []string `json:"problems,omitempty"`
+}
+
+// EndpointSliceInfo summarizes an EndpointSlice of a Service.
+type EndpointSliceInfo struct {
+	Name        string   `json:"name"`
+	AddressType string   `json:"address_type"`
+	Ports       []string `json:"ports,omitempty"`
+	Ready       []string `json:"ready,omitempty"`
+	NotReady    []string `json:"not_ready,omitempty"`
+	Terminating []string `json:"terminating,omitempty"`
+}
+
+// CheckServiceTool provides the check_service tool for the agent.
+type CheckServiceTool struct {
+	clientset *kubernetes.Clientset
+}
+
+// NewCheckServiceTool creates a new CheckServiceTool.
+func NewCheckServiceTool(clientset *kubernetes.Clientset) *CheckServiceTool {
+	return &CheckServiceTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *CheckServiceTool) Name() string {
+	return "check_service"
+}
+
+// Description returns the tool description.
+func (t *CheckServiceTool) Description() string {
+	return "Diagnose why a Service is not reachable: checks that its selector matches pods (and which label doesn't when none match), how many of them are ready, what its EndpointSlices contain, and whether each targetPort resolves to a port the pods' containers declare. Use probe_service afterwards to test the ClusterIP from inside the cluster."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *CheckServiceTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *CheckServiceTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *CheckServiceTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *CheckServiceTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"name": {
+					Type:        "string",
+					Description: "Name of the Service",
+				},
+			},
+			Required: []string{"namespace", "name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *CheckServiceTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+
+	return checkService(toolContext(ctx), t.clientset, namespace, name), nil
+}
+
+// checkService builds the check_service response for a Service.
+func checkService(ctx context.Context, clientset kubernetes.Interface, namespace, name string) map[string]any {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	svc, err := clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to get service: %v", err)}
+	}
+
+	result := map[string]any{
+		"namespace":  namespace,
+		"service":    name,
+		"type":       string(svc.Spec.Type),
+		"cluster_ip": svc.Spec.ClusterIP,
+		"selector":   svc.Spec.Selector,
+	}
+	if svc.Spec.Type == corev1.ServiceTypeExternalName {
+		result["external_name"] = svc.Spec.ExternalName
+		result["ok"] = true
+		result["summary"] = fmt.Sprintf("Service %s is an ExternalName alias for %s; it has no pods or endpoints to check", name, svc.Spec.ExternalName)
+		return result
+	}
+
+	var issues []string
+	var pods []corev1.Pod
+	if len(svc.Spec.Selector) == 0 {
+		issues = append(issues, "Service has no selector, so Kubernetes doesn't manage its endpoints; they must be created by hand or by another controller")
+	} else {
+		all, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return map[string]any{"error": fmt.Sprintf("failed to list pods: %v", err)}
+		}
+		selector := labels.SelectorFromSet(svc.Spec.Selector)
+		for _, pod := range all.Items {
+			if selector.Matches(labels.Set(pod.Labels)) && !podFinished(&pod) {
+				pods = append(pods, pod)
+			}
+		}
+		if len(pods) == 0 {
+			issues = append(issues, selectorMismatch(svc.Spec.Selector, all.Items))
+		}
+	}
+
+	var ready, notReady []string
+	for _, pod := range pods {
+		if podReady(&pod) {
+			ready = append(ready, pod.Name)
+		} else {
+			notReady = append(notReady, pod.Name)
+		}
+	}
+	result["matching_pods"] = map[string]any{
+		"total":     len(pods),
+		"ready":     ready,
+		"not_ready": notReady,
+	}
+	if len(pods) > 0 && len(ready) == 0 {
+		issues = append(issues, fmt.Sprintf("None of the %d selected pods are ready, so the Service has no ready endpoints; check readiness probes with get_logs/get_events", len(pods)))
+	}
+
+	ports := make([]ServicePortCheck, 0, len(svc.Spec.Ports))
+	for _, sp := range svc.Spec.Ports {
+		check := checkServicePort(sp, pods)
+		for _, p := range check.Problems {
+			issues = append(issues, fmt.Sprintf("port %d: %s", sp.Port, p))
+		}
+		ports = append(ports, check)
+	}
+	result["ports"] = ports
+
+	endpointSlices, err := clientset.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + name,
+	})
+	if err != nil {
+		issues = append(issues, fmt.Sprintf("could not list EndpointSlices: %v", err))
+	} else {
+		infos := make([]EndpointSliceInfo, 0, len(endpointSlices.Items))
+		readyEndpoints := 0
+		for _, s := range endpointSlices.Items {
+			info := summarizeEndpointSlice(s)
+			readyEndpoints += len(info.Ready)
+			infos = append(infos, info)
+		}
+		result["endpoint_slices"] = infos
+		if readyEndpoints == 0 && len(ready) > 0 {
+			issues = append(issues, fmt.Sprintf("%d pods are ready but no EndpointSlice lists a ready endpoint; the endpoint controller may be lagging or the pods lack IPs", len(ready)))
+		}
+	}
+
+	result["ok"] = len(issues) == 0
+	if len(issues) > 0 {
+		result["issues"] = issues
+		result["summary"] = issues[0]
+	} else {
+		result["summary"] = fmt.Sprintf("Service %s selects %d ready pod(s) and its ports resolve; if it is still unreachable, test it with probe_service or check NetworkPolicies", name, len(ready))
+	}
+	return result
+}
+
+// selectorMismatch explains which selector labels no pod in the namespace has.
+func selectorMismatch(selector map[string]string, pods []corev1.Pod) string {
+	keys := make([]string, 0, len(selector))
+	for k := range selector {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var missing []string
+	for _, k := range keys {
+		found := false
+		var seen []string
+		for _, pod := range pods {
+			if v, ok := pod.Labels[k]; ok {
+				if v == selector[k] {
+					found = true
+					break
+				}
+				if !slices.Contains(seen, v) {
+					seen = append(seen, v)
+				}
+			}
+		}
+		if found {
+			continue
+		}
+		if len(seen) > 0 {
+			missing = append(missing, fmt.Sprintf("%s=%s (pods have %s=%s)", k, selector[k], k, strings.Join(seen, "|")))
+		} else {
+			missing = append(missing, fmt.Sprintf("%s=%s (no pod has label %s)", k, selector[k], k))
+		}
+	}
+	if len(missing) == 0 {
+		return "Selector matches no running pod: each label matches some pod, but no single pod has all of them"
+	}
+	return "Selector matches no running pod; no pod has " + strings.Join(missing, ", ")
+}
+
+// podReady reports whether the pod's Ready condition is True.
+func podReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// checkServicePort resolves a Service port's targetPort against the
+// containers of the selected pods.
+func checkServicePort(sp corev1.ServicePort, pods []corev1.Pod) ServicePortCheck {
+	target := sp.TargetPort
+	if target.Type == intstr.Int && target.IntVal == 0 {
+		target = intstr.FromInt32(sp.Port)
+	}
+	protocol := sp.Protocol
+	if protocol == "" {
+		protocol = corev1.ProtocolTCP
+	}
+	check := ServicePortCheck{
+		Name:       sp.Name,
+		Port:       sp.Port,
+		TargetPort: target.String(),
+		Protocol:   string(protocol),
+	}
+
+	var unresolved, undeclared []string
+	for _, pod := range pods {
+		port, declared, ok := resolveTargetPort(target, protocol, &pod)
+		switch {
+		case !ok:
+			unresolved = append(unresolved, pod.Name)
+		case !declared:
+			undeclared = append(undeclared, pod.Name)
+		default:
+			if !slices.Contains(check.Resolved, port) {
+				check.Resolved = append(check.Resolved, port)
+			}
+		}
+	}
+	if len(unresolved) > 0 {
+		check.Problems = append(check.Problems, fmt.Sprintf("targetPort %q is not a named %s container port in %s; traffic to these pods is dropped", target.String(), protocol, describePods(unresolved)))
+	}
+	if len(undeclared) > 0 {
+		check.Problems = append(check.Problems, fmt.Sprintf("targetPort %s is not a declared containerPort in %s; make sure the app listens on it", target.String(), describePods(undeclared)))
+	}
+	if len(check.Resolved) > 1 {
+		check.Problems = append(check.Problems, fmt.Sprintf("targetPort %q resolves to different container ports across pods (%v)", target.String(), check.Resolved))
+	}
+	return check
+}
+
+// resolveTargetPort returns the container port target refers to in pod, and
+// whether a container declares it. ok is false for a named port that no
+// container has.
+func resolveTargetPort(target intstr.IntOrString, protocol corev1.Protocol, pod *corev1.Pod) (port int32, declared, ok bool) {
+	hasPorts := false
+	for _, c := range pod.Spec.Containers {
+		for _, cp := range c.Ports {
+			hasPorts = true
+			cpProtocol := cp.Protocol
+			if cpProtocol == "" {
+				cpProtocol = corev1.ProtocolTCP
+			}
+			if cpProtocol != protocol {
+				continue
+			}
+			if target.Type == intstr.String && cp.Name == target.StrVal {
+				return cp.ContainerPort, true, true
+			}
+			if target.Type == intstr.Int && cp.ContainerPort == target.IntVal {
+				return cp.ContainerPort, true, true
+			}
+		}
+	}
+	if target.Type == intstr.String {
+		return 0, false, false
+	}
+	// containerPort is informational; only flag it when the pod declares ports
+	return target.IntVal, !hasPorts, true
+}
+
+func describePods(names []string) string {
+	if len(names) > 3 {
+		return fmt.Sprintf("pods %s and %d more", strings.Join(names[:3], ", "), len(names)-3)
+	}
+	return "pods " + strings.Join(names, ", ")
+}
+
+// summarizeEndpointSlice groups an EndpointSlice's addresses by condition.
+func summarizeEndpointSlice(s discoveryv1.EndpointSlice) EndpointSliceInfo {
+	info := EndpointSliceInfo{
+		Name:        s.Name,
+		AddressType: string(s.AddressType),
+	}
+	for _, p := range s.Ports {
+		var port, name, protocol string
+		if p.Port != nil {
+			port = fmt.Sprintf("%d", *p.Port)
+		}
+		if p.Name != nil && *p.Name != "" {
+			name = *p.Name + ":"
+		}
+		if p.Protocol != nil {
+			protocol = "/" + string(*p.Protocol)
+		}
+		info.Ports = append(info.Ports, name+port+protocol)
+	}
+	for _, e := range s.Endpoints {
+		addr := strings.Join(e.Addresses, ",")
+		if e.TargetRef != nil {
+			addr += " (" + e.TargetRef.Name + ")"
+		}
+		switch {
+		case e.Conditions.Terminating != nil && *e.Conditions.Terminating:
+			info.Terminating = append(info.Terminating, addr)
+		case e.Conditions.Ready == nil || *e.Conditions.Ready:
+			info.Ready = append(info.Ready, addr)
+		default:
+			info.NotReady = append(info.NotReady, addr)
+		}
+	}
+	return info
+}
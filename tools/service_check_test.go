@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func serviceTestPod(name string, labels map[string]string, ready bool, ports ...corev1.ContainerPort) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Ports: ports}}},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func TestCheckService(t *testing.T) {
+	service := func(selector map[string]string, target intstr.IntOrString) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: corev1.ServiceSpec{
+				Type:      corev1.ServiceTypeClusterIP,
+				ClusterIP: "10.0.0.10",
+				Selector:  selector,
+				Ports:     []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: target}},
+			},
+		}
+	}
+	ready := true
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta:  metav1.ObjectMeta{Name: "web-abc", Namespace: "default", Labels: map[string]string{discoveryv1.LabelServiceName: "web"}},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints: []discoveryv1.Endpoint{{
+			Addresses:  []string{"10.1.0.5"},
+			Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+			TargetRef:  &corev1.ObjectReference{Kind: "Pod", Name: "web-1"},
+		}},
+	}
+	httpPort := corev1.ContainerPort{Name: "http", ContainerPort: 8080}
+
+	tests := []struct {
+		name   string
+		svc    *corev1.Service
+		pods   []*corev1.Pod
+		ok     bool
+		issues []string
+	}{
+		{
+			name: "healthy",
+			svc:  service(map[string]string{"app": "web"}, intstr.FromString("http")),
+			pods: []*corev1.Pod{serviceTestPod("web-1", map[string]string{"app": "web"}, true, httpPort)},
+			ok:   true,
+		},
+		{
+			name:   "selector label mismatch",
+			svc:    service(map[string]string{"app": "web", "tier": "frontend"}, intstr.FromString("http")),
+			pods:   []*corev1.Pod{serviceTestPod("web-1", map[string]string{"app": "web", "tier": "backend"}, true, httpPort)},
+			issues: []string{"tier=frontend (pods have tier=backend)"},
+		},
+		{
+			name:   "named target port missing",
+			svc:    service(map[string]string{"app": "web"}, intstr.FromString("web")),
+			pods:   []*corev1.Pod{serviceTestPod("web-1", map[string]string{"app": "web"}, true, httpPort)},
+			issues: []string{`targetPort "web" is not a named TCP container port in pods web-1`},
+		},
+		{
+			name:   "numeric target port not declared",
+			svc:    service(map[string]string{"app": "web"}, intstr.FromInt32(80)),
+			pods:   []*corev1.Pod{serviceTestPod("web-1", map[string]string{"app": "web"}, true, httpPort)},
+			issues: []string{"targetPort 80 is not a declared containerPort in pods web-1"},
+		},
+		{
+			name:   "no ready pods",
+			svc:    service(map[string]string{"app": "web"}, intstr.FromString("http")),
+			pods:   []*corev1.Pod{serviceTestPod("web-1", map[string]string{"app": "web"}, false, httpPort)},
+			issues: []string{"None of the 1 selected pods are ready"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(tt.svc, slice)
+			for _, p := range tt.pods {
+				if err := clientset.Tracker().Add(p); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result := checkService(context.Background(), clientset, "default", "web")
+			if _, failed := result["error"]; failed {
+				t.Fatalf("checkService failed: %v", result["error"])
+			}
+			if result["ok"] != tt.ok {
+				t.Errorf("ok = %v, want %v (issues: %v)", result["ok"], tt.ok, result["issues"])
+			}
+			issues, _ := result["issues"].([]string)
+			joined := strings.Join(issues, "\n")
+			for _, want := range tt.issues {
+				if !strings.Contains(joined, want) {
+					t.Errorf("issues %q should contain %q", joined, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProbeURL(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.0.0.20",
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80},
+				{Name: "https", Port: 443},
+			},
+		},
+	}
+	tests := []struct {
+		port, path, want string
+	}{
+		{"", "", "http://10.0.0.20:80/"},
+		{"https", "healthz", "https://10.0.0.20:443/healthz"},
+		{"80", "/ready", "http://10.0.0.20:80/ready"},
+	}
+	for _, tt := range tests {
+		got, err := probeURL(svc, tt.port, tt.path)
+		if err != nil || got != tt.want {
+			t.Errorf("probeURL(%q, %q) = %q, %v; want %q", tt.port, tt.path, got, err, tt.want)
+		}
+	}
+
+	svc.Spec.ClusterIP = corev1.ClusterIPNone
+	if got, _ := probeURL(svc, "", ""); got != "http://api.shop.svc:80/" {
+		t.Errorf("headless service should be probed by DNS name, got %q", got)
+	}
+	if _, err := probeURL(svc, "grpc", ""); err == nil {
+		t.Error("unknown port should fail")
+	}
+}
+
+func TestProbeResult(t *testing.T) {
+	result := probeResult("http://10.0.0.20:80/", 0, "200 0.012")
+	if result["reachable"] != true || result["http_status"] != "200" {
+		t.Errorf("success result = %v", result)
+	}
+	result = probeResult("http://10.0.0.20:80/", 28, "curl: (28) Connection timed out after 5001 milliseconds\n000 5.001")
+	if result["reachable"] != false || !strings.Contains(result["summary"].(string), "timed out") {
+		t.Errorf("timeout result = %v", result)
+	}
+}
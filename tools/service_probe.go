@@ -0,0 +1,302 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// probeImage is the image of the ephemeral pod probe_service runs curl in.
+const probeImage = "curlimages/curl:8.10.1"
+
+// probeStartupTimeout bounds how long the probe pod may take to be
+// scheduled and pull its image, on top of the request timeout.
+const probeStartupTimeout = 60 * time.Second
+
+// curlExitCodes explains the curl exit codes a connectivity probe can hit.
+var curlExitCodes = map[int32]string{
+	6:  "could not resolve the host (DNS)",
+	7:  "connection refused or no route to the endpoints",
+	28: "timed out; nothing answered (often a NetworkPolicy or a pod not listening)",
+	35: "TLS handshake failed",
+	52: "connected but got an empty reply; the port is open but doesn't speak HTTP",
+	56: "connected but the connection was reset",
+}
+
+// ProbeServiceTool provides the probe_service tool for the agent.
+type ProbeServiceTool struct {
+	clientset *kubernetes.Clientset
+}
+
+// NewProbeServiceTool creates a new ProbeServiceTool.
+func NewProbeServiceTool(clientset *kubernetes.Clientset) *ProbeServiceTool {
+	return &ProbeServiceTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *ProbeServiceTool) Name() string {
+	return "probe_service"
+}
+
+// Description returns the tool description.
+func (t *ProbeServiceTool) Description() string {
+	return "Test a Service from inside the cluster: runs a short-lived curl pod in the Service's namespace against its ClusterIP (or DNS name for headless Services), reports the HTTP status or why the connection failed, then deletes the pod. Run check_service first; use this when the static checks pass but clients still can't connect."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *ProbeServiceTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *ProbeServiceTool) Category() ToolCategory {
+	return CategoryMutating // creates a pod
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *ProbeServiceTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *ProbeServiceTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"name": {
+					Type:        "string",
+					Description: "Name of the Service",
+				},
+				"port": {
+					Type:        "string",
+					Description: "Service port name or number (default: the first port)",
+				},
+				"path": {
+					Type:        "string",
+					Description: "HTTP path to request (default: /)",
+				},
+				"timeout": {
+					Type:        "integer",
+					Description: "Request timeout in seconds (default: 5, max: 30)",
+				},
+			},
+			Required: []string{"namespace", "name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *ProbeServiceTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+	port, _ := argsMap["port"].(string)
+	if p, ok := argsMap["port"].(float64); ok {
+		port = strconv.Itoa(int(p))
+	}
+	path, _ := argsMap["path"].(string)
+
+	timeout := 5
+	if to, ok := argsMap["timeout"].(float64); ok {
+		timeout = int(to)
+	}
+	timeout = max(1, min(timeout, 30))
+
+	timeoutCtx, cancel := context.WithTimeout(toolContext(ctx), probeStartupTimeout+time.Duration(timeout+10)*time.Second)
+	defer cancel()
+
+	svc, err := t.clientset.CoreV1().Services(namespace).Get(timeoutCtx, name, metav1.GetOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to get service: %v", err)}, nil
+	}
+	url, err := probeURL(svc, port, path)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	pod, err := t.clientset.CoreV1().Pods(namespace).Create(timeoutCtx, probePod(namespace, url, timeout), metav1.CreateOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to create probe pod: %v", err)}, nil
+	}
+	defer func() {
+		// Use a fresh context so the pod is removed even after a timeout
+		deleteCtx, cancel := context.WithTimeout(toolContext(ctx), 10*time.Second)
+		defer cancel()
+		grace := int64(0)
+		_ = t.clientset.CoreV1().Pods(namespace).Delete(deleteCtx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: &grace})
+	}()
+
+	for {
+		pod, err = t.clientset.CoreV1().Pods(namespace).Get(timeoutCtx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return map[string]any{"error": fmt.Sprintf("probe pod did not finish: %v", err), "url": url}, nil
+		}
+		if podFinished(pod) {
+			break
+		}
+		select {
+		case <-timeoutCtx.Done():
+			return map[string]any{"error": "probe pod did not finish in time; check that it can be scheduled and can pull " + probeImage, "url": url}, nil
+		case <-time.After(time.Second):
+		}
+	}
+
+	logs, err := t.clientset.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).DoRaw(timeoutCtx)
+	if err != nil {
+		logs = []byte(fmt.Sprintf("failed to get probe output: %v", err))
+	}
+	var exitCode int32
+	for _, s := range pod.Status.ContainerStatuses {
+		if s.State.Terminated != nil {
+			exitCode = s.State.Terminated.ExitCode
+		}
+	}
+	return probeResult(url, exitCode, strings.TrimSpace(string(logs))), nil
+}
+
+// probeURL builds the URL curl requests for a Service port, given by name
+// or number, or the first port if empty.
+func probeURL(svc *corev1.Service, port, path string) (string, error) {
+	if len(svc.Spec.Ports) == 0 {
+		return "", fmt.Errorf("service %s has no ports", svc.Name)
+	}
+	sp := svc.Spec.Ports[0]
+	if port != "" {
+		found := false
+		for _, p := range svc.Spec.Ports {
+			if p.Name == port || strconv.Itoa(int(p.Port)) == port {
+				sp, found = p, true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("service %s has no port %q", svc.Name, port)
+		}
+	}
+	if sp.Protocol != "" && sp.Protocol != corev1.ProtocolTCP {
+		return "", fmt.Errorf("port %d is %s; only TCP ports can be probed", sp.Port, sp.Protocol)
+	}
+
+	host := svc.Spec.ClusterIP
+	if host == "" || host == corev1.ClusterIPNone {
+		host = fmt.Sprintf("%s.%s.svc", svc.Name, svc.Namespace)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	scheme := "http"
+	if sp.Port == 443 || strings.Contains(sp.Name, "https") || (sp.AppProtocol != nil && *sp.AppProtocol == "https") {
+		scheme = "https"
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return fmt.Sprintf("%s://%s:%d%s", scheme, host, sp.Port, path), nil
+}
+
+// probePod returns a pod that requests url once with curl and exits. It
+// satisfies the restricted Pod Security Standard.
+func probePod(namespace, url string, timeout int) *corev1.Pod {
+	yes, no := true, false
+	grace, uid := int64(0), int64(100) // curl_user in the curl image
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "kasa-probe-",
+			Namespace:    namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "kasa-probe",
+				"app.kubernetes.io/managed-by": "kasa",
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:                 corev1.RestartPolicyNever,
+			AutomountServiceAccountToken:  &no,
+			TerminationGracePeriodSeconds: &grace,
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsNonRoot:   &yes,
+				RunAsUser:      &uid,
+				SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+			},
+			Containers: []corev1.Container{{
+				Name:  "curl",
+				Image: probeImage,
+				Args: []string{
+					"-sS", "-k", "-o", "/dev/null",
+					"-w", "%{http_code} %{time_total}\n",
+					"--max-time", strconv.Itoa(timeout),
+					url,
+				},
+				SecurityContext: &corev1.SecurityContext{
+					AllowPrivilegeEscalation: &no,
+					Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+				},
+			}},
+		},
+	}
+}
+
+// probeResult interprets curl's exit code and output.
+func probeResult(url string, exitCode int32, output string) map[string]any {
+	result := map[string]any{
+		"url":       url,
+		"exit_code": exitCode,
+		"output":    output,
+	}
+	if exitCode != 0 {
+		reason, ok := curlExitCodes[exitCode]
+		if !ok {
+			reason = "request failed"
+		}
+		// An open port that isn't HTTP still proves the Service routes traffic
+		result["reachable"] = exitCode == 52 || exitCode == 56
+		result["summary"] = fmt.Sprintf("%s: %s", url, reason)
+		return result
+	}
+
+	// Output is "<status> <seconds>", after any curl error lines
+	lines := strings.Split(output, "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	result["reachable"] = true
+	if len(fields) == 2 {
+		result["http_status"] = fields[0]
+		result["time_seconds"] = fields[1]
+		result["summary"] = fmt.Sprintf("%s answered HTTP %s in %ss", url, fields[0], fields[1])
+	} else {
+		result["summary"] = fmt.Sprintf("%s answered", url)
+	}
+	return result
+}
@@ -0,0 +1,211 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// CreateServiceAccountTool provides the create_serviceaccount tool for the agent.
+type CreateServiceAccountTool struct {
+	clientset *kubernetes.Clientset
+	manifest  *manifest.Manager
+}
+
+// NewCreateServiceAccountTool creates a new CreateServiceAccountTool.
+func NewCreateServiceAccountTool(clientset *kubernetes.Clientset, manifest *manifest.Manager) *CreateServiceAccountTool {
+	return &CreateServiceAccountTool{
+		clientset: clientset,
+		manifest:  manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *CreateServiceAccountTool) Name() string {
+	return "create_serviceaccount"
+}
+
+// Description returns the tool description.
+func (t *CreateServiceAccountTool) Description() string {
+	return "Create or update a ServiceAccount, optionally with image pull secrets (so its pods can pull from a private registry) and annotations such as cloud workload identity bindings. Saves the manifest to git and applies it to the cluster."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *CreateServiceAccountTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *CreateServiceAccountTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *CreateServiceAccountTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *CreateServiceAccountTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"name": {
+					Type:        "string",
+					Description: "The name of the ServiceAccount",
+				},
+				"namespace": {
+					Type:        "string",
+					Description: "The target Kubernetes namespace",
+				},
+				"image_pull_secrets": {
+					Type:        "array",
+					Description: "Names of dockerconfigjson Secrets pods using this account pull images with",
+					Items:       &genai.Schema{Type: "string"},
+				},
+				"automount_token": {
+					Type:        "boolean",
+					Description: "Whether pods mount an API token for this account (default: cluster default, which is true)",
+				},
+				"annotations": {
+					Type:        "object",
+					Description: "Optional annotations (e.g., eks.amazonaws.com/role-arn)",
+				},
+			},
+			Required: []string{"name", "namespace"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *CreateServiceAccountTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+
+	annotations := map[string]string{}
+	if customAnnotations, ok := argsMap["annotations"].(map[string]any); ok {
+		for k, v := range customAnnotations {
+			if vs, ok := v.(string); ok {
+				annotations[k] = vs
+			}
+		}
+	}
+
+	sa := &corev1.ServiceAccount{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ServiceAccount",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       name,
+				"app.kubernetes.io/managed-by": "kasa",
+			},
+			Annotations: annotations,
+		},
+	}
+	pullSecrets := stringArg(argsMap, "image_pull_secrets")
+	for _, s := range pullSecrets {
+		addImagePullSecret(sa, s)
+	}
+	if automount, ok := argsMap["automount_token"].(bool); ok {
+		sa.AutomountServiceAccountToken = &automount
+	}
+
+	yamlBytes, err := yaml.Marshal(sa)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to marshal serviceaccount: %v", err)}, nil
+	}
+	manifestPath, err := t.manifest.SaveManifest(namespace, name, "serviceaccount", yamlBytes)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to save manifest: %v", err)}, nil
+	}
+
+	timeoutCtx, cancel := apiContext(toolContext(ctx))
+	defer cancel()
+
+	var action string
+	existing, err := t.clientset.CoreV1().ServiceAccounts(namespace).Get(timeoutCtx, name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return map[string]any{"error": fmt.Sprintf("failed to check existing serviceaccount: %v", err)}, nil
+		}
+		if _, err := t.clientset.CoreV1().ServiceAccounts(namespace).Create(timeoutCtx, sa, metav1.CreateOptions{}); err != nil {
+			return map[string]any{"error": fmt.Sprintf("failed to create serviceaccount: %v", err)}, nil
+		}
+		action = "created"
+	} else {
+		// Keep token secrets the control plane linked to the account
+		sa.Secrets = existing.Secrets
+		sa.ResourceVersion = existing.ResourceVersion
+		if _, err := t.clientset.CoreV1().ServiceAccounts(namespace).Update(timeoutCtx, sa, metav1.UpdateOptions{}); err != nil {
+			return map[string]any{"error": fmt.Sprintf("failed to update serviceaccount: %v", err)}, nil
+		}
+		action = "updated"
+	}
+
+	result := map[string]any{
+		"success":       true,
+		"action":        action,
+		"name":          name,
+		"namespace":     namespace,
+		"manifest_path": manifestPath,
+		"message":       fmt.Sprintf("ServiceAccount %s %s in namespace %s", name, action, namespace),
+	}
+	if len(pullSecrets) > 0 {
+		result["image_pull_secrets"] = pullSecrets
+		var missing []string
+		for _, s := range pullSecrets {
+			if _, err := t.clientset.CoreV1().Secrets(namespace).Get(timeoutCtx, s, metav1.GetOptions{}); errors.IsNotFound(err) {
+				missing = append(missing, s)
+			}
+		}
+		if len(missing) > 0 {
+			result["warning"] = fmt.Sprintf("image pull secret(s) %v do not exist in %s yet; create them with create_image_pull_secret", missing, namespace)
+		}
+	}
+	return result, nil
+}
+
+// addImagePullSecret adds a pull secret to a ServiceAccount unless it is
+// already listed. Returns true if it was added.
+func addImagePullSecret(sa *corev1.ServiceAccount, secret string) bool {
+	for _, ref := range sa.ImagePullSecrets {
+		if ref.Name == secret {
+			return false
+		}
+	}
+	sa.ImagePullSecrets = append(sa.ImagePullSecrets, corev1.LocalObjectReference{Name: secret})
+	return true
+}
@@ -0,0 +1,503 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/perbu/kasa/manifest"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/yaml"
+)
+
+// SetImageTool provides the set_image tool for the agent.
+type SetImageTool struct {
+	clientset  *kubernetes.Clientset
+	manifest   *manifest.Manager
+	pinDigests bool // default for pin_digest
+}
+
+// NewSetImageTool creates a new SetImageTool. With pinDigests, images are
+// deployed by digest unless pin_digest is false.
+func NewSetImageTool(clientset *kubernetes.Clientset, manifest *manifest.Manager, pinDigests bool) *SetImageTool {
+	return &SetImageTool{
+		clientset:  clientset,
+		manifest:   manifest,
+		pinDigests: pinDigests,
+	}
+}
+
+// Name returns the tool name.
+func (t *SetImageTool) Name() string {
+	return "set_image"
+}
+
+// Description returns the tool description.
+func (t *SetImageTool) Description() string {
+	return "Update the container image of a managed deployment or statefulset. Changes only the image in the stored manifest and the live object, waits for the rollout, and automatically rolls back to the previous image if the new pods don't become available within the timeout. Prefer this over apply_resource for image bumps."
+}
+
+// IsLongRunning returns true as this tool waits for the rollout.
+func (t *SetImageTool) IsLongRunning() bool {
+	return true
+}
+
+// Category returns the tool category.
+func (t *SetImageTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *SetImageTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *SetImageTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"name": {
+					Type:        "string",
+					Description: "The name of the deployment or statefulset",
+				},
+				"image": {
+					Type:        "string",
+					Description: "The new container image with tag (e.g., nginx:1.27)",
+				},
+				"kind": {
+					Type:        "string",
+					Description: "deployment (default) or statefulset",
+				},
+				"container": {
+					Type:        "string",
+					Description: "Container to update. Required if the pod has more than one container",
+				},
+				"app": {
+					Type:        "string",
+					Description: "Application name of the stored manifest (default: name)",
+				},
+				"timeout": {
+					Type:        "integer",
+					Description: "Seconds to wait for the rollout before rolling back (default: 180, max: 600)",
+				},
+				"pin_digest":   pinDigestSchema,
+				"change_cause": changeCauseSchema,
+			},
+			Required: []string{"namespace", "name", "image"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *SetImageTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+
+	name, ok := argsMap["name"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+
+	image, ok := argsMap["image"].(string)
+	if !ok || image == "" {
+		return map[string]any{"error": "image is required"}, nil
+	}
+
+	kind := "deployment"
+	if k, ok := argsMap["kind"].(string); ok && k != "" {
+		kind = NormalizeKindName(k)
+	}
+	if kind != "deployment" && kind != "statefulset" {
+		return map[string]any{"error": fmt.Sprintf("unsupported kind %q: set_image supports deployment and statefulset", kind)}, nil
+	}
+
+	container, _ := argsMap["container"].(string)
+
+	app := name
+	if a, ok := argsMap["app"].(string); ok && a != "" {
+		app = a
+	}
+
+	timeout := 180
+	if to, ok := argsMap["timeout"].(float64); ok {
+		timeout = int(to)
+	}
+	timeout = min(max(timeout, 10), 600)
+
+	// Deploy by digest if asked; a tag that can't be resolved is deployed
+	// as is, with a warning
+	var pinWarning string
+	if pinDigestArg(argsMap, t.pinDigests) {
+		pinned, err := newImageChecker(t.clientset).pinImage(toolContext(ctx), t.Name(), argsMap)
+		if err != nil {
+			pinWarning = fmt.Sprintf("deployed %s by tag, not digest: %v", image, err)
+		} else {
+			image = pinned
+		}
+	}
+
+	result := t.setImage(toolContext(ctx), imageUpdate{
+		namespace: namespace,
+		name:      name,
+		kind:      kind,
+		container: container,
+		app:       app,
+		image:     image,
+		timeout:   time.Duration(timeout) * time.Second,
+		cause:     changeCause(argsMap, fmt.Sprintf("set_image %s to %s", name, image)),
+	})
+	if pinWarning != "" {
+		result["pin_warning"] = pinWarning
+	}
+	return result, nil
+}
+
+// imageUpdate describes a single set_image operation.
+type imageUpdate struct {
+	namespace, name, kind string
+	container             string // may be empty if the pod has one container
+	app                   string // stored manifest app name
+	image                 string
+	timeout               time.Duration
+	cause                 string // kubernetes.io/change-cause for the new revision
+}
+
+// setImage updates the stored manifest and live workload, waits for the
+// rollout and rolls back on failure. Returns the tool response.
+func (t *SetImageTool) setImage(ctx context.Context, u imageUpdate) map[string]any {
+	namespace, name, kind, container, app, image := u.namespace, u.name, u.kind, u.container, u.app, u.image
+
+	// Update the image in the stored manifest, leaving everything else as is
+	content, err := t.manifest.ReadManifest(namespace, app, kind)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("%v (use import_resource to start managing it)", err)}
+	}
+	var stored map[string]any
+	if err := yaml.Unmarshal(content, &stored); err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to parse stored manifest: %v", err)}
+	}
+	container, err = setManifestImage(stored, container, image)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	setManifestImageDigests(stored)
+	updated, err := yaml.Marshal(stored)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to marshal manifest: %v", err)}
+	}
+
+	// Update the live object
+	previous, err := t.updateLiveImage(ctx, namespace, name, kind, container, image, u.cause)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+
+	result := map[string]any{
+		"namespace":      namespace,
+		"name":           name,
+		"kind":           kind,
+		"container":      container,
+		"image":          image,
+		"previous_image": previous,
+	}
+
+	if t.manifest.IsDryRun() {
+		result["success"] = true
+		result["dry_run"] = true
+		result["message"] = fmt.Sprintf("Dry run: would change %s/%s container %s from %s to %s and wait for the rollout", kind, name, container, previous, image)
+		return result
+	}
+
+	if previous != image {
+		startTime := time.Now()
+		done, state, reason := t.waitForRollout(ctx, namespace, name, kind, container, image, u.timeout)
+		result["elapsed_seconds"] = int(time.Since(startTime).Seconds())
+		result["final_state"] = state
+
+		if !done {
+			result["success"] = false
+			result["failure_reason"] = reason
+			rollbackCause := fmt.Sprintf("rollback to %s after failed rollout of %s", previous, image)
+			// Roll back even when the user cancelled, so the live workload
+			// keeps matching the stored manifest
+			if _, err := t.updateLiveImage(context.WithoutCancel(ctx), namespace, name, kind, container, previous, rollbackCause); err != nil {
+				result["rolled_back"] = false
+				result["error"] = fmt.Sprintf("rollout failed (%s) and rollback to %s also failed: %v", reason, previous, err)
+				return result
+			}
+			result["rolled_back"] = true
+			result["message"] = fmt.Sprintf("Rollout of %s to %s failed (%s); rolled back to %s. The stored manifest was not changed.", name, image, reason, previous)
+			return result
+		}
+	}
+
+	manifestPath, err := t.manifest.SaveManifest(namespace, app, kind, updated)
+	if err != nil {
+		result["success"] = true
+		result["manifest_warning"] = fmt.Sprintf("Rolled out but failed to save manifest: %v", err)
+		return result
+	}
+
+	result["success"] = true
+	result["manifest_path"] = manifestPath
+	if previous == image {
+		result["message"] = fmt.Sprintf("%s/%s already runs %s; stored manifest updated", kind, name, image)
+	} else {
+		result["message"] = fmt.Sprintf("Rolled out %s to %s/%s (was %s)", image, kind, name, previous)
+	}
+	return result
+}
+
+// updateLiveImage sets the container image on the live workload, recording
+// cause as its change-cause, and returns the image it replaced.
+func (t *SetImageTool) updateLiveImage(ctx context.Context, namespace, name, kind, container, image, cause string) (string, error) {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	var previous string
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		switch kind {
+		case "statefulset":
+			sts, err := t.clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if previous, err = setContainerImage(&sts.Spec.Template.Spec, container, image); err != nil || previous == image {
+				return err
+			}
+			setImageDigests(&sts.ObjectMeta, &sts.Spec.Template.Spec)
+			setChangeCause(ctx, sts, cause)
+			_, err = t.clientset.AppsV1().StatefulSets(namespace).Update(ctx, sts, metav1.UpdateOptions{})
+			return err
+		default:
+			dep, err := t.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if previous, err = setContainerImage(&dep.Spec.Template.Spec, container, image); err != nil || previous == image {
+				return err
+			}
+			setImageDigests(&dep.ObjectMeta, &dep.Spec.Template.Spec)
+			setChangeCause(ctx, dep, cause)
+			_, err = t.clientset.AppsV1().Deployments(namespace).Update(ctx, dep, metav1.UpdateOptions{})
+			return err
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to update %s %s/%s: %w", kind, namespace, name, err)
+	}
+	return previous, nil
+}
+
+// waitForRollout polls until the new image is fully rolled out, a pod running
+// it fails terminally, or the timeout expires. Returns (done, state, reason).
+func (t *SetImageTool) waitForRollout(ctx context.Context, namespace, name, kind, container, image string, timeout time.Duration) (bool, string, string) {
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	state := "waiting for rollout"
+	for {
+		reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		var done bool
+		var selector *metav1.LabelSelector
+		var err error
+		switch kind {
+		case "statefulset":
+			var sts *appsv1.StatefulSet
+			if sts, err = t.clientset.AppsV1().StatefulSets(namespace).Get(reqCtx, name, metav1.GetOptions{}); err == nil {
+				selector = sts.Spec.Selector
+				done, state, err = statefulSetRolloutStatus(sts)
+			}
+		default:
+			var dep *appsv1.Deployment
+			if dep, err = t.clientset.AppsV1().Deployments(namespace).Get(reqCtx, name, metav1.GetOptions{}); err == nil {
+				selector = dep.Spec.Selector
+				done, state, err = deploymentRolloutStatus(dep)
+			}
+		}
+		if err == nil && !done {
+			if failed, reason := t.newPodFailure(reqCtx, namespace, selector, container, image); failed {
+				cancel()
+				return false, state, reason
+			}
+		}
+		cancel()
+
+		if err != nil {
+			return false, state, err.Error()
+		}
+		if done {
+			return true, state, ""
+		}
+		if time.Now().After(deadline) {
+			return false, state, fmt.Sprintf("timed out after %s", timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return false, state, "cancelled"
+		case <-ticker.C:
+		}
+	}
+}
+
+// newPodFailure reports whether a pod running the new image is in a terminal
+// failure state. Pods still running the old image are ignored.
+func (t *SetImageTool) newPodFailure(ctx context.Context, namespace string, selector *metav1.LabelSelector, container, image string) (bool, string) {
+	sel, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, ""
+	}
+	pods, err := t.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: sel.String()})
+	if err != nil {
+		return false, ""
+	}
+	for _, pod := range pods.Items {
+		if !podRunsImage(&pod, container, image) {
+			continue
+		}
+		if failed, reason := isTerminalPodFailure(&pod); failed {
+			return true, fmt.Sprintf("pod %s: %s", pod.Name, reason)
+		}
+	}
+	return false, ""
+}
+
+// podRunsImage reports whether the named container of pod uses image.
+func podRunsImage(pod *corev1.Pod, container, image string) bool {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == container {
+			return c.Image == image
+		}
+	}
+	return false
+}
+
+// setContainerImage sets the image of the named container and returns the
+// previous image.
+func setContainerImage(spec *corev1.PodSpec, container, image string) (string, error) {
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == container {
+			previous := spec.Containers[i].Image
+			spec.Containers[i].Image = image
+			return previous, nil
+		}
+	}
+	return "", fmt.Errorf("container %q not found in live object", container)
+}
+
+// setManifestImage sets the container image in a deployment or statefulset
+// manifest. If container is empty the pod must have exactly one container.
+// Returns the name of the updated container.
+func setManifestImage(obj map[string]any, container, image string) (string, error) {
+	spec, _ := obj["spec"].(map[string]any)
+	template, _ := spec["template"].(map[string]any)
+	podSpec, _ := template["spec"].(map[string]any)
+	containers, _ := podSpec["containers"].([]any)
+	if len(containers) == 0 {
+		return "", fmt.Errorf("stored manifest has no containers")
+	}
+
+	var names []string
+	for _, c := range containers {
+		cm, _ := c.(map[string]any)
+		n, _ := cm["name"].(string)
+		names = append(names, n)
+	}
+	if container == "" {
+		if len(containers) > 1 {
+			return "", fmt.Errorf("pod has multiple containers (%s); specify container", strings.Join(names, ", "))
+		}
+		container = names[0]
+	}
+
+	for _, c := range containers {
+		cm, _ := c.(map[string]any)
+		if cm["name"] == container {
+			cm["image"] = image
+			return container, nil
+		}
+	}
+	return "", fmt.Errorf("container %q not found (available: %s)", container, strings.Join(names, ", "))
+}
+
+// deploymentRolloutStatus reports whether a deployment rollout has finished,
+// following the same rules as 'kubectl rollout status'.
+func deploymentRolloutStatus(dep *appsv1.Deployment) (bool, string, error) {
+	if dep.Generation > dep.Status.ObservedGeneration {
+		return false, "waiting for the deployment spec update to be observed", nil
+	}
+	for _, cond := range dep.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+			return false, cond.Message, fmt.Errorf("deployment exceeded its progress deadline")
+		}
+	}
+
+	replicas := int32(1)
+	if dep.Spec.Replicas != nil {
+		replicas = *dep.Spec.Replicas
+	}
+	switch {
+	case dep.Status.UpdatedReplicas < replicas:
+		return false, fmt.Sprintf("%d of %d new replicas updated", dep.Status.UpdatedReplicas, replicas), nil
+	case dep.Status.Replicas > dep.Status.UpdatedReplicas:
+		return false, fmt.Sprintf("%d old replicas pending termination", dep.Status.Replicas-dep.Status.UpdatedReplicas), nil
+	case dep.Status.AvailableReplicas < dep.Status.UpdatedReplicas:
+		return false, fmt.Sprintf("%d of %d updated replicas available", dep.Status.AvailableReplicas, dep.Status.UpdatedReplicas), nil
+	}
+	return true, fmt.Sprintf("%d/%d replicas updated and available", dep.Status.AvailableReplicas, replicas), nil
+}
+
+// statefulSetRolloutStatus reports whether a statefulset rolling update has finished.
+func statefulSetRolloutStatus(sts *appsv1.StatefulSet) (bool, string, error) {
+	if sts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		return false, "", fmt.Errorf("statefulset uses the OnDelete update strategy; pods must be deleted to pick up the new image")
+	}
+	if sts.Generation > sts.Status.ObservedGeneration {
+		return false, "waiting for the statefulset spec update to be observed", nil
+	}
+
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+	switch {
+	case sts.Status.UpdatedReplicas < replicas:
+		return false, fmt.Sprintf("%d of %d pods updated", sts.Status.UpdatedReplicas, replicas), nil
+	case sts.Status.ReadyReplicas < replicas:
+		return false, fmt.Sprintf("%d of %d pods ready", sts.Status.ReadyReplicas, replicas), nil
+	case sts.Status.UpdateRevision != "" && sts.Status.CurrentRevision != sts.Status.UpdateRevision:
+		return false, "waiting for the update revision to become current", nil
+	}
+	return true, fmt.Sprintf("%d/%d pods updated and ready", sts.Status.ReadyReplicas, replicas), nil
+}
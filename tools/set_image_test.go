@@ -0,0 +1,131 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestSetManifestImage(t *testing.T) {
+	content := []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.25
+        ports:
+        - containerPort: 80
+`)
+	var obj map[string]any
+	if err := yaml.Unmarshal(content, &obj); err != nil {
+		t.Fatal(err)
+	}
+
+	container, err := setManifestImage(obj, "", "nginx:1.27")
+	if err != nil {
+		t.Fatalf("setManifestImage: %v", err)
+	}
+	if container != "web" {
+		t.Errorf("container = %q, want web", container)
+	}
+
+	out, _ := yaml.Marshal(obj)
+	if !strings.Contains(string(out), "image: nginx:1.27") || !strings.Contains(string(out), "containerPort: 80") {
+		t.Errorf("unexpected manifest:\n%s", out)
+	}
+}
+
+func TestSetManifestImage_MultipleContainers(t *testing.T) {
+	obj := map[string]any{"spec": map[string]any{"template": map[string]any{"spec": map[string]any{
+		"containers": []any{
+			map[string]any{"name": "app", "image": "app:1"},
+			map[string]any{"name": "proxy", "image": "envoy:1"},
+		},
+	}}}}
+
+	if _, err := setManifestImage(obj, "", "app:2"); err == nil || !strings.Contains(err.Error(), "app, proxy") {
+		t.Errorf("expected error listing containers, got %v", err)
+	}
+	if _, err := setManifestImage(obj, "sidecar", "app:2"); err == nil {
+		t.Error("expected error for unknown container")
+	}
+	if _, err := setManifestImage(obj, "proxy", "envoy:2"); err != nil {
+		t.Fatalf("setManifestImage: %v", err)
+	}
+	containers := obj["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["containers"].([]any)
+	if got := containers[1].(map[string]any)["image"]; got != "envoy:2" {
+		t.Errorf("proxy image = %v, want envoy:2", got)
+	}
+	if got := containers[0].(map[string]any)["image"]; got != "app:1" {
+		t.Errorf("app image = %v, want unchanged app:1", got)
+	}
+}
+
+func TestDeploymentRolloutStatus(t *testing.T) {
+	replicas := int32(3)
+	dep := func(gen, observed int64, status appsv1.DeploymentStatus) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Generation: gen},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     status,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		dep     *appsv1.Deployment
+		done    bool
+		wantErr bool
+	}{
+		{"not observed", dep(2, 1, appsv1.DeploymentStatus{ObservedGeneration: 1}), false, false},
+		{"updating", dep(2, 2, appsv1.DeploymentStatus{ObservedGeneration: 2, UpdatedReplicas: 1, Replicas: 4}), false, false},
+		{"old pending", dep(2, 2, appsv1.DeploymentStatus{ObservedGeneration: 2, UpdatedReplicas: 3, Replicas: 4}), false, false},
+		{"not available", dep(2, 2, appsv1.DeploymentStatus{ObservedGeneration: 2, UpdatedReplicas: 3, Replicas: 3, AvailableReplicas: 2}), false, false},
+		{"complete", dep(2, 2, appsv1.DeploymentStatus{ObservedGeneration: 2, UpdatedReplicas: 3, Replicas: 3, AvailableReplicas: 3}), true, false},
+		{"deadline", dep(2, 2, appsv1.DeploymentStatus{ObservedGeneration: 2, Conditions: []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+		}}), false, true},
+	}
+	for _, tt := range tests {
+		done, _, err := deploymentRolloutStatus(tt.dep)
+		if done != tt.done || (err != nil) != tt.wantErr {
+			t.Errorf("%s: done=%v err=%v, want done=%v wantErr=%v", tt.name, done, err, tt.done, tt.wantErr)
+		}
+	}
+}
+
+func TestStatefulSetRolloutStatus(t *testing.T) {
+	replicas := int32(2)
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Generation: 3},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status: appsv1.StatefulSetStatus{
+			ObservedGeneration: 3,
+			UpdatedReplicas:    2,
+			ReadyReplicas:      2,
+			CurrentRevision:    "web-1",
+			UpdateRevision:     "web-2",
+		},
+	}
+	if done, _, _ := statefulSetRolloutStatus(sts); done {
+		t.Error("rollout should not be done while revisions differ")
+	}
+
+	sts.Status.CurrentRevision = "web-2"
+	if done, _, err := statefulSetRolloutStatus(sts); !done || err != nil {
+		t.Errorf("done=%v err=%v, want done", done, err)
+	}
+
+	sts.Spec.UpdateStrategy.Type = appsv1.OnDeleteStatefulSetStrategyType
+	if _, _, err := statefulSetRolloutStatus(sts); err == nil {
+		t.Error("expected error for OnDelete update strategy")
+	}
+}
@@ -0,0 +1,645 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/perbu/kasa/manifest"
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// resourceFields maps the set_resources parameters to the requirement they set.
+var resourceFields = []struct {
+	param    string
+	limits   bool
+	resource corev1.ResourceName
+}{
+	{"cpu_request", false, corev1.ResourceCPU},
+	{"memory_request", false, corev1.ResourceMemory},
+	{"cpu_limit", true, corev1.ResourceCPU},
+	{"memory_limit", true, corev1.ResourceMemory},
+}
+
+// SetResourcesTool provides the set_resources tool for the agent.
+type SetResourcesTool struct {
+	clientset *kubernetes.Clientset
+	manifest  *manifest.Manager
+}
+
+// NewSetResourcesTool creates a new SetResourcesTool.
+func NewSetResourcesTool(clientset *kubernetes.Clientset, manifest *manifest.Manager) *SetResourcesTool {
+	return &SetResourcesTool{
+		clientset: clientset,
+		manifest:  manifest,
+	}
+}
+
+// Name returns the tool name.
+func (t *SetResourcesTool) Name() string {
+	return "set_resources"
+}
+
+// Description returns the tool description.
+func (t *SetResourcesTool) Description() string {
+	return "Change the CPU/memory requests and limits of one container in a managed deployment or statefulset. Checks the new values against the namespace's LimitRanges and ResourceQuotas first and refuses changes they would reject, then patches the live object (triggering a rollout) and updates the stored manifest. Only the given values change."
+}
+
+// IsLongRunning returns false as the rollout is not awaited.
+func (t *SetResourcesTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *SetResourcesTool) Category() ToolCategory {
+	return CategoryMutating
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *SetResourcesTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *SetResourcesTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"name": {
+					Type:        "string",
+					Description: "The name of the deployment or statefulset",
+				},
+				"kind": {
+					Type:        "string",
+					Description: "deployment (default) or statefulset",
+				},
+				"container": {
+					Type:        "string",
+					Description: "Container to update. Required if the pod has more than one container",
+				},
+				"app": {
+					Type:        "string",
+					Description: "Application name of the stored manifest (default: name)",
+				},
+				"cpu_request": {
+					Type:        "string",
+					Description: "CPU request, e.g. 250m or 1. Use 'none' to remove it",
+				},
+				"cpu_limit": {
+					Type:        "string",
+					Description: "CPU limit, e.g. 500m or 2. Use 'none' to remove it",
+				},
+				"memory_request": {
+					Type:        "string",
+					Description: "Memory request, e.g. 256Mi. Use 'none' to remove it",
+				},
+				"memory_limit": {
+					Type:        "string",
+					Description: "Memory limit, e.g. 512Mi or 1Gi. Use 'none' to remove it",
+				},
+				"change_cause": changeCauseSchema,
+			},
+			Required: []string{"namespace", "name"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *SetResourcesTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	u := resourceUpdate{kind: "deployment"}
+
+	u.namespace, _ = argsMap["namespace"].(string)
+	if u.namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+
+	u.name, _ = argsMap["name"].(string)
+	if u.name == "" {
+		return map[string]any{"error": "name is required"}, nil
+	}
+
+	if k, ok := argsMap["kind"].(string); ok && k != "" {
+		u.kind = NormalizeKindName(k)
+	}
+	if u.kind != "deployment" && u.kind != "statefulset" {
+		return map[string]any{"error": fmt.Sprintf("unsupported kind %q: set_resources supports deployment and statefulset", u.kind)}, nil
+	}
+
+	u.container, _ = argsMap["container"].(string)
+
+	u.app = u.name
+	if a, ok := argsMap["app"].(string); ok && a != "" {
+		u.app = a
+	}
+
+	u.changes = make(map[string]*resource.Quantity)
+	for _, f := range resourceFields {
+		v, ok := argsMap[f.param].(string)
+		if !ok || strings.TrimSpace(v) == "" {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if strings.EqualFold(v, "none") {
+			u.changes[f.param] = nil
+			continue
+		}
+		q, err := resource.ParseQuantity(v)
+		if err != nil {
+			return map[string]any{"error": fmt.Sprintf("invalid %s %q: %v", f.param, v, err)}, nil
+		}
+		u.changes[f.param] = &q
+	}
+	if len(u.changes) == 0 {
+		return map[string]any{"error": "at least one of cpu_request, cpu_limit, memory_request or memory_limit is required"}, nil
+	}
+
+	u.cause = changeCause(argsMap, fmt.Sprintf("set_resources %s: %s", u.name, describeResourceChanges(u.changes)))
+
+	return setResources(toolContext(ctx), t.clientset, t.manifest, u), nil
+}
+
+// resourceUpdate describes a set_resources call.
+type resourceUpdate struct {
+	namespace, name, kind string
+	container             string // may be empty if the pod has one container
+	app                   string // stored manifest app name
+	changes               map[string]*resource.Quantity
+	cause                 string
+}
+
+// setResources validates a resource change against the namespace's
+// LimitRanges and ResourceQuotas, patches the live workload and updates the
+// stored manifest. Returns the tool response.
+func setResources(ctx context.Context, clientset kubernetes.Interface, mgr *manifest.Manager, u resourceUpdate) map[string]any {
+	content, err := mgr.ReadManifest(u.namespace, u.app, u.kind)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("%v (use import_resource to start managing it)", err)}
+	}
+
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	podSpec, replicas, err := workloadPodSpec(ctx, clientset, u.kind, u.namespace, u.name)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	container, err := targetContainer(podSpec, u.container)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+
+	previous := container.Resources
+	next := *previous.DeepCopy()
+	applyResourceChanges(&next, u.changes)
+
+	updated, err := setManifestResources(content, container.Name, u.changes)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+
+	limitRanges, err := clientset.CoreV1().LimitRanges(u.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to list LimitRanges: %v", err)}
+	}
+	quotas, err := clientset.CoreV1().ResourceQuotas(u.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to list ResourceQuotas: %v", err)}
+	}
+
+	effective := effectiveRequirements(next, limitRanges.Items)
+	violations := checkLimitRanges(effective, limitRanges.Items)
+	quotaViolations, warnings := checkQuotas(effectiveRequirements(previous, limitRanges.Items), effective, replicas, quotas.Items)
+	violations = append(violations, quotaViolations...)
+
+	result := map[string]any{
+		"namespace": u.namespace,
+		"name":      u.name,
+		"kind":      u.kind,
+		"container": container.Name,
+		"previous":  formatRequirements(previous),
+		"resources": formatRequirements(next),
+		"replicas":  replicas,
+	}
+	if len(warnings) > 0 {
+		result["warnings"] = warnings
+	}
+	if len(violations) > 0 {
+		result["violations"] = violations
+		result["error"] = fmt.Sprintf("the change would be rejected by namespace policy: %s", strings.Join(violations, "; "))
+		return result
+	}
+
+	patch, err := resourcesPatch(ctx, container.Name, u.changes, u.cause)
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to build patch: %v", err)}
+	}
+	switch u.kind {
+	case "statefulset":
+		_, err = clientset.AppsV1().StatefulSets(u.namespace).Patch(ctx, u.name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	default:
+		_, err = clientset.AppsV1().Deployments(u.namespace).Patch(ctx, u.name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	}
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to update %s %s/%s: %v", u.kind, u.namespace, u.name, err)}
+	}
+
+	manifestPath, err := mgr.SaveManifest(u.namespace, u.app, u.kind, updated)
+	if err != nil {
+		result["manifest_warning"] = fmt.Sprintf("Updated in the cluster but failed to save manifest: %v", err)
+	} else {
+		result["manifest_path"] = manifestPath
+	}
+
+	message := fmt.Sprintf("Set %s on %s %s/%s container %s; a rollout has started (use check_deployment_health to follow it)",
+		describeResourceChanges(u.changes), u.kind, u.namespace, u.name, container.Name)
+	if mgr.IsDryRun() {
+		result["dry_run"] = true
+		message = fmt.Sprintf("Dry run: would set %s on %s %s/%s container %s", describeResourceChanges(u.changes), u.kind, u.namespace, u.name, container.Name)
+	}
+	result["success"] = true
+	result["message"] = message
+	return result
+}
+
+// workloadPodSpec returns the pod template spec and replica count of a
+// deployment or statefulset.
+func workloadPodSpec(ctx context.Context, clientset kubernetes.Interface, kind, namespace, name string) (*corev1.PodSpec, int32, error) {
+	replicas := int32(1)
+	switch kind {
+	case "statefulset":
+		sts, err := clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get statefulset %s/%s: %w", namespace, name, err)
+		}
+		if sts.Spec.Replicas != nil {
+			replicas = *sts.Spec.Replicas
+		}
+		return &sts.Spec.Template.Spec, replicas, nil
+	default:
+		dep, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get deployment %s/%s: %w", namespace, name, err)
+		}
+		if dep.Spec.Replicas != nil {
+			replicas = *dep.Spec.Replicas
+		}
+		return &dep.Spec.Template.Spec, replicas, nil
+	}
+}
+
+// targetContainer returns the named container, or the only one if name is
+// empty. Unlike findContainer it refuses to guess between several containers.
+func targetContainer(spec *corev1.PodSpec, name string) (*corev1.Container, error) {
+	var names []string
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == name {
+			return &spec.Containers[i], nil
+		}
+		names = append(names, spec.Containers[i].Name)
+	}
+	if name == "" {
+		if len(spec.Containers) == 1 {
+			return &spec.Containers[0], nil
+		}
+		return nil, fmt.Errorf("pod has multiple containers (%s); specify container", strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("container %q not found (available: %s)", name, strings.Join(names, ", "))
+}
+
+// applyResourceChanges sets or removes the changed values in req.
+func applyResourceChanges(req *corev1.ResourceRequirements, changes map[string]*resource.Quantity) {
+	for _, f := range resourceFields {
+		q, ok := changes[f.param]
+		if !ok {
+			continue
+		}
+		list := &req.Requests
+		if f.limits {
+			list = &req.Limits
+		}
+		if q == nil {
+			delete(*list, f.resource)
+			continue
+		}
+		if *list == nil {
+			*list = corev1.ResourceList{}
+		}
+		(*list)[f.resource] = *q
+	}
+}
+
+// setManifestResources applies the changes to the container in a stored
+// workload manifest, leaving the rest of the document as it is.
+func setManifestResources(content []byte, container string, changes map[string]*resource.Quantity) ([]byte, error) {
+	doc, root, err := parseYAMLDocument(content)
+	if err != nil {
+		return nil, err
+	}
+
+	base := []yamlPathSegment{{key: "spec"}, {key: "template"}, {key: "spec"}, {key: "containers"}, {key: "name", value: container, isSelector: true}}
+	if _, err := lookupYAMLPath(root, base); err != nil {
+		return nil, fmt.Errorf("container %s not found in the stored manifest", container)
+	}
+	base = append(base, yamlPathSegment{key: "resources"})
+
+	for _, f := range resourceFields {
+		q, ok := changes[f.param]
+		if !ok {
+			continue
+		}
+		section := yamlPathSegment{key: "requests"}
+		if f.limits {
+			section = yamlPathSegment{key: "limits"}
+		}
+		path := append(append([]yamlPathSegment{}, base...), section, yamlPathSegment{key: string(f.resource)})
+		if q == nil {
+			// Removing a value that isn't there is fine
+			_, _ = removeYAMLPath(root, path)
+			continue
+		}
+		value, err := parseYAMLValue(q.String())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := setYAMLPath(root, path, value); err != nil {
+			return nil, err
+		}
+	}
+
+	// Drop sections left empty by removals
+	for _, section := range []string{"requests", "limits"} {
+		path := append(append([]yamlPathSegment{}, base...), yamlPathSegment{key: section})
+		if node, err := lookupYAMLPath(root, path); err == nil && node.Kind == yaml.MappingNode && len(node.Content) == 0 {
+			_, _ = removeYAMLPath(root, path)
+		}
+	}
+	if node, err := lookupYAMLPath(root, base); err == nil && node.Kind == yaml.MappingNode && len(node.Content) == 0 {
+		_, _ = removeYAMLPath(root, base)
+	}
+
+	return encodeYAMLDocument(doc, usesCompactSequences(content))
+}
+
+// resourcesPatch builds a strategic merge patch changing only the given
+// values of one container; removed values are sent as null.
+func resourcesPatch(ctx context.Context, container string, changes map[string]*resource.Quantity, cause string) ([]byte, error) {
+	requests := map[string]any{}
+	limits := map[string]any{}
+	for _, f := range resourceFields {
+		q, ok := changes[f.param]
+		if !ok {
+			continue
+		}
+		var value any
+		if q != nil {
+			value = q.String()
+		}
+		if f.limits {
+			limits[string(f.resource)] = value
+		} else {
+			requests[string(f.resource)] = value
+		}
+	}
+	resources := map[string]any{}
+	if len(requests) > 0 {
+		resources["requests"] = requests
+	}
+	if len(limits) > 0 {
+		resources["limits"] = limits
+	}
+
+	patch := map[string]any{
+		"spec": map[string]any{
+			"template": map[string]any{
+				"spec": map[string]any{
+					"containers": []any{map[string]any{"name": container, "resources": resources}},
+				},
+			},
+		},
+	}
+	if cause != "" {
+		patch["metadata"] = map[string]any{"annotations": changeAnnotations(ctx, cause)}
+	}
+	return json.Marshal(patch)
+}
+
+// effectiveRequirements returns the requirements a container ends up with
+// after admission: a missing request defaults to the limit, and LimitRange
+// defaults fill in what is still missing.
+func effectiveRequirements(req corev1.ResourceRequirements, limitRanges []corev1.LimitRange) corev1.ResourceRequirements {
+	eff := *req.DeepCopy()
+	if eff.Requests == nil {
+		eff.Requests = corev1.ResourceList{}
+	}
+	if eff.Limits == nil {
+		eff.Limits = corev1.ResourceList{}
+	}
+	for name, limit := range eff.Limits {
+		if _, ok := eff.Requests[name]; !ok {
+			eff.Requests[name] = limit
+		}
+	}
+	for _, lr := range limitRanges {
+		for _, item := range lr.Spec.Limits {
+			if item.Type != corev1.LimitTypeContainer {
+				continue
+			}
+			for name, q := range item.Default {
+				if _, ok := eff.Limits[name]; !ok {
+					eff.Limits[name] = q
+				}
+			}
+			defaultRequest := item.DefaultRequest
+			if len(defaultRequest) == 0 {
+				defaultRequest = item.Default
+			}
+			for name, q := range defaultRequest {
+				if _, ok := eff.Requests[name]; !ok {
+					eff.Requests[name] = q
+				}
+			}
+		}
+	}
+	return eff
+}
+
+// checkLimitRanges returns the ways in which a container's effective
+// requirements break the namespace's Container LimitRanges.
+func checkLimitRanges(eff corev1.ResourceRequirements, limitRanges []corev1.LimitRange) []string {
+	var violations []string
+	for _, lr := range limitRanges {
+		for _, item := range lr.Spec.Limits {
+			if item.Type != corev1.LimitTypeContainer {
+				continue
+			}
+			for _, name := range sortedResourceNames(item.Min) {
+				min := item.Min[name]
+				if q, ok := eff.Requests[name]; ok && q.Cmp(min) < 0 {
+					violations = append(violations, fmt.Sprintf("%s request %s is below the minimum %s of LimitRange %s", name, q.String(), min.String(), lr.Name))
+				}
+			}
+			for _, name := range sortedResourceNames(item.Max) {
+				max := item.Max[name]
+				q, ok := eff.Limits[name]
+				switch {
+				case !ok:
+					violations = append(violations, fmt.Sprintf("LimitRange %s requires a %s limit (max %s)", lr.Name, name, max.String()))
+				case q.Cmp(max) > 0:
+					violations = append(violations, fmt.Sprintf("%s limit %s exceeds the maximum %s of LimitRange %s", name, q.String(), max.String(), lr.Name))
+				}
+			}
+			for _, name := range sortedResourceNames(item.MaxLimitRequestRatio) {
+				ratio := item.MaxLimitRequestRatio[name]
+				limit, hasLimit := eff.Limits[name]
+				request, hasRequest := eff.Requests[name]
+				if !hasLimit || !hasRequest || request.IsZero() {
+					continue
+				}
+				if float64(limit.MilliValue())/float64(request.MilliValue()) > ratio.AsApproximateFloat64() {
+					violations = append(violations, fmt.Sprintf("%s limit/request ratio %s/%s exceeds the maximum %s of LimitRange %s", name, limit.String(), request.String(), ratio.String(), lr.Name))
+				}
+			}
+		}
+	}
+	return violations
+}
+
+// quotaResources maps quota resource names to the requirement they count.
+var quotaResources = []struct {
+	name     corev1.ResourceName
+	limits   bool
+	resource corev1.ResourceName
+}{
+	{corev1.ResourceRequestsCPU, false, corev1.ResourceCPU},
+	{corev1.ResourceCPU, false, corev1.ResourceCPU},
+	{corev1.ResourceRequestsMemory, false, corev1.ResourceMemory},
+	{corev1.ResourceMemory, false, corev1.ResourceMemory},
+	{corev1.ResourceLimitsCPU, true, corev1.ResourceCPU},
+	{corev1.ResourceLimitsMemory, true, corev1.ResourceMemory},
+}
+
+// checkQuotas checks whether replicas pods changing from the previous to
+// the next effective requirements fit the namespace's ResourceQuotas.
+// Quotas limited by scopes are not evaluated and only produce a warning.
+func checkQuotas(previous, next corev1.ResourceRequirements, replicas int32, quotas []corev1.ResourceQuota) (violations, warnings []string) {
+	for _, quota := range quotas {
+		if len(quota.Spec.Scopes) > 0 || quota.Spec.ScopeSelector != nil {
+			warnings = append(warnings, fmt.Sprintf("ResourceQuota %s is scoped and was not checked", quota.Name))
+			continue
+		}
+		for _, qr := range quotaResources {
+			hard, ok := quota.Status.Hard[qr.name]
+			if !ok {
+				hard, ok = quota.Spec.Hard[qr.name]
+			}
+			if !ok {
+				continue
+			}
+			prevList, nextList := previous.Requests, next.Requests
+			kindName := "request"
+			if qr.limits {
+				prevList, nextList = previous.Limits, next.Limits
+				kindName = "limit"
+			}
+			newValue, hasNew := nextList[qr.resource]
+			if !hasNew {
+				violations = append(violations, fmt.Sprintf("ResourceQuota %s tracks %s, so the container needs a %s %s", quota.Name, qr.name, qr.resource, kindName))
+				continue
+			}
+
+			delta := newValue.DeepCopy()
+			if old, ok := prevList[qr.resource]; ok {
+				delta.Sub(old)
+			}
+			if delta.Sign() <= 0 {
+				continue
+			}
+			total := resource.NewMilliQuantity(delta.MilliValue()*int64(replicas), delta.Format)
+			used := quota.Status.Used[qr.name]
+			after := used.DeepCopy()
+			after.Add(*total)
+			if after.Cmp(hard) > 0 {
+				violations = append(violations, fmt.Sprintf("ResourceQuota %s: %s would rise to %s (used %s + %s for %d replica(s)), over the hard limit %s",
+					quota.Name, qr.name, after.String(), used.String(), total.String(), replicas, hard.String()))
+				continue
+			}
+
+			// A rolling update runs new pods next to old ones for a while
+			headroom := hard.DeepCopy()
+			headroom.Sub(after)
+			if headroom.Cmp(newValue) < 0 {
+				warnings = append(warnings, fmt.Sprintf("ResourceQuota %s leaves %s of %s after the change, less than one new pod needs; the rollout may stall on surge pods", quota.Name, headroom.String(), qr.name))
+			}
+		}
+	}
+	return violations, warnings
+}
+
+// formatRequirements renders requirements as {"requests": {...}, "limits": {...}}.
+func formatRequirements(req corev1.ResourceRequirements) map[string]map[string]string {
+	out := map[string]map[string]string{}
+	for section, list := range map[string]corev1.ResourceList{"requests": req.Requests, "limits": req.Limits} {
+		if len(list) == 0 {
+			continue
+		}
+		out[section] = map[string]string{}
+		for name, q := range list {
+			out[section][string(name)] = q.String()
+		}
+	}
+	return out
+}
+
+// describeResourceChanges renders the changes, e.g. "cpu_request=250m, memory_limit=none".
+func describeResourceChanges(changes map[string]*resource.Quantity) string {
+	var parts []string
+	for _, f := range resourceFields {
+		q, ok := changes[f.param]
+		if !ok {
+			continue
+		}
+		if q == nil {
+			parts = append(parts, f.param+"=none")
+		} else {
+			parts = append(parts, f.param+"="+q.String())
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func sortedResourceNames(list corev1.ResourceList) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(list))
+	for name := range list {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const setResourcesTestDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.27 # pinned
+        resources:
+          requests:
+            cpu: 100m
+            memory: 128Mi
+`
+
+func setResourcesFixture(t *testing.T, objects ...any) (*fake.Clientset, resourceUpdate) {
+	t.Helper()
+	replicas := int32(2)
+	dep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name:  "web",
+				Image: "nginx:1.27",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				}},
+			}}}},
+		},
+	}
+	clientset := fake.NewSimpleClientset(dep)
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *corev1.LimitRange:
+			_, _ = clientset.CoreV1().LimitRanges("default").Create(context.Background(), o, metav1.CreateOptions{})
+		case *corev1.ResourceQuota:
+			_, _ = clientset.CoreV1().ResourceQuotas("default").Create(context.Background(), o, metav1.CreateOptions{})
+		}
+	}
+	return clientset, resourceUpdate{namespace: "default", name: "web", kind: "deployment", app: "web", cause: "more headroom"}
+}
+
+func quantity(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	return &q
+}
+
+func TestSetResources(t *testing.T) {
+	mgr := newTestManifestManager(t)
+	if _, err := mgr.SaveManifest("default", "web", "deployment", []byte(setResourcesTestDeployment)); err != nil {
+		t.Fatal(err)
+	}
+
+	clientset, u := setResourcesFixture(t)
+	u.changes = map[string]*resource.Quantity{"memory_limit": quantity("512Mi"), "cpu_request": quantity("250m")}
+	result := setResources(context.Background(), clientset, mgr, u)
+	if ok, _ := result["success"].(bool); !ok {
+		t.Fatalf("setResources failed: %v", result)
+	}
+
+	dep, _ := clientset.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+	res := dep.Spec.Template.Spec.Containers[0].Resources
+	if res.Requests.Cpu().String() != "250m" || res.Requests.Memory().String() != "128Mi" || res.Limits.Memory().String() != "512Mi" {
+		t.Errorf("live resources = %v", res)
+	}
+	if dep.Annotations[changeCauseAnnotation] != "more headroom" {
+		t.Errorf("change-cause = %q", dep.Annotations[changeCauseAnnotation])
+	}
+
+	stored, _ := mgr.ReadManifest("default", "web", "deployment")
+	want := strings.Replace(setResourcesTestDeployment, "cpu: 100m", "cpu: 250m", 1) + "          limits:\n            memory: 512Mi\n"
+	if string(stored) != want {
+		t.Errorf("stored manifest:\n%s\nwant:\n%s", stored, want)
+	}
+
+	// Removing the only limit drops the empty section again
+	u.changes = map[string]*resource.Quantity{"memory_limit": nil}
+	if result := setResources(context.Background(), clientset, mgr, u); result["success"] != true {
+		t.Fatalf("setResources remove failed: %v", result)
+	}
+	stored, _ = mgr.ReadManifest("default", "web", "deployment")
+	if strings.Contains(string(stored), "limits") {
+		t.Errorf("limits should be removed:\n%s", stored)
+	}
+}
+
+func TestSetResourcesRejected(t *testing.T) {
+	limitRange := &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: "container-limits"},
+		Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+			Type:    corev1.LimitTypeContainer,
+			Max:     corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+			Default: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+		}}},
+	}
+	quota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "compute"},
+		Spec:       corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("1")}},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("1")},
+			Used: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("600m")},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		changes map[string]*resource.Quantity
+		want    string
+	}{
+		{"limit over LimitRange max", map[string]*resource.Quantity{"memory_limit": quantity("2Gi")}, "exceeds the maximum 1Gi of LimitRange container-limits"},
+		{"quota exceeded", map[string]*resource.Quantity{"cpu_request": quantity("400m")}, "over the hard limit 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := newTestManifestManager(t)
+			if _, err := mgr.SaveManifest("default", "web", "deployment", []byte(setResourcesTestDeployment)); err != nil {
+				t.Fatal(err)
+			}
+			clientset, u := setResourcesFixture(t, limitRange, quota)
+			u.changes = tt.changes
+
+			result := setResources(context.Background(), clientset, mgr, u)
+			errMsg, _ := result["error"].(string)
+			if !strings.Contains(errMsg, tt.want) {
+				t.Fatalf("error = %q, want it to contain %q", errMsg, tt.want)
+			}
+			stored, _ := mgr.ReadManifest("default", "web", "deployment")
+			if string(stored) != setResourcesTestDeployment {
+				t.Errorf("rejected change must not touch the stored manifest:\n%s", stored)
+			}
+		})
+	}
+}
+
+func TestEffectiveRequirements(t *testing.T) {
+	limitRanges := []corev1.LimitRange{{Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+		Type:           corev1.LimitTypeContainer,
+		Default:        corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+		DefaultRequest: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+	}}}}}
+
+	eff := effectiveRequirements(corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+	}, limitRanges)
+	if eff.Requests.Cpu().String() != "500m" {
+		t.Errorf("missing cpu request should default to the limit, got %s", eff.Requests.Cpu())
+	}
+	if eff.Limits.Memory().String() != "512Mi" || eff.Requests.Memory().String() != "256Mi" {
+		t.Errorf("memory should come from LimitRange defaults, got request %s limit %s", eff.Requests.Memory(), eff.Limits.Memory())
+	}
+}
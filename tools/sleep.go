@@ -95,7 +95,13 @@ func (t *SleepTool) Run(ctx tool.Context, args any) (map[string]any, error) {
 
 	duration := time.Duration(seconds * float64(time.Second))
 	start := time.Now()
-	time.Sleep(duration)
+	if !pause(toolContext(ctx), duration) {
+		return map[string]any{
+			"slept_seconds": time.Since(start).Seconds(),
+			"cancelled":     true,
+			"message":       "Sleep cancelled",
+		}, nil
+	}
 	elapsed := time.Since(start)
 
 	return map[string]any{
@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"google.golang.org/adk/tool"
+)
+
+// Defaults for unset Timeouts fields.
+const (
+	DefaultReadTimeout   = 30 * time.Second
+	DefaultMutateTimeout = time.Minute
+	DefaultWaitTimeout   = 30 * time.Minute
+	DefaultMaxTimeout    = time.Hour
+)
+
+// waitTools are the tools that wait for the cluster, such as a rollout or a
+// drain, and get the wait timeout instead of the one of their category.
+var waitTools = map[string]bool{
+	"wait_for_condition": true,
+	"set_image":          true,
+	"canary_deploy":      true,
+	"drain_node":         true,
+	"probe_service":      true,
+	"cp_from_pod":        true,
+	"cp_to_pod":          true,
+	"sleep":              true,
+	"watch_events":       true,
+}
+
+// Waits reports whether a tool waits for the cluster, and so gets the wait
+// timeout.
+func Waits(name string) bool {
+	return waitTools[name]
+}
+
+// Timeouts bounds each tool call (the kubernetes.timeouts section of
+// config.yaml). The zero value uses the defaults.
+type Timeouts struct {
+	// Read bounds read-only and planning tools, and each API request of
+	// the tools that wait (default 30s).
+	Read time.Duration `yaml:"read"`
+	// Mutate bounds tools that change the cluster or the manifests
+	// (default 1m).
+	Mutate time.Duration `yaml:"mutate"`
+	// Wait bounds tools that wait for the cluster, such as
+	// wait_for_condition, set_image and drain_node (default 30m).
+	Wait time.Duration `yaml:"wait"`
+	// Max is the longest timeout the model may ask for with the
+	// timeout_seconds argument (default 1h).
+	Max time.Duration `yaml:"max"`
+}
+
+// Validate checks for negative timeouts.
+func (t Timeouts) Validate() error {
+	for name, d := range map[string]time.Duration{"read": t.Read, "mutate": t.Mutate, "wait": t.Wait, "max": t.Max} {
+		if d < 0 {
+			return fmt.Errorf("timeouts.%s must not be negative (0 for the default)", name)
+		}
+	}
+	return nil
+}
+
+// withDefaults fills in the unset timeouts.
+func (t Timeouts) withDefaults() Timeouts {
+	if t.Read == 0 {
+		t.Read = DefaultReadTimeout
+	}
+	if t.Mutate == 0 {
+		t.Mutate = DefaultMutateTimeout
+	}
+	if t.Wait == 0 {
+		t.Wait = DefaultWaitTimeout
+	}
+	if t.Max == 0 {
+		t.Max = DefaultMaxTimeout
+	}
+	return t
+}
+
+// SetTimeouts sets the per-category timeouts of tool calls.
+func (k *KubeTools) SetTimeouts(t Timeouts) {
+	k.timeouts = t
+}
+
+// forTool returns the timeout of a tool's calls and the timeout of each of
+// its API requests. Custom tools keep their own timeout.
+func (k *KubeTools) forTool(t ToolInfo) (call, request time.Duration) {
+	timeouts := k.timeouts.withDefaults()
+	for _, c := range k.customTools {
+		if c.Name() == t.Name {
+			return c.config.Timeout, c.config.Timeout
+		}
+	}
+	switch {
+	case Waits(t.Name):
+		return timeouts.Wait, timeouts.Read
+	case t.Category == CategoryMutating:
+		return timeouts.Mutate, timeouts.Mutate
+	}
+	return timeouts.Read, timeouts.Read
+}
+
+// enforceTimeouts returns middleware that bounds every tool call by the
+// timeout of its category, or the longer timeout_seconds the model asked
+// for, up to the maximum.
+func (k *KubeTools) enforceTimeouts() Middleware {
+	return func(t ToolInfo, next RunFunc) RunFunc {
+		call, request := k.forTool(t)
+		limit := max(k.timeouts.withDefaults().Max, call)
+		return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			timeout := call
+			if seconds, ok := requestedTimeout(args); ok {
+				timeout = min(max(timeout, time.Duration(seconds*float64(time.Second))), limit)
+				args = withoutKey(args, "timeout_seconds")
+			}
+			// Tests and replays run tools without a tool.Context
+			if ctx == nil {
+				return next(ctx, args)
+			}
+			callCtx, cancel := context.WithTimeout(context.WithValue(ctx, requestTimeoutKey{}, min(request, timeout)), timeout)
+			defer cancel()
+
+			result, err := next(WithContext(ctx, callCtx), args)
+			if errorResult(result) && callCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+				result["timeout"] = fmt.Sprintf("%s timed out after %s; call it again with a larger timeout_seconds (at most %d) if the operation needs longer",
+					t.Name, timeout, int(limit.Seconds()))
+			}
+			return result, err
+		}
+	}
+}
+
+// requestedTimeout returns the timeout_seconds argument, if the model set
+// one.
+func requestedTimeout(args map[string]any) (float64, bool) {
+	var seconds float64
+	switch v := args["timeout_seconds"].(type) {
+	case float64:
+		seconds = v
+	case int:
+		seconds = float64(v)
+	case string:
+		var err error
+		if seconds, err = strconv.ParseFloat(v, 64); err != nil {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	return seconds, seconds > 0
+}
+
+// withoutKey returns a copy of args without key.
+func withoutKey(args map[string]any, key string) map[string]any {
+	copied := make(map[string]any, len(args))
+	for k, v := range args {
+		if k != key {
+			copied[k] = v
+		}
+	}
+	return copied
+}
+
+// errorResult reports whether a tool result is an error.
+func errorResult(result map[string]any) bool {
+	if result == nil {
+		return false
+	}
+	_, ok := result["error"]
+	return ok
+}
+
+// requestTimeoutKey holds the timeout of each API request of a tool call.
+type requestTimeoutKey struct{}
+
+// requestTimeout returns the timeout apiContext gives an API request.
+func requestTimeout(ctx context.Context) time.Duration {
+	if d, ok := ctx.Value(requestTimeoutKey{}).(time.Duration); ok {
+		return d
+	}
+	return apiTimeout
+}
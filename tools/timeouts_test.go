@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/tool"
+)
+
+func TestEnforceTimeouts(t *testing.T) {
+	k := &KubeTools{}
+	k.SetTimeouts(Timeouts{Read: 2 * time.Second, Max: 5 * time.Second})
+	mw := k.enforceTimeouts()
+	root := WithContext(nil, context.Background())
+
+	var remaining, request time.Duration
+	var seen map[string]any
+	record := func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		deadline, _ := ctx.Deadline()
+		remaining, request, seen = time.Until(deadline), requestTimeout(ctx), args
+		return map[string]any{}, nil
+	}
+	near := func(got, want time.Duration) bool {
+		return got <= want && got > want-time.Second
+	}
+
+	read := mw(ToolInfo{Name: "list_pods", Category: CategoryReadOnly}, record)
+	read(root, map[string]any{})
+	if !near(remaining, 2*time.Second) || request != 2*time.Second {
+		t.Errorf("read call: got deadline in %s and request timeout %s, want 2s", remaining, request)
+	}
+
+	read(root, map[string]any{"timeout_seconds": 4.0, "name": "web"})
+	if !near(remaining, 4*time.Second) {
+		t.Errorf("expected timeout_seconds to raise the timeout to 4s, got %s", remaining)
+	}
+	if _, ok := seen["timeout_seconds"]; ok || seen["name"] != "web" {
+		t.Errorf("expected timeout_seconds to be removed from the arguments, got %v", seen)
+	}
+
+	read(root, map[string]any{"timeout_seconds": 600.0})
+	if !near(remaining, 5*time.Second) {
+		t.Errorf("expected timeout_seconds to be capped at 5s, got %s", remaining)
+	}
+
+	read(root, map[string]any{"timeout_seconds": 1.0})
+	if !near(remaining, 2*time.Second) {
+		t.Errorf("expected a shorter timeout_seconds to be ignored, got %s", remaining)
+	}
+
+	wait := mw(ToolInfo{Name: "wait_for_condition", Category: CategoryReadOnly}, record)
+	wait(root, map[string]any{})
+	if !near(remaining, DefaultWaitTimeout) || request != 2*time.Second {
+		t.Errorf("wait call: got deadline in %s and request timeout %s, want %s and 2s", remaining, request, DefaultWaitTimeout)
+	}
+
+	mutate := mw(ToolInfo{Name: "scale_deployment", Category: CategoryMutating}, record)
+	mutate(root, map[string]any{})
+	if !near(remaining, DefaultMutateTimeout) {
+		t.Errorf("mutating call: got deadline in %s, want %s", remaining, DefaultMutateTimeout)
+	}
+}
+
+func TestEnforceTimeoutsExplainsTimeout(t *testing.T) {
+	k := &KubeTools{}
+	k.SetTimeouts(Timeouts{Read: 20 * time.Millisecond})
+	slow := k.enforceTimeouts()(ToolInfo{Name: "list_pods", Category: CategoryReadOnly}, func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		<-ctx.Done()
+		return map[string]any{"error": ctx.Err().Error()}, nil
+	})
+
+	result, _ := slow(WithContext(nil, context.Background()), map[string]any{})
+	hint, _ := result["timeout"].(string)
+	if !strings.Contains(hint, "timeout_seconds") {
+		t.Errorf("expected the result to suggest timeout_seconds, got %v", result)
+	}
+}
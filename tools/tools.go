@@ -1,8 +1,13 @@
 package tools
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/perbu/kasa/manifest"
 	"google.golang.org/adk/model"
@@ -10,6 +15,7 @@ import (
 	"google.golang.org/genai"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 // ToolCategory classifies tools by their side effects.
@@ -32,13 +38,62 @@ func IsMutating(t tool.Tool) bool {
 	return false
 }
 
+// IsReadOnly returns true if the given tool is classified as read-only.
+func IsReadOnly(t tool.Tool) bool {
+	if ft, ok := t.(functionTool); ok {
+		return ft.Category() == CategoryReadOnly
+	}
+	return false
+}
+
 // KubeTools holds the Kubernetes clientset and provides tool definitions.
 type KubeTools struct {
 	clientset     *kubernetes.Clientset
 	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
+	restConfig    *rest.Config // for pod exec; nil disables the cp tools
 	manifest      *manifest.Manager
 	jinaAPIKey    string
 	tavilyAPIKey  string
+
+	prometheusURL   string
+	prometheusToken string
+
+	trivyBinary string // "" disables vulnerability scans; see SetTrivy
+	trivyServer string
+
+	pinDigests bool // see SetPinDigests
+
+	dryRunGuard *dryRunGuard // nil unless a dry-run policy is configured
+	windowGuard *windowGuard // nil unless maintenance windows are configured
+
+	namespaceGuard *namespaceGuard // nil unless a namespace policy is configured
+	protectGuard   *protectGuard   // nil until SetProtectedResources
+	policyGuard    *policyGuard    // nil unless policies are configured; see SetPolicies
+
+	drift *driftCache // latest drift result per stored manifest
+
+	maxResultBytes int            // see SetMaxResultBytes
+	scheduler      *toolScheduler // runs read-only calls in parallel
+	cache          *ResourceCache // nil reads everything from the API server
+	results        *resultCache   // nil runs every call; see SetResultCacheTTL
+	middleware     []Middleware   // see Use
+	auditLog       string         // see SetAuditLog
+	timeouts       Timeouts       // see SetTimeouts
+
+	credentials *CredentialRefresher // nil unless set; see SetCredentialRefresher
+
+	customTools []*CustomTool // see SetCustomTools
+	registry    *Registry     // extension tools; nil uses DefaultRegistry
+
+	namespaces *namespaceList // cached for tab completion; see Namespaces
+	tools      *toolSet       // built by the first All call
+}
+
+// toolSet holds the tools All builds, wrapped in the middleware chain.
+type toolSet struct {
+	mu  sync.Mutex
+	all []tool.Tool
 }
 
 // NewKubeTools creates a new KubeTools instance with the given clientset, dynamic client, manifest manager, and API keys.
@@ -46,54 +101,185 @@ func NewKubeTools(clientset *kubernetes.Clientset, dynamicClient dynamic.Interfa
 	return &KubeTools{
 		clientset:     clientset,
 		dynamicClient: dynamicClient,
+		resolver:      NewGVRResolver(clientset.Discovery()),
 		manifest:      manifest,
 		jinaAPIKey:    jinaAPIKey,
 		tavilyAPIKey:  tavilyAPIKey,
+		drift:         newDriftCache(),
+		scheduler:     newToolScheduler(),
+		namespaces:    &namespaceList{},
+		tools:         &toolSet{},
 	}
 }
 
+// Resolver returns the discovery-backed GVR resolver shared by all tools.
+func (k *KubeTools) Resolver() *GVRResolver {
+	return k.resolver
+}
+
+// SetPrometheus configures the Prometheus server used by query_prometheus.
+// The bearer token is optional.
+func (k *KubeTools) SetPrometheus(url, bearerToken string) {
+	k.prometheusURL = url
+	k.prometheusToken = bearerToken
+}
+
+// SetTrivy enables vulnerability scans in inspect_image with the trivy
+// binary (default "trivy" on PATH), in client mode against server if set.
+func (k *KubeTools) SetTrivy(server, binary string) {
+	if binary == "" {
+		binary = "trivy"
+	}
+	k.trivyServer = server
+	k.trivyBinary = binary
+}
+
+// SetPinDigests makes create_deployment and set_image deploy images by the
+// digest their tag resolves to, unless a call sets pin_digest to false.
+func (k *KubeTools) SetPinDigests(pin bool) {
+	k.pinDigests = pin
+}
+
+// SetRESTConfig sets the client configuration used to exec into pods.
+func (k *KubeTools) SetRESTConfig(config *rest.Config) {
+	k.restConfig = config
+}
+
 // All returns all available Kubernetes tools implementing tool.Tool interface.
+// Every tool runs through the middleware chain (see chain): results are
+// limited in size, prefetched results are picked up, panics are recovered
+// and arguments validated, calls are bounded by their category's timeout,
+// recent results of identical read-only calls are reused, calls outside
+// the namespace policy are refused, and mutating calls are audited and
+// subject to the protected resources, maintenance windows and dry-run
+// policy, if set.
+//
+// The tools are built on the first call and shared by later ones, so
+// configure KubeTools before calling it.
 func (k *KubeTools) All() []tool.Tool {
-	return []tool.Tool{
+	if k.tools == nil {
+		return k.buildTools()
+	}
+	k.tools.mu.Lock()
+	defer k.tools.mu.Unlock()
+	if k.tools.all == nil {
+		k.tools.all = k.buildTools()
+	}
+	return slices.Clone(k.tools.all)
+}
+
+// buildTools constructs every tool and wraps it in the middleware chain.
+func (k *KubeTools) buildTools() []tool.Tool {
+	all := k.useCache(k.baseTools())
+	if k.windowGuard != nil {
+		all = append(all, NewRequestWindowOverrideTool(k.windowGuard))
+	}
+	for _, t := range k.customTools {
+		all = append(all, t)
+	}
+	return withMiddleware(all, k.chain()...)
+}
+
+// baseTools constructs every tool, including the registered extension
+// tools, against this KubeTools' clients.
+func (k *KubeTools) baseTools() []tool.Tool {
+	builtin := []tool.Tool{
 		NewListNamespacesTool(k.clientset),
+		NewListNodesTool(k.clientset),
+		NewGetNodeTool(k.clientset),
 		NewCreateNamespaceTool(k.clientset),
 		NewDeleteNamespaceTool(k.clientset, k.manifest),
 		NewListPodsTool(k.clientset),
 		NewGetLogsTool(k.clientset),
+		NewAnalyzeLogsTool(k.clientset),
 		NewGetEventsTool(k.clientset),
-		NewGetResourceTool(k.clientset, k.dynamicClient),
+		NewWatchEventsTool(k.clientset),
+		NewResourceTimelineTool(k.clientset, k.manifest),
+		NewListQuotasTool(k.clientset),
+		NewGetQuotaUsageTool(k.clientset),
+		NewWhyPendingTool(k.clientset),
+		NewCheckServiceTool(k.clientset),
+		NewProbeServiceTool(k.clientset),
+		NewCheckIngressTool(k.clientset, k.dynamicClient, k.resolver),
+		NewCheckCertificateTool(k.dynamicClient, k.resolver),
+		NewGetResourceTool(k.clientset, k.dynamicClient, k.resolver),
+		NewOwnershipTool(k.dynamicClient, k.resolver),
+		NewResourceTreeTool(k.dynamicClient, k.resolver),
+		NewListArgoCDApplicationsTool(k.dynamicClient, k.resolver),
+		NewListFluxResourcesTool(k.dynamicClient, k.resolver),
+		NewGetApplicationStatusTool(k.dynamicClient, k.resolver),
 		NewGetReferenceTool(),
-		NewCreateDeploymentTool(k.clientset, k.manifest),
+		NewCreateDeploymentTool(k.clientset, k.manifest, k.pinDigests),
+		NewSetImageTool(k.clientset, k.manifest, k.pinDigests),
+		NewSetResourcesTool(k.clientset, k.manifest),
+		NewAddContainerTool(k.clientset, k.manifest),
+		NewCanaryDeployTool(k.clientset, k.manifest),
+		NewPauseRolloutTool(k.clientset),
+		NewResumeRolloutTool(k.clientset),
+		NewDeploymentHistoryTool(k.clientset),
+		NewRollbackDeploymentTool(k.clientset, k.manifest),
+		NewEvictPodTool(k.clientset),
+		NewCopyFromPodTool(k.clientset, k.restConfig),
+		NewCopyToPodTool(k.clientset, k.restConfig, k.manifest.IsDryRun()),
+		NewCordonNodeTool(k.clientset),
+		NewDrainNodeTool(k.clientset),
 		NewCreateServiceTool(k.clientset, k.manifest),
 		NewCreateConfigMapTool(k.clientset, k.manifest),
 		NewCreateSecretTool(k.clientset, k.manifest),
+		NewCreateServiceAccountTool(k.clientset, k.manifest),
+		NewCreateImagePullSecretTool(k.clientset, k.manifest),
+		NewUpdateConfigMapKeyTool(k.clientset, k.manifest),
+		NewUpdateSecretKeyTool(k.clientset, k.manifest),
 		NewCreateIngressTool(k.clientset, k.manifest),
+		NewCreateGatewayTool(k.dynamicClient, k.resolver, k.manifest),
+		NewCreateHTTPRouteTool(k.dynamicClient, k.resolver, k.manifest),
+		NewCreatePDBTool(k.clientset, k.manifest),
 		NewCheckDeploymentHealthTool(k.clientset),
+		NewClusterOverviewTool(k.clientset),
 		NewCommitManifestsTool(k.manifest),
 		NewSyncManifestsTool(k.manifest),
 		NewPushManifestsTool(k.manifest),
-		NewListManifestsTool(k.manifest),
+		NewListManifestsTool(k.manifest, k.dynamicClient, k.resolver, k.drift),
 		NewReadManifestTool(k.manifest),
+		NewExportAppTool(k.manifest),
+		NewEditManifestFieldTool(k.manifest),
+		NewEditManifestTool(k.manifest),
 		NewDeleteManifestTool(k.clientset, k.manifest),
-		NewDeleteResourceTool(k.clientset, k.dynamicClient, k.manifest),
-		NewImportResourceTool(k.clientset, k.dynamicClient, k.manifest),
-		NewApplyManifestTool(k.clientset, k.manifest),
+		NewDeleteResourceTool(k.clientset, k.dynamicClient, k.resolver, k.manifest),
+		NewLabelResourceTool(k.dynamicClient, k.resolver, k.manifest),
+		NewAnnotateResourceTool(k.dynamicClient, k.resolver, k.manifest),
+		NewImportResourceTool(k.clientset, k.dynamicClient, k.resolver, k.manifest),
+		NewImportNamespaceTool(k.dynamicClient, k.resolver, k.manifest),
+		NewApplyManifestTool(k.clientset, k.dynamicClient, k.resolver, k.manifest),
 		NewDryRunApplyTool(k.clientset, k.manifest),
-		NewProposePlanTool(),
+		NewProposePlanTool(k.clientset),
 		NewAskClarificationTool(),
+		NewPickResourceTool(),
 		// Generic resource tools using dynamic client
-		NewApplyResourceTool(k.dynamicClient, k.manifest),
-		NewListResourcesTool(k.dynamicClient),
-		NewDiffResourceTool(k.dynamicClient, k.manifest),
+		NewApplyResourceTool(k.dynamicClient, k.resolver, k.manifest),
+		NewListResourcesTool(k.dynamicClient, k.resolver),
+		NewFindResourceTool(k.dynamicClient, k.resolver),
+		NewDiffResourceTool(k.clientset, k.dynamicClient, k.resolver, k.manifest, k.drift),
+		NewAdoptDriftTool(k.dynamicClient, k.resolver, k.manifest),
+		NewFindOrphansTool(k.dynamicClient, k.resolver, k.manifest),
+		NewDiffEnvTool(k.clientset),
+		NewExplainResourceTool(k.clientset.Discovery()),
+		NewValidateManifestTool(k.clientset.Discovery(), k.manifest),
+		NewCheckPoliciesTool(k.policies(), k.manifest),
 		// Utility tools
 		NewSleepTool(),
-		NewWaitForConditionTool(k.clientset, k.dynamicClient),
+		NewWaitForConditionTool(k.clientset, k.dynamicClient, k.resolver),
 		// Web tools
 		NewFetchUrlTool(k.jinaAPIKey),
 		NewSearchWebTool(k.tavilyAPIKey),
+		// Metrics
+		NewQueryPrometheusTool(k.prometheusURL, k.prometheusToken),
+		// Container images
+		NewInspectImageTool(k.clientset, k.trivyBinary, k.trivyServer),
 		// HTTP verification tool
 		NewHTTPRequestTool(),
 	}
+	return append(builtin, k.registeredTools(builtin)...)
 }
 
 // ReadOnlyTools returns tools that only read data and have no side effects.
@@ -120,11 +306,27 @@ func (k *KubeTools) MutatingTools() []tool.Tool {
 	return result
 }
 
+// IsMutatingName returns true if the named tool is classified as mutating.
+func (k *KubeTools) IsMutatingName(name string) bool {
+	for _, t := range k.MutatingTools() {
+		if t.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
 // GenerateToolDocs generates markdown documentation for all tools organized by category.
 func (k *KubeTools) GenerateToolDocs() string {
+	return FormatToolDocs(k.All())
+}
+
+// FormatToolDocs generates markdown documentation for the given tools
+// organized by category. Categories without tools are left out.
+func FormatToolDocs(all []tool.Tool) string {
 	var readOnly, mutating, planning []string
 
-	for _, t := range k.All() {
+	for _, t := range all {
 		ft, ok := t.(functionTool)
 		if !ok {
 			continue
@@ -141,17 +343,41 @@ func (k *KubeTools) GenerateToolDocs() string {
 		}
 	}
 
-	return fmt.Sprintf(`### Read-Only Tools (use freely for gathering information)
-%s
+	var sections []string
+	for _, section := range []struct {
+		heading string
+		lines   []string
+	}{
+		{"### Read-Only Tools (use freely for gathering information)", readOnly},
+		{"### Mutating Tools (require plan approval)", mutating},
+		{"### Planning Tools", planning},
+	} {
+		if len(section.lines) > 0 {
+			sections = append(sections, section.heading+"\n"+strings.Join(section.lines, "\n"))
+		}
+	}
+	return strings.Join(sections, "\n\n")
+}
 
-### Mutating Tools (require plan approval)
-%s
+// injectedArgs are the parameters addFunctionTool adds to every
+// declaration. They don't change what a call does.
+var injectedArgs = []string{"reason", "timeout_seconds"}
 
-### Planning Tools
-%s`,
-		strings.Join(readOnly, "\n"),
-		strings.Join(mutating, "\n"),
-		strings.Join(planning, "\n"))
+// argsKey identifies the arguments of a call, leaving out injectedArgs: a
+// hash of their JSON encoding, which sorts map keys.
+func argsKey(args map[string]any) string {
+	trimmed := make(map[string]any, len(args))
+	for k, v := range args {
+		if !slices.Contains(injectedArgs, k) {
+			trimmed[k] = v
+		}
+	}
+	encoded, err := json.Marshal(trimmed)
+	if err != nil {
+		encoded = fmt.Appendf(nil, "%v", trimmed)
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
 }
 
 // functionTool is an interface for tools that provide function declarations and categories.
@@ -179,6 +405,10 @@ func addFunctionTool(req *model.LLMRequest, t functionTool) error {
 			Type:        "string",
 			Description: "Brief explanation of why you are calling this tool (shown to user)",
 		}
+		decl.Parameters.Properties["timeout_seconds"] = &genai.Schema{
+			Type:        "number",
+			Description: "Optional: allow this call more time than its default timeout, for operations on large clusters or that take long",
+		}
 	}
 
 	// Add to tools map for execution lookup
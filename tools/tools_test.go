@@ -186,7 +186,7 @@ func TestListPodsTool(t *testing.T) {
 
 // TestGetResourceTool tests the get_resource tool.
 func TestGetResourceTool(t *testing.T) {
-	tool := NewGetResourceTool(clientset, dynamicClient)
+	tool := NewGetResourceTool(clientset, dynamicClient, NewGVRResolver(clientset.Discovery()))
 
 	nsName := "test-get-resource"
 	createTestNamespace(t, clientset, nsName)
@@ -419,7 +419,7 @@ func TestCreateDeploymentTool(t *testing.T) {
 	createTestNamespace(t, clientset, nsName)
 	mgr := newTestManifestManager(t)
 
-	tool := NewCreateDeploymentTool(clientset, mgr)
+	tool := NewCreateDeploymentTool(clientset, mgr, false)
 
 	t.Run("creates new deployment", func(t *testing.T) {
 		result, err := tool.Run(nil, map[string]any{
@@ -502,6 +502,8 @@ func TestCreateDeploymentTool(t *testing.T) {
 			"env": map[string]any{
 				"ENV_VAR": "value",
 			},
+			"service_account":   "builder",
+			"image_pull_secret": "ghcr-creds",
 		})
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -524,6 +526,14 @@ func TestCreateDeploymentTool(t *testing.T) {
 		if deploy.Spec.Template.Spec.Containers[0].LivenessProbe == nil {
 			t.Error("expected liveness probe")
 		}
+
+		if deploy.Spec.Template.Spec.ServiceAccountName != "builder" {
+			t.Errorf("expected service account builder, got %q", deploy.Spec.Template.Spec.ServiceAccountName)
+		}
+
+		if ps := deploy.Spec.Template.Spec.ImagePullSecrets; len(ps) != 1 || ps[0].Name != "ghcr-creds" {
+			t.Errorf("expected image pull secret ghcr-creds, got %v", ps)
+		}
 	})
 
 	t.Run("validates required parameters", func(t *testing.T) {
@@ -634,7 +644,7 @@ func TestManifestTools(t *testing.T) {
 	mgr := newTestManifestManager(t)
 
 	t.Run("list_manifests", func(t *testing.T) {
-		tool := NewListManifestsTool(mgr)
+		tool := NewListManifestsTool(mgr, nil, nil, nil)
 
 		// Initially empty
 		result, err := tool.Run(nil, map[string]any{})
@@ -752,7 +762,7 @@ func TestDeleteManifestTool(t *testing.T) {
 
 	t.Run("deletes single manifest", func(t *testing.T) {
 		// Create a deployment in cluster and manifest
-		createTool := NewCreateDeploymentTool(clientset, mgr)
+		createTool := NewCreateDeploymentTool(clientset, mgr, false)
 		_, err := createTool.Run(nil, map[string]any{
 			"name":      "to-delete",
 			"namespace": nsName,
@@ -830,7 +840,7 @@ func TestImportResourceTool(t *testing.T) {
 	createTestNamespace(t, clientset, nsName)
 	mgr := newTestManifestManager(t)
 
-	tool := NewImportResourceTool(clientset, dynamicClient, mgr)
+	tool := NewImportResourceTool(clientset, dynamicClient, NewGVRResolver(clientset.Discovery()), mgr)
 
 	t.Run("imports deployment", func(t *testing.T) {
 		createTestDeployment(t, clientset, nsName, "existing-deploy")
@@ -1059,7 +1069,7 @@ func TestDeleteResourceTool(t *testing.T) {
 	createTestNamespace(t, clientset, nsName)
 	mgr := newTestManifestManager(t)
 
-	tool := NewDeleteResourceTool(clientset, dynamicClient, mgr)
+	tool := NewDeleteResourceTool(clientset, dynamicClient, NewGVRResolver(clientset.Discovery()), mgr)
 
 	t.Run("deletes deployment from cluster", func(t *testing.T) {
 		// Create deployment directly (not using helper to avoid cleanup conflict)
@@ -1175,7 +1185,7 @@ func TestDeleteResourceTool(t *testing.T) {
 
 	t.Run("also deletes manifest when requested", func(t *testing.T) {
 		// Create deployment with manifest
-		createTool := NewCreateDeploymentTool(clientset, mgr)
+		createTool := NewCreateDeploymentTool(clientset, mgr, false)
 		_, err := createTool.Run(nil, map[string]any{
 			"name":      "with-manifest",
 			"namespace": nsName,
@@ -1341,36 +1351,90 @@ func TestKubeToolsAll(t *testing.T) {
 
 	expectedTools := []string{
 		"list_namespaces",
+		"list_nodes",
+		"get_node",
 		"create_namespace",
 		"delete_namespace",
 		"list_pods",
 		"get_logs",
+		"analyze_logs",
 		"get_events",
+		"watch_events",
+		"resource_timeline",
+		"list_quotas",
+		"get_quota_usage",
+		"why_pending",
+		"check_service",
+		"probe_service",
+		"check_ingress",
+		"check_certificate",
 		"get_resource",
+		"get_ownership",
+		"resource_tree",
+		"list_argocd_applications",
+		"list_flux_resources",
+		"get_application_status",
 		"get_reference",
 		"create_deployment",
+		"set_image",
+		"set_resources",
+		"add_container",
+		"canary_deploy",
+		"pause_rollout",
+		"resume_rollout",
+		"deployment_history",
+		"rollback_deployment",
+		"evict_pod",
+		"cp_from_pod",
+		"cp_to_pod",
+		"cordon_node",
+		"drain_node",
 		"create_service",
 		"create_configmap",
 		"create_secret",
+		"create_serviceaccount",
+		"create_image_pull_secret",
+		"update_configmap_key",
+		"update_secret_key",
 		"create_ingress",
+		"create_gateway",
+		"create_httproute",
+		"create_pdb",
 		"check_deployment_health",
+		"cluster_overview",
 		"commit_manifests",
 		"list_manifests",
 		"read_manifest",
+		"export_app",
+		"edit_manifest_field",
+		"edit_manifest",
 		"delete_manifest",
 		"delete_resource",
+		"label_resource",
+		"annotate_resource",
 		"import_resource",
+		"import_namespace",
 		"apply_manifest",
 		"dry_run_apply",
 		"propose_plan",
 		"ask_clarification",
+		"pick_resource",
 		"apply_resource",
 		"list_resources",
+		"find_resource",
 		"diff_resource",
+		"adopt_drift",
+		"find_orphans",
+		"diff_env",
+		"explain_resource",
+		"validate_manifest",
+		"check_policies",
 		"sleep",
 		"wait_for_condition",
 		"fetch_url",
 		"search_web",
+		"query_prometheus",
+		"inspect_image",
 		"http_request",
 	}
 
@@ -1447,3 +1511,111 @@ func TestHTTPRequestTool(t *testing.T) {
 		}
 	})
 }
+
+// TestEvictPodTool tests the evict_pod tool.
+func TestEvictPodTool(t *testing.T) {
+	nsName := "test-evict-pod"
+	createTestNamespace(t, clientset, nsName)
+
+	tool := NewEvictPodTool(clientset)
+
+	t.Run("refuses unmanaged pod by default", func(t *testing.T) {
+		createTestPod(t, clientset, nsName, "unmanaged-pod", nil)
+
+		result, err := tool.Run(nil, map[string]any{
+			"namespace": nsName,
+			"name":      "unmanaged-pod",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := result["error"]; !ok {
+			t.Errorf("expected error for unmanaged pod, got: %v", result)
+		}
+	})
+
+	t.Run("evicts unmanaged pod when allowed", func(t *testing.T) {
+		result, err := tool.Run(nil, map[string]any{
+			"namespace":       nsName,
+			"name":            "unmanaged-pod",
+			"allow_unmanaged": true,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result["success"] != true {
+			t.Errorf("expected success, got: %v", result)
+		}
+	})
+
+	t.Run("returns error for missing pod", func(t *testing.T) {
+		result, err := tool.Run(nil, map[string]any{
+			"namespace": nsName,
+			"name":      "no-such-pod",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := result["error"]; !ok {
+			t.Error("expected error for missing pod")
+		}
+	})
+}
+
+func TestCreateImagePullSecretTool(t *testing.T) {
+	nsName := "test-pull-secret"
+	createTestNamespace(t, clientset, nsName)
+	mgr := newTestManifestManager(t)
+
+	saResult, err := NewCreateServiceAccountTool(clientset, mgr).Run(nil, map[string]any{
+		"name":      "builder",
+		"namespace": nsName,
+	})
+	if err != nil || saResult["success"] != true {
+		t.Fatalf("failed to create serviceaccount: %v %v", saResult, err)
+	}
+
+	tool := NewCreateImagePullSecretTool(clientset, mgr)
+	result, err := tool.Run(nil, map[string]any{
+		"name":            "ghcr-creds",
+		"namespace":       nsName,
+		"registry":        "ghcr.io",
+		"username":        "bot",
+		"password":        "token",
+		"service_account": "builder",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["success"] != true || result["service_account"] != "builder" {
+		t.Fatalf("expected success attached to builder, got: %v", result)
+	}
+
+	secret, err := clientset.CoreV1().Secrets(nsName).Get(t.Context(), "ghcr-creds", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	if secret.Type != corev1.SecretTypeDockerConfigJson {
+		t.Errorf("expected dockerconfigjson secret, got %s", secret.Type)
+	}
+
+	sa, err := clientset.CoreV1().ServiceAccounts(nsName).Get(t.Context(), "builder", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get serviceaccount: %v", err)
+	}
+	if len(sa.ImagePullSecrets) != 1 || sa.ImagePullSecrets[0].Name != "ghcr-creds" {
+		t.Errorf("expected ghcr-creds on serviceaccount, got %v", sa.ImagePullSecrets)
+	}
+
+	// Running again doesn't add the secret twice
+	if _, err := tool.Run(nil, map[string]any{
+		"name": "ghcr-creds", "namespace": nsName, "registry": "ghcr.io",
+		"username": "bot", "password": "rotated", "service_account": "builder",
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sa, _ = clientset.CoreV1().ServiceAccounts(nsName).Get(t.Context(), "builder", metav1.GetOptions{})
+	if len(sa.ImagePullSecrets) != 1 {
+		t.Errorf("expected one image pull secret after rerun, got %v", sa.ImagePullSecrets)
+	}
+}
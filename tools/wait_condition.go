@@ -23,13 +23,15 @@ import (
 type WaitForConditionTool struct {
 	clientset     *kubernetes.Clientset
 	dynamicClient dynamic.Interface
+	resolver      *GVRResolver
 }
 
 // NewWaitForConditionTool creates a new WaitForConditionTool.
-func NewWaitForConditionTool(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface) *WaitForConditionTool {
+func NewWaitForConditionTool(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, resolver *GVRResolver) *WaitForConditionTool {
 	return &WaitForConditionTool{
 		clientset:     clientset,
 		dynamicClient: dynamicClient,
+		resolver:      resolver,
 	}
 }
 
@@ -48,7 +50,9 @@ Supported conditions by resource type:
 - job: complete, failed
 - statefulset: ready
 - pvc: bound
-- any resource: deleted, exists`
+- any resource: deleted, exists
+
+For anything else (CRDs such as Certificates, HTTPRoutes or Argo Rollouts, or specific fields), pass an expression instead of a condition: a JSONPath with an optional comparison, e.g. status.conditions[?(@.type=="Ready")].status == "True" or status.readyReplicas >= 3. Without a comparison the expression is met when the path has a non-empty value.`
 }
 
 // IsLongRunning returns true as this tool may poll for extended periods.
@@ -88,14 +92,22 @@ func (t *WaitForConditionTool) Declaration() *genai.FunctionDeclaration {
 				},
 				"condition": {
 					Type:        "string",
-					Description: "The condition to wait for (e.g., available, ready, complete, deleted, exists)",
+					Description: "The condition to wait for (e.g., available, ready, complete, deleted, exists). Required unless expression is given",
+				},
+				"expression": {
+					Type:        "string",
+					Description: "JSONPath expression with optional comparison (==, !=, >, >=, <, <=) to wait for on any resource, e.g. status.conditions[?(@.type==\"Ready\")].status == \"True\"",
+				},
+				"api_version": {
+					Type:        "string",
+					Description: "API version for CRDs (e.g., 'argoproj.io/v1alpha1'). Only needed for unknown resource types with expression",
 				},
 				"timeout": {
 					Type:        "integer",
 					Description: "Maximum time to wait in seconds (default: 120, max: 300)",
 				},
 			},
-			Required: []string{"kind", "name", "condition"},
+			Required: []string{"kind", "name"},
 		},
 	}
 }
@@ -125,9 +137,19 @@ func (t *WaitForConditionTool) Run(ctx tool.Context, args any) (map[string]any,
 		return map[string]any{"error": "name is required"}, nil
 	}
 
-	condition, ok := argsMap["condition"].(string)
-	if !ok || condition == "" {
-		return map[string]any{"error": "condition is required"}, nil
+	condition, _ := argsMap["condition"].(string)
+	expression, _ := argsMap["expression"].(string)
+	apiVersion, _ := argsMap["api_version"].(string)
+
+	var expr *conditionExpression
+	if expression != "" {
+		var err error
+		if expr, err = parseConditionExpression(expression); err != nil {
+			return map[string]any{"error": err.Error()}, nil
+		}
+		condition = fmt.Sprintf("matching %s", expression)
+	} else if condition == "" {
+		return map[string]any{"error": "condition or expression is required"}, nil
 	}
 
 	// Extract optional parameters
@@ -155,6 +177,29 @@ func (t *WaitForConditionTool) Run(ctx tool.Context, args any) (map[string]any,
 
 	// Start polling
 	startTime := time.Now()
+	goCtx := toolContext(ctx)
+	check := func() (bool, string, error) {
+		if expr != nil {
+			ctx, cancel := context.WithTimeout(goCtx, 10*time.Second)
+			defer cancel()
+			return t.checkExpression(ctx, normalizedKind, apiVersion, name, namespace, expr)
+		}
+		return t.checkCondition(goCtx, normalizedKind, name, namespace, condition)
+	}
+
+	// cancelled reports that the user interrupted the wait
+	cancelled := func(polls int, state string) map[string]any {
+		return map[string]any{
+			"success":         false,
+			"condition_met":   false,
+			"cancelled":       true,
+			"elapsed_seconds": int(time.Since(startTime).Seconds()),
+			"polls":           polls,
+			"final_state":     state,
+			"message":         fmt.Sprintf("Cancelled while waiting for %s %s/%s to be %s", kind, namespace, name, condition),
+		}
+	}
+
 	pollInterval := 2 * time.Second
 	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
@@ -165,7 +210,10 @@ func (t *WaitForConditionTool) Run(ctx tool.Context, args any) (map[string]any,
 	for {
 		polls++
 
-		met, state, err := t.checkCondition(normalizedKind, name, namespace, condition)
+		met, state, err := check()
+		if goCtx.Err() != nil {
+			return cancelled(polls, state), nil
+		}
 		if err != nil {
 			// For "deleted" condition, NotFound error means success
 			if condition == "deleted" && errors.IsNotFound(err) {
@@ -231,11 +279,13 @@ func (t *WaitForConditionTool) Run(ctx tool.Context, args any) (map[string]any,
 
 		// Wait for next poll
 		select {
+		case <-goCtx.Done():
+			return cancelled(polls, state), nil
 		case <-ticker.C:
 			continue
 		case <-time.After(timeoutDuration - time.Since(startTime)):
 			// Final check before timeout
-			met, state, err := t.checkCondition(normalizedKind, name, namespace, condition)
+			met, state, err := check()
 			if err == nil && met {
 				elapsed := time.Since(startTime).Seconds()
 				return map[string]any{
@@ -261,8 +311,8 @@ func (t *WaitForConditionTool) Run(ctx tool.Context, args any) (map[string]any,
 
 // checkCondition checks if the resource meets the specified condition.
 // Returns (conditionMet, statusMessage, error).
-func (t *WaitForConditionTool) checkCondition(kind, name, namespace, condition string) (bool, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (t *WaitForConditionTool) checkCondition(ctx context.Context, kind, name, namespace, condition string) (bool, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	switch kind {
@@ -545,14 +595,13 @@ func (t *WaitForConditionTool) getDeploymentPods(ctx context.Context, dep *appsv
 
 // checkGenericCondition checks exists/deleted conditions for any resource type.
 func (t *WaitForConditionTool) checkGenericCondition(ctx context.Context, kind, name, namespace, condition string) (bool, string, error) {
-	// Look up GVR for the kind
-	gvr, ok := LookupGVR(kind)
-	if !ok {
-		return false, "", fmt.Errorf("unknown resource kind: %s", kind)
+	// Resolve GVR via discovery
+	gvr, err := t.resolver.Resolve(kind, "")
+	if err != nil {
+		return false, "", err
 	}
 
-	var err error
-	if IsNamespaced(kind) {
+	if t.resolver.IsNamespaced(gvr, kind) {
 		_, err = t.dynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
 	} else {
 		_, err = t.dynamicClient.Resource(gvr).Get(ctx, name, metav1.GetOptions{})
@@ -0,0 +1,217 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// conditionOperators are the comparisons supported in wait expressions,
+// two-character operators first so ">=" isn't read as ">".
+var conditionOperators = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+// conditionExpression is a parsed wait_for_condition expression of the form
+// `<jsonpath> [<op> <value>]`, e.g.
+// `status.conditions[?(@.type=="Ready")].status == "True"`. Without an
+// operator the expression holds when the path yields a non-empty value.
+type conditionExpression struct {
+	source string
+	path   *jsonpath.JSONPath
+	op     string
+	value  string
+}
+
+// parseConditionExpression parses a wait expression. The path may be given
+// with or without kubectl's {} delimiters and leading dot.
+func parseConditionExpression(expr string) (*conditionExpression, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("expression is empty")
+	}
+
+	pathPart, op, value := splitConditionExpression(expr)
+	pathPart = strings.TrimSpace(pathPart)
+	if pathPart == "" {
+		return nil, fmt.Errorf("expression %q has no path", expr)
+	}
+	if op != "" {
+		value = unquote(strings.TrimSpace(value))
+		if value == "" && op != "==" && op != "!=" {
+			return nil, fmt.Errorf("expression %q has no value to compare with", expr)
+		}
+		if op != "==" && op != "!=" {
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("operator %s needs a number, got %q", op, value)
+			}
+		}
+	}
+
+	template := pathPart
+	if !strings.HasPrefix(template, "{") {
+		if !strings.HasPrefix(template, ".") {
+			template = "." + template
+		}
+		template = "{" + template + "}"
+	}
+
+	jp := jsonpath.New("condition").AllowMissingKeys(true)
+	if err := jp.Parse(template); err != nil {
+		return nil, fmt.Errorf("invalid JSONPath %q: %w", pathPart, err)
+	}
+
+	return &conditionExpression{source: expr, path: jp, op: op, value: value}, nil
+}
+
+// splitConditionExpression finds the first comparison operator outside of
+// brackets, parentheses and quotes, so filters like [?(@.type=="Ready")]
+// stay part of the path.
+func splitConditionExpression(expr string) (string, string, string) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '"' || c == '\'':
+			quote = c
+			continue
+		case c == '[' || c == '(' || c == '{':
+			depth++
+			continue
+		case c == ']' || c == ')' || c == '}':
+			depth--
+			continue
+		}
+		if depth != 0 {
+			continue
+		}
+		for _, op := range conditionOperators {
+			if strings.HasPrefix(expr[i:], op) {
+				return expr[:i], op, expr[i+len(op):]
+			}
+		}
+	}
+	return expr, "", ""
+}
+
+// unquote strips matching single or double quotes around s.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// evaluate reports whether obj satisfies the expression, along with the
+// values the path resolved to.
+func (e *conditionExpression) evaluate(obj map[string]any) (bool, []string, error) {
+	results, err := e.path.FindResults(obj)
+	if err != nil {
+		return false, nil, err
+	}
+
+	var values []string
+	for _, r := range results {
+		for _, v := range r {
+			values = append(values, fmt.Sprint(v.Interface()))
+		}
+	}
+
+	switch e.op {
+	case "":
+		for _, v := range values {
+			if v != "" && v != "false" {
+				return true, values, nil
+			}
+		}
+		return false, values, nil
+	case "!=":
+		if len(values) == 0 {
+			return false, values, nil
+		}
+		for _, v := range values {
+			if v == e.value {
+				return false, values, nil
+			}
+		}
+		return true, values, nil
+	case "==":
+		for _, v := range values {
+			if v == e.value {
+				return true, values, nil
+			}
+		}
+		return false, values, nil
+	default:
+		want, _ := strconv.ParseFloat(e.value, 64)
+		for _, v := range values {
+			got, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			if compareFloat(got, e.op, want) {
+				return true, values, nil
+			}
+		}
+		return false, values, nil
+	}
+}
+
+// compareFloat applies a numeric comparison operator.
+func compareFloat(a float64, op string, b float64) bool {
+	switch op {
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
+
+// checkExpression fetches any resource via the dynamic client and evaluates
+// the expression against it. A missing resource is reported as not yet met,
+// so callers can wait for resources that are still being created.
+func (t *WaitForConditionTool) checkExpression(ctx context.Context, kind, apiVersion, name, namespace string, expr *conditionExpression) (bool, string, error) {
+	gvr, err := t.resolver.Resolve(kind, apiVersion)
+	if err != nil {
+		return false, "", err
+	}
+
+	var resourceClient dynamic.ResourceInterface
+	if t.resolver.IsNamespaced(gvr, kind) {
+		resourceClient = t.dynamicClient.Resource(gvr).Namespace(namespace)
+	} else {
+		resourceClient = t.dynamicClient.Resource(gvr)
+	}
+
+	obj, err := resourceClient.Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, "Resource not found", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+
+	met, values, err := expr.evaluate(obj.Object)
+	if err != nil {
+		return false, "", fmt.Errorf("evaluating %q: %w", expr.source, err)
+	}
+	if len(values) == 0 {
+		return met, "Path not present", nil
+	}
+	return met, fmt.Sprintf("Path value: %s", strings.Join(values, ", ")), nil
+}
@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"testing"
+)
+
+func TestConditionExpression(t *testing.T) {
+	certificate := map[string]any{
+		"status": map[string]any{
+			"readyReplicas": int64(2),
+			"phase":         "Healthy",
+			"conditions": []any{
+				map[string]any{"type": "Issuing", "status": "False"},
+				map[string]any{"type": "Ready", "status": "True"},
+			},
+		},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`status.conditions[?(@.type=="Ready")].status == "True"`, true},
+		{`status.conditions[?(@.type=="Issuing")].status == "True"`, false},
+		{`{.status.conditions[?(@.type=="Ready")].status}=True`, true},
+		{`.status.phase == 'Healthy'`, true},
+		{`status.phase != Degraded`, true},
+		{`status.phase != Healthy`, false},
+		{`status.readyReplicas >= 2`, true},
+		{`status.readyReplicas>2`, false},
+		{`status.readyReplicas < 3`, true},
+		{`status.phase`, true},
+		{`status.missing`, false},
+		{`status.missing != x`, false},
+	}
+	for _, tt := range tests {
+		expr, err := parseConditionExpression(tt.expr)
+		if err != nil {
+			t.Errorf("parse %q: %v", tt.expr, err)
+			continue
+		}
+		got, values, err := expr.evaluate(certificate)
+		if err != nil {
+			t.Errorf("evaluate %q: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q = %v (values %v), want %v", tt.expr, got, values, tt.want)
+		}
+	}
+}
+
+func TestParseConditionExpression_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"== True",
+		"status.readyReplicas > many",
+		"status.conditions[?(@.type==",
+	} {
+		if _, err := parseConditionExpression(expr); err == nil {
+			t.Errorf("parseConditionExpression(%q) succeeded, want error", expr)
+		}
+	}
+}
@@ -0,0 +1,655 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// pendingMaxNodes caps the node names listed per cause.
+const pendingMaxNodes = 5
+
+// PendingCause is one probable reason a pod is not running, most likely first.
+type PendingCause struct {
+	Cause      string   `json:"cause"`
+	Detail     string   `json:"detail,omitempty"`
+	Nodes      []string `json:"nodes,omitempty"` // nodes this rules out
+	Suggestion string   `json:"suggestion,omitempty"`
+
+	score int
+}
+
+// WhyPendingTool provides the why_pending tool for the agent.
+type WhyPendingTool struct {
+	clientset *kubernetes.Clientset
+}
+
+// NewWhyPendingTool creates a new WhyPendingTool.
+func NewWhyPendingTool(clientset *kubernetes.Clientset) *WhyPendingTool {
+	return &WhyPendingTool{
+		clientset: clientset,
+	}
+}
+
+// Name returns the tool name.
+func (t *WhyPendingTool) Name() string {
+	return "why_pending"
+}
+
+// Description returns the tool description.
+func (t *WhyPendingTool) Description() string {
+	return "Diagnose why a pod is stuck in Pending. Checks scheduler events, scheduling gates, cordoned and NotReady nodes, untolerated taints, nodeSelector and node affinity, requests vs node allocatable, and PVC binding, and returns a ranked list of probable causes with the nodes each one rules out. For pods already bound to a node it reports image pull, mount and config errors instead."
+}
+
+// IsLongRunning returns false as this is a quick operation.
+func (t *WhyPendingTool) IsLongRunning() bool {
+	return false
+}
+
+// Category returns the tool category.
+func (t *WhyPendingTool) Category() ToolCategory {
+	return CategoryReadOnly
+}
+
+// ProcessRequest adds this tool to the LLM request.
+func (t *WhyPendingTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	return addFunctionTool(req, t)
+}
+
+// Declaration returns the function declaration for the tool.
+func (t *WhyPendingTool) Declaration() *genai.FunctionDeclaration {
+	return &genai.FunctionDeclaration{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &genai.Schema{
+			Type: "object",
+			Properties: map[string]*genai.Schema{
+				"namespace": {
+					Type:        "string",
+					Description: "The Kubernetes namespace",
+				},
+				"pod": {
+					Type:        "string",
+					Description: "Name of the Pending pod",
+				},
+			},
+			Required: []string{"namespace", "pod"},
+		},
+	}
+}
+
+// Run executes the tool.
+func (t *WhyPendingTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	argsMap, ok := args.(map[string]any)
+	if !ok {
+		if argsStr, ok := args.(string); ok {
+			if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
+				return map[string]any{"error": "invalid arguments format"}, nil
+			}
+		} else {
+			return map[string]any{"error": "invalid arguments type"}, nil
+		}
+	}
+
+	namespace, ok := argsMap["namespace"].(string)
+	if !ok || namespace == "" {
+		return map[string]any{"error": "namespace is required"}, nil
+	}
+	name, ok := argsMap["pod"].(string)
+	if !ok || name == "" {
+		return map[string]any{"error": "pod is required"}, nil
+	}
+
+	return whyPending(toolContext(ctx), t.clientset, namespace, name), nil
+}
+
+// whyPending builds the why_pending response for a pod.
+func whyPending(ctx context.Context, clientset kubernetes.Interface, namespace, name string) map[string]any {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return map[string]any{"error": fmt.Sprintf("failed to get pod: %v", err)}
+	}
+
+	result := map[string]any{
+		"namespace": namespace,
+		"pod":       name,
+		"phase":     string(pod.Status.Phase),
+	}
+	if pod.Status.Phase != corev1.PodPending {
+		result["summary"] = fmt.Sprintf("Pod %s is %s, not Pending", name, pod.Status.Phase)
+		return result
+	}
+
+	events := podWarningEvents(ctx, clientset, pod)
+	var causes []PendingCause
+	if pod.Spec.NodeName != "" {
+		result["node"] = pod.Spec.NodeName
+		causes = startupCauses(pod, events)
+	} else {
+		schedulerMessage := lastSchedulingFailure(pod, events)
+		if schedulerMessage != "" {
+			result["scheduler_message"] = schedulerMessage
+		}
+		causes, err = schedulingCauses(ctx, clientset, pod, schedulerMessage)
+		if err != nil {
+			return map[string]any{"error": err.Error()}
+		}
+	}
+
+	sort.SliceStable(causes, func(i, j int) bool {
+		return causes[i].score > causes[j].score
+	})
+	result["causes"] = causes
+	if len(causes) > 0 {
+		result["summary"] = causes[0].Cause
+	} else {
+		result["summary"] = "No blocking cause found; the pod may have just been created. Check again shortly."
+	}
+	return result
+}
+
+// podWarningEvents returns the warning events of a pod, newest first.
+func podWarningEvents(ctx context.Context, clientset kubernetes.Interface, pod *corev1.Pod) []corev1.Event {
+	list, err := clientset.CoreV1().Events(pod.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("involvedObject.name=%s", pod.Name),
+	})
+	if err != nil {
+		return nil
+	}
+	var events []corev1.Event
+	for _, e := range list.Items {
+		if e.InvolvedObject.Kind == "Pod" && e.InvolvedObject.Name == pod.Name && e.Type == corev1.EventTypeWarning {
+			events = append(events, e)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return eventTime(events[i]).After(eventTime(events[j]))
+	})
+	return events
+}
+
+// lastSchedulingFailure returns the scheduler's latest explanation, e.g.
+// "0/3 nodes are available: 3 Insufficient cpu.".
+func lastSchedulingFailure(pod *corev1.Pod, events []corev1.Event) string {
+	for _, e := range events {
+		if e.Reason == "FailedScheduling" {
+			return e.Message
+		}
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse {
+			return c.Message
+		}
+	}
+	return ""
+}
+
+// nodeFilter is a scheduling constraint that rules out some nodes.
+type nodeFilter struct {
+	cause      string
+	detail     string
+	suggestion string
+	keywords   []string // phrases the scheduler uses for this constraint
+	nodes      []string
+}
+
+// schedulingCauses checks an unscheduled pod against every node, its
+// scheduling gates and its PVCs.
+func schedulingCauses(ctx context.Context, clientset kubernetes.Interface, pod *corev1.Pod, schedulerMessage string) ([]PendingCause, error) {
+	var causes []PendingCause
+	if len(pod.Spec.SchedulingGates) > 0 {
+		var gates []string
+		for _, g := range pod.Spec.SchedulingGates {
+			gates = append(gates, g.Name)
+		}
+		causes = append(causes, PendingCause{
+			Cause:      "Pod has scheduling gates, so the scheduler ignores it",
+			Detail:     "gates: " + strings.Join(gates, ", "),
+			Suggestion: "The controller that added the gates must remove them",
+			score:      100,
+		})
+	}
+	if name := pod.Spec.SchedulerName; name != "" && name != corev1.DefaultSchedulerName && schedulerMessage == "" {
+		causes = append(causes, PendingCause{
+			Cause:      fmt.Sprintf("Pod uses scheduler %q, which has not reported on it", name),
+			Suggestion: "Check that the scheduler is installed and running",
+			score:      70,
+		})
+	}
+	causes = append(causes, volumeCauses(ctx, clientset, pod, schedulerMessage)...)
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	if len(nodes.Items) == 0 {
+		return append(causes, PendingCause{Cause: "The cluster has no nodes", score: 100}), nil
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	requested := map[string]corev1.ResourceList{}
+	podCount := map[string]int64{}
+	for i := range pods.Items {
+		p := &pods.Items[i]
+		if p.Spec.NodeName == "" || podFinished(p) {
+			continue
+		}
+		if requested[p.Spec.NodeName] == nil {
+			requested[p.Spec.NodeName] = corev1.ResourceList{}
+		}
+		addResourceList(requested[p.Spec.NodeName], podRequests(&p.Spec, false))
+		podCount[p.Spec.NodeName]++
+	}
+
+	filters := map[string]*nodeFilter{}
+	var order []string
+	fail := func(key string, f nodeFilter, node string) {
+		if existing, ok := filters[key]; ok {
+			existing.nodes = append(existing.nodes, node)
+			return
+		}
+		f.nodes = []string{node}
+		filters[key] = &f
+		order = append(order, key)
+	}
+
+	need := podRequests(&pod.Spec, false)
+	feasible := 0
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		ok := true
+		checkNode(pod, node, need, requested[node.Name], podCount[node.Name], func(key string, f nodeFilter) {
+			fail(key, f, node.Name)
+			ok = false
+		})
+		if ok {
+			feasible++
+		}
+	}
+
+	total := len(nodes.Items)
+	for _, key := range order {
+		f := filters[key]
+		cause := PendingCause{
+			Cause:      fmt.Sprintf("%s (%d/%d nodes)", f.cause, len(f.nodes), total),
+			Detail:     f.detail,
+			Suggestion: f.suggestion,
+			Nodes:      f.nodes,
+			score:      40 + 50*len(f.nodes)/total,
+		}
+		if len(cause.Nodes) > pendingMaxNodes {
+			cause.Nodes = append(cause.Nodes[:pendingMaxNodes:pendingMaxNodes], fmt.Sprintf("... %d more", len(f.nodes)-pendingMaxNodes))
+		}
+		if mentions(schedulerMessage, f.keywords) {
+			cause.score += 10
+		}
+		causes = append(causes, cause)
+	}
+
+	// Constraints between pods aren't evaluated here; rank them by whether
+	// the scheduler blames them or nothing else explains the failure
+	score := 30
+	if feasible > 0 {
+		score = 60
+	}
+	if affinity := pod.Spec.Affinity; affinity != nil {
+		if affinity.PodAntiAffinity != nil && len(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) > 0 {
+			causes = append(causes, interPodCause("Required pod anti-affinity may exclude every node that already runs a matching pod",
+				"Scale down, add nodes in more topology domains, or make the anti-affinity preferred", score, schedulerMessage, "anti-affinity"))
+		}
+		if affinity.PodAffinity != nil && len(affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution) > 0 {
+			causes = append(causes, interPodCause("Required pod affinity needs a node near a matching pod, and there may be none",
+				"Check that pods matching the affinity selector are running", score, schedulerMessage, "pod affinity"))
+		}
+	}
+	for _, c := range pod.Spec.TopologySpreadConstraints {
+		if c.WhenUnsatisfiable == corev1.DoNotSchedule {
+			causes = append(causes, interPodCause(fmt.Sprintf("Topology spread on %s (maxSkew %d) may leave no allowed domain", c.TopologyKey, c.MaxSkew),
+				"Add nodes in the under-used domains or set whenUnsatisfiable: ScheduleAnyway", score, schedulerMessage, "topology spread"))
+			break
+		}
+	}
+
+	if feasible > 0 && len(causes) == 0 && schedulerMessage != "" {
+		causes = append(causes, PendingCause{
+			Cause:  "The scheduler rejected the pod for a reason not checked here",
+			Detail: schedulerMessage,
+			score:  50,
+		})
+	}
+	return causes, nil
+}
+
+func interPodCause(cause, suggestion string, score int, schedulerMessage, keyword string) PendingCause {
+	c := PendingCause{Cause: cause, Suggestion: suggestion, score: score}
+	if mentions(schedulerMessage, []string{keyword}) {
+		c.score = 95
+	}
+	return c
+}
+
+// mentions reports whether the scheduler message contains any of keywords.
+func mentions(message string, keywords []string) bool {
+	message = strings.ToLower(message)
+	for _, k := range keywords {
+		if message != "" && strings.Contains(message, strings.ToLower(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNode reports every filter that rules node out for pod.
+func checkNode(pod *corev1.Pod, node *corev1.Node, need, used corev1.ResourceList, pods int64, fail func(key string, f nodeFilter)) {
+	if node.Spec.Unschedulable {
+		fail("unschedulable", nodeFilter{
+			cause:      "Node is cordoned",
+			suggestion: "Uncordon the node with uncordon_node once maintenance is done",
+			keywords:   []string{"unschedulable"},
+		})
+	}
+	if !nodeReady(node) {
+		fail("not-ready", nodeFilter{
+			cause:      "Node is NotReady",
+			suggestion: "Inspect the node with get_node",
+			keywords:   []string{"not-ready", "not ready", "unreachable"},
+		})
+	}
+
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule || tolerates(pod.Spec.Tolerations, taint) {
+			continue
+		}
+		desc := formatTaints([]corev1.Taint{taint})[0]
+		fail("taint:"+desc, nodeFilter{
+			cause:      "Untolerated taint " + desc,
+			suggestion: fmt.Sprintf("Add a toleration for %s to the pod template, or schedule onto nodes without it", desc),
+			keywords:   []string{"untolerated taint", "had taint"},
+		})
+	}
+
+	for key, value := range pod.Spec.NodeSelector {
+		if node.Labels[key] != value {
+			fail("selector:"+key, nodeFilter{
+				cause:      fmt.Sprintf("nodeSelector %s=%s does not match", key, value),
+				suggestion: "Label a node to match, or fix the nodeSelector",
+				keywords:   []string{"node affinity/selector", "node selector"},
+			})
+		}
+	}
+	if affinity := pod.Spec.Affinity; affinity != nil && affinity.NodeAffinity != nil {
+		if required := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; required != nil && !matchesNodeSelector(required, node) {
+			fail("node-affinity", nodeFilter{
+				cause:      "Required node affinity does not match",
+				detail:     describeNodeSelector(required),
+				suggestion: "Label a node to match, or relax the node affinity",
+				keywords:   []string{"node affinity"},
+			})
+		}
+	}
+
+	allocatable := node.Status.Allocatable
+	for _, name := range sortedResourceNames(need) {
+		want := need[name]
+		if want.IsZero() {
+			continue
+		}
+		capacity, ok := allocatable[name]
+		if !ok {
+			fail("resource:"+string(name), nodeFilter{
+				cause:      fmt.Sprintf("Node has no %s", name),
+				detail:     fmt.Sprintf("pod requests %s %s", want.String(), name),
+				suggestion: fmt.Sprintf("Schedule onto nodes that provide %s", name),
+				keywords:   []string{"insufficient " + string(name)},
+			})
+			continue
+		}
+		free := capacity.DeepCopy()
+		free.Sub(used[name])
+		if want.Cmp(free) > 0 {
+			fail("resource:"+string(name), nodeFilter{
+				cause:      fmt.Sprintf("Insufficient %s", name),
+				detail:     fmt.Sprintf("pod requests %s %s", want.String(), name),
+				suggestion: fmt.Sprintf("Lower the %s request with set_resources, free capacity, or add nodes", name),
+				keywords:   []string{"insufficient " + string(name)},
+			})
+		}
+	}
+	if maxPods, ok := allocatable[corev1.ResourcePods]; ok && pods >= maxPods.Value() {
+		fail("resource:pods", nodeFilter{
+			cause:      "Node is at its pod limit",
+			suggestion: "Add nodes or remove finished pods",
+			keywords:   []string{"too many pods"},
+		})
+	}
+}
+
+// nodeReady reports whether the node's Ready condition is True.
+func nodeReady(node *corev1.Node) bool {
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// tolerates reports whether any toleration tolerates taint.
+func tolerates(tolerations []corev1.Toleration, taint corev1.Taint) bool {
+	for _, t := range tolerations {
+		if t.Effect != "" && t.Effect != taint.Effect {
+			continue
+		}
+		if t.Key == "" && t.Operator == corev1.TolerationOpExists {
+			return true
+		}
+		if t.Key != taint.Key {
+			continue
+		}
+		if t.Operator == corev1.TolerationOpExists || t.Value == taint.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesNodeSelector reports whether node satisfies any of the selector's terms.
+func matchesNodeSelector(selector *corev1.NodeSelector, node *corev1.Node) bool {
+	for _, term := range selector.NodeSelectorTerms {
+		if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+			continue
+		}
+		matched := true
+		for _, req := range term.MatchExpressions {
+			value, exists := node.Labels[req.Key]
+			if !matchesRequirement(req, value, exists) {
+				matched = false
+				break
+			}
+		}
+		for _, req := range term.MatchFields {
+			if req.Key == "metadata.name" && !matchesRequirement(req, node.Name, true) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesRequirement(req corev1.NodeSelectorRequirement, value string, exists bool) bool {
+	contains := func() bool {
+		for _, v := range req.Values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+	compare := func(less bool) bool {
+		if !exists || len(req.Values) != 1 {
+			return false
+		}
+		have, err1 := strconv.ParseInt(value, 10, 64)
+		want, err2 := strconv.ParseInt(req.Values[0], 10, 64)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		if less {
+			return have < want
+		}
+		return have > want
+	}
+
+	switch req.Operator {
+	case corev1.NodeSelectorOpIn:
+		return exists && contains()
+	case corev1.NodeSelectorOpNotIn:
+		return !exists || !contains()
+	case corev1.NodeSelectorOpExists:
+		return exists
+	case corev1.NodeSelectorOpDoesNotExist:
+		return !exists
+	case corev1.NodeSelectorOpGt:
+		return compare(false)
+	case corev1.NodeSelectorOpLt:
+		return compare(true)
+	}
+	return false
+}
+
+// describeNodeSelector renders required node affinity terms, ORed.
+func describeNodeSelector(selector *corev1.NodeSelector) string {
+	var terms []string
+	for _, term := range selector.NodeSelectorTerms {
+		var reqs []string
+		for _, req := range append(term.MatchExpressions, term.MatchFields...) {
+			reqs = append(reqs, fmt.Sprintf("%s %s %s", req.Key, req.Operator, strings.Join(req.Values, ",")))
+		}
+		terms = append(terms, strings.Join(reqs, " and "))
+	}
+	return strings.Join(terms, " or ")
+}
+
+// volumeCauses checks that the pod's PVCs exist and can bind.
+func volumeCauses(ctx context.Context, clientset kubernetes.Interface, pod *corev1.Pod, schedulerMessage string) []PendingCause {
+	var causes []PendingCause
+	for _, v := range pod.Spec.Volumes {
+		if v.PersistentVolumeClaim == nil {
+			continue
+		}
+		claim := v.PersistentVolumeClaim.ClaimName
+		pvc, err := clientset.CoreV1().PersistentVolumeClaims(pod.Namespace).Get(ctx, claim, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			causes = append(causes, PendingCause{
+				Cause:      fmt.Sprintf("PersistentVolumeClaim %s does not exist", claim),
+				Suggestion: "Create the PVC or fix the claimName in the pod template",
+				score:      95,
+			})
+			continue
+		}
+		if err != nil || pvc.Status.Phase == corev1.ClaimBound {
+			continue
+		}
+
+		cause := PendingCause{
+			Cause:      fmt.Sprintf("PersistentVolumeClaim %s is %s", claim, pvc.Status.Phase),
+			Suggestion: "Check the PVC's events with get_events for provisioning errors",
+			score:      85,
+		}
+		if sc := pvc.Spec.StorageClassName; sc != nil && *sc != "" {
+			class, err := clientset.StorageV1().StorageClasses().Get(ctx, *sc, metav1.GetOptions{})
+			switch {
+			case apierrors.IsNotFound(err):
+				cause.Detail = fmt.Sprintf("StorageClass %s does not exist", *sc)
+				cause.Suggestion = "Use an existing StorageClass (list_resources storageclasses)"
+				cause.score = 95
+			case err == nil && class.VolumeBindingMode != nil && *class.VolumeBindingMode == "WaitForFirstConsumer":
+				// Binding waits for the pod to be scheduled, so this is only
+				// a cause if the scheduler says so
+				if !mentions(schedulerMessage, []string{"persistentvolumeclaim", "volume"}) {
+					continue
+				}
+				cause.Detail = fmt.Sprintf("StorageClass %s binds on first consumer, and the scheduler found no node where a volume can be provisioned", *sc)
+			}
+		} else if sc == nil {
+			cause.Detail = "no StorageClass set and no default StorageClass picked it up"
+		}
+		causes = append(causes, cause)
+	}
+
+	if mentions(schedulerMessage, []string{"volume node affinity conflict"}) {
+		causes = append(causes, PendingCause{
+			Cause:      "A bound PersistentVolume is pinned to nodes (usually a zone) the pod cannot run on",
+			Suggestion: "Make sure schedulable nodes exist in the volume's zone",
+			score:      90,
+		})
+	}
+	return causes
+}
+
+// startupCauses explains why a pod bound to a node hasn't started.
+func startupCauses(pod *corev1.Pod, events []corev1.Event) []PendingCause {
+	var causes []PendingCause
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, s := range statuses {
+		if s.State.Waiting == nil {
+			continue
+		}
+		w := s.State.Waiting
+		cause := PendingCause{
+			Cause:  fmt.Sprintf("Container %s is waiting: %s", s.Name, w.Reason),
+			Detail: w.Message,
+			score:  60,
+		}
+		switch w.Reason {
+		case "ErrImagePull", "ImagePullBackOff", "InvalidImageName":
+			cause.Suggestion = "Check the image name and tag, and that the pod can pull it (imagePullSecrets)"
+			cause.score = 95
+		case "CreateContainerConfigError":
+			cause.Suggestion = "A referenced ConfigMap, Secret or key is missing"
+			cause.score = 95
+		case "PodInitializing":
+			continue
+		}
+		causes = append(causes, cause)
+	}
+
+	seen := map[string]bool{}
+	for _, e := range events {
+		switch e.Reason {
+		case "FailedMount", "FailedAttachVolume", "FailedCreatePodSandBox":
+		default:
+			continue
+		}
+		if seen[e.Reason] {
+			continue
+		}
+		seen[e.Reason] = true
+		causes = append(causes, PendingCause{
+			Cause:  e.Reason,
+			Detail: e.Message,
+			score:  90,
+		})
+	}
+	return causes
+}
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func pendingTestNode(name, cpu string, taints ...corev1.Taint) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"kubernetes.io/hostname": name}},
+		Spec:       corev1.NodeSpec{Taints: taints},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+				corev1.ResourcePods:   resource.MustParse("110"),
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func TestWhyPending(t *testing.T) {
+	gpuTaint := corev1.Taint{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}
+	pending := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "web", Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("2"),
+			}}}},
+			Volumes: []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "missing"},
+			}}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	running := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "busy", Namespace: "default"},
+		Spec: corev1.PodSpec{NodeName: "node-a", Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("3"),
+		}}}}},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	event := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "web-1.1", Namespace: "default"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "web-1"},
+		Type:           corev1.EventTypeWarning,
+		Reason:         "FailedScheduling",
+		Message:        "0/3 nodes are available: persistentvolumeclaim \"missing\" not found.",
+	}
+
+	clientset := fake.NewSimpleClientset(
+		pendingTestNode("node-a", "4"),
+		pendingTestNode("node-b", "8", gpuTaint),
+		pendingTestNode("node-c", "8", gpuTaint),
+		pending, running, event,
+	)
+
+	result := whyPending(context.Background(), clientset, "default", "web-1")
+	if _, ok := result["error"]; ok {
+		t.Fatalf("whyPending failed: %v", result["error"])
+	}
+	if !strings.Contains(result["scheduler_message"].(string), "0/3 nodes") {
+		t.Errorf("scheduler_message = %v", result["scheduler_message"])
+	}
+
+	causes := result["causes"].([]PendingCause)
+	if len(causes) != 3 {
+		t.Fatalf("causes = %+v, want missing PVC, taint and cpu", causes)
+	}
+	if causes[0].Cause != "PersistentVolumeClaim missing does not exist" {
+		t.Errorf("top cause = %q", causes[0].Cause)
+	}
+	if causes[1].Cause != "Untolerated taint dedicated=gpu:NoSchedule (2/3 nodes)" || len(causes[1].Nodes) != 2 {
+		t.Errorf("second cause = %+v", causes[1])
+	}
+	if causes[2].Cause != "Insufficient cpu (1/3 nodes)" || causes[2].Nodes[0] != "node-a" {
+		t.Errorf("third cause = %+v", causes[2])
+	}
+}
+
+func TestTolerates(t *testing.T) {
+	taint := corev1.Taint{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}
+	tests := []struct {
+		name       string
+		toleration corev1.Toleration
+		want       bool
+	}{
+		{"equal", corev1.Toleration{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}, true},
+		{"exists any effect", corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpExists}, true},
+		{"wildcard", corev1.Toleration{Operator: corev1.TolerationOpExists}, true},
+		{"wrong value", corev1.Toleration{Key: "dedicated", Value: "cpu"}, false},
+		{"wrong effect", corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute}, false},
+	}
+	for _, tt := range tests {
+		if got := tolerates([]corev1.Toleration{tt.toleration}, taint); got != tt.want {
+			t.Errorf("%s: tolerates = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesNodeSelector(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1", Labels: map[string]string{"zone": "a", "cores": "16"}}}
+	selector := func(reqs ...corev1.NodeSelectorRequirement) *corev1.NodeSelector {
+		return &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: reqs}}}
+	}
+	tests := []struct {
+		name     string
+		selector *corev1.NodeSelector
+		want     bool
+	}{
+		{"in", selector(corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a", "b"}}), true},
+		{"not in", selector(corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"a"}}), false},
+		{"gt", selector(corev1.NodeSelectorRequirement{Key: "cores", Operator: corev1.NodeSelectorOpGt, Values: []string{"8"}}), true},
+		{"does not exist", selector(corev1.NodeSelectorRequirement{Key: "gpu", Operator: corev1.NodeSelectorOpDoesNotExist}), true},
+		{"and", selector(
+			corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+			corev1.NodeSelectorRequirement{Key: "gpu", Operator: corev1.NodeSelectorOpExists},
+		), false},
+	}
+	for _, tt := range tests {
+		if got := matchesNodeSelector(tt.selector, node); got != tt.want {
+			t.Errorf("%s: matchesNodeSelector = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// yamlPathSegment is one step of a YAML path: a mapping key, a list index,
+// or a list element selected by one of its fields ([name=web]).
+type yamlPathSegment struct {
+	key        string // mapping key, or the selector field for [field=value]
+	index      int
+	isIndex    bool
+	isSelector bool
+	value      string // selector value
+}
+
+// String formats the segment as it appears in a path.
+func (s yamlPathSegment) String() string {
+	switch {
+	case s.isIndex:
+		return fmt.Sprintf("[%d]", s.index)
+	case s.isSelector:
+		return fmt.Sprintf("[%s=%s]", s.key, s.value)
+	default:
+		return s.key
+	}
+}
+
+// parseYAMLPath parses a dotted path such as
+// "spec.template.spec.containers[0].image" or
+// "spec.template.spec.containers[name=web].image".
+func parseYAMLPath(p string) ([]yamlPathSegment, error) {
+	p = strings.TrimPrefix(strings.TrimSpace(p), ".")
+	if p == "" {
+		return nil, fmt.Errorf("path is empty")
+	}
+
+	var segs []yamlPathSegment
+	for _, part := range strings.Split(p, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key != "" {
+			segs = append(segs, yamlPathSegment{key: key})
+		} else if rest == "" {
+			return nil, fmt.Errorf("invalid path %q: empty segment", p)
+		}
+		for rest != "" {
+			inner, after, ok := strings.Cut(rest, "]")
+			if !ok {
+				return nil, fmt.Errorf("invalid path %q: missing ]", p)
+			}
+			if field, value, ok := strings.Cut(inner, "="); ok {
+				if field == "" {
+					return nil, fmt.Errorf("invalid path %q: empty selector field", p)
+				}
+				segs = append(segs, yamlPathSegment{key: field, value: value, isSelector: true})
+			} else {
+				idx, err := strconv.Atoi(inner)
+				if err != nil || idx < 0 {
+					return nil, fmt.Errorf("invalid path %q: bad list index %q", p, inner)
+				}
+				segs = append(segs, yamlPathSegment{index: idx, isIndex: true})
+			}
+			if after == "" {
+				break
+			}
+			if !strings.HasPrefix(after, "[") {
+				return nil, fmt.Errorf("invalid path %q: unexpected %q after ]", p, after)
+			}
+			rest = after[1:]
+		}
+	}
+	return segs, nil
+}
+
+// parseYAMLDocument parses content into a yaml.v3 node tree, keeping comments.
+// Returns the top-level mapping of the (first) document.
+func parseYAMLDocument(content []byte) (*yaml.Node, *yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, nil, fmt.Errorf("manifest is empty")
+	}
+	return &doc, doc.Content[0], nil
+}
+
+// lookupYAMLPath walks root along segs and returns the node found.
+func lookupYAMLPath(root *yaml.Node, segs []yamlPathSegment) (*yaml.Node, error) {
+	node := root
+	for i, seg := range segs {
+		next, _, err := yamlChild(node, seg)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			return nil, fmt.Errorf("path %s not found", formatYAMLPath(segs[:i+1]))
+		}
+		node = next
+	}
+	return node, nil
+}
+
+// yamlChild returns the child of node selected by seg and its position in
+// node.Content (for mappings, the position of the key). A missing child is
+// returned as nil without error.
+func yamlChild(node *yaml.Node, seg yamlPathSegment) (*yaml.Node, int, error) {
+	switch {
+	case seg.isIndex:
+		if node.Kind != yaml.SequenceNode {
+			return nil, -1, fmt.Errorf("cannot index %s: not a list", seg)
+		}
+		if seg.index >= len(node.Content) {
+			return nil, -1, nil
+		}
+		return node.Content[seg.index], seg.index, nil
+	case seg.isSelector:
+		if node.Kind != yaml.SequenceNode {
+			return nil, -1, fmt.Errorf("cannot select %s: not a list", seg)
+		}
+		for i, item := range node.Content {
+			if v, _, _ := yamlChild(item, yamlPathSegment{key: seg.key}); v != nil && v.Kind == yaml.ScalarNode && v.Value == seg.value {
+				return item, i, nil
+			}
+		}
+		return nil, -1, nil
+	default:
+		if node.Kind != yaml.MappingNode {
+			return nil, -1, fmt.Errorf("cannot look up %q: not a mapping", seg.key)
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == seg.key {
+				return node.Content[i+1], i, nil
+			}
+		}
+		return nil, -1, nil
+	}
+}
+
+// formatYAMLPath renders segments back into path notation.
+func formatYAMLPath(segs []yamlPathSegment) string {
+	var sb strings.Builder
+	for i, seg := range segs {
+		if i > 0 && !seg.isIndex && !seg.isSelector {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(seg.String())
+	}
+	return sb.String()
+}
+
+// encodeYAMLNode marshals a node with the two-space indentation used for
+// stored manifests.
+func encodeYAMLNode(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// setYAMLPath sets the value at segs, creating missing mapping keys along the
+// way. An index one past the end of a list appends. Returns the replaced
+// node, or nil if the value was added.
+func setYAMLPath(root *yaml.Node, segs []yamlPathSegment, value *yaml.Node) (*yaml.Node, error) {
+	node := root
+	for i, seg := range segs[:len(segs)-1] {
+		next, _, err := yamlChild(node, seg)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			if seg.isIndex || seg.isSelector || segs[i+1].isIndex || segs[i+1].isSelector {
+				return nil, fmt.Errorf("path %s not found", formatYAMLPath(segs[:i+1]))
+			}
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: seg.key}, next)
+		}
+		node = next
+	}
+
+	last := segs[len(segs)-1]
+	old, pos, err := yamlChild(node, last)
+	if err != nil {
+		return nil, err
+	}
+	if old == nil {
+		switch {
+		case last.isSelector:
+			return nil, fmt.Errorf("path %s not found", formatYAMLPath(segs))
+		case last.isIndex:
+			if last.index != len(node.Content) {
+				return nil, fmt.Errorf("path %s not found (list has %d items)", formatYAMLPath(segs), len(node.Content))
+			}
+			node.Content = append(node.Content, value)
+		default:
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: last.key}, value)
+		}
+		return nil, nil
+	}
+
+	// Keep the comments attached to the old value, and keep strings strings:
+	// setting a quoted port "8080" to 9090 must not turn it into a number
+	value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+	if old.Kind == yaml.ScalarNode && value.Kind == yaml.ScalarNode && old.Tag == "!!str" {
+		value.Tag = "!!str"
+		value.Style = old.Style
+	}
+	if node.Kind == yaml.MappingNode {
+		pos++
+	}
+	node.Content[pos] = value
+	return old, nil
+}
+
+// removeYAMLPath removes the value at segs and returns it.
+func removeYAMLPath(root *yaml.Node, segs []yamlPathSegment) (*yaml.Node, error) {
+	parent, err := lookupYAMLPath(root, segs[:len(segs)-1])
+	if err != nil {
+		return nil, err
+	}
+	old, pos, err := yamlChild(parent, segs[len(segs)-1])
+	if err != nil {
+		return nil, err
+	}
+	if old == nil {
+		return nil, fmt.Errorf("path %s not found", formatYAMLPath(segs))
+	}
+	n := 1
+	if parent.Kind == yaml.MappingNode {
+		n = 2
+	}
+	parent.Content = append(parent.Content[:pos], parent.Content[pos+n:]...)
+	return old, nil
+}
+
+// parseYAMLValue parses a YAML value given as text into a block-style node.
+func parseYAMLValue(value string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+		return nil, fmt.Errorf("invalid value: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: ""}, nil
+	}
+	node := doc.Content[0]
+	clearFlowStyle(node)
+	return node, nil
+}
+
+// clearFlowStyle switches {a: b} and [a, b] values to block style so they
+// match the surrounding manifest.
+func clearFlowStyle(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle
+	for _, child := range node.Content {
+		clearFlowStyle(child)
+	}
+}
+
+// usesCompactSequences reports whether list items in content sit at the same
+// indentation as their key ("key:\n- item"), which is how sigs.k8s.io/yaml
+// writes them. Defaults to true when content has no block lists.
+func usesCompactSequences(content []byte) bool {
+	lines := strings.Split(string(content), "\n")
+	for i := 0; i+1 < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " ")
+		key := strings.TrimLeft(line, " -")
+		if !strings.HasSuffix(line, ":") || strings.HasPrefix(key, "#") {
+			continue
+		}
+		item := strings.TrimLeft(lines[i+1], " ")
+		if !strings.HasPrefix(item, "- ") && item != "-" {
+			continue
+		}
+		return len(lines[i+1])-len(item) == len(line)-len(key)
+	}
+	return true
+}
+
+// encodeYAMLDocument marshals an edited document with two-space indentation,
+// writing lists in the style the original content used.
+func encodeYAMLDocument(doc *yaml.Node, compactSequences bool) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if compactSequences {
+		enc.CompactSeqIndent()
+	}
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+const yamlPathTestManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      # main containers
+      containers:
+      - name: web
+        image: nginx:1.25
+      - name: proxy
+        image: envoy:1.30
+`
+
+func TestParseYAMLPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"spec.template", "spec.template"},
+		{".spec.replicas", "spec.replicas"},
+		{"spec.containers[0].image", "spec.containers[0].image"},
+		{"spec.containers[name=web].image", "spec.containers[name=web].image"},
+		{"items[1][2]", "items[1][2]"},
+	}
+	for _, tt := range tests {
+		segs, err := parseYAMLPath(tt.path)
+		if err != nil {
+			t.Errorf("parseYAMLPath(%q): %v", tt.path, err)
+			continue
+		}
+		if got := formatYAMLPath(segs); got != tt.want {
+			t.Errorf("parseYAMLPath(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "spec..name", "containers[0", "containers[x]", "containers[-1]", "containers[0]x", "containers[=web]"} {
+		if _, err := parseYAMLPath(bad); err == nil {
+			t.Errorf("parseYAMLPath(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestExtractYAMLPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"spec.template.spec.containers[1].image", "envoy:1.30\n"},
+		{"spec.template.spec.containers[name=web].image", "nginx:1.25\n"},
+		{"spec.template.spec.containers[name=proxy]", "name: proxy\nimage: envoy:1.30\n"},
+		{"metadata", "name: web\n"},
+	}
+	for _, tt := range tests {
+		got, err := extractYAMLPath([]byte(yamlPathTestManifest), tt.path)
+		if err != nil {
+			t.Errorf("extractYAMLPath(%q): %v", tt.path, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("extractYAMLPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	got, err := extractYAMLPath([]byte(yamlPathTestManifest), "spec.template.spec")
+	if err != nil || !strings.Contains(string(got), "# main containers") {
+		t.Errorf("comments should be kept, got %q (%v)", got, err)
+	}
+
+	for _, missing := range []string{"spec.replicas", "spec.template.spec.containers[5]", "spec.template.spec.containers[name=db]", "kind.name"} {
+		if _, err := extractYAMLPath([]byte(yamlPathTestManifest), missing); err == nil {
+			t.Errorf("extractYAMLPath(%q) succeeded, want error", missing)
+		}
+	}
+}
+
+func TestLineWindow(t *testing.T) {
+	content := "a\nb\nc\nd\ne\n"
+
+	chunk, total, end := lineWindow(content, 2, 2)
+	if chunk != "b\nc\n" || total != 5 || end != 3 {
+		t.Errorf("lineWindow(2, 2) = %q, %d, %d", chunk, total, end)
+	}
+	chunk, _, end = lineWindow(content, 4, 10)
+	if chunk != "d\ne\n" || end != 5 {
+		t.Errorf("lineWindow(4, 10) = %q, %d", chunk, end)
+	}
+	chunk, _, _ = lineWindow(content, 9, 10)
+	if chunk != "" {
+		t.Errorf("offset past end should return nothing, got %q", chunk)
+	}
+	if _, total, _ := lineWindow("no newline", 1, 10); total != 1 {
+		t.Errorf("total = %d, want 1", total)
+	}
+}
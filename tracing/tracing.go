@@ -0,0 +1,228 @@
+// Package tracing exports OpenTelemetry spans of agent runs: one span per
+// run with the runner's events on it, the model calls ADK traces, a span
+// per tool call and one per Kubernetes API request. A diagnosis session then
+// shows in Jaeger or any other OTLP backend where its time went.
+//
+// Tracing is off unless enabled in config.yaml. Spans carry tool names,
+// namespaces and request paths, but no prompts, replies or tool results.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/perbu/kasa/tools"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// instrumentation is the name of kasa's tracer.
+const instrumentation = "github.com/perbu/kasa"
+
+// Config configures tracing (tracing in config.yaml).
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+	// Endpoint is the OTLP/HTTP URL of the collector, such as
+	// http://localhost:4318 for Jaeger. Empty uses
+	// OTEL_EXPORTER_OTLP_ENDPOINT, or http://localhost:4318.
+	Endpoint string `yaml:"endpoint"`
+	// Headers are sent with every export, e.g. for authentication.
+	Headers map[string]string `yaml:"headers"`
+}
+
+// Validate checks the endpoint.
+func (c Config) Validate() error {
+	if c.Endpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("endpoint %q must be an http or https URL", c.Endpoint)
+	}
+	return nil
+}
+
+// Setup installs a tracer provider that exports spans over OTLP/HTTP. It
+// returns a function that flushes and stops it, to call before exiting.
+// With tracing disabled it does nothing, and spans cost next to nothing.
+func Setup(ctx context.Context, cfg Config, version string) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName("kasa"),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("creating trace resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Run runs the agent like r.Run, inside a span covering the whole run with
+// the runner's events recorded on it. The spans of model calls, tool calls
+// and Kubernetes API requests made during the run are its descendants.
+func Run(ctx context.Context, r *runner.Runner, userID, sessionID string, msg *genai.Content, cfg agent.RunConfig) iter.Seq2[*session.Event, error] {
+	return func(yield func(*session.Event, error) bool) {
+		ctx, span := tracer().Start(ctx, "agent run", trace.WithAttributes(
+			attribute.String("kasa.session", sessionID),
+		))
+		defer span.End()
+
+		var usage tokenUsage
+		for event, err := range r.Run(ctx, userID, sessionID, msg, cfg) {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			} else if event != nil {
+				recordEvent(span, event, &usage)
+			}
+			if !yield(event, err) {
+				break
+			}
+		}
+		span.SetAttributes(
+			attribute.Int("kasa.tokens.input", usage.input),
+			attribute.Int("kasa.tokens.output", usage.output),
+		)
+		if ctx.Err() != nil {
+			span.SetAttributes(attribute.Bool("kasa.cancelled", true))
+		}
+	}
+}
+
+// tokenUsage sums the tokens of a run's model responses.
+type tokenUsage struct {
+	input, output int
+}
+
+// recordEvent adds a runner event to the run's span: who produced it, the
+// tools it calls or answers, and its token usage. Streamed partial events
+// are left out; the final event repeats them.
+func recordEvent(span trace.Span, event *session.Event, usage *tokenUsage) {
+	if event.Partial {
+		return
+	}
+	attrs := []attribute.KeyValue{attribute.String("kasa.author", event.Author)}
+	name := "event"
+	var calls, responses []string
+	if event.Content != nil {
+		for _, part := range event.Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				calls = append(calls, part.FunctionCall.Name)
+			case part.FunctionResponse != nil:
+				responses = append(responses, part.FunctionResponse.Name)
+			case part.Text != "" && !part.Thought:
+				name = "model response"
+			}
+		}
+	}
+	if len(calls) > 0 {
+		name = "tool calls"
+		attrs = append(attrs, attribute.StringSlice("kasa.tools", calls))
+	}
+	if len(responses) > 0 {
+		name = "tool responses"
+		attrs = append(attrs, attribute.StringSlice("kasa.tools", responses))
+	}
+	if m := event.UsageMetadata; m != nil {
+		usage.input += int(m.PromptTokenCount)
+		usage.output += int(m.CandidatesTokenCount)
+		attrs = append(attrs,
+			attribute.Int("kasa.tokens.input", int(m.PromptTokenCount)),
+			attribute.Int("kasa.tokens.output", int(m.CandidatesTokenCount)),
+		)
+	}
+	if event.ErrorCode != "" {
+		name = "model error"
+		attrs = append(attrs, attribute.String("kasa.error_code", event.ErrorCode))
+	}
+	if event.Actions.TransferToAgent != "" {
+		name = "transfer"
+		attrs = append(attrs, attribute.String("kasa.agent", event.Actions.TransferToAgent))
+	}
+	span.AddEvent(name, trace.WithAttributes(attrs...), trace.WithTimestamp(eventTime(event)))
+}
+
+func eventTime(event *session.Event) time.Time {
+	if event.Timestamp.IsZero() {
+		return time.Now()
+	}
+	return event.Timestamp
+}
+
+// Tools is middleware (see tools.KubeTools.Use) that wraps every tool call
+// in a span, so its Kubernetes API requests show up as its children. Tools
+// that wait for the cluster are marked, to tell waiting from working.
+func Tools(t tools.ToolInfo, next tools.RunFunc) tools.RunFunc {
+	return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		// Replays and tests run tools without a tool.Context
+		if ctx == nil {
+			return next(ctx, args)
+		}
+		attrs := []attribute.KeyValue{
+			attribute.String("kasa.tool", t.Name),
+			attribute.String("kasa.tool.category", string(t.Category)),
+			attribute.Bool("kasa.tool.waits", tools.Waits(t.Name)),
+		}
+		if namespace, ok := args["namespace"].(string); ok && namespace != "" {
+			attrs = append(attrs, attribute.String("k8s.namespace.name", namespace))
+		}
+		spanCtx, span := tracer().Start(ctx, "tool "+t.Name, trace.WithAttributes(attrs...))
+		defer span.End()
+
+		result, err := next(tools.WithContext(ctx, spanCtx), args)
+		switch {
+		case err != nil:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		case result["error"] != nil:
+			span.SetStatus(codes.Error, "the tool returned an error")
+		}
+		return result, err
+	}
+}
+
+// Transport wraps the transport of the Kubernetes clients (see
+// rest.Config.Wrap) so every API request gets a span.
+func Transport(rt http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(rt, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return "k8s " + r.Method
+	}))
+}
@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/perbu/kasa/tools"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// recordSpans installs a tracer provider that keeps the ended spans.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestConfigValidate(t *testing.T) {
+	for endpoint, valid := range map[string]bool{
+		"":                             true,
+		"http://localhost:4318":        true,
+		"https://otlp.example.com/v1/": true,
+		"localhost:4318":               false,
+		"ftp://localhost":              false,
+	} {
+		if err := (Config{Enabled: true, Endpoint: endpoint}).Validate(); (err == nil) != valid {
+			t.Errorf("endpoint %q: got %v, want valid=%v", endpoint, err, valid)
+		}
+	}
+}
+
+func TestToolSpans(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var inner trace.SpanContext
+	run := Tools(tools.ToolInfo{Name: "wait_for_condition", Category: tools.CategoryReadOnly},
+		func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			inner = trace.SpanContextFromContext(ctx)
+			return map[string]any{"error": "timed out"}, nil
+		})
+	run(tools.WithContext(nil, context.Background()), map[string]any{"namespace": "prod"})
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected one span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "tool wait_for_condition" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	if inner.SpanID() != span.SpanContext().SpanID() {
+		t.Error("the tool did not run in the context of its span")
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("expected an error status for an error result, got %v", span.Status())
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if !attrs["kasa.tool.waits"].AsBool() || attrs["k8s.namespace.name"].AsString() != "prod" {
+		t.Errorf("unexpected attributes %v", span.Attributes())
+	}
+}
+
+func TestRecordEvent(t *testing.T) {
+	recorder := recordSpans(t)
+	_, span := tracer().Start(context.Background(), "agent run")
+
+	var usage tokenUsage
+	call := &session.Event{Author: "deployer", LLMResponse: model.LLMResponse{
+		Content:       &genai.Content{Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{Name: "list_pods"}}}},
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 100, CandidatesTokenCount: 10},
+	}}
+	partial := &session.Event{Author: "deployer", LLMResponse: model.LLMResponse{
+		Content: &genai.Content{Parts: []*genai.Part{{Text: "All"}}},
+		Partial: true,
+	}}
+	reply := &session.Event{Author: "deployer", LLMResponse: model.LLMResponse{
+		Content:       &genai.Content{Parts: []*genai.Part{{Text: "All pods are running."}}},
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 150, CandidatesTokenCount: 20},
+	}}
+	for _, event := range []*session.Event{call, partial, reply} {
+		recordEvent(span, event, &usage)
+	}
+	span.End()
+
+	events := recorder.Ended()[0].Events()
+	if len(events) != 2 || events[0].Name != "tool calls" || events[1].Name != "model response" {
+		t.Errorf("unexpected events %v", events)
+	}
+	if usage.input != 250 || usage.output != 30 {
+		t.Errorf("expected 250 input and 30 output tokens, got %+v", usage)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/perbu/kasa/budget"
+	"github.com/perbu/kasa/fallback"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// addBudgetCallbacks enforces the token budget, compacts each request and
+// records the token usage of each response, priced by the model that
+// answered it. They are added before the
+// telemetry callbacks so a refused call isn't counted as a model call.
+func addBudgetCallbacks(cfg *llmagent.Config, tracker *budget.Tracker, compactor *budget.Compactor) {
+	cfg.BeforeModelCallbacks = append(cfg.BeforeModelCallbacks,
+		func(ctx agent.CallbackContext, req *model.LLMRequest) (*model.LLMResponse, error) {
+			if tracker.Exceeded() {
+				return &model.LLMResponse{Content: genai.NewContentFromText(tracker.ExceededMessage(), genai.RoleModel)}, nil
+			}
+			compactor.Compact(ctx, ctx.SessionID(), req)
+			return nil, nil
+		})
+	cfg.AfterModelCallbacks = append(cfg.AfterModelCallbacks,
+		func(ctx agent.CallbackContext, resp *model.LLMResponse, respErr error) (*model.LLMResponse, error) {
+			if resp != nil && !resp.Partial {
+				tracker.Record(ctx.SessionID(), fallback.Model(resp.CustomMetadata), resp.UsageMetadata)
+			}
+			return nil, nil
+		})
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/perbu/kasa/integrations/slack"
+	"github.com/perbu/kasa/manifest"
+	"github.com/perbu/kasa/tools"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/homedir"
+)
+
+// defaultWatchInterval is used when watch.interval is not set.
+const defaultWatchInterval = 5 * time.Minute
+
+// watcher runs periodic drift scans for 'kasa watch' and, where the
+// auto-remediation policy allows, re-applies the stored manifests.
+type watcher struct {
+	cfg          *Config
+	dynClient    dynamic.Interface
+	resolver     *tools.GVRResolver
+	kubeTools    *tools.KubeTools // re-applies through the safety checks
+	manifest     *manifest.Manager
+	slack        *slack.Client
+	auditLogPath string
+
+	lastDrift []string // resources out of sync at the previous scan
+}
+
+// runWatch scans for drift every watch.interval until ctx is cancelled.
+func runWatch(ctx context.Context, cfg *Config, dynClient dynamic.Interface, kubeTools *tools.KubeTools, mgr *manifest.Manager, slackClient *slack.Client) int {
+	interval := cfg.Watch.Interval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	w := &watcher{
+		cfg:          cfg,
+		dynClient:    dynClient,
+		resolver:     kubeTools.Resolver(),
+		kubeTools:    kubeTools,
+		manifest:     mgr,
+		slack:        slackClient,
+		auditLogPath: remediationLogPath(),
+	}
+
+	policy := cfg.Watch.AutoRemediate
+	if policy.IsEmpty() {
+		log.Printf("Watching for drift every %s (auto-remediation disabled)", interval)
+	} else {
+		log.Printf("Watching for drift every %s (auto-remediating namespaces %v, apps %v)", interval, policy.Namespaces, policy.Apps)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		w.scan(ctx)
+		select {
+		case <-ctx.Done():
+			log.Printf("Watch stopped")
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan runs one drift scan, notifies about new drift and remediates it.
+func (w *watcher) scan(ctx context.Context) {
+	if w.cfg.Deployments.Remote != "" {
+		if err := w.manifest.Pull(); err != nil {
+			log.Printf("Warning: failed to pull manifests: %v", err)
+		}
+	}
+
+	results, err := tools.RunDriftScan(ctx, w.dynClient, w.resolver, w.manifest, nil)
+	if err != nil {
+		log.Printf("Drift scan failed: %v", err)
+		return
+	}
+	if results == nil {
+		log.Printf("Drift scan: no stored manifests")
+		return
+	}
+	log.Printf("Drift scan: %d manifests, %d in sync, %d drifted, %d not in cluster, %d errors",
+		results.Total, results.InSync, results.Drifted, results.Missing, results.Errors)
+	if ctx.Err() != nil {
+		return
+	}
+
+	// Only notify when the set of out-of-sync resources changes
+	drift := outOfSync(results)
+	if !slices.Equal(drift, w.lastDrift) {
+		if w.slack != nil {
+			w.slack.DriftDetected(results)
+		}
+		w.lastDrift = drift
+	}
+
+	var remediated []tools.RemediationResult
+	for _, r := range w.kubeTools.RemediateDrift(ctx, w.cfg.Watch.AutoRemediate, results) {
+		if r.Skipped != "" {
+			log.Printf("Not remediating %s/%s/%s: %s", r.Namespace, r.Name, r.Kind, r.Skipped)
+			continue
+		}
+		remediated = append(remediated, r)
+	}
+	if len(remediated) == 0 {
+		return
+	}
+	for _, r := range remediated {
+		if r.Error != "" {
+			log.Printf("Remediation failed for %s/%s/%s: %s", r.Namespace, r.Name, r.Kind, r.Error)
+		} else {
+			log.Printf("Remediated %s/%s/%s: %s (%s)", r.Namespace, r.Name, r.Kind, r.Action, r.Drift)
+		}
+	}
+	if err := appendAuditLog(w.auditLogPath, remediated); err != nil {
+		log.Printf("Warning: failed to write remediation audit log: %v", err)
+	}
+	if w.slack != nil {
+		w.slack.Remediated(remediated)
+	}
+	// The next scan should report the reverted resources as in sync again
+	w.lastDrift = nil
+}
+
+// appendAuditLog appends remediation results to the audit log as JSON lines.
+func appendAuditLog(path string, results []tools.RemediationResult) error {
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, r := range results {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outOfSync returns the sorted resources that are not in sync.
+func outOfSync(results *tools.DriftScanResults) []string {
+	var drift []string
+	for _, r := range results.Results {
+		if r.Status != "in_sync" {
+			drift = append(drift, fmt.Sprintf("%s/%s/%s:%s", r.Namespace, r.Name, r.Kind, r.Status))
+		}
+	}
+	slices.Sort(drift)
+	return drift
+}
+
+// remediationLogPath returns the location of the audit log (~/.kasa/remediation.log).
+func remediationLogPath() string {
+	home := homedir.HomeDir()
+	if home == "" {
+		return ""
+	}
+	kasaDir := filepath.Join(home, ".kasa")
+	_ = os.MkdirAll(kasaDir, 0755)
+	return filepath.Join(kasaDir, "remediation.log")
+}